@@ -0,0 +1,22 @@
+package auth
+
+import "github.com/spf13/cobra"
+
+func newAPIKeyCommand() *cobra.Command {
+	var name string
+	var key string
+
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Generate a gateway API key and its config entry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return authAPIKeyCmd(cmd.OutOrStdout(), name, key)
+		},
+	}
+
+	cmd.Flags().StringVarP(&name, "name", "n", "default", "Name recorded in audit logs for this key")
+	cmd.Flags().StringVar(&key, "key", "", "Hash an existing key instead of generating one")
+
+	return cmd
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/api"
+)
+
+func TestNewAPIKeySubcommand(t *testing.T) {
+	cmd := newAPIKeyCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Generate a gateway API key and its config entry", cmd.Short)
+
+	assert.NotNil(t, cmd.Flags().Lookup("name"))
+	assert.NotNil(t, cmd.Flags().Lookup("key"))
+}
+
+func TestAuthAPIKeyCmd_HashesGivenKey(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, authAPIKeyCmd(&out, "ci", "tc_example"))
+
+	assert.NotContains(t, out.String(), "shown once")
+	assert.Contains(t, out.String(), `"name": "ci"`)
+	assert.Contains(t, out.String(), api.HashAPIKey("tc_example"))
+}
+
+func TestAuthAPIKeyCmd_GeneratesKey(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, authAPIKeyCmd(&out, "default", ""))
+
+	assert.Contains(t, out.String(), "API key (shown once): tc_")
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +13,7 @@ import (
 	"time"
 
 	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal"
+	"github.com/tinyland-inc/tinyclaw/pkg/api"
 	"github.com/tinyland-inc/tinyclaw/pkg/auth"
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
@@ -438,3 +441,27 @@ func isAnthropicModel(model string) bool {
 	return model == "anthropic" ||
 		strings.HasPrefix(model, "anthropic/")
 }
+
+// authAPIKeyCmd prints a gateway API key and the gateway.auth.keys entry
+// holding its hash. Only the hash is stored, so the key is shown once.
+func authAPIKeyCmd(out io.Writer, name, key string) error {
+	generated := key == ""
+	if generated {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("generating key: %w", err)
+		}
+		key = "tc_" + hex.EncodeToString(buf)
+	}
+
+	entry, err := json.MarshalIndent(config.APIKeyConfig{Name: name, Hash: api.HashAPIKey(key)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if generated {
+		fmt.Fprintf(out, "API key (shown once): %s\n\n", key)
+	}
+	fmt.Fprintf(out, "Add to gateway.auth.keys in your config:\n%s\n", entry)
+	fmt.Fprintln(out, "\nOptional fields: agents, endpoints, rate_limit (per minute), max_concurrent.")
+	return nil
+}
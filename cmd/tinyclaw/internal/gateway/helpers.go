@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal"
@@ -21,14 +24,15 @@ import (
 	"github.com/tinyland-inc/tinyclaw/pkg/core"
 	"github.com/tinyland-inc/tinyclaw/pkg/cron"
 	"github.com/tinyland-inc/tinyclaw/pkg/devices"
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
 	"github.com/tinyland-inc/tinyclaw/pkg/health"
 	"github.com/tinyland-inc/tinyclaw/pkg/heartbeat"
 	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 	"github.com/tinyland-inc/tinyclaw/pkg/state"
 	tailscaleint "github.com/tinyland-inc/tinyclaw/pkg/tailscale"
 	"github.com/tinyland-inc/tinyclaw/pkg/tools"
-	"github.com/tinyland-inc/tinyclaw/pkg/voice"
 )
 
 // GatewayMode selects the message processing backend.
@@ -56,6 +60,7 @@ func gatewayCmd(debug bool, mode GatewayMode) error {
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
+	loaded := *cfg
 
 	provider, modelID, err := providers.CreateProvider(cfg)
 	if err != nil {
@@ -186,41 +191,8 @@ func gatewayCmd(debug bool, mode GatewayMode) error {
 		}
 	}
 
-	var transcriber *voice.GroqTranscriber
-	groqAPIKey := cfg.Providers.Groq.APIKey
-	if groqAPIKey == "" {
-		for _, mc := range cfg.ModelList {
-			if strings.HasPrefix(mc.Model, "groq/") && mc.APIKey != "" {
-				groqAPIKey = mc.APIKey
-				break
-			}
-		}
-	}
-	if groqAPIKey != "" {
-		transcriber = voice.NewGroqTranscriber(groqAPIKey)
-		logger.InfoC("voice", "Groq voice transcription enabled")
-	}
-
-	if transcriber != nil {
-		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
-			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
-				tc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Telegram channel")
-			}
-		}
-		if discordChannel, ok := channelManager.GetChannel("discord"); ok {
-			if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
-				dc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Discord channel")
-			}
-		}
-		if slackChannel, ok := channelManager.GetChannel("slack"); ok {
-			if sc, ok := slackChannel.(*channels.SlackChannel); ok {
-				sc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Slack channel")
-			}
-		}
-	}
+	transcriber := newTranscriber(cfg)
+	attachTranscriber(channelManager, transcriber)
 
 	enabledChannels := channelManager.GetEnabledChannels()
 	if len(enabledChannels) > 0 {
@@ -244,10 +216,17 @@ func gatewayCmd(debug bool, mode GatewayMode) error {
 
 	stateManager := state.NewManager(cfg.WorkspacePath())
 	deviceService := devices.NewService(devices.Config{
-		Enabled:    cfg.Devices.Enabled,
-		MonitorUSB: cfg.Devices.MonitorUSB,
+		Enabled:          cfg.Devices.Enabled,
+		MonitorUSB:       cfg.Devices.MonitorUSB,
+		MonitorBlock:     cfg.Devices.MonitorBlock,
+		MonitorNet:       cfg.Devices.MonitorNet,
+		MonitorPower:     cfg.Devices.MonitorPower,
+		MonitorBluetooth: cfg.Devices.MonitorBluetooth,
+		PowerThresholds:  cfg.Devices.PowerThresholds,
+		Rules:            deviceRules(cfg.Devices.Rules),
 	}, stateManager)
 	deviceService.SetBus(msgBus)
+	deviceService.SetAgentHandler(agentLoop.ProcessDirectWithChannel)
 	if err := deviceService.Start(ctx); err != nil {
 		fmt.Printf("Error starting device service: %v\n", err)
 	} else if cfg.Devices.Enabled {
@@ -260,7 +239,38 @@ func gatewayCmd(debug bool, mode GatewayMode) error {
 
 	healthServer := health.NewServer(cfg.Gateway.Host, cfg.Gateway.Port)
 	apiHandlers := api.NewHandlers(agentLoop)
+	apiHandlers.SetJobStore(api.NewJobStore(
+		filepath.Join(cfg.WorkspacePath(), "state", "jobs.json"),
+		cfg.Gateway.Jobs.MaxJobs,
+		cfg.Gateway.Jobs.CallbackSecret,
+		cfg.Gateway.Jobs.AllowPrivateCallbacks,
+	))
+	if len(cfg.Gateway.Auth.Keys) > 0 {
+		var lookup api.SecretLookup
+		if cfg.Gateway.Auth.SetecURL != "" {
+			setec := tailscaleint.NewSetecClient(tailscaleint.SetecConfig{Enabled: true, BaseURL: cfg.Gateway.Auth.SetecURL})
+			lookup = setec.Get
+		}
+		authenticator, err := api.NewAuthenticator(ctx, cfg.Gateway.Auth, lookup)
+		if err != nil {
+			return fmt.Errorf("invalid gateway.auth config: %w", err)
+		}
+		apiHandlers.SetAuthenticator(authenticator)
+		fmt.Printf("✓ API key authentication enabled (%d keys)\n", len(cfg.Gateway.Auth.Keys))
+	} else {
+		fmt.Println("⚠ Warning: no gateway.auth.keys configured; the HTTP API is open to anyone who can reach it")
+	}
 	apiHandlers.Register(healthServer)
+	agentLoop.RegisterMetrics(metrics.Default)
+	if webhooks := channelManager.MountWebhooks(healthServer); len(webhooks) > 0 {
+		fmt.Printf("✓ Channel webhooks mounted under %s: %s\n", channels.WebhookPathPrefix, strings.Join(webhooks, ", "))
+	}
+	if port := cfg.Gateway.Webhooks.TailnetPort; port > 0 {
+		serveWebhooksOnTailnet(tsServer, port, healthServer)
+	}
+	if routes := channelManager.MountRoutes(healthServer); slices.Contains(routes, "web") {
+		fmt.Printf("✓ Web chat available at http://%s:%d%s\n", cfg.Gateway.Host, cfg.Gateway.Port, channels.WebPathPrefix)
+	}
 	go func() {
 		if err := healthServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.ErrorCF("health", "Health server error", map[string]any{"error": err.Error()})
@@ -270,14 +280,46 @@ func gatewayCmd(debug bool, mode GatewayMode) error {
 
 	go agentLoop.Run(ctx)
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
-	<-sigChan
+	reloader := &configReloader{
+		loaded:      &loaded,
+		agentLoop:   agentLoop,
+		channels:    channelManager,
+		transcriber: transcriber,
+		providers:   []providers.LLMProvider{provider},
+	}
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	if cfg.Gateway.WatchConfig {
+		go config.WatchFiles(watchCtx, []string{internal.GetConfigPath(), internal.GetDhallConfigPath()}, 2*time.Second,
+			func() { reloader.reload(ctx, "file changed") })
+	}
 
-	fmt.Println("\nShutting down...")
-	if cp, ok := provider.(providers.StatefulProvider); ok {
-		cp.Close()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	for waiting := true; waiting; {
+		select {
+		case <-sigChan:
+			waiting = false
+		case <-hupChan:
+			reloader.reload(ctx, "SIGHUP")
+		}
 	}
+
+	fmt.Println("\nShutting down... (signal again to stop immediately)")
+	go func() {
+		<-sigChan
+		fmt.Println("\nForced shutdown")
+		os.Exit(1)
+	}()
+	stopWatching()
+	healthServer.SetReady(false)
+	deviceService.Stop()
+	heartbeatService.Stop()
+	cronService.Stop()
+	drain(cfg.Gateway.Shutdown, agentLoop, channelManager, cronService, msgBus)
+
+	reloader.close()
 	if coreProxy != nil {
 		coreProxy.Stop()
 	}
@@ -288,9 +330,6 @@ func gatewayCmd(debug bool, mode GatewayMode) error {
 	_ = meterStore     // metrics are in-memory, no cleanup needed
 	cancel()
 	healthServer.Stop(context.Background())
-	deviceService.Stop()
-	heartbeatService.Stop()
-	cronService.Stop()
 	agentLoop.Stop()
 	channelManager.StopAll(ctx)
 	fmt.Println("✓ Gateway stopped")
@@ -298,6 +337,29 @@ func gatewayCmd(debug bool, mode GatewayMode) error {
 	return nil
 }
 
+// deviceRules converts the configured device rules for the device service.
+func deviceRules(cfgs []config.DeviceRuleConfig) []devices.Rule {
+	rules := make([]devices.Rule, 0, len(cfgs))
+	for _, rc := range cfgs {
+		rules = append(rules, devices.Rule{
+			Name:     rc.Name,
+			Kind:     events.Kind(rc.Kind),
+			Action:   events.Action(rc.Action),
+			Vendor:   rc.Vendor,
+			Product:  rc.Product,
+			Serial:   rc.Serial,
+			Match:    rc.Match,
+			Do:       rc.Do,
+			Prompt:   rc.Prompt,
+			Skill:    rc.Skill,
+			Channel:  rc.Channel,
+			ChatID:   rc.ChatID,
+			Debounce: time.Duration(rc.DebounceSeconds) * time.Second,
+		})
+	}
+	return rules
+}
+
 func setupCronTool(
 	agentLoop *agent.AgentLoop,
 	msgBus *bus.MessageBus,
@@ -323,3 +385,26 @@ func setupCronTool(
 
 	return cronService
 }
+
+// serveWebhooksOnTailnet serves the channel webhooks mounted on gateway on
+// the tsnet node as well. The listener is closed when the node stops.
+func serveWebhooksOnTailnet(tsServer *tailscaleint.Server, port int, gateway http.Handler) {
+	if tsServer == nil {
+		fmt.Println("⚠ gateway.webhooks.tailnet_port is set but Tailscale is not running; webhooks are only served on the gateway port")
+		return
+	}
+	ln, err := tsServer.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		fmt.Printf("⚠ Tailnet webhook listener failed: %v\n", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle(channels.WebhookPathPrefix, gateway)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.ErrorCF("gateway", "Tailnet webhook server error", map[string]any{"error": err.Error()})
+		}
+	}()
+	fmt.Printf("✓ Channel webhooks also served on the tailnet at :%d%s\n", port, channels.WebhookPathPrefix)
+}
@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal"
+	"github.com/tinyland-inc/tinyclaw/pkg/agent"
+	"github.com/tinyland-inc/tinyclaw/pkg/channels"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+	"github.com/tinyland-inc/tinyclaw/pkg/routing"
+	"github.com/tinyland-inc/tinyclaw/pkg/voice"
+)
+
+// agentSections are the config sections the agents are built from; a
+// change to any of them rebuilds the provider and the agents.
+var agentSections = []string{"agents", "bindings", "session", "providers", "model_list", "tools"}
+
+// restartSections are applied only when the gateway starts.
+var restartSections = []string{"heartbeat", "devices", "tailscale", "aperture"}
+
+// configReloader applies config file changes to a running gateway. A new
+// config is loaded and checked in full before anything is touched, so an
+// invalid one is rejected and the gateway carries on as it was.
+type configReloader struct {
+	mu          sync.Mutex
+	loaded      *config.Config // as loaded, before the provider's model is applied
+	agentLoop   *agent.AgentLoop
+	channels    *channels.Manager
+	transcriber *voice.GroqTranscriber
+	providers   []providers.LLMProvider // every provider created, closed on shutdown
+}
+
+// reload loads the config again and applies what changed.
+func (r *configReloader) reload(ctx context.Context, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := loadReloadConfig()
+	if err != nil {
+		fmt.Printf("⚠ Config reload rejected (%s): %v\n", reason, err)
+		logger.WarnCF("gateway", "Config reload rejected", map[string]any{"reason": reason, "error": err.Error()})
+		return
+	}
+	loaded := *next
+
+	changes := config.Diff(r.loaded, next)
+	if changes.Empty() {
+		logger.DebugCF("gateway", "Config unchanged", map[string]any{"reason": reason})
+		return
+	}
+
+	// Build the new provider first: it is the part most likely to fail.
+	var provider providers.LLMProvider
+	if changes.Has(agentSections...) {
+		var modelID string
+		provider, modelID, err = providers.CreateProvider(next)
+		if err != nil {
+			fmt.Printf("⚠ Config reload rejected (%s): error creating provider: %v\n", reason, err)
+			logger.WarnCF("gateway", "Config reload rejected", map[string]any{"reason": reason, "error": err.Error()})
+			return
+		}
+		if modelID != "" {
+			next.Agents.Defaults.ModelName = modelID
+		}
+	}
+
+	stopped, started, err := r.channels.Reload(ctx, next)
+	var startErr *channels.StartError
+	var restored []string
+	if errors.As(err, &startErr) {
+		fmt.Printf("⚠ Config reload (%s): %v\n", reason, err)
+		logger.WarnCF("gateway", "Channels kept previous settings", map[string]any{"reason": reason, "error": err.Error()})
+		// Remember the failed channels' old settings so the next reload
+		// retries them even if the file is unchanged.
+		loaded = *loaded.WithChannelsFrom(r.loaded, startErr.Channels())
+		restored = startErr.Restored
+	} else if err != nil {
+		closeProvider(provider)
+		fmt.Printf("⚠ Config reload rejected (%s): %v\n", reason, err)
+		logger.WarnCF("gateway", "Config reload rejected", map[string]any{"reason": reason, "error": err.Error()})
+		return
+	}
+
+	if provider != nil {
+		r.agentLoop.Reload(next, provider)
+		r.providers = append(r.providers, provider)
+	}
+	if changes.Has("providers", "model_list") {
+		r.transcriber = newTranscriber(next)
+	}
+	attachTranscriber(r.channels, r.transcriber)
+
+	summary := strings.Join(changes.Sections, ", ")
+	if len(started) > 0 || len(stopped) > 0 {
+		summary += fmt.Sprintf("; channels stopped: %v, started: %v", stopped, started)
+	}
+	if len(restored) > 0 {
+		summary += fmt.Sprintf(", restored: %v", restored)
+	}
+	fmt.Printf("✓ Config reloaded (%s): %s\n", reason, summary)
+	logger.InfoCF("gateway", "Config reloaded", map[string]any{
+		"reason":            reason,
+		"sections":          changes.Sections,
+		"channels_stopped":  stopped,
+		"channels_started":  started,
+		"channels_restored": restored,
+	})
+
+	var restart []string
+	for _, section := range restartSections {
+		if changes.Has(section) {
+			restart = append(restart, section)
+		}
+	}
+	// Only the webhook limits of the gateway section apply live.
+	oldGateway, newGateway := r.loaded.Gateway, next.Gateway
+	oldGateway.Webhooks = config.WebhooksConfig{TailnetPort: oldGateway.Webhooks.TailnetPort}
+	newGateway.Webhooks = config.WebhooksConfig{TailnetPort: newGateway.Webhooks.TailnetPort}
+	if !reflect.DeepEqual(oldGateway, newGateway) {
+		restart = append(restart, "gateway")
+	}
+	if len(restart) > 0 {
+		fmt.Printf("⚠ Restart the gateway to apply changes to: %s\n", strings.Join(restart, ", "))
+	}
+	r.loaded = &loaded
+}
+
+// close closes the providers created for the gateway and its reloads.
+func (r *configReloader) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.providers {
+		closeProvider(p)
+	}
+}
+
+// loadReloadConfig loads the config like the gateway does at startup, but
+// refuses a missing file rather than falling back to the defaults, and one
+// with enabled channels missing credentials or bindings to unknown agents.
+func loadReloadConfig() (*config.Config, error) {
+	if _, err := os.Stat(internal.GetDhallConfigPath()); err != nil {
+		if _, err := os.Stat(internal.GetConfigPath()); errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("config file %s not found", internal.GetConfigPath())
+		}
+	}
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateReloadConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validateReloadConfig checks the parts of cfg a reload would otherwise
+// apply half-way.
+func validateReloadConfig(cfg *config.Config) error {
+	return errors.Join(channels.ValidateConfig(cfg), routing.ValidateBindings(cfg))
+}
+
+func closeProvider(p providers.LLMProvider) {
+	if sp, ok := p.(providers.StatefulProvider); ok {
+		sp.Close()
+	}
+}
+
+// newTranscriber returns a Groq voice transcriber when a Groq API key is
+// configured, or nil.
+func newTranscriber(cfg *config.Config) *voice.GroqTranscriber {
+	groqAPIKey := cfg.Providers.Groq.APIKey
+	if groqAPIKey == "" {
+		for _, mc := range cfg.ModelList {
+			if strings.HasPrefix(mc.Model, "groq/") && mc.APIKey != "" {
+				groqAPIKey = mc.APIKey
+				break
+			}
+		}
+	}
+	if groqAPIKey == "" {
+		return nil
+	}
+	logger.InfoC("voice", "Groq voice transcription enabled")
+	return voice.NewGroqTranscriber(groqAPIKey)
+}
+
+// attachTranscriber hands transcriber to the channels that accept voice
+// messages.
+func attachTranscriber(channelManager *channels.Manager, transcriber *voice.GroqTranscriber) {
+	if transcriber == nil {
+		return
+	}
+	if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
+		if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
+			tc.SetTranscriber(transcriber)
+			logger.InfoC("voice", "Groq transcription attached to Telegram channel")
+		}
+	}
+	if discordChannel, ok := channelManager.GetChannel("discord"); ok {
+		if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
+			dc.SetTranscriber(transcriber)
+			logger.InfoC("voice", "Groq transcription attached to Discord channel")
+		}
+	}
+	if slackChannel, ok := channelManager.GetChannel("slack"); ok {
+		if sc, ok := slackChannel.(*channels.SlackChannel); ok {
+			sc.SetTranscriber(transcriber)
+			logger.InfoC("voice", "Groq transcription attached to Slack channel")
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/agent"
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/channels"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/cron"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// noticeTimeout bounds sending the shutdown notice to one chat.
+const noticeTimeout = 5 * time.Second
+
+// drain lets the gateway finish its work before it stops: chats active
+// recently are told it is restarting, the agent loop stops taking new
+// messages and waits for the runs in flight, a running cron job saves its
+// state, and replies still queued are delivered. Everything shares the
+// configured drain timeout.
+func drain(
+	cfg config.ShutdownConfig,
+	agentLoop *agent.AgentLoop,
+	channelManager *channels.Manager,
+	cronService *cron.CronService,
+	msgBus *bus.MessageBus,
+) {
+	timeout := time.Duration(cfg.DrainTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if cfg.Notice != "" {
+		window := time.Duration(cfg.NoticeWindowMinutes) * time.Minute
+		for _, chat := range agentLoop.ActiveChats(window) {
+			sendCtx, sendCancel := context.WithTimeout(ctx, noticeTimeout)
+			err := channelManager.SendToChannel(sendCtx, chat.Channel, chat.ChatID, cfg.Notice)
+			sendCancel()
+			if err != nil {
+				logger.WarnCF("gateway", "Failed to send shutdown notice", map[string]any{
+					"channel": chat.Channel,
+					"chat_id": chat.ChatID,
+					"error":   err.Error(),
+				})
+			}
+		}
+	}
+
+	if err := agentLoop.Drain(ctx); err != nil {
+		fmt.Printf("⚠ Drain timed out after %s with agent runs still in flight\n", timeout)
+	} else {
+		fmt.Println("✓ In-flight agent runs finished")
+	}
+	if err := cronService.Wait(ctx); err != nil {
+		logger.WarnCF("gateway", "Cron job still running at shutdown", nil)
+	}
+	waitForOutbound(ctx, msgBus)
+}
+
+// waitForOutbound waits until the outbound queue is empty or ctx is done.
+func waitForOutbound(ctx context.Context, msgBus *bus.MessageBus) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if _, outbound := msgBus.QueueDepths(); outbound == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
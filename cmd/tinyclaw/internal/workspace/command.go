@@ -0,0 +1,47 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal"
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
+)
+
+func NewWorkspaceCommand() *cobra.Command {
+	var (
+		dir string
+		j   *journal.Journal
+	)
+
+	cmd := &cobra.Command{
+		Use:     "workspace",
+		Aliases: []string{"ws"},
+		Short:   "Inspect and roll back changes to the workspace",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if dir == "" {
+				dir = cfg.WorkspacePath()
+			}
+			j = journal.New(dir, journal.ConfigOptions(cfg.Tools.Journal))
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&dir, "workspace", "w", "", "Workspace directory (default: the default agent's)")
+
+	cmd.AddCommand(
+		newHistoryCommand(func() *journal.Journal { return j }),
+		newRevertCommand(func() *journal.Journal { return j }),
+	)
+
+	return cmd
+}
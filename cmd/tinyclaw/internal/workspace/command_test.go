@@ -0,0 +1,36 @@
+package workspace
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWorkspaceCommand(t *testing.T) {
+	cmd := NewWorkspaceCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Inspect and roll back changes to the workspace", cmd.Short)
+	assert.True(t, cmd.HasAlias("ws"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("workspace"))
+	assert.NotNil(t, cmd.PersistentPreRunE)
+
+	allowedCommands := []string{
+		"history",
+		"revert",
+	}
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, len(allowedCommands))
+
+	for _, subcmd := range subcommands {
+		found := slices.Contains(allowedCommands, subcmd.Name())
+		assert.True(t, found, "unexpected subcommand %q", subcmd.Name())
+
+		assert.Nil(t, subcmd.Run)
+		assert.NotNil(t, subcmd.RunE)
+	}
+}
@@ -0,0 +1,51 @@
+package workspace
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
+)
+
+func newHistoryCommand(j func() *journal.Journal) *cobra.Command {
+	var (
+		session string
+		limit   int
+	)
+
+	cmd := &cobra.Command{
+		Use:     "history",
+		Short:   "List journaled file changes, newest first",
+		Args:    cobra.NoArgs,
+		Example: `tinyclaw workspace history --session telegram:123456 -n 5`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			entries, err := j().Entries()
+			if err != nil {
+				return err
+			}
+
+			shown := 0
+			for i := len(entries) - 1; i >= 0 && (limit <= 0 || shown < limit); i-- {
+				e := entries[i]
+				if session != "" && e.Session != session {
+					continue
+				}
+				fmt.Println(e.String())
+				if e.Session != "" {
+					fmt.Printf("    session %s, turn %s\n", e.Session, e.Turn)
+				}
+				shown++
+			}
+			if shown == 0 {
+				fmt.Println("No journaled changes.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&session, "session", "s", "", "Only show changes made in this session")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of entries, 0 for all")
+
+	return cmd
+}
@@ -0,0 +1,66 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
+)
+
+func newRevertCommand(j func() *journal.Journal) *cobra.Command {
+	var (
+		turn  bool
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "revert <id>",
+		Short: "Roll back a journaled tool call, or its whole turn",
+		Args:  cobra.ExactArgs(1),
+		Example: `tinyclaw workspace revert 12
+tinyclaw workspace revert 12 --turn`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+			if err != nil {
+				return fmt.Errorf("invalid entry ID %q", args[0])
+			}
+
+			ids := []int{id}
+			if turn {
+				entries, err := j().Turn(id)
+				if err != nil {
+					return err
+				}
+				ids = ids[:0]
+				for _, e := range entries {
+					ids = append(ids, e.ID)
+				}
+				if len(ids) == 0 {
+					return fmt.Errorf("every change in the turn of entry %d is already reverted", id)
+				}
+			}
+
+			undone, err := j().Revert(ids, force)
+			var conflict *journal.ConflictError
+			if errors.As(err, &conflict) {
+				return fmt.Errorf("%w (use --force to overwrite)", err)
+			}
+			if err != nil {
+				return err
+			}
+			for _, c := range undone {
+				fmt.Printf("✓ Reverted: %s\n", c)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&turn, "turn", "t", false, "Revert every change of the turn the entry belongs to")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Revert even if files changed since")
+
+	return cmd
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal/skills"
 	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal/status"
 	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal/version"
+	"github.com/tinyland-inc/tinyclaw/cmd/tinyclaw/internal/workspace"
 )
 
 func NewTinyclawCommand() *cobra.Command {
@@ -43,6 +44,7 @@ func NewTinyclawCommand() *cobra.Command {
 		migrate.NewMigrateCommand(),
 		skills.NewSkillsCommand(),
 		version.NewVersionCommand(),
+		workspace.NewWorkspaceCommand(),
 	)
 
 	return cmd
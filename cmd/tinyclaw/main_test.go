@@ -42,6 +42,7 @@ func TestNewTinyclawCommand(t *testing.T) {
 		"skills",
 		"status",
 		"version",
+		"workspace",
 	}
 
 	subcommands := cmd.Commands()
@@ -0,0 +1,255 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+	"github.com/tinyland-inc/tinyclaw/pkg/tokenizer"
+)
+
+// Per-message framing overhead used by OpenAI-style chat templates
+// (role markers and separators), plus the reply primer appended once.
+const (
+	tokensPerMessage   = 4
+	tokensReplyPrimer  = 3
+	tokensPerToolDef   = 8
+	tokenCacheMaxItems = 4096
+)
+
+// tokenCounter counts prompt tokens with the tokenizer for each model and
+// corrects the result with provider-reported usage. Counts for individual
+// strings are cached because history messages are re-counted on every
+// LLM iteration.
+type tokenCounter struct {
+	registry   *tokenizer.Registry
+	calibrator *tokenizer.Calibrator
+
+	mu    sync.Mutex
+	cache map[tokenCacheKey]int
+}
+
+type tokenCacheKey struct {
+	encoding string
+	text     string
+}
+
+func newTokenCounter(registry *tokenizer.Registry) *tokenCounter {
+	return &tokenCounter{
+		registry:   registry,
+		calibrator: tokenizer.NewCalibrator(),
+		cache:      make(map[tokenCacheKey]int),
+	}
+}
+
+// countText counts tokens in a single string for model (uncalibrated).
+func (tc *tokenCounter) countText(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	tok := tc.registry.ForModel(model)
+	key := tokenCacheKey{encoding: tok.Name(), text: text}
+
+	tc.mu.Lock()
+	if n, ok := tc.cache[key]; ok {
+		tc.mu.Unlock()
+		return n
+	}
+	tc.mu.Unlock()
+
+	n := tok.Count(text)
+
+	tc.mu.Lock()
+	if len(tc.cache) >= tokenCacheMaxItems {
+		clear(tc.cache)
+	}
+	tc.cache[key] = n
+	tc.mu.Unlock()
+	return n
+}
+
+// countMessage counts one message including role framing, reasoning
+// content and tool-call names/arguments.
+func (tc *tokenCounter) countMessage(model string, m providers.Message) int {
+	n := tokensPerMessage + tc.countText(model, m.Content) + tc.countText(model, m.ReasoningContent)
+	for _, call := range m.ToolCalls {
+		name := call.Name
+		args := ""
+		if call.Function != nil {
+			if name == "" {
+				name = call.Function.Name
+			}
+			args = call.Function.Arguments
+		}
+		n += tokensPerMessage + tc.countText(model, name) + tc.countText(model, args)
+	}
+	if m.ToolCallID != "" {
+		n += tc.countText(model, m.ToolCallID)
+	}
+	return n
+}
+
+// countMessages counts a message list (uncalibrated).
+func (tc *tokenCounter) countMessages(model string, messages []providers.Message) int {
+	n := tokensReplyPrimer
+	for _, m := range messages {
+		n += tc.countMessage(model, m)
+	}
+	return n
+}
+
+// countTools counts the tool schemas as they are serialized to the provider.
+func (tc *tokenCounter) countTools(model string, defs []providers.ToolDefinition) int {
+	n := 0
+	for _, def := range defs {
+		data, err := json.Marshal(def.Function)
+		if err != nil {
+			continue
+		}
+		n += tokensPerToolDef + tc.countText(model, string(data))
+	}
+	return n
+}
+
+// observeUsage feeds provider-reported prompt tokens back into calibration.
+func (tc *tokenCounter) observeUsage(model string, estimated int, usage *providers.UsageInfo) {
+	if usage == nil || usage.PromptTokens <= 0 {
+		return
+	}
+	tc.calibrator.Observe(model, estimated, usage.PromptTokens)
+}
+
+// promptBudget is the token breakdown of a single LLM request.
+type promptBudget struct {
+	Window         int // model context window
+	ReservedOutput int // tokens reserved for the completion (max_tokens)
+	System         int // system prompt
+	Tools          int // tool schemas
+	History        int // conversation messages after the system prompt
+}
+
+// Limit returns the number of prompt tokens that fit next to the reserved output.
+func (b promptBudget) Limit() int {
+	return b.Window - b.ReservedOutput
+}
+
+// Used returns the prompt tokens the request currently needs.
+func (b promptBudget) Used() int {
+	return b.System + b.Tools + b.History
+}
+
+// Overflow returns how many prompt tokens exceed the limit (0 if it fits).
+func (b promptBudget) Overflow() int {
+	return max(b.Used()-b.Limit(), 0)
+}
+
+// measure computes the calibrated budget for sending messages and defs to model.
+func (tc *tokenCounter) measure(
+	model string,
+	window, reservedOutput int,
+	messages []providers.Message,
+	defs []providers.ToolDefinition,
+) promptBudget {
+	b := promptBudget{
+		Window:         window,
+		ReservedOutput: reservedOutput,
+		Tools:          tc.calibrator.Adjust(model, tc.countTools(model, defs)),
+	}
+	if len(messages) > 0 && messages[0].Role == "system" {
+		b.System = tc.calibrator.Adjust(model, tc.countMessage(model, messages[0]))
+		b.History = tc.calibrator.Adjust(model, tc.countMessages(model, messages[1:]))
+	} else {
+		b.History = tc.calibrator.Adjust(model, tc.countMessages(model, messages))
+	}
+	return b
+}
+
+// trimToBudget drops the oldest whole turns from messages until the request
+// fits the budget. A turn starts at a user message, so assistant tool calls
+// and their tool results are always dropped together. The system prompt and
+// the current turn (from the last user message onwards) are never dropped.
+// Returns the trimmed messages and the number of messages removed.
+func (tc *tokenCounter) trimToBudget(
+	model string,
+	b promptBudget,
+	messages []providers.Message,
+) ([]providers.Message, int) {
+	overflow := b.Overflow()
+	if overflow == 0 || len(messages) < 3 {
+		return messages, 0
+	}
+
+	start := 0
+	if messages[0].Role == "system" {
+		start = 1
+	}
+	current := len(messages) - 1
+	for current > start && messages[current].Role != "user" {
+		current--
+	}
+
+	cut := start
+	freed := 0
+	for cut < current && freed < overflow {
+		// Advance to the start of the next turn, counting what we drop.
+		freed += tc.calibrator.Adjust(model, tc.countMessage(model, messages[cut]))
+		cut++
+		for cut < current && messages[cut].Role != "user" {
+			freed += tc.calibrator.Adjust(model, tc.countMessage(model, messages[cut]))
+			cut++
+		}
+	}
+	if cut == start {
+		return messages, 0
+	}
+
+	trimmed := make([]providers.Message, 0, len(messages)-(cut-start))
+	trimmed = append(trimmed, messages[:start]...)
+	trimmed = append(trimmed, messages[cut:]...)
+	return trimmed, cut - start
+}
+
+// resolveModelLimits returns the context metadata for a model name, which
+// may be a model_list alias or a bare model ID. A context_window set in
+// model_list takes precedence over the built-in table.
+func resolveModelLimits(cfg *config.Config, model string) tokenizer.ModelInfo {
+	id := model
+	configured := 0
+	if cfg != nil {
+		for i := range cfg.ModelList {
+			mc := &cfg.ModelList[i]
+			_, modelID := providers.ExtractProtocol(mc.Model)
+			if mc.ModelName == model || strings.EqualFold(modelID, model) {
+				id = mc.Model
+				configured = mc.ContextWindow
+				break
+			}
+		}
+	}
+
+	info, ok := tokenizer.LookupModel(id)
+	if !ok {
+		info = tokenizer.ModelInfo{ContextWindow: tokenizer.DefaultContextWindow}
+	}
+	if configured > 0 {
+		info.ContextWindow = configured
+	}
+	return info
+}
+
+// logBudget records the token breakdown of an over-budget request.
+func logBudget(agentID string, b promptBudget, dropped int) {
+	logger.WarnCF("agent", "Prompt exceeds context budget, trimmed oldest turns",
+		map[string]any{
+			"agent_id":        agentID,
+			"window":          b.Window,
+			"reserved_output": b.ReservedOutput,
+			"system_tokens":   b.System,
+			"tools_tokens":    b.Tools,
+			"history_tokens":  b.History,
+			"dropped_msgs":    dropped,
+		})
+}
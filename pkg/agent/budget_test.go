@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+	"github.com/tinyland-inc/tinyclaw/pkg/tokenizer"
+)
+
+func newTestTokenCounter(t *testing.T) *tokenCounter {
+	t.Helper()
+	return newTokenCounter(tokenizer.NewRegistry(t.TempDir()))
+}
+
+func TestTrimToBudget_DropsWholeTurns(t *testing.T) {
+	tc := newTestTokenCounter(t)
+	long := "lorem ipsum dolor sit amet consectetur adipiscing elit "
+	for range 4 {
+		long += long
+	}
+
+	messages := []providers.Message{
+		{Role: "system", Content: "you are a helpful assistant"},
+		{Role: "user", Content: long},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "read_file", Arguments: map[string]any{"path": "a"}}}},
+		{Role: "tool", ToolCallID: "c1", Content: long},
+		{Role: "assistant", Content: "done"},
+		{Role: "user", Content: "second question"},
+		{Role: "assistant", Content: "second answer"},
+		{Role: "user", Content: "current question"},
+	}
+
+	full := tc.measure("test-model", 100000, 0, messages, nil)
+	// Leave room for everything except the first turn.
+	b := full
+	b.Window = full.Used() - 10
+
+	trimmed, dropped := tc.trimToBudget("test-model", b, messages)
+	if dropped != 4 {
+		t.Fatalf("dropped = %d, want 4 (user, assistant tool call, tool result, assistant)", dropped)
+	}
+	if trimmed[0].Role != "system" {
+		t.Errorf("system prompt not kept: %+v", trimmed[0])
+	}
+	if trimmed[1].Content != "second question" {
+		t.Errorf("first kept message = %q, want second question", trimmed[1].Content)
+	}
+	for _, m := range trimmed {
+		if m.Role == "tool" {
+			t.Errorf("orphaned tool result kept: %+v", m)
+		}
+	}
+}
+
+func TestTrimToBudget_KeepsCurrentTurn(t *testing.T) {
+	tc := newTestTokenCounter(t)
+	messages := []providers.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "old"},
+		{Role: "assistant", Content: "old answer"},
+		{Role: "user", Content: "current"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "exec"}}},
+		{Role: "tool", ToolCallID: "c1", Content: "output"},
+	}
+
+	// Budget impossible to satisfy: only older turns may be dropped.
+	b := promptBudget{Window: 1, History: 1000}
+	trimmed, dropped := tc.trimToBudget("test-model", b, messages)
+	if dropped != 2 {
+		t.Fatalf("dropped = %d, want 2", dropped)
+	}
+	if len(trimmed) != 4 || trimmed[1].Content != "current" || trimmed[3].Role != "tool" {
+		t.Errorf("current turn not preserved: %+v", trimmed)
+	}
+}
+
+func TestTrimToBudget_FitsUnchanged(t *testing.T) {
+	tc := newTestTokenCounter(t)
+	messages := []providers.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "a"},
+		{Role: "assistant", Content: "b"},
+		{Role: "user", Content: "c"},
+	}
+	b := tc.measure("test-model", 100000, 1000, messages, nil)
+	trimmed, dropped := tc.trimToBudget("test-model", b, messages)
+	if dropped != 0 || len(trimmed) != len(messages) {
+		t.Errorf("expected no trimming, dropped %d", dropped)
+	}
+}
+
+func TestTokenCounter_Calibration(t *testing.T) {
+	tc := newTestTokenCounter(t)
+	messages := []providers.Message{{Role: "user", Content: "hello there, how are you today?"}}
+
+	before := tc.measure("m", 1000, 0, messages, nil).Used()
+	tc.observeUsage("m", before, &providers.UsageInfo{PromptTokens: before * 3 / 2})
+	after := tc.measure("m", 1000, 0, messages, nil).Used()
+
+	if after <= before {
+		t.Errorf("calibrated count %d should exceed uncalibrated %d", after, before)
+	}
+
+	// Missing usage must not change calibration.
+	tc.observeUsage("m", before, nil)
+	if again := tc.measure("m", 1000, 0, messages, nil).Used(); again != after {
+		t.Errorf("count changed without usage: %d != %d", again, after)
+	}
+}
+
+func TestResolveModelLimits(t *testing.T) {
+	cfg := &config.Config{
+		ModelList: []config.ModelConfig{
+			{ModelName: "smart", Model: "anthropic/claude-sonnet-4.6"},
+			{ModelName: "local", Model: "ollama/my-model", ContextWindow: 8192},
+		},
+	}
+
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"smart", 200000},
+		{"claude-sonnet-4.6", 200000},
+		{"local", 8192},
+		{"gpt-4o", 128000},
+		{"unknown-model", tokenizer.DefaultContextWindow},
+	}
+	for _, tt := range tests {
+		if got := resolveModelLimits(cfg, tt.model).ContextWindow; got != tt.want {
+			t.Errorf("resolveModelLimits(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
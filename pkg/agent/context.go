@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/memory"
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 	"github.com/tinyland-inc/tinyclaw/pkg/skills"
 )
@@ -22,6 +24,11 @@ type ContextBuilder struct {
 	skillsLoader *skills.SkillsLoader
 	memory       *MemoryStore
 
+	// memoryIndex, when set, replaces the MEMORY.md/daily-notes dump in the
+	// static prompt with per-message retrieval of the most relevant chunks.
+	memoryIndex *memory.Index
+	memoryTopK  int
+
 	// Cache for system prompt to avoid rebuilding on every call.
 	// This fixes issue #607: repeated reprocessing of the entire context.
 	// The cache auto-invalidates when workspace source files change (mtime check).
@@ -58,6 +65,33 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	}
 }
 
+// SetSkillsFilter limits the skills offered to the agent to those admitted
+// by filter (see skills.MatchSkillFilter).
+func (cb *ContextBuilder) SetSkillsFilter(filter []string) {
+	cb.systemPromptMutex.Lock()
+	defer cb.systemPromptMutex.Unlock()
+
+	cb.skillsLoader.SetFilter(filter)
+	cb.cachedSystemPrompt = ""
+}
+
+// SkillsLoader returns the loader of the skills offered to the agent.
+func (cb *ContextBuilder) SkillsLoader() *skills.SkillsLoader {
+	return cb.skillsLoader
+}
+
+// SetMemoryIndex enables retrieval-based memory: instead of including all of
+// long-term memory in the system prompt, the topK chunks most relevant to
+// each user message are added to the per-request context.
+func (cb *ContextBuilder) SetMemoryIndex(index *memory.Index, topK int) {
+	cb.systemPromptMutex.Lock()
+	defer cb.systemPromptMutex.Unlock()
+
+	cb.memoryIndex = index
+	cb.memoryTopK = topK
+	cb.cachedSystemPrompt = ""
+}
+
 func (cb *ContextBuilder) getIdentity() string {
 	workspacePath, _ := filepath.Abs(cb.workspace)
 
@@ -95,19 +129,22 @@ func (cb *ContextBuilder) BuildSystemPrompt() string {
 		parts = append(parts, bootstrapContent)
 	}
 
-	// Skills - show summary, AI can read full content with read_file tool
+	// Skills - show summary, the agent loads full instructions with load_skill
 	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
 	if skillsSummary != "" {
 		parts = append(parts, `# Skills
 
-The following skills extend your capabilities. To use a skill, read its SKILL.md file using the read_file tool.
+The following skills extend your capabilities. To use a skill, first load its instructions with the load_skill tool.
 
 `+skillsSummary)
 	}
 
-	// Memory context
-	memoryContext := cb.memory.GetMemoryContext()
-	if memoryContext != "" {
+	// Memory context: retrieved per message when an index is attached
+	if cb.memoryIndex != nil {
+		parts = append(parts, `# Memory
+
+Relevant excerpts from long-term memory, daily notes and past conversation summaries are included below for each message. Use memory_search to look up anything else you may have been told before, and memory_write to save new facts worth remembering.`)
+	} else if memoryContext := cb.memory.GetMemoryContext(); memoryContext != "" {
 		parts = append(parts, "# Memory\n\n"+memoryContext)
 	}
 
@@ -374,7 +411,44 @@ func (cb *ContextBuilder) buildDynamicContext(channel, chatID string) string {
 	return sb.String()
 }
 
+// memoryRetrievalTimeout bounds how long prompt building waits on the
+// memory index (which may call an embeddings endpoint).
+const memoryRetrievalTimeout = 10 * time.Second
+
+// retrieveMemory returns the memory chunks relevant to query formatted for
+// the system prompt, or "" when retrieval is disabled or nothing matches.
+func (cb *ContextBuilder) retrieveMemory(ctx context.Context, query string) string {
+	cb.systemPromptMutex.RLock()
+	index, topK := cb.memoryIndex, cb.memoryTopK
+	cb.systemPromptMutex.RUnlock()
+
+	if index == nil || strings.TrimSpace(query) == "" {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, memoryRetrievalTimeout)
+	defer cancel()
+
+	results, err := index.Search(ctx, query, topK)
+	if err != nil {
+		logger.WarnCF("agent", "Memory retrieval failed", map[string]any{"error": err.Error()})
+		return ""
+	}
+	if len(results) == 0 {
+		return ""
+	}
+
+	logger.DebugCF("agent", "Memory retrieved",
+		map[string]any{
+			"chunks": len(results),
+			"mode":   index.Mode(),
+		})
+
+	return "## Relevant Memory\n\n" + memory.FormatResults(results)
+}
+
 func (cb *ContextBuilder) BuildMessages(
+	ctx context.Context,
 	history []providers.Message,
 	summary string,
 	currentMessage string,
@@ -413,6 +487,12 @@ func (cb *ContextBuilder) BuildMessages(
 		{Type: "text", Text: dynamicCtx},
 	}
 
+	// Retrieved memory depends on the message, so it follows the cached block.
+	if memoryCtx := cb.retrieveMemory(ctx, currentMessage); memoryCtx != "" {
+		stringParts = append(stringParts, memoryCtx)
+		contentBlocks = append(contentBlocks, providers.ContentBlock{Type: "text", Text: memoryCtx})
+	}
+
 	if summary != "" {
 		summaryText := fmt.Sprintf(
 			"CONTEXT_SUMMARY: The following is an approximate summary of prior conversation "+
@@ -572,11 +652,13 @@ func (cb *ContextBuilder) GetSkillsInfo() map[string]any {
 	allSkills := cb.skillsLoader.ListSkills()
 	skillNames := make([]string, 0, len(allSkills))
 	for _, s := range allSkills {
-		skillNames = append(skillNames, s.Name)
+		if s.Unavailable == "" {
+			skillNames = append(skillNames, s.Name)
+		}
 	}
 	return map[string]any{
 		"total":     len(allSkills),
-		"available": len(allSkills),
+		"available": len(skillNames),
 		"names":     skillNames,
 	}
 }
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/tinyland-inc/tinyclaw/pkg/memory"
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 )
 
@@ -78,7 +80,7 @@ func TestSingleSystemMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msgs := cb.BuildMessages(tt.history, tt.summary, tt.message, nil, "test", "chat1")
+			msgs := cb.BuildMessages(context.Background(), tt.history, tt.summary, tt.message, nil, "test", "chat1")
 
 			systemCount := 0
 			for _, m := range msgs {
@@ -407,7 +409,7 @@ func TestConcurrentBuildSystemPromptWithCache(t *testing.T) {
 				}
 
 				// Also exercise BuildMessages concurrently
-				msgs := cb.BuildMessages(nil, "", "hello", nil, "test", "chat")
+				msgs := cb.BuildMessages(context.Background(), nil, "", "hello", nil, "test", "chat")
 				if len(msgs) < 2 {
 					errs <- "BuildMessages returned fewer than 2 messages"
 					return
@@ -475,6 +477,38 @@ func TestEmptyWorkspaceBaselineDetectsNewFiles(t *testing.T) {
 	}
 }
 
+// TestMemoryRetrieval verifies that with a memory index attached, the static
+// prompt no longer embeds MEMORY.md and only chunks relevant to the current
+// message are added to the per-request context.
+func TestMemoryRetrieval(t *testing.T) {
+	tmpDir := setupWorkspace(t, map[string]string{
+		"memory/MEMORY.md": "# Food\nUser is allergic to peanuts.\n\n# Travel\nUser visited Kyoto last spring.",
+	})
+
+	cb := NewContextBuilder(tmpDir)
+	cb.SetMemoryIndex(memory.NewIndex(tmpDir, nil, false), 1)
+
+	static := cb.BuildSystemPromptWithCache()
+	if strings.Contains(static, "peanuts") || strings.Contains(static, "Kyoto") {
+		t.Error("static prompt should not include raw memory when retrieval is enabled")
+	}
+	if !strings.Contains(static, "memory_search") {
+		t.Error("static prompt should mention memory_search")
+	}
+
+	msgs := cb.BuildMessages(context.Background(), nil, "", "any peanut allergies I should know about?", nil, "cli", "direct")
+	sys := msgs[0].Content
+	if !strings.Contains(sys, "allergic to peanuts") {
+		t.Error("relevant memory chunk missing from system message")
+	}
+	if strings.Contains(sys, "Kyoto") {
+		t.Error("irrelevant memory chunk included")
+	}
+	if len(msgs[0].SystemParts) != 3 || msgs[0].SystemParts[2].CacheControl != nil {
+		t.Errorf("retrieved memory should be an uncached block after the dynamic context: %+v", msgs[0].SystemParts)
+	}
+}
+
 // BenchmarkBuildMessagesWithCache measures caching performance.
 func BenchmarkBuildMessagesWithCache(b *testing.B) {
 	tmpDir := b.TempDir()
@@ -493,6 +527,6 @@ func BenchmarkBuildMessagesWithCache(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = cb.BuildMessages(history, "summary", "new message", nil, "cli", "test")
+		_ = cb.BuildMessages(context.Background(), history, "summary", "new message", nil, "cli", "test")
 	}
 }
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// ErrDraining is returned for requests that arrive after Drain has begun.
+var ErrDraining = errors.New("agent loop is draining for shutdown")
+
+// ActiveChat is a channel chat the agent has answered recently.
+type ActiveChat struct {
+	Channel    string
+	ChatID     string
+	LastActive time.Time
+}
+
+// beginRun registers an agent run so Drain can wait for it, or reports
+// false once draining has begun.
+func (al *AgentLoop) beginRun() bool {
+	al.drainMu.Lock()
+	defer al.drainMu.Unlock()
+	if al.draining {
+		return false
+	}
+	al.inflight++
+	return true
+}
+
+func (al *AgentLoop) endRun() {
+	al.drainMu.Lock()
+	defer al.drainMu.Unlock()
+	al.inflight--
+	if al.inflight == 0 && al.idle != nil {
+		close(al.idle)
+		al.idle = nil
+	}
+}
+
+// isDraining reports whether Drain has begun.
+func (al *AgentLoop) isDraining() bool {
+	al.drainMu.Lock()
+	defer al.drainMu.Unlock()
+	return al.draining
+}
+
+// recordActiveChat notes that the agent is talking in channel/chatID.
+func (al *AgentLoop) recordActiveChat(channel, chatID string) {
+	al.drainMu.Lock()
+	defer al.drainMu.Unlock()
+	if al.activeChats == nil {
+		al.activeChats = make(map[ActiveChat]time.Time)
+	}
+	al.activeChats[ActiveChat{Channel: channel, ChatID: chatID}] = time.Now()
+}
+
+// ActiveChats returns the channel chats with agent activity within the
+// given window, most recent first.
+func (al *AgentLoop) ActiveChats(within time.Duration) []ActiveChat {
+	al.drainMu.Lock()
+	defer al.drainMu.Unlock()
+	var chats []ActiveChat
+	for chat, last := range al.activeChats {
+		if time.Since(last) <= within {
+			chat.LastActive = last
+			chats = append(chats, chat)
+		}
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i].LastActive.After(chats[j].LastActive) })
+	return chats
+}
+
+// Drain stops the loop from starting new agent runs, waits for the runs in
+// flight to finish or for ctx to end, and then saves every agent's
+// sessions. It returns ctx's error if runs were still going when it ended.
+func (al *AgentLoop) Drain(ctx context.Context) error {
+	al.drainMu.Lock()
+	al.draining = true
+	inflight := al.inflight
+	var idle chan struct{}
+	if inflight > 0 {
+		if al.idle == nil {
+			al.idle = make(chan struct{})
+		}
+		idle = al.idle
+	}
+	al.drainMu.Unlock()
+
+	var err error
+	if idle != nil {
+		logger.InfoCF("agent", "Waiting for in-flight agent runs", map[string]any{"runs": inflight})
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			err = ctx.Err()
+			logger.WarnCF("agent", "Drain deadline reached with agent runs in flight", nil)
+		}
+	}
+
+	for _, id := range al.registry.ListAgentIDs() {
+		if agent, ok := al.registry.GetAgent(id); ok {
+			if saveErr := agent.Sessions.SaveAll(); saveErr != nil {
+				logger.WarnCF("agent", "Failed to save sessions", map[string]any{
+					"agent_id": id,
+					"error":    saveErr.Error(),
+				})
+			}
+		}
+	}
+	return err
+}
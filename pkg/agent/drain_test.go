@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+)
+
+// blockingProvider answers once release is closed, after signalling entered.
+type blockingProvider struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	p.entered <- struct{}{}
+	<-p.release
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (p *blockingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_Drain(t *testing.T) {
+	cfg := testCfg(nil)
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	provider := &blockingProvider{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	defer al.Stop()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := al.ProcessDirectWithChannel(context.Background(), "hello", "telegram:42", "telegram", "42")
+		done <- err
+	}()
+	select {
+	case <-provider.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent run never reached the provider")
+	}
+
+	chats := al.ActiveChats(time.Minute)
+	if len(chats) != 1 || chats[0].Channel != "telegram" || chats[0].ChatID != "42" {
+		t.Errorf("ActiveChats = %+v", chats)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := al.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain with a run in flight = %v, want deadline exceeded", err)
+	}
+	if _, err := al.ProcessDirect(context.Background(), "late", "cli:late"); !errors.Is(err, ErrDraining) {
+		t.Errorf("run started while draining: %v", err)
+	}
+
+	close(provider.release)
+	if err := al.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain after the run finished: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("in-flight run failed: %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(cfg.Agents.Defaults.Workspace, "sessions", "*.json")); len(files) == 0 {
+		t.Error("session not saved")
+	}
+}
+
+func TestAgentLoop_ActiveChatsSkipsInternal(t *testing.T) {
+	cfg := testCfg(nil)
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	defer al.Stop()
+
+	if _, err := al.ProcessDirect(context.Background(), "hi", "cli:direct"); err != nil {
+		t.Fatal(err)
+	}
+	if chats := al.ActiveChats(time.Minute); len(chats) != 0 {
+		t.Errorf("internal chat reported active: %+v", chats)
+	}
+	if err := al.Drain(context.Background()); err != nil {
+		t.Errorf("Drain with nothing in flight: %v", err)
+	}
+}
@@ -6,31 +6,42 @@ import (
 	"strings"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/memory"
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 	"github.com/tinyland-inc/tinyclaw/pkg/routing"
 	"github.com/tinyland-inc/tinyclaw/pkg/session"
+	"github.com/tinyland-inc/tinyclaw/pkg/tokenizer"
 	"github.com/tinyland-inc/tinyclaw/pkg/tools"
 )
 
 // AgentInstance represents a fully configured agent with its own workspace,
 // session manager, context builder, and tool registry.
 type AgentInstance struct {
-	ID             string
-	Name           string
-	Model          string
-	Fallbacks      []string
-	Workspace      string
-	MaxIterations  int
-	MaxTokens      int
-	Temperature    float64
-	ContextWindow  int
-	Provider       providers.LLMProvider
-	Sessions       *session.SessionManager
-	ContextBuilder *ContextBuilder
-	Tools          *tools.ToolRegistry
-	Subagents      *config.SubagentsConfig
-	SkillsFilter   []string
-	Candidates     []providers.FallbackCandidate
+	ID            string
+	Name          string
+	Model         string
+	Fallbacks     []string
+	Workspace     string
+	MaxIterations int
+	MaxTokens     int
+	Temperature   float64
+	ModelLimits   map[string]tokenizer.ModelInfo
+	Provider      providers.LLMProvider
+	// SummaryProvider and SummaryModel summarize session history; they default
+	// to Provider and Model unless a cheaper summary_model is configured.
+	SummaryProvider providers.LLMProvider
+	SummaryModel    string
+	Sessions        *session.SessionManager
+	ContextBuilder  *ContextBuilder
+	Memory          *memory.Index // nil when memory retrieval is disabled
+	Tools           *tools.ToolRegistry
+	Processes       *tools.ProcessManager
+	Journal         *journal.Journal // nil when the change journal is disabled
+	Subagents       *config.SubagentsConfig
+	SkillsFilter    []string
+	Candidates      []providers.FallbackCandidate
 }
 
 // NewAgentInstance creates an agent instance from config.
@@ -51,14 +62,56 @@ func NewAgentInstance(
 	toolsRegistry.Register(tools.NewReadFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewWriteFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewListDirTool(workspace, restrict))
-	toolsRegistry.Register(tools.NewExecToolWithConfig(workspace, restrict, cfg))
+	execTool := tools.NewExecToolWithConfig(workspace, restrict, cfg)
+	if cfg != nil && agentCfg != nil && agentCfg.Sandbox != nil && agentCfg.Sandbox.Network != nil {
+		sandbox := cfg.Tools.Exec.Sandbox
+		sandbox.Network = *agentCfg.Sandbox.Network
+		execTool.SetSandbox(sandbox)
+	}
+	var backgroundCfg config.ExecBackgroundConfig
+	if cfg != nil {
+		backgroundCfg = cfg.Tools.Exec.Background
+	}
+	processes := tools.NewProcessManager(backgroundCfg)
+	execTool.SetProcessManager(processes)
+	toolsRegistry.Register(execTool)
+	toolsRegistry.Register(tools.NewProcessTool(processes))
 	toolsRegistry.Register(tools.NewEditFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewAppendFileTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewApplyPatchTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewMoveFileTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewDeleteFileTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewGlobTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewGrepTool(workspace, restrict))
+
+	var workspaceJournal *journal.Journal
+	if cfg != nil && cfg.Tools.Journal.Enabled {
+		workspaceJournal = journal.New(workspace, journal.ConfigOptions(cfg.Tools.Journal))
+		for _, name := range toolsRegistry.List() {
+			tool, _ := toolsRegistry.Get(name)
+			if jt, ok := tool.(tools.JournaledTool); ok {
+				jt.SetJournal(workspaceJournal)
+			}
+		}
+	}
 
 	sessionsDir := filepath.Join(workspace, "sessions")
 	sessionsManager := session.NewSessionManager(sessionsDir)
 
 	contextBuilder := NewContextBuilder(workspace)
+	if agentCfg != nil {
+		contextBuilder.SetSkillsFilter(agentCfg.Skills)
+	}
+	toolsRegistry.Register(tools.NewLoadSkillTool(contextBuilder.SkillsLoader()))
+
+	var memoryIndex *memory.Index
+	if defaults.Memory.Retrieval {
+		memoryIndex = memory.NewIndex(workspace, resolveMemoryEmbedder(cfg, defaults.Memory.EmbeddingModel),
+			defaults.Memory.IndexSessions)
+		contextBuilder.SetMemoryIndex(memoryIndex, defaults.Memory.TopK)
+		toolsRegistry.Register(tools.NewMemorySearchTool(memoryIndex))
+		toolsRegistry.Register(tools.NewMemoryWriteTool(memoryIndex))
+	}
 
 	agentID := routing.DefaultAgentID
 	agentName := ""
@@ -94,24 +147,115 @@ func NewAgentInstance(
 	}
 	candidates := providers.ResolveCandidates(modelCfg, defaults.Provider)
 
+	// Resolve context metadata for the primary model and every candidate
+	modelLimits := map[string]tokenizer.ModelInfo{model: resolveModelLimits(cfg, model)}
+	for _, c := range candidates {
+		if _, ok := modelLimits[c.Model]; !ok {
+			modelLimits[c.Model] = resolveModelLimits(cfg, c.Model)
+		}
+	}
+
+	summaryName := defaults.SummaryModel
+	if agentCfg != nil && agentCfg.SummaryModel != "" {
+		summaryName = agentCfg.SummaryModel
+	}
+	summaryProvider, summaryModel := resolveSummaryModel(cfg, summaryName, provider, model)
+	if _, ok := modelLimits[summaryModel]; !ok {
+		modelLimits[summaryModel] = resolveModelLimits(cfg, summaryModel)
+	}
+
 	return &AgentInstance{
-		ID:             agentID,
-		Name:           agentName,
-		Model:          model,
-		Fallbacks:      fallbacks,
-		Workspace:      workspace,
-		MaxIterations:  maxIter,
-		MaxTokens:      maxTokens,
-		Temperature:    temperature,
-		ContextWindow:  maxTokens,
-		Provider:       provider,
-		Sessions:       sessionsManager,
-		ContextBuilder: contextBuilder,
-		Tools:          toolsRegistry,
-		Subagents:      subagents,
-		SkillsFilter:   skillsFilter,
-		Candidates:     candidates,
+		ID:              agentID,
+		Name:            agentName,
+		Model:           model,
+		Fallbacks:       fallbacks,
+		Workspace:       workspace,
+		MaxIterations:   maxIter,
+		MaxTokens:       maxTokens,
+		Temperature:     temperature,
+		ModelLimits:     modelLimits,
+		Provider:        provider,
+		SummaryProvider: summaryProvider,
+		SummaryModel:    summaryModel,
+		Sessions:        sessionsManager,
+		ContextBuilder:  contextBuilder,
+		Memory:          memoryIndex,
+		Tools:           toolsRegistry,
+		Processes:       processes,
+		Journal:         workspaceJournal,
+		Subagents:       subagents,
+		SkillsFilter:    skillsFilter,
+		Candidates:      candidates,
+	}
+}
+
+// ContextWindow returns the smallest context window among the agent's
+// current model and its fallback candidates, so a prompt budgeted for the
+// primary also fits whichever candidate ends up serving it.
+func (a *AgentInstance) ContextWindow() int {
+	window := a.limitsFor(a.Model).ContextWindow
+	for _, c := range a.Candidates {
+		window = min(window, a.limitsFor(c.Model).ContextWindow)
+	}
+	return window
+}
+
+// limitsFor returns the context metadata for model, falling back to the
+// built-in table for models switched to at runtime.
+func (a *AgentInstance) limitsFor(model string) tokenizer.ModelInfo {
+	if info, ok := a.ModelLimits[model]; ok {
+		return info
+	}
+	return resolveModelLimits(nil, model)
+}
+
+// resolveSummaryModel returns the provider and model ID for history
+// summarization. An empty name, or one that can't be resolved from
+// model_list, falls back to the agent's own provider and model.
+func resolveSummaryModel(
+	cfg *config.Config,
+	name string,
+	provider providers.LLMProvider,
+	model string,
+) (providers.LLMProvider, string) {
+	if cfg == nil || name == "" {
+		return provider, model
+	}
+	modelCfg, err := cfg.GetModelConfig(name)
+	if err != nil {
+		logger.WarnCF("agent", "Summary model not found, summarizing with the agent model",
+			map[string]any{"summary_model": name, "error": err.Error()})
+		return provider, model
+	}
+	summaryProvider, modelID, err := providers.CreateProviderFromConfig(modelCfg)
+	if err != nil {
+		logger.WarnCF("agent", "Summary model unavailable, summarizing with the agent model",
+			map[string]any{"summary_model": name, "error": err.Error()})
+		return provider, model
+	}
+	return summaryProvider, modelID
+}
+
+// resolveMemoryEmbedder builds the embedder for memory retrieval from a
+// model_list alias. Returns nil (BM25 retrieval) when no model is configured
+// or it cannot produce embeddings.
+func resolveMemoryEmbedder(cfg *config.Config, modelName string) memory.Embedder {
+	if cfg == nil || modelName == "" {
+		return nil
+	}
+	modelCfg, err := cfg.GetModelConfig(modelName)
+	if err != nil {
+		logger.WarnCF("agent", "Memory embedding model not found, using BM25 retrieval",
+			map[string]any{"embedding_model": modelName, "error": err.Error()})
+		return nil
+	}
+	provider, modelID, err := providers.CreateEmbeddingProviderFromConfig(modelCfg)
+	if err != nil {
+		logger.WarnCF("agent", "Memory embedding model unavailable, using BM25 retrieval",
+			map[string]any{"embedding_model": modelName, "error": err.Error()})
+		return nil
 	}
+	return memory.NewProviderEmbedder(provider, modelID)
 }
 
 // resolveAgentWorkspace determines the workspace directory for an agent.
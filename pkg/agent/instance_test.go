@@ -1,6 +1,10 @@
 package agent
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
@@ -80,3 +84,36 @@ func TestNewAgentInstance_DefaultsTemperatureWhenUnset(t *testing.T) {
 		t.Fatalf("Temperature = %f, want %f", agent.Temperature, 0.7)
 	}
 }
+
+func TestNewAgentInstance_SkillsFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"github", "weather"} {
+		dir := filepath.Join(tmpDir, "skills", name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		content := "---\nname: " + name + "\ndescription: The " + name + " skill\n---\n\n# " + name
+		if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Workspace: tmpDir, Model: "test-model"},
+		},
+	}
+	agentCfg := &config.AgentConfig{ID: "reporter", Workspace: tmpDir, Skills: []string{"*", "!github"}}
+	agent := NewAgentInstance(agentCfg, &cfg.Agents.Defaults, cfg, &mockProvider{})
+
+	prompt := agent.ContextBuilder.BuildSystemPrompt()
+	if !strings.Contains(prompt, "<name>weather</name>") || strings.Contains(prompt, "<name>github</name>") {
+		t.Errorf("skills in prompt do not honor the filter:\n%s", prompt)
+	}
+	if _, ok := agent.Tools.Get("load_skill"); !ok {
+		t.Fatal("load_skill tool not registered")
+	}
+	if result := agent.Tools.Execute(context.Background(), "load_skill", map[string]any{"name": "github"}); !result.IsError {
+		t.Errorf("filtered skill loaded: %s", result.ForLLM)
+	}
+}
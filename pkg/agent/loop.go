@@ -9,22 +9,29 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-	"unicode/utf8"
+
+	"github.com/google/uuid"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/bus"
 	"github.com/tinyland-inc/tinyclaw/pkg/channels"
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
 	"github.com/tinyland-inc/tinyclaw/pkg/constants"
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
 	"github.com/tinyland-inc/tinyclaw/pkg/logger"
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 	"github.com/tinyland-inc/tinyclaw/pkg/routing"
+	"github.com/tinyland-inc/tinyclaw/pkg/session"
 	"github.com/tinyland-inc/tinyclaw/pkg/skills"
 	"github.com/tinyland-inc/tinyclaw/pkg/state"
+	"github.com/tinyland-inc/tinyclaw/pkg/tokenizer"
 	"github.com/tinyland-inc/tinyclaw/pkg/tools"
 	"github.com/tinyland-inc/tinyclaw/pkg/utils"
 )
@@ -37,7 +44,22 @@ type AgentLoop struct {
 	running        atomic.Bool
 	summarizing    sync.Map
 	fallback       *providers.FallbackChain
+	cooldown       *providers.CooldownTracker
 	channelManager *channels.Manager
+	tokens         *tokenCounter
+
+	// extraTools were added with RegisterTool and are carried over to the
+	// agents rebuilt by Reload.
+	extraTools []tools.Tool
+	reloadMu   sync.Mutex
+
+	// Drain state: runs in flight, closed idle when the last one ends, and
+	// the chats recently active for shutdown notices.
+	drainMu     sync.Mutex
+	draining    bool
+	inflight    int
+	idle        chan struct{}
+	activeChats map[ActiveChat]time.Time
 }
 
 // processOptions configures how a message is processed
@@ -50,6 +72,9 @@ type processOptions struct {
 	EnableSummary   bool   // Whether to trigger summarization
 	SendResponse    bool   // Whether to send response via bus
 	NoHistory       bool   // If true, don't load session history (for heartbeat)
+
+	Usage   *providers.UsageInfo // If set, accumulates provider-reported usage
+	OnEvent func(bus.AgentEvent) // If set, receives progress events for this request
 }
 
 func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers.LLMProvider) *AgentLoop {
@@ -76,6 +101,8 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		state:       stateManager,
 		summarizing: sync.Map{},
 		fallback:    fallbackChain,
+		cooldown:    cooldown,
+		tokens:      newTokenCounter(tokenizer.NewRegistry(tokenizer.DefaultDir())),
 	}
 }
 
@@ -110,23 +137,32 @@ func registerSharedTools(
 		}); searchTool != nil {
 			agent.Tools.Register(searchTool)
 		}
-		agent.Tools.Register(tools.NewWebFetchToolWithProxy(50000, cfg.Tools.Web.Proxy))
-
-		// Hardware tools (I2C, SPI) - Linux only, returns error on other platforms
-		agent.Tools.Register(tools.NewI2CTool())
-		agent.Tools.Register(tools.NewSPITool())
+		agent.Tools.Register(tools.NewWebFetchToolWithOptions(tools.WebFetchToolOptions{
+			MaxChars:             cfg.Tools.Web.Fetch.MaxChars,
+			MaxBytes:             cfg.Tools.Web.Fetch.MaxBytes,
+			Proxy:                cfg.Tools.Web.Proxy,
+			AllowPrivateNetworks: cfg.Tools.Web.Fetch.AllowPrivateNetworks,
+			CacheDir:             filepath.Join(agent.Workspace, "cache", "web"),
+			CacheTTL:             time.Duration(cfg.Tools.Web.Fetch.CacheTTLMinutes) * time.Minute,
+		}))
+
+		// Hardware tools (I2C, SPI, GPIO) - Linux only, returns error on other platforms
+		agent.Tools.Register(tools.NewI2CToolWithOptions(tools.I2CToolOptions{
+			Devices: cfg.Tools.Hardware.Devices,
+		}))
+		agent.Tools.Register(tools.NewSPIToolWithOptions(tools.SPIToolOptions{
+			Devices: cfg.Tools.Hardware.Devices,
+		}))
+		agent.Tools.Register(tools.NewGPIOTool())
 
 		// Message tool
 		messageTool := tools.NewMessageTool()
-		messageTool.SetSendCallback(func(channel, chatID, content string) error {
-			msgBus.PublishOutbound(bus.OutboundMessage{
-				Channel: channel,
-				ChatID:  chatID,
-				Content: content,
-			})
+		messageTool.SetOutboundCallback(func(msg bus.OutboundMessage) error {
+			msgBus.PublishOutbound(msg)
 			return nil
 		})
 		agent.Tools.Register(messageTool)
+		agent.Tools.Register(tools.NewSendFileTool(messageTool, agent.Workspace, cfg.Agents.Defaults.RestrictToWorkspace))
 
 		// Skill discovery and installation tools
 		registryMgr := skills.NewRegistryManagerFromConfig(skills.RegistryConfig{
@@ -167,6 +203,13 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 			}
 
 			response, err := al.processMessage(ctx, msg)
+			if errors.Is(err, ErrDraining) {
+				logger.WarnCF("agent", "Dropped inbound message while draining", map[string]any{
+					"channel": msg.Channel,
+					"chat_id": msg.ChatID,
+				})
+				continue
+			}
 			if err != nil {
 				response = fmt.Sprintf("Error processing message: %v", err)
 			}
@@ -201,9 +244,17 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
+	for _, agentID := range al.registry.ListAgentIDs() {
+		if agent, ok := al.registry.GetAgent(agentID); ok && agent.Processes != nil {
+			agent.Processes.Close()
+		}
+	}
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
+	al.reloadMu.Lock()
+	defer al.reloadMu.Unlock()
+	al.extraTools = append(al.extraTools, tool)
 	for _, agentID := range al.registry.ListAgentIDs() {
 		if agent, ok := al.registry.GetAgent(agentID); ok {
 			agent.Tools.Register(tool)
@@ -240,6 +291,16 @@ func (al *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey stri
 func (al *AgentLoop) ProcessDirectWithChannel(
 	ctx context.Context,
 	content, sessionKey, channel, chatID string,
+) (string, error) {
+	return al.ProcessDirectWithEvents(ctx, content, sessionKey, channel, chatID, nil)
+}
+
+// ProcessDirectWithEvents is ProcessDirectWithChannel that also reports
+// the run's tool calls and interim text to onEvent as they happen.
+func (al *AgentLoop) ProcessDirectWithEvents(
+	ctx context.Context,
+	content, sessionKey, channel, chatID string,
+	onEvent func(bus.AgentEvent),
 ) (string, error) {
 	msg := bus.InboundMessage{
 		Channel:    channel,
@@ -249,7 +310,85 @@ func (al *AgentLoop) ProcessDirectWithChannel(
 		SessionKey: sessionKey,
 	}
 
-	return al.processMessage(ctx, msg)
+	return al.processMessageWithEvents(ctx, msg, onEvent)
+}
+
+// ListAgentIDs returns the IDs of all configured agents, sorted.
+func (al *AgentLoop) ListAgentIDs() []string {
+	ids := al.registry.ListAgentIDs()
+	sort.Strings(ids)
+	return ids
+}
+
+// ProcessChat runs a conversation supplied by an API caller through an
+// agent (the default one when agentID is empty). The last message must be
+// from the user; earlier user and assistant messages seed the session when
+// it has no history yet, and system messages are passed along as
+// instructions since the agent keeps its own system prompt. An empty
+// sessionKey runs the request without keeping history. The returned usage
+// sums every LLM call made for the request.
+func (al *AgentLoop) ProcessChat(
+	ctx context.Context,
+	agentID, sessionKey string,
+	messages []providers.Message,
+	onEvent func(bus.AgentEvent),
+) (string, providers.UsageInfo, error) {
+	var usage providers.UsageInfo
+
+	agent := al.registry.GetDefaultAgent()
+	if agentID != "" {
+		var ok bool
+		if agent, ok = al.registry.GetAgent(agentID); !ok {
+			return "", usage, fmt.Errorf("unknown agent %q", agentID)
+		}
+	}
+	if agent == nil {
+		return "", usage, errors.New("no agent configured")
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role != "user" {
+		return "", usage, errors.New("the last message must be from the user")
+	}
+
+	var instructions []string
+	seed := make([]providers.Message, 0, len(messages)-1)
+	for _, m := range messages[:len(messages)-1] {
+		switch m.Role {
+		case "system", "developer":
+			instructions = append(instructions, m.Content)
+		case "user", "assistant":
+			seed = append(seed, providers.Message{Role: m.Role, Content: m.Content})
+		}
+	}
+	userMessage := messages[len(messages)-1].Content
+	if len(instructions) > 0 {
+		userMessage = "Instructions:\n" + strings.Join(instructions, "\n\n") + "\n\n" + userMessage
+	}
+
+	ephemeral := sessionKey == ""
+	if ephemeral {
+		sessionKey = "api:ephemeral:" + uuid.NewString()
+		defer agent.Sessions.Delete(sessionKey)
+		if agent.Processes != nil {
+			defer agent.Processes.KillSession(sessionKey)
+		}
+	}
+	if len(seed) > 0 && len(agent.Sessions.GetHistory(sessionKey)) == 0 {
+		agent.Sessions.GetOrCreate(sessionKey)
+		agent.Sessions.SetHistory(sessionKey, seed)
+	}
+
+	content, err := al.runAgentLoop(ctx, agent, processOptions{
+		SessionKey:      sessionKey,
+		Channel:         "api",
+		ChatID:          sessionKey,
+		UserMessage:     userMessage,
+		DefaultResponse: "I've completed processing but have no response to give.",
+		EnableSummary:   !ephemeral,
+		SendResponse:    false,
+		Usage:           &usage,
+		OnEvent:         onEvent,
+	})
+	return content, usage, err
 }
 
 // ProcessHeartbeat processes a heartbeat request without session history.
@@ -269,6 +408,14 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 }
 
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
+	return al.processMessageWithEvents(ctx, msg, nil)
+}
+
+func (al *AgentLoop) processMessageWithEvents(
+	ctx context.Context,
+	msg bus.InboundMessage,
+	onEvent func(bus.AgentEvent),
+) (string, error) {
 	// Add message preview to log (show full content for error messages)
 	var logContent string
 	if strings.Contains(msg.Content, "Error:") || strings.Contains(msg.Content, "error") {
@@ -294,7 +441,40 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		return response, nil
 	}
 
-	// Route to determine agent and session key
+	agent, sessionKey := al.resolveSession(msg)
+
+	return al.runAgentLoop(ctx, agent, processOptions{
+		SessionKey:      sessionKey,
+		Channel:         msg.Channel,
+		ChatID:          msg.ChatID,
+		UserMessage:     msg.Content,
+		DefaultResponse: "I've completed processing but have no response to give.",
+		EnableSummary:   true,
+		SendResponse:    false,
+		OnEvent:         onEvent,
+	})
+}
+
+// DefaultAgentID returns the ID of the agent used when none is named.
+func (al *AgentLoop) DefaultAgentID() string {
+	if agent := al.registry.GetDefaultAgent(); agent != nil {
+		return agent.ID
+	}
+	return ""
+}
+
+// DispatchAgentID reports the ID of the agent that ProcessDirectWithChannel
+// routes requests on channel to.
+func (al *AgentLoop) DispatchAgentID(channel string) string {
+	agent, _ := al.routeMessage(bus.InboundMessage{Channel: channel})
+	if agent == nil {
+		return ""
+	}
+	return agent.ID
+}
+
+// routeMessage resolves the route binding for an inbound message.
+func (al *AgentLoop) routeMessage(msg bus.InboundMessage) (*AgentInstance, routing.ResolvedRoute) {
 	route := al.registry.ResolveRoute(routing.RouteInput{
 		Channel:    msg.Channel,
 		AccountID:  msg.Metadata["account_id"],
@@ -308,10 +488,17 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	if !ok {
 		agent = al.registry.GetDefaultAgent()
 	}
+	return agent, route
+}
+
+// resolveSession routes an inbound message to its agent and session key.
+func (al *AgentLoop) resolveSession(msg bus.InboundMessage) (*AgentInstance, string) {
+	agent, route := al.routeMessage(msg)
 
-	// Use routed session key, but honor pre-set agent-scoped keys (for ProcessDirect/cron)
+	// Use routed session key, but honor pre-set agent-scoped keys (for
+	// ProcessDirect/cron) and API keys (for /api/dispatch)
 	sessionKey := route.SessionKey
-	if msg.SessionKey != "" && strings.HasPrefix(msg.SessionKey, "agent:") {
+	if strings.HasPrefix(msg.SessionKey, "agent:") || strings.HasPrefix(msg.SessionKey, "api:") {
 		sessionKey = msg.SessionKey
 	}
 
@@ -322,15 +509,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 			"matched_by":  route.MatchedBy,
 		})
 
-	return al.runAgentLoop(ctx, agent, processOptions{
-		SessionKey:      sessionKey,
-		Channel:         msg.Channel,
-		ChatID:          msg.ChatID,
-		UserMessage:     msg.Content,
-		DefaultResponse: "I've completed processing but have no response to give.",
-		EnableSummary:   true,
-		SendResponse:    false,
-	})
+	return agent, sessionKey
 }
 
 func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
@@ -391,10 +570,16 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 
 // runAgentLoop is the core message processing logic.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opts processOptions) (string, error) {
+	if !al.beginRun() {
+		return "", ErrDraining
+	}
+	defer al.endRun()
+
 	// 0. Record last channel for heartbeat notifications (skip internal channels)
 	if opts.Channel != "" && opts.ChatID != "" {
 		// Don't record internal channels (cli, system, subagent)
 		if !constants.IsInternalChannel(opts.Channel) {
+			al.recordActiveChat(opts.Channel, opts.ChatID)
 			channelKey := fmt.Sprintf("%s:%s", opts.Channel, opts.ChatID)
 			if err := al.RecordLastChannel(channelKey); err != nil {
 				logger.WarnCF("agent", "Failed to record last channel", map[string]any{"error": err.Error()})
@@ -402,6 +587,11 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		}
 	}
 
+	// The session's background processes count as idle only between turns.
+	if agent.Processes != nil {
+		defer agent.Processes.Hold(opts.SessionKey)()
+	}
+
 	// 1. Update tool contexts
 	al.updateToolContexts(agent, opts.Channel, opts.ChatID)
 
@@ -413,6 +603,7 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		summary = agent.Sessions.GetSummary(opts.SessionKey)
 	}
 	messages := agent.ContextBuilder.BuildMessages(
+		ctx,
 		history,
 		summary,
 		opts.UserMessage,
@@ -485,6 +676,9 @@ func (al *AgentLoop) runLLMIteration(
 ) (string, int, error) {
 	iteration := 0
 	var finalContent string
+	// File changes made by this turn's tool calls are journaled together,
+	// so /undo turn can roll them all back.
+	turn := uuid.NewString()
 
 	for iteration < agent.MaxIterations {
 		iteration++
@@ -499,6 +693,15 @@ func (al *AgentLoop) runLLMIteration(
 		// Build tool definitions
 		providerToolDefs := agent.Tools.ToProviderDefs()
 
+		// Budget system prompt, tool schemas, history and reserved output
+		// against the context window; drop the oldest turns if needed.
+		budget := al.tokens.measure(agent.Model, agent.ContextWindow(), agent.MaxTokens, messages, providerToolDefs)
+		if budget.Overflow() > 0 {
+			var dropped int
+			messages, dropped = al.tokens.trimToBudget(agent.Model, budget, messages)
+			logBudget(agent.ID, budget, dropped)
+		}
+
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
 			map[string]any{
@@ -524,15 +727,43 @@ func (al *AgentLoop) runLLMIteration(
 		var response *providers.LLMResponse
 		var err error
 
+		// The model that served the request and the local prompt count for
+		// it, used to calibrate counts against provider-reported usage.
+		var usedModel string
+		var promptEstimate int
+		estimateFor := func(model string) {
+			usedModel = model
+			promptEstimate = al.tokens.countMessages(model, messages) + al.tokens.countTools(model, providerToolDefs)
+		}
+
+		// Stream reply text when the provider supports it and the caller or
+		// the channel shows progress; otherwise a plain Chat call is cheaper.
+		streamer, canStream := agent.Provider.(providers.StreamingProvider)
+		canStream = canStream && (opts.OnEvent != nil ||
+			!constants.IsInternalChannel(opts.Channel) && al.bus.HasEventSubscriber(opts.Channel))
+		chat := func(ctx context.Context, model string) (*providers.LLMResponse, error) {
+			options := map[string]any{
+				"max_tokens":       agent.MaxTokens,
+				"temperature":      agent.Temperature,
+				"prompt_cache_key": agent.ID,
+			}
+			if canStream {
+				return streamer.ChatStream(ctx, messages, providerToolDefs, model, options, func(delta string) {
+					al.publishEvent(opts, bus.AgentEvent{Kind: bus.EventDelta, Content: delta})
+				})
+			}
+			return agent.Provider.Chat(ctx, messages, providerToolDefs, model, options)
+		}
+
 		callLLM := func() (*providers.LLMResponse, error) {
 			if len(agent.Candidates) > 1 && al.fallback != nil {
 				fbResult, fbErr := al.fallback.Execute(ctx, agent.Candidates,
 					func(ctx context.Context, provider, model string) (*providers.LLMResponse, error) {
-						return agent.Provider.Chat(ctx, messages, providerToolDefs, model, map[string]any{
-							"max_tokens":       agent.MaxTokens,
-							"temperature":      agent.Temperature,
-							"prompt_cache_key": agent.ID,
-						})
+						estimateFor(model)
+						start := time.Now()
+						resp, err := chat(ctx, model)
+						observeLLMCall(provider, model, time.Since(start), resp, err)
+						return resp, err
 					},
 				)
 				if fbErr != nil {
@@ -545,11 +776,11 @@ func (al *AgentLoop) runLLMIteration(
 				}
 				return fbResult.Response, nil
 			}
-			return agent.Provider.Chat(ctx, messages, providerToolDefs, agent.Model, map[string]any{
-				"max_tokens":       agent.MaxTokens,
-				"temperature":      agent.Temperature,
-				"prompt_cache_key": agent.ID,
-			})
+			estimateFor(agent.Model)
+			start := time.Now()
+			resp, err := chat(ctx, agent.Model)
+			observeLLMCall(agent.providerName(), agent.Model, time.Since(start), resp, err)
+			return resp, err
 		}
 
 		// Retry loop for context/token errors
@@ -560,13 +791,7 @@ func (al *AgentLoop) runLLMIteration(
 				break
 			}
 
-			errMsg := strings.ToLower(err.Error())
-			isContextError := strings.Contains(errMsg, "token") ||
-				strings.Contains(errMsg, "context") ||
-				strings.Contains(errMsg, "invalidparameter") ||
-				strings.Contains(errMsg, "length")
-
-			if isContextError && retry < maxRetries {
+			if providers.IsContextLengthError(err) && retry < maxRetries {
 				logger.WarnCF("agent", "Context window error detected, attempting compression", map[string]any{
 					"error": err.Error(),
 					"retry": retry,
@@ -584,7 +809,7 @@ func (al *AgentLoop) runLLMIteration(
 				newHistory := agent.Sessions.GetHistory(opts.SessionKey)
 				newSummary := agent.Sessions.GetSummary(opts.SessionKey)
 				messages = agent.ContextBuilder.BuildMessages(
-					newHistory, newSummary, "",
+					ctx, newHistory, newSummary, "",
 					nil, opts.Channel, opts.ChatID,
 				)
 				continue
@@ -602,6 +827,13 @@ func (al *AgentLoop) runLLMIteration(
 			return "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
 		}
 
+		al.tokens.observeUsage(usedModel, promptEstimate, response.Usage)
+		if opts.Usage != nil && response.Usage != nil {
+			opts.Usage.PromptTokens += response.Usage.PromptTokens
+			opts.Usage.CompletionTokens += response.Usage.CompletionTokens
+			opts.Usage.TotalTokens += response.Usage.TotalTokens
+		}
+
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
@@ -632,6 +864,10 @@ func (al *AgentLoop) runLLMIteration(
 				"iteration": iteration,
 			})
 
+		if response.Content != "" {
+			al.publishEvent(opts, bus.AgentEvent{Kind: bus.EventText, Content: response.Content})
+		}
+
 		// Build assistant message with tool calls
 		assistantMsg := providers.Message{
 			Role:             "assistant",
@@ -698,14 +934,18 @@ func (al *AgentLoop) runLLMIteration(
 				}
 			}
 
+			al.publishEvent(opts, bus.AgentEvent{Kind: bus.EventToolStart, Tool: tc.Name, Content: argsPreview})
+			toolCtx := journal.WithCall(ctx, journal.Call{Session: opts.SessionKey, Turn: turn, ToolCall: tc.ID})
+			toolCtx = tools.WithSessionKey(toolCtx, opts.SessionKey)
 			toolResult := agent.Tools.ExecuteWithContext(
-				ctx,
+				toolCtx,
 				tc.Name,
 				tc.Arguments,
 				opts.Channel,
 				opts.ChatID,
 				asyncCallback,
 			)
+			al.publishEvent(opts, bus.AgentEvent{Kind: bus.EventToolEnd, Tool: tc.Name, IsError: toolResult.IsError})
 
 			// Send ForUser content to user immediately if not Silent
 			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
@@ -742,6 +982,18 @@ func (al *AgentLoop) runLLMIteration(
 	return finalContent, iteration, nil
 }
 
+// publishEvent reports progress on the current message to its channel.
+func (al *AgentLoop) publishEvent(opts processOptions, ev bus.AgentEvent) {
+	ev.Channel = opts.Channel
+	ev.ChatID = opts.ChatID
+	if opts.OnEvent != nil {
+		opts.OnEvent(ev)
+	}
+	if !constants.IsInternalChannel(opts.Channel) {
+		al.bus.PublishEvent(ev)
+	}
+}
+
 // updateToolContexts updates the context for tools that need channel/chatID info.
 func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID string) {
 	// Use ContextualTool interface instead of type assertions
@@ -765,8 +1017,8 @@ func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID st
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
 func (al *AgentLoop) maybeSummarize(agent *AgentInstance, sessionKey, channel, chatID string) {
 	newHistory := agent.Sessions.GetHistory(sessionKey)
-	tokenEstimate := al.estimateTokens(newHistory)
-	threshold := agent.ContextWindow * 75 / 100
+	tokenEstimate := al.tokens.calibrator.Adjust(agent.Model, al.tokens.countMessages(agent.Model, newHistory))
+	threshold := (agent.ContextWindow() - agent.MaxTokens) * 75 / 100
 
 	if len(newHistory) > 20 || tokenEstimate > threshold {
 		summarizeKey := agent.ID + ":" + sessionKey
@@ -802,8 +1054,12 @@ func (al *AgentLoop) forceCompression(agent *AgentInstance, sessionKey string) {
 		return
 	}
 
-	// Helper to find the mid-point of the conversation
-	mid := len(conversation) / 2
+	// Helper to find the mid-point of the conversation, without separating
+	// an assistant tool call from its results
+	mid := session.SafeCutIndex(conversation, len(conversation)/2)
+	if mid == 0 {
+		return
+	}
 
 	// New history structure:
 	// 1. System Prompt (with compression note appended)
@@ -927,137 +1183,6 @@ func formatToolsForLog(toolDefs []providers.ToolDefinition) string {
 	return sb.String()
 }
 
-// summarizeSession summarizes the conversation history for a session.
-func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
-	defer cancel()
-
-	history := agent.Sessions.GetHistory(sessionKey)
-	summary := agent.Sessions.GetSummary(sessionKey)
-
-	// Keep last 4 messages for continuity
-	if len(history) <= 4 {
-		return
-	}
-
-	toSummarize := history[:len(history)-4]
-
-	// Oversized Message Guard
-	maxMessageTokens := agent.ContextWindow / 2
-	validMessages := make([]providers.Message, 0)
-	omitted := false
-
-	for _, m := range toSummarize {
-		if m.Role != "user" && m.Role != "assistant" {
-			continue
-		}
-		msgTokens := len(m.Content) / 2
-		if msgTokens > maxMessageTokens {
-			omitted = true
-			continue
-		}
-		validMessages = append(validMessages, m)
-	}
-
-	if len(validMessages) == 0 {
-		return
-	}
-
-	// Multi-Part Summarization
-	var finalSummary string
-	if len(validMessages) > 10 {
-		mid := len(validMessages) / 2
-		part1 := validMessages[:mid]
-		part2 := validMessages[mid:]
-
-		s1, _ := al.summarizeBatch(ctx, agent, part1, "")
-		s2, _ := al.summarizeBatch(ctx, agent, part2, "")
-
-		mergePrompt := fmt.Sprintf(
-			"Merge these two conversation summaries into one cohesive summary:\n\n1: %s\n\n2: %s",
-			s1,
-			s2,
-		)
-		resp, err := agent.Provider.Chat(
-			ctx,
-			[]providers.Message{{Role: "user", Content: mergePrompt}},
-			nil,
-			agent.Model,
-			map[string]any{
-				"max_tokens":       1024,
-				"temperature":      0.3,
-				"prompt_cache_key": agent.ID,
-			},
-		)
-		if err == nil {
-			finalSummary = resp.Content
-		} else {
-			finalSummary = s1 + " " + s2
-		}
-	} else {
-		finalSummary, _ = al.summarizeBatch(ctx, agent, validMessages, summary)
-	}
-
-	if omitted && finalSummary != "" {
-		finalSummary += "\n[Note: Some oversized messages were omitted from this summary for efficiency.]"
-	}
-
-	if finalSummary != "" {
-		agent.Sessions.SetSummary(sessionKey, finalSummary)
-		agent.Sessions.TruncateHistory(sessionKey, 4)
-		agent.Sessions.Save(sessionKey)
-	}
-}
-
-// summarizeBatch summarizes a batch of messages.
-func (al *AgentLoop) summarizeBatch(
-	ctx context.Context,
-	agent *AgentInstance,
-	batch []providers.Message,
-	existingSummary string,
-) (string, error) {
-	var sb strings.Builder
-	sb.WriteString("Provide a concise summary of this conversation segment, preserving core context and key points.\n")
-	if existingSummary != "" {
-		sb.WriteString("Existing context: ")
-		sb.WriteString(existingSummary)
-		sb.WriteString("\n")
-	}
-	sb.WriteString("\nCONVERSATION:\n")
-	for _, m := range batch {
-		fmt.Fprintf(&sb, "%s: %s\n", m.Role, m.Content)
-	}
-	prompt := sb.String()
-
-	response, err := agent.Provider.Chat(
-		ctx,
-		[]providers.Message{{Role: "user", Content: prompt}},
-		nil,
-		agent.Model,
-		map[string]any{
-			"max_tokens":       1024,
-			"temperature":      0.3,
-			"prompt_cache_key": agent.ID,
-		},
-	)
-	if err != nil {
-		return "", err
-	}
-	return response.Content, nil
-}
-
-// estimateTokens estimates the number of tokens in a message list.
-// Uses a safe heuristic of 2.5 characters per token to account for CJK and other
-// overheads better than the previous 3 chars/token.
-func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
-	totalChars := 0
-	for _, m := range messages {
-		totalChars += utf8.RuneCountInString(m.Content)
-	}
-	// 2.5 chars per token = totalChars * 2 / 5
-	return totalChars * 2 / 5
-}
-
 //nolint:funlen,gocognit,gocyclo // slash command dispatch: large switch over command names
 func (al *AgentLoop) handleCommand(_ context.Context, msg bus.InboundMessage) (string, bool) {
 	content := strings.TrimSpace(msg.Content)
@@ -1144,11 +1269,147 @@ func (al *AgentLoop) handleCommand(_ context.Context, msg bus.InboundMessage) (s
 		default:
 			return "Unknown switch target: " + target, true
 		}
+
+	case "/summary":
+		return al.handleSummaryCommand(msg, args), true
+
+	case "/undo":
+		return al.handleUndoCommand(msg, args), true
 	}
 
 	return "", false
 }
 
+// handleSummaryCommand shows, lists or rolls back the rolling summary of
+// the session the message belongs to.
+func (al *AgentLoop) handleSummaryCommand(msg bus.InboundMessage, args []string) string {
+	agent, sessionKey := al.resolveSession(msg)
+	if agent == nil {
+		return "No default agent configured"
+	}
+
+	action := "show"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "show":
+		summary := agent.Sessions.GetSummary(sessionKey)
+		if summary == "" {
+			return "No summary for this conversation yet"
+		}
+		return summary
+
+	case "history":
+		versions := agent.Sessions.GetSummaryVersions(sessionKey)
+		if len(versions) == 0 {
+			return "No summary versions for this conversation"
+		}
+		var sb strings.Builder
+		sb.WriteString("Summary versions (newest last):")
+		for _, v := range versions {
+			model := v.Model
+			if model == "" {
+				model = "unknown model"
+			}
+			fmt.Fprintf(&sb, "\n  v%d  %s  %s  %d chars",
+				v.Version, v.Created.Format("2006-01-02 15:04"), model, len(v.Summary))
+		}
+		return sb.String()
+
+	case "rollback":
+		restored, ok := agent.Sessions.RollbackSummary(sessionKey)
+		if !ok {
+			return "No summary to roll back"
+		}
+		agent.Sessions.Save(sessionKey)
+		if restored.Version == 0 {
+			return "Summary removed; no earlier version exists"
+		}
+		return fmt.Sprintf("Rolled back to summary v%d", restored.Version)
+
+	default:
+		return "Usage: /summary [show|history|rollback]"
+	}
+}
+
+// handleUndoCommand reverts the file changes of the last tool call, or of
+// the whole last turn, in the session the message belongs to.
+func (al *AgentLoop) handleUndoCommand(msg bus.InboundMessage, args []string) string {
+	agent, sessionKey := al.resolveSession(msg)
+	if agent == nil {
+		return "No default agent configured"
+	}
+	if agent.Journal == nil {
+		return "The change journal is disabled"
+	}
+
+	wholeTurn, force := false, false
+	for _, arg := range args {
+		switch arg {
+		case "turn":
+			wholeTurn = true
+		case "force":
+			force = true
+		case "history":
+			return undoHistory(agent.Journal, sessionKey)
+		default:
+			return "Usage: /undo [turn] [force] | /undo history"
+		}
+	}
+
+	entries, err := agent.Journal.Last(sessionKey, wholeTurn)
+	if err != nil {
+		return "Undo failed: " + err.Error()
+	}
+	if len(entries) == 0 {
+		return "No file changes to undo in this conversation"
+	}
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	_, err = agent.Journal.Revert(ids, force)
+	var conflict *journal.ConflictError
+	if errors.As(err, &conflict) {
+		retry := "/undo force"
+		if wholeTurn {
+			retry = "/undo turn force"
+		}
+		return fmt.Sprintf("Nothing undone: %s changed since. Send %s to overwrite.",
+			strings.Join(conflict.Paths, ", "), retry)
+	}
+	if err != nil {
+		return "Undo failed: " + err.Error()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Undone:")
+	for i := len(entries) - 1; i >= 0; i-- {
+		sb.WriteString("\n  " + entries[i].Summary())
+	}
+	return sb.String()
+}
+
+// undoHistory lists the newest journal entries of a session.
+func undoHistory(j *journal.Journal, sessionKey string) string {
+	entries, err := j.Entries()
+	if err != nil {
+		return "Failed to read the journal: " + err.Error()
+	}
+	var lines []string
+	for i := len(entries) - 1; i >= 0 && len(lines) < 10; i-- {
+		if e := entries[i]; e.Session == sessionKey {
+			lines = append(lines, "  "+e.String())
+		}
+	}
+	if len(lines) == 0 {
+		return "No file changes in this conversation"
+	}
+	return "File changes (newest first):\n" + strings.Join(lines, "\n")
+}
+
 // extractPeer extracts the routing peer from inbound message metadata.
 func extractPeer(msg bus.InboundMessage) *routing.RoutePeer {
 	peerKind := msg.Metadata["peer_kind"]
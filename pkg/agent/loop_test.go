@@ -3,9 +3,11 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -591,3 +593,250 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 		t.Errorf("Expected history to be compressed (len < 8), got %d", len(finalHistory))
 	}
 }
+
+// usageMockProvider records the messages it was sent and reports usage.
+type usageMockProvider struct {
+	got []providers.Message
+}
+
+func (m *usageMockProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	m.got = messages
+	return &providers.LLMResponse{
+		Content: "chat reply",
+		Usage:   &providers.UsageInfo{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10},
+	}, nil
+}
+
+func (m *usageMockProvider) GetDefaultModel() string {
+	return "mock-usage-model"
+}
+
+func TestAgentLoop_ProcessChat(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &usageMockProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	messages := []providers.Message{
+		{Role: "system", Content: "Answer in French."},
+		{Role: "user", Content: "Earlier question"},
+		{Role: "assistant", Content: "Earlier answer"},
+		{Role: "user", Content: "Hello"},
+	}
+
+	// Stateless: the seeded history reaches the provider, then the session is dropped.
+	content, usage, err := al.ProcessChat(context.Background(), "", "", messages, nil)
+	if err != nil {
+		t.Fatalf("ProcessChat: %v", err)
+	}
+	if content != "chat reply" || usage.TotalTokens != 10 {
+		t.Errorf("got %q with usage %+v", content, usage)
+	}
+	var sawSeed, sawInstructions bool
+	for _, m := range provider.got {
+		sawSeed = sawSeed || m.Content == "Earlier answer"
+		sawInstructions = sawInstructions || strings.HasPrefix(m.Content, "Instructions:\nAnswer in French.")
+	}
+	if !sawSeed || !sawInstructions {
+		t.Errorf("provider messages missing seed or instructions: %+v", provider.got)
+	}
+	if n := al.registry.GetDefaultAgent().Sessions.ActiveCount(time.Hour); n != 0 {
+		t.Errorf("expected ephemeral session to be deleted, %d remain", n)
+	}
+
+	// Keyed sessions persist.
+	if _, _, err := al.ProcessChat(context.Background(), "", "api:s1", messages[3:], nil); err != nil {
+		t.Fatalf("ProcessChat: %v", err)
+	}
+	if h := al.registry.GetDefaultAgent().Sessions.GetHistory("api:s1"); len(h) != 2 {
+		t.Errorf("expected 2 history entries, got %d", len(h))
+	}
+
+	if _, _, err := al.ProcessChat(context.Background(), "nope", "", messages, nil); err == nil {
+		t.Error("expected error for unknown agent")
+	}
+}
+
+// streamingMockProvider streams its reply in pieces and counts plain calls.
+type streamingMockProvider struct {
+	chats int
+}
+
+func (m *streamingMockProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	m.chats++
+	return &providers.LLMResponse{Content: "streamed reply"}, nil
+}
+
+func (m *streamingMockProvider) ChatStream(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+	onDelta func(string),
+) (*providers.LLMResponse, error) {
+	for _, piece := range []string{"streamed", " reply"} {
+		onDelta(piece)
+	}
+	return &providers.LLMResponse{Content: "streamed reply"}, nil
+}
+
+func (m *streamingMockProvider) GetDefaultModel() string {
+	return "mock-stream-model"
+}
+
+func TestAgentLoop_StreamsToSubscribedChannel(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	provider := &streamingMockProvider{}
+	al := NewAgentLoop(cfg, msgBus, provider)
+
+	var deltas []string
+	msgBus.SubscribeEvents("web", func(ev bus.AgentEvent) {
+		if ev.Kind == bus.EventDelta && ev.ChatID == "s1" {
+			deltas = append(deltas, ev.Content)
+		}
+	})
+
+	reply, err := al.ProcessDirectWithChannel(context.Background(), "hi", "web:s1", "web", "s1")
+	if err != nil {
+		t.Fatalf("ProcessDirectWithChannel: %v", err)
+	}
+	if reply != "streamed reply" || strings.Join(deltas, "") != "streamed reply" || provider.chats != 0 {
+		t.Fatalf("reply %q, deltas %q, plain calls %d", reply, deltas, provider.chats)
+	}
+
+	// Channels without a subscriber use the plain call.
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "hi", "telegram:1", "telegram", "1"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel: %v", err)
+	}
+	if provider.chats != 1 || len(deltas) != 2 {
+		t.Fatalf("plain calls %d, deltas %q", provider.chats, deltas)
+	}
+
+	// API callers passing an event callback get deltas on the internal channel.
+	var apiDeltas []string
+	reply, _, err = al.ProcessChat(context.Background(), "", "", []providers.Message{{Role: "user", Content: "hi"}},
+		func(ev bus.AgentEvent) {
+			if ev.Kind == bus.EventDelta {
+				apiDeltas = append(apiDeltas, ev.Content)
+			}
+		})
+	if err != nil {
+		t.Fatalf("ProcessChat: %v", err)
+	}
+	if reply != "streamed reply" || len(apiDeltas) != 2 || provider.chats != 1 {
+		t.Fatalf("reply %q, deltas %q, plain calls %d", reply, apiDeltas, provider.chats)
+	}
+}
+
+// writeFileProvider asks for one write_file call, then answers.
+type writeFileProvider struct {
+	calls int
+}
+
+func (m *writeFileProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	m.calls++
+	if m.calls%2 == 1 {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+			ID:        fmt.Sprintf("call_%d", m.calls),
+			Name:      "write_file",
+			Arguments: map[string]any{"path": "notes.md", "content": fmt.Sprintf("version %d", m.calls)},
+		}}}, nil
+	}
+	return &providers.LLMResponse{Content: "Saved."}, nil
+}
+
+func (m *writeFileProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestUndoCommand_RevertsToolChanges(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:           workspace,
+				RestrictToWorkspace: true,
+				Model:               "test-model",
+				MaxTokens:           4096,
+				MaxToolIterations:   10,
+			},
+		},
+		Tools: config.ToolsConfig{Journal: config.JournalConfig{Enabled: true}},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &writeFileProvider{})
+	ctx := context.Background()
+	const session = "agent:main:undo-test"
+	notes := filepath.Join(workspace, "notes.md")
+
+	for range 2 {
+		if _, err := al.ProcessDirectWithChannel(ctx, "save my notes", session, "cli", "direct"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := os.ReadFile(notes); string(data) != "version 3" {
+		t.Fatalf("notes.md = %q", data)
+	}
+
+	resp, err := al.ProcessDirectWithChannel(ctx, "/undo", session, "cli", "direct")
+	if err != nil || !strings.Contains(resp, "write_file: modified notes.md") {
+		t.Fatalf("/undo = %q, %v", resp, err)
+	}
+	if data, _ := os.ReadFile(notes); string(data) != "version 1" {
+		t.Errorf("notes.md after /undo = %q", data)
+	}
+
+	resp, _ = al.ProcessDirectWithChannel(ctx, "/undo history", session, "cli", "direct")
+	if !strings.Contains(resp, "#2") || !strings.Contains(resp, "(reverted)") {
+		t.Errorf("/undo history = %q", resp)
+	}
+
+	resp, _ = al.ProcessDirectWithChannel(ctx, "/undo turn", session, "cli", "direct")
+	if !strings.Contains(resp, "created notes.md") {
+		t.Errorf("/undo turn = %q", resp)
+	}
+	if _, err := os.Stat(notes); !os.IsNotExist(err) {
+		t.Errorf("notes.md should be gone: %v", err)
+	}
+
+	resp, _ = al.ProcessDirectWithChannel(ctx, "/undo", session, "cli", "direct")
+	if resp != "No file changes to undo in this conversation" {
+		t.Errorf("/undo with nothing left = %q", resp)
+	}
+}
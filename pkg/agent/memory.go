@@ -7,11 +7,12 @@
 package agent
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/memory"
 )
 
 // MemoryStore manages persistent memory for the agent.
@@ -41,10 +42,7 @@ func NewMemoryStore(workspace string) *MemoryStore {
 
 // getTodayFile returns the path to today's daily note file (memory/YYYYMM/YYYYMMDD.md).
 func (ms *MemoryStore) getTodayFile() string {
-	today := time.Now().Format("20060102") // YYYYMMDD
-	monthDir := today[:6]                  // YYYYMM
-	filePath := filepath.Join(ms.memoryDir, monthDir, today+".md")
-	return filePath
+	return memory.DailyNotePath(ms.memoryDir, time.Now())
 }
 
 // ReadLongTerm reads the long-term memory (MEMORY.md).
@@ -74,28 +72,7 @@ func (ms *MemoryStore) ReadToday() string {
 // AppendToday appends content to today's daily note.
 // If the file doesn't exist, it creates a new file with a date header.
 func (ms *MemoryStore) AppendToday(content string) error {
-	todayFile := ms.getTodayFile()
-
-	// Ensure month directory exists
-	monthDir := filepath.Dir(todayFile)
-	os.MkdirAll(monthDir, 0o755)
-
-	var existingContent string
-	if data, err := os.ReadFile(todayFile); err == nil {
-		existingContent = string(data)
-	}
-
-	var newContent string
-	if existingContent == "" {
-		// Add header for new day
-		header := fmt.Sprintf("# %s\n\n", time.Now().Format("2006-01-02"))
-		newContent = header + content
-	} else {
-		// Append to existing content
-		newContent = existingContent + "\n" + content
-	}
-
-	return os.WriteFile(todayFile, []byte(newContent), 0o644)
+	return memory.AppendDailyNote(ms.memoryDir, content, time.Now())
 }
 
 // GetRecentDailyNotes returns daily notes from the last N days.
@@ -105,10 +82,7 @@ func (ms *MemoryStore) GetRecentDailyNotes(days int) string {
 	first := true
 
 	for i := range days {
-		date := time.Now().AddDate(0, 0, -i)
-		dateStr := date.Format("20060102") // YYYYMMDD
-		monthDir := dateStr[:6]            // YYYYMM
-		filePath := filepath.Join(ms.memoryDir, monthDir, dateStr+".md")
+		filePath := memory.DailyNotePath(ms.memoryDir, time.Now().AddDate(0, 0, -i))
 
 		if data, err := os.ReadFile(filePath); err == nil {
 			if !first {
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+)
+
+// activeSessionWindow is how recently a session must have been updated to
+// count as active.
+const activeSessionWindow = time.Hour
+
+// RegisterMetrics exports the loop's live state (bus queue depths, provider
+// cooldowns and active sessions) as gauges evaluated at scrape time.
+func (al *AgentLoop) RegisterMetrics(reg *metrics.Registry) {
+	reg.GaugeFunc("tinyclaw_bus_queue_depth", "Messages waiting in the message bus queues.",
+		[]string{"queue"}, func(emit func(float64, ...string)) {
+			inbound, outbound := al.bus.QueueDepths()
+			emit(float64(inbound), "inbound")
+			emit(float64(outbound), "outbound")
+		})
+
+	reg.GaugeFunc("tinyclaw_provider_cooldown_seconds", "Time until a provider leaves fallback cooldown.",
+		[]string{"provider"}, func(emit func(float64, ...string)) {
+			for _, st := range al.cooldown.Snapshot() {
+				emit(st.Remaining.Seconds(), st.Provider)
+			}
+		})
+	reg.GaugeFunc("tinyclaw_provider_cooldown_errors", "Provider failures counted toward the current cooldown.",
+		[]string{"provider"}, func(emit func(float64, ...string)) {
+			for _, st := range al.cooldown.Snapshot() {
+				emit(float64(st.ErrorCount), st.Provider)
+			}
+		})
+
+	reg.GaugeFunc("tinyclaw_sessions_active", "Sessions updated in the last hour.",
+		[]string{"agent"}, func(emit func(float64, ...string)) {
+			for _, id := range al.registry.ListAgentIDs() {
+				if agent, ok := al.registry.GetAgent(id); ok {
+					emit(float64(agent.Sessions.ActiveCount(activeSessionWindow)), id)
+				}
+			}
+		})
+}
+
+// observeLLMCall records latency, token usage and errors for one LLM request.
+func observeLLMCall(provider, model string, elapsed time.Duration, resp *providers.LLMResponse, err error) {
+	metrics.LLMDuration.Observe(elapsed.Seconds(), provider, model)
+	if err != nil {
+		metrics.LLMRequests.Inc(provider, model, "error")
+		reason := string(providers.FailoverUnknown)
+		if failErr := providers.ClassifyError(err, provider, model); failErr != nil {
+			reason = string(failErr.Reason)
+		}
+		metrics.LLMErrors.Inc(provider, model, reason)
+		return
+	}
+	metrics.LLMRequests.Inc(provider, model, "ok")
+	if resp != nil && resp.Usage != nil {
+		metrics.LLMTokens.Add(float64(resp.Usage.PromptTokens), provider, model, "prompt")
+		metrics.LLMTokens.Add(float64(resp.Usage.CompletionTokens), provider, model, "completion")
+	}
+}
+
+// providerName returns the provider label for an agent's primary model.
+func (a *AgentInstance) providerName() string {
+	if len(a.Candidates) > 0 {
+		return a.Candidates[0].Provider
+	}
+	return "default"
+}
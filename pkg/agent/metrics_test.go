@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+)
+
+func TestObserveLLMCall(t *testing.T) {
+	observeLLMCall("obs", "obs-model", 2*time.Second, &providers.LLMResponse{
+		Usage: &providers.UsageInfo{PromptTokens: 120, CompletionTokens: 30},
+	}, nil)
+	observeLLMCall("obs", "obs-model", time.Second, nil, errors.New("429 rate limit exceeded"))
+
+	if got := metrics.LLMRequests.Value("obs", "obs-model", "ok"); got != 1 {
+		t.Errorf("ok requests = %v, want 1", got)
+	}
+	if got := metrics.LLMErrors.Value("obs", "obs-model", "rate_limit"); got != 1 {
+		t.Errorf("rate_limit errors = %v, want 1", got)
+	}
+	if got := metrics.LLMTokens.Value("obs", "obs-model", "prompt"); got != 120 {
+		t.Errorf("prompt tokens = %v, want 120", got)
+	}
+	if got := metrics.LLMDuration.Count("obs", "obs-model"); got != 2 {
+		t.Errorf("duration observations = %d, want 2", got)
+	}
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "metrics-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &simpleMockProvider{response: "hi"})
+
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "hello", "s1", "test", "chat"); err != nil {
+		t.Fatal(err)
+	}
+	provider := al.registry.GetDefaultAgent().providerName()
+	if got := metrics.LLMRequests.Value(provider, "metrics-model", "ok"); got != 1 {
+		t.Errorf("LLM requests = %v, want 1", got)
+	}
+
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "test", ChatID: "chat", Content: "queued"})
+	al.cooldown.MarkFailure("flaky", providers.FailoverTimeout)
+
+	reg := metrics.NewRegistry()
+	al.RegisterMetrics(reg)
+	var sb strings.Builder
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		`tinyclaw_bus_queue_depth{queue="outbound"} 1`,
+		`tinyclaw_provider_cooldown_errors{provider="flaky"} 1`,
+		`tinyclaw_sessions_active{agent="main"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out)
+		}
+	}
+}
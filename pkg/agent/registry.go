@@ -65,7 +65,22 @@ func (r *AgentRegistry) GetAgent(agentID string) (*AgentInstance, bool) {
 
 // ResolveRoute determines which agent handles the message.
 func (r *AgentRegistry) ResolveRoute(input routing.RouteInput) routing.ResolvedRoute {
-	return r.resolver.ResolveRoute(input)
+	r.mu.RLock()
+	resolver := r.resolver
+	r.mu.RUnlock()
+	return resolver.ResolveRoute(input)
+}
+
+// replace swaps in the agents and bindings of next, so callers holding this
+// registry see a reloaded configuration.
+func (r *AgentRegistry) replace(next *AgentRegistry) {
+	next.mu.RLock()
+	agents, resolver := next.agents, next.resolver
+	next.mu.RUnlock()
+
+	r.mu.Lock()
+	r.agents, r.resolver = agents, resolver
+	r.mu.Unlock()
 }
 
 // ListAgentIDs returns all registered agent IDs.
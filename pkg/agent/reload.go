@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+	"github.com/tinyland-inc/tinyclaw/pkg/tools"
+)
+
+// Reload rebuilds every agent from cfg with provider and swaps them in, along
+// with the route bindings. Messages already being processed finish on the
+// agents they started with.
+//
+// Agents that keep their ID keep their background processes, and those that
+// also keep their workspace keep their in-memory sessions. Agents no longer
+// configured have their background processes stopped.
+func (al *AgentLoop) Reload(cfg *config.Config, provider providers.LLMProvider) {
+	al.reloadMu.Lock()
+	defer al.reloadMu.Unlock()
+
+	next := NewAgentRegistry(cfg, provider)
+	registerSharedTools(cfg, al.bus, next, provider)
+	for _, id := range next.ListAgentIDs() {
+		agent, _ := next.GetAgent(id)
+		for _, tool := range al.extraTools {
+			agent.Tools.Register(tool)
+		}
+		if old, ok := al.registry.GetAgent(id); ok {
+			adoptAgentState(agent, old)
+		}
+	}
+
+	for _, id := range al.registry.ListAgentIDs() {
+		if _, ok := next.GetAgent(id); ok {
+			continue
+		}
+		if old, ok := al.registry.GetAgent(id); ok && old.Processes != nil {
+			old.Processes.Close()
+		}
+		logger.InfoCF("agent", "Removed agent", map[string]any{"agent_id": id})
+	}
+
+	al.registry.replace(next)
+	al.cfg = cfg
+}
+
+// adoptAgentState moves the runtime state of old that outlives a reload onto
+// its rebuilt replacement.
+func adoptAgentState(agent, old *AgentInstance) {
+	if old.Processes != nil {
+		agent.Processes.Close()
+		agent.Processes = old.Processes
+		if tool, ok := agent.Tools.Get("exec"); ok {
+			if exec, ok := tool.(*tools.ExecTool); ok {
+				exec.SetProcessManager(old.Processes)
+			}
+		}
+		agent.Tools.Register(tools.NewProcessTool(old.Processes))
+	}
+	if agent.Workspace == old.Workspace {
+		agent.Sessions = old.Sessions
+	}
+}
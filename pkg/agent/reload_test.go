@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/routing"
+)
+
+func TestAgentLoop_Reload(t *testing.T) {
+	cfg := testCfg([]config.AgentConfig{{ID: "main", Default: true}, {ID: "helper", Workspace: t.TempDir()}})
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockRegistryProvider{})
+	al.RegisterTool(&mockCustomTool{})
+	defer al.Stop()
+
+	oldMain, _ := al.registry.GetAgent("main")
+	oldMain.Sessions.AddMessage("s1", "user", "hello")
+
+	next := testCfg([]config.AgentConfig{{ID: "main", Default: true}, {ID: "writer", Workspace: t.TempDir()}})
+	next.Agents.Defaults.Workspace = cfg.Agents.Defaults.Workspace
+	next.Bindings = []config.AgentBinding{{AgentID: "writer", Match: config.BindingMatch{Channel: "telegram"}}}
+	al.Reload(next, &mockRegistryProvider{})
+
+	ids := al.ListAgentIDs()
+	if len(ids) != 2 {
+		t.Fatalf("agents after reload = %v", ids)
+	}
+	if _, ok := al.registry.GetAgent("helper"); ok {
+		t.Error("removed agent still registered")
+	}
+	main, _ := al.registry.GetAgent("main")
+	if main == oldMain {
+		t.Fatal("main agent was not rebuilt")
+	}
+	if main.Processes != oldMain.Processes {
+		t.Error("main agent lost its background processes")
+	}
+	if len(main.Sessions.GetHistory("s1")) != 1 {
+		t.Error("main agent lost its in-memory session")
+	}
+	if _, ok := main.Tools.Get("mock_custom"); !ok {
+		t.Error("tool added with RegisterTool missing after reload")
+	}
+	if _, ok := main.Tools.Get("spawn"); !ok {
+		t.Error("shared tools missing after reload")
+	}
+	if route := al.registry.ResolveRoute(routing.RouteInput{Channel: "telegram"}); route.AgentID != "writer" {
+		t.Errorf("telegram routed to %q, want writer", route.AgentID)
+	}
+}
@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+	"github.com/tinyland-inc/tinyclaw/pkg/session"
+)
+
+const (
+	// summaryKeepMessages is how many recent messages stay verbatim after summarization.
+	summaryKeepMessages = 4
+
+	// Per-item caps when rendering history for the summarizer. Long content
+	// is condensed (head and tail kept) rather than dropped.
+	summaryMessageChars    = 4000
+	summaryToolResultChars = 600
+	summaryToolArgsChars   = 200
+)
+
+const summaryInstructions = `You maintain the running memory of a conversation between a user and an AI assistant that can use tools.
+Update the summary with the new conversation segment. Write concise markdown using only these sections, omitting empty ones:
+
+## Facts
+What is now known, including what tool calls found or changed, as short factual notes (e.g. "config.yaml sets port 8080", "pkg/api tests fail in TestAuth"). State results, not which tools were called.
+
+## Decisions
+Decisions made and the reasons for them.
+
+## User preferences
+Stated preferences, constraints and instructions that should keep applying.
+
+## Open tasks
+Unfinished work and unanswered questions.
+
+Keep items from the existing summary that still apply and drop the ones the new segment makes obsolete. Output only the summary.`
+
+// summarizeSession folds older session history into the rolling summary.
+// History is rendered with tool calls and their results condensed into
+// notes, split into segments that fit the summary model, and summarized
+// segment by segment on top of the previous summary. The new summary is
+// stored as a new version; the history is only truncated once it succeeds.
+func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	history := agent.Sessions.GetHistory(sessionKey)
+	if len(history) <= summaryKeepMessages {
+		return
+	}
+
+	// Never split an assistant tool call from its results.
+	cut := session.SafeCutIndex(history, len(history)-summaryKeepMessages)
+	if cut == 0 {
+		return
+	}
+
+	summary := agent.Sessions.GetSummary(sessionKey)
+	segments := al.summarySegments(agent, history[:cut])
+
+	usedModel := agent.SummaryModel
+	for _, segment := range segments {
+		next, model, err := al.summarizeSegment(ctx, agent, segment, summary)
+		if err != nil {
+			logger.WarnCF("agent", "Summarization failed, keeping history", map[string]any{
+				"agent_id":    agent.ID,
+				"session_key": sessionKey,
+				"error":       err.Error(),
+			})
+			return
+		}
+		summary, usedModel = next, model
+	}
+
+	version := agent.Sessions.SetSummaryVersion(sessionKey, summary, usedModel)
+
+	// Messages may have been appended while we were summarizing; drop only
+	// the ones that made it into the summary.
+	current := len(agent.Sessions.GetHistory(sessionKey))
+	agent.Sessions.TruncateHistory(sessionKey, current-cut)
+	agent.Sessions.Save(sessionKey)
+
+	logger.InfoCF("agent", "Session summarized", map[string]any{
+		"agent_id":        agent.ID,
+		"session_key":     sessionKey,
+		"summarized_msgs": cut,
+		"segments":        len(segments),
+		"summary_version": version,
+		"model":           usedModel,
+	})
+}
+
+// summarySegments renders messages turn by turn and packs the turns into
+// transcript segments that each fit half of the summary model's window.
+func (al *AgentLoop) summarySegments(agent *AgentInstance, messages []providers.Message) []string {
+	budget := agent.limitsFor(agent.SummaryModel).ContextWindow / 2
+
+	var segments []string
+	var cur strings.Builder
+	curTokens := 0
+	for _, turn := range splitTurns(messages) {
+		text := renderTranscript(turn)
+		if text == "" {
+			continue
+		}
+		tokens := al.tokens.countText(agent.SummaryModel, text)
+		if cur.Len() > 0 && curTokens+tokens > budget {
+			segments = append(segments, cur.String())
+			cur.Reset()
+			curTokens = 0
+		}
+		cur.WriteString(text)
+		curTokens += tokens
+	}
+	if cur.Len() > 0 {
+		segments = append(segments, cur.String())
+	}
+	return segments
+}
+
+// summarizeSegment asks the summary model to merge segment into summary,
+// retrying once with the agent's own model if a separate summary model
+// fails. Returns the new summary and the model that produced it.
+func (al *AgentLoop) summarizeSegment(
+	ctx context.Context,
+	agent *AgentInstance,
+	segment, summary string,
+) (string, string, error) {
+	var sb strings.Builder
+	sb.WriteString(summaryInstructions)
+	if summary != "" {
+		sb.WriteString("\n\nEXISTING SUMMARY:\n")
+		sb.WriteString(summary)
+	}
+	sb.WriteString("\n\nNEW SEGMENT:\n")
+	sb.WriteString(segment)
+	prompt := []providers.Message{{Role: "user", Content: sb.String()}}
+
+	options := map[string]any{
+		"max_tokens":       1024,
+		"temperature":      0.3,
+		"prompt_cache_key": agent.ID,
+	}
+
+	resp, err := agent.SummaryProvider.Chat(ctx, prompt, nil, agent.SummaryModel, options)
+	if err == nil && strings.TrimSpace(resp.Content) != "" {
+		return strings.TrimSpace(resp.Content), agent.SummaryModel, nil
+	}
+	if err == nil {
+		err = errors.New("empty summary")
+	}
+	if agent.SummaryProvider == agent.Provider && agent.SummaryModel == agent.Model {
+		return "", "", err
+	}
+
+	logger.WarnCF("agent", "Summary model failed, retrying with agent model", map[string]any{
+		"summary_model": agent.SummaryModel,
+		"error":         err.Error(),
+	})
+	resp, err = agent.Provider.Chat(ctx, prompt, nil, agent.Model, options)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.TrimSpace(resp.Content) == "" {
+		return "", "", errors.New("empty summary")
+	}
+	return strings.TrimSpace(resp.Content), agent.Model, nil
+}
+
+// splitTurns splits history into turns, each starting at a user message, so
+// tool calls and their results always land in the same segment.
+func splitTurns(messages []providers.Message) [][]providers.Message {
+	var turns [][]providers.Message
+	start := 0
+	for i := 1; i < len(messages); i++ {
+		if messages[i].Role == "user" {
+			turns = append(turns, messages[start:i])
+			start = i
+		}
+	}
+	if start < len(messages) {
+		turns = append(turns, messages[start:])
+	}
+	return turns
+}
+
+// renderTranscript renders messages for the summarizer. Each assistant tool
+// call is written on one line together with its (condensed) result, so the
+// summarizer sees what was done and what came of it.
+func renderTranscript(messages []providers.Message) string {
+	results := make(map[string]string)
+	for _, m := range messages {
+		if m.Role == "tool" && m.ToolCallID != "" {
+			results[m.ToolCallID] = m.Content
+		}
+	}
+
+	var sb strings.Builder
+	for _, m := range messages {
+		switch m.Role {
+		case "user", "assistant":
+			if content := strings.TrimSpace(m.Content); content != "" {
+				fmt.Fprintf(&sb, "%s: %s\n", m.Role, condenseText(content, summaryMessageChars))
+			}
+			for _, call := range m.ToolCalls {
+				name, args := toolCallParts(call)
+				result, ok := results[call.ID]
+				if !ok {
+					result = "(no result)"
+				}
+				fmt.Fprintf(&sb, "tool %s(%s) -> %s\n",
+					name,
+					condenseText(args, summaryToolArgsChars),
+					oneLine(condenseText(strings.TrimSpace(result), summaryToolResultChars)))
+			}
+		case "tool":
+			// Rendered with the call that produced it; results whose call
+			// is not part of this history still carry information.
+			if !hasToolCall(messages, m.ToolCallID) {
+				fmt.Fprintf(&sb, "tool result -> %s\n",
+					oneLine(condenseText(strings.TrimSpace(m.Content), summaryToolResultChars)))
+			}
+		}
+	}
+	return sb.String()
+}
+
+func toolCallParts(call providers.ToolCall) (name, args string) {
+	name = call.Name
+	if call.Function != nil {
+		if name == "" {
+			name = call.Function.Name
+		}
+		args = call.Function.Arguments
+	}
+	if args == "" && len(call.Arguments) > 0 {
+		if data, err := json.Marshal(call.Arguments); err == nil {
+			args = string(data)
+		}
+	}
+	return name, args
+}
+
+func hasToolCall(messages []providers.Message, id string) bool {
+	for _, m := range messages {
+		for _, call := range m.ToolCalls {
+			if call.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// condenseText shortens s to about maxChars, keeping the head and the tail
+// (where errors and final results usually are).
+func condenseText(s string, maxChars int) string {
+	if len(s) <= maxChars {
+		return s
+	}
+	head := maxChars * 2 / 3
+	tail := maxChars - head
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	start := len(s) - tail
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return fmt.Sprintf("%s … [%d chars omitted] … %s", s[:head], start-head, s[start:])
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
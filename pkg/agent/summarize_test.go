@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+)
+
+// recordingProvider returns a fixed summary and records every prompt.
+type recordingProvider struct {
+	response string
+	err      error
+	prompts  []string
+	models   []string
+}
+
+func (p *recordingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	p.prompts = append(p.prompts, messages[len(messages)-1].Content)
+	p.models = append(p.models, model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &providers.LLMResponse{Content: p.response}, nil
+}
+
+func (p *recordingProvider) GetDefaultModel() string {
+	return "recording-model"
+}
+
+func newSummarizeTestLoop(t *testing.T, provider providers.LLMProvider) (*AgentLoop, *AgentInstance) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	return al, al.registry.GetDefaultAgent()
+}
+
+func toolPairHistory() []providers.Message {
+	return []providers.Message{
+		{Role: "user", Content: "what port does the server use?"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{
+			{ID: "c1", Name: "read_file", Arguments: map[string]any{"path": "config.yaml"}},
+		}},
+		{Role: "tool", ToolCallID: "c1", Content: "server:\n  port: 8080\n"},
+		{Role: "assistant", Content: "It listens on 8080."},
+		{Role: "user", Content: "change it to 9090"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{
+			{ID: "c2", Name: "edit_file", Arguments: map[string]any{"path": "config.yaml"}},
+		}},
+		{Role: "tool", ToolCallID: "c2", Content: "File edited: config.yaml"},
+		{Role: "assistant", Content: "Done."},
+	}
+}
+
+func TestRenderTranscript_ToolCallsBecomeNotes(t *testing.T) {
+	got := renderTranscript(toolPairHistory()[:4])
+	want := "user: what port does the server use?\n" +
+		`tool read_file({"path":"config.yaml"}) -> server: port: 8080` + "\n" +
+		"assistant: It listens on 8080.\n"
+	if got != want {
+		t.Errorf("renderTranscript =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderTranscript_CondensesOversizedContent(t *testing.T) {
+	long := "BEGIN " + strings.Repeat("x", 20000) + " END"
+	got := renderTranscript([]providers.Message{
+		{Role: "user", Content: long},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c", Name: "exec"}}},
+		{Role: "tool", ToolCallID: "c", Content: long},
+	})
+	if !strings.Contains(got, "BEGIN") || !strings.Contains(got, "END") || !strings.Contains(got, "chars omitted") {
+		t.Errorf("oversized content should be condensed, not dropped: %.200s", got)
+	}
+	if len(got) > summaryMessageChars+summaryToolResultChars+200 {
+		t.Errorf("transcript too long: %d chars", len(got))
+	}
+}
+
+func TestSplitTurns(t *testing.T) {
+	turns := splitTurns(toolPairHistory())
+	if len(turns) != 2 || len(turns[0]) != 4 || len(turns[1]) != 4 {
+		t.Fatalf("unexpected turns: %v", turns)
+	}
+}
+
+func TestSummarizeSession_StructuredAndVersioned(t *testing.T) {
+	provider := &recordingProvider{response: "## Facts\n- server port changed from 8080 to 9090"}
+	al, agent := newSummarizeTestLoop(t, provider)
+
+	key := "test-session"
+	agent.Sessions.GetOrCreate(key)
+	history := append(toolPairHistory(), providers.Message{Role: "user", Content: "thanks"},
+		providers.Message{Role: "assistant", Content: "You're welcome."})
+	agent.Sessions.SetHistory(key, history)
+
+	al.summarizeSession(agent, key)
+
+	if len(provider.prompts) != 1 {
+		t.Fatalf("expected 1 summarization call, got %d", len(provider.prompts))
+	}
+	prompt := provider.prompts[0]
+	for _, want := range []string{"## Facts", "read_file", "port: 8080", "change it to 9090"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	if strings.Contains(prompt, "edit_file") {
+		t.Error("kept tool call should not be summarized")
+	}
+
+	if got := agent.Sessions.GetSummary(key); got != provider.response {
+		t.Errorf("summary = %q", got)
+	}
+	versions := agent.Sessions.GetSummaryVersions(key)
+	if len(versions) != 1 || versions[0].Model != "test-model" {
+		t.Errorf("unexpected versions: %+v", versions)
+	}
+
+	// Keeping the last 4 messages would start at a tool result; the cut
+	// moves back to keep the edit_file call with it.
+	kept := agent.Sessions.GetHistory(key)
+	if len(kept) != 5 || kept[0].Role != "assistant" || len(kept[0].ToolCalls) != 1 {
+		t.Errorf("tool pair split by truncation: %+v", kept)
+	}
+}
+
+func TestSummarizeSession_FailureKeepsHistory(t *testing.T) {
+	provider := &recordingProvider{err: errors.New("boom")}
+	al, agent := newSummarizeTestLoop(t, provider)
+
+	key := "test-session"
+	agent.Sessions.GetOrCreate(key)
+	agent.Sessions.SetHistory(key, toolPairHistory())
+
+	al.summarizeSession(agent, key)
+
+	if len(agent.Sessions.GetHistory(key)) != len(toolPairHistory()) {
+		t.Error("history must not be truncated when summarization fails")
+	}
+	if agent.Sessions.GetSummary(key) != "" {
+		t.Error("no summary should be stored on failure")
+	}
+}
+
+func TestSummarizeSession_SummaryModelFallsBackToAgentModel(t *testing.T) {
+	main := &recordingProvider{response: "summary from main model"}
+	cheap := &recordingProvider{err: errors.New("quota exceeded")}
+	al, agent := newSummarizeTestLoop(t, main)
+	agent.SummaryProvider = cheap
+	agent.SummaryModel = "cheap-model"
+
+	key := "test-session"
+	agent.Sessions.GetOrCreate(key)
+	agent.Sessions.SetHistory(key, toolPairHistory())
+
+	al.summarizeSession(agent, key)
+
+	if len(cheap.models) != 1 || cheap.models[0] != "cheap-model" {
+		t.Errorf("summary model not tried first: %v", cheap.models)
+	}
+	versions := agent.Sessions.GetSummaryVersions(key)
+	if len(versions) != 1 || versions[0].Model != "test-model" || versions[0].Summary != main.response {
+		t.Errorf("unexpected versions: %+v", versions)
+	}
+}
+
+func TestResolveSummaryModel(t *testing.T) {
+	cfg := &config.Config{
+		ModelList: []config.ModelConfig{
+			{ModelName: "cheap", Model: "openai/gpt-4o-mini", APIKey: "k"},
+		},
+	}
+	main := &mockProvider{}
+
+	p, model := resolveSummaryModel(cfg, "cheap", main, "main-model")
+	if model != "gpt-4o-mini" || p == providers.LLMProvider(main) {
+		t.Errorf("expected cheap model provider, got %T %q", p, model)
+	}
+
+	p, model = resolveSummaryModel(cfg, "", main, "main-model")
+	if model != "main-model" || p != providers.LLMProvider(main) {
+		t.Errorf("empty name should use agent model, got %q", model)
+	}
+
+	p, model = resolveSummaryModel(cfg, "missing", main, "main-model")
+	if model != "main-model" || p != providers.LLMProvider(main) {
+		t.Errorf("unknown name should use agent model, got %q", model)
+	}
+}
+
+func TestSummaryCommand_Rollback(t *testing.T) {
+	al, agent := newSummarizeTestLoop(t, &mockProvider{})
+	msg := bus.InboundMessage{Channel: "cli", ChatID: "direct", SessionKey: "agent:main:test"}
+
+	agent.Sessions.GetOrCreate(msg.SessionKey)
+	agent.Sessions.SetSummaryVersion(msg.SessionKey, "good summary", "m")
+	agent.Sessions.SetSummaryVersion(msg.SessionKey, "bad summary", "m")
+
+	msg.Content = "/summary history"
+	resp, handled := al.handleCommand(context.Background(), msg)
+	if !handled || !strings.Contains(resp, "v1") || !strings.Contains(resp, "v2") {
+		t.Fatalf("unexpected history response: %q", resp)
+	}
+
+	msg.Content = "/summary rollback"
+	resp, _ = al.handleCommand(context.Background(), msg)
+	if !strings.Contains(resp, "v1") {
+		t.Errorf("unexpected rollback response: %q", resp)
+	}
+	if got := agent.Sessions.GetSummary(msg.SessionKey); got != "good summary" {
+		t.Errorf("summary after rollback = %q", got)
+	}
+
+	msg.Content = "/summary"
+	if resp, _ = al.handleCommand(context.Background(), msg); resp != "good summary" {
+		t.Errorf("/summary = %q", resp)
+	}
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// Endpoint scopes an API key can be limited to.
+const (
+	ScopeDispatch = "dispatch" // POST /api/dispatch
+	ScopeJobs     = "jobs"     // /api/jobs/{id}
+	ScopeTools    = "tools"    // GET /api/tools
+	ScopeStatus   = "status"   // GET /api/status
+	ScopeChat     = "chat"     // /v1/chat/completions and /v1/models
+)
+
+var validScopes = []string{ScopeDispatch, ScopeJobs, ScopeTools, ScopeStatus, ScopeChat}
+
+const keyHashPrefix = "sha256:"
+
+// HashAPIKey returns the form of key stored in config: "sha256:<hex>".
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return keyHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// SecretLookup fetches a named secret, such as an API key kept in Setec.
+type SecretLookup func(ctx context.Context, name string) (string, error)
+
+// Authenticator checks bearer API keys on the gateway API and applies each
+// key's scopes, rate limit and concurrency cap. Every authenticated
+// request is audit-logged with the key's name.
+type Authenticator struct {
+	keys []*apiKey
+}
+
+type apiKey struct {
+	name      string
+	hash      [sha256.Size]byte
+	agents    []string
+	endpoints []string
+	rate      float64 // requests per minute, 0 for unlimited
+	inflight  chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewAuthenticator builds an authenticator from the configured keys.
+// Keys kept in Setec are fetched with lookup; one that cannot be fetched
+// is skipped with a warning, so requests using it are refused.
+func NewAuthenticator(ctx context.Context, cfg config.APIAuthConfig, lookup SecretLookup) (*Authenticator, error) {
+	a := &Authenticator{keys: make([]*apiKey, 0, len(cfg.Keys))}
+	for _, kc := range cfg.Keys {
+		if kc.Name == "" {
+			return nil, fmt.Errorf("api key without a name")
+		}
+		for _, ep := range kc.Endpoints {
+			if !slices.Contains(validScopes, ep) {
+				return nil, fmt.Errorf("api key %q: unknown endpoint %q", kc.Name, ep)
+			}
+		}
+
+		key := &apiKey{
+			name:      kc.Name,
+			agents:    kc.Agents,
+			endpoints: kc.Endpoints,
+			rate:      float64(kc.RateLimit),
+			tokens:    float64(kc.RateLimit),
+			last:      time.Now(),
+		}
+		if kc.MaxConcurrent > 0 {
+			key.inflight = make(chan struct{}, kc.MaxConcurrent)
+		}
+
+		switch {
+		case kc.Hash != "":
+			sum, err := hex.DecodeString(strings.TrimPrefix(kc.Hash, keyHashPrefix))
+			if !strings.HasPrefix(kc.Hash, keyHashPrefix) || err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("api key %q: hash must be %s<64 hex digits>", kc.Name, keyHashPrefix)
+			}
+			copy(key.hash[:], sum)
+		case kc.Setec != "":
+			if lookup == nil {
+				return nil, fmt.Errorf("api key %q: setec_url is required for setec keys", kc.Name)
+			}
+			secret, err := lookup(ctx, kc.Setec)
+			if err != nil || secret == "" {
+				logger.WarnCF("api", "Skipping API key: secret unavailable", map[string]any{
+					"key":   kc.Name,
+					"error": fmt.Sprint(err),
+				})
+				continue
+			}
+			key.hash = sha256.Sum256([]byte(secret))
+		default:
+			return nil, fmt.Errorf("api key %q: hash or setec is required", kc.Name)
+		}
+		a.keys = append(a.keys, key)
+	}
+	return a, nil
+}
+
+type (
+	keyContextKey  struct{}
+	slotContextKey struct{}
+)
+
+// concurrencySlot is the place a request holds in its key's max_concurrent
+// cap. wrap frees it when the handler returns unless the handler has
+// detached it to hand over to background work.
+type concurrencySlot struct {
+	inflight chan struct{}
+	detached bool
+	once     sync.Once
+}
+
+func (s *concurrencySlot) release() {
+	s.once.Do(func() { <-s.inflight })
+}
+
+// detachSlot takes over the request's concurrency slot and returns the
+// function that frees it, for work that outlives the handler such as an
+// async job. It returns nil when the request holds no slot.
+func detachSlot(ctx context.Context) func() {
+	slot, _ := ctx.Value(slotContextKey{}).(*concurrencySlot)
+	if slot == nil {
+		return nil
+	}
+	slot.detached = true
+	return slot.release
+}
+
+// callerKey returns the API key that authenticated the request, or nil
+// when the API is open.
+func callerKey(ctx context.Context) *apiKey {
+	key, _ := ctx.Value(keyContextKey{}).(*apiKey)
+	return key
+}
+
+// callerName names the API key behind a request for audit records.
+func callerName(ctx context.Context) string {
+	if key := callerKey(ctx); key != nil {
+		return key.name
+	}
+	return ""
+}
+
+// sessionKeyFor returns the session key for a conversation the caller
+// names id. With API keys configured it is namespaced by the key, so one
+// key cannot read or extend another's conversations.
+func sessionKeyFor(ctx context.Context, id string) string {
+	if name := callerName(ctx); name != "" {
+		return "api:" + name + ":" + id
+	}
+	return "api:" + id
+}
+
+// allowsAgent reports whether the request's key may use agentID.
+func allowsAgent(ctx context.Context, agentID string) bool {
+	key := callerKey(ctx)
+	return key == nil || len(key.agents) == 0 || slices.Contains(key.agents, agentID)
+}
+
+// wrap guards next with key authentication for scope. A nil
+// Authenticator leaves the API open.
+func (a *Authenticator) wrap(scope string, next http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return next
+	}
+	fail := writeAuthError
+	if scope == ScopeChat {
+		fail = writeOpenAIAuthError
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := a.authenticate(r)
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tinyclaw"`)
+			fail(w, http.StatusUnauthorized, "invalid or missing API key")
+			a.audit(r, "", http.StatusUnauthorized, 0)
+			return
+		}
+		if len(key.endpoints) > 0 && !slices.Contains(key.endpoints, scope) {
+			fail(w, http.StatusForbidden, "API key not allowed to use "+scope)
+			a.audit(r, key.name, http.StatusForbidden, 0)
+			return
+		}
+		if ok, wait := key.allow(time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			fail(w, http.StatusTooManyRequests, "rate limit exceeded")
+			a.audit(r, key.name, http.StatusTooManyRequests, 0)
+			return
+		}
+		ctx := context.WithValue(r.Context(), keyContextKey{}, key)
+		if key.inflight != nil {
+			select {
+			case key.inflight <- struct{}{}:
+				slot := &concurrencySlot{inflight: key.inflight}
+				ctx = context.WithValue(ctx, slotContextKey{}, slot)
+				defer func() {
+					if !slot.detached {
+						slot.release()
+					}
+				}()
+			default:
+				fail(w, http.StatusTooManyRequests, "too many concurrent requests")
+				a.audit(r, key.name, http.StatusTooManyRequests, 0)
+				return
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		start := time.Now()
+		next(rec, r.WithContext(ctx))
+		a.audit(r, key.name, rec.code, time.Since(start))
+	}
+}
+
+// authenticate returns the key matching the request's bearer token.
+func (a *Authenticator) authenticate(r *http.Request) *apiKey {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(token))
+	var match *apiKey
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], key.hash[:]) == 1 {
+			match = key
+		}
+	}
+	return match
+}
+
+func (a *Authenticator) audit(r *http.Request, keyName string, status int, elapsed time.Duration) {
+	fields := map[string]any{
+		"key":         keyName,
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"status":      status,
+		"remote_addr": r.RemoteAddr,
+	}
+	if elapsed > 0 {
+		fields["duration_ms"] = elapsed.Milliseconds()
+	}
+	if status >= 400 {
+		logger.WarnCF("api", "API request rejected", fields)
+		return
+	}
+	logger.InfoCF("api", "API request", fields)
+}
+
+// allow takes a token from the key's bucket, which refills at the
+// per-minute rate up to one minute's worth. When empty it returns how
+// long until the next token.
+func (k *apiKey) allow(now time.Time) (bool, time.Duration) {
+	if k.rate <= 0 {
+		return true, 0
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.tokens = min(k.rate, k.tokens+now.Sub(k.last).Minutes()*k.rate)
+	k.last = now
+	if k.tokens < 1 {
+		return false, time.Duration((1 - k.tokens) / k.rate * float64(time.Minute))
+	}
+	k.tokens--
+	return true, 0
+}
+
+func writeAuthError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func writeOpenAIAuthError(w http.ResponseWriter, status int, message string) {
+	errType := "invalid_request_error"
+	switch status {
+	case http.StatusUnauthorized:
+		errType = "authentication_error"
+	case http.StatusForbidden:
+		errType = "permission_error"
+	case http.StatusTooManyRequests:
+		errType = "rate_limit_error"
+	}
+	writeOpenAIError(w, status, errType, "", message)
+}
+
+// statusRecorder captures the status code written by a handler. Unwrap
+// lets http.ResponseController reach the underlying writer for flushes
+// and deadlines.
+type statusRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.code = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+func newAuthMux(t *testing.T, d Dispatcher, keys ...config.APIKeyConfig) *http.ServeMux {
+	t.Helper()
+	auth, err := NewAuthenticator(context.Background(), config.APIAuthConfig{Keys: keys}, nil)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	h := NewHandlers(d)
+	h.SetAuthenticator(auth)
+	mux := http.NewServeMux()
+	h.Register(mux)
+	return mux
+}
+
+func authRequest(mux *http.ServeMux, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuth_RequiresValidKey(t *testing.T) {
+	mux := newAuthMux(t, &mockDispatcher{info: map[string]any{"ok": true}},
+		config.APIKeyConfig{Name: "ci", Hash: HashAPIKey("good")})
+
+	if rec := authRequest(mux, http.MethodGet, "/api/status", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing key: expected 401, got %d", rec.Code)
+	}
+	if rec := authRequest(mux, http.MethodGet, "/api/status", "bad", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad key: expected 401, got %d", rec.Code)
+	}
+	if rec := authRequest(mux, http.MethodGet, "/api/status", "good", ""); rec.Code != http.StatusOK {
+		t.Errorf("good key: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestAuth_EndpointScopes(t *testing.T) {
+	mux := newAuthMux(t, &mockDispatcher{result: "ok"},
+		config.APIKeyConfig{Name: "reader", Hash: HashAPIKey("k"), Endpoints: []string{ScopeStatus, ScopeTools}})
+
+	if rec := authRequest(mux, http.MethodGet, "/api/tools", "k", ""); rec.Code != http.StatusOK {
+		t.Errorf("tools: expected 200, got %d", rec.Code)
+	}
+	rec := authRequest(mux, http.MethodPost, "/api/dispatch", "k", `{"content":"hi"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("dispatch: expected 403, got %d", rec.Code)
+	}
+}
+
+func TestAuth_AgentScopes(t *testing.T) {
+	d := &mockChatDispatcher{mockDispatcher: mockDispatcher{result: "ok"}, agents: []string{"main", "research"}}
+	mux := newAuthMux(t, d,
+		config.APIKeyConfig{Name: "research-only", Hash: HashAPIKey("k"), Agents: []string{"research"}})
+
+	// Dispatch routes to the default agent, which this key may not use.
+	if rec := authRequest(mux, http.MethodPost, "/api/dispatch", "k", `{"content":"hi"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("dispatch: expected 403, got %d", rec.Code)
+	}
+
+	body := `{"model":"main","messages":[{"role":"user","content":"hi"}]}`
+	if rec := authRequest(mux, http.MethodPost, "/v1/chat/completions", "k", body); rec.Code != http.StatusForbidden {
+		t.Errorf("main model: expected 403, got %d", rec.Code)
+	}
+	body = `{"model":"research","messages":[{"role":"user","content":"hi"}]}`
+	if rec := authRequest(mux, http.MethodPost, "/v1/chat/completions", "k", body); rec.Code != http.StatusOK {
+		t.Errorf("research model: expected 200, got %d", rec.Code)
+	}
+
+	rec := authRequest(mux, http.MethodGet, "/v1/models", "k", "")
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Data) != 1 || resp.Data[0].ID != "research" {
+		t.Errorf("expected only research model, got %+v", resp.Data)
+	}
+}
+
+func TestAuth_RateLimit(t *testing.T) {
+	mux := newAuthMux(t, &mockDispatcher{},
+		config.APIKeyConfig{Name: "limited", Hash: HashAPIKey("k"), RateLimit: 2})
+
+	for i := range 2 {
+		if rec := authRequest(mux, http.MethodGet, "/api/status", "k", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := authRequest(mux, http.MethodGet, "/api/status", "k", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected Retry-After 30, got %q", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestAPIKey_TokenBucketRefills(t *testing.T) {
+	now := time.Now()
+	key := &apiKey{rate: 60, tokens: 0, last: now}
+	if ok, wait := key.allow(now); ok || wait != time.Second {
+		t.Errorf("empty bucket: got ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := key.allow(now.Add(time.Second)); !ok {
+		t.Error("expected a token after one second")
+	}
+}
+
+func TestAuth_ConcurrencyCap(t *testing.T) {
+	d := &blockingDispatcher{release: make(chan struct{})}
+	mux := newAuthMux(t, d, config.APIKeyConfig{Name: "one", Hash: HashAPIKey("k"), MaxConcurrent: 1})
+
+	first := make(chan int)
+	go func() {
+		first <- authRequest(mux, http.MethodPost, "/api/dispatch", "k", `{"content":"slow"}`).Code
+	}()
+	// Wait for the first request to hold the slot.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := authRequest(mux, http.MethodGet, "/api/status", "k", "")
+		if rec.Code == http.StatusTooManyRequests {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("second request was never limited")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(d.release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("first request: expected 200, got %d", code)
+	}
+}
+
+func TestAuth_AsyncJobHoldsConcurrencySlot(t *testing.T) {
+	d := &blockingDispatcher{release: make(chan struct{})}
+	mux := newAuthMux(t, d, config.APIKeyConfig{Name: "one", Hash: HashAPIKey("k"), MaxConcurrent: 1})
+
+	rec := authRequest(mux, http.MethodPost, "/api/dispatch?async=true", "k", `{"content":"slow"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("async dispatch: expected 202, got %d", rec.Code)
+	}
+	// The job still runs, so the key is at its cap.
+	if rec := authRequest(mux, http.MethodGet, "/api/status", "k", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("while job runs: expected 429, got %d", rec.Code)
+	}
+
+	close(d.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for authRequest(mux, http.MethodGet, "/api/status", "k", "").Code != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("slot not released after the job finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAuth_SessionKeysScopedToKey(t *testing.T) {
+	d := &mockChatDispatcher{mockDispatcher: mockDispatcher{result: "ok"}, agents: []string{"main"}}
+	mux := newAuthMux(t, d, config.APIKeyConfig{Name: "a", Hash: HashAPIKey("ka")})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		bytes.NewBufferString(`{"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer ka")
+	req.Header.Set(SessionHeader, "s1")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	if d.gotSession != "api:a:s1" {
+		t.Errorf("chat session = %q, want api:a:s1", d.gotSession)
+	}
+
+	bd := &blockingDispatcher{release: make(chan struct{})}
+	close(bd.release)
+	mux = newAuthMux(t, bd, config.APIKeyConfig{Name: "a", Hash: HashAPIKey("ka")})
+	for body, want := range map[string]string{
+		`{"content":"hi"}`: "api:a:dispatch",
+		`{"content":"hi","session_key":"agent:main:telegram:1"}`: "api:a:agent:main:telegram:1",
+	} {
+		rec := authRequest(mux, http.MethodPost, "/api/dispatch?async=true", "ka", body)
+		var accepted jobAccepted
+		json.NewDecoder(rec.Body).Decode(&accepted)
+		var job Job
+		json.NewDecoder(authRequest(mux, http.MethodGet, accepted.StatusURL, "ka", "").Body).Decode(&job)
+		if job.SessionKey != want {
+			t.Errorf("%s: session = %q, want %q", body, job.SessionKey, want)
+		}
+	}
+}
+
+func TestAuth_JobsVisibleOnlyToOwner(t *testing.T) {
+	d := &blockingDispatcher{release: make(chan struct{})}
+	defer close(d.release)
+	mux := newAuthMux(t, d,
+		config.APIKeyConfig{Name: "a", Hash: HashAPIKey("ka")},
+		config.APIKeyConfig{Name: "b", Hash: HashAPIKey("kb")})
+
+	rec := authRequest(mux, http.MethodPost, "/api/dispatch?async=true", "ka", `{"content":"slow"}`)
+	var accepted jobAccepted
+	json.NewDecoder(rec.Body).Decode(&accepted)
+
+	if rec := authRequest(mux, http.MethodGet, accepted.StatusURL, "kb", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("other key: expected 404, got %d", rec.Code)
+	}
+	if rec := authRequest(mux, http.MethodDelete, accepted.StatusURL, "kb", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("other key cancel: expected 404, got %d", rec.Code)
+	}
+	if rec := authRequest(mux, http.MethodGet, accepted.StatusURL, "ka", ""); rec.Code != http.StatusOK {
+		t.Errorf("owner: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestNewAuthenticator_Validation(t *testing.T) {
+	cases := []config.APIKeyConfig{
+		{Hash: HashAPIKey("k")},
+		{Name: "x"},
+		{Name: "x", Hash: "md5:abc"},
+		{Name: "x", Hash: HashAPIKey("k"), Endpoints: []string{"everything"}},
+		{Name: "x", Setec: "api-key"}, // no lookup configured
+	}
+	for _, kc := range cases {
+		if _, err := NewAuthenticator(context.Background(), config.APIAuthConfig{Keys: []config.APIKeyConfig{kc}}, nil); err == nil {
+			t.Errorf("expected error for %+v", kc)
+		}
+	}
+}
+
+func TestNewAuthenticator_SetecKeys(t *testing.T) {
+	lookup := func(_ context.Context, name string) (string, error) {
+		if name == "api/ci" {
+			return "from-setec", nil
+		}
+		return "", errors.New("not found")
+	}
+	auth, err := NewAuthenticator(context.Background(), config.APIAuthConfig{Keys: []config.APIKeyConfig{
+		{Name: "ci", Setec: "api/ci"},
+		{Name: "missing", Setec: "api/missing"},
+	}}, lookup)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	if len(auth.keys) != 1 {
+		t.Fatalf("expected the unavailable key to be skipped, got %d keys", len(auth.keys))
+	}
+
+	h := NewHandlers(&mockDispatcher{})
+	h.SetAuthenticator(auth)
+	mux := http.NewServeMux()
+	h.Register(mux)
+	if rec := authRequest(mux, http.MethodGet, "/api/status", "from-setec", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
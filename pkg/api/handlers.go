@@ -6,7 +6,11 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/routing"
 )
 
 // Dispatcher abstracts the agent loop for testability.
@@ -16,6 +20,24 @@ type Dispatcher interface {
 	GetToolDefinitions() []map[string]any
 }
 
+// EventDispatcher is a Dispatcher that can report tool calls and interim
+// replies while it runs. Async jobs record them as steps when available.
+type EventDispatcher interface {
+	ProcessDirectWithEvents(
+		ctx context.Context,
+		content, sessionKey, channel, chatID string,
+		onEvent func(bus.AgentEvent),
+	) (string, error)
+}
+
+// AgentResolver reports which agent a request reaches, so API keys
+// limited to some agents can be enforced. Without it, requests are
+// assumed to reach routing.DefaultAgentID.
+type AgentResolver interface {
+	DefaultAgentID() string
+	DispatchAgentID(channel string) string
+}
+
 // RouteRegistrar accepts new HTTP handler routes.
 type RouteRegistrar interface {
 	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
@@ -24,25 +46,49 @@ type RouteRegistrar interface {
 // Handlers holds references needed by the API endpoints.
 type Handlers struct {
 	dispatcher Dispatcher
+	chat       ChatCompleter // nil unless the dispatcher implements it
+	jobs       *JobStore
+	auth       *Authenticator
+	started    time.Time
 }
 
-// NewHandlers creates a new Handlers instance.
+// NewHandlers creates a new Handlers instance. Async jobs are kept in
+// memory until SetJobStore provides a persistent store.
 func NewHandlers(d Dispatcher) *Handlers {
-	return &Handlers{dispatcher: d}
+	h := &Handlers{dispatcher: d, jobs: NewJobStore("", 0, "", false), started: time.Now()}
+	h.chat, _ = d.(ChatCompleter)
+	return h
+}
+
+// SetJobStore replaces the store used for async dispatch jobs.
+func (h *Handlers) SetJobStore(jobs *JobStore) {
+	h.jobs = jobs
+}
+
+// SetAuthenticator requires API keys on every route registered afterwards.
+func (h *Handlers) SetAuthenticator(auth *Authenticator) {
+	h.auth = auth
 }
 
 // Register adds all API routes to the given registrar.
 func (h *Handlers) Register(r RouteRegistrar) {
-	r.HandleFunc("POST /api/dispatch", h.handleDispatch)
-	r.HandleFunc("GET /api/tools", h.handleTools)
-	r.HandleFunc("GET /api/status", h.handleStatus)
+	r.HandleFunc("POST /api/dispatch", h.auth.wrap(ScopeDispatch, h.handleDispatch))
+	r.HandleFunc("GET /api/tools", h.auth.wrap(ScopeTools, h.handleTools))
+	r.HandleFunc("GET /api/status", h.auth.wrap(ScopeStatus, h.handleStatus))
+	r.HandleFunc("GET /api/jobs/{id}", h.auth.wrap(ScopeJobs, h.handleGetJob))
+	r.HandleFunc("DELETE /api/jobs/{id}", h.auth.wrap(ScopeJobs, h.handleCancelJob))
+	if h.chat != nil {
+		r.HandleFunc("POST /v1/chat/completions", h.auth.wrap(ScopeChat, h.handleChatCompletions))
+		r.HandleFunc("GET /v1/models", h.auth.wrap(ScopeChat, h.handleModels))
+	}
 }
 
 type dispatchRequest struct {
-	Content    string `json:"content"`
-	SessionKey string `json:"session_key"`
-	Channel    string `json:"channel"`
-	ChatID     string `json:"chat_id"`
+	Content     string `json:"content"`
+	SessionKey  string `json:"session_key"`
+	Channel     string `json:"channel"`
+	ChatID      string `json:"chat_id"`
+	CallbackURL string `json:"callback_url"` // async only
 }
 
 type dispatchResponse struct {
@@ -70,8 +116,25 @@ func (h *Handlers) handleDispatch(w http.ResponseWriter, r *http.Request) {
 	if req.ChatID == "" {
 		req.ChatID = "dispatch"
 	}
-	if req.SessionKey == "" {
-		req.SessionKey = "api:" + req.ChatID
+	switch {
+	case req.SessionKey == "":
+		req.SessionKey = sessionKeyFor(r.Context(), req.ChatID)
+	case callerKey(r.Context()) != nil:
+		req.SessionKey = sessionKeyFor(r.Context(), req.SessionKey)
+	}
+
+	if agentID := h.dispatchAgentID(req.Channel); !allowsAgent(r.Context(), agentID) {
+		writeJSON(w, http.StatusForbidden, dispatchResponse{Error: "API key not allowed to use agent " + agentID})
+		return
+	}
+
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		h.startJob(w, r, req)
+		return
+	}
+	if req.CallbackURL != "" {
+		writeJSON(w, http.StatusBadRequest, dispatchResponse{Error: "callback_url requires async=true"})
+		return
 	}
 
 	// Best-effort: extend the write deadline — dispatch can take minutes.
@@ -90,6 +153,84 @@ func (h *Handlers) handleDispatch(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, dispatchResponse{Content: result, FinishReason: "stop"})
 }
 
+type jobAccepted struct {
+	JobID     string    `json:"job_id"`
+	Status    JobStatus `json:"status"`
+	StatusURL string    `json:"status_url"`
+}
+
+// startJob runs a dispatch request in the background and answers with
+// its job ID for polling at /api/jobs/{id}.
+func (h *Handlers) startJob(w http.ResponseWriter, r *http.Request, req dispatchRequest) {
+	if req.CallbackURL != "" {
+		if err := h.jobs.CheckCallbackURL(r.Context(), req.CallbackURL); err != nil {
+			writeJSON(w, http.StatusBadRequest, dispatchResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	run := func(ctx context.Context, onEvent func(bus.AgentEvent)) (string, error) {
+		if ed, ok := h.dispatcher.(EventDispatcher); ok {
+			return ed.ProcessDirectWithEvents(ctx, req.Content, req.SessionKey, req.Channel, req.ChatID, onEvent)
+		}
+		return h.dispatcher.ProcessDirectWithChannel(ctx, req.Content, req.SessionKey, req.Channel, req.ChatID)
+	}
+	job := h.jobs.Start(callerName(r.Context()), req, run, detachSlot(r.Context()))
+	writeJSON(w, http.StatusAccepted, jobAccepted{
+		JobID:     job.ID,
+		Status:    job.Status,
+		StatusURL: "/api/jobs/" + job.ID,
+	})
+}
+
+func (h *Handlers) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.Get(r.PathValue("id"))
+	if !ok || !ownsJob(r, job) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (h *Handlers) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	if job, ok := h.jobs.Get(r.PathValue("id")); ok && !ownsJob(r, job) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	job, canceled, err := h.jobs.Cancel(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if !canceled {
+		writeJSON(w, http.StatusConflict, job)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// ownsJob reports whether the request's API key started job. Jobs are
+// visible to every caller when the API is open.
+func ownsJob(r *http.Request, job Job) bool {
+	return callerKey(r.Context()) == nil || job.Owner == callerName(r.Context())
+}
+
+// dispatchAgentID returns the agent a dispatch on channel is routed to.
+func (h *Handlers) dispatchAgentID(channel string) string {
+	if ar, ok := h.dispatcher.(AgentResolver); ok {
+		return ar.DispatchAgentID(channel)
+	}
+	return routing.DefaultAgentID
+}
+
+// defaultAgentID returns the agent used when a request names none.
+func (h *Handlers) defaultAgentID() string {
+	if ar, ok := h.dispatcher.(AgentResolver); ok {
+		return ar.DefaultAgentID()
+	}
+	return routing.DefaultAgentID
+}
+
 func (h *Handlers) handleTools(w http.ResponseWriter, _ *http.Request) {
 	defs := h.dispatcher.GetToolDefinitions()
 	if defs == nil {
@@ -6,7 +6,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 )
 
 // mockDispatcher implements Dispatcher for testing.
@@ -205,3 +209,176 @@ func TestStatus(t *testing.T) {
 		t.Errorf("expected tool count 5, got %v", count)
 	}
 }
+
+// mockChatDispatcher also implements ChatCompleter.
+type mockChatDispatcher struct {
+	mockDispatcher
+	agents     []string
+	deltas     []string // streamed as EventDelta before returning
+	gotAgent   string
+	gotSession string
+	gotMsgs    []providers.Message
+}
+
+func (m *mockChatDispatcher) ListAgentIDs() []string { return m.agents }
+
+func (m *mockChatDispatcher) ProcessChat(
+	_ context.Context,
+	agentID, sessionKey string,
+	messages []providers.Message,
+	onEvent func(bus.AgentEvent),
+) (string, providers.UsageInfo, error) {
+	m.gotAgent, m.gotSession, m.gotMsgs = agentID, sessionKey, messages
+	if onEvent != nil {
+		onEvent(bus.AgentEvent{Kind: bus.EventToolStart, Tool: "read_file"})
+		for _, d := range m.deltas {
+			onEvent(bus.AgentEvent{Kind: bus.EventDelta, Content: d})
+		}
+	}
+	return m.result, providers.UsageInfo{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, m.err
+}
+
+func TestChatCompletions_NotRegisteredWithoutCompleter(t *testing.T) {
+	mux := newTestMux(&mockDispatcher{})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestModels_ListsAgents(t *testing.T) {
+	mux := newTestMux(&mockChatDispatcher{agents: []string{"main", "research"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Data) != 2 || resp.Data[1].ID != "research" {
+		t.Errorf("unexpected models: %+v", resp.Data)
+	}
+}
+
+func TestChatCompletions_NonStreaming(t *testing.T) {
+	d := &mockChatDispatcher{mockDispatcher: mockDispatcher{result: "hi there"}, agents: []string{"main", "research"}}
+	mux := newTestMux(d)
+
+	body := `{"model":"tinyclaw/research","messages":[
+		{"role":"system","content":"be brief"},
+		{"role":"user","content":[{"type":"text","text":"hello"}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set(SessionHeader, "abc")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp chatCompletion
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Object != "chat.completion" || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.Choices[0].Message.Content != "hi there" {
+		t.Errorf("expected 'hi there', got %q", resp.Choices[0].Message.Content)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 15 {
+		t.Errorf("expected usage total 15, got %+v", resp.Usage)
+	}
+	if d.gotAgent != "research" || d.gotSession != "api:abc" {
+		t.Errorf("got agent %q session %q", d.gotAgent, d.gotSession)
+	}
+	if len(d.gotMsgs) != 2 || d.gotMsgs[1].Content != "hello" {
+		t.Errorf("unexpected messages: %+v", d.gotMsgs)
+	}
+}
+
+func TestChatCompletions_UnknownModel(t *testing.T) {
+	mux := newTestMux(&mockChatDispatcher{agents: []string{"main"}})
+
+	body := `{"model":"gpt-4","messages":[{"role":"user","content":"hi"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	var resp openAIError
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Error.Code != "model_not_found" {
+		t.Errorf("expected model_not_found, got %q", resp.Error.Code)
+	}
+}
+
+func TestChatCompletions_Streaming(t *testing.T) {
+	d := &mockChatDispatcher{mockDispatcher: mockDispatcher{result: "streamed"}, agents: []string{"main"}}
+	mux := newTestMux(d)
+
+	body := `{"messages":[{"role":"user","content":"hi"}],"stream":true,"stream_options":{"include_usage":true}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream, got %q", ct)
+	}
+	out := rec.Body.String()
+	for _, want := range []string{`"role":"assistant"`, ": tool_start read_file", `"content":"streamed"`,
+		`"finish_reason":"stop"`, `"total_tokens":15`, "data: [DONE]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stream missing %q:\n%s", want, out)
+		}
+	}
+	if d.gotAgent != "" || d.gotSession != "" {
+		t.Errorf("expected default agent and no session, got %q %q", d.gotAgent, d.gotSession)
+	}
+}
+
+func TestChatCompletions_StreamsDeltas(t *testing.T) {
+	d := &mockChatDispatcher{
+		mockDispatcher: mockDispatcher{result: "Hello there"},
+		agents:         []string{"main"},
+		deltas:         []string{"Hel", "lo"},
+	}
+	mux := newTestMux(d)
+
+	body := `{"messages":[{"role":"user","content":"hi"}],"stream":true}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var pieces []string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var c chatCompletion
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			t.Fatalf("bad chunk %q: %v", data, err)
+		}
+		if len(c.Choices) == 1 && c.Choices[0].Delta != nil && c.Choices[0].Delta.Content != "" {
+			pieces = append(pieces, c.Choices[0].Delta.Content)
+		}
+	}
+	// The deltas are sent as they arrive and only the unstreamed rest follows.
+	if strings.Join(pieces, "|") != "Hel|lo| there" {
+		t.Errorf("content chunks = %q, want [Hel lo  there]", pieces)
+	}
+}
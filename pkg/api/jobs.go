@@ -0,0 +1,462 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/utils"
+)
+
+// JobStatus is the state of an asynchronous dispatch job.
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// Callback delivery states reported in Job.CallbackStatus.
+const (
+	CallbackPending   = "pending"
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+const (
+	defaultMaxJobs   = 500
+	maxStepContent   = 500
+	callbackAttempts = 3
+	callbackTimeout  = 10 * time.Second
+)
+
+// callbackBackoff is the delay before the first callback retry; it doubles
+// after each attempt.
+var callbackBackoff = 2 * time.Second
+
+// JobStep is a tool call or interim reply recorded while a job runs.
+type JobStep struct {
+	Kind    string    `json:"kind"`
+	Tool    string    `json:"tool,omitempty"`
+	Content string    `json:"content,omitempty"`
+	IsError bool      `json:"is_error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Job is an asynchronous dispatch request and its progress.
+type Job struct {
+	ID             string     `json:"id"`
+	Status         JobStatus  `json:"status"`
+	Content        string     `json:"content"`
+	SessionKey     string     `json:"session_key"`
+	Channel        string     `json:"channel"`
+	ChatID         string     `json:"chat_id"`
+	Owner          string     `json:"owner,omitempty"` // API key that started the job
+	CallbackURL    string     `json:"callback_url,omitempty"`
+	CallbackStatus string     `json:"callback_status,omitempty"`
+	Steps          []JobStep  `json:"steps"`
+	Output         string     `json:"output,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has stopped running.
+func (j *Job) Finished() bool {
+	return j.Status != JobRunning
+}
+
+func (j *Job) clone() Job {
+	c := *j
+	c.Steps = slices.Clone(j.Steps)
+	if c.Steps == nil {
+		c.Steps = []JobStep{}
+	}
+	return c
+}
+
+type jobFile struct {
+	Version int    `json:"version"`
+	Jobs    []*Job `json:"jobs"`
+}
+
+// JobRunner executes a job's request, reporting progress to onEvent.
+type JobRunner func(ctx context.Context, onEvent func(bus.AgentEvent)) (string, error)
+
+// JobStore tracks asynchronous dispatch jobs and persists them to a JSON
+// file so their results survive gateway restarts. The file is written when
+// a job starts or changes status; steps recorded in between are saved with
+// the next status change. Jobs still running when the gateway stopped are
+// reported as failed on the next start.
+//
+// Completion callbacks POST the job as JSON to its callback URL. When a
+// secret is configured, the X-TinyClaw-Signature header carries
+// "sha256=" and the hex HMAC-SHA256 of the X-TinyClaw-Timestamp header
+// value, a ".", and the body. Callback URLs must resolve to public
+// addresses unless private callbacks are allowed.
+type JobStore struct {
+	path         string
+	maxJobs      int
+	secret       string
+	allowPrivate bool
+	client       *http.Client
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+}
+
+// NewJobStore creates a job store persisted at path; an empty path keeps
+// jobs in memory only. At most maxJobs jobs are kept, dropping the oldest
+// finished ones first. allowPrivate permits callbacks to loopback, LAN and
+// tailnet addresses.
+func NewJobStore(path string, maxJobs int, secret string, allowPrivate bool) *JobStore {
+	if maxJobs <= 0 {
+		maxJobs = defaultMaxJobs
+	}
+	client := utils.NewPublicHTTPClient(callbackTimeout)
+	if allowPrivate {
+		client = &http.Client{Timeout: callbackTimeout}
+	}
+	s := &JobStore{
+		path:         path,
+		maxJobs:      maxJobs,
+		secret:       secret,
+		allowPrivate: allowPrivate,
+		client:       client,
+		jobs:         make(map[string]*Job),
+		cancels:      make(map[string]context.CancelFunc),
+	}
+	if err := s.load(); err != nil {
+		log.Printf("api: failed to load jobs: %v", err)
+	}
+	return s
+}
+
+// Start records a new job for req, started by the API key named owner,
+// and runs it in the background. release, if not nil, is called once the
+// run returns, so the owner's concurrency slot is held for the whole job.
+func (s *JobStore) Start(owner string, req dispatchRequest, run JobRunner, release func()) Job {
+	now := time.Now()
+	job := &Job{
+		ID:          uuid.NewString(),
+		Status:      JobRunning,
+		Content:     req.Content,
+		SessionKey:  req.SessionKey,
+		Channel:     req.Channel,
+		ChatID:      req.ChatID,
+		Owner:       owner,
+		CallbackURL: req.CallbackURL,
+		Steps:       []JobStep{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if req.CallbackURL != "" {
+		job.CallbackStatus = CallbackPending
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.cancels[job.ID] = cancel
+	s.prune()
+	s.save()
+	snapshot := job.clone()
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		if release != nil {
+			defer release()
+		}
+		output, err := run(ctx, func(ev bus.AgentEvent) { s.addStep(job.ID, ev) })
+		s.finish(job.ID, output, err)
+	}()
+	return snapshot
+}
+
+// CheckCallbackURL reports whether raw is an acceptable callback URL: an
+// http(s) URL whose host resolves to public addresses, unless private
+// callbacks are allowed.
+func (s *JobStore) CheckCallbackURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("callback_url must be an http(s) URL")
+	}
+	if s.allowPrivate {
+		return nil
+	}
+	if err := utils.CheckPublicHost(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("callback_url: %w", err)
+	}
+	return nil
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (s *JobStore) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.clone(), true
+}
+
+// Cancel stops a running job. It returns the job and whether it was
+// running; cancelling a finished job leaves it unchanged.
+func (s *JobStore) Cancel(id string) (Job, bool, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	if !ok {
+		s.mu.Unlock()
+		return Job{}, false, errors.New("job not found")
+	}
+	if job.Finished() {
+		snapshot := job.clone()
+		s.mu.Unlock()
+		return snapshot, false, nil
+	}
+	if cancel := s.cancels[id]; cancel != nil {
+		cancel()
+	}
+	s.complete(job, JobCanceled, "", "canceled by request")
+	snapshot := job.clone()
+	s.mu.Unlock()
+
+	s.deliver(snapshot)
+	return snapshot, true, nil
+}
+
+func (s *JobStore) addStep(id string, ev bus.AgentEvent) {
+	if ev.Kind != bus.EventToolStart && ev.Kind != bus.EventToolEnd && ev.Kind != bus.EventText {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.Finished() {
+		return
+	}
+	job.Steps = append(job.Steps, JobStep{
+		Kind:    ev.Kind,
+		Tool:    ev.Tool,
+		Content: utils.Truncate(ev.Content, maxStepContent),
+		IsError: ev.IsError,
+		At:      time.Now(),
+	})
+	job.UpdatedAt = time.Now()
+}
+
+func (s *JobStore) finish(id, output string, err error) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	// A cancelled job has already been completed and reported.
+	if !ok || job.Finished() {
+		s.mu.Unlock()
+		return
+	}
+	if err != nil {
+		s.complete(job, JobFailed, output, err.Error())
+	} else {
+		s.complete(job, JobSucceeded, output, "")
+	}
+	snapshot := job.clone()
+	s.mu.Unlock()
+
+	s.deliver(snapshot)
+}
+
+// complete marks job finished and saves. s.mu must be held.
+func (s *JobStore) complete(job *Job, status JobStatus, output, errMsg string) {
+	now := time.Now()
+	job.Status = status
+	job.Output = output
+	job.Error = errMsg
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+	delete(s.cancels, job.ID)
+	s.save()
+}
+
+// deliver posts the finished job to its callback URL in the background.
+func (s *JobStore) deliver(job Job) {
+	if job.CallbackURL == "" {
+		return
+	}
+	go func() {
+		status := CallbackFailed
+		if err := s.postCallback(job); err == nil {
+			status = CallbackDelivered
+		} else {
+			log.Printf("api: job %s callback failed: %v", job.ID, err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if stored, ok := s.jobs[job.ID]; ok {
+			stored.CallbackStatus = status
+			s.save()
+		}
+	}()
+}
+
+func (s *JobStore) postCallback(job Job) error {
+	job.CallbackStatus = ""
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	backoff := callbackBackoff
+	for attempt := 1; ; attempt++ {
+		err = s.sendCallback(job, body)
+		if err == nil || attempt == callbackAttempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *JobStore) sendCallback(job Job, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-TinyClaw-Job-Id", job.ID)
+	req.Header.Set("X-TinyClaw-Timestamp", timestamp)
+	if s.secret != "" {
+		req.Header.Set("X-TinyClaw-Signature", "sha256="+signCallback(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signCallback returns the hex HMAC-SHA256 of timestamp + "." + body.
+func signCallback(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// prune drops the oldest finished jobs beyond maxJobs. s.mu must be held.
+func (s *JobStore) prune() {
+	if len(s.jobs) <= s.maxJobs {
+		return
+	}
+	finished := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if job.Finished() {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].CreatedAt.Before(finished[j].CreatedAt) })
+	for _, job := range finished {
+		if len(s.jobs) <= s.maxJobs {
+			break
+		}
+		delete(s.jobs, job.ID)
+	}
+}
+
+// load reads persisted jobs, failing those interrupted by a restart.
+func (s *JobStore) load() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read jobs file: %w", err)
+	}
+	var file jobFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal jobs: %w", err)
+	}
+
+	s.mu.Lock()
+	var interrupted []Job
+	for _, job := range file.Jobs {
+		if job.Steps == nil {
+			job.Steps = []JobStep{}
+		}
+		s.jobs[job.ID] = job
+		if !job.Finished() {
+			s.complete(job, JobFailed, "", "interrupted by gateway restart")
+			interrupted = append(interrupted, job.clone())
+		}
+	}
+	s.save()
+	s.mu.Unlock()
+
+	for _, job := range interrupted {
+		s.deliver(job)
+	}
+	return nil
+}
+
+// save writes all jobs to disk atomically. s.mu must be held.
+func (s *JobStore) save() {
+	if s.path == "" {
+		return
+	}
+	file := jobFile{Version: 1, Jobs: make([]*Job, 0, len(s.jobs))}
+	for _, job := range s.jobs {
+		file.Jobs = append(file.Jobs, job)
+	}
+	sort.Slice(file.Jobs, func(i, j int) bool { return file.Jobs[i].CreatedAt.Before(file.Jobs[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		log.Printf("api: failed to marshal jobs: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		log.Printf("api: failed to create jobs directory: %v", err)
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Printf("api: failed to write jobs: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		log.Printf("api: failed to save jobs: %v", err)
+	}
+}
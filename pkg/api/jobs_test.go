@@ -0,0 +1,275 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+)
+
+// blockingDispatcher runs until released or cancelled; async runs report
+// one tool call.
+type blockingDispatcher struct {
+	mockDispatcher
+	release chan struct{}
+}
+
+func (b *blockingDispatcher) ProcessDirectWithEvents(
+	ctx context.Context,
+	_, _, _, _ string,
+	onEvent func(bus.AgentEvent),
+) (string, error) {
+	onEvent(bus.AgentEvent{Kind: bus.EventToolStart, Tool: "exec", Content: "ls"})
+	select {
+	case <-b.release:
+		return "all done", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (b *blockingDispatcher) ProcessDirectWithChannel(ctx context.Context, _, _, _, _ string) (string, error) {
+	select {
+	case <-b.release:
+		return "all done", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func waitForJob(t *testing.T, store *JobStore, id string, done func(Job) bool) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := store.Get(id); ok && done(job) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	job, _ := store.Get(id)
+	t.Fatalf("job did not reach expected state: %+v", job)
+	return job
+}
+
+func startAsync(t *testing.T, mux *http.ServeMux, body string) jobAccepted {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/dispatch?async=true", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var accepted jobAccepted
+	if err := json.NewDecoder(rec.Body).Decode(&accepted); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return accepted
+}
+
+func TestAsyncDispatch_PollAndSteps(t *testing.T) {
+	d := &blockingDispatcher{release: make(chan struct{})}
+	h := NewHandlers(d)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	accepted := startAsync(t, mux, `{"content":"list files"}`)
+	if accepted.Status != JobRunning || accepted.StatusURL != "/api/jobs/"+accepted.JobID {
+		t.Fatalf("unexpected accepted response: %+v", accepted)
+	}
+
+	waitForJob(t, h.jobs, accepted.JobID, func(j Job) bool { return len(j.Steps) == 1 })
+	close(d.release)
+	waitForJob(t, h.jobs, accepted.JobID, func(j Job) bool { return j.Finished() })
+
+	req := httptest.NewRequest(http.MethodGet, accepted.StatusURL, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var job Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if job.Status != JobSucceeded || job.Output != "all done" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if job.Steps[0].Tool != "exec" || job.SessionKey != "api:dispatch" {
+		t.Errorf("unexpected steps or session: %+v", job)
+	}
+}
+
+func TestAsyncDispatch_Cancel(t *testing.T) {
+	d := &blockingDispatcher{release: make(chan struct{})}
+	mux := newTestMux(d)
+	accepted := startAsync(t, mux, `{"content":"slow"}`)
+
+	req := httptest.NewRequest(http.MethodDelete, accepted.StatusURL, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	// Cancelling again conflicts.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, accepted.StatusURL, nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+	var job Job
+	json.NewDecoder(rec.Body).Decode(&job)
+	if job.Status != JobCanceled {
+		t.Errorf("expected canceled, got %q", job.Status)
+	}
+}
+
+func TestAsyncDispatch_UnknownJob(t *testing.T) {
+	mux := newTestMux(&mockDispatcher{})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestAsyncDispatch_RejectsBadCallback(t *testing.T) {
+	mux := newTestMux(&mockDispatcher{})
+	for _, path := range []string{"/api/dispatch?async=true", "/api/dispatch"} {
+		body := `{"content":"hi","callback_url":"file:///etc/passwd"}`
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rec.Code)
+		}
+	}
+
+	// Private addresses are refused unless explicitly allowed.
+	for _, target := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest", "http://[::1]/hook"} {
+		body := `{"content":"hi","callback_url":"` + target + `"}`
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/dispatch?async=true", bytes.NewBufferString(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+	store := NewJobStore("", 0, "", true)
+	if err := store.CheckCallbackURL(t.Context(), "http://127.0.0.1:8080/hook"); err != nil {
+		t.Errorf("private callback refused with allowPrivate: %v", err)
+	}
+}
+
+func TestJobStore_SignedCallback(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	store := NewJobStore("", 0, "s3cret", true)
+	fail := func(context.Context, func(bus.AgentEvent)) (string, error) { return "", errors.New("boom") }
+	store.Start("", dispatchRequest{Content: "hi", CallbackURL: server.URL}, fail, nil)
+
+	var r *http.Request
+	select {
+	case r = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback not delivered")
+	}
+	body := <-bodies
+	want := "sha256=" + signCallback("s3cret", r.Header.Get("X-TinyClaw-Timestamp"), body)
+	if got := r.Header.Get("X-TinyClaw-Signature"); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	var job Job
+	if err := json.Unmarshal(body, &job); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if job.Status != JobFailed || job.Error != "boom" {
+		t.Errorf("unexpected callback job: %+v", job)
+	}
+	waitForJob(t, store, job.ID, func(j Job) bool { return j.CallbackStatus == CallbackDelivered })
+}
+
+func TestJobStore_PersistsAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	store := NewJobStore(path, 0, "", false)
+
+	quick := func(context.Context, func(bus.AgentEvent)) (string, error) { return "result", nil }
+	done := store.Start("", dispatchRequest{Content: "quick"}, quick, nil)
+	waitForJob(t, store, done.ID, func(j Job) bool { return j.Finished() })
+
+	hang := make(chan struct{})
+	slow := func(context.Context, func(bus.AgentEvent)) (string, error) {
+		<-hang
+		return "", nil
+	}
+	running := store.Start("", dispatchRequest{Content: "slow"}, slow, nil)
+	// Let the slow job finish saving before the temp dir is removed.
+	t.Cleanup(func() {
+		close(hang)
+		waitForJob(t, store, running.ID, func(j Job) bool { return j.Finished() })
+	})
+
+	reloaded := NewJobStore(path, 0, "", false)
+	if job, ok := reloaded.Get(done.ID); !ok || job.Output != "result" {
+		t.Errorf("finished job not restored: %+v", job)
+	}
+	job, ok := reloaded.Get(running.ID)
+	if !ok || job.Status != JobFailed || job.Error != "interrupted by gateway restart" {
+		t.Errorf("running job not marked interrupted: %+v", job)
+	}
+}
+
+func TestJobStore_SavesStepsOnStatusChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	store := NewJobStore(path, 0, "", false)
+
+	stepped := make(chan struct{})
+	release := make(chan struct{})
+	run := func(_ context.Context, onEvent func(bus.AgentEvent)) (string, error) {
+		onEvent(bus.AgentEvent{Kind: bus.EventToolStart, Tool: "exec"})
+		close(stepped)
+		<-release
+		return "done", nil
+	}
+	job := store.Start("", dispatchRequest{Content: "work"}, run, nil)
+	<-stepped
+
+	// Steps alone do not rewrite the file.
+	if got, _ := NewJobStore(path, 0, "", false).Get(job.ID); len(got.Steps) != 0 {
+		t.Errorf("steps saved before status change: %+v", got.Steps)
+	}
+
+	close(release)
+	waitForJob(t, store, job.ID, func(j Job) bool { return j.Finished() })
+	if got, _ := NewJobStore(path, 0, "", false).Get(job.ID); len(got.Steps) != 1 || got.Output != "done" {
+		t.Errorf("finished job not saved with its steps: %+v", got)
+	}
+}
+
+func TestJobStore_PrunesOldestFinished(t *testing.T) {
+	store := NewJobStore("", 2, "", false)
+	run := func(context.Context, func(bus.AgentEvent)) (string, error) { return "", nil }
+
+	first := store.Start("", dispatchRequest{}, run, nil)
+	waitForJob(t, store, first.ID, func(j Job) bool { return j.Finished() })
+	second := store.Start("", dispatchRequest{}, run, nil)
+	waitForJob(t, store, second.ID, func(j Job) bool { return j.Finished() })
+	store.Start("", dispatchRequest{}, run, nil)
+
+	if _, ok := store.Get(first.ID); ok {
+		t.Error("expected oldest job to be pruned")
+	}
+	if _, ok := store.Get(second.ID); !ok {
+		t.Error("expected newer job to be kept")
+	}
+}
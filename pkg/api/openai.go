@@ -0,0 +1,336 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+)
+
+// SessionHeader names the header that keeps an OpenAI-compatible
+// conversation in a server-side session; without it requests are stateless.
+// Sessions are private to the API key that created them.
+const SessionHeader = "X-Session-Id"
+
+const (
+	modelPrefix       = "tinyclaw/"
+	streamKeepAlive   = 15 * time.Second
+	completionTimeout = 10 * time.Minute
+)
+
+// ChatCompleter runs chat requests through an agent. Dispatchers that
+// implement it also serve the OpenAI-compatible /v1 endpoints.
+type ChatCompleter interface {
+	ListAgentIDs() []string
+	ProcessChat(
+		ctx context.Context,
+		agentID, sessionKey string,
+		messages []providers.Message,
+		onEvent func(bus.AgentEvent),
+	) (string, providers.UsageInfo, error)
+}
+
+type chatCompletionRequest struct {
+	Model         string        `json:"model"`
+	Messages      []chatMessage `json:"messages"`
+	Stream        bool          `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the message content, which may be a string or an array of
+// content parts; only text parts are kept.
+func (m chatMessage) text() string {
+	var s string
+	if json.Unmarshal(m.Content, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(m.Content, &parts) != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type completionChoice struct {
+	Index        int             `json:"index"`
+	Message      *completionText `json:"message,omitempty"`
+	Delta        *completionText `json:"delta,omitempty"`
+	FinishReason *string         `json:"finish_reason"`
+}
+
+type completionText struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type chatCompletion struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []completionChoice `json:"choices"`
+	Usage   *openAIUsage       `json:"usage,omitempty"`
+}
+
+type openAIError struct {
+	Error openAIErrorBody `json:"error"`
+}
+
+type openAIErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, errType, code, message string) {
+	writeJSON(w, status, openAIError{Error: openAIErrorBody{Message: message, Type: errType, Code: code}})
+}
+
+// resolveModel maps a model name to an agent ID. "tinyclaw" and an empty
+// name select the default agent; "tinyclaw/<id>" and "<id>" select agent id.
+func resolveModel(model string, agentIDs []string) (string, bool) {
+	if model == "" || model == "tinyclaw" {
+		return "", true
+	}
+	id := strings.TrimPrefix(model, modelPrefix)
+	for _, known := range agentIDs {
+		if strings.EqualFold(known, id) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+func (h *Handlers) handleModels(w http.ResponseWriter, r *http.Request) {
+	ids := h.chat.ListAgentIDs()
+	models := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		if !allowsAgent(r.Context(), id) {
+			continue
+		}
+		models = append(models, map[string]any{
+			"id":       id,
+			"object":   "model",
+			"created":  h.started.Unix(),
+			"owned_by": "tinyclaw",
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": models})
+}
+
+func (h *Handlers) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", "invalid request body")
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", "messages is required")
+		return
+	}
+	agentID, ok := resolveModel(req.Model, h.chat.ListAgentIDs())
+	if !ok {
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("The model %q does not exist", req.Model))
+		return
+	}
+	if id := cmp.Or(agentID, h.defaultAgentID()); !allowsAgent(r.Context(), id) {
+		writeOpenAIError(w, http.StatusForbidden, "permission_error", "",
+			fmt.Sprintf("API key not allowed to use model %q", req.Model))
+		return
+	}
+
+	messages := make([]providers.Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, providers.Message{Role: m.Role, Content: m.text()})
+	}
+	if messages[len(messages)-1].Role != "user" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", "the last message must be from the user")
+		return
+	}
+
+	sessionKey := ""
+	if id := r.Header.Get(SessionHeader); id != "" {
+		sessionKey = sessionKeyFor(r.Context(), id)
+	}
+	model := req.Model
+	if model == "" {
+		model = "tinyclaw"
+	}
+	completion := chatCompletion{
+		ID:      "chatcmpl-" + uuid.NewString(),
+		Created: time.Now().Unix(),
+		Model:   model,
+	}
+
+	// Best-effort, as in handleDispatch: agent runs can take minutes.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(completionTimeout))
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		h.streamCompletion(r.Context(), w, rc, completion, agentID, sessionKey, messages, includeUsage)
+		return
+	}
+
+	content, usage, err := h.chat.ProcessChat(r.Context(), agentID, sessionKey, messages, nil)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "", err.Error())
+		return
+	}
+	stop := "stop"
+	completion.Object = "chat.completion"
+	completion.Choices = []completionChoice{{
+		Message:      &completionText{Role: "assistant", Content: content},
+		FinishReason: &stop,
+	}}
+	completion.Usage = &openAIUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// streamCompletion answers with server-sent events. Reply text is sent as
+// content chunks as the provider streams it; providers that return whole
+// responses send it as one chunk at the end. Text written before a tool
+// call stays in the reply, separated from what follows by a blank line.
+// Tool activity and keep-alives are sent as SSE comments, which clients
+// ignore.
+func (h *Handlers) streamCompletion(
+	ctx context.Context,
+	w http.ResponseWriter,
+	rc *http.ResponseController,
+	completion chatCompletion,
+	agentID, sessionKey string,
+	messages []providers.Message,
+	includeUsage bool,
+) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	var mu sync.Mutex
+	write := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(w, s)
+		_ = rc.Flush()
+	}
+	completion.Object = "chat.completion.chunk"
+	chunk := func(c chatCompletion) {
+		data, _ := json.Marshal(c)
+		write("data: " + string(data) + "\n\n")
+	}
+	withChoice := func(delta completionText, finish *string) chatCompletion {
+		c := completion
+		c.Choices = []completionChoice{{Delta: &delta, FinishReason: finish}}
+		return c
+	}
+
+	chunk(withChoice(completionText{Role: "assistant"}, nil))
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(streamKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				write(": keep-alive\n\n")
+			}
+		}
+	}()
+
+	var (
+		textMu sync.Mutex
+		tail   strings.Builder // text streamed since the last tool call
+		sent   bool            // whether any text was streamed
+	)
+	sendText := func(text string) {
+		if tail.Len() == 0 && sent {
+			text = "\n\n" + text
+		}
+		chunk(withChoice(completionText{Content: text}, nil))
+		sent = true
+	}
+	onEvent := func(ev bus.AgentEvent) {
+		textMu.Lock()
+		defer textMu.Unlock()
+		switch ev.Kind {
+		case bus.EventDelta:
+			sendText(ev.Content)
+			tail.WriteString(ev.Content)
+		case bus.EventToolStart, bus.EventToolEnd:
+			tail.Reset()
+			write(fmt.Sprintf(": %s %s\n\n", ev.Kind, ev.Tool))
+		}
+	}
+	content, usage, err := h.chat.ProcessChat(ctx, agentID, sessionKey, messages, onEvent)
+	close(done)
+	textMu.Lock()
+	defer textMu.Unlock()
+
+	if err != nil {
+		data, _ := json.Marshal(openAIError{Error: openAIErrorBody{Message: err.Error(), Type: "server_error"}})
+		write("data: " + string(data) + "\n\n")
+		write("data: [DONE]\n\n")
+		return
+	}
+
+	// Send whatever of the reply was not streamed.
+	if rest, ok := strings.CutPrefix(content, tail.String()); !ok {
+		tail.Reset()
+		sendText(content)
+	} else if rest != "" {
+		sendText(rest)
+	}
+	stop := "stop"
+	chunk(withChoice(completionText{}, &stop))
+	if includeUsage {
+		c := completion
+		c.Choices = []completionChoice{}
+		c.Usage = &openAIUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+		chunk(c)
+	}
+	write("data: [DONE]\n\n")
+}
@@ -3,12 +3,15 @@ package bus
 import (
 	"context"
 	"sync"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
 )
 
 type MessageBus struct {
 	inbound  chan InboundMessage
 	outbound chan OutboundMessage
 	handlers map[string]MessageHandler
+	events   map[string]EventHandler
 	closed   bool
 	mu       sync.RWMutex
 }
@@ -18,6 +21,7 @@ func NewMessageBus() *MessageBus {
 		inbound:  make(chan InboundMessage, 100),
 		outbound: make(chan OutboundMessage, 100),
 		handlers: make(map[string]MessageHandler),
+		events:   make(map[string]EventHandler),
 	}
 }
 
@@ -27,6 +31,7 @@ func (mb *MessageBus) PublishInbound(msg InboundMessage) {
 	if mb.closed {
 		return
 	}
+	metrics.MessagesInbound.Inc(msg.Channel)
 	mb.inbound <- msg
 }
 
@@ -45,6 +50,7 @@ func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
 	if mb.closed {
 		return
 	}
+	metrics.MessagesOutbound.Inc(msg.Channel)
 	mb.outbound <- msg
 }
 
@@ -57,6 +63,12 @@ func (mb *MessageBus) SubscribeOutbound(ctx context.Context) (OutboundMessage, b
 	}
 }
 
+// QueueDepths returns how many messages are waiting in the inbound and
+// outbound queues.
+func (mb *MessageBus) QueueDepths() (inbound, outbound int) {
+	return len(mb.inbound), len(mb.outbound)
+}
+
 func (mb *MessageBus) RegisterHandler(channel string, handler MessageHandler) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
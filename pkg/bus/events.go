@@ -0,0 +1,57 @@
+package bus
+
+// Agent event kinds.
+const (
+	EventThinking  = "thinking"
+	EventToolStart = "tool_start"
+	EventToolEnd   = "tool_end"
+	EventText      = "text"
+	EventDelta     = "delta"
+)
+
+// AgentEvent reports progress while the agent works on a message: tool
+// calls starting and finishing, text produced between tool calls, and,
+// for providers that stream, pieces of reply text as they arrive. The
+// final reply still arrives as an OutboundMessage.
+type AgentEvent struct {
+	Channel string `json:"channel"`
+	ChatID  string `json:"chat_id"`
+	Kind    string `json:"kind"`
+	Tool    string `json:"tool,omitempty"`
+	// Content is the interim text for EventText, the new piece of text for
+	// EventDelta and an argument preview for EventToolStart.
+	Content string `json:"content,omitempty"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// EventHandler receives agent events. It is called synchronously from the
+// agent loop and must not block.
+type EventHandler func(AgentEvent)
+
+// SubscribeEvents registers handler for events on channel, replacing any
+// earlier one.
+func (mb *MessageBus) SubscribeEvents(channel string, handler EventHandler) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.events[channel] = handler
+}
+
+// HasEventSubscriber reports whether anything listens for events on
+// channel, so callers can skip work nobody will see.
+func (mb *MessageBus) HasEventSubscriber(channel string) bool {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	_, ok := mb.events[channel]
+	return ok && !mb.closed
+}
+
+// PublishEvent hands ev to the subscriber for its channel, if any.
+func (mb *MessageBus) PublishEvent(ev AgentEvent) {
+	mb.mu.RLock()
+	handler, ok := mb.events[ev.Channel]
+	closed := mb.closed
+	mb.mu.RUnlock()
+	if ok && !closed {
+		handler(ev)
+	}
+}
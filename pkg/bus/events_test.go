@@ -0,0 +1,33 @@
+package bus
+
+import "testing"
+
+func TestPublishEvent(t *testing.T) {
+	mb := NewMessageBus()
+
+	// Without a subscriber events are dropped.
+	mb.PublishEvent(AgentEvent{Channel: "web", Kind: EventThinking})
+
+	if mb.HasEventSubscriber("web") {
+		t.Fatal("HasEventSubscriber before SubscribeEvents")
+	}
+
+	var got []AgentEvent
+	mb.SubscribeEvents("web", func(ev AgentEvent) { got = append(got, ev) })
+	mb.PublishEvent(AgentEvent{Channel: "web", ChatID: "s1", Kind: EventToolStart, Tool: "exec"})
+	mb.PublishEvent(AgentEvent{Channel: "telegram", ChatID: "1", Kind: EventToolStart})
+
+	if len(got) != 1 || got[0].Tool != "exec" || got[0].ChatID != "s1" {
+		t.Fatalf("got %+v, want one exec event for s1", got)
+	}
+
+	if !mb.HasEventSubscriber("web") || mb.HasEventSubscriber("telegram") {
+		t.Fatal("HasEventSubscriber does not match subscriptions")
+	}
+
+	mb.Close()
+	mb.PublishEvent(AgentEvent{Channel: "web", Kind: EventText})
+	if len(got) != 1 {
+		t.Fatalf("event delivered after Close: %+v", got)
+	}
+}
@@ -0,0 +1,114 @@
+package bus
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment kinds returned by Attachment.Kind.
+const (
+	KindImage = "image"
+	KindVideo = "video"
+	KindAudio = "audio"
+	KindFile  = "file"
+)
+
+// IsRich reports whether the message uses anything beyond plain text.
+func (m OutboundMessage) IsRich() bool {
+	return len(m.Media) > 0 || len(m.Buttons) > 0 || m.ReplyTo != "" || m.ThreadID != "" || m.Silent
+}
+
+// PlainText renders the message for channels that only deliver text:
+// remote attachments become links, local ones are named, and buttons are
+// listed as replies the user can type.
+func (m OutboundMessage) PlainText() string {
+	var sb strings.Builder
+	sb.WriteString(m.Content)
+	newline := func() {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+	}
+
+	for _, a := range m.Media {
+		newline()
+		if a.Caption != "" {
+			fmt.Fprintf(&sb, "%s: ", a.Caption)
+		}
+		if a.URL != "" {
+			sb.WriteString(a.URL)
+		} else {
+			fmt.Fprintf(&sb, "[attachment: %s]", a.Name())
+		}
+	}
+
+	var options []string
+	for _, b := range m.Buttons {
+		if b.URL != "" {
+			newline()
+			fmt.Fprintf(&sb, "%s: %s", b.Text, b.URL)
+			continue
+		}
+		options = append(options, b.Text)
+	}
+	if len(options) > 0 {
+		newline()
+		sb.WriteString("Reply with: ")
+		sb.WriteString(strings.Join(options, " / "))
+	}
+	return sb.String()
+}
+
+// Reply returns the text sent back when the button is pressed.
+func (b Button) Reply() string {
+	if b.Value != "" {
+		return b.Value
+	}
+	return b.Text
+}
+
+// Name returns the attachment's file name, derived from Path or URL when
+// Filename is not set.
+func (a Attachment) Name() string {
+	switch {
+	case a.Filename != "":
+		return a.Filename
+	case a.Path != "":
+		return filepath.Base(a.Path)
+	case a.URL != "":
+		if u, err := url.Parse(a.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+			return path.Base(u.Path)
+		}
+	}
+	return "file"
+}
+
+// ContentType returns MimeType, or a type guessed from the file name.
+func (a Attachment) ContentType() string {
+	if a.MimeType != "" {
+		return a.MimeType
+	}
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Name()))); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// Kind classifies the attachment as KindImage, KindVideo, KindAudio or
+// KindFile, which is how most platforms choose their upload method.
+func (a Attachment) Kind() string {
+	ct := a.ContentType()
+	switch {
+	case strings.HasPrefix(ct, "image/"):
+		return KindImage
+	case strings.HasPrefix(ct, "video/"):
+		return KindVideo
+	case strings.HasPrefix(ct, "audio/"):
+		return KindAudio
+	}
+	return KindFile
+}
@@ -0,0 +1,71 @@
+package bus
+
+import "testing"
+
+func TestOutboundMessage_PlainText(t *testing.T) {
+	msg := OutboundMessage{
+		Content: "Report ready",
+		Media: []Attachment{
+			{URL: "https://example.com/chart.png", Caption: "Chart"},
+			{Path: "/workspace/out/report.pdf"},
+		},
+		Buttons: []Button{
+			{Text: "Yes", Value: "yes please"},
+			{Text: "No"},
+			{Text: "Docs", URL: "https://example.com/docs"},
+		},
+	}
+
+	want := "Report ready\n" +
+		"Chart: https://example.com/chart.png\n" +
+		"[attachment: report.pdf]\n" +
+		"Docs: https://example.com/docs\n" +
+		"Reply with: Yes / No"
+	if got := msg.PlainText(); got != want {
+		t.Errorf("PlainText() =\n%q\nwant\n%q", got, want)
+	}
+
+	if got := (OutboundMessage{Content: "hi"}).PlainText(); got != "hi" {
+		t.Errorf("PlainText() for text-only message = %q, want %q", got, "hi")
+	}
+}
+
+func TestOutboundMessage_IsRich(t *testing.T) {
+	if (OutboundMessage{Content: "hi"}).IsRich() {
+		t.Error("text-only message reported as rich")
+	}
+	if !(OutboundMessage{Content: "hi", ThreadID: "42"}).IsRich() {
+		t.Error("threaded message not reported as rich")
+	}
+}
+
+func TestAttachment_NameAndKind(t *testing.T) {
+	tests := []struct {
+		a        Attachment
+		wantName string
+		wantKind string
+	}{
+		{Attachment{Path: "/tmp/photo.JPG"}, "photo.JPG", KindImage},
+		{Attachment{URL: "https://example.com/media/clip.mp4?sig=abc"}, "clip.mp4", KindVideo},
+		{Attachment{Path: "/tmp/voice", MimeType: "audio/ogg"}, "voice", KindAudio},
+		{Attachment{Path: "/tmp/data.bin", Filename: "export.csv"}, "export.csv", KindFile},
+		{Attachment{URL: "https://example.com/"}, "file", KindFile},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Name(); got != tt.wantName {
+			t.Errorf("Name() = %q, want %q", got, tt.wantName)
+		}
+		if got := tt.a.Kind(); got != tt.wantKind {
+			t.Errorf("Kind() of %q = %q, want %q", tt.wantName, got, tt.wantKind)
+		}
+	}
+}
+
+func TestButton_Reply(t *testing.T) {
+	if got := (Button{Text: "Yes"}).Reply(); got != "Yes" {
+		t.Errorf("Reply() = %q, want %q", got, "Yes")
+	}
+	if got := (Button{Text: "Yes", Value: "/approve 1"}).Reply(); got != "/approve 1" {
+		t.Errorf("Reply() = %q, want %q", got, "/approve 1")
+	}
+}
@@ -10,10 +10,41 @@ type InboundMessage struct {
 	Metadata   map[string]string `json:"metadata,omitempty"`
 }
 
+// OutboundMessage is a message for a channel to deliver. Only Channel,
+// ChatID and Content are required; channels honor the other fields where
+// the platform supports them and fall back to PlainText otherwise.
 type OutboundMessage struct {
-	Channel string `json:"channel"`
-	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
+	Channel string       `json:"channel"`
+	ChatID  string       `json:"chat_id"`
+	Content string       `json:"content"`
+	Media   []Attachment `json:"media,omitempty"`
+	// ReplyTo is the platform message ID to reply to (the inbound
+	// "message_id" or "message_ts" metadata).
+	ReplyTo string `json:"reply_to,omitempty"`
+	// ThreadID is the thread, topic or conversation to post in.
+	ThreadID string   `json:"thread_id,omitempty"`
+	Buttons  []Button `json:"buttons,omitempty"`
+	// Silent asks the platform to deliver without a notification.
+	Silent bool `json:"silent,omitempty"`
+}
+
+// Attachment is a file sent with an outbound message, either a local file
+// (Path, absolute) or a remote one (URL).
+type Attachment struct {
+	Path     string `json:"path,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+}
+
+// Button is a quick-reply option. Pressing it sends Value (or Text when
+// Value is empty) back as if the user had typed it; a button with a URL
+// opens the link instead.
+type Button struct {
+	Text  string `json:"text"`
+	Value string `json:"value,omitempty"`
+	URL   string `json:"url,omitempty"`
 }
 
 type MessageHandler func(InboundMessage) error
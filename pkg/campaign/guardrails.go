@@ -49,7 +49,7 @@ func CanExecuteTool(exec *Execution, toolName string) bool {
 	// Read-only mode: deny write operations
 	if g.ReadOnly {
 		switch toolName {
-		case "write_file", "exec_command", "delete_file":
+		case "write_file", "edit_file", "append_file", "apply_patch", "move_file", "delete_file", "exec_command":
 			return false
 		}
 	}
@@ -148,6 +148,9 @@ func TestCanExecuteTool_ReadOnly(t *testing.T) {
 	if CanExecuteTool(exec, "exec_command") {
 		t.Error("expected exec_command to be denied in read-only mode")
 	}
+	if CanExecuteTool(exec, "apply_patch") {
+		t.Error("expected apply_patch to be denied in read-only mode")
+	}
 }
 
 func TestCanExecuteTool_KillSwitch(t *testing.T) {
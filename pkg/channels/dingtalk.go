@@ -121,7 +121,7 @@ func (c *DingTalkChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	})
 
 	// Use the session webhook to send the reply
-	return c.SendDirectReply(ctx, sessionWebhook, msg.Content)
+	return c.SendDirectReply(ctx, sessionWebhook, msg.PlainText())
 }
 
 // onChatBotMessageReceived implements the IChatBotMessageHandler function signature
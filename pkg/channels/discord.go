@@ -1,6 +1,7 @@
 package channels
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -34,6 +35,8 @@ type DiscordChannel struct {
 	typingMu    sync.Mutex
 	typingStop  map[string]chan struct{} // chatID → stop signal
 	botUserID   string                   // stored for mention checking
+
+	buttonReplies sync.Map // custom_id key -> button reply too long for custom_id
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -78,6 +81,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 	c.botUserID = botUser.ID
 
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(c.handleInteraction)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -120,19 +124,43 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	}
 
 	channelID := msg.ChatID
+	if msg.ThreadID != "" {
+		// Threads are channels of their own in Discord.
+		channelID = msg.ThreadID
+	}
 	if channelID == "" {
 		return errors.New("channel ID is empty")
 	}
 
-	runes := []rune(msg.Content)
-	if len(runes) == 0 {
+	if msg.Content == "" && len(msg.Media) == 0 && len(msg.Buttons) == 0 {
 		return nil
 	}
 
 	chunks := utils.SplitMessage(msg.Content, 2000) // Split messages into chunks, Discord length limit: 2000 chars
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
 
-	for _, chunk := range chunks {
-		if err := c.sendChunk(ctx, channelID, chunk); err != nil {
+	var flags discordgo.MessageFlags
+	if msg.Silent {
+		flags = discordgo.MessageFlagsSuppressNotifications
+	}
+
+	for i, chunk := range chunks {
+		send := &discordgo.MessageSend{Content: chunk, Flags: flags}
+		if i == 0 && msg.ReplyTo != "" {
+			send.Reference = &discordgo.MessageReference{MessageID: msg.ReplyTo, ChannelID: channelID}
+		}
+		// Files and buttons go with the last chunk so they follow the text.
+		if i == len(chunks)-1 {
+			files, err := discordFiles(ctx, msg.Media)
+			if err != nil {
+				return err
+			}
+			send.Files = files
+			send.Components = c.discordComponents(msg.Buttons)
+		}
+		if err := c.sendComplex(ctx, channelID, send); err != nil {
 			return err
 		}
 	}
@@ -140,14 +168,14 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	return nil
 }
 
-func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) error {
+func (c *DiscordChannel) sendComplex(ctx context.Context, channelID string, send *discordgo.MessageSend) error {
 	// Use the passed ctx for timeout control
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
 	defer cancel()
 
 	done := make(chan error, 1)
 	go func() {
-		_, err := c.session.ChannelMessageSend(channelID, content)
+		_, err := c.session.ChannelMessageSendComplex(channelID, send)
 		done <- err
 	}()
 
@@ -162,6 +190,103 @@ func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content strin
 	}
 }
 
+func discordFiles(ctx context.Context, media []bus.Attachment) ([]*discordgo.File, error) {
+	files := make([]*discordgo.File, 0, len(media))
+	for _, a := range media {
+		data, err := loadAttachment(ctx, a)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", a.Name(), err)
+		}
+		files = append(files, &discordgo.File{
+			Name:        a.Name(),
+			ContentType: a.ContentType(),
+			Reader:      bytes.NewReader(data),
+		})
+	}
+	return files, nil
+}
+
+// Discord component limits: custom_id length, buttons per row and rows.
+const (
+	discordCustomIDLimit = 100
+	discordRowButtons    = 5
+	discordMaxRows       = 5
+)
+
+func (c *DiscordChannel) discordComponents(buttons []bus.Button) []discordgo.MessageComponent {
+	var rows []discordgo.MessageComponent
+	var row discordgo.ActionsRow
+	for _, b := range buttons {
+		btn := discordgo.Button{Label: b.Text, Style: discordgo.PrimaryButton}
+		if b.URL != "" {
+			btn.Style = discordgo.LinkButton
+			btn.URL = b.URL
+		} else {
+			btn.CustomID = buttonReplyKey(b.Reply(), discordCustomIDLimit, &c.buttonReplies)
+		}
+		row.Components = append(row.Components, btn)
+		if len(row.Components) == discordRowButtons {
+			rows = append(rows, row)
+			row = discordgo.ActionsRow{}
+		}
+	}
+	if len(row.Components) > 0 {
+		rows = append(rows, row)
+	}
+	if len(rows) > discordMaxRows {
+		rows = rows[:discordMaxRows]
+	}
+	return rows
+}
+
+// handleInteraction turns a button press into a user message.
+func (c *DiscordChannel) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i == nil || i.Interaction == nil || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil || !c.IsAllowed(user.ID) {
+		return
+	}
+
+	// Acknowledge so Discord does not show the interaction as failed.
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	content := buttonReply(i.MessageComponentData().CustomID, &c.buttonReplies)
+	if content == "" {
+		return
+	}
+
+	peerKind := "channel"
+	peerID := i.ChannelID
+	if i.GuildID == "" {
+		peerKind = "direct"
+		peerID = user.ID
+	}
+
+	metadata := map[string]string{
+		"user_id":     user.ID,
+		"username":    user.Username,
+		"guild_id":    i.GuildID,
+		"channel_id":  i.ChannelID,
+		"is_dm":       strconv.FormatBool(i.GuildID == ""),
+		"is_callback": "true",
+		"peer_kind":   peerKind,
+		"peer_id":     peerID,
+	}
+	if i.Message != nil {
+		metadata["message_id"] = i.Message.ID
+	}
+
+	c.HandleMessage(user.ID, i.ChannelID, content, nil, metadata)
+}
+
 // appendContent safely appends content to existing text
 func appendContent(content, suffix string) string {
 	if content == "" {
@@ -0,0 +1,685 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+const (
+	emailRetryDelay   = 30 * time.Second
+	emailDefaultPoll  = 60 * time.Second
+	emailMaxIdle      = 25 * time.Minute // RFC 2177 asks clients to re-IDLE within 29 minutes
+	emailSMTPTimeout  = 2 * time.Minute
+	emailDefaultTopic = "Message from tinyclaw"
+)
+
+var (
+	emailMessageIDRe = regexp.MustCompile(`<[^<>\s]+>`)
+	emailTagRe       = regexp.MustCompile(`(?s)<[^>]*>`)
+	emailBreakRe     = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</h[1-6]>`)
+)
+
+// EmailChannel reads mail from an IMAP mailbox and replies over SMTP.
+// Each email thread is its own conversation: the chat ID is
+// "<sender address>/<root Message-ID>".
+type EmailChannel struct {
+	*BaseChannel
+
+	config       config.EmailConfig
+	fromAddress  string
+	pollInterval time.Duration
+	threads      sync.Map // chatID -> emailThread
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// emailThread is what a reply needs to stay in the sender's thread.
+type emailThread struct {
+	subject    string
+	messageID  string // latest message from the sender
+	references []string
+}
+
+// NewEmailChannel creates an email channel from cfg.
+func NewEmailChannel(cfg config.EmailConfig, messageBus *bus.MessageBus) (*EmailChannel, error) {
+	if cfg.IMAPServer == "" || cfg.SMTPServer == "" {
+		return nil, errors.New("email imap_server and smtp_server are required")
+	}
+	if cfg.Username == "" || cfg.Password == "" {
+		return nil, errors.New("email username and password are required")
+	}
+	for _, security := range []string{cfg.IMAPSecurity, cfg.SMTPSecurity} {
+		switch security {
+		case "", "tls", "starttls", "none":
+		default:
+			return nil, fmt.Errorf("unknown email security %q (want tls, starttls or none)", security)
+		}
+	}
+
+	from := cfg.From
+	if from == "" {
+		from = cfg.Username
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email from address %q: %w", from, err)
+	}
+	cfg.From = fromAddr.String()
+
+	// Addresses are compared lowercased.
+	allowFrom := make(config.FlexibleStringSlice, 0, len(cfg.AllowFrom))
+	for _, addr := range cfg.AllowFrom {
+		allowFrom = append(allowFrom, strings.ToLower(addr))
+	}
+
+	pollInterval := time.Duration(cfg.PollInterval) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = emailDefaultPoll
+	}
+
+	return &EmailChannel{
+		BaseChannel:  NewBaseChannel("email", cfg, messageBus, allowFrom),
+		config:       cfg,
+		fromAddress:  strings.ToLower(fromAddr.Address),
+		pollInterval: min(pollInterval, emailMaxIdle),
+	}, nil
+}
+
+// Start connects to the mailbox and begins watching for new mail. Unread
+// messages already in the mailbox are processed on the first poll.
+func (c *EmailChannel) Start(ctx context.Context) error {
+	logger.InfoC("email", "Starting email channel")
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	client, err := c.connect(c.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to imap server: %w", err)
+	}
+
+	c.done = make(chan struct{})
+	c.setRunning(true)
+	go c.pollLoop(client)
+
+	logger.InfoCF("email", "Email channel started", map[string]any{
+		"address": c.fromAddress,
+		"mailbox": c.mailbox(),
+	})
+	return nil
+}
+
+// Stop ends the poll loop and logs out.
+func (c *EmailChannel) Stop(ctx context.Context) error {
+	logger.InfoC("email", "Stopping email channel")
+
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.done != nil {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+		}
+	}
+
+	logger.InfoC("email", "Email channel stopped")
+	return nil
+}
+
+// Send replies in the thread identified by msg.ChatID, with the body as
+// plain text plus Markdown rendered to HTML.
+func (c *EmailChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return errors.New("email channel not running")
+	}
+
+	to, root := parseEmailChatID(msg.ChatID)
+	if to == "" {
+		return fmt.Errorf("invalid email chat ID %q", msg.ChatID)
+	}
+
+	draft := emailDraft{
+		from:    c.config.From,
+		to:      to,
+		subject: emailDefaultTopic,
+		text:    bus.OutboundMessage{Content: msg.Content, Buttons: msg.Buttons}.PlainText(),
+	}
+	if root != "" {
+		draft.inReplyTo = root
+		draft.references = []string{root}
+	}
+	if v, ok := c.threads.Load(msg.ChatID); ok {
+		thread := v.(emailThread)
+		draft.subject = replySubject(thread.subject)
+		draft.inReplyTo = thread.messageID
+		draft.references = thread.references
+	}
+	if msg.ReplyTo != "" {
+		draft.inReplyTo = msg.ReplyTo
+	}
+
+	for _, a := range msg.Media {
+		data, err := loadAttachment(ctx, a)
+		if err != nil {
+			return fmt.Errorf("failed to load attachment %s: %w", a.Name(), err)
+		}
+		draft.attachments = append(draft.attachments, emailAttachment{name: a.Name(), contentType: a.ContentType(), data: data})
+		if a.Caption != "" {
+			draft.text += "\n\n" + a.Name() + ": " + a.Caption
+		}
+	}
+
+	data, messageID := draft.build()
+	if err := c.sendSMTP(ctx, to, data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	// Later replies reference this message too.
+	if v, ok := c.threads.Load(msg.ChatID); ok {
+		thread := v.(emailThread)
+		thread.references = append(append([]string(nil), thread.references...), messageID)
+		c.threads.Store(msg.ChatID, thread)
+	}
+
+	logger.DebugCF("email", "Email sent", map[string]any{
+		"to":         to,
+		"message_id": messageID,
+	})
+	return nil
+}
+
+func (c *EmailChannel) mailbox() string {
+	if c.config.Mailbox == "" {
+		return "INBOX"
+	}
+	return c.config.Mailbox
+}
+
+func (c *EmailChannel) connect(ctx context.Context) (*imapClient, error) {
+	client, err := dialIMAP(ctx, c.config.IMAPServer, c.config.IMAPSecurity)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.login(c.config.Username, c.config.Password); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := client.selectMailbox(c.mailbox()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// pollLoop fetches unread mail, then waits with IDLE (or sleeps) until the
+// next poll, reconnecting after errors.
+func (c *EmailChannel) pollLoop(client *imapClient) {
+	defer close(c.done)
+
+	for {
+		if client == nil {
+			var err error
+			if client, err = c.connect(c.ctx); err != nil {
+				if c.ctx.Err() != nil {
+					return
+				}
+				logger.WarnCF("email", "IMAP reconnect failed", map[string]any{
+					"error": err.Error(),
+				})
+				select {
+				case <-c.ctx.Done():
+					return
+				case <-time.After(emailRetryDelay):
+				}
+				continue
+			}
+		}
+
+		err := c.poll(client)
+		if err == nil {
+			err = client.idle(c.ctx, c.pollInterval)
+		}
+		if c.ctx.Err() != nil {
+			client.Close()
+			return
+		}
+		if err != nil {
+			logger.WarnCF("email", "IMAP connection error, reconnecting", map[string]any{
+				"error": err.Error(),
+			})
+			client.Close()
+			client = nil
+		}
+	}
+}
+
+func (c *EmailChannel) poll(client *imapClient) error {
+	uids, err := client.searchUnseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		raw, err := client.fetch(uid)
+		if err != nil {
+			return err
+		}
+		// Mark read before handling so a message that fails is not retried
+		// on every poll.
+		if err := client.markSeen(uid); err != nil {
+			return err
+		}
+		c.handleEmail(raw)
+	}
+	return nil
+}
+
+func (c *EmailChannel) handleEmail(raw []byte) {
+	e, err := parseEmail(raw)
+	if err != nil {
+		logger.WarnCF("email", "Failed to parse email", map[string]any{
+			"error": err.Error(),
+		})
+		return
+	}
+	if e.from == c.fromAddress || e.autoReply {
+		logger.DebugCF("email", "Ignoring own or automatic email", map[string]any{"from": e.from})
+		return
+	}
+	if !c.IsAllowed(e.from) {
+		logger.DebugCF("email", "Email rejected by allowlist", map[string]any{"from": e.from})
+		return
+	}
+
+	root := e.messageID
+	if len(e.references) > 0 {
+		root = e.references[0]
+	} else if e.inReplyTo != "" {
+		root = e.inReplyTo
+	}
+
+	chatID := e.from
+	metadata := map[string]string{
+		"message_id": e.messageID,
+		"subject":    e.subject,
+		"peer_kind":  "direct",
+		"peer_id":    e.from,
+	}
+	if root != "" {
+		chatID += "/" + root
+		// Threads route as group peers so each gets its own session.
+		metadata["thread_id"] = root
+		metadata["peer_kind"] = "group"
+		metadata["peer_id"] = root
+	}
+
+	references := e.references
+	if e.messageID != "" && (len(references) == 0 || references[len(references)-1] != e.messageID) {
+		references = append(references, e.messageID)
+	}
+	c.threads.Store(chatID, emailThread{subject: e.subject, messageID: e.messageID, references: references})
+
+	var content strings.Builder
+	if e.subject != "" {
+		fmt.Fprintf(&content, "Subject: %s\n\n", e.subject)
+	}
+	content.WriteString(e.text)
+
+	var mediaPaths []string
+	for _, a := range e.attachments {
+		// Files stay in the media directory for the agent to read.
+		if localPath := saveMediaFile("email", a.name, a.data); localPath != "" {
+			mediaPaths = append(mediaPaths, localPath)
+		}
+		fmt.Fprintf(&content, "\n[attachment: %s]", a.name)
+	}
+
+	logger.DebugCF("email", "Received email", map[string]any{
+		"from":    e.from,
+		"subject": e.subject,
+		"files":   len(mediaPaths),
+	})
+
+	c.HandleMessage(e.from, chatID, strings.TrimSpace(content.String()), mediaPaths, metadata)
+}
+
+func (c *EmailChannel) sendSMTP(ctx context.Context, to string, data []byte) error {
+	host, _, err := net.SplitHostPort(c.config.SMTPServer)
+	if err != nil {
+		return fmt.Errorf("invalid smtp server %q: %w", c.config.SMTPServer, err)
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	if c.config.SMTPSecurity == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", c.config.SMTPServer)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.config.SMTPServer)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(emailSMTPTimeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if c.config.SMTPSecurity == "starttls" || c.config.SMTPSecurity == "" {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	username, password := c.config.SMTPUsername, c.config.SMTPPassword
+	if username == "" {
+		username, password = c.config.Username, c.config.Password
+	}
+	if ok, _ := client.Extension("AUTH"); ok && password != "" {
+		if err := client.Auth(smtp.PlainAuth("", username, password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.fromAddress); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// parseEmailChatID splits "<address>/<root Message-ID>" into its parts.
+func parseEmailChatID(chatID string) (address, root string) {
+	address, root, _ = strings.Cut(chatID, "/")
+	return address, root
+}
+
+func replySubject(subject string) string {
+	if subject == "" {
+		return emailDefaultTopic
+	}
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+type inboundEmail struct {
+	from        string
+	subject     string
+	messageID   string
+	inReplyTo   string
+	references  []string
+	text        string
+	attachments []emailAttachment
+	autoReply   bool
+}
+
+type emailAttachment struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// parseEmail extracts the sender, threading headers, reply text (without
+// quoted history) and attachments from a raw message.
+func parseEmail(raw []byte) (*inboundEmail, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	from, err := mail.ParseAddress(m.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid From header: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		subject = m.Header.Get("Subject")
+	}
+
+	e := &inboundEmail{
+		from:       strings.ToLower(from.Address),
+		subject:    strings.TrimSpace(subject),
+		messageID:  emailMessageIDRe.FindString(m.Header.Get("Message-Id")),
+		inReplyTo:  emailMessageIDRe.FindString(m.Header.Get("In-Reply-To")),
+		references: emailMessageIDRe.FindAllString(m.Header.Get("References"), -1),
+	}
+	autoSubmitted := strings.ToLower(m.Header.Get("Auto-Submitted"))
+	switch strings.ToLower(m.Header.Get("Precedence")) {
+	case "bulk", "list", "junk":
+		e.autoReply = true
+	default:
+		e.autoReply = autoSubmitted != "" && autoSubmitted != "no"
+	}
+
+	var htmlBody string
+	if err := walkEmailPart(textproto.MIMEHeader(m.Header), m.Body, e, &htmlBody); err != nil {
+		return nil, err
+	}
+	if e.text == "" && htmlBody != "" {
+		e.text = htmlToText(htmlBody)
+	}
+	e.text = stripQuotedReply(e.text)
+	return e, nil
+}
+
+func walkEmailPart(header textproto.MIMEHeader, body io.Reader, e *inboundEmail, htmlBody *string) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkEmailPart(part.Header, part, e, htmlBody); err != nil {
+				return err
+			}
+		}
+	}
+
+	// multipart.Reader decodes quoted-printable parts itself and drops
+	// the header, so this only sees encodings left to handle.
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := dparams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(name); err == nil {
+		name = decoded
+	}
+
+	switch {
+	case disposition == "attachment" || (name != "" && !strings.HasPrefix(mediaType, "text/")):
+		if name == "" {
+			name = "attachment"
+		}
+		e.attachments = append(e.attachments, emailAttachment{name: name, contentType: mediaType, data: data})
+	case mediaType == "text/plain" && e.text == "":
+		e.text = string(data)
+	case mediaType == "text/html" && *htmlBody == "":
+		*htmlBody = string(data)
+	}
+	return nil
+}
+
+// htmlToText is a rough conversion for HTML-only mail.
+func htmlToText(s string) string {
+	s = emailBreakRe.ReplaceAllString(s, "\n")
+	s = emailTagRe.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
+
+// stripQuotedReply drops quoted history and signatures from a reply.
+func stripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || trimmed == "-----Original Message-----" ||
+			(strings.HasPrefix(trimmed, "On ") && strings.HasSuffix(trimmed, "wrote:")) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// emailDraft is an outbound message before MIME encoding.
+type emailDraft struct {
+	from        string
+	to          string
+	subject     string
+	inReplyTo   string
+	references  []string
+	text        string
+	attachments []emailAttachment
+}
+
+// build renders the draft as a MIME message and returns it with its
+// Message-ID.
+func (d emailDraft) build() ([]byte, string) {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(d.from); err == nil {
+		if _, host, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = host
+		}
+	}
+	messageID := "<" + uuid.New().String() + "@" + domain + ">"
+
+	var buf bytes.Buffer
+	header := func(key, value string) { fmt.Fprintf(&buf, "%s: %s\r\n", key, value) }
+	header("From", d.from)
+	header("To", d.to)
+	header("Subject", mime.QEncoding.Encode("utf-8", d.subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	if d.inReplyTo != "" {
+		header("In-Reply-To", d.inReplyTo)
+	}
+	if len(d.references) > 0 {
+		header("References", strings.Join(d.references, " "))
+	}
+	// RFC 3834: lets other responders (and this channel) skip our mail.
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+
+	altType, altBody := emailAlternative(d.text)
+	if len(d.attachments) == 0 {
+		header("Content-Type", altType)
+		buf.WriteString("\r\n")
+		buf.Write(altBody)
+		return buf.Bytes(), messageID
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString("\r\n")
+	part, _ := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {altType}})
+	part.Write(altBody)
+	for _, a := range d.attachments {
+		part, _ := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		encoded := base64.StdEncoding.EncodeToString(a.data)
+		for len(encoded) > 76 {
+			io.WriteString(part, encoded[:76]+"\r\n")
+			encoded = encoded[76:]
+		}
+		io.WriteString(part, encoded+"\r\n")
+	}
+	mixed.Close()
+	return buf.Bytes(), messageID
+}
+
+// emailAlternative renders text as a multipart/alternative body with a
+// plain part and an HTML part converted from Markdown.
+func emailAlternative(text string) (string, []byte) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", markdownToEmailHTML(text)},
+	}
+	for _, p := range parts {
+		part, _ := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qp := quotedprintable.NewWriter(part)
+		io.WriteString(qp, p.body)
+		qp.Close()
+	}
+	w.Close()
+	return "multipart/alternative; boundary=" + w.Boundary(), buf.Bytes()
+}
+
+// markdownToEmailHTML reuses the Telegram converter; pre-wrap keeps the
+// line breaks it leaves in place.
+func markdownToEmailHTML(text string) string {
+	return `<html><body><div style="white-space: pre-wrap">` + markdownToTelegramHTML(text) + "</div></body></html>"
+}
@@ -0,0 +1,278 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+const testInboundEmail = "From: Alice <Alice@Example.com>\r\n" +
+	"To: bot@example.com\r\n" +
+	"Subject: =?utf-8?q?Quarterly_report?=\r\n" +
+	"Message-ID: <m2@example.com>\r\n" +
+	"In-Reply-To: <m1@example.com>\r\n" +
+	"References: <root@example.com> <m1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=XYZ\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Can you summarize the attached file?\r\n" +
+	"\r\n" +
+	"On Mon, Bot wrote:\r\n" +
+	"> earlier reply\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/csv; name=\"numbers.csv\"\r\n" +
+	"Content-Disposition: attachment; filename=\"numbers.csv\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"YSxiCjEsMgo=\r\n" +
+	"--XYZ--\r\n"
+
+// fakeIMAPServer serves one unread message and records flag changes.
+type fakeIMAPServer struct {
+	ln      net.Listener
+	message string
+
+	mu   sync.Mutex
+	seen bool
+}
+
+func (s *fakeIMAPServer) serve(t *testing.T) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(t, conn)
+	}
+}
+
+func (s *fakeIMAPServer) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch {
+		case strings.HasPrefix(command, "LOGIN "):
+			if command != `LOGIN "bot@example.com" "secret"` {
+				fmt.Fprintf(conn, "%s NO bad credentials\r\n", tag)
+				continue
+			}
+		case command == "CAPABILITY":
+			fmt.Fprint(conn, "* CAPABILITY IMAP4rev1 IDLE\r\n")
+		case strings.HasPrefix(command, "SELECT "):
+			fmt.Fprint(conn, "* 1 EXISTS\r\n")
+		case command == "UID SEARCH UNSEEN":
+			s.mu.Lock()
+			if !s.seen {
+				fmt.Fprint(conn, "* SEARCH 7\r\n")
+			} else {
+				fmt.Fprint(conn, "* SEARCH\r\n")
+			}
+			s.mu.Unlock()
+		case command == "UID FETCH 7 (BODY.PEEK[])":
+			fmt.Fprintf(conn, "* 1 FETCH (UID 7 BODY[] {%d}\r\n%s)\r\n", len(s.message), s.message)
+		case command == `UID STORE 7 +FLAGS.SILENT (\Seen)`:
+			s.mu.Lock()
+			s.seen = true
+			s.mu.Unlock()
+		case command == "IDLE":
+			fmt.Fprint(conn, "+ idling\r\n")
+			if done, err := r.ReadString('\n'); err != nil || done != "DONE\r\n" {
+				return
+			}
+		case command == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK LOGOUT completed\r\n", tag)
+			return
+		default:
+			fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+			continue
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+// fakeSMTPServer accepts mail without authentication and hands each
+// message to the messages channel.
+func fakeSMTPServer(t *testing.T, ln net.Listener, messages chan<- string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			tp := textproto.NewConn(conn)
+			defer tp.Close()
+			tp.PrintfLine("220 fake SMTP ready")
+			for {
+				line, err := tp.ReadLine()
+				if err != nil {
+					return
+				}
+				verb, _, _ := strings.Cut(strings.ToUpper(line), " ")
+				switch verb {
+				case "EHLO", "HELO":
+					tp.PrintfLine("250-fake\r\n250 8BITMIME")
+				case "MAIL", "RCPT", "RSET", "NOOP":
+					tp.PrintfLine("250 OK")
+				case "DATA":
+					tp.PrintfLine("354 go ahead")
+					data, err := tp.ReadDotBytes()
+					if err != nil {
+						return
+					}
+					messages <- string(data)
+					tp.PrintfLine("250 queued")
+				case "QUIT":
+					tp.PrintfLine("221 bye")
+					return
+				default:
+					tp.PrintfLine("502 not implemented")
+				}
+			}
+		}()
+	}
+}
+
+func TestNewEmailChannel(t *testing.T) {
+	messageBus := bus.NewMessageBus()
+
+	_, err := NewEmailChannel(config.EmailConfig{Username: "a@b.c", Password: "x"}, messageBus)
+	assert.ErrorContains(t, err, "imap_server and smtp_server are required")
+
+	_, err = NewEmailChannel(config.EmailConfig{
+		IMAPServer: "imap:993", SMTPServer: "smtp:587", Username: "a@b.c", Password: "x", IMAPSecurity: "ssl",
+	}, messageBus)
+	assert.ErrorContains(t, err, "unknown email security")
+
+	ch, err := NewEmailChannel(config.EmailConfig{
+		IMAPServer: "imap:993", SMTPServer: "smtp:587", Username: "Bot@Example.com", Password: "x",
+	}, messageBus)
+	require.NoError(t, err)
+	assert.Equal(t, "email", ch.Name())
+	assert.Equal(t, "bot@example.com", ch.fromAddress)
+}
+
+func TestParseEmail(t *testing.T) {
+	e, err := parseEmail([]byte(testInboundEmail))
+	require.NoError(t, err)
+
+	assert.Equal(t, "alice@example.com", e.from)
+	assert.Equal(t, "Quarterly report", e.subject)
+	assert.Equal(t, "<m2@example.com>", e.messageID)
+	assert.Equal(t, "<m1@example.com>", e.inReplyTo)
+	assert.Equal(t, []string{"<root@example.com>", "<m1@example.com>"}, e.references)
+	assert.Equal(t, "Can you summarize the attached file?", e.text)
+	require.Len(t, e.attachments, 1)
+	assert.Equal(t, "numbers.csv", e.attachments[0].name)
+	assert.Equal(t, "a,b\n1,2\n", string(e.attachments[0].data))
+	assert.False(t, e.autoReply)
+}
+
+func TestParseEmail_HTMLOnlyAndAutoReply(t *testing.T) {
+	raw := "From: bob@example.com\r\n" +
+		"Auto-Submitted: auto-replied\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<p>Out of office</p><p>Back &amp; soon</p>"
+	e, err := parseEmail([]byte(raw))
+	require.NoError(t, err)
+	assert.True(t, e.autoReply)
+	assert.Equal(t, "Out of office\nBack & soon", e.text)
+}
+
+func TestEmailChannel_IMAPAndSMTP(t *testing.T) {
+	imapLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer imapLn.Close()
+	imap := &fakeIMAPServer{ln: imapLn, message: testInboundEmail}
+	go imap.serve(t)
+
+	smtpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer smtpLn.Close()
+	sent := make(chan string, 1)
+	go fakeSMTPServer(t, smtpLn, sent)
+
+	messageBus := bus.NewMessageBus()
+	ch, err := NewEmailChannel(config.EmailConfig{
+		IMAPServer:   imapLn.Addr().String(),
+		IMAPSecurity: "none",
+		SMTPServer:   smtpLn.Addr().String(),
+		SMTPSecurity: "none",
+		Username:     "bot@example.com",
+		Password:     "secret",
+		AllowFrom:    config.FlexibleStringSlice{"ALICE@example.com"},
+	}, messageBus)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, ch.Start(ctx))
+	defer ch.Stop(context.Background())
+
+	msg, ok := messageBus.ConsumeInbound(ctx)
+	require.True(t, ok, "timed out waiting for inbound email")
+	assert.Equal(t, "alice@example.com", msg.SenderID)
+	assert.Equal(t, "alice@example.com/<root@example.com>", msg.ChatID)
+	assert.Equal(t, "Subject: Quarterly report\n\nCan you summarize the attached file?\n[attachment: numbers.csv]", msg.Content)
+	assert.Equal(t, "group", msg.Metadata["peer_kind"])
+	assert.Equal(t, "<root@example.com>", msg.Metadata["peer_id"])
+	require.Len(t, msg.Media, 1)
+	data, err := os.ReadFile(msg.Media[0])
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,2\n", string(data))
+	os.Remove(msg.Media[0])
+
+	imap.mu.Lock()
+	assert.True(t, imap.seen, "message should be marked read")
+	imap.mu.Unlock()
+
+	require.NoError(t, ch.Send(ctx, bus.OutboundMessage{
+		Channel: "email",
+		ChatID:  msg.ChatID,
+		Content: "Totals are **3** and 3.",
+	}))
+
+	var raw string
+	select {
+	case raw = <-sent:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for outbound email")
+	}
+	reply, err := mail.ReadMessage(strings.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", reply.Header.Get("To"))
+	assert.Equal(t, "Re: Quarterly report", reply.Header.Get("Subject"))
+	assert.Equal(t, "<m2@example.com>", reply.Header.Get("In-Reply-To"))
+	assert.Equal(t, "<root@example.com> <m1@example.com> <m2@example.com>", reply.Header.Get("References"))
+	assert.True(t, strings.HasPrefix(reply.Header.Get("Content-Type"), "multipart/alternative"))
+
+	body, err := io.ReadAll(reply.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "Totals are **3** and 3.")
+	assert.Contains(t, string(body), "<b>3</b>")
+}
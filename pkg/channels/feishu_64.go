@@ -99,7 +99,7 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return errors.New("chat ID is empty")
 	}
 
-	payload, err := json.Marshal(map[string]string{"text": msg.Content})
+	payload, err := json.Marshal(map[string]string{"text": msg.PlainText()})
 	if err != nil {
 		return fmt.Errorf("failed to marshal feishu content: %w", err)
 	}
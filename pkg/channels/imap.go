@@ -0,0 +1,280 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const imapCommandTimeout = 2 * time.Minute
+
+// imapClient speaks the subset of IMAP4rev1 (RFC 3501) the email channel
+// needs: LOGIN, SELECT, UID SEARCH/FETCH/STORE and IDLE (RFC 2177).
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+	caps map[string]bool
+}
+
+// imapResponse is one server response line with any literals it carried.
+// Literal contents are replaced by "{N}" in line.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+func dialIMAP(ctx context.Context, addr, security string) (*imapClient, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid imap server %q: %w", addr, err)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var conn net.Conn
+	if security == "tls" || security == "" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(imapCommandTimeout))
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting.line, "* OK") && !strings.HasPrefix(greeting.line, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected imap greeting: %s", greeting.line)
+	}
+
+	if security == "starttls" {
+		if _, err := c.cmd("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		c.conn = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+	}
+	return c, nil
+}
+
+func (c *imapClient) Close() error {
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	c.cmd("LOGOUT")
+	return c.conn.Close()
+}
+
+// readResponse reads one response, following "{N}" literals onto the
+// continuation line.
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	var line strings.Builder
+	for {
+		part, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		line.WriteString(part)
+
+		n, ok := imapLiteralSize(part)
+		if !ok {
+			break
+		}
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+	resp.line = line.String()
+	return resp, nil
+}
+
+// imapLiteralSize reports the size of a literal announced at the end of line.
+func imapLiteralSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[open+1:len(line)-1], "+"))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// cmd runs a command and returns its untagged responses. A tagged NO or
+// BAD is returned as an error.
+func (c *imapClient) cmd(command string) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("T%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(imapCommandTimeout))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, err
+	}
+	return c.readUntilTagged(tag, command)
+}
+
+func (c *imapClient) readUntilTagged(tag, command string) ([]imapResponse, error) {
+	var untagged []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		rest, ok := strings.CutPrefix(resp.line, tag+" ")
+		if !ok {
+			untagged = append(untagged, resp)
+			continue
+		}
+		if !strings.HasPrefix(rest, "OK") {
+			verb, _, _ := strings.Cut(command, " ")
+			return nil, fmt.Errorf("imap %s failed: %s", verb, rest)
+		}
+		return untagged, nil
+	}
+}
+
+func (c *imapClient) login(username, password string) error {
+	if _, err := c.cmd("LOGIN " + imapQuote(username) + " " + imapQuote(password)); err != nil {
+		return err
+	}
+	resps, err := c.cmd("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = make(map[string]bool)
+	for _, resp := range resps {
+		if rest, ok := strings.CutPrefix(resp.line, "* CAPABILITY "); ok {
+			for _, capability := range strings.Fields(rest) {
+				c.caps[strings.ToUpper(capability)] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (c *imapClient) selectMailbox(name string) error {
+	_, err := c.cmd("SELECT " + imapQuote(name))
+	return err
+}
+
+// searchUnseen returns the UIDs of unread messages.
+func (c *imapClient) searchUnseen() ([]uint32, error) {
+	resps, err := c.cmd("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range resps {
+		rest, ok := strings.CutPrefix(resp.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			if uid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the raw RFC 5322 message without marking it read.
+func (c *imapClient) fetch(uid uint32) ([]byte, error) {
+	resps, err := c.cmd(fmt.Sprintf("UID FETCH %d (BODY.PEEK[])", uid))
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range resps {
+		if strings.Contains(resp.line, " FETCH ") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap message %d not returned", uid)
+}
+
+func (c *imapClient) markSeen(uid uint32) error {
+	_, err := c.cmd(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid))
+	return err
+}
+
+// idle waits until the server reports new mail, timeout passes or ctx is
+// done. It falls back to sleeping when the server lacks IDLE.
+func (c *imapClient) idle(ctx context.Context, timeout time.Duration) error {
+	if !c.caps["IDLE"] {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(timeout):
+			return nil
+		}
+	}
+
+	c.tag++
+	tag := fmt.Sprintf("T%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(imapCommandTimeout))
+	if _, err := fmt.Fprintf(c.conn, "%s IDLE\r\n", tag); err != nil {
+		return err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(resp.line, "+") {
+		return fmt.Errorf("imap IDLE refused: %s", resp.line)
+	}
+
+	// Wake the blocked read when ctx is cancelled.
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	stop := context.AfterFunc(ctx, func() { c.conn.SetReadDeadline(time.Now()) })
+	for {
+		resp, err := c.readResponse()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			break
+		}
+		if err != nil {
+			stop()
+			return err
+		}
+		if strings.HasSuffix(resp.line, " EXISTS") {
+			break
+		}
+	}
+	stop()
+
+	c.conn.SetDeadline(time.Now().Add(imapCommandTimeout))
+	if _, err := io.WriteString(c.conn, "DONE\r\n"); err != nil {
+		return err
+	}
+	if _, err := c.readUntilTagged(tag, "IDLE"); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// imapQuote returns s as an IMAP quoted string.
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
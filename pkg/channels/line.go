@@ -3,9 +3,6 @@ package channels
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -46,6 +43,7 @@ type LINEChannel struct {
 
 	config         config.LINEConfig
 	httpServer     *http.Server
+	ingress        *WebhookIngress
 	botUserID      string   // Bot's user ID
 	botBasicID     string   // Bot's basic ID (e.g. @216ru...)
 	botDisplayName string   // Bot's display name for text-based mention detection
@@ -88,30 +86,17 @@ func (c *LINEChannel) Start(ctx context.Context) error {
 		})
 	}
 
-	mux := http.NewServeMux()
-	path := c.config.WebhookPath
-	if path == "" {
-		path = "/webhook/line"
-	}
-	mux.HandleFunc(path, c.webhookHandler)
-
-	addr := fmt.Sprintf("%s:%d", c.config.WebhookHost, c.config.WebhookPort)
-	c.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: mux,
-	}
-
-	go func() {
-		logger.InfoCF("line", "LINE webhook server listening", map[string]any{
-			"addr": addr,
-			"path": path,
-		})
-		if err := c.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.ErrorCF("line", "Webhook server error", map[string]any{
-				"error": err.Error(),
-			})
+	// The gateway serves /webhooks/line; the dedicated listener on
+	// webhook_port (on by default, as before) is skipped when it is set to 0.
+	if c.config.WebhookPort > 0 {
+		path := c.config.WebhookPath
+		if path == "" {
+			path = "/webhook/line"
 		}
-	}()
+		mux := http.NewServeMux()
+		mux.Handle(path, NewWebhookIngress(config.WebhooksConfig{}).Wrap(c))
+		c.httpServer = startWebhookServer("line", fmt.Sprintf("%s:%d", c.config.WebhookHost, c.config.WebhookPort), mux)
+	}
 
 	c.setRunning(true)
 	logger.InfoC("line", "LINE channel started (Webhook Mode)")
@@ -175,6 +160,13 @@ func (c *LINEChannel) Stop(ctx context.Context) error {
 	return nil
 }
 
+// WebhookHandler returns the handler for LINE webhook deliveries, using in
+// for replay protection.
+func (c *LINEChannel) WebhookHandler(in *WebhookIngress) http.Handler {
+	c.ingress = in
+	return http.HandlerFunc(c.webhookHandler)
+}
+
 // webhookHandler handles incoming LINE webhook requests.
 func (c *LINEChannel) webhookHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -182,19 +174,16 @@ func (c *LINEChannel) webhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.ErrorCF("line", "Failed to read request body", map[string]any{
-			"error": err.Error(),
-		})
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	body, ok := readWebhookBody(w, r)
+	if !ok {
+		logger.WarnC("line", "Failed to read request body")
 		return
 	}
 
 	signature := r.Header.Get("X-Line-Signature")
 	if !c.verifySignature(body, signature) {
 		logger.WarnC("line", "Invalid webhook signature")
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		rejectSignature(w, "line")
 		return
 	}
 
@@ -213,30 +202,40 @@ func (c *LINEChannel) webhookHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
 	for _, event := range payload.Events {
+		if c.ingress != nil && event.WebhookEventID != "" {
+			// Redeliveries may legitimately be older than the replay window.
+			var ts time.Time
+			if !event.DeliveryContext.IsRedelivery {
+				ts = time.UnixMilli(event.Timestamp)
+			}
+			if err := c.ingress.CheckReplay("line", event.WebhookEventID, ts); err != nil {
+				logger.DebugCF("line", "Dropping replayed event", map[string]any{
+					"event_id": event.WebhookEventID,
+					"reason":   err.Error(),
+				})
+				continue
+			}
+		}
 		go c.processEvent(event)
 	}
 }
 
 // verifySignature validates the X-Line-Signature using HMAC-SHA256.
 func (c *LINEChannel) verifySignature(body []byte, signature string) bool {
-	if signature == "" {
-		return false
-	}
-
-	mac := hmac.New(sha256.New, []byte(c.config.ChannelSecret))
-	mac.Write(body)
-	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
-	return hmac.Equal([]byte(expected), []byte(signature))
+	return verifyHMACSHA256(c.config.ChannelSecret, body, signature)
 }
 
 // LINE webhook event types
 type lineEvent struct {
-	Type       string          `json:"type"`
-	ReplyToken string          `json:"replyToken"`
-	Source     lineSource      `json:"source"`
-	Message    json.RawMessage `json:"message"`
-	Timestamp  int64           `json:"timestamp"`
+	Type            string          `json:"type"`
+	WebhookEventID  string          `json:"webhookEventId"`
+	ReplyToken      string          `json:"replyToken"`
+	Source          lineSource      `json:"source"`
+	Message         json.RawMessage `json:"message"`
+	Timestamp       int64           `json:"timestamp"`
+	DeliveryContext struct {
+		IsRedelivery bool `json:"isRedelivery"`
+	} `json:"deliveryContext"`
 }
 
 type lineSource struct {
@@ -507,11 +506,16 @@ func (c *LINEChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		quoteToken, _ = qt.(string)
 	}
 
+	messages := buildLineMessages(msg, quoteToken)
+	if len(messages) == 0 {
+		return nil
+	}
+
 	// Try reply token first (free, valid for ~25 seconds)
 	if entry, ok := c.replyTokens.LoadAndDelete(msg.ChatID); ok {
 		tokenEntry, _ := entry.(replyTokenEntry)
 		if time.Since(tokenEntry.timestamp) < lineReplyTokenMaxAge {
-			if err := c.sendReply(ctx, tokenEntry.token, msg.Content, quoteToken); err == nil {
+			if err := c.sendReply(ctx, tokenEntry.token, messages, msg.Silent); err == nil {
 				logger.DebugCF("line", "Message sent via Reply API", map[string]any{
 					"chat_id": msg.ChatID,
 					"quoted":  quoteToken != "",
@@ -523,12 +527,74 @@ func (c *LINEChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	}
 
 	// Fall back to Push API
-	return c.sendPush(ctx, msg.ChatID, msg.Content, quoteToken)
+	return c.sendPush(ctx, msg.ChatID, messages, msg.Silent)
+}
+
+// LINE limits: message objects per request, quick reply items and label length.
+const (
+	lineMaxMessages   = 5
+	lineMaxQuickReply = 13
+	lineMaxLabelRunes = 20
+)
+
+// buildLineMessages converts an outbound message into LINE message objects.
+// Media must be reachable over HTTPS, so local files and plain-HTTP URLs
+// are listed in the text instead. Buttons become quick replies on the last
+// message.
+func buildLineMessages(msg bus.OutboundMessage, quoteToken string) []map[string]any {
+	text := msg.Content
+	var media []map[string]any
+	for _, a := range msg.Media {
+		kind := a.Kind()
+		if !strings.HasPrefix(a.URL, "https://") || (kind != bus.KindImage && kind != bus.KindVideo) {
+			text = bus.OutboundMessage{Content: text, Media: []bus.Attachment{a}}.PlainText()
+			continue
+		}
+		m := map[string]any{
+			"type":               kind,
+			"originalContentUrl": a.URL,
+			"previewImageUrl":    a.URL,
+		}
+		media = append(media, m)
+	}
+
+	var messages []map[string]any
+	if text != "" {
+		messages = append(messages, buildTextMessage(text, quoteToken))
+	}
+	messages = append(messages, media...)
+	if len(messages) > lineMaxMessages {
+		messages = messages[:lineMaxMessages]
+	}
+
+	if items := lineQuickReplyItems(msg.Buttons); len(items) > 0 && len(messages) > 0 {
+		messages[len(messages)-1]["quickReply"] = map[string]any{"items": items}
+	}
+	return messages
+}
+
+func lineQuickReplyItems(buttons []bus.Button) []map[string]any {
+	items := make([]map[string]any, 0, len(buttons))
+	for _, b := range buttons {
+		if len(items) == lineMaxQuickReply {
+			break
+		}
+		label := b.Text
+		if runes := []rune(label); len(runes) > lineMaxLabelRunes {
+			label = string(runes[:lineMaxLabelRunes])
+		}
+		action := map[string]any{"type": "message", "label": label, "text": b.Reply()}
+		if b.URL != "" {
+			action = map[string]any{"type": "uri", "label": label, "uri": b.URL}
+		}
+		items = append(items, map[string]any{"type": "action", "action": action})
+	}
+	return items
 }
 
 // buildTextMessage creates a text message object, optionally with quoteToken.
-func buildTextMessage(content, quoteToken string) map[string]string {
-	msg := map[string]string{
+func buildTextMessage(content, quoteToken string) map[string]any {
+	msg := map[string]any{
 		"type": "text",
 		"text": content,
 	}
@@ -538,21 +604,27 @@ func buildTextMessage(content, quoteToken string) map[string]string {
 	return msg
 }
 
-// sendReply sends a message using the LINE Reply API.
-func (c *LINEChannel) sendReply(ctx context.Context, replyToken, content, quoteToken string) error {
+// sendReply sends messages using the LINE Reply API.
+func (c *LINEChannel) sendReply(ctx context.Context, replyToken string, messages []map[string]any, silent bool) error {
 	payload := map[string]any{
 		"replyToken": replyToken,
-		"messages":   []map[string]string{buildTextMessage(content, quoteToken)},
+		"messages":   messages,
+	}
+	if silent {
+		payload["notificationDisabled"] = true
 	}
 
 	return c.callAPI(ctx, lineReplyEndpoint, payload)
 }
 
-// sendPush sends a message using the LINE Push API.
-func (c *LINEChannel) sendPush(ctx context.Context, to, content, quoteToken string) error {
+// sendPush sends messages using the LINE Push API.
+func (c *LINEChannel) sendPush(ctx context.Context, to string, messages []map[string]any, silent bool) error {
 	payload := map[string]any{
 		"to":       to,
-		"messages": []map[string]string{buildTextMessage(content, quoteToken)},
+		"messages": messages,
+	}
+	if silent {
+		payload["notificationDisabled"] = true
 	}
 
 	return c.callAPI(ctx, linePushEndpoint, payload)
@@ -0,0 +1,63 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+)
+
+func TestBuildLineMessages(t *testing.T) {
+	msg := bus.OutboundMessage{
+		Content: "Here you go",
+		Media: []bus.Attachment{
+			{URL: "https://example.com/cat.jpg"},
+			{Path: "/workspace/report.pdf"},
+		},
+		Buttons: []bus.Button{
+			{Text: "A label longer than twenty runes", Value: "more"},
+			{Text: "Site", URL: "https://example.com"},
+		},
+	}
+
+	messages := buildLineMessages(msg, "qt")
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2: %v", len(messages), messages)
+	}
+
+	text := messages[0]
+	if text["type"] != "text" || text["quoteToken"] != "qt" {
+		t.Errorf("first message = %v", text)
+	}
+	if body, _ := text["text"].(string); !strings.Contains(body, "[attachment: report.pdf]") {
+		t.Errorf("local file not listed in text: %q", body)
+	}
+
+	image := messages[1]
+	if image["type"] != bus.KindImage || image["originalContentUrl"] != "https://example.com/cat.jpg" {
+		t.Errorf("image message = %v", image)
+	}
+
+	qr, ok := image["quickReply"].(map[string]any)
+	if !ok {
+		t.Fatalf("quick reply missing from last message: %v", image)
+	}
+	items, _ := qr["items"].([]map[string]any)
+	if len(items) != 2 {
+		t.Fatalf("got %d quick reply items, want 2", len(items))
+	}
+	first, _ := items[0]["action"].(map[string]any)
+	if label, _ := first["label"].(string); len([]rune(label)) > lineMaxLabelRunes || first["text"] != "more" {
+		t.Errorf("message action = %v", first)
+	}
+	second, _ := items[1]["action"].(map[string]any)
+	if second["type"] != "uri" || second["uri"] != "https://example.com" {
+		t.Errorf("uri action = %v", second)
+	}
+}
+
+func TestBuildLineMessages_Empty(t *testing.T) {
+	if got := buildLineMessages(bus.OutboundMessage{}, ""); len(got) != 0 {
+		t.Errorf("empty message produced %v", got)
+	}
+}
@@ -2,31 +2,108 @@ package channels
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/bus"
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
 	"github.com/tinyland-inc/tinyclaw/pkg/logger"
 )
 
+// MaixCam devices speak JSON messages over TCP. A device opens with
+//
+//	{"type": "hello", "version": 1, "device_id": "porch", "token": "..."}
+//
+// and is answered with a "welcome" carrying the detection settings, or an
+// "error" before the connection is closed. It then sends "detection"
+// (data holds one object, or "objects", with class_name, class_id, score,
+// x, y, w, h and an optional base64 "image"), "snapshot" (data holds
+// "image" and "format", with the request_id it answers), "status" and
+// "heartbeat". The server sends "command" messages with text for the
+// device and "snapshot_request" messages with a request_id.
+//
+// Devices that skip the hello speak version 0: they are accepted only when
+// no token is configured, and share the device ID "default".
+const (
+	maixcamProtocolVersion = 1
+	maixcamDefaultDevice   = "default"
+	maixcamHelloTimeout    = 10 * time.Second
+	maixcamWriteTimeout    = 10 * time.Second
+	maxMaixCamMessageBytes = 8 << 20
+
+	// maixcamSnapshotCommand, as the content of an outbound message, asks
+	// the addressed devices for a snapshot instead of showing text.
+	maixcamSnapshotCommand = "/snapshot"
+)
+
+var maixcamDeviceIDRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
 type MaixCamChannel struct {
 	*BaseChannel
 
 	config     config.MaixCamConfig
 	listener   net.Listener
-	clients    map[net.Conn]bool
+	clients    map[net.Conn]*maixcamClient
 	clientsMux sync.RWMutex
+	limiter    *detectionLimiter
 }
 
 type MaixCamMessage struct {
 	Type      string         `json:"type"`
-	Tips      string         `json:"tips"`
+	Version   int            `json:"version,omitempty"`
+	DeviceID  string         `json:"device_id,omitempty"`
+	Token     string         `json:"token,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Tips      string         `json:"tips,omitempty"`
 	Timestamp float64        `json:"timestamp"`
-	Data      map[string]any `json:"data"`
+	Message   string         `json:"message,omitempty"`
+	ChatID    string         `json:"chat_id,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// maixcamClient is one device connection. deviceID is empty until the
+// device has registered.
+type maixcamClient struct {
+	conn     net.Conn
+	deviceID string
+	version  int
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	pendingMu sync.Mutex
+	pending   map[string]string // snapshot request ID -> reason
+}
+
+func (cl *maixcamClient) send(msg MaixCamMessage) error {
+	cl.writeMu.Lock()
+	defer cl.writeMu.Unlock()
+	cl.conn.SetWriteDeadline(time.Now().Add(maixcamWriteTimeout))
+	return cl.enc.Encode(msg)
+}
+
+// takePending returns the reason a snapshot was requested, forgetting the
+// request.
+func (cl *maixcamClient) takePending(requestID string) (string, bool) {
+	cl.pendingMu.Lock()
+	defer cl.pendingMu.Unlock()
+	reason, ok := cl.pending[requestID]
+	delete(cl.pending, requestID)
+	return reason, ok
 }
 
 func NewMaixCamChannel(cfg config.MaixCamConfig, bus *bus.MessageBus) (*MaixCamChannel, error) {
@@ -35,7 +112,8 @@ func NewMaixCamChannel(cfg config.MaixCamConfig, bus *bus.MessageBus) (*MaixCamC
 	return &MaixCamChannel{
 		BaseChannel: base,
 		config:      cfg,
-		clients:     make(map[net.Conn]bool),
+		clients:     make(map[net.Conn]*maixcamClient),
+		limiter:     newDetectionLimiter(time.Duration(cfg.EventIntervalSeconds) * time.Second),
 	}, nil
 }
 
@@ -84,104 +162,381 @@ func (c *MaixCamChannel) acceptConnections(ctx context.Context) {
 				"remote_addr": conn.RemoteAddr().String(),
 			})
 
+			client := &maixcamClient{
+				conn:    conn,
+				enc:     json.NewEncoder(conn),
+				pending: make(map[string]string),
+			}
 			c.clientsMux.Lock()
-			c.clients[conn] = true
+			c.clients[conn] = client
 			c.clientsMux.Unlock()
 
-			go c.handleConnection(conn, ctx)
+			go c.handleConnection(client, ctx)
 		}
 	}
 }
 
-func (c *MaixCamChannel) handleConnection(conn net.Conn, ctx context.Context) {
+// messageLimitReader fails once a single message has read more than
+// maxMaixCamMessageBytes, so a device cannot make the decoder buffer
+// without bound. n is reset before each message.
+type messageLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *messageLimitReader) Read(p []byte) (int, error) {
+	if l.n >= maxMaixCamMessageBytes {
+		return 0, fmt.Errorf("message exceeds %d MB", maxMaixCamMessageBytes>>20)
+	}
+	if int64(len(p)) > maxMaixCamMessageBytes-l.n {
+		p = p[:maxMaixCamMessageBytes-l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	return n, err
+}
+
+func (c *MaixCamChannel) handleConnection(client *maixcamClient, ctx context.Context) {
 	logger.DebugC("maixcam", "Handling MaixCam connection")
+	conn := client.conn
 
 	defer func() {
 		conn.Close()
 		c.clientsMux.Lock()
 		delete(c.clients, conn)
 		c.clientsMux.Unlock()
-		logger.DebugC("maixcam", "Connection closed")
+		logger.DebugCF("maixcam", "Connection closed", map[string]any{
+			"device_id": client.deviceID,
+		})
 	}()
 
-	decoder := json.NewDecoder(conn)
+	if c.config.Token != "" {
+		conn.SetReadDeadline(time.Now().Add(maixcamHelloTimeout))
+	}
+	limited := &messageLimitReader{r: conn}
+	decoder := json.NewDecoder(limited)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
+			limited.n = 0
 			var msg MaixCamMessage
 			if err := decoder.Decode(&msg); err != nil {
-				if err.Error() != "EOF" {
+				if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
 					logger.ErrorCF("maixcam", "Failed to decode message", map[string]any{
-						"error": err.Error(),
+						"device_id": client.deviceID,
+						"error":     err.Error(),
 					})
 				}
 				return
 			}
 
-			c.processMessage(msg, conn)
+			if !c.processMessage(msg, client) {
+				return
+			}
 		}
 	}
 }
 
-func (c *MaixCamChannel) processMessage(msg MaixCamMessage, _ net.Conn) {
+// processMessage handles one message from a device and reports whether the
+// connection should stay open.
+func (c *MaixCamChannel) processMessage(msg MaixCamMessage, client *maixcamClient) bool {
+	if msg.Type == "hello" {
+		return c.handleHello(msg, client)
+	}
+	if client.deviceID == "" {
+		if c.config.Token != "" {
+			c.reject(client, "hello with a token required before other messages")
+			return false
+		}
+		c.register(client, maixcamDefaultDevice, 0)
+	}
+
 	switch msg.Type {
+	case "detection":
+		c.handleDetection(msg, client, "object")
 	case "person_detected":
-		c.handlePersonDetection(msg)
+		c.handleDetection(msg, client, "person")
+	case "snapshot":
+		c.handleSnapshot(msg, client)
 	case "heartbeat":
-		logger.DebugC("maixcam", "Received heartbeat")
+		logger.DebugCF("maixcam", "Received heartbeat", map[string]any{"device_id": client.deviceID})
 	case "status":
-		c.handleStatusUpdate(msg)
+		c.handleStatusUpdate(msg, client)
+	case "error":
+		logger.WarnCF("maixcam", "Device reported an error", map[string]any{
+			"device_id":  client.deviceID,
+			"request_id": msg.RequestID,
+			"error":      msg.Error,
+		})
 	default:
 		logger.WarnCF("maixcam", "Unknown message type", map[string]any{
-			"type": msg.Type,
+			"device_id": client.deviceID,
+			"type":      msg.Type,
 		})
 	}
+	return true
 }
 
-func (c *MaixCamChannel) handlePersonDetection(msg MaixCamMessage) {
-	logger.InfoCF("maixcam", "", map[string]any{
-		"timestamp": msg.Timestamp,
-		"data":      msg.Data,
+// reject tells a device why it is being disconnected.
+func (c *MaixCamChannel) reject(client *maixcamClient, reason string) {
+	logger.WarnCF("maixcam", "Rejecting MaixCam device", map[string]any{
+		"remote_addr": client.conn.RemoteAddr().String(),
+		"device_id":   client.deviceID,
+		"reason":      reason,
+	})
+	client.send(MaixCamMessage{
+		Type:      "error",
+		Version:   maixcamProtocolVersion,
+		Error:     reason,
+		Timestamp: maixcamTimestamp(),
 	})
+}
 
-	senderID := "maixcam"
-	chatID := "default"
+func maixcamTimestamp() float64 {
+	return float64(time.Now().UnixMilli()) / 1000
+}
 
-	classInfo, ok := msg.Data["class_name"].(string)
-	if !ok {
-		classInfo = "person"
+func (c *MaixCamChannel) handleHello(msg MaixCamMessage, client *maixcamClient) bool {
+	switch {
+	case client.deviceID != "":
+		c.reject(client, "already registered")
+		return false
+	case msg.Version < 1 || msg.Version > maixcamProtocolVersion:
+		c.reject(client, fmt.Sprintf("unsupported protocol version %d (server speaks %d)",
+			msg.Version, maixcamProtocolVersion))
+		return false
+	case c.config.Token != "" && subtle.ConstantTimeCompare([]byte(msg.Token), []byte(c.config.Token)) != 1:
+		c.reject(client, "unauthorized")
+		return false
+	case !maixcamDeviceIDRe.MatchString(msg.DeviceID):
+		c.reject(client, "device_id must be 1-64 letters, digits, '.', '_' or '-'")
+		return false
+	case !c.IsAllowed(msg.DeviceID):
+		c.reject(client, "device not allowed")
+		return false
 	}
 
-	score, _ := msg.Data["score"].(float64)
-	x, _ := msg.Data["x"].(float64)
-	y, _ := msg.Data["y"].(float64)
-	w, _ := msg.Data["w"].(float64)
-	h, _ := msg.Data["h"].(float64)
+	client.conn.SetReadDeadline(time.Time{})
+	c.register(client, msg.DeviceID, msg.Version)
+	logger.InfoCF("maixcam", "MaixCam device registered", map[string]any{
+		"device_id":   msg.DeviceID,
+		"remote_addr": client.conn.RemoteAddr().String(),
+		"info":        msg.Data,
+	})
 
-	content := fmt.Sprintf("📷 Person detected!\nClass: %s\nConfidence: %.2f%%\nPosition: (%.0f, %.0f)\nSize: %.0fx%.0f",
-		classInfo, score*100, x, y, w, h)
+	settings := map[string]any{
+		"classes":                []string(c.config.Classes),
+		"min_confidence":         c.config.MinConfidence,
+		"event_interval_seconds": c.config.EventIntervalSeconds,
+	}
+	if len(c.config.ClassThresholds) > 0 {
+		settings["class_thresholds"] = c.config.ClassThresholds
+	}
+	if err := client.send(MaixCamMessage{
+		Type:      "welcome",
+		Version:   maixcamProtocolVersion,
+		DeviceID:  msg.DeviceID,
+		Timestamp: maixcamTimestamp(),
+		Data:      settings,
+	}); err != nil {
+		return false
+	}
+	return true
+}
 
+// register names a connection's device. A device that reconnects replaces
+// its previous connection, which may be half-open after a network drop;
+// version 0 devices all share one ID and are kept side by side.
+func (c *MaixCamChannel) register(client *maixcamClient, deviceID string, version int) {
+	c.clientsMux.Lock()
+	defer c.clientsMux.Unlock()
+	if version > 0 {
+		for conn, other := range c.clients {
+			if other != client && other.deviceID == deviceID {
+				logger.InfoCF("maixcam", "Replacing previous connection of device", map[string]any{
+					"device_id": deviceID,
+				})
+				conn.Close()
+			}
+		}
+	}
+	client.deviceID = deviceID
+	client.version = version
+}
+
+// maixcamObject is one detected object.
+type maixcamObject struct {
+	Class   string
+	ClassID float64
+	Score   float64
+	X, Y    float64
+	W, H    float64
+}
+
+func parseMaixCamObject(data map[string]any, fallbackClass string) maixcamObject {
+	o := maixcamObject{Class: fallbackClass}
+	if name, ok := data["class_name"].(string); ok && name != "" {
+		o.Class = name
+	}
+	o.ClassID, _ = data["class_id"].(float64)
+	o.Score, _ = data["score"].(float64)
+	o.X, _ = data["x"].(float64)
+	o.Y, _ = data["y"].(float64)
+	o.W, _ = data["w"].(float64)
+	o.H, _ = data["h"].(float64)
+	return o
+}
+
+// wanted reports whether an object passes the class and confidence filters.
+func (c *MaixCamChannel) wanted(o maixcamObject) bool {
+	if len(c.config.Classes) > 0 && !slices.ContainsFunc(c.config.Classes, func(class string) bool {
+		return strings.EqualFold(class, o.Class)
+	}) {
+		return false
+	}
+	threshold := c.config.MinConfidence
+	if t, ok := c.config.ClassThresholds[o.Class]; ok {
+		threshold = t
+	}
+	return o.Score >= threshold
+}
+
+func (c *MaixCamChannel) handleDetection(msg MaixCamMessage, client *maixcamClient, fallbackClass string) {
+	var objects []maixcamObject
+	if list, ok := msg.Data["objects"].([]any); ok {
+		for _, item := range list {
+			if data, ok := item.(map[string]any); ok {
+				objects = append(objects, parseMaixCamObject(data, fallbackClass))
+			}
+		}
+	} else {
+		objects = append(objects, parseMaixCamObject(msg.Data, fallbackClass))
+	}
+
+	// Rate limiting is per class, so decide each class once per event.
+	device := client.deviceID
+	decided := make(map[string]bool)
+	suppressed := 0
+	var kept []maixcamObject
+	for _, o := range objects {
+		if !c.wanted(o) {
+			continue
+		}
+		allowed, seen := decided[o.Class]
+		if !seen {
+			var dropped int
+			allowed, dropped = c.limiter.allow(device+"|"+o.Class, time.Now())
+			decided[o.Class] = allowed
+			suppressed += dropped
+		}
+		if allowed {
+			kept = append(kept, o)
+		}
+	}
+	if len(kept) == 0 {
+		logger.DebugCF("maixcam", "Detection filtered or rate limited", map[string]any{
+			"device_id": device,
+			"objects":   len(objects),
+		})
+		return
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Score > kept[j].Score })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📷 Detected on %s:", device)
+	classes := make([]string, 0, len(kept))
+	for _, o := range kept {
+		fmt.Fprintf(&b, "\n- %s %.2f%% at (%.0f, %.0f), size %.0fx%.0f", o.Class, o.Score*100, o.X, o.Y, o.W, o.H)
+		if !slices.Contains(classes, o.Class) {
+			classes = append(classes, o.Class)
+		}
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(&b, "\n(%d similar detection(s) suppressed since the last report)", suppressed)
+	}
+
+	top := kept[0]
 	metadata := map[string]string{
-		"timestamp": fmt.Sprintf("%.0f", msg.Timestamp),
-		"class_id":  fmt.Sprintf("%.0f", msg.Data["class_id"]),
-		"score":     fmt.Sprintf("%.2f", score),
-		"x":         fmt.Sprintf("%.0f", x),
-		"y":         fmt.Sprintf("%.0f", y),
-		"w":         fmt.Sprintf("%.0f", w),
-		"h":         fmt.Sprintf("%.0f", h),
-		"peer_kind": "channel",
-		"peer_id":   "default",
+		"event":      "detection",
+		"device_id":  device,
+		"timestamp":  fmt.Sprintf("%.0f", msg.Timestamp),
+		"classes":    strings.Join(classes, ","),
+		"objects":    fmt.Sprintf("%d", len(kept)),
+		"suppressed": fmt.Sprintf("%d", suppressed),
+		"class_id":   fmt.Sprintf("%.0f", top.ClassID),
+		"score":      fmt.Sprintf("%.2f", top.Score),
+		"x":          fmt.Sprintf("%.0f", top.X),
+		"y":          fmt.Sprintf("%.0f", top.Y),
+		"w":          fmt.Sprintf("%.0f", top.W),
+		"h":          fmt.Sprintf("%.0f", top.H),
+		"peer_kind":  "channel",
+		"peer_id":    device,
+	}
+
+	media := []string{}
+	if path := c.saveImage(msg, device, "detection"); path != "" {
+		media = append(media, path)
+	}
+	c.HandleMessage(device, device, b.String(), media, metadata)
+}
+
+// saveImage stores the base64 image in a message's data as a media file
+// and returns its path, or "" when there is none.
+func (c *MaixCamChannel) saveImage(msg MaixCamMessage, device, kind string) string {
+	encoded, _ := msg.Data["image"].(string)
+	if encoded == "" {
+		return ""
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		logger.WarnCF("maixcam", "Invalid image data", map[string]any{
+			"device_id": device,
+			"error":     err.Error(),
+		})
+		return ""
+	}
+	ext := "jpg"
+	if format, _ := msg.Data["format"].(string); strings.EqualFold(format, "png") {
+		ext = "png"
+	}
+	return saveMediaFile("maixcam", fmt.Sprintf("%s_%s.%s", device, kind, ext), data)
+}
+
+func (c *MaixCamChannel) handleSnapshot(msg MaixCamMessage, client *maixcamClient) {
+	device := client.deviceID
+	path := c.saveImage(msg, device, "snapshot")
+	if path == "" {
+		logger.WarnCF("maixcam", "Snapshot without an image", map[string]any{
+			"device_id":  device,
+			"request_id": msg.RequestID,
+		})
+		return
 	}
 
-	c.HandleMessage(senderID, chatID, content, []string{}, metadata)
+	content := fmt.Sprintf("📷 Snapshot from %s", device)
+	reason, requested := client.takePending(msg.RequestID)
+	if reason != "" {
+		content += "\nRequested for: " + reason
+	}
+	metadata := map[string]string{
+		"event":      "snapshot",
+		"device_id":  device,
+		"request_id": msg.RequestID,
+		"requested":  fmt.Sprintf("%t", requested),
+		"timestamp":  fmt.Sprintf("%.0f", msg.Timestamp),
+		"peer_kind":  "channel",
+		"peer_id":    device,
+	}
+	c.HandleMessage(device, device, content, []string{path}, metadata)
 }
 
-func (c *MaixCamChannel) handleStatusUpdate(msg MaixCamMessage) {
+func (c *MaixCamChannel) handleStatusUpdate(msg MaixCamMessage, client *maixcamClient) {
 	logger.InfoCF("maixcam", "Status update from MaixCam", map[string]any{
-		"status": msg.Data,
+		"device_id": client.deviceID,
+		"status":    msg.Data,
 	})
 }
 
@@ -199,12 +554,16 @@ func (c *MaixCamChannel) Stop(ctx context.Context) error {
 	for conn := range c.clients {
 		conn.Close()
 	}
-	c.clients = make(map[net.Conn]bool)
+	c.clients = make(map[net.Conn]*maixcamClient)
 
 	logger.InfoC("maixcam", "MaixCam channel stopped")
 	return nil
 }
 
+// Send delivers to the device named by msg.ChatID, or to every device when
+// it is empty or "*". Content of "/snapshot", optionally followed by a
+// reason, requests a snapshot, which arrives as an inbound message with the
+// image in Media.
 func (c *MaixCamChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if !c.IsRunning() {
 		return errors.New("maixcam channel not running")
@@ -213,29 +572,52 @@ func (c *MaixCamChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	c.clientsMux.RLock()
 	defer c.clientsMux.RUnlock()
 
-	if len(c.clients) == 0 {
-		logger.WarnC("maixcam", "No MaixCam devices connected")
-		return errors.New("no connected MaixCam devices")
+	var targets []*maixcamClient
+	for _, client := range c.clients {
+		if client.deviceID == "" {
+			continue
+		}
+		if msg.ChatID == "" || msg.ChatID == "*" || msg.ChatID == client.deviceID {
+			targets = append(targets, client)
+		}
 	}
-
-	response := map[string]any{
-		"type":      "command",
-		"timestamp": float64(0),
-		"message":   msg.Content,
-		"chat_id":   msg.ChatID,
+	if len(targets) == 0 {
+		if msg.ChatID == "" || msg.ChatID == "*" {
+			logger.WarnC("maixcam", "No MaixCam devices connected")
+			return errors.New("no connected MaixCam devices")
+		}
+		return fmt.Errorf("MaixCam device %q is not connected", msg.ChatID)
 	}
 
-	data, err := json.Marshal(response)
-	if err != nil {
-		return fmt.Errorf("failed to marshal response: %w", err)
-	}
+	reason, snapshot := strings.CutPrefix(strings.TrimSpace(msg.Content), maixcamSnapshotCommand)
+	snapshot = snapshot && (reason == "" || reason[0] == ' ')
 
 	var sendErr error
-	for conn := range c.clients {
-		if _, err := conn.Write(data); err != nil {
+	for _, client := range targets {
+		out := MaixCamMessage{
+			Type:      "command",
+			Timestamp: maixcamTimestamp(),
+			Message:   msg.Content,
+			ChatID:    msg.ChatID,
+		}
+		if snapshot && client.version > 0 {
+			out = MaixCamMessage{
+				Type:      "snapshot_request",
+				RequestID: uuid.New().String()[:8],
+				Timestamp: maixcamTimestamp(),
+			}
+			client.pendingMu.Lock()
+			client.pending[out.RequestID] = strings.TrimSpace(reason)
+			client.pendingMu.Unlock()
+		} else if snapshot {
+			sendErr = fmt.Errorf("MaixCam device %q does not support snapshots (protocol version 0)", client.deviceID)
+			continue
+		}
+		if err := client.send(out); err != nil {
 			logger.ErrorCF("maixcam", "Failed to send to client", map[string]any{
-				"client": conn.RemoteAddr().String(),
-				"error":  err.Error(),
+				"device_id": client.deviceID,
+				"client":    client.conn.RemoteAddr().String(),
+				"error":     err.Error(),
 			})
 			sendErr = err
 		}
@@ -243,3 +625,35 @@ func (c *MaixCamChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 
 	return sendErr
 }
+
+// detectionLimiter lets each device report a class at most once per
+// interval, counting the reports it drops.
+type detectionLimiter struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+func newDetectionLimiter(interval time.Duration) *detectionLimiter {
+	return &detectionLimiter{
+		interval:   interval,
+		last:       make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// allow reports whether an event for key may pass at now and, if so, how
+// many were suppressed since the last one that did.
+func (l *detectionLimiter) allow(key string, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if last, ok := l.last[key]; ok && now.Sub(last) < l.interval {
+		l.suppressed[key]++
+		return false, 0
+	}
+	l.last[key] = now
+	dropped := l.suppressed[key]
+	delete(l.suppressed, key)
+	return true, dropped
+}
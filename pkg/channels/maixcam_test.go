@@ -0,0 +1,234 @@
+package channels
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+// startMaixCam runs a MaixCam channel on a free local port.
+func startMaixCam(t *testing.T, cfg config.MaixCamConfig) (*MaixCamChannel, *bus.MessageBus) {
+	t.Helper()
+	cfg.Host, cfg.Port = "127.0.0.1", 0
+	mb := bus.NewMessageBus()
+	ch, err := NewMaixCamChannel(cfg, mb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		ch.Stop(context.Background())
+	})
+	return ch, mb
+}
+
+type maixcamTestDevice struct {
+	t    *testing.T
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+func dialMaixCam(t *testing.T, ch *MaixCamChannel) *maixcamTestDevice {
+	t.Helper()
+	conn, err := net.Dial("tcp", ch.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &maixcamTestDevice{t: t, conn: conn, enc: json.NewEncoder(conn), dec: json.NewDecoder(conn)}
+}
+
+func (d *maixcamTestDevice) send(msg MaixCamMessage) {
+	d.t.Helper()
+	if err := d.enc.Encode(msg); err != nil {
+		d.t.Fatal(err)
+	}
+}
+
+func (d *maixcamTestDevice) receive() MaixCamMessage {
+	d.t.Helper()
+	d.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg MaixCamMessage
+	if err := d.dec.Decode(&msg); err != nil {
+		d.t.Fatalf("no message from server: %v", err)
+	}
+	return msg
+}
+
+func consumeInbound(t *testing.T, mb *bus.MessageBus, wait time.Duration) (bus.InboundMessage, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	return mb.ConsumeInbound(ctx)
+}
+
+// waitForDevice waits until a device has registered, since the server
+// handles the hello asynchronously.
+func waitForDevice(t *testing.T, ch *MaixCamChannel, id string) {
+	t.Helper()
+	for range 100 {
+		ch.clientsMux.RLock()
+		for _, c := range ch.clients {
+			if c.deviceID == id {
+				ch.clientsMux.RUnlock()
+				return
+			}
+		}
+		ch.clientsMux.RUnlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("device %q never registered", id)
+}
+
+func TestMaixCam_HelloAndAuth(t *testing.T) {
+	ch, _ := startMaixCam(t, config.MaixCamConfig{
+		Token:         "secret",
+		Classes:       config.FlexibleStringSlice{"person"},
+		MinConfidence: 0.6,
+	})
+
+	for _, tc := range []struct {
+		hello MaixCamMessage
+		want  string
+	}{
+		{MaixCamMessage{Type: "hello", Version: 1, DeviceID: "porch", Token: "wrong"}, "unauthorized"},
+		{MaixCamMessage{Type: "hello", Version: 9, DeviceID: "porch", Token: "secret"}, "unsupported protocol version"},
+		{MaixCamMessage{Type: "hello", Version: 1, DeviceID: "../x", Token: "secret"}, "device_id"},
+		{MaixCamMessage{Type: "person_detected"}, "hello with a token required"},
+	} {
+		d := dialMaixCam(t, ch)
+		d.send(tc.hello)
+		reply := d.receive()
+		if reply.Type != "error" || !strings.Contains(reply.Error, tc.want) {
+			t.Errorf("%+v: got %+v, want error containing %q", tc.hello, reply, tc.want)
+		}
+	}
+
+	d := dialMaixCam(t, ch)
+	d.send(MaixCamMessage{Type: "hello", Version: 1, DeviceID: "porch", Token: "secret"})
+	welcome := d.receive()
+	if welcome.Type != "welcome" || welcome.DeviceID != "porch" || welcome.Version != maixcamProtocolVersion {
+		t.Fatalf("welcome = %+v", welcome)
+	}
+	if welcome.Data["min_confidence"] != 0.6 {
+		t.Errorf("welcome settings = %v", welcome.Data)
+	}
+}
+
+func TestMaixCam_DetectionFilteringAndRateLimit(t *testing.T) {
+	ch, mb := startMaixCam(t, config.MaixCamConfig{
+		Classes:              config.FlexibleStringSlice{"person", "dog"},
+		MinConfidence:        0.5,
+		ClassThresholds:      map[string]float64{"dog": 0.8},
+		EventIntervalSeconds: 60,
+	})
+	d := dialMaixCam(t, ch)
+	d.send(MaixCamMessage{Type: "hello", Version: 1, DeviceID: "porch"})
+	d.receive()
+
+	detection := MaixCamMessage{Type: "detection", Data: map[string]any{"objects": []any{
+		map[string]any{"class_name": "person", "score": 0.9, "x": 10.0, "y": 20.0, "w": 30.0, "h": 40.0},
+		map[string]any{"class_name": "dog", "score": 0.7},
+		map[string]any{"class_name": "cat", "score": 0.99},
+	}}}
+	d.send(detection)
+	msg, ok := consumeInbound(t, mb, 2*time.Second)
+	if !ok {
+		t.Fatal("no inbound message for the detection")
+	}
+	if msg.SenderID != "porch" || msg.ChatID != "porch" || msg.Metadata["classes"] != "person" {
+		t.Errorf("inbound = %+v", msg)
+	}
+	if !strings.Contains(msg.Content, "person 90.00% at (10, 20)") || strings.Contains(msg.Content, "dog") {
+		t.Errorf("content = %q", msg.Content)
+	}
+
+	// Within the interval the same class is suppressed, a new one is not.
+	d.send(detection)
+	if msg, ok := consumeInbound(t, mb, 200*time.Millisecond); ok {
+		t.Errorf("rate-limited detection reached the agent: %q", msg.Content)
+	}
+	d.send(MaixCamMessage{Type: "detection", Data: map[string]any{"class_name": "dog", "score": 0.85}})
+	if msg, ok := consumeInbound(t, mb, 2*time.Second); !ok || msg.Metadata["classes"] != "dog" {
+		t.Errorf("dog detection: %+v", msg)
+	}
+}
+
+func TestMaixCam_SnapshotAndAddressing(t *testing.T) {
+	ch, mb := startMaixCam(t, config.MaixCamConfig{})
+	porch := dialMaixCam(t, ch)
+	porch.send(MaixCamMessage{Type: "hello", Version: 1, DeviceID: "porch"})
+	porch.receive()
+	garage := dialMaixCam(t, ch)
+	garage.send(MaixCamMessage{Type: "hello", Version: 1, DeviceID: "garage"})
+	garage.receive()
+	waitForDevice(t, ch, "garage")
+
+	ctx := context.Background()
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "garage", Content: "Door open"}); err != nil {
+		t.Fatal(err)
+	}
+	if cmd := garage.receive(); cmd.Type != "command" || cmd.Message != "Door open" {
+		t.Errorf("garage got %+v", cmd)
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "attic", Content: "hi"}); err == nil ||
+		!strings.Contains(err.Error(), "not connected") {
+		t.Errorf("send to an unknown device: %v", err)
+	}
+
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "porch", Content: "/snapshot who is at the door"}); err != nil {
+		t.Fatal(err)
+	}
+	req := porch.receive()
+	if req.Type != "snapshot_request" || req.RequestID == "" {
+		t.Fatalf("porch got %+v", req)
+	}
+	image := []byte("\xff\xd8\xff\xe0fake jpeg")
+	porch.send(MaixCamMessage{Type: "snapshot", RequestID: req.RequestID, Data: map[string]any{
+		"format": "jpeg", "image": base64.StdEncoding.EncodeToString(image),
+	}})
+
+	msg, ok := consumeInbound(t, mb, 2*time.Second)
+	if !ok {
+		t.Fatal("no inbound message for the snapshot")
+	}
+	if msg.ChatID != "porch" || msg.Metadata["requested"] != "true" ||
+		!strings.Contains(msg.Content, "who is at the door") || len(msg.Media) != 1 {
+		t.Fatalf("inbound = %+v", msg)
+	}
+	defer os.Remove(msg.Media[0])
+	if got, err := os.ReadFile(msg.Media[0]); err != nil || string(got) != string(image) {
+		t.Errorf("snapshot file = %q, %v", got, err)
+	}
+}
+
+func TestMaixCam_LegacyDevice(t *testing.T) {
+	ch, mb := startMaixCam(t, config.MaixCamConfig{})
+	d := dialMaixCam(t, ch)
+	d.send(MaixCamMessage{Type: "person_detected", Data: map[string]any{"score": 0.75}})
+
+	msg, ok := consumeInbound(t, mb, 2*time.Second)
+	if !ok {
+		t.Fatal("no inbound message from the legacy device")
+	}
+	if msg.ChatID != "default" || msg.Metadata["classes"] != "person" {
+		t.Errorf("inbound = %+v", msg)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "default", Content: "/snapshot"}); err == nil {
+		t.Error("snapshot request to a version 0 device should fail")
+	}
+}
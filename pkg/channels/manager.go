@@ -9,6 +9,10 @@ package channels
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/bus"
@@ -23,6 +27,14 @@ type Manager struct {
 	config       *config.Config
 	dispatchTask *asyncTask
 	mu           sync.RWMutex
+
+	// Gateway mounting. Patterns are registered on gateway once and served
+	// through handlers, so a channel replaced by Reload takes them over.
+	gateway       WebhookMux
+	ingress       *WebhookIngress // nil until MountWebhooks
+	routesMounted bool
+	handlers      map[string]http.Handler // by pattern; nil when unowned
+	patterns      map[string][]string     // by channel
 }
 
 type asyncTask struct {
@@ -34,6 +46,8 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 		channels: make(map[string]Channel),
 		bus:      messageBus,
 		config:   cfg,
+		handlers: make(map[string]http.Handler),
+		patterns: make(map[string][]string),
 	}
 
 	if err := m.initChannels(); err != nil {
@@ -43,6 +57,41 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 	return m, nil
 }
 
+// ValidateConfig reports enabled channels missing the setting they need
+// to be created, which initChannels would otherwise skip with only a log
+// line.
+func ValidateConfig(cfg *config.Config) error {
+	ch := cfg.Channels
+	required := []struct {
+		name, setting string
+		missing       bool
+	}{
+		{"telegram", "token", ch.Telegram.Enabled && ch.Telegram.Token == ""},
+		{"whatsapp", "bridge_url", ch.WhatsApp.Enabled && ch.WhatsApp.BridgeURL == ""},
+		{"discord", "token", ch.Discord.Enabled && ch.Discord.Token == ""},
+		{"dingtalk", "client_id", ch.DingTalk.Enabled && ch.DingTalk.ClientID == ""},
+		{"slack", "bot_token", ch.Slack.Enabled && ch.Slack.BotToken == ""},
+		{"line", "channel_access_token", ch.LINE.Enabled && ch.LINE.ChannelAccessToken == ""},
+		{"onebot", "ws_url", ch.OneBot.Enabled && ch.OneBot.WSUrl == ""},
+		{"wecom", "token", ch.WeCom.Enabled && ch.WeCom.Token == ""},
+		{"wecom_app", "corp_id", ch.WeComApp.Enabled && ch.WeComApp.CorpID == ""},
+		{"xmpp", "jid", ch.XMPP.Enabled && ch.XMPP.JID == ""},
+		{"matrix", "homeserver", ch.Matrix.Enabled && ch.Matrix.Homeserver == ""},
+		{"email", "imap_server", ch.Email.Enabled && ch.Email.IMAPServer == ""},
+		{"web", "token", ch.Web.Enabled && ch.Web.Token == ""},
+	}
+	var problems []string
+	for _, r := range required {
+		if r.missing {
+			problems = append(problems, fmt.Sprintf("channel %s is enabled without %s", r.name, r.setting))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 //nolint:gocognit,gocyclo // initializes all channel types; one branch per channel kind
 func (m *Manager) initChannels() error { //nolint:unparam // error return kept for future use
 	logger.InfoC("channels", "Initializing channel manager")
@@ -142,6 +191,45 @@ func (m *Manager) initChannels() error { //nolint:unparam // error return kept f
 		}
 	}
 
+	if m.config.Channels.Matrix.Enabled && m.config.Channels.Matrix.Homeserver != "" {
+		logger.DebugC("channels", "Attempting to initialize Matrix channel")
+		matrixCh, err := NewMatrixChannel(m.config.Channels.Matrix, m.bus)
+		if err != nil {
+			logger.ErrorCF("channels", "Failed to initialize Matrix channel", map[string]any{
+				"error": err.Error(),
+			})
+		} else {
+			m.channels["matrix"] = matrixCh
+			logger.InfoC("channels", "Matrix channel enabled successfully")
+		}
+	}
+
+	if m.config.Channels.Email.Enabled && m.config.Channels.Email.IMAPServer != "" {
+		logger.DebugC("channels", "Attempting to initialize email channel")
+		emailCh, err := NewEmailChannel(m.config.Channels.Email, m.bus)
+		if err != nil {
+			logger.ErrorCF("channels", "Failed to initialize email channel", map[string]any{
+				"error": err.Error(),
+			})
+		} else {
+			m.channels["email"] = emailCh
+			logger.InfoC("channels", "Email channel enabled successfully")
+		}
+	}
+
+	if m.config.Channels.Web.Enabled {
+		logger.DebugC("channels", "Attempting to initialize web chat channel")
+		webCh, err := NewWebChannel(m.config.Channels.Web, m.bus)
+		if err != nil {
+			logger.ErrorCF("channels", "Failed to initialize web chat channel", map[string]any{
+				"error": err.Error(),
+			})
+		} else {
+			m.channels["web"] = webCh
+			logger.InfoC("channels", "Web chat channel enabled successfully")
+		}
+	}
+
 	logger.InfoCF("channels", "Channel initialization completed", map[string]any{
 		"enabled_channels": len(m.channels),
 	})
@@ -248,6 +336,228 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 	}
 }
 
+// MountWebhooks registers every webhook channel on mux under
+// /webhooks/<name>, applying the gateway's webhook limits, and returns the
+// names mounted. Channels added by Reload are mounted too.
+func (m *Manager) MountWebhooks(mux WebhookMux) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gateway = mux
+	m.ingress = NewWebhookIngress(m.config.Gateway.Webhooks)
+	var mounted []string
+	for name, ch := range m.channels {
+		if wc, ok := ch.(WebhookChannel); ok {
+			m.ingress.Mount(channelMux{m: m, name: name}, map[string]Channel{name: wc})
+			mounted = append(mounted, name)
+		}
+	}
+	sort.Strings(mounted)
+	return mounted
+}
+
+// MountRoutes registers the routes of channels that serve their own pages,
+// such as the web chat UI, on mux and returns their names. Channels added
+// by Reload are mounted too.
+func (m *Manager) MountRoutes(mux WebhookMux) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gateway = mux
+	m.routesMounted = true
+	var mounted []string
+	for name, ch := range m.channels {
+		if rc, ok := ch.(RouteChannel); ok {
+			rc.RegisterRoutes(channelMux{m: m, name: name})
+			mounted = append(mounted, name)
+		}
+	}
+	sort.Strings(mounted)
+	return mounted
+}
+
+// channelMux mounts one channel's handlers on the gateway through the
+// manager.
+type channelMux struct {
+	m    *Manager
+	name string
+}
+
+func (cm channelMux) Handle(pattern string, handler http.Handler) {
+	m := cm.m
+	if _, ok := m.handlers[pattern]; !ok {
+		m.gateway.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.mu.RLock()
+			h := m.handlers[pattern]
+			m.mu.RUnlock()
+			if h == nil {
+				http.NotFound(w, r)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}))
+	}
+	m.handlers[pattern] = handler
+	m.patterns[cm.name] = append(m.patterns[cm.name], pattern)
+}
+
+// mountLocked mounts a channel started by Reload, the way MountWebhooks
+// and MountRoutes would have.
+func (m *Manager) mountLocked(name string, ch Channel) {
+	if wc, ok := ch.(WebhookChannel); ok && m.ingress != nil {
+		m.ingress.Mount(channelMux{m: m, name: name}, map[string]Channel{name: wc})
+	}
+	if rc, ok := ch.(RouteChannel); ok && m.routesMounted {
+		rc.RegisterRoutes(channelMux{m: m, name: name})
+	}
+}
+
+// unmountLocked leaves a channel's patterns answering 404.
+func (m *Manager) unmountLocked(name string) {
+	for _, pattern := range m.patterns[name] {
+		m.handlers[pattern] = nil
+	}
+	delete(m.patterns, name)
+}
+
+// StartError reports channels whose new settings failed to start during a
+// reload. Each was put back with its previous settings (listed in
+// Restored), or left stopped if those failed to start too; the rest of the
+// reload was applied. The manager keeps the previous settings of these
+// channels, so reloading the same config again retries them.
+type StartError struct {
+	Errs     map[string]error
+	Restored []string
+}
+
+// Channels returns the names of the channels that failed to start, sorted.
+func (e *StartError) Channels() []string {
+	names := make([]string, 0, len(e.Errs))
+	for name := range e.Errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *StartError) Error() string {
+	names := e.Channels()
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("channel %s: %v", name, e.Errs[name])
+	}
+	return "kept previous settings after start failure: " + strings.Join(msgs, "; ")
+}
+
+// Reload applies cfg to the running channels. Channels whose settings did
+// not change keep running; changed ones are stopped and, if still
+// enabled, replaced by new instances. The replacements are all created
+// before anything is stopped, and if an enabled channel cannot be created
+// the reload is refused with nothing changed. A replacement that fails to
+// start is swapped back for a fresh instance with the previous settings
+// and reported in a *StartError. It returns the names of the channels
+// stopped and started; restored channels are not counted as started.
+func (m *Manager) Reload(ctx context.Context, cfg *config.Config) (stopped, started []string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := config.Diff(m.config, cfg)
+	next := &Manager{channels: make(map[string]Channel), bus: m.bus, config: cfg}
+	if len(changes.Channels) > 0 {
+		if err := next.initChannels(); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, name := range changes.Channels {
+		if _, ok := next.channels[name]; !ok && cfg.ChannelEnabled(name) {
+			return nil, nil, fmt.Errorf("channel %s is enabled but could not be created; check its settings", name)
+		}
+	}
+
+	// Built on the first start failure, to put the previous settings back.
+	var prev *Manager
+	startErrs := map[string]error{}
+	var restored []string
+	for _, name := range changes.Channels {
+		old, wasRunning := m.channels[name]
+		if wasRunning {
+			logger.InfoCF("channels", "Stopping channel for reload", map[string]any{"channel": name})
+			if err := old.Stop(ctx); err != nil {
+				logger.ErrorCF("channels", "Error stopping channel", map[string]any{
+					"channel": name,
+					"error":   err.Error(),
+				})
+			}
+			delete(m.channels, name)
+			m.unmountLocked(name)
+			stopped = append(stopped, name)
+		}
+		ch, ok := next.channels[name]
+		if !ok {
+			continue
+		}
+		logger.InfoCF("channels", "Starting channel", map[string]any{"channel": name})
+		if err := ch.Start(ctx); err != nil {
+			logger.ErrorCF("channels", "Failed to start channel, restoring previous settings", map[string]any{
+				"channel": name,
+				"error":   err.Error(),
+			})
+			startErrs[name] = err
+			if !wasRunning {
+				continue
+			}
+			if prev == nil {
+				prev = &Manager{channels: make(map[string]Channel), bus: m.bus, config: m.config}
+				prev.initChannels()
+			}
+			if ch, ok = prev.channels[name]; !ok {
+				continue
+			}
+			if err := ch.Start(ctx); err != nil {
+				logger.ErrorCF("channels", "Failed to restart channel with previous settings", map[string]any{
+					"channel": name,
+					"error":   err.Error(),
+				})
+				continue
+			}
+			restored = append(restored, name)
+		} else {
+			started = append(started, name)
+		}
+		m.channels[name] = ch
+		m.mountLocked(name, ch)
+	}
+
+	if m.ingress != nil && !reflect.DeepEqual(m.config.Gateway.Webhooks, cfg.Gateway.Webhooks) {
+		m.ingress = NewWebhookIngress(cfg.Gateway.Webhooks)
+		for name, ch := range m.channels {
+			if wc, ok := ch.(WebhookChannel); ok {
+				m.unmountLocked(name)
+				m.ingress.Mount(channelMux{m: m, name: name}, map[string]Channel{name: wc})
+				if rc, ok := ch.(RouteChannel); ok && m.routesMounted {
+					rc.RegisterRoutes(channelMux{m: m, name: name})
+				}
+			}
+		}
+	}
+	var startErr *StartError
+	if len(startErrs) > 0 {
+		startErr = &StartError{Errs: startErrs, Restored: restored}
+		cfg = cfg.WithChannelsFrom(m.config, startErr.Channels())
+	}
+	m.config = cfg
+
+	if m.dispatchTask == nil && len(m.channels) > 0 {
+		dispatchCtx, cancel := context.WithCancel(ctx)
+		m.dispatchTask = &asyncTask{cancel: cancel}
+		go m.dispatchOutbound(dispatchCtx)
+	}
+	if startErr != nil {
+		return stopped, started, startErr
+	}
+	return stopped, started, nil
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
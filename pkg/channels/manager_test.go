@@ -0,0 +1,138 @@
+package channels
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+func webManagerConfig(enabled bool, token string) *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Channels.Web = config.WebConfig{Enabled: enabled, Token: token, MaxUploadBytes: 1024}
+	return cfg
+}
+
+func TestManager_Reload(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewManager(webManagerConfig(true, "first"), bus.NewMessageBus())
+	require.NoError(t, err)
+	require.NoError(t, m.StartAll(ctx))
+	t.Cleanup(func() { m.StopAll(ctx) })
+
+	mux := http.NewServeMux()
+	assert.Equal(t, []string{"web"}, m.MountRoutes(mux))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	conn, _, err := dialWeb(t, server, "first")
+	require.NoError(t, err)
+	conn.Close()
+
+	// An unrelated change leaves the channel running.
+	unchanged := webManagerConfig(true, "first")
+	unchanged.Heartbeat.Interval = 5
+	stopped, started, err := m.Reload(ctx, unchanged)
+	require.NoError(t, err)
+	assert.Empty(t, stopped)
+	assert.Empty(t, started)
+
+	// A changed channel is replaced and takes over its routes.
+	stopped, started, err = m.Reload(ctx, webManagerConfig(true, "second"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, stopped)
+	assert.Equal(t, []string{"web"}, started)
+	_, resp, err := dialWeb(t, server, "first")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	conn, _, err = dialWeb(t, server, "second")
+	require.NoError(t, err)
+	conn.Close()
+
+	// An enabled channel that cannot be created rejects the whole reload.
+	_, _, err = m.Reload(ctx, webManagerConfig(true, ""))
+	require.ErrorContains(t, err, "channel web")
+	conn, _, err = dialWeb(t, server, "second")
+	require.NoError(t, err)
+	conn.Close()
+
+	// A disabled channel is stopped and its routes stop answering.
+	stopped, started, err = m.Reload(ctx, webManagerConfig(false, "second"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, stopped)
+	assert.Empty(t, started)
+	_, ok := m.GetChannel("web")
+	assert.False(t, ok)
+	resp, err = http.Get(server.URL + WebPathPrefix)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func maixcamManagerConfig(port int) *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Channels.MaixCam = config.MaixCamConfig{Enabled: true, Host: "127.0.0.1", Port: port}
+	return cfg
+}
+
+func TestManager_ReloadKeepsChannelThatFailsToStart(t *testing.T) {
+	ctx := context.Background()
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { busy.Close() })
+
+	m, err := NewManager(maixcamManagerConfig(port), bus.NewMessageBus())
+	require.NoError(t, err)
+	require.NoError(t, m.StartAll(ctx))
+	t.Cleanup(func() { m.StopAll(ctx) })
+
+	// The new port is taken, so the channel comes back on the old one.
+	busyPort := busy.Addr().(*net.TCPAddr).Port
+	stopped, started, err := m.Reload(ctx, maixcamManagerConfig(busyPort))
+	var startErr *StartError
+	require.ErrorAs(t, err, &startErr)
+	assert.Contains(t, startErr.Errs, "maixcam")
+	assert.Equal(t, []string{"maixcam"}, startErr.Restored)
+	assert.Equal(t, []string{"maixcam"}, stopped)
+	assert.Empty(t, started)
+
+	ch, ok := m.GetChannel("maixcam")
+	require.True(t, ok)
+	assert.True(t, ch.IsRunning())
+	conn, err := net.Dial("tcp", free.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+
+	// The previous settings are kept, so the same config is tried again.
+	busy.Close()
+	stopped, started, err = m.Reload(ctx, maixcamManagerConfig(busyPort))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"maixcam"}, stopped)
+	assert.Equal(t, []string{"maixcam"}, started)
+	conn, err = net.Dial("tcp", busy.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestValidateConfig(t *testing.T) {
+	assert.NoError(t, ValidateConfig(webManagerConfig(true, "secret")))
+	assert.NoError(t, ValidateConfig(webManagerConfig(false, "")))
+
+	cfg := webManagerConfig(true, "")
+	cfg.Channels.Telegram.Enabled = true
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "telegram")
+	assert.Contains(t, err.Error(), "web")
+}
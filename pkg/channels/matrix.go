@@ -0,0 +1,761 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/utils"
+)
+
+const (
+	matrixSyncTimeout   = 30 * time.Second
+	matrixRetryDelay    = 5 * time.Second
+	matrixMaxRateWait   = 10 * time.Second
+	matrixMessageMaxLen = 32000 // events are capped at 64 KiB including JSON overhead
+	matrixPlaceholder   = "Thinking... 💭"
+
+	// matrixSyncFilter keeps sync responses to room messages; member state
+	// is lazy-loaded and everything else is dropped.
+	matrixSyncFilter = `{"presence":{"types":[]},"account_data":{"types":[]},` +
+		`"room":{"account_data":{"types":[]},"ephemeral":{"types":[]},` +
+		`"state":{"lazy_load_members":true,"types":[]},` +
+		`"timeline":{"limit":50,"types":["m.room.message","m.room.encrypted"]}}}`
+)
+
+// MatrixChannel implements the Channel interface over the Matrix
+// client-server API. Conversations in a thread use "<room_id>/<thread_root>"
+// as the chat ID so replies stay in the thread.
+type MatrixChannel struct {
+	*BaseChannel
+
+	config      config.MatrixConfig
+	homeserver  string
+	client      *http.Client
+	accessToken string
+	userID      string
+	displayName string
+
+	rooms        map[string]bool // allowed room IDs; nil allows every room
+	memberCounts sync.Map        // roomID -> joined member count
+	placeholders sync.Map        // chatID -> placeholder event ID
+	warnedRooms  sync.Map        // roomID -> struct{}, encrypted rooms already logged
+	txnCounter   atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewMatrixChannel creates a Matrix channel. It needs a homeserver URL and
+// either an access token or a user ID and password.
+func NewMatrixChannel(cfg config.MatrixConfig, messageBus *bus.MessageBus) (*MatrixChannel, error) {
+	if cfg.Homeserver == "" {
+		return nil, errors.New("matrix homeserver is required")
+	}
+	if cfg.AccessToken == "" && (cfg.UserID == "" || cfg.Password == "") {
+		return nil, errors.New("matrix access_token, or user_id and password, are required")
+	}
+
+	base := NewBaseChannel("matrix", cfg, messageBus, cfg.AllowFrom)
+
+	return &MatrixChannel{
+		BaseChannel: base,
+		config:      cfg,
+		homeserver:  strings.TrimRight(cfg.Homeserver, "/"),
+		client:      &http.Client{Timeout: matrixSyncTimeout + time.Minute},
+		accessToken: cfg.AccessToken,
+		userID:      cfg.UserID,
+	}, nil
+}
+
+// Start logs in if needed, joins the configured rooms and begins syncing.
+// Messages sent before startup are skipped.
+func (c *MatrixChannel) Start(ctx context.Context) error {
+	logger.InfoC("matrix", "Starting Matrix channel")
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	if err := c.authenticate(c.ctx); err != nil {
+		return fmt.Errorf("matrix login failed: %w", err)
+	}
+	c.fetchDisplayName(c.ctx)
+	c.joinConfiguredRooms(c.ctx)
+
+	initial, err := c.sync(c.ctx, "", 0)
+	if err != nil {
+		return fmt.Errorf("matrix initial sync failed: %w", err)
+	}
+	c.handleInvites(c.ctx, initial)
+
+	c.done = make(chan struct{})
+	c.setRunning(true)
+	go c.syncLoop(initial.NextBatch)
+
+	logger.InfoCF("matrix", "Matrix channel started", map[string]any{
+		"user_id":    c.userID,
+		"homeserver": c.homeserver,
+		"rooms":      len(c.rooms),
+	})
+	return nil
+}
+
+// Stop ends the sync loop.
+func (c *MatrixChannel) Stop(ctx context.Context) error {
+	logger.InfoC("matrix", "Stopping Matrix channel")
+
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.done != nil {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+		}
+	}
+
+	logger.InfoC("matrix", "Matrix channel stopped")
+	return nil
+}
+
+// Send delivers msg to a room. A pending "Thinking..." placeholder is
+// edited in place when the reply is plain text, and redacted otherwise.
+func (c *MatrixChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return errors.New("matrix channel not running")
+	}
+
+	roomID, threadID := parseMatrixChatID(msg.ChatID)
+	if msg.ThreadID != "" {
+		threadID = msg.ThreadID
+	}
+
+	text := msg.Content
+	if len(msg.Buttons) > 0 {
+		// Matrix has no buttons; list the options as text.
+		text = bus.OutboundMessage{Content: msg.Content, Buttons: msg.Buttons}.PlainText()
+	}
+	msgType := "m.text"
+	if msg.Silent {
+		msgType = "m.notice"
+	}
+
+	if pID, ok := c.placeholders.LoadAndDelete(msg.ChatID); ok {
+		placeholderID, _ := pID.(string)
+		canEdit := text != "" && len(text) <= matrixMessageMaxLen && len(msg.Media) == 0 && msg.ReplyTo == ""
+		if canEdit {
+			if err := c.editMessage(ctx, roomID, placeholderID, msgType, text); err == nil {
+				return nil
+			}
+		}
+		c.redact(ctx, roomID, placeholderID)
+	}
+
+	if text != "" {
+		for i, chunk := range utils.SplitMessage(text, matrixMessageMaxLen) {
+			content := map[string]any{"msgtype": msgType, "body": chunk}
+			replyTo := ""
+			if i == 0 {
+				replyTo = msg.ReplyTo
+			}
+			if rel := matrixRelation(threadID, replyTo); rel != nil {
+				content["m.relates_to"] = rel
+			}
+			if _, err := c.sendEvent(ctx, roomID, "m.room.message", content); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, a := range msg.Media {
+		if err := c.sendAttachment(ctx, roomID, threadID, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseMatrixChatID splits "<room_id>/<thread_root>" into its parts.
+func parseMatrixChatID(chatID string) (roomID, threadID string) {
+	roomID, threadID, _ = strings.Cut(chatID, "/")
+	return roomID, threadID
+}
+
+// matrixRelation builds the m.relates_to content for a thread and/or reply.
+func matrixRelation(threadID, replyTo string) map[string]any {
+	switch {
+	case threadID != "":
+		inReplyTo := replyTo
+		if inReplyTo == "" {
+			inReplyTo = threadID
+		}
+		return map[string]any{
+			"rel_type":        "m.thread",
+			"event_id":        threadID,
+			"is_falling_back": replyTo == "",
+			"m.in_reply_to":   map[string]any{"event_id": inReplyTo},
+		}
+	case replyTo != "":
+		return map[string]any{"m.in_reply_to": map[string]any{"event_id": replyTo}}
+	}
+	return nil
+}
+
+// matrixError is an error response from the homeserver.
+type matrixError struct {
+	Status       int
+	ErrCode      string `json:"errcode"`
+	Message      string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+func (e *matrixError) Error() string {
+	return fmt.Sprintf("matrix %s (HTTP %d): %s", e.ErrCode, e.Status, e.Message)
+}
+
+// api performs a JSON request against the client-server API. Rate-limited
+// requests are retried after the delay the server asks for.
+func (c *MatrixChannel) api(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal matrix request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		endpoint := c.homeserver + path
+		if len(query) > 0 {
+			endpoint += "?" + query.Encode()
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		err = c.do(req, out)
+
+		var mErr *matrixError
+		if attempt < 2 && errors.As(err, &mErr) && mErr.ErrCode == "M_LIMIT_EXCEEDED" {
+			wait := min(time.Duration(mErr.RetryAfterMs)*time.Millisecond, matrixMaxRateWait)
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return err
+	}
+}
+
+// do sends an authorized request and decodes a JSON response into out.
+func (c *MatrixChannel) do(req *http.Request, out any) error {
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		mErr := &matrixError{Status: resp.StatusCode}
+		if json.Unmarshal(data, mErr) != nil || mErr.ErrCode == "" {
+			mErr.ErrCode = "M_UNKNOWN"
+			mErr.Message = utils.Truncate(string(data), 200)
+		}
+		return mErr
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// authenticate logs in with the password when no access token is set, and
+// otherwise resolves the token's user ID.
+func (c *MatrixChannel) authenticate(ctx context.Context) error {
+	if c.accessToken != "" {
+		var who struct {
+			UserID string `json:"user_id"`
+		}
+		if err := c.api(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, nil, &who); err != nil {
+			return err
+		}
+		c.userID = who.UserID
+		return nil
+	}
+
+	login := map[string]any{
+		"type":                        "m.login.password",
+		"identifier":                  map[string]any{"type": "m.id.user", "user": c.config.UserID},
+		"password":                    c.config.Password,
+		"initial_device_display_name": "tinyclaw",
+	}
+	if c.config.DeviceID != "" {
+		login["device_id"] = c.config.DeviceID
+	}
+	var resp struct {
+		UserID      string `json:"user_id"`
+		AccessToken string `json:"access_token"`
+		DeviceID    string `json:"device_id"`
+	}
+	if err := c.api(ctx, http.MethodPost, "/_matrix/client/v3/login", nil, login, &resp); err != nil {
+		return err
+	}
+	c.accessToken = resp.AccessToken
+	c.userID = resp.UserID
+	logger.InfoCF("matrix", "Logged in with password", map[string]any{
+		"user_id":   resp.UserID,
+		"device_id": resp.DeviceID,
+	})
+	return nil
+}
+
+// fetchDisplayName loads the bot's display name for mention detection.
+func (c *MatrixChannel) fetchDisplayName(ctx context.Context) {
+	var profile struct {
+		DisplayName string `json:"displayname"`
+	}
+	path := "/_matrix/client/v3/profile/" + url.PathEscape(c.userID) + "/displayname"
+	if err := c.api(ctx, http.MethodGet, path, nil, nil, &profile); err != nil {
+		logger.DebugCF("matrix", "Failed to fetch display name", map[string]any{"error": err.Error()})
+		return
+	}
+	c.displayName = profile.DisplayName
+}
+
+// joinConfiguredRooms joins every configured room, which also resolves
+// aliases to room IDs for the allowlist.
+func (c *MatrixChannel) joinConfiguredRooms(ctx context.Context) {
+	if len(c.config.Rooms) == 0 {
+		return
+	}
+	c.rooms = make(map[string]bool, len(c.config.Rooms))
+	for _, room := range c.config.Rooms {
+		roomID, err := c.join(ctx, room)
+		if err != nil {
+			logger.ErrorCF("matrix", "Failed to join room", map[string]any{
+				"room":  room,
+				"error": err.Error(),
+			})
+			if strings.HasPrefix(room, "!") {
+				c.rooms[room] = true
+			}
+			continue
+		}
+		c.rooms[roomID] = true
+	}
+}
+
+func (c *MatrixChannel) join(ctx context.Context, roomIDOrAlias string) (string, error) {
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	path := "/_matrix/client/v3/join/" + url.PathEscape(roomIDOrAlias)
+	if err := c.api(ctx, http.MethodPost, path, nil, map[string]any{}, &resp); err != nil {
+		return "", err
+	}
+	return resp.RoomID, nil
+}
+
+func (c *MatrixChannel) roomAllowed(roomID string) bool {
+	return c.rooms == nil || c.rooms[roomID]
+}
+
+type matrixSyncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Summary struct {
+				JoinedMemberCount *int `json:"m.joined_member_count"`
+			} `json:"summary"`
+			Timeline struct {
+				Events []matrixEvent `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+type matrixEvent struct {
+	Type    string          `json:"type"`
+	EventID string          `json:"event_id"`
+	Sender  string          `json:"sender"`
+	Content json.RawMessage `json:"content"`
+}
+
+type matrixMessageContent struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	FormattedBody string `json:"formatted_body"`
+	FileName      string `json:"filename"`
+	URL           string `json:"url"`
+	Info          struct {
+		MimeType string `json:"mimetype"`
+	} `json:"info"`
+	Mentions *struct {
+		UserIDs []string `json:"user_ids"`
+	} `json:"m.mentions"`
+	RelatesTo *struct {
+		RelType   string `json:"rel_type"`
+		EventID   string `json:"event_id"`
+		InReplyTo *struct {
+			EventID string `json:"event_id"`
+		} `json:"m.in_reply_to"`
+	} `json:"m.relates_to"`
+}
+
+func (c *MatrixChannel) sync(ctx context.Context, since string, timeout time.Duration) (*matrixSyncResponse, error) {
+	query := url.Values{
+		"filter":  {matrixSyncFilter},
+		"timeout": {fmt.Sprint(timeout.Milliseconds())},
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	var resp matrixSyncResponse
+	if err := c.api(ctx, http.MethodGet, "/_matrix/client/v3/sync", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *MatrixChannel) syncLoop(since string) {
+	defer close(c.done)
+	logger.InfoC("matrix", "Matrix sync loop started")
+
+	for {
+		resp, err := c.sync(c.ctx, since, matrixSyncTimeout)
+		if err != nil {
+			if c.ctx.Err() != nil {
+				logger.InfoC("matrix", "Matrix sync loop stopped")
+				return
+			}
+			logger.WarnCF("matrix", "Sync failed, retrying", map[string]any{
+				"error": err.Error(),
+			})
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(matrixRetryDelay):
+			}
+			continue
+		}
+		since = resp.NextBatch
+
+		c.handleInvites(c.ctx, resp)
+		for roomID, room := range resp.Rooms.Join {
+			if room.Summary.JoinedMemberCount != nil {
+				c.memberCounts.Store(roomID, *room.Summary.JoinedMemberCount)
+			}
+			if !c.roomAllowed(roomID) {
+				continue
+			}
+			for _, ev := range room.Timeline.Events {
+				c.handleEvent(c.ctx, roomID, ev)
+			}
+		}
+	}
+}
+
+func (c *MatrixChannel) handleInvites(ctx context.Context, resp *matrixSyncResponse) {
+	for roomID := range resp.Rooms.Invite {
+		if !c.config.AutoJoin || !c.roomAllowed(roomID) {
+			logger.DebugCF("matrix", "Ignoring room invite", map[string]any{"room_id": roomID})
+			continue
+		}
+		if _, err := c.join(ctx, roomID); err != nil {
+			logger.WarnCF("matrix", "Failed to accept room invite", map[string]any{
+				"room_id": roomID,
+				"error":   err.Error(),
+			})
+			continue
+		}
+		logger.InfoCF("matrix", "Joined room on invite", map[string]any{"room_id": roomID})
+	}
+}
+
+//nolint:funlen // event filtering, mention handling and media download in one pass
+func (c *MatrixChannel) handleEvent(ctx context.Context, roomID string, ev matrixEvent) {
+	if ev.Sender == c.userID {
+		return
+	}
+	if ev.Type == "m.room.encrypted" {
+		if _, warned := c.warnedRooms.LoadOrStore(roomID, struct{}{}); !warned {
+			logger.WarnCF("matrix", "Encrypted room messages cannot be read; use an unencrypted room", map[string]any{
+				"room_id": roomID,
+			})
+		}
+		return
+	}
+	if ev.Type != "m.room.message" {
+		return
+	}
+
+	var msg matrixMessageContent
+	if err := json.Unmarshal(ev.Content, &msg); err != nil {
+		logger.DebugCF("matrix", "Failed to parse message content", map[string]any{"error": err.Error()})
+		return
+	}
+	// Skip edits (handled as the original message) and other bots' notices.
+	if msg.MsgType == "m.notice" || (msg.RelatesTo != nil && msg.RelatesTo.RelType == "m.replace") {
+		return
+	}
+	if !c.IsAllowed(ev.Sender) {
+		logger.DebugCF("matrix", "Message rejected by allowlist", map[string]any{"sender": ev.Sender})
+		return
+	}
+
+	isGroup := !c.isDirect(ctx, roomID)
+	content := msg.Body
+	if isGroup {
+		if !c.isMentioned(msg) {
+			return
+		}
+		content = c.stripMention(content)
+	}
+
+	var mediaPaths []string
+	switch msg.MsgType {
+	case "m.image", "m.audio", "m.video", "m.file":
+		kind := strings.TrimPrefix(msg.MsgType, "m.")
+		name := msg.FileName
+		if name == "" {
+			name = msg.Body
+		}
+		// Files stay in the media directory for the agent to read.
+		if localPath := c.downloadMedia(msg.URL, name); localPath != "" {
+			mediaPaths = append(mediaPaths, localPath)
+		}
+		content = fmt.Sprintf("[%s: %s]", kind, name)
+		if msg.FileName != "" && msg.Body != "" && msg.Body != msg.FileName {
+			content += " " + msg.Body
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	chatID := roomID
+	threadID := ""
+	if msg.RelatesTo != nil && msg.RelatesTo.RelType == "m.thread" {
+		threadID = msg.RelatesTo.EventID
+		chatID = roomID + "/" + threadID
+	}
+
+	metadata := map[string]string{
+		"message_id": ev.EventID,
+		"room_id":    roomID,
+		"is_group":   fmt.Sprint(isGroup),
+		"peer_kind":  "direct",
+		"peer_id":    ev.Sender,
+	}
+	if isGroup {
+		metadata["peer_kind"] = "group"
+		metadata["peer_id"] = roomID
+	}
+	if threadID != "" {
+		metadata["thread_id"] = threadID
+	}
+
+	logger.DebugCF("matrix", "Received message", map[string]any{
+		"sender":  ev.Sender,
+		"room_id": roomID,
+		"preview": utils.Truncate(content, 50),
+	})
+
+	placeholder := map[string]any{"msgtype": "m.notice", "body": matrixPlaceholder}
+	if rel := matrixRelation(threadID, ""); rel != nil {
+		placeholder["m.relates_to"] = rel
+	}
+	if eventID, err := c.sendEvent(ctx, roomID, "m.room.message", placeholder); err == nil {
+		c.placeholders.Store(chatID, eventID)
+	}
+
+	c.HandleMessage(ev.Sender, chatID, content, mediaPaths, metadata)
+}
+
+// isDirect reports whether a room has at most two members.
+func (c *MatrixChannel) isDirect(ctx context.Context, roomID string) bool {
+	if n, ok := c.memberCounts.Load(roomID); ok {
+		count, _ := n.(int)
+		return count <= 2
+	}
+	var resp struct {
+		Joined map[string]json.RawMessage `json:"joined"`
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/joined_members"
+	if err := c.api(ctx, http.MethodGet, path, nil, nil, &resp); err != nil {
+		return false
+	}
+	c.memberCounts.Store(roomID, len(resp.Joined))
+	return len(resp.Joined) <= 2
+}
+
+func (c *MatrixChannel) isMentioned(msg matrixMessageContent) bool {
+	if msg.Mentions != nil {
+		for _, id := range msg.Mentions.UserIDs {
+			if id == c.userID {
+				return true
+			}
+		}
+	}
+	if strings.Contains(msg.Body, c.userID) || strings.Contains(msg.FormattedBody, c.userID) {
+		return true
+	}
+	return c.displayName != "" && strings.Contains(strings.ToLower(msg.Body), strings.ToLower(c.displayName))
+}
+
+// stripMention removes the bot's user ID or display name, as clients insert
+// them at the start of a message ("Bot: hello").
+func (c *MatrixChannel) stripMention(body string) string {
+	for _, name := range []string{c.userID, c.displayName} {
+		if name == "" {
+			continue
+		}
+		if start, end := indexFold(body, name); start >= 0 {
+			body = body[:start] + body[end:]
+		}
+	}
+	return strings.TrimLeft(strings.TrimSpace(body), ":, ")
+}
+
+// indexFold returns the byte range of the first case-insensitive match of
+// substr in s, or -1, -1. Simple case folding maps rune to rune, so a match
+// spans as many runes as substr, though not always as many bytes.
+func indexFold(s, substr string) (start, end int) {
+	n := utf8.RuneCountInString(substr)
+	for i := range s {
+		j, count := i, 0
+		for j < len(s) && count < n {
+			_, size := utf8.DecodeRuneInString(s[j:])
+			j += size
+			count++
+		}
+		if count == n && strings.EqualFold(s[i:j], substr) {
+			return i, j
+		}
+	}
+	return -1, -1
+}
+
+// downloadMedia fetches an mxc:// URI, preferring the authenticated media
+// endpoint and falling back to the legacy one.
+func (c *MatrixChannel) downloadMedia(mxc, filename string) string {
+	serverAndID, ok := strings.CutPrefix(mxc, "mxc://")
+	if !ok || !strings.Contains(serverAndID, "/") {
+		return ""
+	}
+	opts := utils.DownloadOptions{
+		LoggerPrefix: "matrix",
+		ExtraHeaders: map[string]string{"Authorization": "Bearer " + c.accessToken},
+	}
+	for _, prefix := range []string{"/_matrix/client/v1/media/download/", "/_matrix/media/v3/download/"} {
+		if localPath := utils.DownloadFile(c.homeserver+prefix+serverAndID, filename, opts); localPath != "" {
+			return localPath
+		}
+	}
+	return ""
+}
+
+func (c *MatrixChannel) sendEvent(ctx context.Context, roomID, eventType string, content map[string]any) (string, error) {
+	txnID := fmt.Sprintf("tinyclaw.%d.%d", time.Now().UnixMilli(), c.txnCounter.Add(1))
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/" + eventType + "/" + txnID
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := c.api(ctx, http.MethodPut, path, nil, content, &resp); err != nil {
+		return "", fmt.Errorf("failed to send matrix event: %w", err)
+	}
+	return resp.EventID, nil
+}
+
+func (c *MatrixChannel) editMessage(ctx context.Context, roomID, eventID, msgType, text string) error {
+	_, err := c.sendEvent(ctx, roomID, "m.room.message", map[string]any{
+		"msgtype":       msgType,
+		"body":          "* " + text,
+		"m.new_content": map[string]any{"msgtype": msgType, "body": text},
+		"m.relates_to":  map[string]any{"rel_type": "m.replace", "event_id": eventID},
+	})
+	return err
+}
+
+func (c *MatrixChannel) redact(ctx context.Context, roomID, eventID string) {
+	txnID := fmt.Sprintf("tinyclaw.%d.%d", time.Now().UnixMilli(), c.txnCounter.Add(1))
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/redact/" + url.PathEscape(eventID) + "/" + txnID
+	if err := c.api(ctx, http.MethodPut, path, nil, map[string]any{}, nil); err != nil {
+		logger.DebugCF("matrix", "Failed to redact placeholder", map[string]any{"error": err.Error()})
+	}
+}
+
+// sendAttachment uploads a file to the media repository and posts it.
+func (c *MatrixChannel) sendAttachment(ctx context.Context, roomID, threadID string, a bus.Attachment) error {
+	data, err := loadAttachment(ctx, a)
+	if err != nil {
+		return fmt.Errorf("failed to load attachment %s: %w", a.Name(), err)
+	}
+
+	query := url.Values{"filename": {a.Name()}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.homeserver+"/_matrix/media/v3/upload?"+query.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", a.ContentType())
+	var upload struct {
+		ContentURI string `json:"content_uri"`
+	}
+	if err := c.do(req, &upload); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", a.Name(), err)
+	}
+
+	msgType := "m.file"
+	switch a.Kind() {
+	case bus.KindImage:
+		msgType = "m.image"
+	case bus.KindVideo:
+		msgType = "m.video"
+	case bus.KindAudio:
+		msgType = "m.audio"
+	}
+	body := a.Caption
+	if body == "" {
+		body = a.Name()
+	}
+	content := map[string]any{
+		"msgtype":  msgType,
+		"body":     body,
+		"filename": a.Name(),
+		"url":      upload.ContentURI,
+		"info":     map[string]any{"mimetype": a.ContentType(), "size": len(data)},
+	}
+	if rel := matrixRelation(threadID, ""); rel != nil {
+		content["m.relates_to"] = rel
+	}
+	_, err = c.sendEvent(ctx, roomID, "m.room.message", content)
+	return err
+}
@@ -0,0 +1,275 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+type fakeMatrixEvent struct {
+	Path    string
+	Content map[string]any
+}
+
+// fakeHomeserver implements the slice of the client-server API the Matrix
+// channel uses.
+type fakeHomeserver struct {
+	mu       sync.Mutex
+	events   []fakeMatrixEvent
+	joined   []string
+	uploads  []string
+	redacted []string
+	nextID   int
+}
+
+func (f *fakeHomeserver) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /_matrix/client/v3/login", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Password string `json:"password"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Password != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"errcode":"M_FORBIDDEN","error":"Invalid password"}`)
+			return
+		}
+		io.WriteString(w, `{"user_id":"@bot:hs","access_token":"tok","device_id":"DEV"}`)
+	})
+
+	authed := http.NewServeMux()
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"errcode":"M_UNKNOWN_TOKEN","error":"bad token"}`)
+			return
+		}
+		authed.ServeHTTP(w, r)
+	}))
+
+	authed.HandleFunc("GET /_matrix/client/v3/profile/{user}/displayname", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"displayname":"Claw"}`)
+	})
+	authed.HandleFunc("POST /_matrix/client/v3/join/{room}", func(w http.ResponseWriter, r *http.Request) {
+		room := r.PathValue("room")
+		f.mu.Lock()
+		f.joined = append(f.joined, room)
+		f.mu.Unlock()
+		if room == "#room:hs" {
+			room = "!room:hs"
+		}
+		fmt.Fprintf(w, `{"room_id":%q}`, room)
+	})
+	authed.HandleFunc("GET /_matrix/client/v3/sync", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("since") {
+		case "":
+			// Backlog from before startup must be skipped.
+			io.WriteString(w, `{"next_batch":"s1","rooms":{"join":{"!room:hs":{"timeline":{"events":[
+				{"type":"m.room.message","event_id":"$old","sender":"@alice:hs","content":{"msgtype":"m.text","body":"@bot:hs old"}}
+			]}}}}}`)
+		case "s1":
+			io.WriteString(w, `{"next_batch":"s2","rooms":{
+				"invite":{"!dm:hs":{}, "!other:hs":{}},
+				"join":{
+					"!room:hs":{"summary":{"m.joined_member_count":5},"timeline":{"events":[
+						{"type":"m.room.message","event_id":"$self","sender":"@bot:hs","content":{"msgtype":"m.text","body":"@bot:hs echo"}},
+						{"type":"m.room.message","event_id":"$chat","sender":"@alice:hs","content":{"msgtype":"m.text","body":"just chatting"}},
+						{"type":"m.room.encrypted","event_id":"$enc","sender":"@alice:hs","content":{}},
+						{"type":"m.room.message","event_id":"$q","sender":"@alice:hs","content":{
+							"msgtype":"m.text","body":"Claw: what time is it?",
+							"m.mentions":{"user_ids":["@bot:hs"]},
+							"m.relates_to":{"rel_type":"m.thread","event_id":"$root"}}}
+					]}},
+					"!dm:hs":{"summary":{"m.joined_member_count":2},"timeline":{"events":[
+						{"type":"m.room.message","event_id":"$img","sender":"@alice:hs","content":{
+							"msgtype":"m.image","body":"cat.png","url":"mxc://hs/abc","info":{"mimetype":"image/png"}}}
+					]}},
+					"!other:hs":{"summary":{"m.joined_member_count":2},"timeline":{"events":[
+						{"type":"m.room.message","event_id":"$x","sender":"@alice:hs","content":{"msgtype":"m.text","body":"not allowed"}}
+					]}}
+				}}}`)
+		default:
+			select {
+			case <-r.Context().Done():
+			case <-time.After(50 * time.Millisecond):
+			}
+			io.WriteString(w, `{"next_batch":"s2"}`)
+		}
+	})
+	authed.HandleFunc("GET /_matrix/client/v1/media/download/hs/abc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, "png-bytes")
+	})
+	authed.HandleFunc("PUT /_matrix/client/v3/rooms/{room}/send/{type}/{txn}", func(w http.ResponseWriter, r *http.Request) {
+		var content map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&content))
+		f.mu.Lock()
+		f.nextID++
+		id := fmt.Sprintf("$ev%d", f.nextID)
+		f.events = append(f.events, fakeMatrixEvent{Path: r.PathValue("room"), Content: content})
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"event_id":%q}`, id)
+	})
+	authed.HandleFunc("PUT /_matrix/client/v3/rooms/{room}/redact/{event}/{txn}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.redacted = append(f.redacted, r.PathValue("event"))
+		f.mu.Unlock()
+		io.WriteString(w, `{"event_id":"$redaction"}`)
+	})
+	authed.HandleFunc("POST /_matrix/media/v3/upload", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.uploads = append(f.uploads, r.URL.Query().Get("filename")+";"+r.Header.Get("Content-Type"))
+		f.mu.Unlock()
+		io.WriteString(w, `{"content_uri":"mxc://hs/uploaded"}`)
+	})
+	return mux
+}
+
+func (f *fakeHomeserver) sent() []fakeMatrixEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeMatrixEvent(nil), f.events...)
+}
+
+func TestNewMatrixChannel(t *testing.T) {
+	messageBus := bus.NewMessageBus()
+
+	_, err := NewMatrixChannel(config.MatrixConfig{AccessToken: "tok"}, messageBus)
+	assert.ErrorContains(t, err, "homeserver is required")
+
+	_, err = NewMatrixChannel(config.MatrixConfig{Homeserver: "https://hs", UserID: "@bot:hs"}, messageBus)
+	assert.ErrorContains(t, err, "access_token")
+
+	ch, err := NewMatrixChannel(config.MatrixConfig{Homeserver: "https://hs/", AccessToken: "tok"}, messageBus)
+	require.NoError(t, err)
+	assert.Equal(t, "matrix", ch.Name())
+	assert.Equal(t, "https://hs", ch.homeserver)
+}
+
+func TestParseMatrixChatID(t *testing.T) {
+	room, thread := parseMatrixChatID("!abc:hs/$root")
+	assert.Equal(t, "!abc:hs", room)
+	assert.Equal(t, "$root", thread)
+
+	room, thread = parseMatrixChatID("!abc:hs")
+	assert.Equal(t, "!abc:hs", room)
+	assert.Empty(t, thread)
+}
+
+func TestMatrixChannel_StripMention(t *testing.T) {
+	ch := &MatrixChannel{userID: "@bot:hs", displayName: "Kelvin"}
+	assert.Equal(t, "hello", ch.stripMention("@bot:hs: hello"))
+	assert.Equal(t, "hello", ch.stripMention("kelvin, hello"))
+	// U+212A KELVIN SIGN folds to "k" but is three bytes long.
+	assert.Equal(t, "what time is it?", ch.stripMention("\u212Aelvin: what time is it?"))
+
+	ch.displayName = "Zoë"
+	assert.Equal(t, "ping ünïcode", ch.stripMention("ZOË: ping ünïcode"))
+}
+
+func TestMatrixChannel_FakeHomeserver(t *testing.T) {
+	fake := &fakeHomeserver{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	messageBus := bus.NewMessageBus()
+	ch, err := NewMatrixChannel(config.MatrixConfig{
+		Homeserver: server.URL,
+		UserID:     "@bot:hs",
+		Password:   "secret",
+		Rooms:      []string{"#room:hs", "!dm:hs"},
+		AutoJoin:   true,
+	}, messageBus)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, ch.Start(ctx))
+	defer ch.Stop(context.Background())
+
+	inbound := map[string]bus.InboundMessage{}
+	for len(inbound) < 2 {
+		msg, ok := messageBus.ConsumeInbound(ctx)
+		require.True(t, ok, "timed out waiting for inbound messages")
+		inbound[msg.ChatID] = msg
+	}
+
+	// Group room: only the mention is delivered, in its thread, with the
+	// mention stripped.
+	q, ok := inbound["!room:hs/$root"]
+	require.True(t, ok, "missing threaded group message: %v", inbound)
+	assert.Equal(t, "what time is it?", q.Content)
+	assert.Equal(t, "@alice:hs", q.SenderID)
+	assert.Equal(t, "group", q.Metadata["peer_kind"])
+	assert.Equal(t, "$root", q.Metadata["thread_id"])
+	assert.Equal(t, "$q", q.Metadata["message_id"])
+
+	// Direct room: media is downloaded into Media.
+	img, ok := inbound["!dm:hs"]
+	require.True(t, ok, "missing direct message: %v", inbound)
+	assert.Equal(t, "[image: cat.png]", img.Content)
+	assert.Equal(t, "direct", img.Metadata["peer_kind"])
+	require.Len(t, img.Media, 1)
+	data, err := os.ReadFile(img.Media[0])
+	require.NoError(t, err)
+	assert.Equal(t, "png-bytes", string(data))
+	os.Remove(img.Media[0])
+
+	fake.mu.Lock()
+	assert.ElementsMatch(t, []string{"#room:hs", "!dm:hs", "!dm:hs"}, fake.joined,
+		"configured rooms joined and only the allowed invite accepted")
+	fake.mu.Unlock()
+
+	// Text replies edit the placeholder in place.
+	placeholders := fake.sent()
+	require.Len(t, placeholders, 2)
+	require.NoError(t, ch.Send(ctx, bus.OutboundMessage{Channel: "matrix", ChatID: "!room:hs/$root", Content: "noon"}))
+	events := fake.sent()
+	edit := events[len(events)-1]
+	assert.Equal(t, "!room:hs", edit.Path)
+	assert.Equal(t, map[string]any{"msgtype": "m.text", "body": "noon"}, edit.Content["m.new_content"])
+	relatesTo := edit.Content["m.relates_to"].(map[string]any)
+	assert.Equal(t, "m.replace", relatesTo["rel_type"])
+
+	// Media replies redact the placeholder and upload the file.
+	path := filepath.Join(t.TempDir(), "chart.png")
+	require.NoError(t, os.WriteFile(path, []byte("png"), 0o600))
+	require.NoError(t, ch.Send(ctx, bus.OutboundMessage{
+		Channel: "matrix",
+		ChatID:  "!dm:hs",
+		Media:   []bus.Attachment{{Path: path, Caption: "today"}},
+	}))
+	events = fake.sent()
+	last := events[len(events)-1]
+	assert.Equal(t, "m.image", last.Content["msgtype"])
+	assert.Equal(t, "today", last.Content["body"])
+	assert.Equal(t, "mxc://hs/uploaded", last.Content["url"])
+
+	fake.mu.Lock()
+	assert.Len(t, fake.redacted, 1)
+	require.Len(t, fake.uploads, 1)
+	assert.True(t, strings.HasPrefix(fake.uploads[0], "chart.png;image/png"), fake.uploads[0])
+	fake.mu.Unlock()
+
+	// Threaded sends without a placeholder carry the thread relation.
+	require.NoError(t, ch.Send(ctx, bus.OutboundMessage{Channel: "matrix", ChatID: "!room:hs/$root", Content: "again", Silent: true}))
+	events = fake.sent()
+	last = events[len(events)-1]
+	assert.Equal(t, "m.notice", last.Content["msgtype"])
+	assert.Equal(t, "m.thread", last.Content["m.relates_to"].(map[string]any)["rel_type"])
+}
@@ -0,0 +1,122 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/utils"
+)
+
+// maxAttachmentBytes caps outbound attachments, which are held in memory
+// while uploading.
+const maxAttachmentBytes = 50 << 20
+
+// attachmentClient downloads URL attachments. The URL may come from the
+// model (send_file), so only public addresses are reachable.
+var attachmentClient = utils.NewPublicHTTPClient(60 * time.Second)
+
+// loadAttachment reads an outbound attachment from disk or downloads it.
+func loadAttachment(ctx context.Context, a bus.Attachment) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case a.Path != "":
+		f, err := os.Open(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	case a.URL != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := attachmentClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("download %s: HTTP %d", a.URL, resp.StatusCode)
+		}
+		r = resp.Body
+	default:
+		return nil, errors.New("attachment has neither path nor url")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxAttachmentBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAttachmentBytes {
+		return nil, fmt.Errorf("attachment %s exceeds %d MB", a.Name(), maxAttachmentBytes>>20)
+	}
+	return data, nil
+}
+
+// namedReader gives in-memory attachment data the file name SDK upload
+// helpers expect.
+type namedReader struct {
+	*bytes.Reader
+	name string
+}
+
+func (r namedReader) Name() string { return r.name }
+
+func newNamedReader(name string, data []byte) namedReader {
+	return namedReader{Reader: bytes.NewReader(data), name: name}
+}
+
+// buttonReplyKey returns reply itself when it fits a platform's limit on
+// button payloads, otherwise a short key under which the reply is stored
+// in replies for lookup when the button is pressed.
+func buttonReplyKey(reply string, limit int, replies *sync.Map) string {
+	if len(reply) <= limit {
+		return reply
+	}
+	sum := sha256.Sum256([]byte(reply))
+	key := "btn:" + hex.EncodeToString(sum[:12])
+	replies.Store(key, reply)
+	return key
+}
+
+// buttonReply resolves a button payload produced by buttonReplyKey.
+func buttonReply(payload string, replies *sync.Map) string {
+	if v, ok := replies.Load(payload); ok {
+		reply, _ := v.(string)
+		return reply
+	}
+	return payload
+}
+
+// saveMediaFile writes inbound file content to the shared media directory
+// used by utils.DownloadFile and returns its path, or "" on failure.
+func saveMediaFile(component, name string, data []byte) string {
+	mediaDir := filepath.Join(os.TempDir(), "tinyclaw_media")
+	if err := os.MkdirAll(mediaDir, 0o700); err != nil {
+		return ""
+	}
+	localPath := filepath.Join(mediaDir, uuid.New().String()[:8]+"_"+utils.SanitizeFilename(name))
+	if err := os.WriteFile(localPath, data, 0o600); err != nil {
+		logger.WarnCF(component, "Failed to save file", map[string]any{
+			"name":  name,
+			"error": err.Error(),
+		})
+		return ""
+	}
+	return localPath
+}
@@ -0,0 +1,74 @@
+package channels
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+)
+
+func TestLoadAttachment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.txt")
+	if err := os.WriteFile(path, []byte("local"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("remote"))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	if _, err := loadAttachment(ctx, bus.Attachment{URL: srv.URL + "/file"}); err == nil ||
+		!strings.Contains(err.Error(), "non-public") {
+		t.Errorf("expected loopback download to be refused, got %v", err)
+	}
+
+	defaultClient := attachmentClient
+	attachmentClient = srv.Client()
+	defer func() { attachmentClient = defaultClient }()
+
+	if data, err := loadAttachment(ctx, bus.Attachment{Path: path}); err != nil || string(data) != "local" {
+		t.Errorf("path attachment = %q, %v", data, err)
+	}
+	if data, err := loadAttachment(ctx, bus.Attachment{URL: srv.URL + "/file"}); err != nil || string(data) != "remote" {
+		t.Errorf("url attachment = %q, %v", data, err)
+	}
+	if _, err := loadAttachment(ctx, bus.Attachment{URL: srv.URL + "/missing"}); err == nil {
+		t.Error("expected error for HTTP 404")
+	}
+	if _, err := loadAttachment(ctx, bus.Attachment{}); err == nil {
+		t.Error("expected error for empty attachment")
+	}
+}
+
+func TestButtonReplyKey(t *testing.T) {
+	var replies sync.Map
+
+	if got := buttonReplyKey("yes", 64, &replies); got != "yes" {
+		t.Errorf("short reply key = %q, want it unchanged", got)
+	}
+
+	long := strings.Repeat("approve the deployment ", 5)
+	key := buttonReplyKey(long, 64, &replies)
+	if len(key) > 64 || !strings.HasPrefix(key, "btn:") {
+		t.Fatalf("long reply key = %q", key)
+	}
+	if got := buttonReply(key, &replies); got != long {
+		t.Errorf("buttonReply(%q) = %q, want original reply", key, got)
+	}
+	if got := buttonReply("yes", &replies); got != "yes" {
+		t.Errorf("buttonReply passthrough = %q", got)
+	}
+}
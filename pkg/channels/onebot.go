@@ -444,7 +444,7 @@ func (c *OneBotChannel) buildMessageSegments(chatID, content string) []oneBotMes
 
 func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, any, error) {
 	chatID := msg.ChatID
-	segments := c.buildMessageSegments(chatID, msg.Content)
+	segments := c.buildMessageSegments(chatID, msg.PlainText())
 
 	var action, idKey string
 	var rawID string
@@ -125,7 +125,7 @@ func (c *QQChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 
 	// construct message
 	msgToCreate := &dto.MessageToCreate{
-		Content: msg.Content,
+		Content: msg.PlainText(),
 	}
 
 	// send C2C message
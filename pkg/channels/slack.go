@@ -1,6 +1,7 @@
 package channels
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -120,18 +121,48 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	if channelID == "" {
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
-
-	opts := []slack.MsgOption{
-		slack.MsgOptionText(msg.Content, false),
+	// An explicit thread wins; replying to a message means threading under it.
+	switch {
+	case msg.ThreadID != "":
+		threadTS = msg.ThreadID
+	case msg.ReplyTo != "":
+		threadTS = msg.ReplyTo
 	}
 
-	if threadTS != "" {
-		opts = append(opts, slack.MsgOptionTS(threadTS))
+	if msg.Content != "" || len(msg.Buttons) > 0 {
+		opts := []slack.MsgOption{
+			slack.MsgOptionText(msg.Content, false),
+		}
+		if blocks := slackBlocks(msg); len(blocks) > 0 {
+			opts = append(opts, slack.MsgOptionBlocks(blocks...))
+		}
+
+		if threadTS != "" {
+			opts = append(opts, slack.MsgOptionTS(threadTS))
+		}
+
+		_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to send slack message: %w", err)
+		}
 	}
 
-	_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
-	if err != nil {
-		return fmt.Errorf("failed to send slack message: %w", err)
+	for _, a := range msg.Media {
+		data, err := loadAttachment(ctx, a)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", a.Name(), err)
+		}
+		_, err = c.api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+			Reader:          bytes.NewReader(data),
+			FileSize:        len(data),
+			Filename:        a.Name(),
+			InitialComment:  a.Caption,
+			Channel:         channelID,
+			ThreadTimestamp: threadTS,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload %s to slack: %w", a.Name(), err)
+		}
 	}
 
 	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
@@ -143,13 +174,48 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	}
 
 	logger.DebugCF("slack", "Message sent", map[string]any{
-		"channel_id": channelID,
-		"thread_ts":  threadTS,
+		"channel_id":  channelID,
+		"thread_ts":   threadTS,
+		"attachments": len(msg.Media),
 	})
 
 	return nil
 }
 
+// slackSectionChars is the text limit of a Slack section block.
+const slackSectionChars = 3000
+
+// slackButtonAction prefixes the action IDs of quick-reply buttons.
+const slackButtonAction = "tinyclaw_reply_"
+
+// slackBlocks renders the message as Block Kit when it has buttons; plain
+// messages are sent as text so Slack formats them as before.
+func slackBlocks(msg bus.OutboundMessage) []slack.Block {
+	if len(msg.Buttons) == 0 {
+		return nil
+	}
+	var blocks []slack.Block
+	for _, part := range utils.SplitMessage(msg.Content, slackSectionChars) {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, part, false, false), nil, nil))
+	}
+	elements := make([]slack.BlockElement, 0, len(msg.Buttons))
+	for i, b := range msg.Buttons {
+		value := b.Reply()
+		if b.URL != "" {
+			value = "" // link buttons open the URL and send nothing back
+		}
+		btn := slack.NewButtonBlockElement(
+			fmt.Sprintf("%s%d", slackButtonAction, i),
+			value,
+			slack.NewTextBlockObject(slack.PlainTextType, b.Text, false, false),
+		)
+		btn.URL = b.URL
+		elements = append(elements, btn)
+	}
+	return append(blocks, slack.NewActionBlock("", elements...))
+}
+
 func (c *SlackChannel) eventLoop() {
 	for {
 		select {
@@ -168,6 +234,7 @@ func (c *SlackChannel) eventLoop() {
 				if event.Request != nil {
 					c.socketClient.Ack(*event.Request)
 				}
+				c.handleInteraction(event)
 			default:
 				// Ignore other event types (connecting, disconnect, errors, etc.)
 			}
@@ -417,6 +484,50 @@ func (c *SlackChannel) handleSlashCommand(event socketmode.Event) {
 	c.HandleMessage(senderID, chatID, content, nil, metadata)
 }
 
+// handleInteraction turns a quick-reply button press into a user message.
+func (c *SlackChannel) handleInteraction(event socketmode.Event) {
+	callback, ok := event.Data.(slack.InteractionCallback)
+	if !ok || callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+	if !c.IsAllowed(callback.User.ID) {
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		if !strings.HasPrefix(action.ActionID, slackButtonAction) || action.Value == "" {
+			continue
+		}
+		channelID := callback.Channel.ID
+		threadTS := callback.Container.ThreadTs
+		if threadTS == "" {
+			threadTS = callback.Message.ThreadTimestamp
+		}
+		chatID := channelID
+		if threadTS != "" {
+			chatID = channelID + "/" + threadTS
+		}
+
+		peerKind := "channel"
+		peerID := channelID
+		if strings.HasPrefix(channelID, "D") {
+			peerKind = "direct"
+			peerID = callback.User.ID
+		}
+
+		c.HandleMessage(callback.User.ID, chatID, action.Value, nil, map[string]string{
+			"message_ts":  callback.Container.MessageTs,
+			"channel_id":  channelID,
+			"thread_ts":   threadTS,
+			"platform":    "slack",
+			"is_callback": "true",
+			"peer_kind":   peerKind,
+			"peer_id":     peerID,
+			"team_id":     c.teamID,
+		})
+	}
+}
+
 func (c *SlackChannel) downloadSlackFile(file slack.File) string {
 	downloadURL := file.URLPrivateDownload
 	if downloadURL == "" {
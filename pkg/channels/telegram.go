@@ -34,6 +34,8 @@ type TelegramChannel struct {
 	transcriber  *voice.GroqTranscriber
 	placeholders sync.Map // chatID -> messageID
 	stopThinking sync.Map // chatID -> thinkingCancel
+
+	buttonReplies sync.Map // callback key -> button reply too long for callback data
 }
 
 type thinkingCancel struct {
@@ -142,6 +144,13 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		th.AnyMessage(),
 	)
 
+	bh.HandleCallbackQuery(
+		func(ctx *th.Context, query telego.CallbackQuery) error { //nolint:contextcheck // telego handler callback; ctx is th.Context, not context.Context
+			return c.handleCallbackQuery(ctx, query)
+		},
+		th.AnyCallbackQueryWithMessage(),
+	)
+
 	c.setRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]any{
 		"username": c.bot.Username(),
@@ -183,31 +192,194 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 
 	htmlContent := markdownToTelegramHTML(msg.Content)
 
-	// Try to edit placeholder
 	if pID, ok := c.placeholders.Load(msg.ChatID); ok {
 		c.placeholders.Delete(msg.ChatID)
 		placeholderID, _ := pID.(int)
-		editMsg := tu.EditMessageText(tu.ID(chatID), placeholderID, htmlContent)
-		editMsg.ParseMode = telego.ModeHTML
+		if msg.IsRich() || htmlContent == "" {
+			// Replies, buttons and files need a fresh message; drop the placeholder.
+			_ = c.bot.DeleteMessage(ctx, tu.Delete(tu.ID(chatID), placeholderID))
+		} else {
+			// Try to edit placeholder
+			editMsg := tu.EditMessageText(tu.ID(chatID), placeholderID, htmlContent)
+			editMsg.ParseMode = telego.ModeHTML
+
+			if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil {
+				return nil
+			}
+			// Fallback to new message if edit fails
+		}
+	}
 
-		if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil {
-			return nil
+	opts := c.sendOptions(msg)
+	keyboard := c.inlineKeyboard(msg.Buttons)
+
+	if htmlContent != "" {
+		tgMsg := tu.Message(tu.ID(chatID), htmlContent)
+		tgMsg.ParseMode = telego.ModeHTML
+		tgMsg.MessageThreadID = opts.threadID
+		tgMsg.ReplyParameters = opts.replyTo
+		tgMsg.DisableNotification = opts.silent
+		if keyboard != nil {
+			tgMsg.ReplyMarkup = keyboard
+		}
+
+		if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+			logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]any{
+				"error": err.Error(),
+			})
+			tgMsg.Text = msg.Content
+			tgMsg.ParseMode = ""
+			if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+				return err
+			}
 		}
-		// Fallback to new message if edit fails
+		// Only the first message replies; the keyboard stays on the text.
+		opts.replyTo = nil
+		keyboard = nil
 	}
 
-	tgMsg := tu.Message(tu.ID(chatID), htmlContent)
-	tgMsg.ParseMode = telego.ModeHTML
+	for i, a := range msg.Media {
+		var markup *telego.InlineKeyboardMarkup
+		if i == len(msg.Media)-1 {
+			markup = keyboard
+		}
+		if err := c.sendAttachment(ctx, chatID, a, opts, markup); err != nil {
+			return fmt.Errorf("failed to send %s: %w", a.Name(), err)
+		}
+		opts.replyTo = nil
+	}
 
-	if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
-		logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]any{
-			"error": err.Error(),
-		})
-		tgMsg.ParseMode = ""
-		_, err = c.bot.SendMessage(ctx, tgMsg)
-		return err
+	return nil
+}
+
+// telegramSendOptions are the per-message options shared by text and media sends.
+type telegramSendOptions struct {
+	threadID int
+	replyTo  *telego.ReplyParameters
+	silent   bool
+}
+
+func (c *TelegramChannel) sendOptions(msg bus.OutboundMessage) telegramSendOptions {
+	opts := telegramSendOptions{silent: msg.Silent}
+	if msg.ThreadID != "" {
+		if id, err := strconv.Atoi(msg.ThreadID); err == nil {
+			opts.threadID = id
+		}
+	}
+	if msg.ReplyTo != "" {
+		if id, err := strconv.Atoi(msg.ReplyTo); err == nil {
+			opts.replyTo = &telego.ReplyParameters{MessageID: id, AllowSendingWithoutReply: true}
+		}
+	}
+	return opts
+}
+
+// telegramCallbackLimit is Telegram's maximum callback_data size in bytes.
+const telegramCallbackLimit = 64
+
+// inlineKeyboard lays buttons out one per row. Replies that do not fit in
+// callback data are stored and referenced by a short key.
+func (c *TelegramChannel) inlineKeyboard(buttons []bus.Button) *telego.InlineKeyboardMarkup {
+	if len(buttons) == 0 {
+		return nil
+	}
+	rows := make([][]telego.InlineKeyboardButton, 0, len(buttons))
+	for _, b := range buttons {
+		btn := tu.InlineKeyboardButton(b.Text)
+		if b.URL != "" {
+			btn = btn.WithURL(b.URL)
+		} else {
+			btn = btn.WithCallbackData(buttonReplyKey(b.Reply(), telegramCallbackLimit, &c.buttonReplies))
+		}
+		rows = append(rows, tu.InlineKeyboardRow(btn))
+	}
+	return tu.InlineKeyboard(rows...)
+}
+
+func (c *TelegramChannel) sendAttachment(
+	ctx context.Context,
+	chatID int64,
+	a bus.Attachment,
+	opts telegramSendOptions,
+	markup *telego.InlineKeyboardMarkup,
+) error {
+	var file telego.InputFile
+	if a.URL != "" {
+		file = tu.FileFromURL(a.URL)
+	} else {
+		data, err := loadAttachment(ctx, a)
+		if err != nil {
+			return err
+		}
+		file = tu.File(newNamedReader(a.Name(), data))
+	}
+
+	var replyMarkup telego.ReplyMarkup
+	if markup != nil {
+		replyMarkup = markup
+	}
+
+	var err error
+	switch a.Kind() {
+	case bus.KindImage:
+		p := tu.Photo(tu.ID(chatID), file)
+		p.Caption, p.MessageThreadID, p.ReplyParameters = a.Caption, opts.threadID, opts.replyTo
+		p.DisableNotification, p.ReplyMarkup = opts.silent, replyMarkup
+		_, err = c.bot.SendPhoto(ctx, p)
+	case bus.KindVideo:
+		p := tu.Video(tu.ID(chatID), file)
+		p.Caption, p.MessageThreadID, p.ReplyParameters = a.Caption, opts.threadID, opts.replyTo
+		p.DisableNotification, p.ReplyMarkup = opts.silent, replyMarkup
+		_, err = c.bot.SendVideo(ctx, p)
+	case bus.KindAudio:
+		p := tu.Audio(tu.ID(chatID), file)
+		p.Caption, p.MessageThreadID, p.ReplyParameters = a.Caption, opts.threadID, opts.replyTo
+		p.DisableNotification, p.ReplyMarkup = opts.silent, replyMarkup
+		_, err = c.bot.SendAudio(ctx, p)
+	default:
+		p := tu.Document(tu.ID(chatID), file)
+		p.Caption, p.MessageThreadID, p.ReplyParameters = a.Caption, opts.threadID, opts.replyTo
+		p.DisableNotification, p.ReplyMarkup = opts.silent, replyMarkup
+		_, err = c.bot.SendDocument(ctx, p)
+	}
+	return err
+}
+
+// handleCallbackQuery turns an inline button press into a user message.
+func (c *TelegramChannel) handleCallbackQuery(ctx context.Context, query telego.CallbackQuery) error {
+	_ = c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+
+	senderID := strconv.FormatInt(query.From.ID, 10)
+	if query.From.Username != "" {
+		senderID += "|" + query.From.Username
+	}
+	if !c.IsAllowed(senderID) || query.Message == nil {
+		return nil
+	}
+
+	content := buttonReply(query.Data, &c.buttonReplies)
+	if content == "" {
+		return nil
 	}
 
+	chat := query.Message.GetChat()
+	peerKind := "direct"
+	peerID := strconv.FormatInt(query.From.ID, 10)
+	if chat.Type != "private" {
+		peerKind = "group"
+		peerID = strconv.FormatInt(chat.ID, 10)
+	}
+
+	c.HandleMessage(strconv.FormatInt(query.From.ID, 10), strconv.FormatInt(chat.ID, 10), content, nil, map[string]string{
+		"message_id":  strconv.Itoa(query.Message.GetMessageID()),
+		"user_id":     strconv.FormatInt(query.From.ID, 10),
+		"username":    query.From.Username,
+		"first_name":  query.From.FirstName,
+		"is_group":    strconv.FormatBool(chat.Type != "private"),
+		"is_callback": "true",
+		"peer_kind":   peerKind,
+		"peer_id":     peerID,
+	})
 	return nil
 }
 
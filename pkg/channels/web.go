@@ -0,0 +1,382 @@
+package channels
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/utils"
+)
+
+// WebPathPrefix is where the web chat UI is served on the gateway.
+const WebPathPrefix = "/chat/"
+
+const (
+	webWriteWait      = 10 * time.Second
+	webPongWait       = 60 * time.Second
+	webPingInterval   = 50 * time.Second
+	webSendBuffer     = 64
+	webDefaultUpload  = 10 << 20
+	webMaxInlineMedia = 5 << 20 // larger local files are named rather than sent
+)
+
+//go:embed webui
+var webUI embed.FS
+
+var webSessionRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// WebChannel serves a browser chat UI and its WebSocket protocol on the
+// gateway. Each browser session is its own conversation, with the session
+// ID as chat ID; every connected tab receives every session's frames.
+type WebChannel struct {
+	*BaseChannel
+
+	config   config.WebConfig
+	maxBody  int64
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	clients map[*webClient]struct{}
+}
+
+type webClient struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+// webFrame is a WebSocket message in either direction. The browser sends
+// "message" and "ping"; the server sends "ready", "user" (a message typed
+// in another tab), "activity", "delta", "interim", "message", "pong" and
+// "error".
+type webFrame struct {
+	Type    string       `json:"type"`
+	Session string       `json:"session,omitempty"`
+	Content string       `json:"content,omitempty"`
+	Files   []webFile    `json:"files,omitempty"`
+	Buttons []bus.Button `json:"buttons,omitempty"`
+	Kind    string       `json:"kind,omitempty"`
+	Tool    string       `json:"tool,omitempty"`
+	IsError bool         `json:"is_error,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// webFile is a file in a frame: base64 Data from the browser, or a URL
+// (possibly a data: URL) to it.
+type webFile struct {
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"`
+	Data    string `json:"data,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// NewWebChannel creates the web chat channel. A token is required since
+// the UI is served on the gateway's public port.
+func NewWebChannel(cfg config.WebConfig, messageBus *bus.MessageBus) (*WebChannel, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("web token is required")
+	}
+	maxBody := cfg.MaxUploadBytes
+	if maxBody <= 0 {
+		maxBody = webDefaultUpload
+	}
+	return &WebChannel{
+		BaseChannel: NewBaseChannel("web", cfg, messageBus, nil),
+		config:      cfg,
+		maxBody:     maxBody,
+		clients:     make(map[*webClient]struct{}),
+	}, nil
+}
+
+func (c *WebChannel) Start(ctx context.Context) error {
+	c.bus.SubscribeEvents(c.Name(), c.handleEvent)
+	c.setRunning(true)
+	logger.InfoC("web", "Web chat channel started")
+	return nil
+}
+
+// Stop disconnects every browser.
+func (c *WebChannel) Stop(ctx context.Context) error {
+	c.setRunning(false)
+	c.mu.Lock()
+	for client := range c.clients {
+		delete(c.clients, client)
+		close(client.send)
+	}
+	c.mu.Unlock()
+	logger.InfoC("web", "Web chat channel stopped")
+	return nil
+}
+
+// Send delivers a reply to every tab showing msg.ChatID. Local files are
+// inlined as data: URLs up to 5 MB.
+func (c *WebChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return errors.New("web channel not running")
+	}
+
+	frame := webFrame{
+		Type:    "message",
+		Session: msg.ChatID,
+		Content: msg.Content,
+		Buttons: msg.Buttons,
+	}
+	for _, a := range msg.Media {
+		file := webFile{Name: a.Name(), Type: a.ContentType(), URL: a.URL, Caption: a.Caption}
+		if a.Path != "" {
+			if info, err := os.Stat(a.Path); err == nil && info.Size() <= webMaxInlineMedia {
+				data, err := os.ReadFile(a.Path)
+				if err != nil {
+					return fmt.Errorf("failed to read attachment %s: %w", a.Name(), err)
+				}
+				file.URL = "data:" + a.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(data)
+			}
+		}
+		frame.Files = append(frame.Files, file)
+	}
+
+	if c.broadcast(frame, nil) == 0 {
+		logger.DebugCF("web", "No browser connected for reply", map[string]any{"session": msg.ChatID})
+	}
+	return nil
+}
+
+// RegisterRoutes serves the UI at /chat/ and the WebSocket at /chat/ws.
+func (c *WebChannel) RegisterRoutes(mux WebhookMux) {
+	ui, _ := fs.Sub(webUI, "webui")
+	mux.Handle("GET "+WebPathPrefix, http.StripPrefix(WebPathPrefix, http.FileServerFS(ui)))
+	mux.Handle("GET "+WebPathPrefix+"ws", http.HandlerFunc(c.serveWebSocket))
+}
+
+func (c *WebChannel) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.config.Token)) == 1
+}
+
+func (c *WebChannel) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !c.IsRunning() {
+		http.Error(w, "Channel not running", http.StatusServiceUnavailable)
+		return
+	}
+	if !c.authorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := c.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.DebugCF("web", "WebSocket upgrade failed", map[string]any{"error": err.Error()})
+		return
+	}
+	// Base64 inflates uploads by a third; leave room for the JSON around them.
+	conn.SetReadLimit(c.maxBody*4/3 + 64<<10)
+
+	client := &webClient{conn: conn, send: make(chan []byte, webSendBuffer)}
+	c.mu.Lock()
+	c.clients[client] = struct{}{}
+	c.mu.Unlock()
+
+	go c.writeLoop(client)
+	c.push(client, webFrame{Type: "ready"})
+	c.readLoop(client)
+}
+
+func (c *WebChannel) readLoop(client *webClient) {
+	defer c.removeClient(client)
+
+	client.conn.SetReadDeadline(time.Now().Add(webPongWait))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(webPongWait))
+	})
+
+	for {
+		var frame webFrame
+		if err := client.conn.ReadJSON(&frame); err != nil {
+			return
+		}
+		client.conn.SetReadDeadline(time.Now().Add(webPongWait))
+
+		switch frame.Type {
+		case "ping":
+			c.push(client, webFrame{Type: "pong"})
+		case "message":
+			if err := c.handleFrame(client, frame); err != nil {
+				c.push(client, webFrame{Type: "error", Session: frame.Session, Error: err.Error()})
+			}
+		default:
+			c.push(client, webFrame{Type: "error", Error: "unknown frame type: " + frame.Type})
+		}
+	}
+}
+
+func (c *WebChannel) writeLoop(client *webClient) {
+	ticker := time.NewTicker(webPingInterval)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(webWriteWait))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(webWriteWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *WebChannel) handleFrame(client *webClient, frame webFrame) error {
+	if !webSessionRe.MatchString(frame.Session) {
+		return errors.New("invalid session ID")
+	}
+	content := strings.TrimSpace(frame.Content)
+
+	files := make([][]byte, len(frame.Files))
+	var total int64
+	for i, f := range frame.Files {
+		data, err := base64.StdEncoding.DecodeString(f.Data)
+		if err != nil {
+			return fmt.Errorf("invalid file data for %s", f.Name)
+		}
+		if total += int64(len(data)); total > c.maxBody {
+			return fmt.Errorf("uploads exceed %d bytes", c.maxBody)
+		}
+		files[i] = data
+	}
+
+	var mediaPaths []string
+	for i, f := range frame.Files {
+		// Files stay in the media directory for the agent to read.
+		if localPath := saveMediaFile("web", f.Name, files[i]); localPath != "" {
+			mediaPaths = append(mediaPaths, localPath)
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += fmt.Sprintf("[file: %s]", f.Name)
+	}
+	if content == "" {
+		return nil
+	}
+
+	logger.DebugCF("web", "Received message", map[string]any{
+		"session": frame.Session,
+		"preview": utils.Truncate(content, 50),
+		"files":   len(mediaPaths),
+	})
+
+	// Show the message in the user's other tabs and the thinking state in all.
+	c.broadcast(webFrame{Type: "user", Session: frame.Session, Content: frame.Content}, client)
+	c.broadcast(webFrame{Type: "activity", Session: frame.Session, Kind: bus.EventThinking}, nil)
+
+	// Sessions route as group peers so each keeps its own history.
+	c.HandleMessage("web", frame.Session, content, mediaPaths, map[string]string{
+		"session":   frame.Session,
+		"peer_kind": "group",
+		"peer_id":   frame.Session,
+	})
+	return nil
+}
+
+// handleEvent relays agent progress: streamed pieces of the reply as
+// "delta" frames, the full text written before a tool call as "interim"
+// and tool calls as "activity".
+func (c *WebChannel) handleEvent(ev bus.AgentEvent) {
+	frame := webFrame{Session: ev.ChatID, Kind: ev.Kind, Tool: ev.Tool, IsError: ev.IsError}
+	switch ev.Kind {
+	case bus.EventDelta:
+		frame.Type = "delta"
+		frame.Content = ev.Content
+	case bus.EventText:
+		frame.Type = "interim"
+		frame.Content = ev.Content
+	default:
+		frame.Type = "activity"
+		frame.Content = utils.Truncate(ev.Content, 200)
+	}
+	c.broadcast(frame, nil)
+}
+
+// push queues a frame for one client.
+func (c *WebChannel) push(client *webClient, frame webFrame) {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enqueue(client, data)
+}
+
+// broadcast queues a frame for every client except skip and returns how
+// many received it.
+func (c *WebChannel) broadcast(frame webFrame, skip *webClient) int {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent := 0
+	for client := range c.clients {
+		if client != skip && c.enqueue(client, data) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// enqueue sends data to client without blocking, disconnecting clients
+// that fall too far behind. c.mu must be held.
+func (c *WebChannel) enqueue(client *webClient, data []byte) bool {
+	if _, ok := c.clients[client]; !ok {
+		return false
+	}
+	select {
+	case client.send <- data:
+		return true
+	default:
+		delete(c.clients, client)
+		close(client.send)
+		return false
+	}
+}
+
+func (c *WebChannel) removeClient(client *webClient) {
+	c.mu.Lock()
+	if _, ok := c.clients[client]; ok {
+		delete(c.clients, client)
+		close(client.send)
+	}
+	c.mu.Unlock()
+}
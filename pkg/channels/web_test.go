@@ -0,0 +1,168 @@
+package channels
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+func startTestWebChannel(t *testing.T) (*WebChannel, *bus.MessageBus, *httptest.Server) {
+	t.Helper()
+	messageBus := bus.NewMessageBus()
+	ch, err := NewWebChannel(config.WebConfig{Enabled: true, Token: "s3cret", MaxUploadBytes: 1024}, messageBus)
+	require.NoError(t, err)
+	require.NoError(t, ch.Start(context.Background()))
+	t.Cleanup(func() { ch.Stop(context.Background()) })
+
+	mux := http.NewServeMux()
+	ch.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return ch, messageBus, server
+}
+
+func dialWeb(t *testing.T, server *httptest.Server, token string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/chat/ws?token=" + token
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func readWebFrame(t *testing.T, conn *websocket.Conn) webFrame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frame webFrame
+	require.NoError(t, conn.ReadJSON(&frame))
+	return frame
+}
+
+func TestNewWebChannel_RequiresToken(t *testing.T) {
+	_, err := NewWebChannel(config.WebConfig{Enabled: true}, bus.NewMessageBus())
+	assert.ErrorContains(t, err, "token is required")
+}
+
+func TestWebChannel_ServesUI(t *testing.T) {
+	_, _, server := startTestWebChannel(t)
+
+	resp, err := http.Get(server.URL + "/chat/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "<title>TinyClaw</title>")
+}
+
+func TestWebChannel_RejectsBadToken(t *testing.T) {
+	_, _, server := startTestWebChannel(t)
+
+	_, resp, err := dialWeb(t, server, "wrong")
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestWebChannel_Conversation(t *testing.T) {
+	ch, messageBus, server := startTestWebChannel(t)
+
+	conn, _, err := dialWeb(t, server, "s3cret")
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "ready", readWebFrame(t, conn).Type)
+
+	other, _, err := dialWeb(t, server, "s3cret")
+	require.NoError(t, err)
+	defer other.Close()
+	assert.Equal(t, "ready", readWebFrame(t, other).Type)
+
+	require.NoError(t, conn.WriteJSON(webFrame{
+		Type:    "message",
+		Session: "s1",
+		Content: "what is in this file?",
+		Files:   []webFile{{Name: "notes.txt", Data: base64.StdEncoding.EncodeToString([]byte("hello"))}},
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, ok := messageBus.ConsumeInbound(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "web", msg.Channel)
+	assert.Equal(t, "s1", msg.ChatID)
+	assert.Equal(t, "what is in this file?\n[file: notes.txt]", msg.Content)
+	assert.Equal(t, "group", msg.Metadata["peer_kind"])
+	require.Len(t, msg.Media, 1)
+	data, err := os.ReadFile(msg.Media[0])
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	os.Remove(msg.Media[0])
+
+	// The other tab sees the message, then both see activity.
+	echo := readWebFrame(t, other)
+	assert.Equal(t, "user", echo.Type)
+	assert.Equal(t, "what is in this file?", echo.Content)
+	assert.Equal(t, bus.EventThinking, readWebFrame(t, conn).Kind)
+	assert.Equal(t, bus.EventThinking, readWebFrame(t, other).Kind)
+
+	messageBus.PublishEvent(bus.AgentEvent{Channel: "web", ChatID: "s1", Kind: bus.EventToolStart, Tool: "read_file"})
+	activity := readWebFrame(t, conn)
+	assert.Equal(t, "activity", activity.Type)
+	assert.Equal(t, "read_file", activity.Tool)
+
+	messageBus.PublishEvent(bus.AgentEvent{Channel: "web", ChatID: "s1", Kind: bus.EventDelta, Content: "Reading"})
+	delta := readWebFrame(t, conn)
+	assert.Equal(t, "delta", delta.Type)
+	assert.Equal(t, "Reading", delta.Content)
+
+	messageBus.PublishEvent(bus.AgentEvent{Channel: "web", ChatID: "s1", Kind: bus.EventText, Content: "Reading it now"})
+	interim := readWebFrame(t, conn)
+	assert.Equal(t, "interim", interim.Type)
+	assert.Equal(t, "Reading it now", interim.Content)
+
+	path := filepath.Join(t.TempDir(), "chart.png")
+	require.NoError(t, os.WriteFile(path, []byte("png"), 0o600))
+	require.NoError(t, ch.Send(ctx, bus.OutboundMessage{
+		Channel: "web",
+		ChatID:  "s1",
+		Content: "It says hello.",
+		Media:   []bus.Attachment{{Path: path}},
+		Buttons: []bus.Button{{Text: "Thanks"}},
+	}))
+	reply := readWebFrame(t, conn)
+	assert.Equal(t, "message", reply.Type)
+	assert.Equal(t, "s1", reply.Session)
+	assert.Equal(t, "It says hello.", reply.Content)
+	require.Len(t, reply.Files, 1)
+	assert.Equal(t, "data:image/png;base64,"+base64.StdEncoding.EncodeToString([]byte("png")), reply.Files[0].URL)
+	require.Len(t, reply.Buttons, 1)
+}
+
+func TestWebChannel_RejectsInvalidFrames(t *testing.T) {
+	_, _, server := startTestWebChannel(t)
+
+	conn, _, err := dialWeb(t, server, "s3cret")
+	require.NoError(t, err)
+	defer conn.Close()
+	readWebFrame(t, conn)
+
+	require.NoError(t, conn.WriteJSON(webFrame{Type: "message", Session: "../etc", Content: "hi"}))
+	assert.Equal(t, "invalid session ID", readWebFrame(t, conn).Error)
+
+	big := base64.StdEncoding.EncodeToString(make([]byte, 900))
+	require.NoError(t, conn.WriteJSON(webFrame{
+		Type: "message", Session: "s1",
+		Files: []webFile{{Name: "a", Data: big}, {Name: "b", Data: big}},
+	}))
+	assert.Contains(t, readWebFrame(t, conn).Error, "uploads exceed")
+}
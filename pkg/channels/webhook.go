@@ -0,0 +1,270 @@
+package channels
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
+)
+
+// WebhookPathPrefix is where webhook channels are mounted on the gateway.
+const WebhookPathPrefix = "/webhooks/"
+
+const (
+	defaultWebhookMaxBody      = 1 << 20
+	defaultWebhookReplayWindow = 5 * time.Minute
+)
+
+// Errors returned by WebhookIngress.CheckReplay. Duplicates are usually
+// platform retries and should be acknowledged without processing; stale
+// requests should be refused.
+var (
+	errWebhookDuplicate = errors.New("webhook delivery already processed")
+	errWebhookStale     = errors.New("webhook timestamp outside replay window")
+)
+
+// WebhookChannel is implemented by channels that receive events over HTTP.
+type WebhookChannel interface {
+	Channel
+	// WebhookHandler returns the handler for inbound webhook requests. It
+	// should use in.CheckReplay to drop redelivered or replayed events.
+	WebhookHandler(in *WebhookIngress) http.Handler
+}
+
+// RouteChannel is implemented by channels that serve their own routes on
+// the gateway instead of a single webhook.
+type RouteChannel interface {
+	Channel
+	RegisterRoutes(mux WebhookMux)
+}
+
+// WebhookMux is the route table webhooks are mounted on; *http.ServeMux
+// and *health.Server both satisfy it.
+type WebhookMux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// WebhookIngress applies the limits shared by all webhook channels: a
+// request body cap, replay protection and per-channel metrics.
+type WebhookIngress struct {
+	maxBody int64
+	window  time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewWebhookIngress creates an ingress from the gateway webhook settings,
+// falling back to 1 MB bodies and a five-minute replay window.
+func NewWebhookIngress(cfg config.WebhooksConfig) *WebhookIngress {
+	in := &WebhookIngress{
+		maxBody: cfg.MaxBodyBytes,
+		window:  time.Duration(cfg.ReplayWindowSeconds) * time.Second,
+		seen:    make(map[string]time.Time),
+	}
+	if in.maxBody <= 0 {
+		in.maxBody = defaultWebhookMaxBody
+	}
+	if in.window <= 0 {
+		in.window = defaultWebhookReplayWindow
+	}
+	return in
+}
+
+// Wrap returns ch's webhook handler behind the shared body limit, a
+// running check and request metrics.
+func (in *WebhookIngress) Wrap(ch WebhookChannel) http.Handler {
+	name := ch.Name()
+	next := ch.WebhookHandler(in)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		defer func() {
+			metrics.WebhookRequests.Inc(name, strconv.Itoa(rec.code))
+			metrics.WebhookDuration.Observe(time.Since(start).Seconds(), name)
+		}()
+
+		if !ch.IsRunning() {
+			metrics.WebhookRejected.Inc(name, "not_running")
+			http.Error(rec, "Channel not running", http.StatusServiceUnavailable)
+			return
+		}
+		if r.ContentLength > in.maxBody {
+			metrics.WebhookRejected.Inc(name, "too_large")
+			http.Error(rec, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(rec, r.Body, in.maxBody)
+		next.ServeHTTP(rec, r)
+
+		if rec.code == http.StatusRequestEntityTooLarge {
+			metrics.WebhookRejected.Inc(name, "too_large")
+		}
+	})
+}
+
+// CheckReplay records key for channel. It returns errWebhookStale when ts
+// falls outside the replay window and errWebhookDuplicate when key was
+// already seen within it. A zero ts skips the freshness check.
+func (in *WebhookIngress) CheckReplay(channel, key string, ts time.Time) error {
+	now := time.Now()
+	if !ts.IsZero() && (now.Sub(ts) > in.window || ts.Sub(now) > in.window) {
+		metrics.WebhookRejected.Inc(channel, "replay")
+		return errWebhookStale
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if now.Sub(in.lastPrune) > in.window {
+		for k, seenAt := range in.seen {
+			if now.Sub(seenAt) > in.window {
+				delete(in.seen, k)
+			}
+		}
+		in.lastPrune = now
+	}
+
+	id := channel + "\x00" + key
+	if seenAt, ok := in.seen[id]; ok && now.Sub(seenAt) <= in.window {
+		metrics.WebhookRejected.Inc(channel, "duplicate")
+		return errWebhookDuplicate
+	}
+	in.seen[id] = now
+	return nil
+}
+
+// accept applies CheckReplay to a whole request. Duplicates are answered
+// with ack so the platform stops retrying, and stale requests with 403.
+// It reports whether the request should be processed; a nil ingress
+// accepts everything.
+func (in *WebhookIngress) accept(w http.ResponseWriter, channel, key string, ts time.Time, ack string) bool {
+	if in == nil {
+		return true
+	}
+	switch err := in.CheckReplay(channel, key, ts); {
+	case errors.Is(err, errWebhookDuplicate):
+		w.Write([]byte(ack))
+		return false
+	case err != nil:
+		http.Error(w, "Request expired", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Mount registers every webhook channel in channels on mux under
+// /webhooks/<name> and returns the mounted names in sorted order.
+func (in *WebhookIngress) Mount(mux WebhookMux, channels map[string]Channel) []string {
+	var mounted []string
+	for name, ch := range channels {
+		wc, ok := ch.(WebhookChannel)
+		if !ok {
+			continue
+		}
+		mux.Handle(WebhookPathPrefix+name, in.Wrap(wc))
+		mounted = append(mounted, name)
+	}
+	sort.Strings(mounted)
+	return mounted
+}
+
+// startWebhookServer serves handler on a dedicated listener. It backs the
+// per-channel webhook_port setting kept for setups that predate the shared
+// gateway routes.
+func startWebhookServer(component, addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logger.InfoCF(component, "Dedicated webhook server listening", map[string]any{
+			"addr": addr,
+		})
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.ErrorCF(component, "Webhook server error", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}()
+	return server
+}
+
+// readWebhookBody reads the request body, answering 413 when it exceeds
+// the ingress limit and 400 on other read errors.
+func readWebhookBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		return body, true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+	} else {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+	}
+	return nil, false
+}
+
+// parseUnixSeconds parses a webhook timestamp parameter, returning the
+// zero time when it is not a valid number.
+func parseUnixSeconds(s string) time.Time {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// rejectSignature answers a webhook request whose signature did not verify.
+func rejectSignature(w http.ResponseWriter, channel string) {
+	metrics.WebhookRejected.Inc(channel, "signature")
+	http.Error(w, "Invalid signature", http.StatusForbidden)
+}
+
+// verifyHMACSHA256 reports whether signature is the HMAC-SHA256 of body
+// keyed with secret. Base64 and hex encodings are accepted, with or
+// without a "sha256=" prefix.
+func verifyHMACSHA256(secret string, body []byte, signature string) bool {
+	signature = strings.TrimPrefix(signature, "sha256=")
+	if signature == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	sum := mac.Sum(nil)
+
+	if got, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(got, sum) {
+		return true
+	}
+	if got, err := hex.DecodeString(signature); err == nil && hmac.Equal(got, sum) {
+		return true
+	}
+	return false
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
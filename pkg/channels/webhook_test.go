@@ -0,0 +1,206 @@
+package channels
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
+)
+
+func signLINE(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newTestLINEChannel(t *testing.T) *LINEChannel {
+	t.Helper()
+	ch, err := NewLINEChannel(config.LINEConfig{
+		ChannelSecret:      "line-secret",
+		ChannelAccessToken: "token",
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+func TestWebhookIngress_Mount(t *testing.T) {
+	line := newTestLINEChannel(t)
+	line.setRunning(true)
+
+	mux := http.NewServeMux()
+	in := NewWebhookIngress(config.WebhooksConfig{MaxBodyBytes: 64})
+	mounted := in.Mount(mux, map[string]Channel{
+		"line":    line,
+		"maixcam": &MaixCamChannel{BaseChannel: NewBaseChannel("maixcam", nil, nil, nil)},
+	})
+	if len(mounted) != 1 || mounted[0] != "line" {
+		t.Fatalf("mounted = %v, want [line]", mounted)
+	}
+
+	body := []byte(`{"events":[]}`)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/line", bytes.NewReader(body))
+	req.Header.Set("X-Line-Signature", signLINE("line-secret", body))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("signed request status = %d, want 200", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/line", bytes.NewReader(body))
+	req.Header.Set("X-Line-Signature", "bogus")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("bad signature status = %d, want 403", w.Code)
+	}
+
+	large := []byte(`{"events":[],"pad":"` + strings.Repeat("x", 100) + `"}`)
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/line", bytes.NewReader(large))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body status = %d, want 413", w.Code)
+	}
+
+	// Unknown length bodies are cut off while reading.
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/line", bytes.NewReader(large))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed oversized body status = %d, want 413", w.Code)
+	}
+
+	if got := metrics.WebhookRejected.Value("line", "signature"); got < 1 {
+		t.Errorf("signature rejections = %v, want >= 1", got)
+	}
+	if got := metrics.WebhookRequests.Value("line", "200"); got < 1 {
+		t.Errorf("ok requests = %v, want >= 1", got)
+	}
+}
+
+func TestWebhookIngress_NotRunning(t *testing.T) {
+	line := newTestLINEChannel(t)
+	handler := NewWebhookIngress(config.WebhooksConfig{}).Wrap(line)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks/line", strings.NewReader("{}")))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestWebhookIngress_CheckReplay(t *testing.T) {
+	in := NewWebhookIngress(config.WebhooksConfig{ReplayWindowSeconds: 60})
+	now := time.Now()
+
+	if err := in.CheckReplay("test", "a", now); err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+	if err := in.CheckReplay("test", "a", now); !errors.Is(err, errWebhookDuplicate) {
+		t.Errorf("repeat delivery err = %v, want duplicate", err)
+	}
+	if err := in.CheckReplay("other", "a", now); err != nil {
+		t.Errorf("keys should be scoped per channel: %v", err)
+	}
+	if err := in.CheckReplay("test", "b", now.Add(-2*time.Minute)); !errors.Is(err, errWebhookStale) {
+		t.Errorf("old delivery err = %v, want stale", err)
+	}
+	if err := in.CheckReplay("test", "c", now.Add(2*time.Minute)); !errors.Is(err, errWebhookStale) {
+		t.Errorf("future delivery err = %v, want stale", err)
+	}
+	if err := in.CheckReplay("test", "d", time.Time{}); err != nil {
+		t.Errorf("zero timestamp should skip freshness check: %v", err)
+	}
+}
+
+func TestLINEWebhook_DropsReplayedEvents(t *testing.T) {
+	line := newTestLINEChannel(t)
+	line.setRunning(true)
+	handler := NewWebhookIngress(config.WebhooksConfig{}).Wrap(line)
+
+	body := []byte(fmt.Sprintf(`{"events":[{"type":"unfollow","webhookEventId":"evt-1","timestamp":%d}]}`,
+		time.Now().UnixMilli()))
+	for i := range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/line", bytes.NewReader(body))
+		req.Header.Set("X-Line-Signature", signLINE("line-secret", body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("delivery %d status = %d, want 200", i, w.Code)
+		}
+	}
+	if got := metrics.WebhookRejected.Value("line", "duplicate"); got < 1 {
+		t.Errorf("duplicate rejections = %v, want >= 1", got)
+	}
+}
+
+func TestWeComWebhook_AcknowledgesDuplicates(t *testing.T) {
+	in := NewWebhookIngress(config.WebhooksConfig{})
+	ts := fmt.Sprint(time.Now().Unix())
+
+	w := httptest.NewRecorder()
+	if !in.accept(w, "wecom", "sig", parseUnixSeconds(ts), "success") {
+		t.Fatal("first callback should be accepted")
+	}
+
+	w = httptest.NewRecorder()
+	if in.accept(w, "wecom", "sig", parseUnixSeconds(ts), "success") {
+		t.Fatal("duplicate callback should not be processed")
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "success" {
+		t.Errorf("duplicate response = %d %q, want 200 success", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	if in.accept(w, "wecom", "old", time.Now().Add(-time.Hour), "success") {
+		t.Fatal("stale callback should not be processed")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("stale response = %d, want 403", w.Code)
+	}
+
+	var nilIngress *WebhookIngress
+	if !nilIngress.accept(httptest.NewRecorder(), "wecom", "sig", time.Time{}, "success") {
+		t.Error("nil ingress should accept everything")
+	}
+}
+
+func TestVerifyHMACSHA256(t *testing.T) {
+	body := []byte("payload")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	sum := mac.Sum(nil)
+
+	for _, sig := range []string{
+		base64.StdEncoding.EncodeToString(sum),
+		hex.EncodeToString(sum),
+		"sha256=" + hex.EncodeToString(sum),
+	} {
+		if !verifyHMACSHA256("secret", body, sig) {
+			t.Errorf("signature %q rejected", sig)
+		}
+	}
+	for _, sig := range []string{"", "sha256=", hex.EncodeToString(sum[:16]), base64.StdEncoding.EncodeToString([]byte("nope"))} {
+		if verifyHMACSHA256("secret", body, sig) {
+			t.Errorf("signature %q accepted", sig)
+		}
+	}
+	if verifyHMACSHA256("other", body, hex.EncodeToString(sum)) {
+		t.Error("signature accepted under wrong secret")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/md5"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
@@ -20,7 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"sync"
@@ -39,6 +42,7 @@ type WeComBotChannel struct {
 
 	config        config.WeComConfig
 	server        *http.Server
+	ingress       *WebhookIngress
 	ctx           context.Context
 	cancel        context.CancelFunc
 	processedMsgs map[string]bool // Message deduplication: msg_id -> processed
@@ -93,8 +97,19 @@ type WeComBotReplyMessage struct {
 	Text    struct {
 		Content string `json:"content"`
 	} `json:"text,omitzero"`
+	Image struct {
+		Base64 string `json:"base64"`
+		MD5    string `json:"md5"`
+	} `json:"image,omitzero"`
+	File struct {
+		MediaID string `json:"media_id"`
+	} `json:"file,omitzero"`
 }
 
+// wecomBotMaxImageBytes is the largest image the webhook accepts inline;
+// larger images are sent as files.
+const wecomBotMaxImageBytes = 2 << 20
+
 // NewWeComBotChannel creates a new WeCom Bot channel instance
 func NewWeComBotChannel(cfg config.WeComConfig, messageBus *bus.MessageBus) (*WeComBotChannel, error) {
 	if cfg.Token == "" || cfg.WebhookURL == "" {
@@ -121,37 +136,21 @@ func (c *WeComBotChannel) Start(ctx context.Context) error {
 
 	c.ctx, c.cancel = context.WithCancel(ctx)
 
-	// Setup HTTP server for webhook
-	mux := http.NewServeMux()
-	webhookPath := c.config.WebhookPath
-	if webhookPath == "" {
-		webhookPath = "/webhook/wecom"
-	}
-	mux.HandleFunc(webhookPath, c.handleWebhook)
-
-	// Health check endpoint
-	mux.HandleFunc("/health/wecom", c.handleHealth)
-
-	addr := fmt.Sprintf("%s:%d", c.config.WebhookHost, c.config.WebhookPort)
-	c.server = &http.Server{
-		Addr:    addr,
-		Handler: mux,
+	// The gateway serves /webhooks/wecom; the dedicated listener on
+	// webhook_port (on by default, as before) is skipped when it is set to 0.
+	if c.config.WebhookPort > 0 {
+		webhookPath := c.config.WebhookPath
+		if webhookPath == "" {
+			webhookPath = "/webhook/wecom"
+		}
+		mux := http.NewServeMux()
+		mux.Handle(webhookPath, NewWebhookIngress(config.WebhooksConfig{}).Wrap(c))
+		mux.HandleFunc("/health/wecom", c.handleHealth)
+		c.server = startWebhookServer("wecom", fmt.Sprintf("%s:%d", c.config.WebhookHost, c.config.WebhookPort), mux)
 	}
 
 	c.setRunning(true)
-	logger.InfoCF("wecom", "WeCom Bot channel started", map[string]any{
-		"address": addr,
-		"path":    webhookPath,
-	})
-
-	// Start server in goroutine
-	go func() {
-		if err := c.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.ErrorCF("wecom", "HTTP server error", map[string]any{
-				"error": err.Error(),
-			})
-		}
-	}()
+	logger.InfoC("wecom", "WeCom Bot channel started")
 
 	return nil
 }
@@ -188,7 +187,114 @@ func (c *WeComBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		"preview": utils.Truncate(msg.Content, 100),
 	})
 
-	return c.sendWebhookReply(ctx, msg.ChatID, msg.Content)
+	text := msg
+	text.Media = nil
+	if content := text.PlainText(); content != "" {
+		if err := c.sendWebhookReply(ctx, msg.ChatID, content); err != nil {
+			return err
+		}
+	}
+	for _, a := range msg.Media {
+		if err := c.sendWebhookAttachment(ctx, a); err != nil {
+			return fmt.Errorf("failed to send %s: %w", a.Name(), err)
+		}
+	}
+	return nil
+}
+
+// sendWebhookAttachment sends a through the webhook, preceded by its
+// caption. JPEG and PNG images up to 2 MB are sent inline as images;
+// anything else is uploaded and sent as a file.
+func (c *WeComBotChannel) sendWebhookAttachment(ctx context.Context, a bus.Attachment) error {
+	data, err := loadAttachment(ctx, a)
+	if err != nil {
+		return err
+	}
+	if a.Caption != "" {
+		if err := c.sendWebhookReply(ctx, "", a.Caption); err != nil {
+			return err
+		}
+	}
+
+	var reply WeComBotReplyMessage
+	switch ct := a.ContentType(); {
+	case (ct == "image/jpeg" || ct == "image/png") && len(data) <= wecomBotMaxImageBytes:
+		sum := md5.Sum(data)
+		reply.MsgType = "image"
+		reply.Image.Base64 = base64.StdEncoding.EncodeToString(data)
+		reply.Image.MD5 = hex.EncodeToString(sum[:])
+	default:
+		mediaID, err := c.uploadWebhookMedia(ctx, a.Name(), data)
+		if err != nil {
+			return err
+		}
+		reply.MsgType = "file"
+		reply.File.MediaID = mediaID
+	}
+	return c.postWebhook(ctx, reply)
+}
+
+// uploadWebhookMedia uploads data as a file through the webhook's
+// upload_media API and returns its media_id.
+func (c *WeComBotChannel) uploadWebhookMedia(ctx context.Context, name string, data []byte) (string, error) {
+	u, err := url.Parse(c.config.WebhookURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid webhook_url: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/send") + "/upload_media"
+	q := u.Query()
+	q.Set("type", "file")
+	u.RawQuery = q.Encode()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("media", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		MediaID string `json:"media_id"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return "", fmt.Errorf("media upload error: %s (code: %d)", result.ErrMsg, result.ErrCode)
+	}
+	return result.MediaID, nil
+}
+
+// WebhookHandler returns the handler for WeCom callbacks, using in for
+// replay protection.
+func (c *WeComBotChannel) WebhookHandler(in *WebhookIngress) http.Handler {
+	c.ingress = in
+	return http.HandlerFunc(c.handleWebhook)
 }
 
 // handleWebhook handles incoming webhook requests from WeCom
@@ -226,7 +332,7 @@ func (c *WeComBotChannel) handleVerification(_ context.Context, w http.ResponseW
 	// Verify signature
 	if !WeComVerifySignature(c.config.Token, msgSignature, timestamp, nonce, echostr) {
 		logger.WarnC("wecom", "Signature verification failed")
-		http.Error(w, "Invalid signature", http.StatusForbidden)
+		rejectSignature(w, "wecom")
 		return
 	}
 
@@ -262,12 +368,10 @@ func (c *WeComBotChannel) handleMessageCallback(ctx context.Context, w http.Resp
 	}
 
 	// Read request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read body", http.StatusBadRequest)
+	body, ok := readWebhookBody(w, r)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	// Parse XML to get encrypted message
 	var encryptedMsg struct {
@@ -277,7 +381,7 @@ func (c *WeComBotChannel) handleMessageCallback(ctx context.Context, w http.Resp
 		AgentID    string   `xml:"AgentID"`
 	}
 
-	if err = xml.Unmarshal(body, &encryptedMsg); err != nil {
+	if err := xml.Unmarshal(body, &encryptedMsg); err != nil {
 		logger.ErrorCF("wecom", "Failed to parse XML", map[string]any{
 			"error": err.Error(),
 		})
@@ -288,7 +392,12 @@ func (c *WeComBotChannel) handleMessageCallback(ctx context.Context, w http.Resp
 	// Verify signature
 	if !WeComVerifySignature(c.config.Token, msgSignature, timestamp, nonce, encryptedMsg.Encrypt) {
 		logger.WarnC("wecom", "Message signature verification failed")
-		http.Error(w, "Invalid signature", http.StatusForbidden)
+		rejectSignature(w, "wecom")
+		return
+	}
+
+	// Drop retries and replays of a callback we already accepted
+	if !c.ingress.accept(w, "wecom", msgSignature, parseUnixSeconds(timestamp), "success") {
 		return
 	}
 
@@ -427,7 +536,11 @@ func (c *WeComBotChannel) sendWebhookReply(ctx context.Context, _, content strin
 		MsgType: "text",
 	}
 	reply.Text.Content = content
+	return c.postWebhook(ctx, reply)
+}
 
+// postWebhook posts reply to the configured webhook URL.
+func (c *WeComBotChannel) postWebhook(ctx context.Context, reply WeComBotReplyMessage) error {
 	jsonData, err := json.Marshal(reply)
 	if err != nil {
 		return fmt.Errorf("failed to marshal reply: %w", err)
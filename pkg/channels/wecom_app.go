@@ -14,6 +14,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -36,7 +37,9 @@ type WeComAppChannel struct {
 	*BaseChannel
 
 	config        config.WeComAppConfig
+	apiBase       string
 	server        *http.Server
+	ingress       *WebhookIngress
 	accessToken   string
 	tokenExpiry   time.Time
 	tokenMu       sync.RWMutex
@@ -102,6 +105,24 @@ type WeComImageMessage struct {
 	} `json:"image"`
 }
 
+// WeComFileMessage represents file message for sending
+type WeComFileMessage struct {
+	ToUser  string `json:"touser"`
+	MsgType string `json:"msgtype"`
+	AgentID int64  `json:"agentid"`
+	File    struct {
+		MediaID string `json:"media_id"`
+	} `json:"file"`
+}
+
+// WeComMediaUploadResponse represents the temporary media upload API response
+type WeComMediaUploadResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Type    string `json:"type"`
+	MediaID string `json:"media_id"`
+}
+
 // WeComAccessTokenResponse represents the access token API response
 type WeComAccessTokenResponse struct {
 	ErrCode     int    `json:"errcode"`
@@ -133,6 +154,7 @@ func NewWeComAppChannel(cfg config.WeComAppConfig, messageBus *bus.MessageBus) (
 	return &WeComAppChannel{
 		BaseChannel:   base,
 		config:        cfg,
+		apiBase:       wecomAPIBase,
 		processedMsgs: make(map[string]bool),
 	}, nil
 }
@@ -158,37 +180,21 @@ func (c *WeComAppChannel) Start(ctx context.Context) error {
 	// Start token refresh goroutine
 	go c.tokenRefreshLoop()
 
-	// Setup HTTP server for webhook
-	mux := http.NewServeMux()
-	webhookPath := c.config.WebhookPath
-	if webhookPath == "" {
-		webhookPath = "/webhook/wecom-app"
-	}
-	mux.HandleFunc(webhookPath, c.handleWebhook)
-
-	// Health check endpoint
-	mux.HandleFunc("/health/wecom-app", c.handleHealth)
-
-	addr := fmt.Sprintf("%s:%d", c.config.WebhookHost, c.config.WebhookPort)
-	c.server = &http.Server{
-		Addr:    addr,
-		Handler: mux,
+	// The gateway serves /webhooks/wecom_app; the dedicated listener on
+	// webhook_port (on by default, as before) is skipped when it is set to 0.
+	if c.config.WebhookPort > 0 {
+		webhookPath := c.config.WebhookPath
+		if webhookPath == "" {
+			webhookPath = "/webhook/wecom-app"
+		}
+		mux := http.NewServeMux()
+		mux.Handle(webhookPath, NewWebhookIngress(config.WebhooksConfig{}).Wrap(c))
+		mux.HandleFunc("/health/wecom-app", c.handleHealth)
+		c.server = startWebhookServer("wecom_app", fmt.Sprintf("%s:%d", c.config.WebhookHost, c.config.WebhookPort), mux)
 	}
 
 	c.setRunning(true)
-	logger.InfoCF("wecom_app", "WeCom App channel started", map[string]any{
-		"address": addr,
-		"path":    webhookPath,
-	})
-
-	// Start server in goroutine
-	go func() {
-		if err := c.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.ErrorCF("wecom_app", "HTTP server error", map[string]any{
-				"error": err.Error(),
-			})
-		}
-	}()
+	logger.InfoC("wecom_app", "WeCom App channel started")
 
 	return nil
 }
@@ -228,7 +234,104 @@ func (c *WeComAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		"preview": utils.Truncate(msg.Content, 100),
 	})
 
-	return c.sendTextMessage(ctx, accessToken, msg.ChatID, msg.Content)
+	text := msg
+	text.Media = nil
+	if content := text.PlainText(); content != "" {
+		if err := c.sendTextMessage(ctx, accessToken, msg.ChatID, content); err != nil {
+			return err
+		}
+	}
+	for _, a := range msg.Media {
+		if err := c.sendAttachment(ctx, accessToken, msg.ChatID, a); err != nil {
+			return fmt.Errorf("failed to send %s: %w", a.Name(), err)
+		}
+	}
+	return nil
+}
+
+// sendAttachment uploads a as temporary media and sends it as an image or
+// file message, preceded by its caption.
+func (c *WeComAppChannel) sendAttachment(ctx context.Context, accessToken, userID string, a bus.Attachment) error {
+	data, err := loadAttachment(ctx, a)
+	if err != nil {
+		return err
+	}
+	if a.Caption != "" {
+		if err := c.sendTextMessage(ctx, accessToken, userID, a.Caption); err != nil {
+			return err
+		}
+	}
+
+	// Voice and video messages need AMR and MP4 media, so anything that is
+	// not an image goes out as a file.
+	if a.Kind() == bus.KindImage {
+		mediaID, err := c.uploadMedia(ctx, accessToken, "image", a.Name(), data)
+		if err != nil {
+			return err
+		}
+		msg := WeComImageMessage{ToUser: userID, MsgType: "image", AgentID: c.config.AgentID}
+		msg.Image.MediaID = mediaID
+		return c.postMessage(ctx, accessToken, msg)
+	}
+	mediaID, err := c.uploadMedia(ctx, accessToken, "file", a.Name(), data)
+	if err != nil {
+		return err
+	}
+	msg := WeComFileMessage{ToUser: userID, MsgType: "file", AgentID: c.config.AgentID}
+	msg.File.MediaID = mediaID
+	return c.postMessage(ctx, accessToken, msg)
+}
+
+// uploadMedia uploads data as temporary media of mediaType and returns its
+// media_id.
+func (c *WeComAppChannel) uploadMedia(ctx context.Context, accessToken, mediaType, name string, data []byte) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("media", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	apiURL := fmt.Sprintf("%s/cgi-bin/media/upload?access_token=%s&type=%s",
+		c.apiBase, url.QueryEscape(accessToken), mediaType)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	var uploadResp WeComMediaUploadResponse
+	if err := json.Unmarshal(respBody, &uploadResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if uploadResp.ErrCode != 0 {
+		return "", fmt.Errorf("media upload error: %s (code: %d)", uploadResp.ErrMsg, uploadResp.ErrCode)
+	}
+	return uploadResp.MediaID, nil
+}
+
+// WebhookHandler returns the handler for WeCom callbacks, using in for
+// replay protection.
+func (c *WeComAppChannel) WebhookHandler(in *WebhookIngress) http.Handler {
+	c.ingress = in
+	return http.HandlerFunc(c.handleWebhook)
 }
 
 // handleWebhook handles incoming webhook requests from WeCom
@@ -291,7 +394,7 @@ func (c *WeComAppChannel) handleVerification(_ context.Context, w http.ResponseW
 			"timestamp":     timestamp,
 			"nonce":         nonce,
 		})
-		http.Error(w, "Invalid signature", http.StatusForbidden)
+		rejectSignature(w, "wecom_app")
 		return
 	}
 
@@ -338,12 +441,10 @@ func (c *WeComAppChannel) handleMessageCallback(ctx context.Context, w http.Resp
 	}
 
 	// Read request body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read body", http.StatusBadRequest)
+	body, ok := readWebhookBody(w, r)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	// Parse XML to get encrypted message
 	var encryptedMsg struct {
@@ -353,7 +454,7 @@ func (c *WeComAppChannel) handleMessageCallback(ctx context.Context, w http.Resp
 		AgentID    string   `xml:"AgentID"`
 	}
 
-	if err = xml.Unmarshal(body, &encryptedMsg); err != nil {
+	if err := xml.Unmarshal(body, &encryptedMsg); err != nil {
 		logger.ErrorCF("wecom_app", "Failed to parse XML", map[string]any{
 			"error": err.Error(),
 		})
@@ -364,7 +465,12 @@ func (c *WeComAppChannel) handleMessageCallback(ctx context.Context, w http.Resp
 	// Verify signature
 	if !WeComVerifySignature(c.config.Token, msgSignature, timestamp, nonce, encryptedMsg.Encrypt) {
 		logger.WarnC("wecom_app", "Message signature verification failed")
-		http.Error(w, "Invalid signature", http.StatusForbidden)
+		rejectSignature(w, "wecom_app")
+		return
+	}
+
+	// Drop retries and replays of a callback we already accepted
+	if !c.ingress.accept(w, "wecom_app", msgSignature, parseUnixSeconds(timestamp), "success") {
 		return
 	}
 
@@ -478,7 +584,7 @@ func (c *WeComAppChannel) tokenRefreshLoop() {
 // refreshAccessToken gets a new access token from WeCom API
 func (c *WeComAppChannel) refreshAccessToken() error {
 	apiURL := fmt.Sprintf("%s/cgi-bin/gettoken?corpid=%s&corpsecret=%s",
-		wecomAPIBase, url.QueryEscape(c.config.CorpID), url.QueryEscape(c.config.CorpSecret))
+		c.apiBase, url.QueryEscape(c.config.CorpID), url.QueryEscape(c.config.CorpSecret))
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
@@ -523,14 +629,18 @@ func (c *WeComAppChannel) getAccessToken() string {
 
 // sendTextMessage sends a text message to a user
 func (c *WeComAppChannel) sendTextMessage(ctx context.Context, accessToken, userID, content string) error {
-	apiURL := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", wecomAPIBase, accessToken)
-
 	msg := WeComTextMessage{
 		ToUser:  userID,
 		MsgType: "text",
 		AgentID: c.config.AgentID,
 	}
 	msg.Text.Content = content
+	return c.postMessage(ctx, accessToken, msg)
+}
+
+// postMessage sends msg through the message/send API.
+func (c *WeComAppChannel) postMessage(ctx context.Context, accessToken string, msg any) error {
+	apiURL := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", c.apiBase, accessToken)
 
 	jsonData, err := json.Marshal(msg)
 	if err != nil {
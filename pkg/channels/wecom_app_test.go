@@ -18,6 +18,8 @@ import (
 	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -1108,3 +1110,69 @@ func TestWeComAppXMLMessageEvent(t *testing.T) {
 		t.Errorf("EventKey = %q, want %q", msg.EventKey, "event_key_123")
 	}
 }
+
+func TestWeComAppSendAttachments(t *testing.T) {
+	var uploads []string
+	var sent []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/media/upload":
+			mediaType := r.URL.Query().Get("type")
+			_, header, err := r.FormFile("media")
+			if err != nil {
+				t.Errorf("upload without media part: %v", err)
+				return
+			}
+			uploads = append(uploads, mediaType+":"+header.Filename)
+			w.Write([]byte(`{"errcode":0,"type":"` + mediaType + `","media_id":"id-` + header.Filename + `"}`))
+		case "/cgi-bin/message/send":
+			var msg map[string]any
+			json.NewDecoder(r.Body).Decode(&msg)
+			sent = append(sent, msg)
+			w.Write([]byte(`{"errcode":0}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	image := filepath.Join(dir, "photo.jpg")
+	doc := filepath.Join(dir, "notes.txt")
+	os.WriteFile(image, []byte("jpeg"), 0o644)
+	os.WriteFile(doc, []byte("notes"), 0o644)
+
+	ch, err := NewWeComAppChannel(config.WeComAppConfig{
+		CorpID:     "test_corp_id",
+		CorpSecret: "test_secret",
+		AgentID:    1000002,
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.apiBase = server.URL
+	ch.accessToken = "token"
+	ch.tokenExpiry = time.Now().Add(time.Hour)
+	ch.setRunning(true)
+
+	err = ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID: "user1",
+		Media:  []bus.Attachment{{Path: image}, {Path: doc}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := strings.Join(uploads, ","); got != "image:photo.jpg,file:notes.txt" {
+		t.Errorf("uploads = %q", got)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("sent %d messages, want 2 (no empty text): %+v", len(sent), sent)
+	}
+	if sent[0]["msgtype"] != "image" || sent[0]["image"].(map[string]any)["media_id"] != "id-photo.jpg" {
+		t.Errorf("first message = %v, want the image", sent[0])
+	}
+	if sent[1]["msgtype"] != "file" || sent[1]["file"].(map[string]any)["media_id"] != "id-notes.txt" {
+		t.Errorf("second message = %v, want the file", sent[1])
+	}
+}
@@ -18,8 +18,11 @@ import (
 	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/bus"
@@ -789,3 +792,69 @@ func TestWeComBotMessageStructure(t *testing.T) {
 		t.Errorf("Text.Content = %q, want %q", msg.Text.Content, "Hello World")
 	}
 }
+
+func TestWeComBotSendAttachments(t *testing.T) {
+	var mu sync.Mutex
+	var sent []WeComBotReplyMessage
+	var uploadType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/webhook/upload_media":
+			uploadType = r.URL.Query().Get("type")
+			if _, _, err := r.FormFile("media"); err != nil {
+				t.Errorf("upload without media part: %v", err)
+			}
+			w.Write([]byte(`{"errcode":0,"media_id":"m-1"}`))
+		case "/cgi-bin/webhook/send":
+			var reply WeComBotReplyMessage
+			json.NewDecoder(r.Body).Decode(&reply)
+			mu.Lock()
+			sent = append(sent, reply)
+			mu.Unlock()
+			w.Write([]byte(`{"errcode":0}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	image := filepath.Join(dir, "chart.png")
+	doc := filepath.Join(dir, "report.pdf")
+	os.WriteFile(image, []byte("\x89PNG fake"), 0o644)
+	os.WriteFile(doc, []byte("%PDF fake"), 0o644)
+
+	ch, err := NewWeComBotChannel(config.WeComConfig{
+		Token:      "test_token",
+		WebhookURL: server.URL + "/cgi-bin/webhook/send?key=test",
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.setRunning(true)
+
+	err = ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:  "chat",
+		Content: "Here you go",
+		Media:   []bus.Attachment{{Path: image}, {Path: doc, Caption: "The report"}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(sent) != 4 {
+		t.Fatalf("sent %d messages, want 4: %+v", len(sent), sent)
+	}
+	if sent[0].MsgType != "text" || sent[0].Text.Content != "Here you go" {
+		t.Errorf("first message = %+v, want the text", sent[0])
+	}
+	if sent[1].MsgType != "image" || sent[1].Image.Base64 == "" || sent[1].Image.MD5 == "" {
+		t.Errorf("second message = %+v, want an inline image", sent[1])
+	}
+	if sent[2].MsgType != "text" || sent[2].Text.Content != "The report" {
+		t.Errorf("third message = %+v, want the caption", sent[2])
+	}
+	if sent[3].MsgType != "file" || sent[3].File.MediaID != "m-1" || uploadType != "file" {
+		t.Errorf("fourth message = %+v (upload type %q), want the uploaded file", sent[3], uploadType)
+	}
+}
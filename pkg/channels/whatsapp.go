@@ -94,7 +94,7 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	payload := map[string]any{
 		"type":    "message",
 		"to":      msg.ChatID,
-		"content": msg.Content,
+		"content": msg.PlainText(),
 	}
 
 	data, err := json.Marshal(payload)
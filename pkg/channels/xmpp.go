@@ -34,7 +34,8 @@ const (
 type xmppMessageBody struct {
 	stanza.Message
 
-	Body string `xml:"body"`
+	Body   string `xml:"body"`
+	Thread string `xml:"thread"`
 }
 
 // XMPPChannel implements the Channel interface for XMPP with optional MUC support.
@@ -130,13 +131,14 @@ func (c *XMPPChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 		return fmt.Errorf("invalid recipient jid %q: %w", msg.ChatID, err)
 	}
 
-	if len(msg.Content) == 0 {
+	content := msg.PlainText()
+	if len(content) == 0 {
 		return nil
 	}
 
-	chunks := utils.SplitMessage(msg.Content, xmppMessageMaxLen)
+	chunks := utils.SplitMessage(content, xmppMessageMaxLen)
 	for _, chunk := range chunks {
-		if err := c.sendMessage(ctx, toJID, chunk); err != nil {
+		if err := c.sendMessage(ctx, toJID, chunk, msg.ThreadID); err != nil {
 			return err
 		}
 	}
@@ -312,6 +314,9 @@ func (c *XMPPChannel) handleIncoming(msg xmppMessageBody) {
 	if res := fromJID.Resourcepart(); res != "" {
 		metadata["resource"] = res
 	}
+	if msg.Thread != "" {
+		metadata["thread_id"] = msg.Thread
+	}
 
 	logger.DebugCF("xmpp", "Received message", map[string]any{
 		"sender": senderID,
@@ -321,7 +326,7 @@ func (c *XMPPChannel) handleIncoming(msg xmppMessageBody) {
 	c.HandleMessage(senderID, chatID, msg.Body, nil, metadata)
 }
 
-func (c *XMPPChannel) sendMessage(ctx context.Context, to jid.JID, content string) error {
+func (c *XMPPChannel) sendMessage(ctx context.Context, to jid.JID, content, thread string) error {
 	sendCtx, cancel := context.WithTimeout(ctx, xmppSendTimeout)
 	defer cancel()
 
@@ -343,13 +348,6 @@ func (c *XMPPChannel) sendMessage(ctx context.Context, to jid.JID, content strin
 		}
 	}
 
-	// Build <body>content</body> as a token reader
-	bodyStart := xml.StartElement{Name: xml.Name{Local: "body"}}
-	bodyPayload := xmlstream.Wrap(
-		xmlstream.Token(xml.CharData(content)),
-		bodyStart,
-	)
-
 	// Wrap body in <message to="..." type="...">
 	msg := stanza.Message{
 		To:   to,
@@ -359,7 +357,7 @@ func (c *XMPPChannel) sendMessage(ctx context.Context, to jid.JID, content strin
 
 	done := make(chan error, 1)
 	go func() {
-		done <- session.Send(sendCtx, msg.Wrap(bodyPayload))
+		done <- session.Send(sendCtx, msg.Wrap(xmppMessagePayload(content, thread)))
 	}()
 
 	select {
@@ -459,3 +457,19 @@ func (c *XMPPChannel) serverAddr() string {
 	}
 	return ""
 }
+
+// xmppMessagePayload builds the <body> of a message and, when thread is
+// set, the <thread> element that keeps it in that conversation.
+func xmppMessagePayload(content, thread string) xml.TokenReader {
+	body := xmlstream.Wrap(
+		xmlstream.Token(xml.CharData(content)),
+		xml.StartElement{Name: xml.Name{Local: "body"}},
+	)
+	if thread == "" {
+		return body
+	}
+	return xmlstream.MultiReader(body, xmlstream.Wrap(
+		xmlstream.Token(xml.CharData(thread)),
+		xml.StartElement{Name: xml.Name{Local: "thread"}},
+	))
+}
@@ -1,11 +1,14 @@
 package channels
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"mellium.im/xmlstream"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/bus"
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
@@ -201,6 +204,20 @@ func TestXMPPChannelSendInvalidJID(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid recipient jid")
 }
 
+func TestXMPPMessagePayload(t *testing.T) {
+	encode := func(r xml.TokenReader) string {
+		var buf bytes.Buffer
+		enc := xml.NewEncoder(&buf)
+		_, err := xmlstream.Copy(enc, r)
+		require.NoError(t, err)
+		require.NoError(t, enc.Flush())
+		return buf.String()
+	}
+
+	assert.Equal(t, "<body>hi &amp; bye</body>", encode(xmppMessagePayload("hi & bye", "")))
+	assert.Equal(t, "<body>hi</body><thread>t-1</thread>", encode(xmppMessagePayload("hi", "t-1")))
+}
+
 func TestXMPPChannelMUCConfig(t *testing.T) {
 	messageBus := bus.NewMessageBus()
 	cfg := config.XMPPConfig{
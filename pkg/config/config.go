@@ -138,13 +138,20 @@ func (m AgentModelConfig) MarshalJSON() ([]byte, error) {
 }
 
 type AgentConfig struct {
-	ID        string            `json:"id"`
-	Default   bool              `json:"default,omitempty"`
-	Name      string            `json:"name,omitempty"`
-	Workspace string            `json:"workspace,omitempty"`
-	Model     *AgentModelConfig `json:"model,omitempty"`
-	Skills    []string          `json:"skills,omitempty"`
-	Subagents *SubagentsConfig  `json:"subagents,omitempty"`
+	ID           string            `json:"id"`
+	Default      bool              `json:"default,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Workspace    string            `json:"workspace,omitempty"`
+	Model        *AgentModelConfig `json:"model,omitempty"`
+	Skills       []string          `json:"skills,omitempty"`
+	Subagents    *SubagentsConfig  `json:"subagents,omitempty"`
+	SummaryModel string            `json:"summary_model,omitempty"` // model_list alias used for history summarization
+	Sandbox      *AgentSandbox     `json:"sandbox,omitempty"`
+}
+
+// AgentSandbox overrides tools.exec.sandbox settings for one agent.
+type AgentSandbox struct {
+	Network *bool `json:"network,omitempty"`
 }
 
 type SubagentsConfig struct {
@@ -176,17 +183,31 @@ type SessionConfig struct {
 }
 
 type AgentDefaults struct {
-	Workspace           string   `env:"TINYCLAW_AGENTS_DEFAULTS_WORKSPACE"             json:"workspace"`
-	RestrictToWorkspace bool     `env:"TINYCLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE" json:"restrict_to_workspace"`
-	Provider            string   `env:"TINYCLAW_AGENTS_DEFAULTS_PROVIDER"              json:"provider"`
-	ModelName           string   `env:"TINYCLAW_AGENTS_DEFAULTS_MODEL_NAME"            json:"model_name,omitempty"`
-	Model               string   `env:"TINYCLAW_AGENTS_DEFAULTS_MODEL"                 json:"model,omitempty"`                 // Deprecated: use model_name instead
-	ModelFallbacks      []string `                                                     json:"model_fallbacks,omitempty"`       //nolint:tagalign // golines conflict
-	ImageModel          string   `env:"TINYCLAW_AGENTS_DEFAULTS_IMAGE_MODEL"           json:"image_model,omitempty"`           //nolint:tagalign // golines conflict
-	ImageModelFallbacks []string `                                                     json:"image_model_fallbacks,omitempty"` //nolint:tagalign // golines conflict
-	MaxTokens           int      `env:"TINYCLAW_AGENTS_DEFAULTS_MAX_TOKENS"            json:"max_tokens"`
-	Temperature         *float64 `env:"TINYCLAW_AGENTS_DEFAULTS_TEMPERATURE"           json:"temperature,omitempty"`
-	MaxToolIterations   int      `env:"TINYCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"   json:"max_tool_iterations"`
+	Workspace           string       `env:"TINYCLAW_AGENTS_DEFAULTS_WORKSPACE"             json:"workspace"`
+	RestrictToWorkspace bool         `env:"TINYCLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE" json:"restrict_to_workspace"`
+	Provider            string       `env:"TINYCLAW_AGENTS_DEFAULTS_PROVIDER"              json:"provider"`
+	ModelName           string       `env:"TINYCLAW_AGENTS_DEFAULTS_MODEL_NAME"            json:"model_name,omitempty"`
+	Model               string       `env:"TINYCLAW_AGENTS_DEFAULTS_MODEL"                 json:"model,omitempty"`                 // Deprecated: use model_name instead
+	ModelFallbacks      []string     `                                                     json:"model_fallbacks,omitempty"`       //nolint:tagalign // golines conflict
+	ImageModel          string       `env:"TINYCLAW_AGENTS_DEFAULTS_IMAGE_MODEL"           json:"image_model,omitempty"`           //nolint:tagalign // golines conflict
+	ImageModelFallbacks []string     `                                                     json:"image_model_fallbacks,omitempty"` //nolint:tagalign // golines conflict
+	MaxTokens           int          `env:"TINYCLAW_AGENTS_DEFAULTS_MAX_TOKENS"            json:"max_tokens"`
+	Temperature         *float64     `env:"TINYCLAW_AGENTS_DEFAULTS_TEMPERATURE"           json:"temperature,omitempty"`
+	MaxToolIterations   int          `env:"TINYCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"   json:"max_tool_iterations"`
+	SummaryModel        string       `env:"TINYCLAW_AGENTS_DEFAULTS_SUMMARY_MODEL"         json:"summary_model,omitempty"` // model_list alias; empty uses the agent's model
+	Memory              MemoryConfig `                                                     json:"memory,omitzero"`
+}
+
+// MemoryConfig controls how long-term memory reaches the prompt. With
+// retrieval enabled, only the chunks relevant to each message are included
+// instead of the whole of MEMORY.md and recent daily notes. IndexSessions
+// adds the summaries of all the agent's sessions to the index, so any chat
+// or API key can retrieve what was said in the others; it is off by default.
+type MemoryConfig struct {
+	Retrieval      bool   `env:"TINYCLAW_AGENTS_DEFAULTS_MEMORY_RETRIEVAL"       json:"retrieval"`
+	EmbeddingModel string `env:"TINYCLAW_AGENTS_DEFAULTS_MEMORY_EMBEDDING_MODEL" json:"embedding_model,omitempty"` // model_list alias; empty uses BM25
+	TopK           int    `env:"TINYCLAW_AGENTS_DEFAULTS_MEMORY_TOP_K"           json:"top_k,omitempty"`
+	IndexSessions  bool   `env:"TINYCLAW_AGENTS_DEFAULTS_MEMORY_INDEX_SESSIONS"  json:"index_sessions"`
 }
 
 // GetModelName returns the effective model name for the agent defaults.
@@ -212,6 +233,9 @@ type ChannelsConfig struct {
 	WeCom    WeComConfig    `json:"wecom"`
 	WeComApp WeComAppConfig `json:"wecom_app"`
 	XMPP     XMPPConfig     `json:"xmpp"`
+	Matrix   MatrixConfig   `json:"matrix"`
+	Email    EmailConfig    `json:"email"`
+	Web      WebConfig      `json:"web"`
 }
 
 type WhatsAppConfig struct {
@@ -243,11 +267,21 @@ type DiscordConfig struct {
 	MentionOnly bool                `env:"TINYCLAW_CHANNELS_DISCORD_MENTION_ONLY" json:"mention_only"`
 }
 
+// MaixCamConfig configures the server MaixCam devices connect to. When
+// Token is set, devices must present it in their hello message. Detections
+// of classes outside Classes (empty means all) or below MinConfidence, or
+// the class's entry in ClassThresholds, are dropped, and a device reports
+// each class at most once per EventIntervalSeconds.
 type MaixCamConfig struct {
-	Enabled   bool                `env:"TINYCLAW_CHANNELS_MAIXCAM_ENABLED"    json:"enabled"`
-	Host      string              `env:"TINYCLAW_CHANNELS_MAIXCAM_HOST"       json:"host"`
-	Port      int                 `env:"TINYCLAW_CHANNELS_MAIXCAM_PORT"       json:"port"`
-	AllowFrom FlexibleStringSlice `env:"TINYCLAW_CHANNELS_MAIXCAM_ALLOW_FROM" json:"allow_from"`
+	Enabled              bool                `env:"TINYCLAW_CHANNELS_MAIXCAM_ENABLED"                json:"enabled"`
+	Host                 string              `env:"TINYCLAW_CHANNELS_MAIXCAM_HOST"                   json:"host"`
+	Port                 int                 `env:"TINYCLAW_CHANNELS_MAIXCAM_PORT"                   json:"port"`
+	Token                string              `env:"TINYCLAW_CHANNELS_MAIXCAM_TOKEN"                  json:"token"`
+	Classes              FlexibleStringSlice `env:"TINYCLAW_CHANNELS_MAIXCAM_CLASSES"                json:"classes"`
+	MinConfidence        float64             `env:"TINYCLAW_CHANNELS_MAIXCAM_MIN_CONFIDENCE"         json:"min_confidence"`
+	ClassThresholds      map[string]float64  `env:"TINYCLAW_CHANNELS_MAIXCAM_CLASS_THRESHOLDS"       json:"class_thresholds,omitempty"`
+	EventIntervalSeconds int                 `env:"TINYCLAW_CHANNELS_MAIXCAM_EVENT_INTERVAL_SECONDS" json:"event_interval_seconds"`
+	AllowFrom            FlexibleStringSlice `env:"TINYCLAW_CHANNELS_MAIXCAM_ALLOW_FROM"             json:"allow_from"`
 }
 
 type QQConfig struct {
@@ -326,6 +360,49 @@ type XMPPConfig struct {
 	AllowFrom    FlexibleStringSlice `env:"TINYCLAW_CHANNELS_XMPP_ALLOW_FROM"    json:"allow_from"`
 }
 
+// MatrixConfig configures the Matrix channel. Either AccessToken or
+// Password is required; Rooms, when set, limits the bot to those room IDs
+// or aliases. End-to-end encrypted rooms are not supported.
+type MatrixConfig struct {
+	Enabled     bool                `env:"TINYCLAW_CHANNELS_MATRIX_ENABLED"      json:"enabled"`
+	Homeserver  string              `env:"TINYCLAW_CHANNELS_MATRIX_HOMESERVER"   json:"homeserver"`
+	UserID      string              `env:"TINYCLAW_CHANNELS_MATRIX_USER_ID"      json:"user_id"`
+	AccessToken string              `env:"TINYCLAW_CHANNELS_MATRIX_ACCESS_TOKEN" json:"access_token"`
+	Password    string              `env:"TINYCLAW_CHANNELS_MATRIX_PASSWORD"     json:"password"`
+	DeviceID    string              `env:"TINYCLAW_CHANNELS_MATRIX_DEVICE_ID"    json:"device_id"`
+	Rooms       []string            `env:"TINYCLAW_CHANNELS_MATRIX_ROOMS"        json:"rooms"`
+	AutoJoin    bool                `env:"TINYCLAW_CHANNELS_MATRIX_AUTO_JOIN"    json:"auto_join"`
+	AllowFrom   FlexibleStringSlice `env:"TINYCLAW_CHANNELS_MATRIX_ALLOW_FROM"   json:"allow_from"`
+}
+
+// EmailConfig configures the email channel. Mail is read from Mailbox on
+// IMAPServer and replies go out through SMTPServer (both "host:port").
+// Security is "tls" (implicit TLS), "starttls" or "none"; SMTP credentials
+// default to the IMAP ones.
+type EmailConfig struct {
+	Enabled      bool                `env:"TINYCLAW_CHANNELS_EMAIL_ENABLED"       json:"enabled"`
+	IMAPServer   string              `env:"TINYCLAW_CHANNELS_EMAIL_IMAP_SERVER"   json:"imap_server"`
+	IMAPSecurity string              `env:"TINYCLAW_CHANNELS_EMAIL_IMAP_SECURITY" json:"imap_security"`
+	SMTPServer   string              `env:"TINYCLAW_CHANNELS_EMAIL_SMTP_SERVER"   json:"smtp_server"`
+	SMTPSecurity string              `env:"TINYCLAW_CHANNELS_EMAIL_SMTP_SECURITY" json:"smtp_security"`
+	Username     string              `env:"TINYCLAW_CHANNELS_EMAIL_USERNAME"      json:"username"`
+	Password     string              `env:"TINYCLAW_CHANNELS_EMAIL_PASSWORD"      json:"password"`
+	SMTPUsername string              `env:"TINYCLAW_CHANNELS_EMAIL_SMTP_USERNAME" json:"smtp_username"`
+	SMTPPassword string              `env:"TINYCLAW_CHANNELS_EMAIL_SMTP_PASSWORD" json:"smtp_password"`
+	From         string              `env:"TINYCLAW_CHANNELS_EMAIL_FROM"          json:"from"`
+	Mailbox      string              `env:"TINYCLAW_CHANNELS_EMAIL_MAILBOX"       json:"mailbox"`
+	PollInterval int                 `env:"TINYCLAW_CHANNELS_EMAIL_POLL_INTERVAL" json:"poll_interval"` // seconds
+	AllowFrom    FlexibleStringSlice `env:"TINYCLAW_CHANNELS_EMAIL_ALLOW_FROM"    json:"allow_from"`
+}
+
+// WebConfig configures the browser chat UI served by the gateway under
+// /chat/. Token is required to open the WebSocket.
+type WebConfig struct {
+	Enabled        bool   `env:"TINYCLAW_CHANNELS_WEB_ENABLED"          json:"enabled"`
+	Token          string `env:"TINYCLAW_CHANNELS_WEB_TOKEN"            json:"token"`
+	MaxUploadBytes int64  `env:"TINYCLAW_CHANNELS_WEB_MAX_UPLOAD_BYTES" json:"max_upload_bytes"`
+}
+
 type HeartbeatConfig struct {
 	Enabled  bool `env:"TINYCLAW_HEARTBEAT_ENABLED"  json:"enabled"`
 	Interval int  `env:"TINYCLAW_HEARTBEAT_INTERVAL" json:"interval"` // minutes, min 5
@@ -334,6 +411,41 @@ type HeartbeatConfig struct {
 type DevicesConfig struct {
 	Enabled    bool `env:"TINYCLAW_DEVICES_ENABLED"     json:"enabled"`
 	MonitorUSB bool `env:"TINYCLAW_DEVICES_MONITOR_USB" json:"monitor_usb"`
+	// Kernel uevent sources: disks, partitions and mounts; network
+	// interfaces, link state and addresses; AC adapters and batteries;
+	// Bluetooth adapters.
+	MonitorBlock     bool `env:"TINYCLAW_DEVICES_MONITOR_BLOCK"     json:"monitor_block"`
+	MonitorNet       bool `env:"TINYCLAW_DEVICES_MONITOR_NET"       json:"monitor_net"`
+	MonitorPower     bool `env:"TINYCLAW_DEVICES_MONITOR_POWER"     json:"monitor_power"`
+	MonitorBluetooth bool `env:"TINYCLAW_DEVICES_MONITOR_BLUETOOTH" json:"monitor_bluetooth"`
+	// PowerThresholds are the battery percentages to report when
+	// discharging past them.
+	PowerThresholds []int              `env:"TINYCLAW_DEVICES_POWER_THRESHOLDS" json:"power_thresholds,omitempty"`
+	Rules           []DeviceRuleConfig `                                        json:"rules,omitempty"`
+}
+
+// DeviceRuleConfig reacts to device events. Empty match fields match
+// anything; vendor, product, serial and match values are case-insensitive
+// glob patterns. Do is "notify" (the default), "agent" (run prompt through
+// the agent with the event as context), "skill" (have the agent run the
+// named skill) or "ignore". Events that match no rule are posted to the
+// last active chat as before.
+type DeviceRuleConfig struct {
+	Name    string            `json:"name"`
+	Kind    string            `json:"kind,omitempty"`
+	Action  string            `json:"action,omitempty"`
+	Vendor  string            `json:"vendor,omitempty"`
+	Product string            `json:"product,omitempty"`
+	Serial  string            `json:"serial,omitempty"`
+	Match   map[string]string `json:"match,omitempty"` // raw event properties, e.g. ID_VENDOR_ID
+	Do      string            `json:"do,omitempty"`
+	Prompt  string            `json:"prompt,omitempty"`
+	Skill   string            `json:"skill,omitempty"`
+	// Channel and ChatID pick where results go; empty uses the last
+	// active chat.
+	Channel         string `json:"channel,omitempty"`
+	ChatID          string `json:"chat_id,omitempty"`
+	DebounceSeconds int    `json:"debounce_seconds,omitempty"`
 }
 
 // TailscaleConfig holds Tailscale tsnet integration settings.
@@ -446,6 +558,9 @@ type ModelConfig struct {
 	// Optional optimizations
 	RPM            int    `json:"rpm,omitempty"`              // Requests per minute limit
 	MaxTokensField string `json:"max_tokens_field,omitempty"` // Field name for max tokens (e.g., "max_completion_tokens")
+
+	// Context metadata; overrides the built-in table used for token budgeting
+	ContextWindow int `json:"context_window,omitempty"` // Total tokens the model accepts (prompt + output)
 }
 
 // Validate checks if the ModelConfig has all required fields.
@@ -459,9 +574,63 @@ func (c *ModelConfig) Validate() error {
 	return nil
 }
 
+// GatewayConfig configures the gateway server. With WatchConfig the
+// gateway reloads the config file when it changes, as it does on SIGHUP.
 type GatewayConfig struct {
-	Host string `env:"TINYCLAW_GATEWAY_HOST" json:"host"`
-	Port int    `env:"TINYCLAW_GATEWAY_PORT" json:"port"`
+	Host        string         `env:"TINYCLAW_GATEWAY_HOST"         json:"host"`
+	Port        int            `env:"TINYCLAW_GATEWAY_PORT"         json:"port"`
+	WatchConfig bool           `env:"TINYCLAW_GATEWAY_WATCH_CONFIG" json:"watch_config"`
+	Webhooks    WebhooksConfig `json:"webhooks,omitzero"`
+	Jobs        JobsConfig     `json:"jobs,omitzero"`
+	Auth        APIAuthConfig  `json:"auth,omitzero"`
+	Shutdown    ShutdownConfig `json:"shutdown,omitzero"`
+}
+
+// WebhooksConfig limits the channel webhooks mounted on the gateway under
+// /webhooks/<channel>. With TailnetPort set (and tailscale enabled) the
+// webhooks are also served on the tsnet node at that port.
+type WebhooksConfig struct {
+	MaxBodyBytes        int64 `env:"TINYCLAW_GATEWAY_WEBHOOKS_MAX_BODY_BYTES"        json:"max_body_bytes"`
+	ReplayWindowSeconds int   `env:"TINYCLAW_GATEWAY_WEBHOOKS_REPLAY_WINDOW_SECONDS" json:"replay_window_seconds"`
+	TailnetPort         int   `env:"TINYCLAW_GATEWAY_WEBHOOKS_TAILNET_PORT"          json:"tailnet_port"`
+}
+
+// JobsConfig controls asynchronous dispatch jobs (POST /api/dispatch?async=true).
+// Completion callbacks are signed with CallbackSecret when it is set, and
+// only go to public addresses unless AllowPrivateCallbacks is set.
+type JobsConfig struct {
+	CallbackSecret        string `env:"TINYCLAW_GATEWAY_JOBS_CALLBACK_SECRET"         json:"callback_secret"`
+	MaxJobs               int    `env:"TINYCLAW_GATEWAY_JOBS_MAX_JOBS"                json:"max_jobs"`
+	AllowPrivateCallbacks bool   `env:"TINYCLAW_GATEWAY_JOBS_ALLOW_PRIVATE_CALLBACKS" json:"allow_private_callbacks"`
+}
+
+// ShutdownConfig controls how the gateway drains on SIGINT or SIGTERM. In-flight
+// agent runs get up to DrainTimeoutSeconds to finish, and chats active in the
+// last NoticeWindowMinutes are sent Notice first (empty to send nothing).
+type ShutdownConfig struct {
+	DrainTimeoutSeconds int    `env:"TINYCLAW_GATEWAY_SHUTDOWN_DRAIN_TIMEOUT_SECONDS" json:"drain_timeout_seconds"`
+	Notice              string `env:"TINYCLAW_GATEWAY_SHUTDOWN_NOTICE"                json:"notice"`
+	NoticeWindowMinutes int    `env:"TINYCLAW_GATEWAY_SHUTDOWN_NOTICE_WINDOW_MINUTES" json:"notice_window_minutes"`
+}
+
+// APIAuthConfig lists the keys accepted by the gateway HTTP API. With no
+// keys the API is open. /health and /ready never require a key.
+type APIAuthConfig struct {
+	SetecURL string         `env:"TINYCLAW_GATEWAY_AUTH_SETEC_URL" json:"setec_url,omitempty"`
+	Keys     []APIKeyConfig `                                      json:"keys,omitempty"`
+}
+
+// APIKeyConfig is one API key. The key itself is given either as Hash
+// ("sha256:<hex>", see `tinyclaw auth apikey`) or as the name of a Setec
+// secret holding it. Empty Agents or Endpoints allow all of them.
+type APIKeyConfig struct {
+	Name          string   `json:"name"`
+	Hash          string   `json:"hash,omitempty"`
+	Setec         string   `json:"setec,omitempty"`
+	Agents        []string `json:"agents,omitempty"`
+	Endpoints     []string `json:"endpoints,omitempty"`      // dispatch, jobs, tools, status, chat
+	RateLimit     int      `json:"rate_limit,omitempty"`     // requests per minute
+	MaxConcurrent int      `json:"max_concurrent,omitempty"` // in-flight requests and running async jobs
 }
 
 type BraveConfig struct {
@@ -495,7 +664,19 @@ type WebToolsConfig struct {
 	Perplexity PerplexityConfig `json:"perplexity"`
 	// Proxy is an optional proxy URL for web tools (http/https/socks5/socks5h).
 	// For authenticated proxies, prefer HTTP_PROXY/HTTPS_PROXY env vars instead of embedding credentials in config.
-	Proxy string `env:"TINYCLAW_TOOLS_WEB_PROXY" json:"proxy,omitempty"`
+	Proxy string         `env:"TINYCLAW_TOOLS_WEB_PROXY" json:"proxy,omitempty"`
+	Fetch WebFetchConfig `                               json:"fetch,omitzero"`
+}
+
+// WebFetchConfig configures the web_fetch tool. Requests to loopback,
+// private, link-local and other non-public addresses are refused unless
+// AllowPrivateNetworks is set; the check runs after DNS resolution and on
+// every redirect. Fetched pages are cached on disk for CacheTTLMinutes.
+type WebFetchConfig struct {
+	MaxChars             int   `env:"TINYCLAW_TOOLS_WEB_FETCH_MAX_CHARS"              json:"max_chars"`
+	MaxBytes             int64 `env:"TINYCLAW_TOOLS_WEB_FETCH_MAX_BYTES"              json:"max_bytes"`
+	CacheTTLMinutes      int   `env:"TINYCLAW_TOOLS_WEB_FETCH_CACHE_TTL_MINUTES"      json:"cache_ttl_minutes"`
+	AllowPrivateNetworks bool  `env:"TINYCLAW_TOOLS_WEB_FETCH_ALLOW_PRIVATE_NETWORKS" json:"allow_private_networks"`
 }
 
 type CronToolsConfig struct {
@@ -503,16 +684,81 @@ type CronToolsConfig struct {
 }
 
 type ExecConfig struct {
-	EnableDenyPatterns bool     `env:"TINYCLAW_TOOLS_EXEC_ENABLE_DENY_PATTERNS" json:"enable_deny_patterns"`
-	CustomDenyPatterns []string `env:"TINYCLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS" json:"custom_deny_patterns"`
+	EnableDenyPatterns bool                 `env:"TINYCLAW_TOOLS_EXEC_ENABLE_DENY_PATTERNS" json:"enable_deny_patterns"`
+	CustomDenyPatterns []string             `env:"TINYCLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS" json:"custom_deny_patterns"`
+	Sandbox            ExecSandboxConfig    `                                               json:"sandbox,omitzero"`
+	Background         ExecBackgroundConfig `                                               json:"background,omitzero"`
+}
+
+// ExecBackgroundConfig limits the background processes an agent can start
+// with exec. Processes belong to the conversation that started them and are
+// killed when it ends, after IdleMinutes without a message in it, after
+// MaxLifetimeMinutes, or at shutdown.
+type ExecBackgroundConfig struct {
+	MaxProcesses       int `env:"TINYCLAW_TOOLS_EXEC_BACKGROUND_MAX_PROCESSES"        json:"max_processes"`
+	MaxLifetimeMinutes int `env:"TINYCLAW_TOOLS_EXEC_BACKGROUND_MAX_LIFETIME_MINUTES" json:"max_lifetime_minutes"`
+	IdleMinutes        int `env:"TINYCLAW_TOOLS_EXEC_BACKGROUND_IDLE_MINUTES"         json:"idle_minutes"`
+	OutputBufferKB     int `env:"TINYCLAW_TOOLS_EXEC_BACKGROUND_OUTPUT_BUFFER_KB"     json:"output_buffer_kb"`
+}
+
+// ExecSandboxConfig runs exec commands in a Linux sandbox: new user, mount,
+// pid and (unless Network is set) network namespaces, with the filesystem
+// read-only except the workspace, ReadWritePaths and a private /tmp,
+// enforced by Landlock where available, plus a seccomp filter and resource
+// limits. Limits use a cgroup under CgroupParent when set (it must be a
+// writable, delegated cgroup v2 directory) and rlimits otherwise. Commands
+// see only PATH, HOME, LANG and TERM from the gateway's environment, plus
+// the variables named in Env. The deny patterns still apply first.
+type ExecSandboxConfig struct {
+	Enabled        bool     `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_ENABLED"          json:"enabled"`
+	Network        bool     `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_NETWORK"          json:"network"`
+	ReadWritePaths []string `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_READ_WRITE_PATHS" json:"read_write_paths,omitempty"`
+	MemoryMB       int      `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_MEMORY_MB"        json:"memory_mb"`
+	CPUSeconds     int      `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_CPU_SECONDS"      json:"cpu_seconds"`
+	MaxProcesses   int      `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_MAX_PROCESSES"    json:"max_processes"`
+	CgroupParent   string   `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_CGROUP_PARENT"    json:"cgroup_parent,omitempty"`
+	Env            []string `env:"TINYCLAW_TOOLS_EXEC_SANDBOX_ENV"              json:"env,omitempty"`
 }
 
 type ToolsConfig struct {
-	Web    WebToolsConfig    `json:"web"`
-	Cron   CronToolsConfig   `json:"cron"`
-	Exec   ExecConfig        `json:"exec"`
-	Skills SkillsToolsConfig `json:"skills"`
-	MCP    MCPConfig         `json:"mcp"`
+	Web      WebToolsConfig    `json:"web"`
+	Cron     CronToolsConfig   `json:"cron"`
+	Exec     ExecConfig        `json:"exec"`
+	Skills   SkillsToolsConfig `json:"skills"`
+	MCP      MCPConfig         `json:"mcp"`
+	Journal  JournalConfig     `json:"journal"`
+	Hardware HardwareConfig    `json:"hardware"`
+}
+
+// HardwareConfig names the peripherals wired to the board, so the i2c and
+// spi tools can address them by name and read them through a driver
+// profile instead of raw bytes.
+type HardwareConfig struct {
+	Devices []HardwareDeviceConfig `json:"devices"`
+}
+
+// HardwareDeviceConfig is one peripheral. Interface is "i2c" (the default)
+// or "spi"; Bus is the I2C bus number ("1" for /dev/i2c-1) or the SPI
+// device ("0.0" for /dev/spidev0.0); Address ("0x76") is only used on I2C.
+// Options are passed to the profile, e.g. {"shunt_ohms": 0.05} for an
+// INA219.
+type HardwareDeviceConfig struct {
+	Name      string             `json:"name"`
+	Profile   string             `json:"profile"`
+	Interface string             `json:"interface,omitempty"`
+	Bus       string             `json:"bus"`
+	Address   string             `json:"address,omitempty"`
+	Options   map[string]float64 `json:"options,omitempty"`
+}
+
+// JournalConfig controls the workspace change journal, which snapshots
+// files before the filesystem tools change them so /undo and
+// `tinyclaw workspace revert` can roll the changes back. Snapshots beyond
+// MaxSizeMB, or older than MaxAgeDays, are dropped oldest first.
+type JournalConfig struct {
+	Enabled    bool `env:"TINYCLAW_TOOLS_JOURNAL_ENABLED"      json:"enabled"`
+	MaxSizeMB  int  `env:"TINYCLAW_TOOLS_JOURNAL_MAX_SIZE_MB"  json:"max_size_mb"`
+	MaxAgeDays int  `env:"TINYCLAW_TOOLS_JOURNAL_MAX_AGE_DAYS" json:"max_age_days"`
 }
 
 // MCPConfig configures MCP (Model Context Protocol) server integrations.
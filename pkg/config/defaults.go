@@ -19,6 +19,10 @@ func DefaultConfig() *Config {
 				MaxTokens:           8192,
 				Temperature:         nil, // nil means use provider default
 				MaxToolIterations:   20,
+				Memory: MemoryConfig{
+					Retrieval: true,
+					TopK:      5,
+				},
 			},
 		},
 		Bindings: []AgentBinding{},
@@ -51,10 +55,14 @@ func DefaultConfig() *Config {
 				MentionOnly: false,
 			},
 			MaixCam: MaixCamConfig{
-				Enabled:   false,
-				Host:      "0.0.0.0",
-				Port:      18790,
-				AllowFrom: FlexibleStringSlice{},
+				Enabled:              false,
+				Host:                 "0.0.0.0",
+				Port:                 18790,
+				Token:                "",
+				Classes:              FlexibleStringSlice{},
+				MinConfidence:        0.5,
+				EventIntervalSeconds: 30,
+				AllowFrom:            FlexibleStringSlice{},
 			},
 			QQ: QQConfig{
 				Enabled:   false,
@@ -119,6 +127,23 @@ func DefaultConfig() *Config {
 				Enabled:   false,
 				AllowFrom: FlexibleStringSlice{},
 			},
+			Matrix: MatrixConfig{
+				Enabled:   false,
+				AutoJoin:  true,
+				AllowFrom: FlexibleStringSlice{},
+			},
+			Email: EmailConfig{
+				Enabled:      false,
+				IMAPSecurity: "tls",
+				SMTPSecurity: "starttls",
+				Mailbox:      "INBOX",
+				PollInterval: 60,
+				AllowFrom:    FlexibleStringSlice{},
+			},
+			Web: WebConfig{
+				Enabled:        false,
+				MaxUploadBytes: 10 << 20,
+			},
 		},
 		Providers: ProvidersConfig{
 			OpenAI: OpenAIProviderConfig{WebSearch: true},
@@ -284,8 +309,21 @@ func DefaultConfig() *Config {
 			},
 		},
 		Gateway: GatewayConfig{
-			Host: "127.0.0.1",
-			Port: 18790,
+			Host:        "127.0.0.1",
+			Port:        18790,
+			WatchConfig: true,
+			Webhooks: WebhooksConfig{
+				MaxBodyBytes:        1 << 20,
+				ReplayWindowSeconds: 300,
+			},
+			Jobs: JobsConfig{
+				MaxJobs: 500,
+			},
+			Shutdown: ShutdownConfig{
+				DrainTimeoutSeconds: 30,
+				Notice:              "Restarting, back in a moment.",
+				NoticeWindowMinutes: 30,
+			},
 		},
 		Tools: ToolsConfig{
 			Web: WebToolsConfig{
@@ -304,12 +342,28 @@ func DefaultConfig() *Config {
 					APIKey:     "",
 					MaxResults: 5,
 				},
+				Fetch: WebFetchConfig{
+					MaxChars:        50000,
+					MaxBytes:        5 << 20,
+					CacheTTLMinutes: 15,
+				},
 			},
 			Cron: CronToolsConfig{
 				ExecTimeoutMinutes: 5,
 			},
 			Exec: ExecConfig{
 				EnableDenyPatterns: true,
+				Sandbox: ExecSandboxConfig{
+					MemoryMB:     1024,
+					CPUSeconds:   300,
+					MaxProcesses: 256,
+				},
+				Background: ExecBackgroundConfig{
+					MaxProcesses:       4,
+					MaxLifetimeMinutes: 60,
+					IdleMinutes:        30,
+					OutputBufferKB:     256,
+				},
 			},
 			Skills: SkillsToolsConfig{
 				Registries: SkillsRegistriesConfig{
@@ -324,14 +378,24 @@ func DefaultConfig() *Config {
 					TTLSeconds: 300,
 				},
 			},
+			Journal: JournalConfig{
+				Enabled:    true,
+				MaxSizeMB:  100,
+				MaxAgeDays: 14,
+			},
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
 			Interval: 30,
 		},
 		Devices: DevicesConfig{
-			Enabled:    false,
-			MonitorUSB: true,
+			Enabled:          false,
+			MonitorUSB:       true,
+			MonitorBlock:     true,
+			MonitorNet:       false,
+			MonitorPower:     true,
+			MonitorBluetooth: true,
+			PowerThresholds:  []int{20, 10, 5},
 		},
 	}
 }
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Changes lists what differs between two configurations: the top-level
+// sections by JSON name ("agents", "bindings", "model_list", ...) and,
+// within "channels", the individual channels.
+type Changes struct {
+	Sections []string
+	Channels []string
+}
+
+// Empty reports whether nothing changed.
+func (c Changes) Empty() bool {
+	return len(c.Sections) == 0
+}
+
+// Has reports whether any of the given sections changed.
+func (c Changes) Has(sections ...string) bool {
+	for _, s := range sections {
+		if slices.Contains(c.Sections, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares two configurations section by section.
+func Diff(old, next *Config) Changes {
+	var changes Changes
+	changes.Sections = diffFields(reflect.ValueOf(*old), reflect.ValueOf(*next))
+	if changes.Has("channels") {
+		changes.Channels = diffFields(reflect.ValueOf(old.Channels), reflect.ValueOf(next.Channels))
+	}
+	return changes
+}
+
+// diffFields returns the JSON names of the struct fields that differ.
+func diffFields(a, b reflect.Value) []string {
+	var names []string
+	for i := range a.NumField() {
+		if reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = a.Type().Field(i).Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// ChannelEnabled reports whether the named channel (its JSON name under
+// "channels") is switched on.
+func (c *Config) ChannelEnabled(name string) bool {
+	v := reflect.ValueOf(c.Channels)
+	for i := range v.NumField() {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if tag != name {
+			continue
+		}
+		enabled := v.Field(i).FieldByName("Enabled")
+		return enabled.IsValid() && enabled.Bool()
+	}
+	return false
+}
+
+// WithChannelsFrom returns a copy of c in which the named channels (JSON
+// names under "channels") have their settings from prev.
+func (c *Config) WithChannelsFrom(prev *Config, names []string) *Config {
+	out := *c
+	v, pv := reflect.ValueOf(&out.Channels).Elem(), reflect.ValueOf(prev.Channels)
+	for i := range v.NumField() {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if slices.Contains(names, tag) {
+			v.Field(i).Set(pv.Field(i))
+		}
+	}
+	return &out
+}
+
+// WatchFiles polls paths every interval and calls onChange when any of
+// them has been created, removed or modified, once it has then stayed
+// unchanged for one more interval so a file is not read half-written. It
+// returns when ctx is done.
+func WatchFiles(ctx context.Context, paths []string, interval time.Duration, onChange func()) {
+	stamp := func() string {
+		var b strings.Builder
+		for _, p := range paths {
+			if info, err := os.Stat(p); err == nil {
+				fmt.Fprintf(&b, "%d:%d", info.ModTime().UnixNano(), info.Size())
+			}
+			b.WriteByte(';')
+		}
+		return b.String()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	seen, pending := stamp(), ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := stamp()
+		switch {
+		case current != seen && current != pending:
+			pending = current
+		case current != seen && current == pending:
+			seen, pending = current, ""
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := DefaultConfig()
+	if changes := Diff(old, DefaultConfig()); !changes.Empty() {
+		t.Fatalf("identical configs differ: %+v", changes)
+	}
+
+	next := DefaultConfig()
+	next.Channels.Telegram.Enabled = true
+	next.Channels.Web.Token = "secret"
+	next.Bindings = []AgentBinding{{AgentID: "main"}}
+	changes := Diff(old, next)
+	if !slices.Equal(changes.Sections, []string{"bindings", "channels"}) {
+		t.Errorf("Sections = %v", changes.Sections)
+	}
+	if !slices.Equal(changes.Channels, []string{"telegram", "web"}) {
+		t.Errorf("Channels = %v", changes.Channels)
+	}
+	if !changes.Has("tools", "bindings") || changes.Has("tools", "agents") {
+		t.Errorf("Has is wrong for %v", changes.Sections)
+	}
+}
+
+func TestConfig_ChannelEnabled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Channels.MaixCam.Enabled = true
+	if !cfg.ChannelEnabled("maixcam") {
+		t.Error("maixcam should be enabled")
+	}
+	if cfg.ChannelEnabled("telegram") || cfg.ChannelEnabled("nope") {
+		t.Error("telegram and unknown channels should be disabled")
+	}
+}
+
+func TestConfig_WithChannelsFrom(t *testing.T) {
+	prev := DefaultConfig()
+	next := DefaultConfig()
+	next.Channels.Telegram.Token = "new"
+	next.Channels.Web.Token = "new"
+	next.Heartbeat.Interval = 5
+
+	kept := next.WithChannelsFrom(prev, []string{"telegram"})
+	if kept.Channels.Telegram.Token != "" || kept.Channels.Web.Token != "new" || kept.Heartbeat.Interval != 5 {
+		t.Errorf("unexpected channels %+v", kept.Channels)
+	}
+	if next.Channels.Telegram.Token != "new" {
+		t.Error("WithChannelsFrom modified its receiver")
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 10)
+	go WatchFiles(ctx, []string{path, path + ".missing"}, 10*time.Millisecond, func() { changed <- struct{}{} })
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-changed:
+		t.Fatal("change reported before the file was touched")
+	default:
+	}
+
+	if err := os.WriteFile(path, []byte(`{"agents": {}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not reported")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(changed) != 0 {
+		t.Errorf("one write reported %d more times", len(changed))
+	}
+}
@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -14,6 +15,8 @@ import (
 	"time"
 
 	"github.com/adhocore/gronx"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
 )
 
 type CronSchedule struct {
@@ -66,6 +69,7 @@ type CronService struct {
 	mu        sync.RWMutex
 	running   bool
 	stopChan  chan struct{}
+	loopDone  chan struct{} // closed when the run loop has exited
 	gronx     *gronx.Gronx
 }
 
@@ -98,8 +102,9 @@ func (cs *CronService) Start() error {
 	}
 
 	cs.stopChan = make(chan struct{})
+	cs.loopDone = make(chan struct{})
 	cs.running = true
-	go cs.runLoop(cs.stopChan)
+	go cs.runLoop(cs.stopChan, cs.loopDone)
 
 	return nil
 }
@@ -119,7 +124,25 @@ func (cs *CronService) Stop() {
 	}
 }
 
-func (cs *CronService) runLoop(stopChan chan struct{}) {
+// Wait blocks until the run loop has exited after Stop, so that a job that
+// was running has finished and saved its state, or until ctx is done.
+func (cs *CronService) Wait(ctx context.Context) error {
+	cs.mu.RLock()
+	done := cs.loopDone
+	cs.mu.RUnlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (cs *CronService) runLoop(stopChan, loopDone chan struct{}) {
+	defer close(loopDone)
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
@@ -225,6 +248,7 @@ func (cs *CronService) executeJobByID(jobID string) {
 		job.State.LastStatus = "ok"
 		job.State.LastError = ""
 	}
+	metrics.CronRuns.Inc(job.State.LastStatus)
 
 	// Compute next run time
 	if job.Schedule.Kind == "at" {
@@ -1,10 +1,13 @@
 package cron
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSaveStore_FilePermissions(t *testing.T) {
@@ -33,6 +36,46 @@ func TestSaveStore_FilePermissions(t *testing.T) {
 	}
 }
 
+func TestWait_RunningJobFinishesAfterStop(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "jobs.json")
+	started, release := make(chan struct{}), make(chan struct{})
+	cs := NewCronService(storePath, func(*CronJob) (string, error) {
+		close(started)
+		<-release
+		return "done", nil
+	})
+	if _, err := cs.AddJob("once", CronSchedule{Kind: "every", EveryMS: int64Ptr(10)}, "hi", false, "cli", "direct"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job never started")
+	}
+	cs.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cs.Wait(ctx); err == nil {
+		t.Fatal("Wait returned while the job was still running")
+	}
+
+	close(release)
+	if err := cs.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"lastStatus": "ok"`) {
+		t.Errorf("job state not saved after Wait:\n%s", data)
+	}
+}
+
 func int64Ptr(v int64) *int64 {
 	return &v
 }
@@ -1,6 +1,9 @@
 package events
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 type EventSource interface {
 	Kind() Kind
@@ -22,6 +25,9 @@ const (
 	KindUSB       Kind = "usb"
 	KindBluetooth Kind = "bluetooth"
 	KindPCI       Kind = "pci"
+	KindBlock     Kind = "block"
+	KindNet       Kind = "net"
+	KindPower     Kind = "power"
 	KindGeneric   Kind = "generic"
 )
 
@@ -33,20 +39,27 @@ type DeviceEvent struct {
 	Product      string            // Product name or ID
 	Serial       string            // Serial number if available
 	Capabilities string            // Human-readable capability description
+	Status       string            // What changed, e.g. "link up" or "battery at 10%"
 	Raw          map[string]string // Raw properties for extensibility
 }
 
 func (e *DeviceEvent) FormatMessage() string {
 	actionEmoji := "🔌"
 	actionText := "Connected"
-	if e.Action == ActionRemove {
-		actionEmoji = "🔌"
+	switch e.Action {
+	case ActionRemove:
 		actionText = "Disconnected"
+	case ActionChange:
+		actionEmoji = "🔄"
+		actionText = "Changed"
 	}
 
 	msg := actionEmoji + " Device " + actionText + "\n\n"
 	msg += "Type: " + string(e.Kind) + "\n"
-	msg += "Device: " + e.Vendor + " " + e.Product + "\n"
+	msg += "Device: " + strings.TrimSpace(e.Vendor+" "+e.Product) + "\n"
+	if e.Status != "" {
+		msg += "Status: " + e.Status + "\n"
+	}
 	if e.Capabilities != "" {
 		msg += "Capabilities: " + e.Capabilities + "\n"
 	}
@@ -0,0 +1,215 @@
+package devices
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+)
+
+// What a rule does with a matching event.
+const (
+	DoNotify = "notify" // post the event to the rule's target chat
+	DoAgent  = "agent"  // run the rule's prompt through the agent
+	DoSkill  = "skill"  // have the agent run the rule's skill
+	DoIgnore = "ignore" // drop the event
+)
+
+// Rule matches device events and says how to react to them. Empty match
+// fields match anything; Vendor, Product, Serial and Match values are
+// case-insensitive glob patterns.
+type Rule struct {
+	Name   string
+	Kind   events.Kind
+	Action events.Action
+	// Vendor, Product and Serial are matched against both the event's
+	// display value and the raw udev properties (ID_VENDOR_ID, ID_MODEL_ID,
+	// ID_SERIAL_SHORT and friends), so "046d" and "Logitech*" both work.
+	Vendor  string
+	Product string
+	Serial  string
+	Match   map[string]string // raw property name -> pattern
+
+	Do     string
+	Prompt string
+	Skill  string
+
+	// Channel and ChatID select where notifications and agent replies go;
+	// when empty the last active chat is used.
+	Channel string
+	ChatID  string
+
+	// Debounce delays the rule until a device has been quiet for this
+	// long, then handles only its latest event, so a flapping device
+	// triggers once.
+	Debounce time.Duration
+}
+
+func (r *Rule) validate() error {
+	switch r.Do {
+	case "", DoNotify, DoIgnore:
+	case DoAgent:
+		if r.Prompt == "" {
+			return fmt.Errorf("rule %q: do=agent requires a prompt", r.Name)
+		}
+	case DoSkill:
+		if r.Skill == "" {
+			return fmt.Errorf("rule %q: do=skill requires a skill", r.Name)
+		}
+	default:
+		return fmt.Errorf("rule %q: unknown do %q (want notify, agent, skill or ignore)", r.Name, r.Do)
+	}
+	if (r.Channel == "") != (r.ChatID == "") {
+		return fmt.Errorf("rule %q: channel and chat_id must be set together", r.Name)
+	}
+	if r.Debounce < 0 {
+		return fmt.Errorf("rule %q: debounce must not be negative", r.Name)
+	}
+	patterns := []string{r.Vendor, r.Product, r.Serial}
+	for _, p := range r.Match {
+		patterns = append(patterns, p)
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("rule %q: invalid pattern %q: %w", r.Name, p, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether ev satisfies every condition of the rule.
+func (r *Rule) Matches(ev *events.DeviceEvent) bool {
+	if r.Kind != "" && r.Kind != ev.Kind {
+		return false
+	}
+	if r.Action != "" && r.Action != ev.Action {
+		return false
+	}
+	if !matchAny(r.Vendor, ev.Vendor, ev.Raw["ID_VENDOR_ID"], ev.Raw["ID_VENDOR"]) ||
+		!matchAny(r.Product, ev.Product, ev.Raw["ID_MODEL_ID"], ev.Raw["ID_MODEL"]) ||
+		!matchAny(r.Serial, ev.Serial, ev.Raw["ID_SERIAL_SHORT"], ev.Raw["ID_SERIAL"]) {
+		return false
+	}
+	for key, pattern := range r.Match {
+		if !matchPattern(pattern, ev.Raw[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny reports whether pattern is empty or matches one of the
+// non-empty values.
+func matchAny(pattern string, values ...string) bool {
+	if pattern == "" {
+		return true
+	}
+	for _, v := range values {
+		if v != "" && matchPattern(pattern, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPattern(pattern, value string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return ok
+}
+
+// deviceKey identifies the physical device behind an event for debouncing.
+// Add and remove events of one device share a key.
+func deviceKey(ev *events.DeviceEvent) string {
+	switch {
+	case ev.Serial != "":
+		return string(ev.Kind) + ":" + ev.Serial
+	case ev.Raw["DEVPATH"] != "":
+		return string(ev.Kind) + ":" + ev.Raw["DEVPATH"]
+	default:
+		return string(ev.Kind) + ":" + ev.DeviceID
+	}
+}
+
+// agentPrompt is the message the agent receives for an agent or skill rule.
+func agentPrompt(r *Rule, ev *events.DeviceEvent) string {
+	var b strings.Builder
+	if r.Do == DoSkill {
+		fmt.Fprintf(&b, "Use the %q skill to handle the device event below.\n", r.Skill)
+	}
+	if r.Prompt != "" {
+		b.WriteString(r.Prompt)
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\n[Device event matched rule %q]\n", r.Name)
+	fmt.Fprintf(&b, "Action: %s\nType: %s\n", ev.Action, ev.Kind)
+	for _, f := range []struct{ name, value string }{
+		{"Device ID", ev.DeviceID},
+		{"Vendor", ev.Vendor},
+		{"Product", ev.Product},
+		{"Serial", ev.Serial},
+		{"Capabilities", ev.Capabilities},
+		{"Status", ev.Status},
+	} {
+		if f.value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", f.name, f.value)
+		}
+	}
+	if len(ev.Raw) > 0 {
+		keys := make([]string, 0, len(ev.Raw))
+		for k := range ev.Raw {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("Properties:\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %s=%s\n", k, ev.Raw[k])
+		}
+	}
+	return b.String()
+}
+
+// debouncer runs a function once its key has been quiet for a while; each
+// call for a pending key replaces the function and restarts the wait.
+type debouncer struct {
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+func newDebouncer() *debouncer {
+	return &debouncer{pending: make(map[string]*time.Timer)}
+}
+
+func (d *debouncer) do(key string, wait time.Duration, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.pending[key]; ok {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(wait, func() {
+		d.mu.Lock()
+		current := d.pending[key] == t
+		if current {
+			delete(d.pending, key)
+		}
+		d.mu.Unlock()
+		if current {
+			fn()
+		}
+	})
+	d.pending[key] = t
+}
+
+// stop drops all pending calls.
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, t := range d.pending {
+		t.Stop()
+		delete(d.pending, key)
+	}
+}
@@ -0,0 +1,228 @@
+package devices
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+	"github.com/tinyland-inc/tinyclaw/pkg/state"
+)
+
+func testEvent(action events.Action) *events.DeviceEvent {
+	return &events.DeviceEvent{
+		Action:   action,
+		Kind:     events.KindUSB,
+		DeviceID: "1:4",
+		Vendor:   "Logitech",
+		Product:  "HD Pro Webcam C920",
+		Serial:   "A1B2C3",
+		Raw: map[string]string{
+			"ID_VENDOR_ID":    "046d",
+			"ID_MODEL_ID":     "082d",
+			"ID_SERIAL_SHORT": "A1B2C3",
+			"ID_USB_CLASS":    "0e",
+			"DEVPATH":         "/devices/pci0000:00/usb1/1-2",
+		},
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	ev := testEvent(events.ActionAdd)
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"empty rule matches everything", Rule{}, true},
+		{"kind", Rule{Kind: events.KindUSB}, true},
+		{"other kind", Rule{Kind: events.KindBluetooth}, false},
+		{"action", Rule{Action: events.ActionAdd}, true},
+		{"other action", Rule{Action: events.ActionRemove}, false},
+		{"vendor name glob", Rule{Vendor: "logi*"}, true},
+		{"vendor id", Rule{Vendor: "046D"}, true},
+		{"other vendor", Rule{Vendor: "1a86"}, false},
+		{"product id", Rule{Vendor: "046d", Product: "082d"}, true},
+		{"product name glob", Rule{Product: "*webcam*"}, true},
+		{"serial", Rule{Serial: "a1b2c3"}, true},
+		{"other serial", Rule{Serial: "ZZZ"}, false},
+		{"raw property", Rule{Match: map[string]string{"ID_USB_CLASS": "0e"}}, true},
+		{"raw property mismatch", Rule{Match: map[string]string{"ID_USB_CLASS": "08"}}, false},
+		{"missing raw property", Rule{Match: map[string]string{"ID_FS_TYPE": "vfat"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(ev); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{"notify by default", Rule{Name: "r"}, ""},
+		{"agent needs prompt", Rule{Name: "r", Do: DoAgent}, "requires a prompt"},
+		{"skill needs skill", Rule{Name: "r", Do: DoSkill}, "requires a skill"},
+		{"unknown do", Rule{Name: "r", Do: "reboot"}, "unknown do"},
+		{"half a target", Rule{Name: "r", Channel: "telegram"}, "set together"},
+		{"bad pattern", Rule{Name: "r", Vendor: "[046d"}, "invalid pattern"},
+		{"bad raw pattern", Rule{Name: "r", Match: map[string]string{"X": "["}}, "invalid pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDebouncer_CollapsesBursts(t *testing.T) {
+	d := newDebouncer()
+	var calls, last atomic.Int32
+	for i := 1; i <= 5; i++ {
+		d.do("dev", 50*time.Millisecond, func() {
+			calls.Add(1)
+			last.Store(int32(i))
+		})
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if calls.Load() != 1 || last.Load() != 5 {
+		t.Fatalf("calls = %d, last = %d; want one call for the latest event", calls.Load(), last.Load())
+	}
+
+	d.do("dev", 50*time.Millisecond, func() { calls.Add(1) })
+	d.stop()
+	time.Sleep(100 * time.Millisecond)
+	if calls.Load() != 1 {
+		t.Fatalf("stopped debouncer still ran: calls = %d", calls.Load())
+	}
+}
+
+func newTestService(t *testing.T, rules ...Rule) (*Service, *bus.MessageBus) {
+	t.Helper()
+	stateMgr := state.NewManager(t.TempDir())
+	if err := stateMgr.SetLastChannel("telegram:42"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(Config{Enabled: true, Rules: rules}, stateMgr)
+	msgBus := bus.NewMessageBus()
+	s.SetBus(msgBus)
+	return s, msgBus
+}
+
+func nextOutbound(t *testing.T, msgBus *bus.MessageBus) bus.OutboundMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("no outbound message")
+	}
+	return msg
+}
+
+func TestServiceDispatch_UnmatchedNotifiesLastChannel(t *testing.T) {
+	s, msgBus := newTestService(t, Rule{Name: "bt", Kind: events.KindBluetooth, Do: DoIgnore})
+	s.dispatch(testEvent(events.ActionAdd))
+
+	msg := nextOutbound(t, msgBus)
+	if msg.Channel != "telegram" || msg.ChatID != "42" {
+		t.Errorf("sent to %s:%s, want telegram:42", msg.Channel, msg.ChatID)
+	}
+	if !strings.Contains(msg.Content, "Connected") {
+		t.Errorf("content = %q, want the event notification", msg.Content)
+	}
+}
+
+func TestServiceDispatch_RuleTargetAndIgnore(t *testing.T) {
+	s, msgBus := newTestService(t,
+		Rule{Name: "quiet", Action: events.ActionRemove, Do: DoIgnore},
+		Rule{Name: "ops", Vendor: "046d", Channel: "slack", ChatID: "C123"},
+	)
+	s.dispatch(testEvent(events.ActionRemove)) // ignored by the first, notified by the second
+	msg := nextOutbound(t, msgBus)
+	if msg.Channel != "slack" || msg.ChatID != "C123" {
+		t.Errorf("sent to %s:%s, want slack:C123", msg.Channel, msg.ChatID)
+	}
+	if inbound, outbound := msgBus.QueueDepths(); inbound != 0 || outbound != 0 {
+		t.Errorf("queue depths = %d/%d, want nothing else sent", inbound, outbound)
+	}
+}
+
+func TestServiceDispatch_AgentRule(t *testing.T) {
+	s, msgBus := newTestService(t, Rule{
+		Name:   "camera",
+		Vendor: "046d",
+		Do:     DoSkill,
+		Skill:  "camera-check",
+		Prompt: "Report the resolution.",
+	})
+	prompts := make(chan string, 1)
+	s.SetAgentHandler(func(_ context.Context, prompt, sessionKey, channel, chatID string) (string, error) {
+		if sessionKey != "device-camera" || channel != "telegram" || chatID != "42" {
+			t.Errorf("handler got session %q, target %s:%s", sessionKey, channel, chatID)
+		}
+		prompts <- prompt
+		return "Camera ready at 1080p", nil
+	})
+	s.dispatch(testEvent(events.ActionAdd))
+
+	msg := nextOutbound(t, msgBus)
+	if msg.Content != "Camera ready at 1080p" || msg.Channel != "telegram" {
+		t.Errorf("reply = %+v", msg)
+	}
+	prompt := <-prompts
+	for _, want := range []string{`"camera-check" skill`, "Report the resolution.", "Action: add", "ID_VENDOR_ID=046d"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestServiceDispatch_DebouncesFlappingDevice(t *testing.T) {
+	s, _ := newTestService(t, Rule{Name: "flap", Do: DoAgent, Prompt: "x", Debounce: 50 * time.Millisecond})
+	var runs atomic.Int32
+	actions := make(chan string, 4)
+	s.SetAgentHandler(func(_ context.Context, prompt, _, _, _ string) (string, error) {
+		runs.Add(1)
+		if strings.Contains(prompt, "Action: remove") {
+			actions <- "remove"
+		} else {
+			actions <- "add"
+		}
+		return "", nil
+	})
+	for _, a := range []events.Action{events.ActionAdd, events.ActionRemove, events.ActionAdd, events.ActionRemove} {
+		s.dispatch(testEvent(a))
+	}
+
+	select {
+	case got := <-actions:
+		if got != "remove" {
+			t.Errorf("handled %q, want the latest event (remove)", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("debounced rule never ran")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := runs.Load(); n != 1 {
+		t.Errorf("agent ran %d times, want 1", n)
+	}
+}
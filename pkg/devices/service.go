@@ -2,6 +2,7 @@ package devices
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -13,32 +14,72 @@ import (
 	"github.com/tinyland-inc/tinyclaw/pkg/state"
 )
 
+// AgentHandler runs prompt through the agent in the given session and
+// returns its reply. channel and chatID name the chat the run acts for.
+type AgentHandler func(ctx context.Context, prompt, sessionKey, channel, chatID string) (string, error)
+
 type Service struct {
-	bus     *bus.MessageBus
-	state   *state.Manager
-	sources []events.EventSource
-	enabled bool
-	ctx     context.Context
-	cancel  context.CancelFunc
-	mu      sync.RWMutex
+	bus      *bus.MessageBus
+	state    *state.Manager
+	sources  []events.EventSource
+	rules    []Rule
+	handler  AgentHandler
+	debounce *debouncer
+	enabled  bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.RWMutex
 }
 
 type Config struct {
-	Enabled    bool
-	MonitorUSB bool // When true, monitor USB hotplug (Linux only)
-	// Future: MonitorBluetooth, MonitorPCI, etc.
+	Enabled          bool
+	MonitorUSB       bool // When true, monitor USB hotplug (Linux only)
+	MonitorBlock     bool // Disks, partitions and mounts (Linux only)
+	MonitorNet       bool // Network interfaces, link state and addresses (Linux only)
+	MonitorPower     bool // AC adapters and battery thresholds (Linux only)
+	MonitorBluetooth bool // Bluetooth adapters (Linux only)
+	PowerThresholds  []int
+
+	// Rules are checked in order and every matching rule runs. Events that
+	// match no rule are posted to the last active chat.
+	Rules []Rule
 }
 
 func NewService(cfg Config, stateMgr *state.Manager) *Service {
 	s := &Service{
-		state:   stateMgr,
-		enabled: cfg.Enabled,
-		sources: make([]EventSource, 0),
+		state:    stateMgr,
+		enabled:  cfg.Enabled,
+		sources:  make([]EventSource, 0),
+		debounce: newDebouncer(),
+		ctx:      context.Background(),
 	}
 
 	if cfg.Enabled && cfg.MonitorUSB {
 		s.sources = append(s.sources, sources.NewUSBMonitor())
 	}
+	if cfg.Enabled && cfg.MonitorBlock {
+		s.sources = append(s.sources, sources.NewBlockMonitor())
+	}
+	if cfg.Enabled && cfg.MonitorNet {
+		s.sources = append(s.sources, sources.NewNetMonitor())
+	}
+	if cfg.Enabled && cfg.MonitorPower {
+		s.sources = append(s.sources, sources.NewPowerMonitor(cfg.PowerThresholds))
+	}
+	if cfg.Enabled && cfg.MonitorBluetooth {
+		s.sources = append(s.sources, sources.NewBluetoothMonitor())
+	}
+
+	for i, r := range cfg.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if err := r.validate(); err != nil {
+			logger.WarnCF("devices", "Skipping invalid device rule", map[string]any{"error": err.Error()})
+			continue
+		}
+		s.rules = append(s.rules, r)
+	}
 
 	return s
 }
@@ -49,6 +90,14 @@ func (s *Service) SetBus(msgBus *bus.MessageBus) {
 	s.bus = msgBus
 }
 
+// SetAgentHandler sets the function agent and skill rules run through.
+// Without one those rules fall back to a plain notification.
+func (s *Service) SetAgentHandler(handler AgentHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
 func (s *Service) Start(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -89,6 +138,7 @@ func (s *Service) Stop() {
 		s.cancel()
 		s.cancel = nil
 	}
+	s.debounce.stop()
 
 	for _, src := range s.sources {
 		src.Stop()
@@ -102,43 +152,115 @@ func (s *Service) handleEvents(_ events.Kind, eventCh <-chan *events.DeviceEvent
 		if ev == nil {
 			continue
 		}
+		s.dispatch(ev)
+	}
+}
+
+// dispatch runs every rule matching ev, debouncing where configured, and
+// falls back to a notification when no rule matches.
+func (s *Service) dispatch(ev *events.DeviceEvent) {
+	matched := false
+	for i := range s.rules {
+		rule := &s.rules[i]
+		if !rule.Matches(ev) {
+			continue
+		}
+		matched = true
+		if rule.Debounce > 0 {
+			s.debounce.do(rule.Name+"\x00"+deviceKey(ev), rule.Debounce, func() { s.runRule(rule, ev) })
+		} else {
+			s.runRule(rule, ev)
+		}
+	}
+	if !matched {
 		s.sendNotification(ev)
 	}
 }
 
-func (s *Service) sendNotification(ev *events.DeviceEvent) {
+func (s *Service) runRule(rule *Rule, ev *events.DeviceEvent) {
 	s.mu.RLock()
-	msgBus := s.bus
+	handler, ctx := s.handler, s.ctx
 	s.mu.RUnlock()
 
-	if msgBus == nil {
-		return
+	logger.DebugCF("devices", "Device rule matched", map[string]any{
+		"rule":   rule.Name,
+		"kind":   ev.Kind,
+		"action": ev.Action,
+	})
+	switch {
+	case rule.Do == DoIgnore:
+	case (rule.Do == DoAgent || rule.Do == DoSkill) && handler != nil:
+		// Agent runs take a while; don't hold up the event source.
+		go s.runAgent(ctx, handler, rule, ev)
+	default:
+		channel, chatID := s.target(rule)
+		s.publish(channel, chatID, ev.FormatMessage(), ev)
 	}
+}
 
-	lastChannel := s.state.GetLastChannel()
-	if lastChannel == "" {
-		logger.DebugCF("devices", "No last channel, skipping notification", map[string]any{
-			"event": ev.FormatMessage(),
+func (s *Service) runAgent(ctx context.Context, handler AgentHandler, rule *Rule, ev *events.DeviceEvent) {
+	channel, chatID := s.target(rule)
+	runChannel, runChatID := channel, chatID
+	if runChannel == "" {
+		runChannel, runChatID = "cli", "direct"
+	}
+	response, err := handler(ctx, agentPrompt(rule, ev), "device-"+rule.Name, runChannel, runChatID)
+	if err != nil {
+		logger.ErrorCF("devices", "Device rule agent run failed", map[string]any{
+			"rule":  rule.Name,
+			"error": err.Error(),
 		})
 		return
 	}
+	if response != "" {
+		s.publish(channel, chatID, response, ev)
+	}
+}
 
+// target returns the chat a rule reports to: its own, or the last active
+// one. It returns empty strings when there is nowhere to report.
+func (s *Service) target(rule *Rule) (channel, chatID string) {
+	if rule != nil && rule.Channel != "" {
+		return rule.Channel, rule.ChatID
+	}
+	lastChannel := s.state.GetLastChannel()
 	platform, userID := parseLastChannel(lastChannel)
 	if platform == "" || userID == "" || constants.IsInternalChannel(platform) {
+		return "", ""
+	}
+	return platform, userID
+}
+
+func (s *Service) sendNotification(ev *events.DeviceEvent) {
+	channel, chatID := s.target(nil)
+	s.publish(channel, chatID, ev.FormatMessage(), ev)
+}
+
+func (s *Service) publish(channel, chatID, content string, ev *events.DeviceEvent) {
+	s.mu.RLock()
+	msgBus := s.bus
+	s.mu.RUnlock()
+
+	if msgBus == nil {
+		return
+	}
+	if channel == "" {
+		logger.DebugCF("devices", "No target channel, skipping notification", map[string]any{
+			"event": ev.FormatMessage(),
+		})
 		return
 	}
 
-	msg := ev.FormatMessage()
 	msgBus.PublishOutbound(bus.OutboundMessage{
-		Channel: platform,
-		ChatID:  userID,
-		Content: msg,
+		Channel: channel,
+		ChatID:  chatID,
+		Content: content,
 	})
 
 	logger.InfoCF("devices", "Device notification sent", map[string]any{
 		"kind":   ev.Kind,
 		"action": ev.Action,
-		"to":     platform,
+		"to":     channel,
 	})
 }
 
@@ -0,0 +1,340 @@
+//go:build linux
+
+package sources
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// ueventKernelGroup is the multicast group the kernel sends uevents to.
+// It works without udevd, which many embedded boards don't run.
+const ueventKernelGroup = 1
+
+// netlinkConn is a netlink socket registered with the runtime poller, so
+// reads block a goroutine rather than a thread and Close unblocks them.
+type netlinkConn struct {
+	f *os.File
+}
+
+func dialNetlink(proto int, groups uint32) (*netlinkConn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: groups}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+	// A larger receive buffer rides out bursts such as a hub full of
+	// devices being plugged in; failure only means the default size.
+	_ = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, 1<<20)
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink nonblock: %w", err)
+	}
+	return &netlinkConn{f: os.NewFile(uintptr(fd), "netlink")}, nil
+}
+
+func (c *netlinkConn) Read(b []byte) (int, error)  { return c.f.Read(b) }
+func (c *netlinkConn) Write(b []byte) (int, error) { return c.f.Write(b) }
+func (c *netlinkConn) Close() error                { return c.f.Close() }
+
+// readNetlink calls handle for every datagram until ctx is done or the
+// connection is closed. Overruns (ENOBUFS) lose events but are not fatal.
+func readNetlink(ctx context.Context, kind events.Kind, conn *netlinkConn, handle func([]byte) bool) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, os.ErrClosed) {
+				return
+			}
+			if errors.Is(err, syscall.ENOBUFS) {
+				logger.WarnCF("devices", "Netlink receive buffer overrun, events were lost", map[string]any{
+					"kind": kind,
+				})
+				continue
+			}
+			logger.ErrorCF("devices", "Netlink read error", map[string]any{
+				"kind":  kind,
+				"error": err.Error(),
+			})
+			return
+		}
+		if !handle(buf[:n]) {
+			return
+		}
+	}
+}
+
+// UeventMonitor reports one subsystem's kernel uevents read from a
+// NETLINK_KOBJECT_UEVENT socket.
+type UeventMonitor struct {
+	kind        events.Kind
+	parse       func(props map[string]string) *events.DeviceEvent
+	enrich      func(props map[string]string)
+	watchMounts bool
+
+	conn *netlinkConn
+	mu   sync.Mutex
+}
+
+// NewBlockMonitor reports disks and partitions appearing and going away,
+// media changes, and device filesystems being mounted and unmounted.
+func NewBlockMonitor() *UeventMonitor {
+	return &UeventMonitor{
+		kind:        events.KindBlock,
+		parse:       parseBlockEvent,
+		enrich:      readBlockAttrs,
+		watchMounts: true,
+	}
+}
+
+// NewPowerMonitor reports external power being connected or disconnected
+// and batteries discharging past each of thresholds (percent).
+func NewPowerMonitor(thresholds []int) *UeventMonitor {
+	return &UeventMonitor{
+		kind:  events.KindPower,
+		parse: newPowerTracker(thresholds).handle,
+	}
+}
+
+// NewBluetoothMonitor reports Bluetooth adapters appearing and going away.
+func NewBluetoothMonitor() *UeventMonitor {
+	return &UeventMonitor{
+		kind:  events.KindBluetooth,
+		parse: parseBluetoothEvent,
+	}
+}
+
+func (m *UeventMonitor) Kind() events.Kind {
+	return m.kind
+}
+
+func (m *UeventMonitor) Start(ctx context.Context) (<-chan *events.DeviceEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn, err := dialNetlink(syscall.NETLINK_KOBJECT_UEVENT, ueventKernelGroup)
+	if err != nil {
+		return nil, fmt.Errorf("uevent monitor: %w", err)
+	}
+	m.conn = conn
+
+	eventCh := make(chan *events.DeviceEvent, 16)
+	emit := func(ev *events.DeviceEvent) bool {
+		select {
+		case eventCh <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		readNetlink(ctx, m.kind, conn, func(b []byte) bool {
+			props := parseUevent(b)
+			if props == nil {
+				return true
+			}
+			if m.enrich != nil {
+				m.enrich(props)
+			}
+			if ev := m.parse(props); ev != nil {
+				return emit(ev)
+			}
+			return true
+		})
+	})
+	if m.watchMounts {
+		wg.Go(func() { watchMountTable(ctx, emit) })
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		wg.Wait()
+		close(eventCh)
+	}()
+
+	return eventCh, nil
+}
+
+func (m *UeventMonitor) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+	return nil
+}
+
+// readBlockAttrs adds the sysfs attributes parseBlockEvent uses. They are
+// only readable while the device exists, so remove events go without.
+func readBlockAttrs(props map[string]string) {
+	if props["ACTION"] == "remove" || props["DEVPATH"] == "" {
+		return
+	}
+	dir := filepath.Join("/sys", props["DEVPATH"])
+	disk := dir
+	if props["DEVTYPE"] == "partition" {
+		disk = filepath.Dir(dir)
+	}
+	for key, file := range map[string]string{
+		"ATTR_REMOVABLE": filepath.Join(disk, "removable"),
+		"ATTR_VENDOR":    filepath.Join(disk, "device", "vendor"),
+		"ATTR_MODEL":     filepath.Join(disk, "device", "model"),
+		"ATTR_SIZE":      filepath.Join(dir, "size"),
+	} {
+		if data, err := os.ReadFile(file); err == nil {
+			props[key] = strings.TrimSpace(string(data))
+		}
+	}
+}
+
+// watchMountTable reports device mounts coming and going. The kernel flags
+// /proc/self/mounts with POLLPRI|POLLERR whenever the mount table changes;
+// rereading the same open file clears the flag.
+func watchMountTable(ctx context.Context, emit func(*events.DeviceEvent) bool) {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		logger.WarnCF("devices", "Mount monitoring unavailable", map[string]any{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		logger.WarnCF("devices", "Mount monitoring unavailable", map[string]any{"error": err.Error()})
+		return
+	}
+	defer syscall.Close(epfd)
+	ev := syscall.EpollEvent{Events: syscall.EPOLLPRI | syscall.EPOLLERR, Fd: int32(fd)}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+		logger.WarnCF("devices", "Mount monitoring unavailable", map[string]any{"error": err.Error()})
+		return
+	}
+
+	read := func() map[string]mount {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil
+		}
+		return parseMounts(data)
+	}
+	mounts := read()
+	ready := make([]syscall.EpollEvent, 1)
+	for ctx.Err() == nil {
+		// Wake up every second to notice ctx being cancelled.
+		n, err := syscall.EpollWait(epfd, ready, 1000)
+		if errors.Is(err, syscall.EINTR) || n == 0 {
+			continue
+		}
+		if err != nil {
+			logger.ErrorCF("devices", "Mount monitoring failed", map[string]any{"error": err.Error()})
+			return
+		}
+		current := read()
+		if current == nil {
+			continue
+		}
+		for _, ev := range diffMounts(mounts, current) {
+			if !emit(ev) {
+				return
+			}
+		}
+		mounts = current
+	}
+}
+
+// NetMonitor reports network interfaces appearing and going away, link
+// up/down transitions and address changes from an rtnetlink socket.
+type NetMonitor struct {
+	conn *netlinkConn
+	mu   sync.Mutex
+}
+
+func NewNetMonitor() *NetMonitor {
+	return &NetMonitor{}
+}
+
+func (m *NetMonitor) Kind() events.Kind {
+	return events.KindNet
+}
+
+func (m *NetMonitor) Start(ctx context.Context) (<-chan *events.DeviceEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn, err := dialNetlink(syscall.NETLINK_ROUTE, rtmgrpLink|rtmgrpIPv4Ifaddr|rtmgrpIPv6Ifaddr)
+	if err != nil {
+		return nil, fmt.Errorf("net monitor: %w", err)
+	}
+	m.conn = conn
+
+	// Dump the current links first so that existing interfaces are not
+	// reported as new and the first transition of each is noticed.
+	tracker := newNetTracker()
+	req := make([]byte, syscall.SizeofNlMsghdr+syscall.SizeofIfInfomsg)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], syscall.RTM_GETLINK)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+	if _, err := conn.Write(req); err != nil {
+		logger.WarnCF("devices", "Link dump failed, existing interfaces will be reported as new", map[string]any{
+			"error": err.Error(),
+		})
+	} else {
+		tracker.seeding = true
+	}
+
+	eventCh := make(chan *events.DeviceEvent, 16)
+	go func() {
+		defer close(eventCh)
+		readNetlink(ctx, events.KindNet, conn, func(b []byte) bool {
+			for _, ev := range tracker.handle(b) {
+				select {
+				case eventCh <- ev:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		})
+	}()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	return eventCh, nil
+}
+
+func (m *NetMonitor) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+//go:build !linux
+
+package sources
+
+import (
+	"context"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+)
+
+type UeventMonitor struct {
+	kind events.Kind
+}
+
+func NewBlockMonitor() *UeventMonitor {
+	return &UeventMonitor{kind: events.KindBlock}
+}
+
+func NewPowerMonitor(thresholds []int) *UeventMonitor {
+	return &UeventMonitor{kind: events.KindPower}
+}
+
+func NewBluetoothMonitor() *UeventMonitor {
+	return &UeventMonitor{kind: events.KindBluetooth}
+}
+
+func (m *UeventMonitor) Kind() events.Kind {
+	return m.kind
+}
+
+func (m *UeventMonitor) Start(ctx context.Context) (<-chan *events.DeviceEvent, error) {
+	ch := make(chan *events.DeviceEvent)
+	close(ch) // Immediately close, no events
+	return ch, nil
+}
+
+func (m *UeventMonitor) Stop() error {
+	return nil
+}
+
+type NetMonitor struct{}
+
+func NewNetMonitor() *NetMonitor {
+	return &NetMonitor{}
+}
+
+func (m *NetMonitor) Kind() events.Kind {
+	return events.KindNet
+}
+
+func (m *NetMonitor) Start(ctx context.Context) (<-chan *events.DeviceEvent, error) {
+	ch := make(chan *events.DeviceEvent)
+	close(ch) // Immediately close, no events
+	return ch, nil
+}
+
+func (m *NetMonitor) Stop() error {
+	return nil
+}
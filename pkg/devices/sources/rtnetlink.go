@@ -0,0 +1,205 @@
+package sources
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+)
+
+// rtnetlink constants from <linux/netlink.h>, <linux/rtnetlink.h> and
+// <linux/if.h>. They are spelled out here so the parser builds and is
+// tested on every platform.
+const (
+	nlmsgHdrLen   = 16
+	ifInfoMsgLen  = 16
+	ifAddrMsgLen  = 8
+	rtAttrHdrLen  = 4
+	nlmsgError    = 2
+	nlmsgDone     = 3
+	rtmNewLink    = 16
+	rtmDelLink    = 17
+	rtmNewAddr    = 20
+	rtmDelAddr    = 21
+	iflaIfname    = 3
+	ifaAddress    = 1
+	ifaLocal      = 2
+	iffUp         = 0x1
+	iffLoopback   = 0x8
+	iffLowerUp    = 0x10000
+	rtScopeLink   = 253
+	rtScopeHost   = 254
+	afInet        = 2
+	afInet6Linux  = 10
+	netlinkAlignN = 4
+
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4Ifaddr = 0x10
+	rtmgrpIPv6Ifaddr = 0x100
+)
+
+func nlAlign(n int) int {
+	return (n + netlinkAlignN - 1) &^ (netlinkAlignN - 1)
+}
+
+// netTracker turns rtnetlink messages into interface events: interfaces
+// appearing and going away, link up/down transitions and global addresses
+// being added or removed. While seeding (answering the initial link dump)
+// it only records state.
+type netTracker struct {
+	links   map[int32]netLink
+	seeding bool
+}
+
+type netLink struct {
+	name string
+	up   bool
+}
+
+func newNetTracker() *netTracker {
+	return &netTracker{links: make(map[int32]netLink)}
+}
+
+// handle parses one datagram, which may hold several netlink messages.
+func (t *netTracker) handle(b []byte) []*events.DeviceEvent {
+	var evs []*events.DeviceEvent
+	for len(b) >= nlmsgHdrLen {
+		n := int(binary.NativeEndian.Uint32(b[0:4]))
+		typ := binary.NativeEndian.Uint16(b[4:6])
+		if n < nlmsgHdrLen || n > len(b) {
+			break
+		}
+		body := b[nlmsgHdrLen:n]
+		switch typ {
+		case nlmsgDone, nlmsgError:
+			t.seeding = false
+		case rtmNewLink, rtmDelLink:
+			if ev := t.link(typ, body); ev != nil {
+				evs = append(evs, ev)
+			}
+		case rtmNewAddr, rtmDelAddr:
+			if ev := t.addr(typ, body); ev != nil {
+				evs = append(evs, ev)
+			}
+		}
+		b = b[min(nlAlign(n), len(b)):]
+	}
+	return evs
+}
+
+func (t *netTracker) link(typ uint16, body []byte) *events.DeviceEvent {
+	if len(body) < ifInfoMsgLen {
+		return nil
+	}
+	index := int32(binary.NativeEndian.Uint32(body[4:8]))
+	flags := binary.NativeEndian.Uint32(body[8:12])
+	if flags&iffLoopback != 0 {
+		return nil
+	}
+	name := ""
+	if v, ok := rtAttrs(body[ifInfoMsgLen:])[iflaIfname]; ok {
+		name = string(bytes.TrimRight(v, "\x00"))
+	}
+	cur := netLink{name: name, up: flags&iffUp != 0 && flags&iffLowerUp != 0}
+	prev, known := t.links[index]
+	if cur.name == "" {
+		cur.name = prev.name
+	}
+
+	if typ == rtmDelLink {
+		delete(t.links, index)
+		if t.seeding {
+			return nil
+		}
+		return netEvent(events.ActionRemove, index, cur.name, "interface removed", map[string]string{
+			"EVENT": "interface",
+		})
+	}
+	t.links[index] = cur
+	switch {
+	case t.seeding:
+		return nil
+	case !known:
+		return netEvent(events.ActionAdd, index, cur.name, "interface added", map[string]string{
+			"EVENT": "interface",
+			"STATE": linkState(cur.up),
+		})
+	case prev.up != cur.up:
+		return netEvent(events.ActionChange, index, cur.name, "link "+linkState(cur.up), map[string]string{
+			"EVENT": "link",
+			"STATE": linkState(cur.up),
+		})
+	}
+	return nil
+}
+
+func (t *netTracker) addr(typ uint16, body []byte) *events.DeviceEvent {
+	if len(body) < ifAddrMsgLen || t.seeding {
+		return nil
+	}
+	family := body[0]
+	prefixLen := body[1]
+	scope := body[3]
+	index := int32(binary.NativeEndian.Uint32(body[4:8]))
+	if scope == rtScopeHost || scope == rtScopeLink {
+		return nil // loopback and link-local addresses come with every link
+	}
+	attrs := rtAttrs(body[ifAddrMsgLen:])
+	raw, ok := attrs[ifaLocal]
+	if !ok {
+		raw, ok = attrs[ifaAddress]
+	}
+	if !ok || (family == afInet && len(raw) != net.IPv4len) || (family == afInet6Linux && len(raw) != net.IPv6len) {
+		return nil
+	}
+	addr := fmt.Sprintf("%s/%d", net.IP(raw), prefixLen)
+
+	name := t.links[index].name
+	action, status := events.ActionAdd, "address "+addr+" added"
+	if typ == rtmDelAddr {
+		action, status = events.ActionRemove, "address "+addr+" removed"
+	}
+	return netEvent(action, index, name, status, map[string]string{
+		"EVENT":   "address",
+		"ADDRESS": addr,
+	})
+}
+
+// rtAttrs splits a run of rtattr structures into type -> payload.
+func rtAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= rtAttrHdrLen {
+		n := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4])
+		if n < rtAttrHdrLen || n > len(b) {
+			break
+		}
+		attrs[typ] = b[rtAttrHdrLen:n]
+		b = b[min(nlAlign(n), len(b)):]
+	}
+	return attrs
+}
+
+func linkState(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+func netEvent(action events.Action, index int32, name, status string, raw map[string]string) *events.DeviceEvent {
+	raw["INTERFACE"] = name
+	raw["IFINDEX"] = strconv.Itoa(int(index))
+	return &events.DeviceEvent{
+		Action:       action,
+		Kind:         events.KindNet,
+		DeviceID:     name,
+		Product:      name,
+		Capabilities: "Network interface",
+		Status:       status,
+		Raw:          raw,
+	}
+}
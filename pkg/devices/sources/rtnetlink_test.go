@@ -0,0 +1,119 @@
+package sources
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+)
+
+// nlmsg encodes one netlink message the way the kernel sends it on an
+// rtnetlink socket.
+func nlmsg(typ uint16, body []byte) []byte {
+	n := nlmsgHdrLen + len(body)
+	b := make([]byte, nlAlign(n))
+	binary.NativeEndian.PutUint32(b[0:4], uint32(n))
+	binary.NativeEndian.PutUint16(b[4:6], typ)
+	copy(b[nlmsgHdrLen:], body)
+	return b
+}
+
+func rtattr(typ uint16, data []byte) []byte {
+	n := rtAttrHdrLen + len(data)
+	b := make([]byte, nlAlign(n))
+	binary.NativeEndian.PutUint16(b[0:2], uint16(n))
+	binary.NativeEndian.PutUint16(b[2:4], typ)
+	copy(b[rtAttrHdrLen:], data)
+	return b
+}
+
+func linkMsg(typ uint16, index int32, name string, flags uint32) []byte {
+	body := make([]byte, ifInfoMsgLen)
+	binary.NativeEndian.PutUint32(body[4:8], uint32(index))
+	binary.NativeEndian.PutUint32(body[8:12], flags)
+	body = append(body, rtattr(iflaIfname, append([]byte(name), 0))...)
+	return nlmsg(typ, body)
+}
+
+func addrMsg(typ uint16, index int32, ip net.IP, prefix, scope byte) []byte {
+	family, raw := byte(afInet6Linux), ip.To16()
+	if v4 := ip.To4(); v4 != nil {
+		family, raw = afInet, v4
+	}
+	body := []byte{family, prefix, 0, scope, 0, 0, 0, 0}
+	binary.NativeEndian.PutUint32(body[4:8], uint32(index))
+	body = append(body, rtattr(ifaAddress, raw)...)
+	body = append(body, rtattr(ifaLocal, raw)...)
+	return nlmsg(typ, body)
+}
+
+const (
+	flagsUp   = iffUp | iffLowerUp | 0x1000 // IFF_MULTICAST
+	flagsDown = 0x1000
+)
+
+func TestNetTracker(t *testing.T) {
+	tracker := newNetTracker()
+	tracker.seeding = true
+
+	// The initial dump arrives as several messages in one datagram.
+	var dump []byte
+	dump = append(dump, linkMsg(rtmNewLink, 1, "lo", iffUp|iffLoopback|iffLowerUp)...)
+	dump = append(dump, linkMsg(rtmNewLink, 2, "eth0", flagsUp)...)
+	dump = append(dump, linkMsg(rtmNewLink, 3, "wlan0", flagsDown)...)
+	dump = append(dump, nlmsg(nlmsgDone, make([]byte, 4))...)
+	if evs := tracker.handle(dump); len(evs) != 0 {
+		t.Fatalf("link dump produced events: %+v", evs[0])
+	}
+	if tracker.seeding {
+		t.Fatal("still seeding after NLMSG_DONE")
+	}
+
+	expect := func(msg []byte, action events.Action, iface, status string) *events.DeviceEvent {
+		t.Helper()
+		evs := tracker.handle(msg)
+		if len(evs) != 1 {
+			t.Fatalf("got %d events, want 1 (%s)", len(evs), status)
+		}
+		ev := evs[0]
+		if ev.Action != action || ev.Kind != events.KindNet || ev.DeviceID != iface || ev.Status != status {
+			t.Errorf("event = %+v, want %s %s %q", ev, action, iface, status)
+		}
+		return ev
+	}
+	expectNone := func(msg []byte, what string) {
+		t.Helper()
+		if evs := tracker.handle(msg); len(evs) != 0 {
+			t.Errorf("%s produced %+v", what, evs[0])
+		}
+	}
+
+	ev := expect(linkMsg(rtmNewLink, 3, "wlan0", flagsUp), events.ActionChange, "wlan0", "link up")
+	if ev.Raw["EVENT"] != "link" || ev.Raw["STATE"] != "up" || ev.Raw["IFINDEX"] != "3" {
+		t.Errorf("raw = %v", ev.Raw)
+	}
+	expectNone(linkMsg(rtmNewLink, 3, "wlan0", flagsUp|0x100), "a flag change that keeps the link up")
+	expect(linkMsg(rtmNewLink, 2, "eth0", iffUp|0x1000), events.ActionChange, "eth0", "link down")
+
+	ev = expect(addrMsg(rtmNewAddr, 3, net.ParseIP("192.168.1.23"), 24, 0), events.ActionAdd, "wlan0",
+		"address 192.168.1.23/24 added")
+	if ev.Raw["ADDRESS"] != "192.168.1.23/24" || ev.Raw["EVENT"] != "address" {
+		t.Errorf("raw = %v", ev.Raw)
+	}
+	expect(addrMsg(rtmNewAddr, 3, net.ParseIP("2001:db8::23"), 64, 0), events.ActionAdd, "wlan0",
+		"address 2001:db8::23/64 added")
+	expectNone(addrMsg(rtmNewAddr, 3, net.ParseIP("fe80::1"), 64, rtScopeLink), "a link-local address")
+	expectNone(addrMsg(rtmNewAddr, 1, net.ParseIP("127.0.0.1"), 8, rtScopeHost), "a loopback address")
+	expect(addrMsg(rtmDelAddr, 3, net.ParseIP("192.168.1.23"), 24, 0), events.ActionRemove, "wlan0",
+		"address 192.168.1.23/24 removed")
+
+	ev = expect(linkMsg(rtmNewLink, 7, "usb0", flagsDown), events.ActionAdd, "usb0", "interface added")
+	if ev.Raw["EVENT"] != "interface" || ev.Raw["STATE"] != "down" {
+		t.Errorf("raw = %v", ev.Raw)
+	}
+	expect(linkMsg(rtmDelLink, 7, "usb0", flagsDown), events.ActionRemove, "usb0", "interface removed")
+
+	// Truncated input is ignored rather than misread.
+	expectNone(linkMsg(rtmNewLink, 3, "wlan0", flagsDown)[:20], "a truncated message")
+}
@@ -0,0 +1,376 @@
+package sources
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+)
+
+// udevMagic starts the header udevd puts on the events it rebroadcasts.
+var udevMagic = []byte("libudev\x00")
+
+// parseUevent decodes one NETLINK_KOBJECT_UEVENT datagram into its
+// properties. Kernel messages are "action@devpath" followed by
+// NUL-separated KEY=value pairs; messages rebroadcast by udevd carry a
+// "libudev" header pointing at the same kind of property list. It returns
+// nil for anything without an ACTION.
+func parseUevent(msg []byte) map[string]string {
+	if bytes.HasPrefix(msg, udevMagic) {
+		// struct udev_monitor_netlink_header: prefix[8], magic (big
+		// endian), header_size, properties_off, properties_len (host order).
+		if len(msg) < 24 {
+			return nil
+		}
+		off := binary.NativeEndian.Uint32(msg[16:20])
+		n := binary.NativeEndian.Uint32(msg[20:24])
+		if uint64(off)+uint64(n) > uint64(len(msg)) {
+			return nil
+		}
+		msg = msg[off : off+n]
+	} else if i := bytes.IndexByte(msg, 0); i >= 0 && bytes.IndexByte(msg[:i], '@') > 0 {
+		msg = msg[i+1:] // drop the "action@devpath" summary
+	} else {
+		return nil
+	}
+
+	props := make(map[string]string)
+	for field := range bytes.SplitSeq(msg, []byte{0}) {
+		if k, v, ok := strings.Cut(string(field), "="); ok && k != "" {
+			props[k] = v
+		}
+	}
+	if props["ACTION"] == "" {
+		return nil
+	}
+	return props
+}
+
+func ueventAction(props map[string]string) (events.Action, bool) {
+	switch props["ACTION"] {
+	case "add":
+		return events.ActionAdd, true
+	case "remove":
+		return events.ActionRemove, true
+	case "change":
+		return events.ActionChange, true
+	}
+	return "", false
+}
+
+// firstOf returns the first non-empty value of keys in props.
+func firstOf(props map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(props[k]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Block devices
+
+// skippedBlockDevices are virtual disks that come and go with software
+// (snaps, compressed swap) rather than hardware.
+var skippedBlockDevices = []string{"loop", "ram", "zram", "nbd"}
+
+// parseBlockEvent turns a block subsystem uevent into a disk or partition
+// event. Sysfs attributes the monitor read when the device appeared are
+// expected as ATTR_REMOVABLE, ATTR_VENDOR, ATTR_MODEL and ATTR_SIZE.
+func parseBlockEvent(props map[string]string) *events.DeviceEvent {
+	if props["SUBSYSTEM"] != "block" {
+		return nil
+	}
+	action, ok := ueventAction(props)
+	if !ok {
+		return nil
+	}
+	name := firstOf(props, "DEVNAME")
+	if name == "" {
+		name = path.Base(props["DEVPATH"])
+	}
+	name = strings.TrimPrefix(name, "/dev/")
+	for _, prefix := range skippedBlockDevices {
+		if strings.HasPrefix(name, prefix) {
+			return nil
+		}
+	}
+	devType := props["DEVTYPE"]
+	if devType != "disk" && devType != "partition" {
+		return nil
+	}
+	if action == events.ActionChange && props["DISK_MEDIA_CHANGE"] != "1" {
+		return nil // most change events are udev rescans
+	}
+
+	ev := &events.DeviceEvent{
+		Action:   action,
+		Kind:     events.KindBlock,
+		DeviceID: "/dev/" + name,
+		Vendor:   firstOf(props, "ID_VENDOR", "ATTR_VENDOR"),
+		Product:  firstOf(props, "ID_MODEL", "ATTR_MODEL"),
+		Serial:   firstOf(props, "ID_SERIAL_SHORT"),
+		Raw:      props,
+	}
+	if ev.Product == "" {
+		ev.Product = name
+	}
+	ev.Capabilities = "Disk"
+	if devType == "partition" {
+		ev.Capabilities = "Partition"
+	}
+	if props["ATTR_REMOVABLE"] == "1" || props["ID_BUS"] == "usb" {
+		ev.Capabilities = "Removable " + strings.ToLower(ev.Capabilities)
+	}
+	if sectors, err := strconv.ParseUint(props["ATTR_SIZE"], 10, 64); err == nil && sectors > 0 {
+		ev.Capabilities += ", " + formatBytes(sectors*512)
+	}
+	if fs := props["ID_FS_TYPE"]; fs != "" {
+		ev.Capabilities += ", " + fs
+		if label := props["ID_FS_LABEL"]; label != "" {
+			ev.Capabilities += " \"" + label + "\""
+		}
+	}
+	if action == events.ActionChange {
+		ev.Status = "media changed"
+	}
+	return ev
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// mount is one line of /proc/self/mounts.
+type mount struct {
+	device, fsType string
+}
+
+// parseMounts reads /proc/self/mounts content into mount point -> mount,
+// keeping only filesystems backed by a device node.
+func parseMounts(data []byte) map[string]mount {
+	mounts := make(map[string]mount)
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		mounts[unescapeMount(fields[1])] = mount{device: unescapeMount(fields[0]), fsType: fields[2]}
+	}
+	return mounts
+}
+
+// unescapeMount decodes the octal escapes (\040 for space) the kernel
+// uses in mount table fields.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// diffMounts reports device mounts that appeared or went away between two
+// snapshots of the mount table, ordered by mount point.
+func diffMounts(before, after map[string]mount) []*events.DeviceEvent {
+	var evs []*events.DeviceEvent
+	for point, m := range after {
+		if old, ok := before[point]; !ok || old != m {
+			evs = append(evs, mountEvent(events.ActionAdd, point, m))
+		}
+	}
+	for point, m := range before {
+		if cur, ok := after[point]; !ok || cur != m {
+			evs = append(evs, mountEvent(events.ActionRemove, point, m))
+		}
+	}
+	sort.SliceStable(evs, func(i, j int) bool {
+		pi, pj := evs[i].Raw["MOUNTPOINT"], evs[j].Raw["MOUNTPOINT"]
+		if pi != pj {
+			return pi < pj
+		}
+		return evs[i].Action == events.ActionRemove // unmount before remount
+	})
+	return evs
+}
+
+func mountEvent(action events.Action, point string, m mount) *events.DeviceEvent {
+	status := "mounted at " + point
+	if action == events.ActionRemove {
+		status = "unmounted from " + point
+	}
+	return &events.DeviceEvent{
+		Action:       action,
+		Kind:         events.KindBlock,
+		DeviceID:     m.device,
+		Product:      path.Base(m.device),
+		Capabilities: "Filesystem (" + m.fsType + ")",
+		Status:       status,
+		Raw: map[string]string{
+			"EVENT":      "mount",
+			"DEVNAME":    m.device,
+			"MOUNTPOINT": point,
+			"FSTYPE":     m.fsType,
+		},
+	}
+}
+
+// Power supplies
+
+// powerTracker turns power_supply uevents into events for AC adapters
+// going on or offline and batteries dropping through capacity thresholds.
+// The kernel sends change events for every property update, so it
+// remembers the last reported state of each supply.
+type powerTracker struct {
+	thresholds []int
+	online     map[string]string
+	capacity   map[string]int
+}
+
+func newPowerTracker(thresholds []int) *powerTracker {
+	t := &powerTracker{
+		online:   make(map[string]string),
+		capacity: make(map[string]int),
+	}
+	for _, th := range thresholds {
+		if th > 0 && th < 100 {
+			t.thresholds = append(t.thresholds, th)
+		}
+	}
+	return t
+}
+
+func (t *powerTracker) handle(props map[string]string) *events.DeviceEvent {
+	if props["SUBSYSTEM"] != "power_supply" {
+		return nil
+	}
+	action, ok := ueventAction(props)
+	if !ok {
+		return nil
+	}
+	name := firstOf(props, "POWER_SUPPLY_NAME")
+	if name == "" {
+		name = path.Base(props["DEVPATH"])
+	}
+	supplyType := props["POWER_SUPPLY_TYPE"]
+	ev := &events.DeviceEvent{
+		Action:   action,
+		Kind:     events.KindPower,
+		DeviceID: name,
+		Vendor:   firstOf(props, "POWER_SUPPLY_MANUFACTURER"),
+		Product:  firstOf(props, "POWER_SUPPLY_MODEL_NAME"),
+		Serial:   firstOf(props, "POWER_SUPPLY_SERIAL_NUMBER"),
+		Raw:      props,
+	}
+	if ev.Product == "" {
+		ev.Product = name
+	}
+
+	if action == events.ActionRemove {
+		delete(t.online, name)
+		delete(t.capacity, name)
+		ev.Capabilities = supplyType
+		return ev
+	}
+
+	if supplyType == "Battery" {
+		ev.Capabilities = "Battery"
+		capacity, err := strconv.Atoi(props["POWER_SUPPLY_CAPACITY"])
+		if err != nil {
+			return nil
+		}
+		prev, seen := t.capacity[name]
+		t.capacity[name] = capacity
+		if action == events.ActionAdd {
+			ev.Status = fmt.Sprintf("battery at %d%%", capacity)
+			return ev
+		}
+		if !seen || props["POWER_SUPPLY_STATUS"] == "Charging" {
+			return nil
+		}
+		// A big drop between reports may cross several thresholds; report
+		// the lowest.
+		crossed := 0
+		for _, th := range t.thresholds {
+			if prev > th && capacity <= th && (crossed == 0 || th < crossed) {
+				crossed = th
+			}
+		}
+		if crossed == 0 {
+			return nil
+		}
+		props["EVENT"] = "capacity"
+		props["THRESHOLD"] = strconv.Itoa(crossed)
+		ev.Status = fmt.Sprintf("battery at %d%% (below %d%%)", capacity, crossed)
+		return ev
+	}
+
+	// Mains, USB and other external supplies.
+	online, ok := props["POWER_SUPPLY_ONLINE"]
+	if !ok {
+		return nil
+	}
+	prev, seen := t.online[name]
+	t.online[name] = online
+	ev.Capabilities = "External power"
+	if supplyType != "" {
+		ev.Capabilities += " (" + supplyType + ")"
+	}
+	if action == events.ActionChange && (!seen || prev == online) {
+		return nil
+	}
+	props["EVENT"] = "online"
+	ev.Status = "power connected"
+	if online == "0" {
+		ev.Status = "power disconnected"
+	}
+	return ev
+}
+
+// Bluetooth
+
+// parseBluetoothEvent reports Bluetooth adapters (hciN) appearing and
+// going away. Connection links (DEVTYPE=link) are ignored.
+func parseBluetoothEvent(props map[string]string) *events.DeviceEvent {
+	if props["SUBSYSTEM"] != "bluetooth" || props["DEVTYPE"] != "host" {
+		return nil
+	}
+	action, ok := ueventAction(props)
+	if !ok || action == events.ActionChange {
+		return nil
+	}
+	name := path.Base(props["DEVPATH"])
+	return &events.DeviceEvent{
+		Action:       action,
+		Kind:         events.KindBluetooth,
+		DeviceID:     name,
+		Vendor:       firstOf(props, "ID_VENDOR_FROM_DATABASE", "ID_VENDOR"),
+		Product:      name,
+		Capabilities: "Bluetooth adapter",
+		Raw:          props,
+	}
+}
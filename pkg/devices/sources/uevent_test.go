@@ -0,0 +1,213 @@
+package sources
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/devices/events"
+)
+
+// Payloads recorded from NETLINK_KOBJECT_UEVENT sockets, with the NUL
+// separators written as \x00.
+const (
+	usbDiskPath   = "/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdb"
+	ueventDiskAdd = "add@" + usbDiskPath + "\x00" +
+		"ACTION=add\x00" +
+		"DEVPATH=" + usbDiskPath + "\x00" +
+		"SUBSYSTEM=block\x00MAJOR=8\x00MINOR=16\x00DEVNAME=sdb\x00DEVTYPE=disk\x00DISKSEQ=12\x00SEQNUM=5123\x00"
+	ueventPartitionRemove = "remove@" + usbDiskPath + "/sdb1\x00" +
+		"ACTION=remove\x00" +
+		"DEVPATH=" + usbDiskPath + "/sdb1\x00" +
+		"SUBSYSTEM=block\x00MAJOR=8\x00MINOR=17\x00DEVNAME=sdb1\x00DEVTYPE=partition\x00DISKSEQ=12\x00PARTN=1\x00" +
+		"SEQNUM=5140\x00"
+	cardReaderPath    = "/devices/pci0000:00/0000:00:14.0/usb2/2-3/2-3:1.0/host7/target7:0:0/7:0:0:0/block/sdc"
+	ueventMediaChange = "change@" + cardReaderPath + "\x00" +
+		"ACTION=change\x00" +
+		"DEVPATH=" + cardReaderPath + "\x00" +
+		"SUBSYSTEM=block\x00DISK_MEDIA_CHANGE=1\x00MAJOR=8\x00MINOR=32\x00DEVNAME=sdc\x00DEVTYPE=disk\x00" +
+		"DISKSEQ=14\x00SEQNUM=5201\x00"
+	ueventLoopAdd = "add@/devices/virtual/block/loop7\x00ACTION=add\x00DEVPATH=/devices/virtual/block/loop7\x00" +
+		"SUBSYSTEM=block\x00MAJOR=7\x00MINOR=7\x00DEVNAME=loop7\x00DEVTYPE=disk\x00DISKSEQ=9\x00SEQNUM=4870\x00"
+	ueventACOffline = "change@/devices/LNXSYSTM:00/LNXSYBUS:00/ACPI0003:00/power_supply/AC\x00" +
+		"ACTION=change\x00DEVPATH=/devices/LNXSYSTM:00/LNXSYBUS:00/ACPI0003:00/power_supply/AC\x00" +
+		"SUBSYSTEM=power_supply\x00POWER_SUPPLY_NAME=AC\x00POWER_SUPPLY_TYPE=Mains\x00POWER_SUPPLY_ONLINE=0\x00" +
+		"SEQNUM=6012\x00"
+	ueventBatteryFmt = "change@/devices/LNXSYSTM:00/LNXSYBUS:00/PNP0C0A:00/power_supply/BAT0\x00" +
+		"ACTION=change\x00DEVPATH=/devices/LNXSYSTM:00/LNXSYBUS:00/PNP0C0A:00/power_supply/BAT0\x00" +
+		"SUBSYSTEM=power_supply\x00POWER_SUPPLY_NAME=BAT0\x00POWER_SUPPLY_TYPE=Battery\x00" +
+		"POWER_SUPPLY_STATUS=%s\x00POWER_SUPPLY_PRESENT=1\x00POWER_SUPPLY_TECHNOLOGY=Li-ion\x00" +
+		"POWER_SUPPLY_CAPACITY=%s\x00POWER_SUPPLY_MODEL_NAME=5B10W13975\x00POWER_SUPPLY_MANUFACTURER=SMP\x00" +
+		"POWER_SUPPLY_SERIAL_NUMBER=1234\x00SEQNUM=6100\x00"
+	ueventBluetoothAdd = "add@/devices/pci0000:00/0000:00:14.0/usb1/1-10/1-10:1.0/bluetooth/hci0\x00" +
+		"ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:14.0/usb1/1-10/1-10:1.0/bluetooth/hci0\x00" +
+		"SUBSYSTEM=bluetooth\x00DEVTYPE=host\x00SEQNUM=3311\x00"
+	ueventBluetoothLink = "add@/devices/pci0000:00/0000:00:14.0/usb1/1-10/1-10:1.0/bluetooth/hci0/hci0:3585\x00" +
+		"ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:14.0/usb1/1-10/1-10:1.0/bluetooth/hci0/hci0:3585\x00" +
+		"SUBSYSTEM=bluetooth\x00DEVTYPE=link\x00SEQNUM=3402\x00"
+)
+
+func battery(status, capacity string) []byte {
+	s := strings.Replace(ueventBatteryFmt, "%s", status, 1)
+	return []byte(strings.Replace(s, "%s", capacity, 1))
+}
+
+// udevMessage wraps properties in the header udevd adds when it
+// rebroadcasts an event.
+func udevMessage(props string) []byte {
+	const headerSize = 40
+	b := make([]byte, headerSize, headerSize+len(props))
+	copy(b, udevMagic)
+	binary.BigEndian.PutUint32(b[8:12], 0xfeedcafe)
+	binary.NativeEndian.PutUint32(b[12:16], headerSize)
+	binary.NativeEndian.PutUint32(b[16:20], headerSize)
+	binary.NativeEndian.PutUint32(b[20:24], uint32(len(props)))
+	return append(b, props...)
+}
+
+func TestParseUevent(t *testing.T) {
+	props := parseUevent([]byte(ueventDiskAdd))
+	if props["ACTION"] != "add" || props["SUBSYSTEM"] != "block" || props["DEVNAME"] != "sdb" {
+		t.Fatalf("kernel message props = %v", props)
+	}
+
+	props = parseUevent(udevMessage("ACTION=add\x00SUBSYSTEM=block\x00DEVNAME=/dev/sdb\x00ID_VENDOR=SanDisk\x00"))
+	if props["ACTION"] != "add" || props["ID_VENDOR"] != "SanDisk" {
+		t.Fatalf("udev message props = %v", props)
+	}
+
+	for _, bad := range []string{"", "libudev\x00short", "no summary line", "add@/x\x00SUBSYSTEM=block\x00"} {
+		if props := parseUevent([]byte(bad)); props != nil {
+			t.Errorf("parseUevent(%q) = %v, want nil", bad, props)
+		}
+	}
+}
+
+func TestParseBlockEvent(t *testing.T) {
+	props := parseUevent([]byte(ueventDiskAdd))
+	props["ATTR_REMOVABLE"] = "1"
+	props["ATTR_VENDOR"] = "SanDisk"
+	props["ATTR_MODEL"] = "Cruzer Blade"
+	props["ATTR_SIZE"] = "30031872"
+	ev := parseBlockEvent(props)
+	if ev == nil {
+		t.Fatal("disk add was not reported")
+	}
+	if ev.Action != events.ActionAdd || ev.Kind != events.KindBlock || ev.DeviceID != "/dev/sdb" {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.Vendor != "SanDisk" || ev.Product != "Cruzer Blade" {
+		t.Errorf("vendor/product = %q/%q", ev.Vendor, ev.Product)
+	}
+	if ev.Capabilities != "Removable disk, 15.4 GB" {
+		t.Errorf("capabilities = %q", ev.Capabilities)
+	}
+
+	ev = parseBlockEvent(parseUevent([]byte(ueventPartitionRemove)))
+	if ev == nil || ev.Action != events.ActionRemove || ev.DeviceID != "/dev/sdb1" || ev.Capabilities != "Partition" {
+		t.Errorf("partition remove = %+v", ev)
+	}
+
+	ev = parseBlockEvent(parseUevent([]byte(ueventMediaChange)))
+	if ev == nil || ev.Action != events.ActionChange || ev.Status != "media changed" {
+		t.Errorf("media change = %+v", ev)
+	}
+
+	if ev := parseBlockEvent(parseUevent([]byte(ueventLoopAdd))); ev != nil {
+		t.Errorf("loop device reported: %+v", ev)
+	}
+	rescan := strings.Replace(ueventMediaChange, "DISK_MEDIA_CHANGE=1\x00", "", 1)
+	if ev := parseBlockEvent(parseUevent([]byte(rescan))); ev != nil {
+		t.Errorf("plain change reported: %+v", ev)
+	}
+	if ev := parseBlockEvent(parseUevent([]byte(ueventACOffline))); ev != nil {
+		t.Errorf("power_supply event reported as block: %+v", ev)
+	}
+}
+
+func TestMountDiff(t *testing.T) {
+	before := parseMounts([]byte(`/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+tmpfs /run tmpfs rw,nosuid,nodev,size=1623340k,mode=755 0 0
+/dev/nvme0n1p1 /boot/efi vfat rw,relatime,fmask=0077,dmask=0077 0 0
+`))
+	if len(before) != 2 {
+		t.Fatalf("parseMounts kept %d mounts, want the 2 device mounts: %v", len(before), before)
+	}
+	after := parseMounts([]byte(`/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+/dev/sdb1 /media/me/USB\040STICK vfat rw,nosuid,nodev,relatime 0 0
+`))
+
+	evs := diffMounts(before, after)
+	if len(evs) != 2 {
+		t.Fatalf("diffMounts returned %d events, want 2", len(evs))
+	}
+	if evs[0].Action != events.ActionRemove || evs[0].Raw["MOUNTPOINT"] != "/boot/efi" {
+		t.Errorf("first event = %+v, want /boot/efi unmounted", evs[0])
+	}
+	add := evs[1]
+	if add.Action != events.ActionAdd || add.DeviceID != "/dev/sdb1" || add.Raw["MOUNTPOINT"] != "/media/me/USB STICK" ||
+		add.Raw["FSTYPE"] != "vfat" || add.Raw["EVENT"] != "mount" || add.Status != "mounted at /media/me/USB STICK" {
+		t.Errorf("mount event = %+v", add)
+	}
+	if evs := diffMounts(after, after); len(evs) != 0 {
+		t.Errorf("unchanged table produced %d events", len(evs))
+	}
+}
+
+func TestPowerTracker(t *testing.T) {
+	tracker := newPowerTracker([]int{10, 20, 150})
+
+	// The first report of a supply only records its state.
+	if ev := tracker.handle(parseUevent([]byte(ueventACOffline))); ev != nil {
+		t.Errorf("first AC report produced %+v", ev)
+	}
+	online := strings.Replace(ueventACOffline, "POWER_SUPPLY_ONLINE=0", "POWER_SUPPLY_ONLINE=1", 1)
+	ev := tracker.handle(parseUevent([]byte(online)))
+	if ev == nil || ev.Action != events.ActionChange || ev.Status != "power connected" || ev.Raw["EVENT"] != "online" {
+		t.Errorf("AC online = %+v", ev)
+	}
+	if ev := tracker.handle(parseUevent([]byte(online))); ev != nil {
+		t.Errorf("repeated AC state produced %+v", ev)
+	}
+	ev = tracker.handle(parseUevent([]byte(ueventACOffline)))
+	if ev == nil || ev.Status != "power disconnected" {
+		t.Errorf("AC offline = %+v", ev)
+	}
+
+	steps := []struct {
+		status, capacity string
+		want             string
+	}{
+		{"Discharging", "25", ""}, // first report
+		{"Discharging", "21", ""},
+		{"Discharging", "19", "battery at 19% (below 20%)"},
+		{"Discharging", "15", ""},
+		{"Charging", "9", ""}, // charging never alerts
+		{"Discharging", "30", ""},
+		{"Discharging", "8", "battery at 8% (below 10%)"}, // jumps report the lowest crossing
+	}
+	for _, s := range steps {
+		ev := tracker.handle(parseUevent(battery(s.status, s.capacity)))
+		got := ""
+		if ev != nil {
+			got = ev.Status
+			if ev.Kind != events.KindPower || ev.Vendor != "SMP" || ev.Product != "5B10W13975" {
+				t.Errorf("battery event = %+v", ev)
+			}
+		}
+		if got != s.want {
+			t.Errorf("%s at %s%%: status %q, want %q", s.status, s.capacity, got, s.want)
+		}
+	}
+}
+
+func TestParseBluetoothEvent(t *testing.T) {
+	ev := parseBluetoothEvent(parseUevent([]byte(ueventBluetoothAdd)))
+	if ev == nil || ev.Action != events.ActionAdd || ev.Kind != events.KindBluetooth || ev.DeviceID != "hci0" {
+		t.Fatalf("adapter add = %+v", ev)
+	}
+	if ev := parseBluetoothEvent(parseUevent([]byte(ueventBluetoothLink))); ev != nil {
+		t.Errorf("connection link reported: %+v", ev)
+	}
+}
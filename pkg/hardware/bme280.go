@@ -0,0 +1,146 @@
+package hardware
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// BME280 registers.
+const (
+	bme280RegCalib1   = 0x88 // 26 bytes: dig_T1..dig_P9, dig_H1 at 0xA1
+	bme280RegID       = 0xD0
+	bme280RegCalib2   = 0xE1 // 7 bytes: dig_H2..dig_H6
+	bme280RegCtrlHum  = 0xF2
+	bme280RegStatus   = 0xF3
+	bme280RegCtrlMeas = 0xF4
+	bme280RegData     = 0xF7 // 8 bytes: press, temp, hum
+
+	bme280ChipID = 0x60
+)
+
+func init() {
+	register(&Profile{
+		Name:        "bme280",
+		Description: "Bosch BME280 temperature, pressure and humidity sensor",
+		Addresses:   []uint16{0x76, 0x77},
+		SPI:         true,
+		ID:          &RegisterValue{Register: bme280RegID, Value: bme280ChipID},
+		Read:        readBME280,
+	})
+}
+
+// bme280Calib holds the factory trimming parameters.
+type bme280Calib struct {
+	T1             uint16
+	T2, T3         int16
+	P1             uint16
+	P2, P3, P4, P5 int16
+	P6, P7, P8, P9 int16
+	H1, H3         uint8
+	H2, H4, H5     int16
+	H6             int8
+}
+
+func parseBME280Calib(c1, c2 []byte) bme280Calib {
+	u16 := func(b []byte, i int) uint16 { return binary.LittleEndian.Uint16(b[i:]) }
+	s16 := func(b []byte, i int) int16 { return int16(u16(b, i)) }
+	return bme280Calib{
+		T1: u16(c1, 0), T2: s16(c1, 2), T3: s16(c1, 4),
+		P1: u16(c1, 6), P2: s16(c1, 8), P3: s16(c1, 10), P4: s16(c1, 12), P5: s16(c1, 14),
+		P6: s16(c1, 16), P7: s16(c1, 18), P8: s16(c1, 20), P9: s16(c1, 22),
+		H1: c1[25],
+		H2: s16(c2, 0),
+		H3: c2[2],
+		// H4 and H5 are 12-bit values sharing the nibbles of 0xE5.
+		H4: int16(int8(c2[3]))<<4 | int16(c2[4]&0x0f),
+		H5: int16(int8(c2[5]))<<4 | int16(c2[4]>>4),
+		H6: int8(c2[6]),
+	}
+}
+
+func readBME280(d Device, _ map[string]float64) ([]Measurement, error) {
+	var c1 [26]byte
+	var c2 [7]byte
+	if err := d.ReadReg(bme280RegCalib1, c1[:]); err != nil {
+		return nil, fmt.Errorf("bme280: read calibration: %w", err)
+	}
+	if err := d.ReadReg(bme280RegCalib2, c2[:]); err != nil {
+		return nil, fmt.Errorf("bme280: read calibration: %w", err)
+	}
+	cal := parseBME280Calib(c1[:], c2[:])
+
+	// One forced measurement with 1x oversampling on every channel. ctrl_hum
+	// only takes effect after the following ctrl_meas write.
+	if err := d.WriteReg(bme280RegCtrlHum, 0x01); err != nil {
+		return nil, fmt.Errorf("bme280: configure: %w", err)
+	}
+	if err := d.WriteReg(bme280RegCtrlMeas, 0x01<<5|0x01<<2|0x01); err != nil {
+		return nil, fmt.Errorf("bme280: start measurement: %w", err)
+	}
+	// A 1x measurement takes under 10 ms; status bit 3 is set while busy.
+	var status [1]byte
+	for range 20 {
+		if err := d.ReadReg(bme280RegStatus, status[:]); err != nil {
+			return nil, fmt.Errorf("bme280: read status: %w", err)
+		}
+		if status[0]&0x08 == 0 {
+			break
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	var data [8]byte
+	if err := d.ReadReg(bme280RegData, data[:]); err != nil {
+		return nil, fmt.Errorf("bme280: read data: %w", err)
+	}
+	adcP := int32(data[0])<<12 | int32(data[1])<<4 | int32(data[2])>>4
+	adcT := int32(data[3])<<12 | int32(data[4])<<4 | int32(data[5])>>4
+	adcH := int32(data[6])<<8 | int32(data[7])
+
+	temp, fine := cal.temperature(adcT)
+	return []Measurement{
+		{Name: "temperature", Value: temp, Unit: "°C"},
+		{Name: "pressure", Value: cal.pressure(adcP, fine) / 100, Unit: "hPa"},
+		{Name: "humidity", Value: cal.humidity(adcH, fine), Unit: "%"},
+	}, nil
+}
+
+// The compensation formulas below are the floating point versions from
+// section 8.1 of the BME280 datasheet.
+
+// temperature returns °C and the fine temperature the other channels use.
+func (c *bme280Calib) temperature(adc int32) (float64, float64) {
+	v1 := (float64(adc)/16384 - float64(c.T1)/1024) * float64(c.T2)
+	v2 := float64(adc)/131072 - float64(c.T1)/8192
+	v2 = v2 * v2 * float64(c.T3)
+	fine := v1 + v2
+	return fine / 5120, fine
+}
+
+// pressure returns Pa.
+func (c *bme280Calib) pressure(adc int32, fine float64) float64 {
+	v1 := fine/2 - 64000
+	v2 := v1 * v1 * float64(c.P6) / 32768
+	v2 += v1 * float64(c.P5) * 2
+	v2 = v2/4 + float64(c.P4)*65536
+	v1 = (float64(c.P3)*v1*v1/524288 + float64(c.P2)*v1) / 524288
+	v1 = (1 + v1/32768) * float64(c.P1)
+	if v1 == 0 {
+		return 0 // avoid division by zero on an unprogrammed chip
+	}
+	p := 1048576 - float64(adc)
+	p = (p - v2/4096) * 6250 / v1
+	v1 = float64(c.P9) * p * p / 2147483648
+	v2 = p * float64(c.P8) / 32768
+	return p + (v1+v2+float64(c.P7))/16
+}
+
+// humidity returns %RH.
+func (c *bme280Calib) humidity(adc int32, fine float64) float64 {
+	h := fine - 76800
+	h = (float64(adc) - (float64(c.H4)*64 + float64(c.H5)/16384*h)) *
+		(float64(c.H2) / 65536 * (1 + float64(c.H6)/67108864*h*(1+float64(c.H3)/67108864*h)))
+	h *= 1 - float64(c.H1)*h/524288
+	return min(max(h, 0), 100)
+}
@@ -0,0 +1,133 @@
+// Package hardware talks to peripherals on I2C and SPI buses and to GPIO
+// and PWM pins. Buses and chips are interfaces with a Linux implementation
+// on the kernel's i2c-dev, spidev and GPIO character device drivers and an
+// in-memory simulation, and driver profiles turn register maps into sensor
+// readings and outputs.
+package hardware
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned when opening a bus or chip on a platform
+// without the Linux device interfaces.
+var ErrUnsupported = errors.New("I2C, SPI and GPIO are only supported on Linux")
+
+// I2C is an I2C bus adapter.
+type I2C interface {
+	// Tx addresses the 7-bit device addr, writes w and then reads len(r)
+	// bytes into r. Either may be empty.
+	Tx(addr uint16, w, r []byte) error
+	// Scan probes the usable 7-bit addresses (0x08-0x77) and reports the
+	// devices that respond, in address order.
+	Scan() ([]ScanEntry, error)
+	Close() error
+}
+
+// ScanEntry is a device found by I2C.Scan.
+type ScanEntry struct {
+	Addr uint16
+	// Busy means a kernel driver owns the address, so it can't be probed
+	// or used from user space.
+	Busy bool
+}
+
+// SPI is an SPI device: one chip select on a controller.
+type SPI interface {
+	// Tx clocks out w while reading the same number of bytes into r
+	// (full duplex). r may be nil, or must be as long as w.
+	Tx(w, r []byte) error
+	Close() error
+}
+
+// SPIConfig is the clocking an SPI device is opened with.
+type SPIConfig struct {
+	Speed uint32 // Hz
+	Mode  uint8  // 0-3: CPOL<<1 | CPHA
+	Bits  uint8  // bits per word
+}
+
+// DefaultSPIConfig is 1 MHz, mode 0, 8-bit words.
+var DefaultSPIConfig = SPIConfig{Speed: 1000000, Mode: 0, Bits: 8}
+
+// Device is a register-addressed peripheral, hiding whether it sits on an
+// I2C or an SPI bus.
+type Device interface {
+	// ReadReg reads len(buf) bytes starting at register reg.
+	ReadReg(reg uint8, buf []byte) error
+	// WriteReg writes data starting at register reg. On I2C the register
+	// is simply the first byte written, which also suits chips such as
+	// SSD1306 that use it as a control byte.
+	WriteReg(reg uint8, data ...byte) error
+	// Tx is a raw transfer for command-based chips. On SPI, w and r are
+	// clocked together; on I2C, w is written and then r is read.
+	Tx(w, r []byte) error
+}
+
+// NewI2CDevice returns the device at addr on bus.
+func NewI2CDevice(bus I2C, addr uint16) Device {
+	return &i2cDevice{bus: bus, addr: addr}
+}
+
+type i2cDevice struct {
+	bus  I2C
+	addr uint16
+}
+
+func (d *i2cDevice) ReadReg(reg uint8, buf []byte) error {
+	return d.bus.Tx(d.addr, []byte{reg}, buf)
+}
+
+func (d *i2cDevice) WriteReg(reg uint8, data ...byte) error {
+	return d.bus.Tx(d.addr, append([]byte{reg}, data...), nil)
+}
+
+func (d *i2cDevice) Tx(w, r []byte) error {
+	return d.bus.Tx(d.addr, w, r)
+}
+
+// NewSPIDevice returns a Device using the common SPI register convention
+// (BME280 and most Bosch and ST sensors): the first byte is the register
+// with bit 7 set for reads and clear for writes.
+func NewSPIDevice(spi SPI) Device {
+	return &spiDevice{spi: spi}
+}
+
+type spiDevice struct {
+	spi SPI
+}
+
+func (d *spiDevice) ReadReg(reg uint8, buf []byte) error {
+	w := make([]byte, len(buf)+1)
+	w[0] = reg | 0x80
+	r := make([]byte, len(w))
+	if err := d.spi.Tx(w, r); err != nil {
+		return err
+	}
+	copy(buf, r[1:])
+	return nil
+}
+
+func (d *spiDevice) WriteReg(reg uint8, data ...byte) error {
+	// Multi-byte SPI writes repeat the register before every byte.
+	w := make([]byte, 0, 2*len(data))
+	for i, b := range data {
+		w = append(w, (reg+uint8(i))&0x7f, b)
+	}
+	if len(data) == 0 {
+		w = append(w, reg&0x7f)
+	}
+	return d.spi.Tx(w, nil)
+}
+
+func (d *spiDevice) Tx(w, r []byte) error {
+	if r != nil && len(r) != len(w) {
+		if len(w) == 0 {
+			w = make([]byte, len(r))
+		} else {
+			return fmt.Errorf("spi: read length %d differs from write length %d", len(r), len(w))
+		}
+	}
+	return d.spi.Tx(w, r)
+}
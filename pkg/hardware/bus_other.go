@@ -0,0 +1,18 @@
+//go:build !linux
+
+package hardware
+
+// OpenI2C is a stub for non-Linux platforms.
+func OpenI2C(bus string) (I2C, error) {
+	return nil, ErrUnsupported
+}
+
+// OpenSPI is a stub for non-Linux platforms.
+func OpenSPI(dev string, cfg SPIConfig) (SPI, error) {
+	return nil, ErrUnsupported
+}
+
+// OpenGPIOChip is a stub for non-Linux platforms.
+func OpenGPIOChip(id string) (GPIOChip, error) {
+	return nil, ErrUnsupported
+}
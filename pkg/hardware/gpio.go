@@ -0,0 +1,113 @@
+package hardware
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrTimeout is returned by GPIOLine.WaitEdge when no edge arrives in time.
+var ErrTimeout = errors.New("timed out")
+
+// GPIOChip is a GPIO controller, one /dev/gpiochipN.
+type GPIOChip interface {
+	Info() (GPIOChipInfo, error)
+	LineInfo(offset int) (GPIOLineInfo, error)
+	// Request claims a line with the given configuration. The line keeps
+	// its configuration, and an output its value, until it is closed.
+	Request(offset int, cfg GPIOLineConfig) (GPIOLine, error)
+	Close() error
+}
+
+// GPIOChipInfo describes a chip.
+type GPIOChipInfo struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Lines int    `json:"lines"`
+}
+
+// GPIOLineInfo describes one line of a chip.
+type GPIOLineInfo struct {
+	Offset    int    `json:"offset"`
+	Name      string `json:"name,omitempty"`
+	Consumer  string `json:"consumer,omitempty"`
+	Used      bool   `json:"used"`
+	Output    bool   `json:"output"`
+	ActiveLow bool   `json:"active_low,omitempty"`
+}
+
+// Bias settings for GPIOLineConfig.
+const (
+	BiasPullUp   = "pull-up"
+	BiasPullDown = "pull-down"
+	BiasDisabled = "disabled"
+)
+
+// Edge settings for GPIOLineConfig, and the kinds of GPIOEdge.
+const (
+	EdgeRising  = "rising"
+	EdgeFalling = "falling"
+	EdgeBoth    = "both"
+)
+
+// GPIOLineConfig is how a line is requested.
+type GPIOLineConfig struct {
+	Output    bool
+	Value     bool // initial value of an output
+	ActiveLow bool
+	Bias      string // "" leaves the bias as it is
+	// Edge enables edge events on an input; Debounce filters them.
+	Edge     string
+	Debounce time.Duration
+}
+
+// GPIOLine is a requested line.
+type GPIOLine interface {
+	Value() (bool, error)
+	SetValue(v bool) error
+	// WaitEdge returns the next edge event, or ErrTimeout after timeout.
+	// The line must have been requested with an Edge.
+	WaitEdge(timeout time.Duration) (GPIOEdge, error)
+	Close() error
+}
+
+// GPIOEdge is an edge event.
+type GPIOEdge struct {
+	Edge string    // EdgeRising or EdgeFalling
+	Time time.Time // when the kernel saw it
+}
+
+var gpioChipRe = regexp.MustCompile(`^(?:gpiochip)?(\d+)$`)
+
+// GPIOChipPath maps a chip identifier ("0" or "gpiochip0") to its device
+// path, rejecting anything else.
+func GPIOChipPath(id string) (string, bool) {
+	m := gpioChipRe.FindStringSubmatch(id)
+	if m == nil {
+		return "", false
+	}
+	return "/dev/gpiochip" + m[1], true
+}
+
+// GPIOChips lists the chip identifiers present, in numeric order.
+func GPIOChips() ([]string, error) {
+	matches, err := filepath.Glob("/dev/gpiochip*")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range matches {
+		if sub := gpioChipRe.FindStringSubmatch(filepath.Base(m)); sub != nil {
+			ids = append(ids, sub[1])
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.Atoi(ids[i])
+		b, _ := strconv.Atoi(ids[j])
+		return a < b
+	})
+	return ids, nil
+}
@@ -0,0 +1,264 @@
+package hardware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// GPIO character device ioctls and structures from <linux/gpio.h> (uAPI v2,
+// Linux 5.10+). Structures are encoded by hand into byte buffers at the
+// kernel's offsets rather than mirrored as Go structs.
+//
+//	_IOR(0xB4, nr, size) = 2<<30 | size<<16 | 0xB4<<8 | nr, _IOWR uses 3<<30
+const (
+	gpioGetChipInfo     = 0x8044B401 // _IOR(0xB4, 0x01, struct gpiochip_info) — 68 bytes
+	gpioV2GetLineInfo   = 0xC100B405 // _IOWR(0xB4, 0x05, struct gpio_v2_line_info) — 256 bytes
+	gpioV2GetLine       = 0xC250B407 // _IOWR(0xB4, 0x07, struct gpio_v2_line_request) — 592 bytes
+	gpioV2LineGetValues = 0xC010B40E // _IOWR(0xB4, 0x0E, struct gpio_v2_line_values)
+	gpioV2LineSetValues = 0xC010B40F // _IOWR(0xB4, 0x0F, struct gpio_v2_line_values)
+
+	gpioChipInfoSize = 68
+	gpioLineInfoSize = 256
+	gpioRequestSize  = 592
+	gpioEventSize    = 48
+
+	// gpio_v2_line_flag
+	gpioFlagUsed          = 1 << 0
+	gpioFlagActiveLow     = 1 << 1
+	gpioFlagInput         = 1 << 2
+	gpioFlagOutput        = 1 << 3
+	gpioFlagEdgeRising    = 1 << 4
+	gpioFlagEdgeFalling   = 1 << 5
+	gpioFlagBiasPullUp    = 1 << 8
+	gpioFlagBiasPullDown  = 1 << 9
+	gpioFlagBiasDisabled  = 1 << 10
+	gpioFlagClockRealtime = 1 << 11
+
+	// gpio_v2_line_attr_id
+	gpioAttrOutputValues = 2
+	gpioAttrDebounce     = 3
+
+	// gpio_v2_line_event_id
+	gpioEventRisingEdge = 1
+
+	gpioConsumer = "tinyclaw"
+)
+
+type linuxGPIOChip struct {
+	fd   int
+	path string
+}
+
+// OpenGPIOChip opens /dev/gpiochip<id>; id is "0" or "gpiochip0".
+func OpenGPIOChip(id string) (GPIOChip, error) {
+	path, ok := GPIOChipPath(id)
+	if !ok {
+		return nil, fmt.Errorf("invalid GPIO chip %q", id)
+	}
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w (check permissions and that the board exposes GPIO)", path, err)
+	}
+	return &linuxGPIOChip{fd: fd, path: path}, nil
+}
+
+func (c *linuxGPIOChip) Close() error {
+	return syscall.Close(c.fd)
+}
+
+func ioctlBuf(fd int, req uintptr, buf []byte) syscall.Errno {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(&buf[0])))
+	return errno
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func (c *linuxGPIOChip) Info() (GPIOChipInfo, error) {
+	buf := make([]byte, gpioChipInfoSize)
+	if errno := ioctlBuf(c.fd, gpioGetChipInfo, buf); errno != 0 {
+		return GPIOChipInfo{}, fmt.Errorf("failed to query %s: %w", c.path, errno)
+	}
+	return GPIOChipInfo{
+		Name:  cString(buf[0:32]),
+		Label: cString(buf[32:64]),
+		Lines: int(binary.NativeEndian.Uint32(buf[64:68])),
+	}, nil
+}
+
+func (c *linuxGPIOChip) LineInfo(offset int) (GPIOLineInfo, error) {
+	buf := make([]byte, gpioLineInfoSize)
+	binary.NativeEndian.PutUint32(buf[64:68], uint32(offset))
+	if errno := ioctlBuf(c.fd, gpioV2GetLineInfo, buf); errno != 0 {
+		return GPIOLineInfo{}, fmt.Errorf("failed to query line %d on %s: %w", offset, c.path, errno)
+	}
+	flags := binary.NativeEndian.Uint64(buf[72:80])
+	return GPIOLineInfo{
+		Offset:    offset,
+		Name:      cString(buf[0:32]),
+		Consumer:  cString(buf[32:64]),
+		Used:      flags&gpioFlagUsed != 0,
+		Output:    flags&gpioFlagOutput != 0,
+		ActiveLow: flags&gpioFlagActiveLow != 0,
+	}, nil
+}
+
+func lineFlags(cfg GPIOLineConfig) (uint64, error) {
+	var flags uint64 = gpioFlagInput
+	if cfg.Output {
+		flags = gpioFlagOutput
+	}
+	if cfg.ActiveLow {
+		flags |= gpioFlagActiveLow
+	}
+	switch cfg.Bias {
+	case "":
+	case BiasPullUp:
+		flags |= gpioFlagBiasPullUp
+	case BiasPullDown:
+		flags |= gpioFlagBiasPullDown
+	case BiasDisabled:
+		flags |= gpioFlagBiasDisabled
+	default:
+		return 0, fmt.Errorf("unknown bias %q", cfg.Bias)
+	}
+	switch cfg.Edge {
+	case "":
+	case EdgeRising:
+		flags |= gpioFlagEdgeRising
+	case EdgeFalling:
+		flags |= gpioFlagEdgeFalling
+	case EdgeBoth:
+		flags |= gpioFlagEdgeRising | gpioFlagEdgeFalling
+	default:
+		return 0, fmt.Errorf("unknown edge %q", cfg.Edge)
+	}
+	if cfg.Output && cfg.Edge != "" {
+		return 0, errors.New("edge detection needs an input line")
+	}
+	return flags, nil
+}
+
+func (c *linuxGPIOChip) Request(offset int, cfg GPIOLineConfig) (GPIOLine, error) {
+	flags, err := lineFlags(cfg)
+	if err != nil {
+		return nil, err
+	}
+	realtime := cfg.Edge != ""
+	if realtime {
+		flags |= gpioFlagClockRealtime
+	}
+
+	buf := make([]byte, gpioRequestSize)
+	binary.NativeEndian.PutUint32(buf[0:4], uint32(offset))
+	copy(buf[256:288], gpioConsumer)
+	// Attributes: 24 bytes each from offset 320 (id, padding, value, mask).
+	attrs := 0
+	addAttr := func(id uint32, value uint64) {
+		a := buf[320+24*attrs:]
+		binary.NativeEndian.PutUint32(a[0:4], id)
+		binary.NativeEndian.PutUint64(a[8:16], value)
+		binary.NativeEndian.PutUint64(a[16:24], 1) // applies to the first (only) line
+		attrs++
+	}
+	if cfg.Output && cfg.Value {
+		addAttr(gpioAttrOutputValues, 1)
+	}
+	if cfg.Debounce > 0 {
+		addAttr(gpioAttrDebounce, uint64(cfg.Debounce/time.Microsecond))
+	}
+	binary.NativeEndian.PutUint32(buf[296:300], uint32(attrs))
+	binary.NativeEndian.PutUint32(buf[560:564], 1) // num_lines
+
+	binary.NativeEndian.PutUint64(buf[288:296], flags)
+	errno := ioctlBuf(c.fd, gpioV2GetLine, buf)
+	if errno == syscall.EINVAL && realtime {
+		// Kernels before 5.11 lack realtime event timestamps.
+		realtime = false
+		binary.NativeEndian.PutUint64(buf[288:296], flags&^gpioFlagClockRealtime)
+		errno = ioctlBuf(c.fd, gpioV2GetLine, buf)
+	}
+	if errno == syscall.EBUSY {
+		return nil, fmt.Errorf("line %d on %s is in use by another consumer", offset, c.path)
+	}
+	if errno != 0 {
+		return nil, fmt.Errorf("failed to request line %d on %s: %w", offset, c.path, errno)
+	}
+
+	fd := int(int32(binary.NativeEndian.Uint32(buf[588:592])))
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("gpio line nonblock: %w", err)
+	}
+	return &linuxGPIOLine{
+		fd:       fd,
+		f:        os.NewFile(uintptr(fd), fmt.Sprintf("%s line %d", c.path, offset)),
+		realtime: realtime,
+	}, nil
+}
+
+type linuxGPIOLine struct {
+	fd       int
+	f        *os.File // for deadline-aware event reads
+	realtime bool
+}
+
+func (l *linuxGPIOLine) Close() error {
+	return l.f.Close()
+}
+
+func (l *linuxGPIOLine) Value() (bool, error) {
+	buf := make([]byte, 16)
+	binary.NativeEndian.PutUint64(buf[8:16], 1) // mask
+	if errno := ioctlBuf(l.fd, gpioV2LineGetValues, buf); errno != 0 {
+		return false, fmt.Errorf("failed to read line: %w", errno)
+	}
+	return binary.NativeEndian.Uint64(buf[0:8])&1 != 0, nil
+}
+
+func (l *linuxGPIOLine) SetValue(v bool) error {
+	buf := make([]byte, 16)
+	if v {
+		binary.NativeEndian.PutUint64(buf[0:8], 1)
+	}
+	binary.NativeEndian.PutUint64(buf[8:16], 1) // mask
+	if errno := ioctlBuf(l.fd, gpioV2LineSetValues, buf); errno != 0 {
+		return fmt.Errorf("failed to set line: %w", errno)
+	}
+	return nil
+}
+
+func (l *linuxGPIOLine) WaitEdge(timeout time.Duration) (GPIOEdge, error) {
+	if err := l.f.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return GPIOEdge{}, err
+	}
+	buf := make([]byte, gpioEventSize)
+	n, err := l.f.Read(buf)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return GPIOEdge{}, ErrTimeout
+	}
+	if err != nil {
+		return GPIOEdge{}, fmt.Errorf("failed to read edge event: %w", err)
+	}
+	if n < gpioEventSize {
+		return GPIOEdge{}, fmt.Errorf("short edge event: %d bytes", n)
+	}
+	ev := GPIOEdge{Edge: EdgeFalling, Time: time.Now()}
+	if binary.NativeEndian.Uint32(buf[8:12]) == gpioEventRisingEdge {
+		ev.Edge = EdgeRising
+	}
+	if l.realtime {
+		ev.Time = time.Unix(0, int64(binary.NativeEndian.Uint64(buf[0:8])))
+	}
+	return ev, nil
+}
@@ -0,0 +1,155 @@
+package hardware
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// I2C ioctl constants from Linux kernel headers (<linux/i2c-dev.h>, <linux/i2c.h>)
+const (
+	i2cSlave = 0x0703 // Set slave address (fails if in use by driver)
+	i2cFuncs = 0x0705 // Query adapter functionality bitmask
+	i2cSmbus = 0x0720 // Perform SMBus transaction
+
+	// I2C_FUNC capability bits
+	i2cFuncSmbusQuick    = 0x00010000
+	i2cFuncSmbusReadByte = 0x00020000
+
+	// SMBus transaction types
+	i2cSmbusRead  = 0
+	i2cSmbusWrite = 1
+
+	// SMBus protocol sizes
+	i2cSmbusQuick = 0
+	i2cSmbusByte  = 1
+)
+
+// i2cSmbusData matches the kernel union i2c_smbus_data (34 bytes max).
+// For quick and byte transactions only the first byte is used (if at all).
+type i2cSmbusData [34]byte
+
+// i2cSmbusArgs matches the kernel struct i2c_smbus_ioctl_data.
+type i2cSmbusArgs struct {
+	readWrite uint8
+	command   uint8
+	size      uint32
+	data      *i2cSmbusData
+}
+
+type linuxI2C struct {
+	fd   int
+	path string
+}
+
+// OpenI2C opens /dev/i2c-<bus>.
+func OpenI2C(bus string) (I2C, error) {
+	path := "/dev/i2c-" + bus
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w (check permissions and i2c-dev module)", path, err)
+	}
+	return &linuxI2C{fd: fd, path: path}, nil
+}
+
+func (b *linuxI2C) Close() error {
+	return syscall.Close(b.fd)
+}
+
+func (b *linuxI2C) setAddr(addr uint16) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(b.fd), i2cSlave, uintptr(addr))
+	if errno != 0 {
+		return fmt.Errorf("failed to set I2C address 0x%02x: %w", addr, errno)
+	}
+	return nil
+}
+
+func (b *linuxI2C) Tx(addr uint16, w, r []byte) error {
+	if err := b.setAddr(addr); err != nil {
+		return err
+	}
+	if len(w) > 0 {
+		if _, err := syscall.Write(b.fd, w); err != nil {
+			return fmt.Errorf("failed to write to device 0x%02x: %w", addr, err)
+		}
+	}
+	if len(r) > 0 {
+		n, err := syscall.Read(b.fd, r)
+		if err != nil {
+			return fmt.Errorf("failed to read from device 0x%02x: %w", addr, err)
+		}
+		if n != len(r) {
+			return fmt.Errorf("short read from device 0x%02x: %d of %d bytes", addr, n, len(r))
+		}
+	}
+	return nil
+}
+
+// smbusProbe performs a single SMBus probe at the given address.
+// Uses SMBus Quick Write (safest) or falls back to SMBus Read Byte for
+// EEPROM address ranges where quick write can corrupt AT24RF08 chips.
+// This matches i2cdetect's MODE_AUTO behavior.
+func smbusProbe(fd int, addr uint16, hasQuick bool) bool {
+	// EEPROM ranges: use read byte (quick write can corrupt AT24RF08)
+	useReadByte := (addr >= 0x30 && addr <= 0x37) || (addr >= 0x50 && addr <= 0x5F)
+
+	if !useReadByte && hasQuick {
+		// SMBus Quick Write: [START] [ADDR|W] [ACK/NACK] [STOP]
+		// Safest probe — no data transferred
+		args := i2cSmbusArgs{
+			readWrite: i2cSmbusWrite,
+			command:   0,
+			size:      i2cSmbusQuick,
+			data:      nil,
+		}
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSmbus, uintptr(unsafe.Pointer(&args)))
+		return errno == 0
+	}
+
+	// SMBus Read Byte: [START] [ADDR|R] [ACK/NACK] [DATA] [STOP]
+	var data i2cSmbusData
+	args := i2cSmbusArgs{
+		readWrite: i2cSmbusRead,
+		command:   0,
+		size:      i2cSmbusByte,
+		data:      &data,
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSmbus, uintptr(unsafe.Pointer(&args)))
+	return errno == 0
+}
+
+// Scan uses the same hybrid probe strategy as i2cdetect's MODE_AUTO:
+// SMBus Quick Write for most addresses, SMBus Read Byte for EEPROM ranges.
+func (b *linuxI2C) Scan() ([]ScanEntry, error) {
+	// Query adapter capabilities to determine available probe methods.
+	// I2C_FUNCS writes an unsigned long, which is word-sized on Linux.
+	var funcs uintptr
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(b.fd), i2cFuncs, uintptr(unsafe.Pointer(&funcs)))
+	if errno != 0 {
+		return nil, fmt.Errorf("failed to query I2C adapter capabilities on %s: %w", b.path, errno)
+	}
+
+	hasQuick := funcs&i2cFuncSmbusQuick != 0
+	hasReadByte := funcs&i2cFuncSmbusReadByte != 0
+	if !hasQuick && !hasReadByte {
+		return nil, fmt.Errorf(
+			"I2C adapter %s supports neither SMBus Quick nor Read Byte — cannot probe safely", b.path)
+	}
+
+	var found []ScanEntry
+	// Scan 0x08-0x77, skipping I2C reserved addresses 0x00-0x07
+	for addr := uint16(0x08); addr <= 0x77; addr++ {
+		// Set slave address — EBUSY means a kernel driver owns this address
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(b.fd), i2cSlave, uintptr(addr))
+		if errno != 0 {
+			if errno == syscall.EBUSY {
+				found = append(found, ScanEntry{Addr: addr, Busy: true})
+			}
+			continue
+		}
+		if smbusProbe(b.fd, addr, hasQuick) {
+			found = append(found, ScanEntry{Addr: addr})
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,32 @@
+package hardware
+
+func init() {
+	addrs := make([]uint16, 0, 16)
+	for a := uint16(0x40); a <= 0x4f; a++ {
+		addrs = append(addrs, a)
+	}
+	register(&Profile{
+		Name:        "ina219",
+		Description: "TI INA219 current and power monitor",
+		Addresses:   addrs,
+		Fields: []Field{
+			// 10 µV per bit
+			{Name: "shunt_voltage", Unit: "mV", Register: 0x01, Format: "s16be", Scale: 0.01},
+			// 4 mV per bit in bits 15-3
+			{Name: "bus_voltage", Unit: "V", Register: 0x02, Format: "u16be", Shift: 3, Scale: 0.004},
+		},
+		// Current and power are derived from the shunt voltage rather than
+		// the chip's own registers, which need a calibration write first.
+		Derive: func(values, opts map[string]float64) []Measurement {
+			if opts["shunt_ohms"] <= 0 {
+				return nil
+			}
+			current := values["shunt_voltage"] / opts["shunt_ohms"]
+			return []Measurement{
+				{Name: "current", Value: current, Unit: "mA"},
+				{Name: "power", Value: current * values["bus_voltage"], Unit: "mW"},
+			}
+		},
+		Options: map[string]float64{"shunt_ohms": 0.1},
+	})
+}
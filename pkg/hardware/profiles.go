@@ -0,0 +1,290 @@
+package hardware
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile describes a peripheral chip: where it lives, how to recognise
+// it, how to turn its registers into measurements and which outputs it
+// can drive. Simple chips are described entirely by Fields; chips that
+// need commands or calibration supply Read.
+type Profile struct {
+	Name        string
+	Description string
+	// Addresses are the I2C addresses the chip can be strapped to.
+	Addresses []uint16
+	// SPI reports whether the chip can also be driven over SPI using the
+	// register convention of NewSPIDevice.
+	SPI bool
+	// ID, when set, is a register whose value identifies the chip, so a
+	// scan can tell it apart from other chips at the same address.
+	ID *RegisterValue
+	// Init is written before every reading of Fields.
+	Init []RegisterValue
+	// Fields are read and converted in order.
+	Fields []Field
+	// Derive computes extra measurements from the Fields' values.
+	Derive func(values, opts map[string]float64) []Measurement
+	// Read replaces Fields for chips that need more than register reads.
+	Read func(d Device, opts map[string]float64) ([]Measurement, error)
+	// Options are the tunables the profile understands, with defaults.
+	Options map[string]float64
+	Outputs []Output
+}
+
+// RegisterValue is one register and its value.
+type RegisterValue struct {
+	Register uint8
+	Value    uint8
+}
+
+// Field is a measurement read directly from registers. The raw value is
+// shifted right by Shift, then multiplied by Scale and offset by Offset.
+type Field struct {
+	Name     string
+	Unit     string
+	Register uint8
+	Format   string // u8, s8, u16be, s16be, u16le or s16le
+	Shift    uint
+	Scale    float64
+	Offset   float64
+}
+
+// Measurement is one converted reading.
+type Measurement struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// Output types, which decide how a set_output value is parsed.
+const (
+	OutputBool   = "bool"
+	OutputNumber = "number"
+	OutputString = "string"
+	OutputNone   = "none" // an action that takes no value
+)
+
+// Output is something a profile can set: a display, a mode, a relay.
+type Output struct {
+	Name        string
+	Description string
+	Type        string
+	// Set receives a bool, float64, string or nil according to Type.
+	Set func(d Device, value any, opts map[string]float64) error
+}
+
+var registry = map[string]*Profile{}
+
+func register(p *Profile) {
+	registry[p.Name] = p
+}
+
+// Profiles returns every known profile, sorted by name.
+func Profiles() []*Profile {
+	names := slices.Sorted(maps.Keys(registry))
+	out := make([]*Profile, len(names))
+	for i, name := range names {
+		out[i] = registry[name]
+	}
+	return out
+}
+
+// Lookup returns the profile with the given name, ignoring case.
+func Lookup(name string) (*Profile, bool) {
+	p, ok := registry[strings.ToLower(name)]
+	return p, ok
+}
+
+// Candidate is a profile that may describe the chip at an address.
+type Candidate struct {
+	Profile *Profile
+	// Confirmed means the chip's ID register matched; otherwise only the
+	// address fits.
+	Confirmed bool
+}
+
+// Identify returns the profiles that may describe the device at addr,
+// confirmed ones first. Profiles whose ID register doesn't match are left
+// out.
+func Identify(bus I2C, addr uint16) []Candidate {
+	var out []Candidate
+	for _, p := range Profiles() {
+		if !slices.Contains(p.Addresses, addr) {
+			continue
+		}
+		if p.ID == nil {
+			out = append(out, Candidate{Profile: p})
+			continue
+		}
+		var id [1]byte
+		if err := NewI2CDevice(bus, addr).ReadReg(p.ID.Register, id[:]); err != nil || id[0] != p.ID.Value {
+			continue
+		}
+		out = append(out, Candidate{Profile: p, Confirmed: true})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Confirmed && !out[j].Confirmed })
+	return out
+}
+
+// options merges the caller's options over the profile's defaults and
+// rejects names the profile doesn't know.
+func (p *Profile) options(opts map[string]float64) (map[string]float64, error) {
+	merged := maps.Clone(p.Options)
+	if merged == nil {
+		merged = map[string]float64{}
+	}
+	for k, v := range opts {
+		if _, ok := p.Options[k]; !ok {
+			return nil, fmt.Errorf("%s: unknown option %q", p.Name, k)
+		}
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// ReadSensor takes a reading from d.
+func (p *Profile) ReadSensor(d Device, opts map[string]float64) ([]Measurement, error) {
+	opts, err := p.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	if p.Read != nil {
+		return p.Read(d, opts)
+	}
+	if len(p.Fields) == 0 {
+		return nil, fmt.Errorf("%s has no sensor readings", p.Name)
+	}
+	for _, w := range p.Init {
+		if err := d.WriteReg(w.Register, w.Value); err != nil {
+			return nil, fmt.Errorf("%s: init register 0x%02x: %w", p.Name, w.Register, err)
+		}
+	}
+	values := make(map[string]float64, len(p.Fields))
+	out := make([]Measurement, 0, len(p.Fields))
+	for _, f := range p.Fields {
+		v, err := f.read(d)
+		if err != nil {
+			return nil, fmt.Errorf("%s: read %s: %w", p.Name, f.Name, err)
+		}
+		values[f.Name] = v
+		out = append(out, Measurement{Name: f.Name, Value: v, Unit: f.Unit})
+	}
+	if p.Derive != nil {
+		out = append(out, p.Derive(values, opts)...)
+	}
+	return out, nil
+}
+
+func (f *Field) read(d Device) (float64, error) {
+	size := 2
+	if f.Format == "u8" || f.Format == "s8" {
+		size = 1
+	}
+	buf := make([]byte, size)
+	if err := d.ReadReg(f.Register, buf); err != nil {
+		return 0, err
+	}
+	var raw int64
+	switch f.Format {
+	case "u8":
+		raw = int64(buf[0])
+	case "s8":
+		raw = int64(int8(buf[0]))
+	case "u16be":
+		raw = int64(binary.BigEndian.Uint16(buf))
+	case "s16be":
+		raw = int64(int16(binary.BigEndian.Uint16(buf)))
+	case "u16le":
+		raw = int64(binary.LittleEndian.Uint16(buf))
+	case "s16le":
+		raw = int64(int16(binary.LittleEndian.Uint16(buf)))
+	default:
+		return 0, fmt.Errorf("unknown format %q", f.Format)
+	}
+	raw >>= f.Shift
+	scale := f.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return float64(raw)*scale + f.Offset, nil
+}
+
+// Output returns the named output.
+func (p *Profile) Output(name string) (*Output, bool) {
+	for i := range p.Outputs {
+		if p.Outputs[i].Name == name {
+			return &p.Outputs[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetOutput parses value according to the output's type and applies it.
+func (p *Profile) SetOutput(d Device, name string, value any, opts map[string]float64) error {
+	o, ok := p.Output(name)
+	if !ok {
+		names := make([]string, len(p.Outputs))
+		for i, o := range p.Outputs {
+			names[i] = o.Name
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("%s has no outputs", p.Name)
+		}
+		return fmt.Errorf("%s has no output %q (available: %s)", p.Name, name, strings.Join(names, ", "))
+	}
+	opts, err := p.options(opts)
+	if err != nil {
+		return err
+	}
+	v, err := parseOutputValue(o.Type, value)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", p.Name, name, err)
+	}
+	return o.Set(d, v, opts)
+}
+
+func parseOutputValue(typ string, value any) (any, error) {
+	switch typ {
+	case OutputNone:
+		return nil, nil
+	case OutputBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			switch strings.ToLower(v) {
+			case "on", "true", "1", "yes":
+				return true, nil
+			case "off", "false", "0", "no":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("value must be true/false or on/off, got %v", value)
+	case OutputNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, fmt.Errorf("value must be a number, got %v", value)
+	case OutputString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("value must be a string, got %v", value)
+	default:
+		return nil, fmt.Errorf("unknown output type %q", typ)
+	}
+}
@@ -0,0 +1,230 @@
+package hardware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func measurement(t *testing.T, ms []Measurement, name string) float64 {
+	t.Helper()
+	for _, m := range ms {
+		if m.Name == name {
+			return m.Value
+		}
+	}
+	t.Fatalf("no %s in %+v", name, ms)
+	return 0
+}
+
+// bme280Regs returns a BME280 register file loaded with the compensation
+// example from the datasheet.
+func bme280Regs() *SimRegisters {
+	regs := NewSimRegisters(1)
+	regs.Set(bme280RegID, bme280ChipID)
+
+	calib := make([]byte, 26)
+	for i, v := range []int{27504, 26435, -1000, 36477, -10685, 3024, 2855, 140, -7, 15500, -14600, 6000} {
+		binary.LittleEndian.PutUint16(calib[2*i:], uint16(int16(v)))
+	}
+	calib[25] = 75 // H1
+	regs.Set(bme280RegCalib1, calib...)
+	// H2=362, H3=0, H4=313, H5=50, H6=30
+	regs.Set(bme280RegCalib2, 0x6a, 0x01, 0x00, 313>>4, 313&0x0f|(50&0x0f)<<4, 50>>4, 30)
+
+	adcP, adcT, adcH := 415148, 519888, 27000
+	regs.Set(bme280RegData,
+		byte(adcP>>12), byte(adcP>>4), byte(adcP<<4),
+		byte(adcT>>12), byte(adcT>>4), byte(adcT<<4),
+		byte(adcH>>8), byte(adcH))
+	return regs
+}
+
+func checkBME280(t *testing.T, ms []Measurement) {
+	t.Helper()
+	if v := measurement(t, ms, "temperature"); !near(v, 25.08, 0.01) {
+		t.Errorf("temperature = %v, want 25.08", v)
+	}
+	if v := measurement(t, ms, "pressure"); !near(v, 1006.53, 0.01) {
+		t.Errorf("pressure = %v, want 1006.53", v)
+	}
+	if v := measurement(t, ms, "humidity"); !near(v, 38.28, 0.01) {
+		t.Errorf("humidity = %v, want 38.28", v)
+	}
+}
+
+func TestBME280(t *testing.T) {
+	p, _ := Lookup("BME280")
+
+	regs := bme280Regs()
+	bus := NewSimI2C()
+	bus.Attach(0x76, regs)
+	ms, err := p.ReadSensor(NewI2CDevice(bus, 0x76), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBME280(t, ms)
+	if got := regs.Get(bme280RegCtrlMeas)[0]; got != 0x25 {
+		t.Errorf("ctrl_meas = 0x%02x, want a forced measurement (0x25)", got)
+	}
+
+	// The same chip over SPI.
+	spi := &SimSPI{Regs: bme280Regs(), High: true}
+	ms, err = p.ReadSensor(NewSPIDevice(spi), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBME280(t, ms)
+}
+
+func TestSHT3x(t *testing.T) {
+	if crc := sensirionCRC([]byte{0xbe, 0xef}); crc != 0x92 {
+		t.Fatalf("crc(0xBEEF) = 0x%02x, want 0x92", crc)
+	}
+
+	var command []byte
+	reply := []byte{0x66, 0x66, 0, 0x80, 0x00, 0}
+	reply[2] = sensirionCRC(reply[0:2])
+	reply[5] = sensirionCRC(reply[3:5])
+	bus := NewSimI2C()
+	bus.Attach(0x44, SimFunc(func(w, r []byte) error {
+		if len(w) > 0 {
+			command = append([]byte(nil), w...)
+		}
+		copy(r, reply)
+		return nil
+	}))
+
+	p, _ := Lookup("sht3x")
+	ms, err := p.ReadSensor(NewI2CDevice(bus, 0x44), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(command, []byte{0x24, 0x00}) {
+		t.Errorf("command = % x, want 24 00", command)
+	}
+	if v := measurement(t, ms, "temperature"); !near(v, 25.0, 0.01) {
+		t.Errorf("temperature = %v, want 25.0", v)
+	}
+	if v := measurement(t, ms, "humidity"); !near(v, 50.0, 0.01) {
+		t.Errorf("humidity = %v, want 50.0", v)
+	}
+
+	reply[5] ^= 0xff
+	if _, err := p.ReadSensor(NewI2CDevice(bus, 0x44), nil); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("corrupt reply: err = %v, want checksum error", err)
+	}
+}
+
+func TestINA219(t *testing.T) {
+	regs := NewSimRegisters(2)
+	regs.Set(0x01, 0x03, 0xe8) // 1000 × 10 µV = 10 mV
+	regs.Set(0x02, 0x5d, 0xc0) // 3000 × 4 mV = 12 V, shifted left 3
+	bus := NewSimI2C()
+	bus.Attach(0x40, regs)
+	dev := NewI2CDevice(bus, 0x40)
+	p, _ := Lookup("ina219")
+
+	ms, err := p.ReadSensor(dev, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"shunt_voltage": 10, "bus_voltage": 12, "current": 100, "power": 1200}
+	for name, v := range want {
+		if got := measurement(t, ms, name); !near(got, v, 1e-6) {
+			t.Errorf("%s = %v, want %v", name, got, v)
+		}
+	}
+
+	ms, err = p.ReadSensor(dev, map[string]float64{"shunt_ohms": 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := measurement(t, ms, "current"); !near(got, 200, 1e-6) {
+		t.Errorf("current with 0.05 Ω shunt = %v, want 200", got)
+	}
+
+	if _, err := p.ReadSensor(dev, map[string]float64{"gain": 2}); err == nil {
+		t.Error("unknown option accepted")
+	}
+}
+
+func TestSSD1306(t *testing.T) {
+	regs := NewSimRegisters(1)
+	bus := NewSimI2C()
+	bus.Attach(0x3c, regs)
+	dev := NewI2CDevice(bus, 0x3c)
+	p, _ := Lookup("ssd1306")
+
+	if err := p.SetOutput(dev, "text", "Hi\nthere", nil); err != nil {
+		t.Fatal(err)
+	}
+	var pages [][]byte
+	for _, w := range regs.Writes {
+		if w[0] == ssd1306Data {
+			pages = append(pages, w[1:])
+		}
+	}
+	if len(pages) != 8 {
+		t.Fatalf("wrote %d pages, want 8", len(pages))
+	}
+	if !bytes.Equal(pages[0][0:5], glyph('H')) || !bytes.Equal(pages[0][6:11], glyph('i')) {
+		t.Errorf("page 0 starts % x, want H and i", pages[0][:12])
+	}
+	if !bytes.Equal(pages[1][0:5], glyph('t')) || len(bytes.Trim(pages[2], "\x00")) != 0 {
+		t.Error("second line not drawn on page 1, or page 2 not blank")
+	}
+
+	regs.Writes = nil
+	if err := p.SetOutput(dev, "power", "on", map[string]float64{"height": 32}); err != nil {
+		t.Fatal(err)
+	}
+	seq := regs.Writes[0]
+	if seq[0] != ssd1306Command || seq[len(seq)-1] != 0xaf || !bytes.Contains(seq, []byte{0xa8, 0x1f}) {
+		t.Errorf("power on for 128x32 sent % x", seq)
+	}
+
+	if err := p.SetOutput(dev, "contrast", 300.0, nil); err == nil {
+		t.Error("contrast 300 accepted")
+	}
+	if err := p.SetOutput(dev, "text", strings.Repeat("x\n", 8)+"x", nil); err == nil {
+		t.Error("9 lines of text accepted")
+	}
+	if err := p.SetOutput(dev, "volume", 1.0, nil); err == nil {
+		t.Error("unknown output accepted")
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	bus := NewSimI2C()
+	bus.Attach(0x76, bme280Regs())
+	bus.Attach(0x44, NewSimRegisters(1))
+	other := NewSimRegisters(1)
+	other.Set(bme280RegID, 0x58) // a BMP280
+	bus.Attach(0x77, other)
+
+	got := Identify(bus, 0x76)
+	if len(got) != 1 || got[0].Profile.Name != "bme280" || !got[0].Confirmed {
+		t.Errorf("0x76: %+v, want confirmed bme280", got)
+	}
+	if got := Identify(bus, 0x77); len(got) != 0 {
+		t.Errorf("0x77 with the wrong chip ID matched %s", got[0].Profile.Name)
+	}
+
+	var names []string
+	for _, c := range Identify(bus, 0x44) {
+		if c.Confirmed {
+			t.Errorf("%s confirmed without an ID register", c.Profile.Name)
+		}
+		names = append(names, c.Profile.Name)
+	}
+	if strings.Join(names, ",") != "ina219,sht3x" {
+		t.Errorf("0x44 candidates = %v, want ina219 and sht3x", names)
+	}
+}
@@ -0,0 +1,186 @@
+package hardware
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PWM drives PWM channels through the kernel's sysfs interface.
+type PWM struct {
+	// Root is normally /sys/class/pwm; tests point it at a directory tree.
+	Root string
+}
+
+// NewPWM returns a PWM on the real sysfs tree.
+func NewPWM() *PWM {
+	return &PWM{Root: "/sys/class/pwm"}
+}
+
+// PWMChip is a PWM controller.
+type PWMChip struct {
+	Chip     int `json:"chip"`
+	Channels int `json:"channels"`
+}
+
+// PWMState is the setting of one channel. Times are in nanoseconds, as in
+// sysfs.
+type PWMState struct {
+	Period   int64  `json:"period_ns"`
+	Duty     int64  `json:"duty_cycle_ns"`
+	Polarity string `json:"polarity,omitempty"` // "normal" or "inversed"
+	Enabled  bool   `json:"enabled"`
+}
+
+var pwmChipRe = regexp.MustCompile(`^pwmchip(\d+)$`)
+
+// Chips lists the PWM controllers.
+func (p *PWM) Chips() ([]PWMChip, error) {
+	entries, err := os.ReadDir(p.Root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var chips []PWMChip
+	for _, e := range entries {
+		m := pwmChipRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		id, _ := strconv.Atoi(m[1])
+		n, _ := p.readInt(filepath.Join(p.Root, e.Name(), "npwm"))
+		chips = append(chips, PWMChip{Chip: id, Channels: int(n)})
+	}
+	return chips, nil
+}
+
+func (p *PWM) chipDir(chip int) string {
+	return filepath.Join(p.Root, fmt.Sprintf("pwmchip%d", chip))
+}
+
+func (p *PWM) channelDir(chip, channel int) string {
+	return filepath.Join(p.chipDir(chip), fmt.Sprintf("pwm%d", channel))
+}
+
+// export makes a channel's sysfs directory appear. udev may still be
+// fixing up permissions when it does, so wait until it is writable.
+func (p *PWM) export(chip, channel int) error {
+	dir := p.channelDir(chip, channel)
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	n, err := p.readInt(filepath.Join(p.chipDir(chip), "npwm"))
+	if err != nil {
+		return fmt.Errorf("no PWM chip %d: %w", chip, err)
+	}
+	if int64(channel) >= n {
+		return fmt.Errorf("PWM chip %d has %d channel(s), no channel %d", chip, n, channel)
+	}
+	if err := p.write(filepath.Join(p.chipDir(chip), "export"), strconv.Itoa(channel)); err != nil {
+		return err
+	}
+	for range 20 {
+		if f, err := os.OpenFile(filepath.Join(dir, "enable"), os.O_WRONLY, 0); err == nil {
+			f.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("PWM channel %d on chip %d did not appear after export", channel, chip)
+}
+
+// State reads a channel's setting.
+func (p *PWM) State(chip, channel int) (PWMState, error) {
+	dir := p.channelDir(chip, channel)
+	var s PWMState
+	var err error
+	if s.Period, err = p.readInt(filepath.Join(dir, "period")); err != nil {
+		return s, fmt.Errorf("PWM channel %d on chip %d is not exported: %w", channel, chip, err)
+	}
+	if s.Duty, err = p.readInt(filepath.Join(dir, "duty_cycle")); err != nil {
+		return s, err
+	}
+	enabled, err := p.readInt(filepath.Join(dir, "enable"))
+	if err != nil {
+		return s, err
+	}
+	s.Enabled = enabled == 1
+	if b, err := os.ReadFile(filepath.Join(dir, "polarity")); err == nil {
+		s.Polarity = strings.TrimSpace(string(b))
+	}
+	return s, nil
+}
+
+// Set exports the channel if needed and applies s. The kernel rejects a
+// period shorter than the current duty cycle, so the two are written in
+// whichever order keeps that true; polarity can only change while the
+// channel is disabled.
+func (p *PWM) Set(chip, channel int, s PWMState) error {
+	if s.Period <= 0 || s.Duty < 0 || s.Duty > s.Period {
+		return fmt.Errorf("invalid PWM setting: period %d ns, duty cycle %d ns", s.Period, s.Duty)
+	}
+	if s.Polarity != "" && s.Polarity != "normal" && s.Polarity != "inversed" {
+		return fmt.Errorf("polarity must be normal or inversed, got %q", s.Polarity)
+	}
+	if err := p.export(chip, channel); err != nil {
+		return err
+	}
+	dir := p.channelDir(chip, channel)
+	cur, err := p.State(chip, channel)
+	if err != nil {
+		return err
+	}
+
+	if s.Polarity != "" && s.Polarity != cur.Polarity {
+		if err := p.write(filepath.Join(dir, "enable"), "0"); err != nil {
+			return err
+		}
+		if err := p.write(filepath.Join(dir, "polarity"), s.Polarity); err != nil {
+			return err
+		}
+	}
+	period := strconv.FormatInt(s.Period, 10)
+	duty := strconv.FormatInt(s.Duty, 10)
+	if s.Period < cur.Duty {
+		if err := p.write(filepath.Join(dir, "duty_cycle"), duty); err != nil {
+			return err
+		}
+		if err := p.write(filepath.Join(dir, "period"), period); err != nil {
+			return err
+		}
+	} else {
+		if err := p.write(filepath.Join(dir, "period"), period); err != nil {
+			return err
+		}
+		if err := p.write(filepath.Join(dir, "duty_cycle"), duty); err != nil {
+			return err
+		}
+	}
+	enable := "0"
+	if s.Enabled {
+		enable = "1"
+	}
+	return p.write(filepath.Join(dir, "enable"), enable)
+}
+
+func (p *PWM) readInt(path string) (int64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func (p *PWM) write(path, value string) error {
+	if err := os.WriteFile(path, []byte(value), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s to %s: %w", value, path, err)
+	}
+	return nil
+}
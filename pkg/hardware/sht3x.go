@@ -0,0 +1,56 @@
+package hardware
+
+import (
+	"fmt"
+	"time"
+)
+
+func init() {
+	register(&Profile{
+		Name:        "sht3x",
+		Description: "Sensirion SHT30/31/35 temperature and humidity sensor",
+		Addresses:   []uint16{0x44, 0x45},
+		Read:        readSHT3x,
+	})
+}
+
+func readSHT3x(d Device, _ map[string]float64) ([]Measurement, error) {
+	// Single shot, high repeatability, no clock stretching.
+	if err := d.Tx([]byte{0x24, 0x00}, nil); err != nil {
+		return nil, fmt.Errorf("sht3x: start measurement: %w", err)
+	}
+	time.Sleep(16 * time.Millisecond)
+
+	var data [6]byte
+	if err := d.Tx(nil, data[:]); err != nil {
+		return nil, fmt.Errorf("sht3x: read data: %w", err)
+	}
+	for i := 0; i < 6; i += 3 {
+		if crc := sensirionCRC(data[i : i+2]); crc != data[i+2] {
+			return nil, fmt.Errorf("sht3x: checksum mismatch (got 0x%02x, want 0x%02x)", data[i+2], crc)
+		}
+	}
+	rawT := float64(uint16(data[0])<<8 | uint16(data[1]))
+	rawH := float64(uint16(data[3])<<8 | uint16(data[4]))
+	return []Measurement{
+		{Name: "temperature", Value: -45 + 175*rawT/65535, Unit: "°C"},
+		{Name: "humidity", Value: 100 * rawH / 65535, Unit: "%"},
+	}, nil
+}
+
+// sensirionCRC is the CRC-8 Sensirion sensors append to each word
+// (polynomial 0x31, initial value 0xFF).
+func sensirionCRC(data []byte) byte {
+	crc := byte(0xff)
+	for _, b := range data {
+		crc ^= b
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,313 @@
+package hardware
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SimDevice is a device attached to a simulated bus. Tx sees one
+// transaction: the bytes the host wrote, and r to fill with the bytes it
+// reads back.
+type SimDevice interface {
+	Tx(w, r []byte) error
+}
+
+// SimFunc adapts a function to SimDevice.
+type SimFunc func(w, r []byte) error
+
+func (f SimFunc) Tx(w, r []byte) error { return f(w, r) }
+
+// SimI2C is an in-memory I2C bus for tests and for trying profiles without
+// hardware.
+type SimI2C struct {
+	mu      sync.Mutex
+	devices map[uint16]SimDevice
+	busy    map[uint16]bool
+}
+
+// NewSimI2C returns an empty simulated bus.
+func NewSimI2C() *SimI2C {
+	return &SimI2C{devices: make(map[uint16]SimDevice), busy: make(map[uint16]bool)}
+}
+
+// Attach places dev at addr, replacing whatever was there.
+func (b *SimI2C) Attach(addr uint16, dev SimDevice) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.devices[addr] = dev
+}
+
+// SetBusy marks addr as claimed by a kernel driver.
+func (b *SimI2C) SetBusy(addr uint16) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.busy[addr] = true
+}
+
+func (b *SimI2C) Tx(addr uint16, w, r []byte) error {
+	b.mu.Lock()
+	dev, ok := b.devices[addr]
+	busy := b.busy[addr]
+	b.mu.Unlock()
+	if busy {
+		return fmt.Errorf("failed to set I2C address 0x%02x: device or resource busy", addr)
+	}
+	if !ok {
+		return fmt.Errorf("no device at address 0x%02x", addr)
+	}
+	return dev.Tx(w, r)
+}
+
+func (b *SimI2C) Scan() ([]ScanEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var found []ScanEntry
+	for addr := range b.devices {
+		found = append(found, ScanEntry{Addr: addr, Busy: b.busy[addr]})
+	}
+	for addr := range b.busy {
+		if _, ok := b.devices[addr]; !ok {
+			found = append(found, ScanEntry{Addr: addr, Busy: true})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Addr < found[j].Addr })
+	return found, nil
+}
+
+func (b *SimI2C) Close() error { return nil }
+
+// SimRegisters is a register-file device: the first byte of a write sets
+// the register pointer and any further bytes are stored from that
+// register on; reads return bytes from the pointer on. Registers are width
+// bytes wide (2 for chips such as the INA219) and transfers run on into
+// the following registers, as on most sensors.
+type SimRegisters struct {
+	mu    sync.Mutex
+	width int
+	data  []byte
+	off   int
+	// Writes records every write transaction, for asserting on commands.
+	Writes [][]byte
+}
+
+// NewSimRegisters returns a device with 256 registers of width bytes, all
+// zero.
+func NewSimRegisters(width int) *SimRegisters {
+	return &SimRegisters{width: width, data: make([]byte, 256*width)}
+}
+
+// Set stores data from register reg on.
+func (d *SimRegisters) Set(reg uint8, data ...byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, b := range data {
+		d.data[(int(reg)*d.width+i)%len(d.data)] = b
+	}
+}
+
+// Get returns the contents of register reg.
+func (d *SimRegisters) Get(reg uint8) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	off := int(reg) * d.width
+	return append([]byte(nil), d.data[off:off+d.width]...)
+}
+
+func (d *SimRegisters) Tx(w, r []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(w) > 0 {
+		d.Writes = append(d.Writes, append([]byte(nil), w...))
+		d.off = int(w[0]) * d.width
+		for _, b := range w[1:] {
+			d.data[d.off] = b
+			d.off = (d.off + 1) % len(d.data)
+		}
+	}
+	for i := range r {
+		r[i] = d.data[d.off]
+		d.off = (d.off + 1) % len(d.data)
+	}
+	return nil
+}
+
+// SimSPI is a simulated SPI device. It decodes the register convention
+// NewSPIDevice uses (bit 7 set for reads) onto a register file.
+type SimSPI struct {
+	Regs *SimRegisters
+	// High means the chip's registers live at 0x80-0xFF and bit 7 of the
+	// address is implied, as on the BME280.
+	High bool
+}
+
+// NewSimSPI returns a simulated SPI device backed by regs.
+func NewSimSPI(regs *SimRegisters) *SimSPI {
+	return &SimSPI{Regs: regs}
+}
+
+func (s *SimSPI) reg(addr byte) byte {
+	if s.High {
+		return addr | 0x80
+	}
+	return addr & 0x7f
+}
+
+func (s *SimSPI) Tx(w, r []byte) error {
+	if len(w) == 0 {
+		return nil
+	}
+	if r != nil && len(r) != len(w) {
+		return fmt.Errorf("SPI read length %d differs from write length %d", len(r), len(w))
+	}
+	if w[0]&0x80 != 0 {
+		if r == nil {
+			return nil
+		}
+		r[0] = 0
+		return s.Regs.Tx([]byte{s.reg(w[0])}, r[1:])
+	}
+	// Writes are register/value pairs.
+	for i := 0; i+1 < len(w); i += 2 {
+		if err := s.Regs.Tx([]byte{s.reg(w[i]), w[i+1]}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SimSPI) Close() error { return nil }
+
+// SimGPIOChip is an in-memory GPIO chip. SetInput drives a line as an
+// external signal would, raising edge events on lines being watched.
+type SimGPIOChip struct {
+	mu        sync.Mutex
+	name      string
+	names     []string
+	levels    []bool // physical levels
+	requested map[int]*simGPIOLine
+}
+
+// NewSimGPIOChip returns a chip with one line per name, all low.
+func NewSimGPIOChip(name string, lines ...string) *SimGPIOChip {
+	return &SimGPIOChip{
+		name:      name,
+		names:     lines,
+		levels:    make([]bool, len(lines)),
+		requested: make(map[int]*simGPIOLine),
+	}
+}
+
+// SetInput sets the physical level of a line.
+func (c *SimGPIOChip) SetInput(offset int, level bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.levels[offset] == level {
+		return
+	}
+	c.levels[offset] = level
+	l, ok := c.requested[offset]
+	if !ok || l.cfg.Edge == "" {
+		return
+	}
+	// Edges are reported on the logical value, so active-low lines see a
+	// falling level as a rising edge, as with the kernel.
+	edge := EdgeFalling
+	if level != l.cfg.ActiveLow {
+		edge = EdgeRising
+	}
+	if l.cfg.Edge == EdgeBoth || l.cfg.Edge == edge {
+		select {
+		case l.events <- GPIOEdge{Edge: edge, Time: time.Now()}:
+		default: // overflowing events are dropped, like a full kernel buffer
+		}
+	}
+}
+
+// Level returns the physical level of a line.
+func (c *SimGPIOChip) Level(offset int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.levels[offset]
+}
+
+func (c *SimGPIOChip) Info() (GPIOChipInfo, error) {
+	return GPIOChipInfo{Name: c.name, Label: "simulated", Lines: len(c.names)}, nil
+}
+
+func (c *SimGPIOChip) LineInfo(offset int) (GPIOLineInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset < 0 || offset >= len(c.names) {
+		return GPIOLineInfo{}, fmt.Errorf("no line %d", offset)
+	}
+	info := GPIOLineInfo{Offset: offset, Name: c.names[offset]}
+	if l, ok := c.requested[offset]; ok {
+		info.Used, info.Consumer = true, "tinyclaw"
+		info.Output, info.ActiveLow = l.cfg.Output, l.cfg.ActiveLow
+	}
+	return info, nil
+}
+
+func (c *SimGPIOChip) Request(offset int, cfg GPIOLineConfig) (GPIOLine, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset < 0 || offset >= len(c.names) {
+		return nil, fmt.Errorf("no line %d", offset)
+	}
+	if _, ok := c.requested[offset]; ok {
+		return nil, fmt.Errorf("line %d on %s is in use by another consumer", offset, c.name)
+	}
+	if cfg.Output && cfg.Edge != "" {
+		return nil, errors.New("edge detection needs an input line")
+	}
+	l := &simGPIOLine{chip: c, offset: offset, cfg: cfg, events: make(chan GPIOEdge, 16)}
+	if cfg.Output {
+		c.levels[offset] = cfg.Value != cfg.ActiveLow
+	}
+	c.requested[offset] = l
+	return l, nil
+}
+
+func (c *SimGPIOChip) Close() error { return nil }
+
+type simGPIOLine struct {
+	chip   *SimGPIOChip
+	offset int
+	cfg    GPIOLineConfig
+	events chan GPIOEdge
+}
+
+func (l *simGPIOLine) Value() (bool, error) {
+	return l.chip.Level(l.offset) != l.cfg.ActiveLow, nil
+}
+
+func (l *simGPIOLine) SetValue(v bool) error {
+	if !l.cfg.Output {
+		return fmt.Errorf("line %d is an input", l.offset)
+	}
+	l.chip.mu.Lock()
+	defer l.chip.mu.Unlock()
+	l.chip.levels[l.offset] = v != l.cfg.ActiveLow
+	return nil
+}
+
+func (l *simGPIOLine) WaitEdge(timeout time.Duration) (GPIOEdge, error) {
+	select {
+	case ev := <-l.events:
+		return ev, nil
+	case <-time.After(timeout):
+		return GPIOEdge{}, ErrTimeout
+	}
+}
+
+func (l *simGPIOLine) Close() error {
+	l.chip.mu.Lock()
+	defer l.chip.mu.Unlock()
+	if l.chip.requested[l.offset] == l {
+		delete(l.chip.requested, l.offset)
+	}
+	return nil
+}
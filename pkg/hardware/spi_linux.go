@@ -0,0 +1,103 @@
+package hardware
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// SPI ioctl constants from Linux kernel headers.
+// Calculated from _IOW('k', nr, size) macro:
+//
+//	direction(1)<<30 | size<<16 | type(0x6B)<<8 | nr
+const (
+	spiIocWrMode        = 0x40016B01 // _IOW('k', 1, __u8)
+	spiIocWrBitsPerWord = 0x40016B03 // _IOW('k', 3, __u8)
+	spiIocWrMaxSpeedHz  = 0x40046B04 // _IOW('k', 4, __u32)
+	spiIocMessage1      = 0x40206B00 // _IOW('k', 0, struct spi_ioc_transfer) — 32 bytes
+)
+
+// spiTransfer matches Linux kernel struct spi_ioc_transfer (32 bytes on all architectures).
+type spiTransfer struct {
+	txBuf       uint64
+	rxBuf       uint64
+	length      uint32
+	speedHz     uint32
+	delayUsecs  uint16
+	bitsPerWord uint8
+	csChange    uint8
+	txNbits     uint8
+	rxNbits     uint8
+	wordDelay   uint8
+	pad         uint8
+}
+
+type linuxSPI struct {
+	fd  int
+	cfg SPIConfig
+}
+
+// OpenSPI opens /dev/spidev<dev> (dev is "X.Y") and sets its mode, word
+// size and speed.
+func OpenSPI(dev string, cfg SPIConfig) (SPI, error) {
+	path := "/dev/spidev" + dev
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w (check permissions and spidev module)", path, err)
+	}
+
+	// Set SPI mode
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocWrMode, uintptr(unsafe.Pointer(&cfg.Mode)))
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to set SPI mode %d: %w", cfg.Mode, errno)
+	}
+
+	// Set bits per word
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocWrBitsPerWord, uintptr(unsafe.Pointer(&cfg.Bits)))
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to set bits per word %d: %w", cfg.Bits, errno)
+	}
+
+	// Set max speed
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocWrMaxSpeedHz, uintptr(unsafe.Pointer(&cfg.Speed)))
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to set SPI speed %d Hz: %w", cfg.Speed, errno)
+	}
+
+	return &linuxSPI{fd: fd, cfg: cfg}, nil
+}
+
+func (s *linuxSPI) Close() error {
+	return syscall.Close(s.fd)
+}
+
+func (s *linuxSPI) Tx(w, r []byte) error {
+	if len(w) == 0 {
+		return nil
+	}
+	if r == nil {
+		r = make([]byte, len(w))
+	} else if len(r) != len(w) {
+		return fmt.Errorf("SPI read length %d differs from write length %d", len(r), len(w))
+	}
+
+	xfer := spiTransfer{
+		txBuf:       uint64(uintptr(unsafe.Pointer(&w[0]))),
+		rxBuf:       uint64(uintptr(unsafe.Pointer(&r[0]))),
+		length:      uint32(len(w)),
+		speedHz:     s.cfg.Speed,
+		bitsPerWord: s.cfg.Bits,
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(s.fd), spiIocMessage1, uintptr(unsafe.Pointer(&xfer)))
+	runtime.KeepAlive(w)
+	runtime.KeepAlive(r)
+	if errno != 0 {
+		return fmt.Errorf("SPI transfer failed: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package hardware
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SSD1306 control bytes: the first byte of every I2C write says whether
+// the rest are commands or display data.
+const (
+	ssd1306Command = 0x00
+	ssd1306Data    = 0x40
+
+	ssd1306Width = 128
+)
+
+func init() {
+	register(&Profile{
+		Name:        "ssd1306",
+		Description: "SSD1306 128x64 or 128x32 monochrome OLED display",
+		Addresses:   []uint16{0x3c, 0x3d},
+		Options:     map[string]float64{"height": 64},
+		Outputs: []Output{
+			{
+				Name:        "power",
+				Description: "Turn the display on (initialising it) or off",
+				Type:        OutputBool,
+				Set: func(d Device, v any, opts map[string]float64) error {
+					if v.(bool) {
+						return ssd1306Init(d, opts)
+					}
+					return d.WriteReg(ssd1306Command, 0xae)
+				},
+			},
+			{
+				Name:        "contrast",
+				Description: "Set brightness, 0-255",
+				Type:        OutputNumber,
+				Set: func(d Device, v any, _ map[string]float64) error {
+					c := v.(float64)
+					if c < 0 || c > 255 {
+						return fmt.Errorf("contrast %v out of range 0-255", c)
+					}
+					return d.WriteReg(ssd1306Command, 0x81, byte(c))
+				},
+			},
+			{
+				Name:        "invert",
+				Description: "Invert the display colours",
+				Type:        OutputBool,
+				Set: func(d Device, v any, _ map[string]float64) error {
+					if v.(bool) {
+						return d.WriteReg(ssd1306Command, 0xa7)
+					}
+					return d.WriteReg(ssd1306Command, 0xa6)
+				},
+			},
+			{
+				Name:        "clear",
+				Description: "Blank the display",
+				Type:        OutputNone,
+				Set: func(d Device, _ any, opts map[string]float64) error {
+					return ssd1306Draw(d, opts, nil)
+				},
+			},
+			{
+				Name:        "text",
+				Description: "Show text: 21 characters per line, 8 lines (4 on 128x32), newlines start a line",
+				Type:        OutputString,
+				Set: func(d Device, v any, opts map[string]float64) error {
+					return ssd1306Draw(d, opts, strings.Split(v.(string), "\n"))
+				},
+			},
+		},
+	})
+}
+
+func ssd1306Pages(opts map[string]float64) (int, error) {
+	switch opts["height"] {
+	case 64:
+		return 8, nil
+	case 32:
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("ssd1306: height must be 32 or 64, got %v", opts["height"])
+	}
+}
+
+// ssd1306Init runs the power-up sequence from the datasheet's application
+// note, using horizontal addressing so a frame can be streamed page by page.
+func ssd1306Init(d Device, opts map[string]float64) error {
+	pages, err := ssd1306Pages(opts)
+	if err != nil {
+		return err
+	}
+	mux, comPins := byte(0x3f), byte(0x12)
+	if pages == 4 {
+		mux, comPins = 0x1f, 0x02
+	}
+	// Display off; clock divide; multiplex ratio; display offset 0; start
+	// line 0; charge pump on; horizontal addressing; segment remap; COM scan
+	// descending; COM pins; contrast; pre-charge; VCOMH deselect; display
+	// follows RAM; not inverted; display on.
+	return d.WriteReg(ssd1306Command,
+		0xae, 0xd5, 0x80, 0xa8, mux, 0xd3, 0x00, 0x40, 0x8d, 0x14, 0x20, 0x00,
+		0xa1, 0xc8, 0xda, comPins, 0x81, 0xcf, 0xd9, 0xf1, 0xdb, 0x40, 0xa4, 0xa6, 0xaf)
+}
+
+// ssd1306Draw renders lines of text in the 5x7 font, one line per 8-pixel
+// page, and writes the whole frame.
+func ssd1306Draw(d Device, opts map[string]float64, lines []string) error {
+	pages, err := ssd1306Pages(opts)
+	if err != nil {
+		return err
+	}
+	if len(lines) > pages {
+		return fmt.Errorf("ssd1306: %d lines of text, the display fits %d", len(lines), pages)
+	}
+	if err := d.WriteReg(ssd1306Command, 0x21, 0, ssd1306Width-1, 0x22, 0, byte(pages-1)); err != nil {
+		return fmt.Errorf("ssd1306: set window: %w", err)
+	}
+	for page := range pages {
+		row := make([]byte, ssd1306Width)
+		if page < len(lines) {
+			for i, r := range []rune(lines[page]) {
+				if (i+1)*6 > ssd1306Width {
+					break
+				}
+				copy(row[i*6:], glyph(r))
+			}
+		}
+		if err := d.WriteReg(ssd1306Data, row...); err != nil {
+			return fmt.Errorf("ssd1306: write page %d: %w", page, err)
+		}
+	}
+	return nil
+}
+
+// glyph returns the five columns of r, least significant bit at the top.
+// Characters outside printable ASCII are drawn as '?'.
+func glyph(r rune) []byte {
+	if r < 0x20 || r > 0x7e {
+		r = '?'
+	}
+	i := int(r-0x20) * 5
+	return font5x7[i : i+5]
+}
+
+// font5x7 is the classic 5x7 column font for ASCII 0x20-0x7E.
+var font5x7 = []byte{
+	0x00, 0x00, 0x00, 0x00, 0x00, // ' '
+	0x00, 0x00, 0x5f, 0x00, 0x00, // !
+	0x00, 0x07, 0x00, 0x07, 0x00, // "
+	0x14, 0x7f, 0x14, 0x7f, 0x14, // #
+	0x24, 0x2a, 0x7f, 0x2a, 0x12, // $
+	0x23, 0x13, 0x08, 0x64, 0x62, // %
+	0x36, 0x49, 0x55, 0x22, 0x50, // &
+	0x00, 0x05, 0x03, 0x00, 0x00, // '
+	0x00, 0x1c, 0x22, 0x41, 0x00, // (
+	0x00, 0x41, 0x22, 0x1c, 0x00, // )
+	0x14, 0x08, 0x3e, 0x08, 0x14, // *
+	0x08, 0x08, 0x3e, 0x08, 0x08, // +
+	0x00, 0x50, 0x30, 0x00, 0x00, // ,
+	0x08, 0x08, 0x08, 0x08, 0x08, // -
+	0x00, 0x60, 0x60, 0x00, 0x00, // .
+	0x20, 0x10, 0x08, 0x04, 0x02, // /
+	0x3e, 0x51, 0x49, 0x45, 0x3e, // 0
+	0x00, 0x42, 0x7f, 0x40, 0x00, // 1
+	0x42, 0x61, 0x51, 0x49, 0x46, // 2
+	0x21, 0x41, 0x45, 0x4b, 0x31, // 3
+	0x18, 0x14, 0x12, 0x7f, 0x10, // 4
+	0x27, 0x45, 0x45, 0x45, 0x39, // 5
+	0x3c, 0x4a, 0x49, 0x49, 0x30, // 6
+	0x01, 0x71, 0x09, 0x05, 0x03, // 7
+	0x36, 0x49, 0x49, 0x49, 0x36, // 8
+	0x06, 0x49, 0x49, 0x29, 0x1e, // 9
+	0x00, 0x36, 0x36, 0x00, 0x00, // :
+	0x00, 0x56, 0x36, 0x00, 0x00, // ;
+	0x08, 0x14, 0x22, 0x41, 0x00, // <
+	0x14, 0x14, 0x14, 0x14, 0x14, // =
+	0x00, 0x41, 0x22, 0x14, 0x08, // >
+	0x02, 0x01, 0x51, 0x09, 0x06, // ?
+	0x32, 0x49, 0x79, 0x41, 0x3e, // @
+	0x7e, 0x11, 0x11, 0x11, 0x7e, // A
+	0x7f, 0x49, 0x49, 0x49, 0x36, // B
+	0x3e, 0x41, 0x41, 0x41, 0x22, // C
+	0x7f, 0x41, 0x41, 0x22, 0x1c, // D
+	0x7f, 0x49, 0x49, 0x49, 0x41, // E
+	0x7f, 0x09, 0x09, 0x09, 0x01, // F
+	0x3e, 0x41, 0x49, 0x49, 0x7a, // G
+	0x7f, 0x08, 0x08, 0x08, 0x7f, // H
+	0x00, 0x41, 0x7f, 0x41, 0x00, // I
+	0x20, 0x40, 0x41, 0x3f, 0x01, // J
+	0x7f, 0x08, 0x14, 0x22, 0x41, // K
+	0x7f, 0x40, 0x40, 0x40, 0x40, // L
+	0x7f, 0x02, 0x0c, 0x02, 0x7f, // M
+	0x7f, 0x04, 0x08, 0x10, 0x7f, // N
+	0x3e, 0x41, 0x41, 0x41, 0x3e, // O
+	0x7f, 0x09, 0x09, 0x09, 0x06, // P
+	0x3e, 0x41, 0x51, 0x21, 0x5e, // Q
+	0x7f, 0x09, 0x19, 0x29, 0x46, // R
+	0x46, 0x49, 0x49, 0x49, 0x31, // S
+	0x01, 0x01, 0x7f, 0x01, 0x01, // T
+	0x3f, 0x40, 0x40, 0x40, 0x3f, // U
+	0x1f, 0x20, 0x40, 0x20, 0x1f, // V
+	0x3f, 0x40, 0x38, 0x40, 0x3f, // W
+	0x63, 0x14, 0x08, 0x14, 0x63, // X
+	0x07, 0x08, 0x70, 0x08, 0x07, // Y
+	0x61, 0x51, 0x49, 0x45, 0x43, // Z
+	0x00, 0x7f, 0x41, 0x41, 0x00, // [
+	0x02, 0x04, 0x08, 0x10, 0x20, // \
+	0x00, 0x41, 0x41, 0x7f, 0x00, // ]
+	0x04, 0x02, 0x01, 0x02, 0x04, // ^
+	0x40, 0x40, 0x40, 0x40, 0x40, // _
+	0x00, 0x01, 0x02, 0x04, 0x00, // `
+	0x20, 0x54, 0x54, 0x54, 0x78, // a
+	0x7f, 0x48, 0x44, 0x44, 0x38, // b
+	0x38, 0x44, 0x44, 0x44, 0x20, // c
+	0x38, 0x44, 0x44, 0x48, 0x7f, // d
+	0x38, 0x54, 0x54, 0x54, 0x18, // e
+	0x08, 0x7e, 0x09, 0x01, 0x02, // f
+	0x0c, 0x52, 0x52, 0x52, 0x3e, // g
+	0x7f, 0x08, 0x04, 0x04, 0x78, // h
+	0x00, 0x44, 0x7d, 0x40, 0x00, // i
+	0x20, 0x40, 0x44, 0x3d, 0x00, // j
+	0x7f, 0x10, 0x28, 0x44, 0x00, // k
+	0x00, 0x41, 0x7f, 0x40, 0x00, // l
+	0x7c, 0x04, 0x18, 0x04, 0x78, // m
+	0x7c, 0x08, 0x04, 0x04, 0x78, // n
+	0x38, 0x44, 0x44, 0x44, 0x38, // o
+	0x7c, 0x14, 0x14, 0x14, 0x08, // p
+	0x08, 0x14, 0x14, 0x18, 0x7c, // q
+	0x7c, 0x08, 0x04, 0x04, 0x08, // r
+	0x48, 0x54, 0x54, 0x54, 0x20, // s
+	0x04, 0x3f, 0x44, 0x40, 0x20, // t
+	0x3c, 0x40, 0x40, 0x20, 0x7c, // u
+	0x1c, 0x20, 0x40, 0x20, 0x1c, // v
+	0x3c, 0x40, 0x30, 0x40, 0x3c, // w
+	0x44, 0x28, 0x10, 0x28, 0x44, // x
+	0x0c, 0x50, 0x50, 0x50, 0x3c, // y
+	0x44, 0x64, 0x54, 0x4c, 0x44, // z
+	0x00, 0x08, 0x36, 0x41, 0x00, // {
+	0x00, 0x00, 0x7f, 0x00, 0x00, // |
+	0x00, 0x41, 0x36, 0x08, 0x00, // }
+	0x08, 0x04, 0x08, 0x10, 0x08, // ~
+}
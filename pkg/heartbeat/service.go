@@ -17,6 +17,7 @@ import (
 	"github.com/tinyland-inc/tinyclaw/pkg/bus"
 	"github.com/tinyland-inc/tinyclaw/pkg/constants"
 	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
 	"github.com/tinyland-inc/tinyclaw/pkg/state"
 	"github.com/tinyland-inc/tinyclaw/pkg/tools"
 )
@@ -162,11 +163,13 @@ func (hs *HeartbeatService) executeHeartbeat() {
 	prompt := hs.buildPrompt()
 	if prompt == "" {
 		logger.InfoC("heartbeat", "No heartbeat prompt (HEARTBEAT.md empty or missing)")
+		metrics.HeartbeatRuns.Inc("skipped")
 		return
 	}
 
 	if handler == nil {
 		hs.logErrorf("Heartbeat handler not configured")
+		metrics.HeartbeatRuns.Inc("error")
 		return
 	}
 
@@ -181,16 +184,19 @@ func (hs *HeartbeatService) executeHeartbeat() {
 
 	if result == nil {
 		hs.logInfof("Heartbeat handler returned nil result")
+		metrics.HeartbeatRuns.Inc("ok")
 		return
 	}
 
 	// Handle different result types
 	if result.IsError {
 		hs.logErrorf("Heartbeat error: %s", result.ForLLM)
+		metrics.HeartbeatRuns.Inc("error")
 		return
 	}
 
 	if result.Async {
+		metrics.HeartbeatRuns.Inc("async")
 		hs.logInfof("Async task started: %s", result.ForLLM)
 		logger.InfoCF("heartbeat", "Async heartbeat task started",
 			map[string]any{
@@ -199,6 +205,8 @@ func (hs *HeartbeatService) executeHeartbeat() {
 		return
 	}
 
+	metrics.HeartbeatRuns.Inc("ok")
+
 	// Check if silent
 	if result.Silent {
 		hs.logInfof("Heartbeat OK - silent")
@@ -0,0 +1,587 @@
+// Package journal records the changes filesystem tools make to a workspace
+// so they can be rolled back.
+//
+// Each tool call that changes files becomes an Entry listing its changes.
+// The previous content of every changed file is stored once, as a blob
+// named by its SHA-256, and an index ties entries to the session, turn and
+// tool call that made them. Everything lives under DirName in the
+// workspace.
+package journal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+// DirName is the workspace directory holding the journal.
+const DirName = ".journal"
+
+// Options bound how much history a Journal keeps. Zero values mean no
+// limit.
+type Options struct {
+	MaxBytes int64         // total size of stored snapshots
+	MaxAge   time.Duration // age after which entries are dropped
+}
+
+// ConfigOptions returns the retention limits set in cfg.
+func ConfigOptions(cfg config.JournalConfig) Options {
+	return Options{
+		MaxBytes: int64(cfg.MaxSizeMB) << 20,
+		MaxAge:   time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+	}
+}
+
+// Journal is the change journal of one workspace. It is safe for
+// concurrent use. Every operation rereads the index, and changes to the
+// index and snapshots hold a lock file in the journal directory, so a CLI
+// and a running gateway can share it (on Windows the lock only covers one
+// process).
+type Journal struct {
+	workspace string
+	dir       string
+	opts      Options
+	now       func() time.Time
+
+	mu sync.Mutex
+}
+
+// New returns the journal of workspace. Nothing is written until the first
+// change is recorded.
+func New(workspace string, opts Options) *Journal {
+	return &Journal{
+		workspace: workspace,
+		dir:       filepath.Join(workspace, DirName),
+		opts:      opts,
+		now:       time.Now,
+	}
+}
+
+// Entry is one tool call's changes.
+type Entry struct {
+	ID       int       `json:"id"`
+	Time     time.Time `json:"time"`
+	Session  string    `json:"session,omitempty"`
+	Turn     string    `json:"turn,omitempty"`
+	ToolCall string    `json:"tool_call,omitempty"`
+	Tool     string    `json:"tool"`
+	Changes  []Change  `json:"changes"`
+	Reverted bool      `json:"reverted,omitempty"`
+}
+
+// Change is a single file change. Paths are relative to the workspace, or
+// absolute when outside it. A rename has To set; otherwise Before and After
+// are content hashes, empty when the file did not exist.
+type Change struct {
+	Path   string `json:"path"`
+	To     string `json:"to,omitempty"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Summary describes the entry's changes, e.g. "write_file: modified a.txt".
+func (e Entry) Summary() string {
+	changes := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		changes[i] = c.String()
+	}
+	return e.Tool + ": " + strings.Join(changes, ", ")
+}
+
+// String formats the entry as a history line.
+func (e Entry) String() string {
+	s := fmt.Sprintf("#%d  %s  %s", e.ID, e.Time.Local().Format("2006-01-02 15:04"), e.Summary())
+	if e.Reverted {
+		s += "  (reverted)"
+	}
+	return s
+}
+
+func (c Change) String() string {
+	switch {
+	case c.To != "":
+		return fmt.Sprintf("moved %s to %s", c.Path, c.To)
+	case c.Before == "":
+		return "created " + c.Path
+	case c.After == "":
+		return "deleted " + c.Path
+	default:
+		return "modified " + c.Path
+	}
+}
+
+// index is the on-disk form of the entry list.
+type index struct {
+	NextID  int     `json:"next_id"`
+	Entries []Entry `json:"entries"`
+}
+
+// Call identifies the tool call a change belongs to.
+type Call struct {
+	Session  string
+	Turn     string
+	ToolCall string
+}
+
+type callKey struct{}
+
+// WithCall returns a context carrying call, for tools to attribute their
+// changes.
+func WithCall(ctx context.Context, call Call) context.Context {
+	return context.WithValue(ctx, callKey{}, call)
+}
+
+// CallFrom returns the call stored by WithCall, if any.
+func CallFrom(ctx context.Context) Call {
+	call, _ := ctx.Value(callKey{}).(Call)
+	return call
+}
+
+// Recorder collects the changes of one tool call. A nil Recorder, as
+// returned by a nil Journal, records nothing.
+type Recorder struct {
+	j     *Journal
+	entry Entry
+	err   error
+}
+
+// Begin starts recording the changes tool makes in the call stored in ctx.
+func (j *Journal) Begin(ctx context.Context, tool string) *Recorder {
+	if j == nil {
+		return nil
+	}
+	call := CallFrom(ctx)
+	return &Recorder{j: j, entry: Entry{
+		Session:  call.Session,
+		Turn:     call.Turn,
+		ToolCall: call.ToolCall,
+		Tool:     tool,
+	}}
+}
+
+// Write records that the file at the absolute path was written with after.
+// before is its previous content, if existed.
+func (r *Recorder) Write(path string, before []byte, existed bool, after []byte) {
+	if r == nil {
+		return
+	}
+	c := Change{Path: r.j.relPath(path), After: hash(after)}
+	if existed {
+		c.Before = r.snapshot(before)
+	}
+	r.entry.Changes = append(r.entry.Changes, c)
+}
+
+// Remove records that the file at the absolute path, with content before,
+// was deleted.
+func (r *Recorder) Remove(path string, before []byte) {
+	if r == nil {
+		return
+	}
+	r.entry.Changes = append(r.entry.Changes, Change{Path: r.j.relPath(path), Before: r.snapshot(before)})
+}
+
+// Rename records that the absolute path from was moved to to.
+func (r *Recorder) Rename(from, to string) {
+	if r == nil {
+		return
+	}
+	r.entry.Changes = append(r.entry.Changes, Change{Path: r.j.relPath(from), To: r.j.relPath(to)})
+}
+
+func (r *Recorder) snapshot(data []byte) string {
+	sum, err := r.j.putBlob(data)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return sum
+}
+
+// Commit saves the recorded changes as an entry, if there were any, and
+// applies the retention limits. The changes were made either way; an error
+// means they may not be undoable.
+func (r *Recorder) Commit() error {
+	if r == nil || len(r.entry.Changes) == 0 {
+		return nil
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return r.j.update(func(idx *index) error {
+		r.entry.ID = idx.NextID
+		r.entry.Time = r.j.now()
+		idx.NextID++
+		idx.Entries = append(idx.Entries, r.entry)
+		r.j.prune(idx)
+		return nil
+	})
+}
+
+// Entries returns all entries, oldest first.
+func (j *Journal) Entries() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	idx, err := j.load()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Entries, nil
+}
+
+// Last returns the newest entry of session that has not been reverted,
+// and with wholeTurn every other unreverted entry of its turn.
+func (j *Journal) Last(session string, wholeTurn bool) ([]Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		last := entries[i]
+		if last.Session != session || last.Reverted {
+			continue
+		}
+		if !wholeTurn || last.Turn == "" {
+			return []Entry{last}, nil
+		}
+		return j.turnEntries(entries, last.Turn), nil
+	}
+	return nil, nil
+}
+
+// Turn returns the unreverted entries of the turn entry id belongs to.
+func (j *Journal) Turn(id int) ([]Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID != id {
+			continue
+		}
+		if e.Turn == "" {
+			return []Entry{e}, nil
+		}
+		return j.turnEntries(entries, e.Turn), nil
+	}
+	return nil, fmt.Errorf("no journal entry %d", id)
+}
+
+func (j *Journal) turnEntries(entries []Entry, turn string) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if e.Turn == turn && !e.Reverted {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ConflictError reports files changed since the entries being reverted.
+type ConflictError struct {
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return "changed since they were journaled: " + strings.Join(e.Paths, ", ")
+}
+
+// Revert undoes the entries with the given IDs, newest first, and marks
+// them reverted. Unless force is set it first checks that every file is
+// still as the entries left it, and changes nothing if one is not,
+// returning a *ConflictError.
+func (j *Journal) Revert(ids []int, force bool) ([]Change, error) {
+	var undone []Change
+	err := j.update(func(idx *index) error {
+		want := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			want[id] = true
+		}
+		var targets []*Entry
+		for i := range idx.Entries {
+			if e := &idx.Entries[i]; want[e.ID] {
+				if e.Reverted {
+					return fmt.Errorf("journal entry %d is already reverted", e.ID)
+				}
+				targets = append(targets, e)
+				delete(want, e.ID)
+			}
+		}
+		for id := range want {
+			return fmt.Errorf("no journal entry %d", id)
+		}
+		sort.Slice(targets, func(a, b int) bool { return targets[a].ID > targets[b].ID })
+
+		ws, err := openWorkspace(j.workspace)
+		if err != nil {
+			return err
+		}
+		defer ws.close()
+
+		if !force {
+			if err := j.checkRevert(ws, targets); err != nil {
+				return err
+			}
+		}
+		for _, e := range targets {
+			for i := len(e.Changes) - 1; i >= 0; i-- {
+				if err := j.undo(ws, e.Changes[i], force); err != nil {
+					return fmt.Errorf("reverting entry %d: %w", e.ID, err)
+				}
+				undone = append(undone, e.Changes[i])
+			}
+			e.Reverted = true
+		}
+		return nil
+	})
+	return undone, err
+}
+
+// checkRevert replays the reverts against the current state of the files,
+// tracked as content hashes with "" for absent and "present" for anything
+// that is not a regular file.
+func (j *Journal) checkRevert(ws *workspace, targets []*Entry) error {
+	state := map[string]string{}
+	current := func(path string) string {
+		if s, ok := state[path]; ok {
+			return s
+		}
+		s := ws.state(path)
+		state[path] = s
+		return s
+	}
+
+	conflicts := map[string]bool{}
+	for _, e := range targets {
+		for i := len(e.Changes) - 1; i >= 0; i-- {
+			c := e.Changes[i]
+			if c.To != "" {
+				if current(c.To) == "" || current(c.Path) != "" {
+					conflicts[c.To] = true
+				}
+				state[c.Path], state[c.To] = current(c.To), ""
+				continue
+			}
+			if current(c.Path) != c.After {
+				conflicts[c.Path] = true
+			}
+			state[c.Path] = c.Before
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(conflicts))
+	for p := range conflicts {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return &ConflictError{Paths: paths}
+}
+
+func (j *Journal) undo(ws *workspace, c Change, force bool) error {
+	if c.To != "" {
+		if force && ws.state(c.To) == "" {
+			return nil
+		}
+		if force {
+			ws.removeAll(c.Path)
+		}
+		return ws.rename(c.To, c.Path)
+	}
+	if c.Before == "" {
+		err := ws.removeAll(c.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	data, err := os.ReadFile(j.blobPath(c.Before))
+	if err != nil {
+		return fmt.Errorf("reading snapshot of %s: %w", c.Path, err)
+	}
+	return ws.writeFile(c.Path, data)
+}
+
+// relPath makes an absolute path relative to the workspace when it is
+// inside it.
+func (j *Journal) relPath(path string) string {
+	rel, err := filepath.Rel(j.workspace, path)
+	if err == nil && filepath.IsLocal(rel) {
+		return rel
+	}
+	return path
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (j *Journal) blobPath(sum string) string {
+	return filepath.Join(j.dir, "blobs", sum[:2], sum)
+}
+
+// putBlob stores data under its hash unless it is already there. It holds
+// the lock so a concurrent prune cannot collect the blob in between.
+func (j *Journal) putBlob(data []byte) (string, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	sum := hash(data)
+	path := j.blobPath(sum)
+	if _, err := os.Stat(path); err == nil {
+		// Refresh the time so prune doesn't collect it before the entry
+		// that now uses it is committed.
+		now := j.now()
+		os.Chtimes(path, now, now)
+		return sum, nil
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return "", fmt.Errorf("saving snapshot: %w", err)
+	}
+	return sum, nil
+}
+
+func (j *Journal) indexPath() string {
+	return filepath.Join(j.dir, "index.json")
+}
+
+func (j *Journal) load() (*index, error) {
+	idx := &index{NextID: 1}
+	data, err := os.ReadFile(j.indexPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("parsing journal: %w", err)
+	}
+	return idx, nil
+}
+
+// update loads the index, applies fn and saves the result. The index is
+// saved even when fn fails part way, so completed reverts stay recorded.
+func (j *Journal) update(fn func(idx *index) error) error {
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	idx, err := j.load()
+	if err != nil {
+		return err
+	}
+	fnErr := fn(idx)
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(j.indexPath(), data); err != nil {
+		return fmt.Errorf("saving journal: %w", err)
+	}
+	return fnErr
+}
+
+// lock serializes changes to the journal, within this process through mu
+// and with other processes through the lock file.
+func (j *Journal) lock() (func(), error) {
+	j.mu.Lock()
+	unlock, err := lockFile(filepath.Join(j.dir, "lock"))
+	if err != nil {
+		j.mu.Unlock()
+		return nil, fmt.Errorf("locking journal: %w", err)
+	}
+	return func() {
+		unlock()
+		j.mu.Unlock()
+	}, nil
+}
+
+// prune drops entries past the age limit, then the oldest entries until
+// the snapshots the rest need fit the size limit, and deletes snapshots no
+// entry needs any more. The newest entry is always kept.
+func (j *Journal) prune(idx *index) {
+	keep := len(idx.Entries)
+	var total int64
+	seen := map[string]bool{}
+	for i := len(idx.Entries) - 1; i >= 0; i-- {
+		e := idx.Entries[i]
+		if i < len(idx.Entries)-1 && j.opts.MaxAge > 0 && j.now().Sub(e.Time) > j.opts.MaxAge {
+			break
+		}
+		for _, c := range e.Changes {
+			if c.Before == "" || seen[c.Before] {
+				continue
+			}
+			seen[c.Before] = true
+			if info, err := os.Stat(j.blobPath(c.Before)); err == nil {
+				total += info.Size()
+			}
+		}
+		if i < len(idx.Entries)-1 && j.opts.MaxBytes > 0 && total > j.opts.MaxBytes {
+			break
+		}
+		keep = i
+	}
+	if keep == 0 {
+		return
+	}
+	idx.Entries = append([]Entry(nil), idx.Entries[keep:]...)
+
+	needed := map[string]bool{}
+	for _, e := range idx.Entries {
+		for _, c := range e.Changes {
+			needed[c.Before] = true
+		}
+	}
+	// Recent blobs may belong to a tool call that is still running.
+	blobs := filepath.Join(j.dir, "blobs")
+	filepath.WalkDir(blobs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || needed[d.Name()] {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && j.now().Sub(info.ModTime()) > time.Hour {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// writeAtomic writes data to path through a temporary file, creating
+// parent directories.
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr == nil && cerr == nil {
+		werr = os.Rename(tmp.Name(), path)
+	}
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		return errors.Join(werr, cerr)
+	}
+	return nil
+}
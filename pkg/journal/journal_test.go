@@ -0,0 +1,232 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// write changes a file and records it as a tool would.
+func write(t *testing.T, j *Journal, ctx context.Context, path, content string) {
+	t.Helper()
+	old, err := os.ReadFile(path)
+	existed := err == nil
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := j.Begin(ctx, "write_file")
+	rec.Write(path, old, existed, []byte(content))
+	if err := rec.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevertWriteAndCreate(t *testing.T) {
+	workspace := t.TempDir()
+	j := New(workspace, Options{})
+	ctx := WithCall(context.Background(), Call{Session: "s1", Turn: "t1", ToolCall: "c1"})
+	notes := filepath.Join(workspace, "notes.md")
+	os.WriteFile(notes, []byte("original"), 0o644)
+
+	write(t, j, ctx, notes, "edited")
+	write(t, j, ctx, filepath.Join(workspace, "new.md"), "fresh")
+
+	entries, err := j.Entries()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("entries = %v, %v", entries, err)
+	}
+	if e := entries[0]; e.Session != "s1" || e.Turn != "t1" || e.ToolCall != "c1" || e.Changes[0].Path != "notes.md" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	last, _ := j.Last("s1", false)
+	if len(last) != 1 || last[0].ID != 2 {
+		t.Fatalf("Last = %+v", last)
+	}
+	if _, err := j.Revert([]int{2}, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "new.md")); !os.IsNotExist(err) {
+		t.Errorf("created file survived the revert: %v", err)
+	}
+
+	// The reverted entry is skipped; the next undo reaches the edit.
+	last, _ = j.Last("s1", false)
+	if len(last) != 1 || last[0].ID != 1 {
+		t.Fatalf("Last after revert = %+v", last)
+	}
+	if _, err := j.Revert([]int{1}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, notes); got != "original" {
+		t.Errorf("notes.md = %q", got)
+	}
+	if _, err := j.Revert([]int{1}, false); err == nil {
+		t.Error("expected reverting twice to fail")
+	}
+}
+
+func TestRevertWholeTurn(t *testing.T) {
+	workspace := t.TempDir()
+	j := New(workspace, Options{})
+	a, b := filepath.Join(workspace, "a"), filepath.Join(workspace, "b")
+	turn1 := WithCall(context.Background(), Call{Session: "s", Turn: "t1"})
+	turn2 := WithCall(context.Background(), Call{Session: "s", Turn: "t2"})
+
+	write(t, j, turn1, a, "a1")
+	write(t, j, turn2, a, "a2")
+	write(t, j, turn2, b, "b2")
+	write(t, j, WithCall(context.Background(), Call{Session: "other", Turn: "t3"}), b, "b3")
+
+	entries, _ := j.Last("s", true)
+	if len(entries) != 2 || entries[0].ID != 2 || entries[1].ID != 3 {
+		t.Fatalf("Last(turn) = %+v", entries)
+	}
+	// b was changed again by the other session.
+	var conflict *ConflictError
+	if _, err := j.Revert([]int{2, 3}, false); !errors.As(err, &conflict) || conflict.Paths[0] != "b" {
+		t.Fatalf("expected a conflict on b, got %v", err)
+	}
+	if got := readFile(t, a); got != "a2" {
+		t.Errorf("a conflicting revert changed a: %q", got)
+	}
+
+	if _, err := j.Revert([]int{2, 3}, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, a); got != "a1" {
+		t.Errorf("a = %q, want a1", got)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Errorf("b should be gone again: %v", err)
+	}
+
+	turn, err := j.Turn(1)
+	if err != nil || len(turn) != 1 {
+		t.Errorf("Turn(1) = %+v, %v", turn, err)
+	}
+}
+
+func TestRevertRename(t *testing.T) {
+	workspace := t.TempDir()
+	j := New(workspace, Options{})
+	from, to := filepath.Join(workspace, "dir"), filepath.Join(workspace, ".trash", "1", "dir")
+	os.MkdirAll(from, 0o755)
+	os.WriteFile(filepath.Join(from, "f"), []byte("x"), 0o644)
+	os.MkdirAll(filepath.Dir(to), 0o755)
+	if err := os.Rename(from, to); err != nil {
+		t.Fatal(err)
+	}
+	rec := j.Begin(context.Background(), "delete_file")
+	rec.Rename(from, to)
+	if err := rec.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	undone, err := j.Revert([]int{1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 1 || undone[0].String() != "moved dir to "+filepath.Join(".trash", "1", "dir") {
+		t.Errorf("undone = %v", undone)
+	}
+	if got := readFile(t, filepath.Join(from, "f")); got != "x" {
+		t.Errorf("restored file = %q", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	workspace := t.TempDir()
+	j := New(workspace, Options{MaxBytes: 250, MaxAge: 24 * time.Hour})
+	now := time.Now()
+	j.now = func() time.Time { return now }
+	ctx := context.Background()
+	path := filepath.Join(workspace, "f")
+
+	// Each write snapshots the previous 100-byte content.
+	for i := range 5 {
+		write(t, j, ctx, path, strings.Repeat(string(rune('a'+i)), 100))
+	}
+	entries, _ := j.Entries()
+	if len(entries) != 2 || entries[0].ID != 4 {
+		t.Fatalf("expected the two newest entries within 250 bytes, got %+v", entries)
+	}
+	blobs, _ := filepath.Glob(filepath.Join(workspace, DirName, "blobs", "*", "*"))
+	if len(blobs) != 4 {
+		// Unreferenced blobs are kept for an hour in case a call is still
+		// using them.
+		t.Fatalf("blobs = %d", len(blobs))
+	}
+
+	now = now.Add(48 * time.Hour)
+	write(t, j, ctx, path, "last")
+	entries, _ = j.Entries()
+	if len(entries) != 1 || entries[0].ID != 6 {
+		t.Fatalf("expected only the newest entry after aging, got %+v", entries)
+	}
+	blobs, _ = filepath.Glob(filepath.Join(workspace, DirName, "blobs", "*", "*"))
+	if len(blobs) != 1 {
+		t.Errorf("expected old blobs to be collected, got %d", len(blobs))
+	}
+}
+
+func TestNilJournal(t *testing.T) {
+	var j *Journal
+	rec := j.Begin(context.Background(), "write_file")
+	rec.Write("/x", nil, false, []byte("x"))
+	if err := rec.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharedJournalKeepsEveryEntry(t *testing.T) {
+	// Two Journals on one workspace stand in for a CLI and a gateway; each
+	// opens the lock file separately, as separate processes would.
+	workspace := t.TempDir()
+	journals := []*Journal{New(workspace, Options{}), New(workspace, Options{})}
+
+	const perJournal = 20
+	var wg sync.WaitGroup
+	for n, j := range journals {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perJournal {
+				rec := j.Begin(context.Background(), "write_file")
+				path := filepath.Join(workspace, fmt.Sprintf("f%d-%d.txt", n, i))
+				rec.Write(path, nil, false, []byte("x"))
+				if err := rec.Commit(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := journals[0].Entries()
+	if err != nil || len(entries) != 2*perJournal {
+		t.Fatalf("got %d entries, want %d (%v)", len(entries), 2*perJournal, err)
+	}
+	ids := map[int]bool{}
+	for _, e := range entries {
+		ids[e.ID] = true
+	}
+	if len(ids) != 2*perJournal {
+		t.Fatalf("duplicate entry IDs: %d unique", len(ids))
+	}
+}
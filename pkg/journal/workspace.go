@@ -0,0 +1,105 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// workspace applies reverts. Relative paths go through an os.Root, so a
+// symlink swapped in since the change cannot redirect a revert outside the
+// workspace; absolute paths, recorded when tools were not restricted to
+// the workspace, are used as they are.
+type workspace struct {
+	dir  string
+	root *os.Root
+}
+
+func openWorkspace(dir string) (*workspace, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening workspace: %w", err)
+	}
+	return &workspace{dir: dir, root: root}, nil
+}
+
+func (w *workspace) close() {
+	w.root.Close()
+}
+
+// state describes path for conflict checks: the content hash of a regular
+// file, "present" for anything else, or "" if it does not exist.
+func (w *workspace) state(path string) string {
+	var info fs.FileInfo
+	var err error
+	if filepath.IsAbs(path) {
+		info, err = os.Lstat(path)
+	} else {
+		info, err = w.root.Lstat(path)
+	}
+	if err != nil {
+		return ""
+	}
+	if !info.Mode().IsRegular() {
+		return "present"
+	}
+	var data []byte
+	if filepath.IsAbs(path) {
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = w.root.ReadFile(path)
+	}
+	if err != nil {
+		return "present"
+	}
+	return hash(data)
+}
+
+func (w *workspace) writeFile(path string, data []byte) error {
+	if filepath.IsAbs(path) {
+		return writeAtomic(path, data)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := w.root.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return w.root.WriteFile(path, data, 0o644)
+}
+
+func (w *workspace) removeAll(path string) error {
+	if filepath.IsAbs(path) {
+		return os.RemoveAll(path)
+	}
+	if path == "." {
+		return errors.New("refusing to remove the workspace root")
+	}
+	return w.root.RemoveAll(path)
+}
+
+// rename moves from to to. A move between the workspace and an outside
+// path can only be done by full path.
+func (w *workspace) rename(from, to string) error {
+	if !filepath.IsAbs(from) && !filepath.IsAbs(to) {
+		if dir := filepath.Dir(to); dir != "." {
+			if err := w.root.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+		return w.root.Rename(from, to)
+	}
+	from, to = w.abs(from), w.abs(to)
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+func (w *workspace) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(w.dir, path)
+}
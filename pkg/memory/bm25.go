@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters (Robertson/Sparck Jones defaults).
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopwords are dropped from BM25 terms; they carry no retrieval signal.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "do": true, "for": true, "from": true, "has": true,
+	"have": true, "i": true, "in": true, "is": true, "it": true, "me": true,
+	"my": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "with": true,
+	"you": true, "your": true,
+}
+
+// lexicalIndex is an in-memory BM25 index over chunk texts.
+type lexicalIndex struct {
+	docs   []map[string]int // term frequencies per document
+	lens   []int
+	avgLen float64
+	df     map[string]int
+}
+
+func newLexicalIndex(chunks []Chunk) *lexicalIndex {
+	idx := &lexicalIndex{
+		docs: make([]map[string]int, len(chunks)),
+		lens: make([]int, len(chunks)),
+		df:   make(map[string]int),
+	}
+	total := 0
+	for i, c := range chunks {
+		tf := make(map[string]int)
+		terms := tokenize(c.embedText())
+		for _, t := range terms {
+			tf[t]++
+		}
+		for t := range tf {
+			idx.df[t]++
+		}
+		idx.docs[i] = tf
+		idx.lens[i] = len(terms)
+		total += len(terms)
+	}
+	if len(chunks) > 0 {
+		idx.avgLen = float64(total) / float64(len(chunks))
+	}
+	return idx
+}
+
+// scores returns the BM25 score of every document for query.
+func (idx *lexicalIndex) scores(query string) []float64 {
+	out := make([]float64, len(idx.docs))
+	if idx.avgLen == 0 {
+		return out
+	}
+	n := float64(len(idx.docs))
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		df := idx.df[term]
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+		for i, tf := range idx.docs {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(idx.lens[i])/idx.avgLen
+			out[i] += idf * f * (bm25K1 + 1) / (f + bm25K1*norm)
+		}
+	}
+	return out
+}
+
+// tokenize lowercases text and splits it into letter/digit terms. CJK
+// characters become one term each since the script has no word spacing.
+func tokenize(text string) []string {
+	var terms []string
+	var cur strings.Builder
+	emit := func() {
+		if cur.Len() == 0 {
+			return
+		}
+		t := cur.String()
+		cur.Reset()
+		if !stopwords[t] {
+			terms = append(terms, stem(t))
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			emit()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur.WriteRune(r)
+		default:
+			emit()
+		}
+	}
+	emit()
+	return terms
+}
+
+// stem folds simple English plurals so "peanuts" matches "peanut". It is
+// deliberately conservative; anything smarter belongs in embeddings mode.
+func stem(term string) string {
+	switch {
+	case len(term) > 4 && strings.HasSuffix(term, "ies"):
+		return term[:len(term)-3] + "y"
+	case len(term) > 3 && strings.HasSuffix(term, "s") &&
+		!strings.HasSuffix(term, "ss") && !strings.HasSuffix(term, "us") && !strings.HasSuffix(term, "is"):
+		return term[:len(term)-1]
+	}
+	return term
+}
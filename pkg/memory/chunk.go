@@ -0,0 +1,167 @@
+// Package memory indexes the agent's long-term memory (MEMORY.md, daily
+// notes and session summaries) and retrieves the chunks most relevant to a
+// query, either by embedding similarity or by BM25 when no embeddings
+// provider is configured.
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// DefaultChunkSize is the target chunk length in characters.
+const DefaultChunkSize = 800
+
+// Chunk is a retrievable piece of a memory source.
+type Chunk struct {
+	Source  string // workspace-relative path, or "session:<key>" for summaries
+	Heading string // nearest markdown heading above the chunk
+	Text    string
+	Hash    string // content hash, used as the embedding cache key
+}
+
+// embedText is the text sent to the embeddings provider; the heading gives
+// short chunks enough context to be matched on their own.
+func (c Chunk) embedText() string {
+	if c.Heading == "" {
+		return c.Text
+	}
+	return c.Heading + "\n" + c.Text
+}
+
+// ChunkMarkdown splits a markdown document into chunks of at most maxChars,
+// breaking at headings first and blank lines second. Paragraphs longer
+// than maxChars are split at line boundaries, and single lines longer than
+// that are cut.
+func ChunkMarkdown(source, text string, maxChars int) []Chunk {
+	if maxChars <= 0 {
+		maxChars = DefaultChunkSize
+	}
+
+	var chunks []Chunk
+	heading := ""
+	var buf strings.Builder
+
+	flush := func() {
+		body := strings.TrimSpace(buf.String())
+		buf.Reset()
+		if body == "" {
+			return
+		}
+		chunks = append(chunks, newChunk(source, heading, body))
+	}
+
+	for _, para := range splitParagraphs(text) {
+		if h, ok := headingText(para); ok {
+			flush()
+			heading = h
+			// A heading followed directly by text (no blank line) keeps that text.
+			_, rest, _ := strings.Cut(para, "\n")
+			para = strings.TrimSpace(rest)
+			if para == "" {
+				continue
+			}
+		}
+		for _, piece := range splitLong(para, maxChars) {
+			if buf.Len() > 0 && buf.Len()+len(piece)+2 > maxChars {
+				flush()
+			}
+			if buf.Len() > 0 {
+				buf.WriteString("\n\n")
+			}
+			buf.WriteString(piece)
+		}
+	}
+	flush()
+	return chunks
+}
+
+func newChunk(source, heading, text string) Chunk {
+	c := Chunk{Source: source, Heading: heading, Text: text}
+	sum := sha256.Sum256([]byte(c.embedText()))
+	c.Hash = hex.EncodeToString(sum[:16])
+	return c
+}
+
+// splitParagraphs splits text at blank lines.
+func splitParagraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var paras []string
+	var cur []string
+	for line := range strings.SplitSeq(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(cur) > 0 {
+				paras = append(paras, strings.Join(cur, "\n"))
+				cur = cur[:0]
+			}
+			continue
+		}
+		// Headings always start a new paragraph.
+		if len(cur) > 0 && strings.HasPrefix(strings.TrimSpace(line), "#") {
+			paras = append(paras, strings.Join(cur, "\n"))
+			cur = cur[:0]
+		}
+		cur = append(cur, line)
+	}
+	if len(cur) > 0 {
+		paras = append(paras, strings.Join(cur, "\n"))
+	}
+	return paras
+}
+
+// headingText reports whether para starts with a markdown heading and returns its text.
+func headingText(para string) (string, bool) {
+	first, _, _ := strings.Cut(para, "\n")
+	first = strings.TrimSpace(first)
+	level := len(first) - len(strings.TrimLeft(first, "#"))
+	if level == 0 || level > 6 || len(first) == level || first[level] != ' ' {
+		return "", false
+	}
+	return strings.TrimSpace(first[level:]), true
+}
+
+// splitLong splits a paragraph longer than maxChars at line boundaries,
+// cutting individual lines that still don't fit.
+func splitLong(para string, maxChars int) []string {
+	if len(para) <= maxChars {
+		return []string{para}
+	}
+	var out []string
+	var buf strings.Builder
+	for line := range strings.SplitSeq(para, "\n") {
+		for len(line) > maxChars {
+			if buf.Len() > 0 {
+				out = append(out, buf.String())
+				buf.Reset()
+			}
+			cut := cutPoint(line, maxChars)
+			out = append(out, line[:cut])
+			line = line[cut:]
+		}
+		if buf.Len() > 0 && buf.Len()+len(line)+1 > maxChars {
+			out = append(out, buf.String())
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		out = append(out, buf.String())
+	}
+	return out
+}
+
+// cutPoint returns a cut offset <= limit that prefers the last space and
+// never splits a UTF-8 sequence.
+func cutPoint(s string, limit int) int {
+	if i := strings.LastIndexByte(s[:limit], ' '); i > limit/2 {
+		return i + 1
+	}
+	for limit > 0 && s[limit]&0xC0 == 0x80 {
+		limit--
+	}
+	return limit
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChunkMarkdown_SplitsAtHeadings(t *testing.T) {
+	text := `# People
+Alice is the user's sister.
+
+Bob is a coworker.
+
+## Pets
+The cat is called Miso.
+`
+	chunks := ChunkMarkdown("memory/MEMORY.md", text, 800)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2: %+v", len(chunks), chunks)
+	}
+	if chunks[0].Heading != "People" || !strings.Contains(chunks[0].Text, "Alice") || !strings.Contains(chunks[0].Text, "Bob") {
+		t.Errorf("chunk 0 = %+v", chunks[0])
+	}
+	if chunks[1].Heading != "Pets" || chunks[1].Text != "The cat is called Miso." {
+		t.Errorf("chunk 1 = %+v", chunks[1])
+	}
+	if chunks[0].Hash == "" || chunks[0].Hash == chunks[1].Hash {
+		t.Errorf("hashes not set or not distinct: %q %q", chunks[0].Hash, chunks[1].Hash)
+	}
+}
+
+func TestChunkMarkdown_RespectsMaxChars(t *testing.T) {
+	var sb strings.Builder
+	for range 20 {
+		sb.WriteString("This paragraph talks about something moderately interesting.\n\n")
+	}
+	sb.WriteString(strings.Repeat("verylongword ", 50))
+
+	chunks := ChunkMarkdown("x.md", sb.String(), 200)
+	if len(chunks) < 5 {
+		t.Fatalf("expected text to be split, got %d chunks", len(chunks))
+	}
+	for _, c := range chunks {
+		if len(c.Text) > 200 {
+			t.Errorf("chunk exceeds max size (%d): %q", len(c.Text), c.Text)
+		}
+	}
+}
+
+func TestChunkMarkdown_Empty(t *testing.T) {
+	if chunks := ChunkMarkdown("x.md", "\n\n# Only a heading\n\n", 100); len(chunks) != 0 {
+		t.Errorf("expected no chunks, got %+v", chunks)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("The user's ESP32 projects, 天气! Batteries, glass, status.")
+	want := []string{"user", "s", "esp32", "project", "天", "气", "battery", "glass", "status"}
+	if !slices.Equal(got, want) {
+		t.Errorf("tokenize = %q, want %q", got, want)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	scores := []float64{0.1, 0.9, 0.5, 0.9, -0.2}
+	if got := topK(scores, 3, 0); !slices.Equal(got, []int{1, 3, 2}) {
+		t.Errorf("topK = %v, want [1 3 2]", got)
+	}
+	if got := topK(scores, 10, 0.4); !slices.Equal(got, []int{1, 3, 2}) {
+		t.Errorf("topK with min score = %v, want [1 3 2]", got)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	if s := cosineSimilarity([]float32{1, 0}, []float32{2, 0}); s < 0.999 {
+		t.Errorf("parallel vectors: %f", s)
+	}
+	if s := cosineSimilarity([]float32{1, 0}, []float32{0, 1}); s != 0 {
+		t.Errorf("orthogonal vectors: %f", s)
+	}
+	if s := cosineSimilarity([]float32{1, 0}, []float32{1, 0, 0}); s != 0 {
+		t.Errorf("dimension mismatch: %f", s)
+	}
+	if s := cosineSimilarity([]float32{0, 0}, []float32{1, 0}); s != 0 {
+		t.Errorf("zero vector: %f", s)
+	}
+}
@@ -0,0 +1,478 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
+)
+
+const (
+	// DefaultTopK is the number of chunks retrieved per query.
+	DefaultTopK = 5
+
+	embedBatchSize = 64
+	// embedRetryDelay throttles re-embedding after a provider failure so an
+	// unreachable endpoint doesn't add latency to every message.
+	embedRetryDelay = 5 * time.Minute
+	// minCosineScore filters out unrelated chunks in embedding mode.
+	minCosineScore = 0.2
+)
+
+// Embedder turns texts into embedding vectors.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+}
+
+type providerEmbedder struct {
+	provider providers.EmbeddingProvider
+	model    string
+}
+
+// NewProviderEmbedder adapts an embeddings-capable provider to Embedder.
+func NewProviderEmbedder(provider providers.EmbeddingProvider, model string) Embedder {
+	return &providerEmbedder{provider: provider, model: model}
+}
+
+func (e *providerEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.provider.Embed(ctx, texts, e.model)
+}
+
+func (e *providerEmbedder) Model() string {
+	return e.model
+}
+
+// Result is a retrieved chunk with its relevance score.
+type Result struct {
+	Chunk
+	Score float64
+}
+
+// Index is a retrieval index over a workspace's memory sources:
+// memory/MEMORY.md, daily notes under memory/YYYYMM/ and, if enabled,
+// session summaries.
+// It re-reads a source file only when its size or mtime changes, and
+// re-chunks it only when its indexed content (a session's summary rather
+// than the whole session file) changed. Embeddings are cached by content
+// hash in memory/.index so unchanged chunks are never re-embedded.
+type Index struct {
+	memoryDir   string
+	sessionsDir string // empty when session summaries are not indexed; ReadDir("") fails
+	cachePath   string
+	embedder    Embedder
+	chunkSize   int
+
+	mu          sync.Mutex
+	files       map[string]*indexedFile // by path
+	fingerprint string
+	chunks      []Chunk
+	vectors     [][]float32 // parallel to chunks; nil when embeddings are unavailable
+	lexical     *lexicalIndex
+	cache       map[string][]float32
+	cacheLoaded bool
+	retryAfter  time.Time
+	embedding   bool // a refresh is embedding chunks
+}
+
+// NewIndex creates an index for workspace. A nil embedder selects BM25-only
+// retrieval. Session summaries are indexed only when sessions is true;
+// they come from every chat and API key using the workspace.
+func NewIndex(workspace string, embedder Embedder, sessions bool) *Index {
+	memoryDir := filepath.Join(workspace, "memory")
+	sessionsDir := ""
+	if sessions {
+		sessionsDir = filepath.Join(workspace, "sessions")
+	}
+	return &Index{
+		memoryDir:   memoryDir,
+		sessionsDir: sessionsDir,
+		cachePath:   filepath.Join(memoryDir, ".index", "embeddings.json"),
+		embedder:    embedder,
+		chunkSize:   DefaultChunkSize,
+		cache:       make(map[string][]float32),
+	}
+}
+
+// Mode returns "embeddings" or "bm25", describing how queries are currently answered.
+func (idx *Index) Mode() string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.vectors != nil {
+		return "embeddings"
+	}
+	return "bm25"
+}
+
+// Search returns up to k chunks relevant to query, best first. Embedding
+// calls are made without holding the index lock, so a slow endpoint does
+// not block memory writes or other searches.
+func (idx *Index) Search(ctx context.Context, query string, k int) ([]Result, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	if k <= 0 {
+		k = DefaultTopK
+	}
+
+	idx.refresh(ctx)
+
+	idx.mu.Lock()
+	chunks, vectors, lexical := idx.chunks, idx.vectors, idx.lexical
+	idx.mu.Unlock()
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	if vectors != nil && ctx.Err() == nil {
+		qv, err := idx.embedder.Embed(ctx, []string{query})
+		if err == nil && len(qv) == 1 {
+			return results(chunks, batchCosineSimilarity(qv[0], vectors), k, minCosineScore), nil
+		}
+		logger.WarnCF("memory", "Query embedding failed, using BM25", map[string]any{"error": fmt.Sprint(err)})
+	}
+	return results(chunks, lexical.scores(query), k, 0), nil
+}
+
+func results(chunks []Chunk, scores []float64, k int, minScore float64) []Result {
+	top := topK(scores, k, minScore)
+	out := make([]Result, len(top))
+	for i, j := range top {
+		out[i] = Result{Chunk: chunks[j], Score: scores[j]}
+	}
+	return out
+}
+
+// Write appends content to long-term memory (target "long_term") or to
+// today's daily note (target "daily"). The index picks the change up on
+// the next search.
+func (idx *Index) Write(target, content string) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return errors.New("content is empty")
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	switch target {
+	case "long_term", "":
+		return AppendLongTerm(idx.memoryDir, content)
+	case "daily":
+		return AppendDailyNote(idx.memoryDir, content, time.Now())
+	default:
+		return fmt.Errorf("unknown memory target %q (want long_term or daily)", target)
+	}
+}
+
+// sourceFile is one indexed file and its stat fingerprint.
+type sourceFile struct {
+	path  string // absolute
+	rel   string // source name shown in results
+	stamp string
+}
+
+// listSources returns every memory source currently on disk, in a stable order.
+func (idx *Index) listSources() []sourceFile {
+	var files []sourceFile
+	add := func(path, rel string) {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			return
+		}
+		files = append(files, sourceFile{
+			path:  path,
+			rel:   rel,
+			stamp: fmt.Sprintf("%s:%d:%d", rel, info.Size(), info.ModTime().UnixNano()),
+		})
+	}
+
+	add(filepath.Join(idx.memoryDir, LongTermFile), filepath.ToSlash(filepath.Join("memory", LongTermFile)))
+
+	_ = filepath.WalkDir(idx.memoryDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != idx.memoryDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Dir(path) == idx.memoryDir || !strings.HasSuffix(d.Name(), ".md") {
+			return nil
+		}
+		rel, _ := filepath.Rel(filepath.Dir(idx.memoryDir), path)
+		add(path, filepath.ToSlash(rel))
+		return nil
+	})
+
+	if entries, err := os.ReadDir(idx.sessionsDir); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+				add(filepath.Join(idx.sessionsDir, e.Name()), "session:"+strings.TrimSuffix(e.Name(), ".json"))
+			}
+		}
+	}
+
+	slices.SortFunc(files, func(a, b sourceFile) int { return strings.Compare(a.rel, b.rel) })
+	return files
+}
+
+// refresh re-chunks the sources if any of them changed and embeds chunks
+// missing from the cache. Embedding failures leave the index in BM25 mode.
+func (idx *Index) refresh(ctx context.Context) {
+	idx.mu.Lock()
+	idx.rechunkLocked()
+	embed := idx.embedder != nil && idx.vectors == nil && !idx.embedding &&
+		len(idx.chunks) > 0 && time.Now().After(idx.retryAfter)
+	if embed {
+		idx.embedding = true
+	}
+	idx.mu.Unlock()
+	if !embed {
+		return
+	}
+
+	err := idx.embedChunks(ctx)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.embedding = false
+	if err != nil {
+		// A cancelled request is not the provider's fault; retry on the next search.
+		if ctx.Err() == nil {
+			idx.retryAfter = time.Now().Add(embedRetryDelay)
+		}
+		logger.WarnCF("memory", "Embedding memory chunks failed, falling back to BM25",
+			map[string]any{"error": err.Error(), "chunks": len(idx.chunks)})
+	}
+}
+
+// indexedFile is what the index last read from one source file.
+type indexedFile struct {
+	stamp  string // stat fingerprint when it was read
+	digest string // hash of the indexed content
+	chunks []Chunk
+}
+
+// rechunkLocked re-reads the source files whose stat changed and rebuilds
+// the index if any indexed content changed. Vectors are kept when every
+// chunk is already embedded. Caller must hold idx.mu.
+func (idx *Index) rechunkLocked() {
+	files := idx.listSources()
+	next := make(map[string]*indexedFile, len(files))
+	digests := make([]string, len(files))
+	for i, f := range files {
+		cur := idx.files[f.path]
+		if cur == nil || cur.stamp != f.stamp {
+			cur = idx.readFile(f, cur)
+		}
+		next[f.path] = cur
+		digests[i] = f.rel + ":" + cur.digest
+	}
+	idx.files = next
+	fingerprint := strings.Join(digests, "\n")
+	if fingerprint == idx.fingerprint {
+		return
+	}
+
+	var chunks []Chunk
+	for _, f := range files {
+		chunks = append(chunks, next[f.path].chunks...)
+	}
+	idx.chunks = chunks
+	idx.lexical = newLexicalIndex(chunks)
+	idx.vectors = idx.cachedVectorsLocked()
+	idx.fingerprint = fingerprint
+}
+
+// readFile reads f and chunks its indexed content, reusing prev's chunks
+// when that content is unchanged.
+func (idx *Index) readFile(f sourceFile, prev *indexedFile) *indexedFile {
+	source, text := idx.indexedContent(f)
+	sum := sha256.Sum256([]byte(source + "\x00" + text))
+	out := &indexedFile{stamp: f.stamp, digest: hex.EncodeToString(sum[:16])}
+	if prev != nil && prev.digest == out.digest {
+		out.chunks = prev.chunks
+	} else if strings.TrimSpace(text) != "" {
+		out.chunks = ChunkMarkdown(source, text, idx.chunkSize)
+	}
+	return out
+}
+
+// indexedContent returns the source name and text indexed for f: the
+// whole file for notes, the summary for sessions.
+func (idx *Index) indexedContent(f sourceFile) (source, text string) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.rel, ""
+	}
+	if !strings.HasPrefix(f.rel, "session:") {
+		return f.rel, string(data)
+	}
+
+	var s struct {
+		Key     string `json:"key"`
+		Summary string `json:"summary"`
+	}
+	if json.Unmarshal(data, &s) != nil {
+		return f.rel, ""
+	}
+	if s.Key != "" {
+		return "session:" + s.Key, s.Summary
+	}
+	return f.rel, s.Summary
+}
+
+// cachedVectorsLocked returns vectors for idx.chunks from the embedding
+// cache, or nil if any chunk still needs embedding. Caller must hold idx.mu.
+func (idx *Index) cachedVectorsLocked() [][]float32 {
+	if idx.embedder == nil || !idx.cacheLoaded || len(idx.chunks) == 0 {
+		return nil
+	}
+	vectors := make([][]float32, len(idx.chunks))
+	for i, c := range idx.chunks {
+		v, ok := idx.cache[c.Hash]
+		if !ok {
+			return nil
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+// embedChunks fills idx.vectors, embedding only chunks whose hash is not
+// cached yet. The lock is released while the embedder is called; if the
+// sources change meanwhile the new vectors are cached but not installed.
+func (idx *Index) embedChunks(ctx context.Context) error {
+	idx.mu.Lock()
+	idx.loadCacheLocked()
+	fingerprint, chunks := idx.fingerprint, idx.chunks
+	var missing []Chunk
+	for _, c := range chunks {
+		if _, ok := idx.cache[c.Hash]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	idx.mu.Unlock()
+
+	embedded := make(map[string][]float32, len(missing))
+	for start := 0; start < len(missing); start += embedBatchSize {
+		batch := missing[start:min(start+embedBatchSize, len(missing))]
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = c.embedText()
+		}
+		vecs, err := idx.embedder.Embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vecs) != len(batch) {
+			return fmt.Errorf("embedder returned %d vectors for %d texts", len(vecs), len(batch))
+		}
+		for i, c := range batch {
+			embedded[c.Hash] = vecs[i]
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for hash, v := range embedded {
+		idx.cache[hash] = v
+	}
+	if idx.fingerprint != fingerprint {
+		return nil
+	}
+
+	vectors := make([][]float32, len(idx.chunks))
+	for i, c := range idx.chunks {
+		vectors[i] = idx.cache[c.Hash]
+	}
+	idx.vectors = vectors
+
+	if len(missing) > 0 {
+		idx.saveCacheLocked()
+	}
+	return nil
+}
+
+type embeddingCache struct {
+	Model   string               `json:"model"`
+	Vectors map[string][]float32 `json:"vectors"`
+}
+
+func (idx *Index) loadCacheLocked() {
+	if idx.cacheLoaded {
+		return
+	}
+	idx.cacheLoaded = true
+
+	data, err := os.ReadFile(idx.cachePath)
+	if err != nil {
+		return
+	}
+	var c embeddingCache
+	if json.Unmarshal(data, &c) != nil || c.Model != idx.embedder.Model() || c.Vectors == nil {
+		return
+	}
+	idx.cache = c.Vectors
+}
+
+// saveCacheLocked persists vectors for the current chunks only, dropping
+// entries for content that no longer exists.
+func (idx *Index) saveCacheLocked() {
+	live := make(map[string][]float32, len(idx.chunks))
+	for _, c := range idx.chunks {
+		if v, ok := idx.cache[c.Hash]; ok {
+			live[c.Hash] = v
+		}
+	}
+	idx.cache = live
+
+	data, err := json.Marshal(embeddingCache{Model: idx.embedder.Model(), Vectors: live})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.cachePath), 0o755); err != nil {
+		return
+	}
+	tmp := idx.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, idx.cachePath); err != nil {
+		logger.WarnCF("memory", "Failed to save embedding cache", map[string]any{"error": err.Error()})
+	}
+}
+
+// FormatResults renders results as a markdown block for the system prompt.
+func FormatResults(results []Result) string {
+	var sb strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString("### ")
+		sb.WriteString(r.Source)
+		if r.Heading != "" {
+			sb.WriteString(" — ")
+			sb.WriteString(r.Heading)
+		}
+		sb.WriteString("\n")
+		sb.WriteString(r.Text)
+	}
+	return sb.String()
+}
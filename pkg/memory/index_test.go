@@ -0,0 +1,311 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// keywordEmbedder embeds texts as keyword-presence vectors, which is enough
+// to make cosine similarity behave like a real embedding for tests.
+type keywordEmbedder struct {
+	keywords []string
+	calls    int
+	texts    int
+	err      error
+}
+
+func (e *keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.texts += len(texts)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(e.keywords)+1)
+		v[len(e.keywords)] = 0.1 // avoid zero vectors
+		for j, kw := range e.keywords {
+			if strings.Contains(strings.ToLower(text), kw) {
+				v[j] = 1
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (e *keywordEmbedder) Model() string {
+	return "keywords"
+}
+
+func setupMemory(t *testing.T) string {
+	t.Helper()
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "memory", "MEMORY.md"), `# Preferences
+
+The user prefers dark roast coffee in the morning.
+
+# Projects
+
+The user is building a weather station with an ESP32 and a BME280 sensor.
+`)
+	writeFile(t, filepath.Join(ws, "memory", "202601", "20260105.md"), `# 2026-01-05
+
+Went hiking in the mountains; planning a trip to Norway in June.
+`)
+	writeFile(t, filepath.Join(ws, "sessions", "telegram_123.json"),
+		`{"key":"telegram:123","summary":"Discussed the garden irrigation timer and tomato plants."}`)
+	return ws
+}
+
+func TestIndex_SearchBM25(t *testing.T) {
+	idx := NewIndex(setupMemory(t), nil, true)
+
+	results, err := idx.Search(t.Context(), "what coffee does the user like?", 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) == 0 || !strings.Contains(results[0].Text, "dark roast") {
+		t.Fatalf("expected coffee chunk first, got %+v", results)
+	}
+	if results[0].Source != "memory/MEMORY.md" || results[0].Heading != "Preferences" {
+		t.Errorf("unexpected source/heading: %q / %q", results[0].Source, results[0].Heading)
+	}
+
+	results, _ = idx.Search(t.Context(), "Norway trip", 1)
+	if len(results) != 1 || results[0].Source != "memory/202601/20260105.md" {
+		t.Errorf("expected daily note, got %+v", results)
+	}
+
+	results, _ = idx.Search(t.Context(), "tomato irrigation", 1)
+	if len(results) != 1 || results[0].Source != "session:telegram:123" {
+		t.Errorf("expected session summary, got %+v", results)
+	}
+
+	if idx.Mode() != "bm25" {
+		t.Errorf("Mode() = %q, want bm25", idx.Mode())
+	}
+}
+
+func TestIndex_SearchNoMatch(t *testing.T) {
+	idx := NewIndex(setupMemory(t), nil, true)
+	results, err := idx.Search(t.Context(), "quantum chromodynamics", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no results, got %+v", results)
+	}
+}
+
+func TestIndex_SearchEmbeddings(t *testing.T) {
+	ws := setupMemory(t)
+	emb := &keywordEmbedder{keywords: []string{"coffee", "esp32", "norway", "tomato"}}
+	idx := NewIndex(ws, emb, true)
+
+	results, err := idx.Search(t.Context(), "coffee", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Text, "dark roast") {
+		t.Fatalf("expected coffee chunk, got %+v", results)
+	}
+	if idx.Mode() != "embeddings" {
+		t.Errorf("Mode() = %q, want embeddings", idx.Mode())
+	}
+	chunkTexts := emb.texts - 1 // minus the query
+
+	// A second index over the same workspace reuses the persisted cache:
+	// only the query needs embedding.
+	emb2 := &keywordEmbedder{keywords: emb.keywords}
+	idx2 := NewIndex(ws, emb2, true)
+	if _, err := idx2.Search(t.Context(), "esp32", 1); err != nil {
+		t.Fatal(err)
+	}
+	if emb2.texts != 1 {
+		t.Errorf("expected only the query to be embedded, got %d texts (index has %d chunks)", emb2.texts, chunkTexts)
+	}
+}
+
+func TestIndex_EmbeddingFailureFallsBackToBM25(t *testing.T) {
+	emb := &keywordEmbedder{err: errors.New("connection refused")}
+	idx := NewIndex(setupMemory(t), emb, true)
+
+	results, err := idx.Search(t.Context(), "coffee", 1)
+	if err != nil {
+		t.Fatalf("Search should fall back, got error: %v", err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Text, "dark roast") {
+		t.Fatalf("expected BM25 result, got %+v", results)
+	}
+
+	// Failed provider is not retried on every search.
+	calls := emb.calls
+	idx.Search(t.Context(), "norway", 1)
+	if emb.calls != calls {
+		t.Errorf("embedder called again within retry delay")
+	}
+}
+
+// stallingEmbedder blocks every call until its context is cancelled.
+type stallingEmbedder struct{ started chan struct{} }
+
+func (e *stallingEmbedder) Embed(ctx context.Context, _ []string) ([][]float32, error) {
+	e.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (e *stallingEmbedder) Model() string {
+	return "stalling"
+}
+
+func TestIndex_SlowEmbedderDoesNotHoldLock(t *testing.T) {
+	emb := &stallingEmbedder{started: make(chan struct{}, 1)}
+	idx := NewIndex(setupMemory(t), emb, true)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan []Result)
+	go func() {
+		results, _ := idx.Search(ctx, "coffee", 1)
+		done <- results
+	}()
+	<-emb.started
+
+	written := make(chan error)
+	go func() { written <- idx.Write("daily", "Bought more coffee.") }()
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Write blocked while the embedder was running")
+	}
+
+	cancel()
+	select {
+	case results := <-done:
+		if len(results) != 1 {
+			t.Errorf("expected the BM25 fallback after cancel, got %+v", results)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Search did not return after its context was cancelled")
+	}
+}
+
+func TestIndex_WriteIsSearchable(t *testing.T) {
+	ws := t.TempDir()
+	idx := NewIndex(ws, nil, true)
+
+	if err := idx.Write("long_term", "The user's cat is called Miso."); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Write("daily", "Fixed the bike chain."); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Write("elsewhere", "x"); err == nil {
+		t.Error("expected error for unknown target")
+	}
+	if err := idx.Write("daily", "   "); err == nil {
+		t.Error("expected error for empty content")
+	}
+
+	results, _ := idx.Search(t.Context(), "cat name", 1)
+	if len(results) != 1 || !strings.Contains(results[0].Text, "Miso") {
+		t.Errorf("long-term write not found: %+v", results)
+	}
+
+	daily, err := os.ReadFile(DailyNotePath(filepath.Join(ws, "memory"), time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(daily), "# "+time.Now().Format("2006-01-02")) {
+		t.Errorf("daily note missing date header: %q", daily)
+	}
+	results, _ = idx.Search(t.Context(), "bike", 1)
+	if len(results) != 1 || !strings.Contains(results[0].Text, "bike chain") {
+		t.Errorf("daily write not found: %+v", results)
+	}
+}
+
+func TestIndex_IgnoresCacheDirectory(t *testing.T) {
+	ws := setupMemory(t)
+	writeFile(t, filepath.Join(ws, "memory", ".index", "notes.md"), "coffee coffee coffee")
+
+	idx := NewIndex(ws, nil, true)
+	results, _ := idx.Search(t.Context(), "coffee", 5)
+	for _, r := range results {
+		if strings.Contains(r.Source, ".index") {
+			t.Errorf("hidden directory was indexed: %s", r.Source)
+		}
+	}
+}
+
+func TestIndex_RechunksOnlyChangedSummaries(t *testing.T) {
+	ws := setupMemory(t)
+	emb := &keywordEmbedder{keywords: []string{"coffee", "esp32", "norway", "tomato", "bicycle"}}
+	idx := NewIndex(ws, emb, true)
+	if _, err := idx.Search(t.Context(), "coffee", 1); err != nil {
+		t.Fatal(err)
+	}
+	session := filepath.Join(ws, "sessions", "telegram_123.json")
+
+	// A turn rewrites the session file without touching its summary.
+	texts, lexical := emb.texts, idx.lexical
+	writeFile(t, session, `{"key":"telegram:123","summary":"Discussed the garden irrigation timer and tomato plants.","messages":[{"role":"user","content":"hi"}]}`)
+	if _, err := idx.Search(t.Context(), "tomato", 1); err != nil {
+		t.Fatal(err)
+	}
+	if idx.lexical != lexical {
+		t.Error("index rebuilt after an unchanged summary")
+	}
+	if emb.texts != texts+1 {
+		t.Errorf("embedded %d texts after an unchanged summary, want only the query", emb.texts-texts)
+	}
+	if idx.Mode() != "embeddings" {
+		t.Errorf("Mode() = %q, want embeddings", idx.Mode())
+	}
+
+	// A new summary embeds its own chunk and keeps the other vectors.
+	texts = emb.texts
+	writeFile(t, session, `{"key":"telegram:123","summary":"Planned a bicycle tour."}`)
+	results, err := idx.Search(t.Context(), "bicycle", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Source != "session:telegram:123" {
+		t.Fatalf("expected the new summary, got %+v", results)
+	}
+	if emb.texts != texts+2 {
+		t.Errorf("embedded %d texts after a summary change, want the new chunk and the query", emb.texts-texts)
+	}
+}
+
+func TestIndex_SessionsOptIn(t *testing.T) {
+	idx := NewIndex(setupMemory(t), nil, false)
+	results, err := idx.Search(t.Context(), "tomato irrigation", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if strings.HasPrefix(r.Source, "session:") {
+			t.Errorf("session summary indexed without opting in: %+v", r)
+		}
+	}
+}
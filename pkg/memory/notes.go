@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LongTermFile is the long-term memory file inside the memory directory.
+const LongTermFile = "MEMORY.md"
+
+// DailyNotePath returns the daily note path for t (memoryDir/YYYYMM/YYYYMMDD.md).
+func DailyNotePath(memoryDir string, t time.Time) string {
+	day := t.Format("20060102")
+	return filepath.Join(memoryDir, day[:6], day+".md")
+}
+
+// AppendDailyNote appends content to the daily note for t, creating it with
+// a date header if it doesn't exist yet.
+func AppendDailyNote(memoryDir, content string, t time.Time) error {
+	path := DailyNotePath(memoryDir, t)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var newContent string
+	if len(existing) == 0 {
+		newContent = fmt.Sprintf("# %s\n\n", t.Format("2006-01-02")) + content
+	} else {
+		newContent = string(existing) + "\n" + content
+	}
+	return os.WriteFile(path, []byte(newContent), 0o644)
+}
+
+// AppendLongTerm appends content to MEMORY.md as a new paragraph.
+func AppendLongTerm(memoryDir, content string) error {
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(memoryDir, LongTermFile)
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.Write(existing)
+	if len(existing) > 0 {
+		if !strings.HasSuffix(string(existing), "\n") {
+			sb.WriteByte('\n')
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(strings.TrimSpace(content))
+	sb.WriteByte('\n')
+	return os.WriteFile(path, []byte(sb.String()), 0o644)
+}
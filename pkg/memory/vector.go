@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0
+// when either is a zero vector or the dimensions differ.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// batchCosineSimilarity scores query against every candidate
+// (batch_cosine_similarity in futhark/src/similarity.fut).
+func batchCosineSimilarity(query []float32, candidates [][]float32) []float64 {
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		scores[i] = cosineSimilarity(query, c)
+	}
+	return scores
+}
+
+// topK returns the indices of the k highest scores in descending order,
+// skipping scores <= minScore. Ties keep index order
+// (top_k_similar in futhark/src/similarity.fut).
+func topK(scores []float64, k int, minScore float64) []int {
+	idx := make([]int, 0, len(scores))
+	for i, s := range scores {
+		if s > minScore {
+			idx = append(idx, i)
+		}
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	if k > 0 && len(idx) > k {
+		idx = idx[:k]
+	}
+	return idx
+}
@@ -0,0 +1,243 @@
+// Package metrics is a small, dependency-free metrics registry that serves
+// the Prometheus text exposition format.
+//
+// Event metrics (counters and histograms) are package-level variables that
+// subsystems update directly. State that already lives elsewhere, such as
+// queue depths or cooldowns, is exported with GaugeFunc callbacks that are
+// evaluated at scrape time.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// labelSep joins label values into series keys; it cannot appear in UTF-8 text.
+const labelSep = "\xff"
+
+type sample struct {
+	suffix string
+	labels []string // alternating name, value
+	value  float64
+}
+
+type collector interface {
+	describe() *desc
+	collect() []sample
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d *desc) describe() *desc { return d }
+
+// labelPairs zips the metric's label names with the values of one series.
+func (d *desc) labelPairs(values []string) []string {
+	pairs := make([]string, 0, 2*len(d.labels))
+	for i, name := range d.labels {
+		pairs = append(pairs, name, values[i])
+	}
+	return pairs
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, labelSep)
+}
+
+func splitKey(key string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	return strings.SplitN(key, labelSep, n)
+}
+
+// CounterVec is a monotonically increasing value per label combination.
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Inc adds one to the series identified by labelValues.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (which must not be negative) to the series identified by labelValues.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of a series.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) collect() []sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]sample, 0, len(c.values))
+	for key, v := range c.values {
+		out = append(out, sample{labels: c.labelPairs(splitKey(key, len(c.labels))), value: v})
+	}
+	return out
+}
+
+// GaugeVec is a value per label combination that can go up and down.
+type GaugeVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Set sets the series identified by labelValues to v.
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	g.values[key] = v
+	g.mu.Unlock()
+}
+
+// Add adds v (which may be negative) to the series identified by labelValues.
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	g.values[key] += v
+	g.mu.Unlock()
+}
+
+// Value returns the current value of a series.
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[key]
+}
+
+func (g *GaugeVec) collect() []sample {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]sample, 0, len(g.values))
+	for key, v := range g.values {
+		out = append(out, sample{labels: g.labelPairs(splitKey(key, len(g.labels))), value: v})
+	}
+	return out
+}
+
+// gaugeFunc evaluates a callback at scrape time.
+type gaugeFunc struct {
+	desc
+	fn func(emit func(v float64, labelValues ...string))
+}
+
+func (g *gaugeFunc) collect() []sample {
+	var out []sample
+	g.fn(func(v float64, labelValues ...string) {
+		g.key(labelValues) // validates the label count
+		out = append(out, sample{labels: g.labelPairs(labelValues), value: v})
+	})
+	return out
+}
+
+// HistogramVec counts observations into cumulative buckets per label combination.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Observe records v in the series identified by labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations in a series.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s := h.series[key]; s != nil {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) collect() []sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []sample
+	for key, s := range h.series {
+		labels := h.labelPairs(splitKey(key, len(h.labels)))
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			out = append(out, sample{
+				suffix: "_bucket",
+				labels: append(slices.Clone(labels), "le", formatFloat(le)),
+				value:  float64(cumulative),
+			})
+		}
+		out = append(out,
+			sample{suffix: "_bucket", labels: append(slices.Clone(labels), "le", "+Inf"), value: float64(s.count)},
+			sample{suffix: "_sum", labels: labels, value: s.sum},
+			sample{suffix: "_count", labels: labels, value: float64(s.count)},
+		)
+	}
+	return out
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", v)
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_requests_total", "Requests.", "channel")
+	c.Inc("telegram")
+	c.Add(2, "discord")
+	c.Add(-1, "discord") // counters never decrease
+
+	g := r.NewGauge("test_temperature", "Line one\nline two.")
+	g.Set(21.5)
+
+	r.GaugeFunc("test_queue_depth", "Queue depth.", []string{"queue"}, func(emit func(float64, ...string)) {
+		emit(3, "inbound")
+		emit(0, `out"bound`)
+	})
+
+	r.NewCounter("test_unused_total", "Never incremented.", "kind")
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_queue_depth Queue depth.
+# TYPE test_queue_depth gauge
+test_queue_depth{queue="inbound"} 3
+test_queue_depth{queue="out\"bound"} 0
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{channel="discord"} 2
+test_requests_total{channel="telegram"} 1
+# HELP test_temperature Line one\nline two.
+# TYPE test_temperature gauge
+test_temperature 21.5
+`
+	if got := sb.String(); got != want {
+		t.Errorf("WriteText =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("test_seconds", "Latency.", []float64{0.1, 1}, "op")
+	h.Observe(0.05, "a")
+	h.Observe(0.1, "a")
+	h.Observe(5, "a")
+	h.Observe(0.5, "b")
+
+	var sb strings.Builder
+	r.WriteText(&sb)
+	want := `# HELP test_seconds Latency.
+# TYPE test_seconds histogram
+test_seconds_bucket{op="a",le="0.1"} 2
+test_seconds_bucket{op="a",le="1"} 2
+test_seconds_bucket{op="a",le="+Inf"} 3
+test_seconds_sum{op="a"} 5.15
+test_seconds_count{op="a"} 3
+test_seconds_bucket{op="b",le="0.1"} 0
+test_seconds_bucket{op="b",le="1"} 1
+test_seconds_bucket{op="b",le="+Inf"} 1
+test_seconds_sum{op="b"} 0.5
+test_seconds_count{op="b"} 1
+`
+	if got := sb.String(); got != want {
+		t.Errorf("WriteText =\n%s\nwant\n%s", got, want)
+	}
+	if h.Count("a") != 3 {
+		t.Errorf("Count = %d, want 3", h.Count("a"))
+	}
+}
+
+func TestRegistry_DuplicatesAndReplacement(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("dup_total", "x")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic on duplicate registration")
+			}
+		}()
+		r.NewCounter("dup_total", "x")
+	}()
+
+	r.GaugeFunc("live", "x", nil, func(emit func(float64, ...string)) { emit(1) })
+	r.GaugeFunc("live", "x", nil, func(emit func(float64, ...string)) { emit(2) })
+	var sb strings.Builder
+	r.WriteText(&sb)
+	if !strings.Contains(sb.String(), "live 2\n") || strings.Contains(sb.String(), "live 1\n") {
+		t.Errorf("GaugeFunc was not replaced:\n%s", sb.String())
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Default.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "# TYPE go_goroutines gauge") {
+		t.Errorf("default registry missing runtime metrics:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics by name and renders them for scraping.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// Default is the registry the package-level metrics are registered in and
+// the one served on the gateway's /metrics endpoint.
+var Default = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// NewCounter registers a counter. It panics if the name is already taken,
+// since that is always a programming error.
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, kind: kindCounter, labels: labels},
+		values: make(map[string]float64),
+	}
+	r.register(c, false)
+	return c
+}
+
+// NewGauge registers a gauge. It panics if the name is already taken.
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{
+		desc:   desc{name: name, help: help, kind: kindGauge, labels: labels},
+		values: make(map[string]float64),
+	}
+	r.register(g, false)
+	return g
+}
+
+// NewHistogram registers a histogram with the given upper bucket bounds,
+// which must be sorted in increasing order. It panics if the name is
+// already taken.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s buckets are not sorted", name))
+	}
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, kind: kindHistogram, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+	r.register(h, false)
+	return h
+}
+
+// GaugeFunc registers a gauge whose series are produced by fn at scrape
+// time; fn calls emit once per series. Registering the same name again
+// replaces the previous callback, so owners can re-register after they are
+// recreated.
+func (r *Registry) GaugeFunc(name, help string, labels []string, fn func(emit func(v float64, labelValues ...string))) {
+	r.register(&gaugeFunc{
+		desc: desc{name: name, help: help, kind: kindGauge, labels: labels},
+		fn:   fn,
+	}, true)
+}
+
+// Unregister removes a metric by name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.collectors, name)
+}
+
+func (r *Registry) register(c collector, replace bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := c.describe().name
+	if _, exists := r.collectors[name]; exists && !replace {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.collectors[name] = c
+}
+
+// WriteText writes every metric in the Prometheus text exposition format
+// (version 0.0.4), sorted by name and labels so output is stable.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(collectors, func(a, b collector) int {
+		return strings.Compare(a.describe().name, b.describe().name)
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		d := c.describe()
+		samples := c.collect()
+		if len(samples) == 0 && len(d.labels) > 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", d.name, d.kind)
+		if len(samples) == 0 {
+			// Unlabelled metrics are always exported, starting at zero.
+			fmt.Fprintf(bw, "%s 0\n", d.name)
+			continue
+		}
+		if d.kind == kindHistogram {
+			// Bucket order matters: sort by series and keep each series'
+			// buckets, sum and count in the order they were collected.
+			slices.SortStableFunc(samples, func(a, b sample) int {
+				return strings.Compare(seriesKey(a), seriesKey(b))
+			})
+		} else {
+			slices.SortFunc(samples, func(a, b sample) int {
+				return strings.Compare(seriesKey(a), seriesKey(b))
+			})
+		}
+		for _, s := range samples {
+			bw.WriteString(formatSample(d.name, s))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the registry in the text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.WriteText(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func formatSample(name string, s sample) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteString(s.suffix)
+	if len(s.labels) > 0 {
+		sb.WriteByte('{')
+		for i := 0; i < len(s.labels); i += 2 {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%s=\"%s\"", s.labels[i], escapeLabel(s.labels[i+1]))
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(formatFloat(s.value))
+	return sb.String()
+}
+
+// seriesKey identifies the series a sample belongs to; a histogram's "le"
+// label distinguishes buckets, not series.
+func seriesKey(s sample) string {
+	var sb strings.Builder
+	for i := 0; i < len(s.labels); i += 2 {
+		if s.labels[i] == "le" {
+			continue
+		}
+		sb.WriteString(s.labels[i+1])
+		sb.WriteString(labelSep)
+	}
+	return sb.String()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+// Bucket bounds in seconds.
+var (
+	// LLMBuckets covers completions from sub-second cache hits to long
+	// reasoning runs.
+	LLMBuckets = []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80, 160}
+
+	// ToolBuckets covers in-process tools through long shell commands.
+	ToolBuckets = []float64{0.005, 0.025, 0.1, 0.25, 1, 2.5, 10, 30, 60, 300}
+)
+
+// Metrics updated by the gateway's subsystems.
+var (
+	MessagesInbound = Default.NewCounter("tinyclaw_messages_inbound_total",
+		"Messages received from channels.", "channel")
+	MessagesOutbound = Default.NewCounter("tinyclaw_messages_outbound_total",
+		"Messages sent to channels.", "channel")
+
+	LLMRequests = Default.NewCounter("tinyclaw_llm_requests_total",
+		"LLM requests by provider, model and status (ok or error).", "provider", "model", "status")
+	LLMDuration = Default.NewHistogram("tinyclaw_llm_request_duration_seconds",
+		"LLM request latency.", LLMBuckets, "provider", "model")
+	LLMTokens = Default.NewCounter("tinyclaw_llm_tokens_total",
+		"Tokens reported by providers, by type (prompt or completion).", "provider", "model", "type")
+	LLMErrors = Default.NewCounter("tinyclaw_llm_errors_total",
+		"Failed LLM requests by classified failover reason.", "provider", "model", "reason")
+	FallbackAttempts = Default.NewCounter("tinyclaw_fallback_attempts_total",
+		"Fallback chain attempts by outcome (success, failure or skipped).", "provider", "model", "outcome")
+
+	ToolExecutions = Default.NewCounter("tinyclaw_tool_executions_total",
+		"Tool executions by status (ok, error or async).", "tool", "status")
+	ToolDuration = Default.NewHistogram("tinyclaw_tool_duration_seconds",
+		"Tool execution time.", ToolBuckets, "tool")
+
+	WebhookRequests = Default.NewCounter("tinyclaw_webhook_requests_total",
+		"Channel webhook requests by HTTP status code.", "channel", "code")
+	WebhookRejected = Default.NewCounter("tinyclaw_webhook_rejected_total",
+		"Channel webhook requests rejected by reason (too_large, signature, replay, duplicate or not_running).", "channel", "reason")
+	WebhookDuration = Default.NewHistogram("tinyclaw_webhook_duration_seconds",
+		"Channel webhook handling time.", ToolBuckets, "channel")
+
+	CronRuns = Default.NewCounter("tinyclaw_cron_runs_total",
+		"Cron job runs by status (ok or error).", "status")
+	HeartbeatRuns = Default.NewCounter("tinyclaw_heartbeat_runs_total",
+		"Heartbeat runs by status (ok, error, async or skipped).", "status")
+)
+
+func init() {
+	start := float64(time.Now().Unix())
+	Default.GaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch.", nil,
+		func(emit func(float64, ...string)) { emit(start) })
+	Default.GaugeFunc("go_goroutines", "Number of goroutines that currently exist.", nil,
+		func(emit func(float64, ...string)) { emit(float64(runtime.NumGoroutine())) })
+}
@@ -162,6 +162,34 @@ func (ct *CooldownTracker) FailureCount(provider string, reason FailoverReason)
 	return entry.FailureCounts[reason]
 }
 
+// CooldownStatus is a point-in-time view of one provider's cooldown state.
+type CooldownStatus struct {
+	Provider   string
+	ErrorCount int
+	Remaining  time.Duration
+}
+
+// Snapshot returns the cooldown state of every provider that has failed
+// since the tracker was created.
+func (ct *CooldownTracker) Snapshot() []CooldownStatus {
+	ct.mu.RLock()
+	providers := make([]string, 0, len(ct.entries))
+	for provider := range ct.entries {
+		providers = append(providers, provider)
+	}
+	ct.mu.RUnlock()
+
+	out := make([]CooldownStatus, 0, len(providers))
+	for _, provider := range providers {
+		out = append(out, CooldownStatus{
+			Provider:   provider,
+			ErrorCount: ct.ErrorCount(provider),
+			Remaining:  ct.CooldownRemaining(provider),
+		})
+	}
+	return out
+}
+
 func (ct *CooldownTracker) getOrCreate(provider string) *cooldownEntry {
 	entry := ct.entries[provider]
 	if entry == nil {
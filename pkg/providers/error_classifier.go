@@ -79,6 +79,31 @@ var (
 		substr("invalid request format"),
 	}
 
+	// Context window overflow, as reported by OpenAI, Anthropic, Gemini and
+	// common OpenAI-compatible servers (vLLM, Ollama, Zhipu, VolcEngine).
+	contextLengthPatterns = []errorPattern{
+		substr("context_length_exceeded"),
+		substr("context length"),
+		substr("context window"),
+		substr("maximum context"),
+		substr("prompt is too long"),
+		substr("input is too long"),
+		substr("too many tokens"),
+		substr("reduce the length"),
+		substr("token limit"),
+		rxp(`tokens?\b.*\bexceed`),
+		rxp(`exceeds?\b.*\btokens?\b`),
+		rxp(`input token count.*exceeds`),
+	}
+
+	tokenRateLimitPatterns = []errorPattern{
+		rxp(`rate[_ ]limit`),
+		substr("too many requests"),
+		substr("per minute"),
+		substr("per min"),
+		rxp(`\btpm\b`),
+	}
+
 	imageDimensionPatterns = []errorPattern{
 		rxp(`image dimensions exceed max`),
 	}
@@ -216,6 +241,21 @@ func extractHTTPStatus(msg string) int {
 	return 0
 }
 
+// IsContextLengthError returns true if err reports that the request did not
+// fit the model's context window. Such errors are fixed by shrinking the
+// prompt, not by retrying or falling back.
+func IsContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	// "tokens per minute exceeded" is a rate limit, not an oversized prompt.
+	if matchesAny(msg, tokenRateLimitPatterns) {
+		return false
+	}
+	return matchesAny(msg, contextLengthPatterns)
+}
+
 // IsImageDimensionError returns true if the message indicates an image dimension error.
 func IsImageDimensionError(msg string) bool {
 	return matchesAny(msg, imageDimensionPatterns)
@@ -335,3 +335,27 @@ func TestIsImageSizeError(t *testing.T) {
 		t.Error("should not match normal error")
 	}
 }
+
+func TestIsContextLengthError(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"This model's maximum context length is 8192 tokens, however you requested 9293 tokens", true},
+		{`{"error":{"code":"context_length_exceeded"}}`, true},
+		{"prompt is too long: 210000 tokens > 200000 maximum", true},
+		{"InvalidParameter: Total tokens of image and text exceed max message tokens", true},
+		{"The input token count (1200000) exceeds the maximum number of tokens allowed", true},
+		{"Rate limit reached for gpt-4o on tokens per min (TPM): limit exceeded", false},
+		{"invalid token", false},
+		{"connection reset by peer", false},
+	}
+	for _, tt := range tests {
+		if got := IsContextLengthError(errors.New(tt.msg)); got != tt.want {
+			t.Errorf("IsContextLengthError(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+	if IsContextLengthError(nil) {
+		t.Error("IsContextLengthError(nil) = true, want false")
+	}
+}
@@ -193,3 +193,18 @@ func getDefaultAPIBase(protocol string) string {
 		return ""
 	}
 }
+
+// CreateEmbeddingProviderFromConfig creates a provider for a model_list entry
+// and checks that it can produce embeddings.
+// Returns the provider and the model ID (without protocol prefix).
+func CreateEmbeddingProviderFromConfig(cfg *config.ModelConfig) (EmbeddingProvider, string, error) {
+	provider, modelID, err := CreateProviderFromConfig(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	embedder, ok := provider.(EmbeddingProvider)
+	if !ok {
+		return nil, "", fmt.Errorf("model %q does not support embeddings", cfg.Model)
+	}
+	return embedder, modelID, nil
+}
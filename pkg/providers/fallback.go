@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
 )
 
 // FallbackChain orchestrates model fallback across multiple candidates.
@@ -96,6 +98,7 @@ func (fc *FallbackChain) Execute(
 	result := &FallbackResult{
 		Attempts: make([]FallbackAttempt, 0, len(candidates)),
 	}
+	defer observeFallback(result)
 
 	for i, candidate := range candidates {
 		// Check context before each attempt.
@@ -207,6 +210,7 @@ func (fc *FallbackChain) ExecuteImage(
 	result := &FallbackResult{
 		Attempts: make([]FallbackAttempt, 0, len(candidates)),
 	}
+	defer observeFallback(result)
 
 	for i, candidate := range candidates {
 		if ctx.Err() == context.Canceled {
@@ -268,6 +272,20 @@ func (fc *FallbackChain) ExecuteImage(
 	return nil, &FallbackExhaustedError{Attempts: result.Attempts}
 }
 
+// observeFallback records the outcome of every attempt in the fallback metrics.
+func observeFallback(result *FallbackResult) {
+	for _, a := range result.Attempts {
+		outcome := "failure"
+		if a.Skipped {
+			outcome = "skipped"
+		}
+		metrics.FallbackAttempts.Inc(a.Provider, a.Model, outcome)
+	}
+	if result.Response != nil {
+		metrics.FallbackAttempts.Inc(result.Provider, result.Model, "success")
+	}
+}
+
 // FallbackExhaustedError indicates all fallback candidates were tried and failed.
 type FallbackExhaustedError struct {
 	Attempts []FallbackAttempt
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
 )
 
 func makeCandidate(provider, model string) FallbackCandidate {
@@ -471,3 +473,47 @@ func TestFallbackExhaustedError_Message(t *testing.T) {
 		t.Error("expected non-empty error message")
 	}
 }
+
+func TestFallback_RecordsAttemptMetrics(t *testing.T) {
+	ct := NewCooldownTracker()
+	fc := NewFallbackChain(ct)
+	ct.MarkFailure("metrics-cold", FailoverRateLimit)
+
+	candidates := []FallbackCandidate{
+		makeCandidate("metrics-cold", "m0"),
+		makeCandidate("metrics-a", "m1"),
+		makeCandidate("metrics-b", "m2"),
+	}
+	run := func(ctx context.Context, provider, model string) (*LLMResponse, error) {
+		if provider == "metrics-a" {
+			return nil, errors.New("rate limit exceeded")
+		}
+		return &LLMResponse{Content: "ok"}, nil
+	}
+	if _, err := fc.Execute(context.Background(), candidates, run); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		provider, model, outcome string
+	}{
+		{"metrics-cold", "m0", "skipped"},
+		{"metrics-a", "m1", "failure"},
+		{"metrics-b", "m2", "success"},
+	}
+	for _, c := range checks {
+		if got := metrics.FallbackAttempts.Value(c.provider, c.model, c.outcome); got != 1 {
+			t.Errorf("%s/%s %s = %v, want 1", c.provider, c.model, c.outcome, got)
+		}
+	}
+
+	snap := ct.Snapshot()
+	if len(snap) != 2 {
+		t.Fatalf("Snapshot = %+v, want 2 providers", snap)
+	}
+	for _, st := range snap {
+		if st.ErrorCount != 1 || st.Remaining <= 0 {
+			t.Errorf("unexpected cooldown status %+v", st)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package openai_compat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ChatStream is Chat with server-sent events: onDelta receives each piece
+// of reply text as it arrives, and the assembled response is returned once
+// the stream ends. Endpoints that ignore "stream" and answer with plain
+// JSON are handled as Chat would, with the whole content as one delta.
+func (p *Provider) ChatStream(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+	onDelta func(string),
+) (*LLMResponse, error) {
+	req, err := p.newChatRequest(ctx, messages, tools, model, options, true)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		out, err := parseResponse(body)
+		if err == nil && out.Content != "" && onDelta != nil {
+			onDelta(out.Content)
+		}
+		return out, err
+	}
+
+	return readStream(resp.Body, onDelta)
+}
+
+// streamToolCall accumulates the fragments of one streamed tool call.
+type streamToolCall struct {
+	id, name, thoughtSignature string
+	args                       strings.Builder
+}
+
+// readStream assembles a response from chat.completion.chunk events.
+func readStream(r io.Reader, onDelta func(string)) (*LLMResponse, error) {
+	var (
+		content, reasoning strings.Builder
+		calls              []*streamToolCall
+		finishReason       string
+		usage              *UsageInfo
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+					ToolCalls        []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function *struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+						ExtraContent *struct {
+							Google *struct {
+								ThoughtSignature string `json:"thought_signature"`
+							} `json:"google"`
+						} `json:"extra_content"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *UsageInfo `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("API stream failed: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+		reasoning.WriteString(choice.Delta.ReasoningContent)
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			for len(calls) <= tc.Index {
+				calls = append(calls, &streamToolCall{})
+			}
+			call := calls[tc.Index]
+			if tc.ID != "" {
+				call.id = tc.ID
+			}
+			if tc.Function != nil {
+				call.name += tc.Function.Name
+				call.args.WriteString(tc.Function.Arguments)
+			}
+			if tc.ExtraContent != nil && tc.ExtraContent.Google != nil {
+				call.thoughtSignature = tc.ExtraContent.Google.ThoughtSignature
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+	toolCalls := make([]ToolCall, 0, len(calls))
+	for _, call := range calls {
+		toolCalls = append(toolCalls, newToolCall(call.id, call.name, call.args.String(), call.thoughtSignature))
+	}
+
+	return &LLMResponse{
+		Content:          content.String(),
+		ReasoningContent: reasoning.String(),
+		ToolCalls:        toolCalls,
+		FinishReason:     finishReason,
+		Usage:            usage,
+	}, nil
+}
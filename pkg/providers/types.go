@@ -32,11 +32,31 @@ type LLMProvider interface {
 	GetDefaultModel() string
 }
 
+// StreamingProvider is implemented by providers that can deliver reply
+// text incrementally. onDelta is called with each piece of content as it
+// arrives; the returned response is the same one Chat would produce.
+type StreamingProvider interface {
+	LLMProvider
+	ChatStream(
+		ctx context.Context,
+		messages []Message,
+		tools []ToolDefinition,
+		model string,
+		options map[string]any,
+		onDelta func(string),
+	) (*LLMResponse, error)
+}
+
 type StatefulProvider interface {
 	LLMProvider
 	Close()
 }
 
+// EmbeddingProvider is implemented by providers that expose an embeddings endpoint.
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, texts []string, model string) ([][]float32, error)
+}
+
 // FailoverReason classifies why an LLM request failed for fallback decisions.
 type FailoverReason string
 
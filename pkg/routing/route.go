@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
@@ -214,6 +215,29 @@ func (r *RouteResolver) findChannelWildcardMatch(bindings []config.AgentBinding)
 	return nil
 }
 
+// ValidateBindings reports bindings naming an agent that is not
+// configured. Routing would silently send their messages to the default
+// agent instead.
+func ValidateBindings(cfg *config.Config) error {
+	known := map[string]bool{}
+	for _, a := range cfg.Agents.List {
+		known[NormalizeAgentID(a.ID)] = true
+	}
+	if len(known) == 0 {
+		known[DefaultAgentID] = true
+	}
+	var problems []string
+	for i, b := range cfg.Bindings {
+		if id := strings.TrimSpace(b.AgentID); id != "" && !known[NormalizeAgentID(id)] {
+			problems = append(problems, fmt.Sprintf("bindings[%d]: unknown agent %q", i, b.AgentID))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 func (r *RouteResolver) pickAgentID(agentID string) string {
 	trimmed := strings.TrimSpace(agentID)
 	if trimmed == "" {
@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/config"
@@ -295,3 +296,21 @@ func TestResolveRoute_NoDefaultUsesFirst(t *testing.T) {
 		t.Errorf("AgentID = %q, want 'alpha' (first in list)", route.AgentID)
 	}
 }
+
+func TestValidateBindings(t *testing.T) {
+	bind := func(agentID string) config.AgentBinding {
+		return config.AgentBinding{AgentID: agentID, Match: config.BindingMatch{Channel: "telegram"}}
+	}
+
+	if err := ValidateBindings(testConfig(nil, []config.AgentBinding{bind("main")})); err != nil {
+		t.Errorf("default agent: %v", err)
+	}
+	agents := []config.AgentConfig{{ID: "Sales"}}
+	if err := ValidateBindings(testConfig(agents, []config.AgentBinding{bind("sales")})); err != nil {
+		t.Errorf("listed agent: %v", err)
+	}
+	err := ValidateBindings(testConfig(agents, []config.AgentBinding{bind("sales"), bind("support")}))
+	if err == nil || !strings.Contains(err.Error(), `bindings[1]: unknown agent "support"`) {
+		t.Errorf("err = %v, want unknown agent support", err)
+	}
+}
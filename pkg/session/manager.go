@@ -2,6 +2,8 @@ package session
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,12 +13,24 @@ import (
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 )
 
+// maxSummaryVersions bounds how many past summaries are kept for rollback.
+const maxSummaryVersions = 10
+
 type Session struct {
-	Key      string              `json:"key"`
-	Messages []providers.Message `json:"messages"`
-	Summary  string              `json:"summary,omitempty"`
-	Created  time.Time           `json:"created"`
-	Updated  time.Time           `json:"updated"`
+	Key       string              `json:"key"`
+	Messages  []providers.Message `json:"messages"`
+	Summary   string              `json:"summary,omitempty"`
+	Summaries []SummaryVersion    `json:"summaries,omitempty"` // oldest first; the last entry is Summary
+	Created   time.Time           `json:"created"`
+	Updated   time.Time           `json:"updated"`
+}
+
+// SummaryVersion is one revision of a session's rolling summary.
+type SummaryVersion struct {
+	Version int       `json:"version"`
+	Summary string    `json:"summary"`
+	Model   string    `json:"model,omitempty"`
+	Created time.Time `json:"created"`
 }
 
 type SessionManager struct {
@@ -100,6 +114,21 @@ func (sm *SessionManager) GetHistory(key string) []providers.Message {
 	return history
 }
 
+// ActiveCount returns how many sessions were updated within the given window.
+func (sm *SessionManager) ActiveCount(within time.Duration) int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	cutoff := time.Now().Add(-within)
+	n := 0
+	for _, session := range sm.sessions {
+		if session.Updated.After(cutoff) {
+			n++
+		}
+	}
+	return n
+}
+
 func (sm *SessionManager) GetSummary(key string) string {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
@@ -112,16 +141,89 @@ func (sm *SessionManager) GetSummary(key string) string {
 }
 
 func (sm *SessionManager) SetSummary(key string, summary string) {
+	sm.SetSummaryVersion(key, summary, "")
+}
+
+// SetSummaryVersion replaces the session summary and records it as a new
+// version, noting the model that produced it. Returns the version number,
+// or 0 if the session does not exist.
+func (sm *SessionManager) SetSummaryVersion(key, summary, model string) int {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	session, ok := sm.sessions[key]
-	if ok {
-		session.Summary = summary
-		session.Updated = time.Now()
+	if !ok {
+		return 0
+	}
+
+	// Sessions saved before versioning have a summary but no history.
+	if len(session.Summaries) == 0 && session.Summary != "" {
+		session.Summaries = append(session.Summaries, SummaryVersion{
+			Version: 1,
+			Summary: session.Summary,
+			Created: session.Updated,
+		})
 	}
+
+	version := 1
+	if n := len(session.Summaries); n > 0 {
+		version = session.Summaries[n-1].Version + 1
+	}
+	session.Summaries = append(session.Summaries, SummaryVersion{
+		Version: version,
+		Summary: summary,
+		Model:   model,
+		Created: time.Now(),
+	})
+	if len(session.Summaries) > maxSummaryVersions {
+		session.Summaries = session.Summaries[len(session.Summaries)-maxSummaryVersions:]
+	}
+	session.Summary = summary
+	session.Updated = time.Now()
+	return version
 }
 
+// GetSummaryVersions returns the recorded summary versions, oldest first.
+func (sm *SessionManager) GetSummaryVersions(key string) []SummaryVersion {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return nil
+	}
+	versions := make([]SummaryVersion, len(session.Summaries))
+	copy(versions, session.Summaries)
+	return versions
+}
+
+// RollbackSummary discards the current summary version and restores the
+// previous one (or no summary, if it was the first). Messages already
+// truncated by summarization are not restored. Returns false when there is
+// nothing to roll back.
+func (sm *SessionManager) RollbackSummary(key string) (SummaryVersion, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok || len(session.Summaries) == 0 {
+		return SummaryVersion{}, false
+	}
+
+	session.Summaries = session.Summaries[:len(session.Summaries)-1]
+	var restored SummaryVersion
+	if n := len(session.Summaries); n > 0 {
+		restored = session.Summaries[n-1]
+	}
+	session.Summary = restored.Summary
+	session.Updated = time.Now()
+	return restored, true
+}
+
+// TruncateHistory keeps roughly the last keepLast messages. The cut never
+// lands between an assistant tool call and its tool results: if the first
+// kept message would be a tool result, the cut moves back to include the
+// assistant message that issued the call.
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -141,10 +243,24 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 		return
 	}
 
-	session.Messages = session.Messages[len(session.Messages)-keepLast:]
+	cut := SafeCutIndex(session.Messages, len(session.Messages)-keepLast)
+	if cut == 0 {
+		return
+	}
+	session.Messages = session.Messages[cut:]
 	session.Updated = time.Now()
 }
 
+// SafeCutIndex adjusts a split point so that messages[cut:] does not start
+// with tool results orphaned from their assistant tool call.
+func SafeCutIndex(messages []providers.Message, cut int) int {
+	cut = min(max(cut, 0), len(messages))
+	for cut > 0 && cut < len(messages) && messages[cut].Role == "tool" {
+		cut--
+	}
+	return cut
+}
+
 // sanitizeFilename converts a session key into a cross-platform safe filename.
 // Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
 // volume separator on Windows, so filepath.Base would misinterpret the key.
@@ -179,10 +295,11 @@ func (sm *SessionManager) Save(key string) error {
 	}
 
 	snapshot := Session{
-		Key:     stored.Key,
-		Summary: stored.Summary,
-		Created: stored.Created,
-		Updated: stored.Updated,
+		Key:       stored.Key,
+		Summary:   stored.Summary,
+		Summaries: append([]SummaryVersion(nil), stored.Summaries...),
+		Created:   stored.Created,
+		Updated:   stored.Updated,
 	}
 	if len(stored.Messages) > 0 {
 		snapshot.Messages = make([]providers.Message, len(stored.Messages))
@@ -234,6 +351,44 @@ func (sm *SessionManager) Save(key string) error {
 	return nil
 }
 
+// SaveAll writes every session held in memory to storage.
+func (sm *SessionManager) SaveAll() error {
+	sm.mu.RLock()
+	keys := make([]string, 0, len(sm.sessions))
+	for key := range sm.sessions {
+		keys = append(keys, key)
+	}
+	sm.mu.RUnlock()
+
+	var errs []error
+	for _, key := range keys {
+		if err := sm.Save(key); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Delete removes a session from memory and from storage.
+func (sm *SessionManager) Delete(key string) error {
+	sm.mu.Lock()
+	delete(sm.sessions, key)
+	sm.mu.Unlock()
+
+	if sm.storage == "" {
+		return nil
+	}
+	filename := sanitizeFilename(key)
+	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+		return os.ErrInvalid
+	}
+	err := os.Remove(filepath.Join(sm.storage, filename+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
 func (sm *SessionManager) loadSessions() error {
 	files, err := os.ReadDir(sm.storage)
 	if err != nil {
@@ -1,9 +1,12 @@
 package session
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 )
 
 func TestSanitizeFilename(t *testing.T) {
@@ -72,3 +75,140 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestTruncateHistory_KeepsToolPairsAtomic(t *testing.T) {
+	sm := NewSessionManager("")
+	key := "s"
+	sm.GetOrCreate(key)
+	sm.SetHistory(key, []providers.Message{
+		{Role: "user", Content: "read it"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "a"}, {ID: "b"}}},
+		{Role: "tool", ToolCallID: "a", Content: "A"},
+		{Role: "tool", ToolCallID: "b", Content: "B"},
+		{Role: "assistant", Content: "done"},
+	})
+
+	// Keeping 3 would start at tool result "b"; the cut moves back to the call.
+	sm.TruncateHistory(key, 3)
+	history := sm.GetHistory(key)
+	if len(history) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(history))
+	}
+	if history[0].Role != "assistant" || len(history[0].ToolCalls) != 2 {
+		t.Errorf("expected assistant tool call first, got %+v", history[0])
+	}
+}
+
+func TestSummaryVersions_Rollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	key := "telegram:1"
+	sm.GetOrCreate(key)
+
+	if v := sm.SetSummaryVersion(key, "first", "cheap-model"); v != 1 {
+		t.Fatalf("version = %d, want 1", v)
+	}
+	if v := sm.SetSummaryVersion(key, "second", "cheap-model"); v != 2 {
+		t.Fatalf("version = %d, want 2", v)
+	}
+	if err := sm.Save(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// Versions survive a reload.
+	sm2 := NewSessionManager(tmpDir)
+	versions := sm2.GetSummaryVersions(key)
+	if len(versions) != 2 || versions[1].Model != "cheap-model" {
+		t.Fatalf("unexpected versions after reload: %+v", versions)
+	}
+
+	restored, ok := sm2.RollbackSummary(key)
+	if !ok || restored.Version != 1 || sm2.GetSummary(key) != "first" {
+		t.Fatalf("rollback restored %+v, summary %q", restored, sm2.GetSummary(key))
+	}
+	if _, ok := sm2.RollbackSummary(key); !ok || sm2.GetSummary(key) != "" {
+		t.Fatalf("second rollback should clear the summary, got %q", sm2.GetSummary(key))
+	}
+	if _, ok := sm2.RollbackSummary(key); ok {
+		t.Fatal("expected nothing left to roll back")
+	}
+}
+
+func TestSummaryVersions_LegacySummaryIsVersioned(t *testing.T) {
+	sm := NewSessionManager("")
+	key := "k"
+	sm.GetOrCreate(key)
+	sm.sessions[key].Summary = "legacy" // loaded from a pre-versioning file
+
+	if v := sm.SetSummaryVersion(key, "new", ""); v != 2 {
+		t.Fatalf("version = %d, want 2", v)
+	}
+	if _, ok := sm.RollbackSummary(key); !ok || sm.GetSummary(key) != "legacy" {
+		t.Errorf("expected rollback to legacy summary, got %q", sm.GetSummary(key))
+	}
+}
+
+func TestSummaryVersions_Bounded(t *testing.T) {
+	sm := NewSessionManager("")
+	key := "k"
+	sm.GetOrCreate(key)
+	for i := range maxSummaryVersions + 5 {
+		sm.SetSummaryVersion(key, fmt.Sprintf("v%d", i+1), "")
+	}
+	versions := sm.GetSummaryVersions(key)
+	if len(versions) != maxSummaryVersions {
+		t.Fatalf("kept %d versions, want %d", len(versions), maxSummaryVersions)
+	}
+	if versions[len(versions)-1].Version != maxSummaryVersions+5 {
+		t.Errorf("latest version = %d", versions[len(versions)-1].Version)
+	}
+}
+
+func TestDelete_RemovesMemoryAndFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	key := "api:ephemeral:1"
+	sm.AddMessage(key, "user", "hello")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	path := filepath.Join(tmpDir, sanitizeFilename(key)+".json")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("session file missing: %v", err)
+	}
+
+	if err := sm.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := sm.GetHistory(key); len(got) != 0 {
+		t.Errorf("history after Delete = %v, want empty", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("session file still present: %v", err)
+	}
+
+	// Deleting an unknown session is not an error.
+	if err := sm.Delete("missing"); err != nil {
+		t.Errorf("Delete(missing) = %v", err)
+	}
+}
+
+func TestSaveAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	sm.AddMessage("telegram:1", "user", "hello")
+	sm.AddMessage("slack:C2", "user", "hi")
+
+	if err := sm.SaveAll(); err != nil {
+		t.Fatalf("SaveAll: %v", err)
+	}
+	for _, name := range []string{"telegram_1.json", "slack_C2.json"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+			t.Errorf("%s not saved: %v", name, err)
+		}
+	}
+	if got := NewSessionManager(tmpDir).GetHistory("telegram:1"); len(got) != 1 {
+		t.Errorf("reloaded history = %v", got)
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/logger"
@@ -21,8 +24,9 @@ const (
 )
 
 type SkillMetadata struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Requires    SkillRequirements `json:"requires"`
 }
 
 type SkillInfo struct {
@@ -30,6 +34,9 @@ type SkillInfo struct {
 	Path        string `json:"path"`
 	Source      string `json:"source"`
 	Description string `json:"description"`
+	// Unavailable says why the skill's requirements are not met on this
+	// host; it is empty when the skill can be used.
+	Unavailable string `json:"unavailable,omitempty"`
 }
 
 func (info SkillInfo) validate() error {
@@ -58,6 +65,12 @@ type SkillsLoader struct {
 	workspaceSkills string // workspace skills (project-level)
 	globalSkills    string // global skills (~/.tinyclaw/skills)
 	builtinSkills   string // builtin skills
+	filter          []string
+
+	// Host lookups for checking skill requirements.
+	lookPath func(string) (string, error)
+	getenv   func(string) string
+	goos     string
 }
 
 func NewSkillsLoader(workspace string, globalSkills string, builtinSkills string) *SkillsLoader {
@@ -66,9 +79,18 @@ func NewSkillsLoader(workspace string, globalSkills string, builtinSkills string
 		workspaceSkills: filepath.Join(workspace, "skills"),
 		globalSkills:    globalSkills, // ~/.tinyclaw/skills
 		builtinSkills:   builtinSkills,
+		lookPath:        exec.LookPath,
+		getenv:          os.Getenv,
+		goos:            runtime.GOOS,
 	}
 }
 
+// SetFilter limits the skills listed and loaded to the names admitted by
+// filter (see MatchSkillFilter).
+func (sl *SkillsLoader) SetFilter(filter []string) {
+	sl.filter = filter
+}
+
 func (sl *SkillsLoader) ListSkills() []SkillInfo {
 	skills := make([]SkillInfo, 0)
 	seen := make(map[string]bool)
@@ -98,11 +120,15 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 			if metadata != nil {
 				info.Description = metadata.Description
 				info.Name = metadata.Name
+				info.Unavailable = metadata.Requires.check(sl)
 			}
 			if err := info.validate(); err != nil {
 				slog.Warn("invalid skill from "+source, "name", info.Name, "error", err)
 				continue
 			}
+			if !MatchSkillFilter(sl.filter, info.Name) {
+				continue
+			}
 			if seen[info.Name] {
 				continue
 			}
@@ -120,6 +146,10 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 }
 
 func (sl *SkillsLoader) LoadSkill(name string) (string, bool) {
+	if !MatchSkillFilter(sl.filter, name) {
+		return "", false
+	}
+
 	// 1. load from workspace skills first (project-level)
 	if sl.workspaceSkills != "" {
 		skillFile := filepath.Join(sl.workspaceSkills, name, "SKILL.md")
@@ -147,6 +177,30 @@ func (sl *SkillsLoader) LoadSkill(name string) (string, bool) {
 	return "", false
 }
 
+// ReadSkill returns a listed skill and its instructions without the
+// frontmatter. It fails for skills that are unknown, filtered out, or
+// unavailable on this host.
+func (sl *SkillsLoader) ReadSkill(name string) (SkillInfo, string, error) {
+	var names []string
+	for _, info := range sl.ListSkills() {
+		if info.Name != name {
+			if info.Unavailable == "" {
+				names = append(names, info.Name)
+			}
+			continue
+		}
+		if info.Unavailable != "" {
+			return info, "", fmt.Errorf("skill %q is unavailable: %s", name, info.Unavailable)
+		}
+		content, err := os.ReadFile(info.Path)
+		if err != nil {
+			return info, "", fmt.Errorf("reading skill %q: %w", name, err)
+		}
+		return info, sl.stripFrontmatter(string(content)), nil
+	}
+	return SkillInfo{}, "", fmt.Errorf("no skill named %q; available skills: %s", name, strings.Join(names, ", "))
+}
+
 func (sl *SkillsLoader) LoadSkillsForContext(skillNames []string) string {
 	if len(skillNames) == 0 {
 		return ""
@@ -163,8 +217,10 @@ func (sl *SkillsLoader) LoadSkillsForContext(skillNames []string) string {
 	return strings.Join(parts, "\n\n---\n\n")
 }
 
+// BuildSkillsSummary lists the skills available on this host for the
+// system prompt; unavailable ones are left out.
 func (sl *SkillsLoader) BuildSkillsSummary() string {
-	allSkills := sl.ListSkills()
+	allSkills := slices.DeleteFunc(sl.ListSkills(), func(s SkillInfo) bool { return s.Unavailable != "" })
 	if len(allSkills) == 0 {
 		return ""
 	}
@@ -208,13 +264,15 @@ func (sl *SkillsLoader) getSkillMetadata(skillPath string) *SkillMetadata {
 
 	// Try JSON first (for backward compatibility)
 	var jsonMeta struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Metadata    json.RawMessage `json:"metadata"`
 	}
 	if err := json.Unmarshal([]byte(frontmatter), &jsonMeta); err == nil {
 		return &SkillMetadata{
 			Name:        jsonMeta.Name,
 			Description: jsonMeta.Description,
+			Requires:    parseRequirements(string(jsonMeta.Metadata)),
 		}
 	}
 
@@ -223,6 +281,7 @@ func (sl *SkillsLoader) getSkillMetadata(skillPath string) *SkillMetadata {
 	return &SkillMetadata{
 		Name:        yamlMeta["name"],
 		Description: yamlMeta["description"],
+		Requires:    parseRequirements(yamlMeta["metadata"]),
 	}
 }
 
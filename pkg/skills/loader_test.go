@@ -326,3 +326,103 @@ func TestStripFrontmatter(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchSkillFilter(t *testing.T) {
+	tests := []struct {
+		filter []string
+		name   string
+		want   bool
+	}{
+		{nil, "github", true},
+		{[]string{"github"}, "github", true},
+		{[]string{"github"}, "weather", false},
+		{[]string{"git*", "weather"}, "github", true},
+		{[]string{"!tmux"}, "github", true},
+		{[]string{"!tmux"}, "tmux", false},
+		{[]string{"*", "!hard*"}, "hardware", false},
+		{[]string{"hardware", "!hardware"}, "hardware", false},
+		{[]string{"["}, "github", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchSkillFilter(tt.filter, tt.name), "filter %v, name %s", tt.filter, tt.name)
+	}
+}
+
+// createSkillWithMetadata writes a skill whose frontmatter carries metadata.
+func createSkillWithMetadata(t *testing.T, base, name, metadata string) {
+	t.Helper()
+	dir := filepath.Join(base, name)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	content := "---\nname: " + name + "\ndescription: " + name + " skill\nmetadata: " + metadata + "\n---\n\n# " + name
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o644))
+}
+
+func TestListSkillsRequirements(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, "skills")
+	createSkillWithMetadata(t, dir, "has-bin", `{"nanobot":{"requires":{"bins":["gh"]}}}`)
+	createSkillWithMetadata(t, dir, "no-bin", `{"nanobot":{"requires":{"bins":["gh","tmux"]}}}`)
+	createSkillWithMetadata(t, dir, "any-bin", `{"openclaw":{"requires":{"anyBins":["tmux","gh"]}}}`)
+	createSkillWithMetadata(t, dir, "no-env", `{"nanobot":{"requires":{"env":["API_TOKEN"]}}}`)
+	createSkillWithMetadata(t, dir, "wrong-os", `{"nanobot":{"os":["darwin"],"requires":{"bins":["gh"]}}}`)
+	createSkillDir(t, dir, "plain", "plain", "no requirements")
+
+	sl := NewSkillsLoader(ws, "", "")
+	sl.lookPath = func(bin string) (string, error) {
+		if bin == "gh" {
+			return "/usr/bin/gh", nil
+		}
+		return "", os.ErrNotExist
+	}
+	sl.getenv = func(string) string { return "" }
+	sl.goos = "linux"
+
+	unavailable := make(map[string]string)
+	for _, s := range sl.ListSkills() {
+		unavailable[s.Name] = s.Unavailable
+	}
+	assert.Equal(t, map[string]string{
+		"has-bin":  "",
+		"no-bin":   "missing binaries: tmux",
+		"any-bin":  "",
+		"no-env":   "missing environment variables: API_TOKEN",
+		"wrong-os": "requires darwin (this is linux)",
+		"plain":    "",
+	}, unavailable)
+
+	summary := sl.BuildSkillsSummary()
+	assert.Contains(t, summary, "<name>has-bin</name>")
+	assert.NotContains(t, summary, "no-bin")
+	assert.NotContains(t, summary, "wrong-os")
+
+	_, _, err := sl.ReadSkill("no-env")
+	require.ErrorContains(t, err, "unavailable: missing environment variables")
+}
+
+func TestSkillsLoaderFilter(t *testing.T) {
+	ws := t.TempDir()
+	global := t.TempDir()
+	createSkillDir(t, filepath.Join(ws, "skills"), "github", "github", "GitHub")
+	createSkillDir(t, filepath.Join(ws, "skills"), "weather", "weather", "Weather")
+	createSkillDir(t, global, "tmux", "tmux", "tmux")
+
+	sl := NewSkillsLoader(ws, global, "")
+	sl.SetFilter([]string{"*", "!weather"})
+
+	var names []string
+	for _, s := range sl.ListSkills() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"github", "tmux"}, names)
+
+	_, ok := sl.LoadSkill("weather")
+	assert.False(t, ok)
+
+	info, content, err := sl.ReadSkill("tmux")
+	require.NoError(t, err)
+	assert.Equal(t, "global", info.Source)
+	assert.Equal(t, "# tmux", content)
+
+	_, _, err = sl.ReadSkill("weather")
+	require.ErrorContains(t, err, "available skills: github, tmux")
+}
@@ -0,0 +1,102 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// metadataKeys are the blocks of a skill's "metadata" frontmatter field that
+// may carry requirements; skills written for related agents use their own.
+var metadataKeys = []string{"tinyclaw", "nanobot", "openclaw", "clawdbot"}
+
+// SkillRequirements is what a skill needs from the host, declared in its
+// frontmatter as metadata: {"nanobot": {"os": [...], "requires": {...}}}.
+type SkillRequirements struct {
+	Bins    []string `json:"bins,omitempty"`    // all must be on PATH
+	AnyBins []string `json:"anyBins,omitempty"` // at least one must be on PATH
+	Env     []string `json:"env,omitempty"`     // must be set and non-empty
+	OS      []string `json:"os,omitempty"`      // GOOS values; empty means any
+}
+
+// parseRequirements reads the requirements from a metadata JSON value.
+// Malformed metadata declares no requirements.
+func parseRequirements(metadata string) SkillRequirements {
+	var blocks map[string]json.RawMessage
+	if metadata == "" || json.Unmarshal([]byte(metadata), &blocks) != nil {
+		return SkillRequirements{}
+	}
+	var reqs SkillRequirements
+	for _, key := range metadataKeys {
+		raw, ok := blocks[key]
+		if !ok {
+			continue
+		}
+		var block struct {
+			OS       []string          `json:"os"`
+			Requires SkillRequirements `json:"requires"`
+		}
+		if json.Unmarshal(raw, &block) != nil {
+			continue
+		}
+		reqs = block.Requires
+		reqs.OS = append(reqs.OS, block.OS...)
+		break
+	}
+	return reqs
+}
+
+// check returns why the requirements are not met on this host, or "".
+func (r SkillRequirements) check(sl *SkillsLoader) string {
+	if len(r.OS) > 0 && !slices.Contains(r.OS, sl.goos) {
+		return fmt.Sprintf("requires %s (this is %s)", strings.Join(r.OS, " or "), sl.goos)
+	}
+	var missing []string
+	for _, bin := range r.Bins {
+		if _, err := sl.lookPath(bin); err != nil {
+			missing = append(missing, bin)
+		}
+	}
+	if len(missing) > 0 {
+		return "missing binaries: " + strings.Join(missing, ", ")
+	}
+	if len(r.AnyBins) > 0 && !slices.ContainsFunc(r.AnyBins, func(bin string) bool {
+		_, err := sl.lookPath(bin)
+		return err == nil
+	}) {
+		return "needs one of: " + strings.Join(r.AnyBins, ", ")
+	}
+	for _, name := range r.Env {
+		if sl.getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "missing environment variables: " + strings.Join(missing, ", ")
+	}
+	return ""
+}
+
+// MatchSkillFilter reports whether filter admits the skill name. Entries are
+// glob patterns; an entry starting with "!" denies the names it matches. A
+// name is admitted when no deny entry matches it and, if there are any
+// allow entries, one of them does. An empty filter admits every skill.
+func MatchSkillFilter(filter []string, name string) bool {
+	allowed, hasAllow := false, false
+	for _, pattern := range filter {
+		pattern = strings.TrimSpace(pattern)
+		if deny, ok := strings.CutPrefix(pattern, "!"); ok {
+			if matched, _ := path.Match(deny, name); matched {
+				return false
+			}
+			continue
+		}
+		hasAllow = true
+		if matched, _ := path.Match(pattern, name); matched {
+			allowed = true
+		}
+	}
+	return allowed || !hasAllow
+}
@@ -31,10 +31,10 @@ type Config struct {
 // When tsnet is not available (build without tsnet tag), it provides a no-op
 // fallback that uses standard net.Listen.
 type Server struct {
-	config   Config
-	listener net.Listener
-	mu       sync.Mutex
-	running  bool
+	config    Config
+	listeners []net.Listener
+	mu        sync.Mutex
+	running   bool
 }
 
 // NewServer creates a new Tailscale server with the given config.
@@ -95,13 +95,32 @@ func (s *Server) Stop() {
 		return
 	}
 
-	if s.listener != nil {
-		s.listener.Close()
+	for _, ln := range s.listeners {
+		ln.Close()
 	}
+	s.listeners = nil
 	s.running = false
 	logger.InfoC("tailscale", "tsnet server stopped")
 }
 
+// Listen announces on the tailnet at addr (":port"). The listener is closed
+// by Stop. When tsnet is fully integrated this is the tsnet server's
+// Listen; currently it listens on the host's interfaces.
+func (s *Server) Listen(network, addr string) (net.Listener, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil, errors.New("tsnet server not running")
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("tsnet listen on %s: %w", addr, err)
+	}
+	s.listeners = append(s.listeners, ln)
+	return ln, nil
+}
+
 // HTTPClient returns an http.Client that routes through the tailnet.
 // When tsnet is fully integrated, this uses the tsnet server's HTTPClient().
 // Currently returns a standard http.Client.
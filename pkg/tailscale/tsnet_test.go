@@ -67,6 +67,23 @@ func TestServer_DoubleStart(t *testing.T) {
 	}
 }
 
+func TestServer_Listen(t *testing.T) {
+	s := NewServer(Config{Enabled: true, StateDir: t.TempDir()})
+	if _, err := s.Listen("tcp", "127.0.0.1:0"); err == nil {
+		t.Error("expected error listening before start")
+	}
+
+	s.Start(context.Background())
+	ln, err := s.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s.Stop()
+	if _, err := ln.Accept(); err == nil {
+		t.Error("expected listener to be closed by Stop")
+	}
+}
+
 func TestServer_HTTPClient(t *testing.T) {
 	s := NewServer(Config{})
 	client := s.HTTPClient()
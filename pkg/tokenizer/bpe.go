@@ -0,0 +1,108 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// BPE is a byte-pair encoder driven by a tiktoken rank table.
+type BPE struct {
+	name  string
+	ranks map[string]int
+}
+
+// LoadBPE reads a tiktoken rank file ("<base64 token> <rank>" per line).
+func LoadBPE(name, path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseBPE(name, f)
+}
+
+// ParseBPE parses tiktoken rank data from r.
+func ParseBPE(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("tokenizer %s: line %d: expected 2 fields, got %d", name, line, len(fields))
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s: line %d: %w", name, line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s: line %d: %w", name, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("tokenizer %s: %w", name, err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("tokenizer %s: empty rank table", name)
+	}
+	return &BPE{name: name, ranks: ranks}, nil
+}
+
+// Name returns the encoding name.
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count returns the exact number of tokens text encodes to.
+func (b *BPE) Count(text string) int {
+	total := 0
+	for _, piece := range splitPieces(text) {
+		total += b.countPiece(piece)
+	}
+	return total
+}
+
+// countPiece runs the byte-pair merge over a single pre-token and returns
+// how many tokens remain. Bytes missing from the rank table (only possible
+// with a truncated file) count as one token each.
+func (b *BPE) countPiece(piece string) int {
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+	if len(piece) == 1 {
+		return 1
+	}
+
+	// bounds[i] is the start offset of the i-th part; the last entry is len(piece).
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best, bestAt := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < best {
+				best, bestAt = rank, i
+			}
+		}
+		if bestAt < 0 {
+			break
+		}
+		bounds = append(bounds[:bestAt+1], bounds[bestAt+2:]...)
+	}
+	return len(bounds) - 1
+}
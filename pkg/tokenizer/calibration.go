@@ -0,0 +1,62 @@
+package tokenizer
+
+import "sync"
+
+// Calibration bounds: a single odd sample can't push estimates beyond 2x
+// in either direction.
+const (
+	calibrationAlpha    = 0.3
+	calibrationMinRatio = 0.5
+	calibrationMaxRatio = 2.0
+)
+
+// Calibrator learns, per model, the ratio between locally counted prompt
+// tokens and the prompt tokens the provider actually billed. The ratio
+// absorbs hidden per-request overhead (chat templates, tool schema
+// rendering) and estimator bias for models without an exact tokenizer.
+type Calibrator struct {
+	mu     sync.RWMutex
+	ratios map[string]float64
+}
+
+// NewCalibrator creates an empty Calibrator (ratio 1.0 for every model).
+func NewCalibrator() *Calibrator {
+	return &Calibrator{ratios: make(map[string]float64)}
+}
+
+// Observe records that a prompt counted locally as estimated tokens was
+// reported by the provider as actual tokens.
+func (c *Calibrator) Observe(model string, estimated, actual int) {
+	if estimated <= 0 || actual <= 0 {
+		return
+	}
+	sample := clampRatio(float64(actual) / float64(estimated))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, ok := c.ratios[model]
+	if !ok {
+		c.ratios[model] = sample
+		return
+	}
+	c.ratios[model] = clampRatio(prev + calibrationAlpha*(sample-prev))
+}
+
+// Ratio returns the current correction factor for model.
+func (c *Calibrator) Ratio(model string) float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if r, ok := c.ratios[model]; ok {
+		return r
+	}
+	return 1.0
+}
+
+// Adjust applies the model's correction factor to a local count.
+func (c *Calibrator) Adjust(model string, tokens int) int {
+	return int(float64(tokens)*c.Ratio(model) + 0.5)
+}
+
+func clampRatio(r float64) float64 {
+	return min(max(r, calibrationMinRatio), calibrationMaxRatio)
+}
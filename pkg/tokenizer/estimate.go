@@ -0,0 +1,98 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator approximates BPE token counts without a vocabulary. It uses the
+// same pre-tokenization as BPE and scores each piece from its shape, which
+// tracks real tokenizers far better than a flat characters-per-token ratio
+// (code, numbers and CJK text in particular).
+type Estimator struct{}
+
+// NewEstimator creates an Estimator.
+func NewEstimator() *Estimator {
+	return &Estimator{}
+}
+
+// Name returns "estimate".
+func (e *Estimator) Name() string {
+	return "estimate"
+}
+
+// Count returns the estimated number of tokens in text.
+func (e *Estimator) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	total := 0
+	for _, piece := range splitPieces(text) {
+		total += estimatePiece(piece)
+	}
+	return total
+}
+
+// estimatePiece scores one pre-token:
+//   - ASCII letter runs: common words up to ~6 letters are a single token,
+//     longer words split roughly every 6 letters.
+//   - Digit groups (at most 3 digits after pre-tokenization): one token.
+//   - Punctuation/symbol runs: about two characters per token.
+//   - Whitespace runs: one token.
+//   - CJK ideographs and kana: about 1.5 tokens per character.
+//   - Other non-ASCII letters: about one token per 3 bytes of UTF-8.
+func estimatePiece(piece string) int {
+	var letters, digits, symbols, cjk, otherBytes int
+	spaces := false
+	for i, r := range piece {
+		switch {
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r):
+			digits++
+		case unicode.IsSpace(r):
+			if i == 0 && len(piece) > 1 {
+				// Leading space folded into a word or symbol run.
+				continue
+			}
+			spaces = true
+		case isCJK(r):
+			cjk++
+		case r >= utf8.RuneSelf:
+			otherBytes += utf8.RuneLen(r)
+		default:
+			symbols++
+		}
+	}
+
+	n := 0
+	if letters > 0 {
+		n += (letters + 5) / 6
+	}
+	if digits > 0 {
+		n += (digits + 2) / 3
+	}
+	if symbols > 0 {
+		n += (symbols + 1) / 2
+	}
+	if cjk > 0 {
+		n += (cjk*3 + 1) / 2
+	}
+	if otherBytes > 0 {
+		n += (otherBytes + 2) / 3
+	}
+	if n == 0 && spaces {
+		n = 1
+	}
+	return n
+}
+
+// isCJK reports whether r is a CJK ideograph, kana or Hangul syllable.
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x3040 && r <= 0x309F) ||
+		(r >= 0x30A0 && r <= 0x30FF) ||
+		(r >= 0xAC00 && r <= 0xD7AF)
+}
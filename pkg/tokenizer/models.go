@@ -0,0 +1,81 @@
+package tokenizer
+
+import "strings"
+
+// DefaultContextWindow is assumed for models with no known or configured window.
+const DefaultContextWindow = 32768
+
+// ModelInfo describes the token limits of a model.
+type ModelInfo struct {
+	ContextWindow   int // total tokens the model accepts (prompt + output)
+	MaxOutputTokens int // upper bound on completion tokens, 0 if unknown
+}
+
+// modelPrefix maps a model ID prefix to its limits. Entries are matched in
+// order, so more specific prefixes must come first.
+type modelPrefix struct {
+	prefix string
+	info   ModelInfo
+}
+
+var knownModels = []modelPrefix{
+	// Anthropic
+	{"claude-opus-4", ModelInfo{200000, 32000}},
+	{"claude-sonnet-4", ModelInfo{200000, 64000}},
+	{"claude-haiku-4", ModelInfo{200000, 64000}},
+	{"claude-3-7", ModelInfo{200000, 64000}},
+	{"claude-3-5", ModelInfo{200000, 8192}},
+	{"claude", ModelInfo{200000, 4096}},
+
+	// OpenAI
+	{"gpt-5", ModelInfo{400000, 128000}},
+	{"gpt-4.1", ModelInfo{1047576, 32768}},
+	{"gpt-4o", ModelInfo{128000, 16384}},
+	{"chatgpt-4o", ModelInfo{128000, 16384}},
+	{"gpt-4-turbo", ModelInfo{128000, 4096}},
+	{"gpt-4-32k", ModelInfo{32768, 4096}},
+	{"gpt-4.5", ModelInfo{128000, 16384}},
+	{"gpt-4-1106", ModelInfo{128000, 4096}},
+	{"gpt-4-0125", ModelInfo{128000, 4096}},
+	{"gpt-4-vision", ModelInfo{128000, 4096}},
+	{"gpt-4", ModelInfo{8192, 4096}},
+	{"gpt-3.5-turbo", ModelInfo{16385, 4096}},
+	{"o1-mini", ModelInfo{128000, 65536}},
+	{"o1", ModelInfo{200000, 100000}},
+	{"o3", ModelInfo{200000, 100000}},
+	{"o4", ModelInfo{200000, 100000}},
+	{"codex", ModelInfo{200000, 100000}},
+
+	// Google
+	{"gemini-2", ModelInfo{1048576, 65536}},
+	{"gemini-1.5-pro", ModelInfo{2097152, 8192}},
+	{"gemini-1.5", ModelInfo{1048576, 8192}},
+	{"gemini-3", ModelInfo{1048576, 65536}},
+
+	// Others commonly used through OpenAI-compatible endpoints
+	{"deepseek", ModelInfo{128000, 8192}},
+	{"qwen", ModelInfo{131072, 8192}},
+	{"glm-4", ModelInfo{128000, 4096}},
+	{"kimi", ModelInfo{131072, 8192}},
+	{"moonshot-v1-8k", ModelInfo{8192, 0}},
+	{"moonshot-v1-32k", ModelInfo{32768, 0}},
+	{"moonshot-v1-128k", ModelInfo{131072, 0}},
+	{"mistral-large", ModelInfo{131072, 0}},
+	{"mistral-small", ModelInfo{32768, 0}},
+	{"codestral", ModelInfo{262144, 0}},
+	{"llama-3", ModelInfo{131072, 0}},
+	{"llama3", ModelInfo{131072, 0}},
+}
+
+// LookupModel returns built-in limits for a model ID. The ID may carry a
+// protocol prefix ("anthropic/claude-sonnet-4.6") and is matched
+// case-insensitively by prefix.
+func LookupModel(model string) (ModelInfo, bool) {
+	id := strings.ToLower(stripProtocol(model))
+	for _, m := range knownModels {
+		if strings.HasPrefix(id, m.prefix) {
+			return m.info, true
+		}
+	}
+	return ModelInfo{}, false
+}
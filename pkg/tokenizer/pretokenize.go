@@ -0,0 +1,62 @@
+package tokenizer
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pretokenPattern is the cl100k_base split pattern adapted to RE2. The
+// original ends with `\s+(?!\S)|\s+`; RE2 has no lookahead, so the final
+// alternative is plain `\s+` and splitPieces re-creates the lookahead
+// behaviour by handing the last whitespace rune to the following piece.
+var pretokenPattern = regexp.MustCompile(
+	`(?i:'s|'t|'re|'ve|'m|'ll|'d)` +
+		`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+		`|\p{N}{1,3}` +
+		`| ?[^\s\p{L}\p{N}]+[\r\n]*` +
+		`|\s*[\r\n]+` +
+		`|\s+`)
+
+// splitPieces splits text into the pre-tokens a BPE encoder merges within.
+func splitPieces(text string) []string {
+	locs := pretokenPattern.FindAllStringIndex(text, -1)
+	pieces := make([]string, 0, len(locs))
+	for i := 0; i < len(locs); i++ {
+		start, end := locs[i][0], locs[i][1]
+		piece := text[start:end]
+
+		// Emulate `\s+(?!\S)`: a whitespace run followed by a non-space
+		// piece gives up its last rune, which becomes the leading space of
+		// the next word (" hello" instead of "  " + "hello").
+		if i+1 < len(locs) && end == locs[i+1][0] && isSpaceRun(piece) {
+			next := text[locs[i+1][0]:locs[i+1][1]]
+			r, size := utf8.DecodeLastRuneInString(piece)
+			if r == ' ' && takesLeadingSpace(next) {
+				if len(piece) > size {
+					pieces = append(pieces, piece[:len(piece)-size])
+				}
+				locs[i+1][0] = end - size
+				continue
+			}
+		}
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+func isSpaceRun(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) || r == '\r' || r == '\n' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// takesLeadingSpace reports whether a piece's pattern admits an optional
+// leading space (letter runs and punctuation runs; digits do not).
+func takesLeadingSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return !unicode.IsSpace(r) && !unicode.IsNumber(r) && r != '\''
+}
@@ -0,0 +1,129 @@
+// Package tokenizer counts LLM tokens for context-window budgeting.
+//
+// Two counters are provided:
+//   - BPE: an exact byte-pair encoder driven by a tiktoken rank file
+//     (cl100k_base, o200k_base, ...), used for OpenAI-style models when
+//     the rank file is available on disk.
+//   - Estimator: a vocabulary-free approximation that runs the same
+//     pre-tokenization and scores each piece, used for every other model.
+//
+// Estimates can be corrected over time with a Calibrator fed from the
+// provider-reported usage of real requests.
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// Tokenizer counts tokens in a piece of text.
+type Tokenizer interface {
+	// Name identifies the encoding (e.g. "cl100k_base" or "estimate").
+	Name() string
+	// Count returns the number of tokens text encodes to.
+	Count(text string) int
+}
+
+// Encoding names understood by Registry.
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+)
+
+// DefaultDir returns the directory searched for tiktoken rank files
+// (~/.tinyclaw/tokenizers).
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tinyclaw", "tokenizers")
+}
+
+// Registry resolves a Tokenizer for a model, loading tiktoken rank files
+// from dir on first use. Models without a known encoding, or whose rank
+// file is missing, fall back to the Estimator.
+type Registry struct {
+	dir       string
+	mu        sync.Mutex
+	encodings map[string]Tokenizer
+	estimator *Estimator
+}
+
+// NewRegistry creates a Registry that looks for <encoding>.tiktoken files in dir.
+func NewRegistry(dir string) *Registry {
+	return &Registry{
+		dir:       dir,
+		encodings: make(map[string]Tokenizer),
+		estimator: NewEstimator(),
+	}
+}
+
+// ForModel returns the tokenizer for model. The model may carry a protocol
+// prefix ("openai/gpt-4o").
+func (r *Registry) ForModel(model string) Tokenizer {
+	enc := EncodingForModel(model)
+	if enc == "" || r.dir == "" {
+		return r.estimator
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tok, ok := r.encodings[enc]; ok {
+		return tok
+	}
+
+	var tok Tokenizer = r.estimator
+	path := filepath.Join(r.dir, enc+".tiktoken")
+	if _, err := os.Stat(path); err == nil {
+		bpe, err := LoadBPE(enc, path)
+		if err != nil {
+			logger.WarnCF("tokenizer", "Failed to load rank file, using estimator", map[string]any{
+				"encoding": enc,
+				"path":     path,
+				"error":    err.Error(),
+			})
+		} else {
+			tok = bpe
+		}
+	}
+	r.encodings[enc] = tok
+	return tok
+}
+
+// EncodingForModel returns the tiktoken encoding used by an OpenAI-style
+// model, or "" when the model does not use a known public encoding.
+func EncodingForModel(model string) string {
+	id := strings.ToLower(stripProtocol(model))
+	switch {
+	case strings.HasPrefix(id, "gpt-4o"),
+		strings.HasPrefix(id, "gpt-4.1"),
+		strings.HasPrefix(id, "gpt-4.5"),
+		strings.HasPrefix(id, "gpt-5"),
+		strings.HasPrefix(id, "chatgpt-4o"),
+		strings.HasPrefix(id, "o1"),
+		strings.HasPrefix(id, "o3"),
+		strings.HasPrefix(id, "o4"),
+		strings.HasPrefix(id, "codex"):
+		return EncodingO200K
+	case strings.HasPrefix(id, "gpt-4"),
+		strings.HasPrefix(id, "gpt-3.5"),
+		strings.HasPrefix(id, "text-embedding-3"),
+		strings.HasPrefix(id, "text-embedding-ada-002"):
+		return EncodingCL100K
+	}
+	return ""
+}
+
+// stripProtocol removes a leading "protocol/" prefix from a model reference.
+func stripProtocol(model string) string {
+	if idx := strings.LastIndex(model, "/"); idx >= 0 {
+		return model[idx+1:]
+	}
+	return model
+}
@@ -0,0 +1,212 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// rankFile renders tokens as a tiktoken rank file; rank = slice index.
+func rankFile(tokens []string) string {
+	var sb strings.Builder
+	for i, tok := range tokens {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), i)
+	}
+	return sb.String()
+}
+
+// byteTokens returns the 256 single-byte tokens every rank table starts with.
+func byteTokens() []string {
+	tokens := make([]string, 256)
+	for i := range tokens {
+		tokens[i] = string([]byte{byte(i)})
+	}
+	return tokens
+}
+
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"hello world", []string{"hello", " world"}},
+		{"hello  world", []string{"hello", " ", " world"}},
+		{"it's 12345!", []string{"it", "'s", " ", "123", "45", "!"}},
+		{"a\n\nb", []string{"a", "\n\n", "b"}},
+	}
+	for _, tt := range tests {
+		got := splitPieces(tt.text)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitPieces(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if strings.Join(got, "") != tt.text {
+			t.Errorf("splitPieces(%q) does not round-trip: %q", tt.text, got)
+		}
+	}
+}
+
+func TestBPE_MergesByRank(t *testing.T) {
+	tokens := append(byteTokens(), "he", "ll", "hell", "hello", " w", " wo", "or", "ld")
+	bpe, err := ParseBPE("test", strings.NewReader(rankFile(tokens)))
+	if err != nil {
+		t.Fatalf("ParseBPE: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},             // whole piece is a token
+		{"hell", 1},              // he + ll -> hell
+		{" world", 3},            // " wo", "r", "ld"
+		{"hello world", 4},       // "hello", " wo", "r", "ld"
+		{"xyz", 3},               // no merges
+		{"hello hello hello", 5}, // "hello", then " ", "hello" twice
+	}
+	for _, tt := range tests {
+		if got := bpe.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseBPE_Errors(t *testing.T) {
+	if _, err := ParseBPE("empty", strings.NewReader("")); err == nil {
+		t.Error("expected error for empty rank table")
+	}
+	if _, err := ParseBPE("bad", strings.NewReader("!!! 1\n")); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+	if _, err := ParseBPE("bad", strings.NewReader("aGk= x\n")); err == nil {
+		t.Error("expected error for invalid rank")
+	}
+}
+
+func TestEstimator(t *testing.T) {
+	e := NewEstimator()
+
+	if got := e.Count(""); got != 0 {
+		t.Errorf("Count(\"\") = %d, want 0", got)
+	}
+	if got := e.Count("hello world"); got != 2 {
+		t.Errorf("Count(hello world) = %d, want 2", got)
+	}
+	// Three digit groups.
+	if got := e.Count("1234567"); got != 3 {
+		t.Errorf("Count(1234567) = %d, want 3", got)
+	}
+	// CJK text is denser than English per character.
+	english := e.Count("the weather is nice")
+	cjk := e.Count("今天天气很好")
+	if cjk <= english {
+		t.Errorf("expected CJK (%d) to exceed English (%d) token count", cjk, english)
+	}
+	// Code with symbols costs more than prose of the same length.
+	prose := e.Count("one two three four five six")
+	code := e.Count(`{"a":[1,2],"b":{}}`)
+	if code <= prose/2 {
+		t.Errorf("expected code (%d) to cost a meaningful share of prose (%d)", code, prose)
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o":                      EncodingO200K,
+		"openai/gpt-4o-mini":          EncodingO200K,
+		"o3-mini":                     EncodingO200K,
+		"gpt-4-turbo":                 EncodingCL100K,
+		"gpt-3.5-turbo":               EncodingCL100K,
+		"anthropic/claude-sonnet-4.6": "",
+		"llama3":                      "",
+	}
+	for model, want := range tests {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestRegistry_ForModel(t *testing.T) {
+	dir := t.TempDir()
+	tokens := append(byteTokens(), "hi")
+	if err := os.WriteFile(filepath.Join(dir, EncodingCL100K+".tiktoken"), []byte(rankFile(tokens)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(dir)
+
+	if got := r.ForModel("gpt-4").Name(); got != EncodingCL100K {
+		t.Errorf("gpt-4 tokenizer = %q, want %q", got, EncodingCL100K)
+	}
+	// Rank file missing: falls back to the estimator.
+	if got := r.ForModel("gpt-4o").Name(); got != "estimate" {
+		t.Errorf("gpt-4o tokenizer = %q, want estimate", got)
+	}
+	if got := r.ForModel("claude-opus-4-6").Name(); got != "estimate" {
+		t.Errorf("claude tokenizer = %q, want estimate", got)
+	}
+	if got := r.ForModel("gpt-4").Count("hi"); got != 1 {
+		t.Errorf("Count(hi) = %d, want 1", got)
+	}
+}
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model  string
+		window int
+		ok     bool
+	}{
+		{"claude-opus-4-6", 200000, true},
+		{"anthropic/claude-sonnet-4.6", 200000, true},
+		{"gpt-4o-mini", 128000, true},
+		{"gpt-4", 8192, true},
+		{"gpt-4-turbo-preview", 128000, true},
+		{"gpt-4-1106-preview", 128000, true},
+		{"gpt-4-0125-preview", 128000, true},
+		{"gpt-4.5-preview", 128000, true},
+		{"gpt-4-0613", 8192, true},
+		{"GPT-4.1", 1047576, true},
+		{"my-local-model", 0, false},
+	}
+	for _, tt := range tests {
+		info, ok := LookupModel(tt.model)
+		if ok != tt.ok || info.ContextWindow != tt.window {
+			t.Errorf("LookupModel(%q) = (%d, %v), want (%d, %v)", tt.model, info.ContextWindow, ok, tt.window, tt.ok)
+		}
+	}
+}
+
+func TestCalibrator(t *testing.T) {
+	c := NewCalibrator()
+
+	if got := c.Adjust("m", 100); got != 100 {
+		t.Errorf("uncalibrated Adjust = %d, want 100", got)
+	}
+
+	c.Observe("m", 100, 120)
+	if got := c.Adjust("m", 100); got != 120 {
+		t.Errorf("Adjust after first sample = %d, want 120", got)
+	}
+
+	// Subsequent samples move the ratio gradually.
+	c.Observe("m", 100, 100)
+	if r := c.Ratio("m"); r <= 1.0 || r >= 1.2 {
+		t.Errorf("ratio after second sample = %f, want between 1.0 and 1.2", r)
+	}
+
+	// Outliers are clamped.
+	c.Observe("other", 100, 10000)
+	if r := c.Ratio("other"); r != calibrationMaxRatio {
+		t.Errorf("ratio = %f, want clamp to %f", r, calibrationMaxRatio)
+	}
+
+	// Invalid samples are ignored.
+	c.Observe("none", 0, 50)
+	if r := c.Ratio("none"); r != 1.0 {
+		t.Errorf("ratio = %f, want 1.0", r)
+	}
+}
@@ -11,6 +11,7 @@ import (
 // EditFileTool edits a file by replacing old_text with new_text.
 // The old_text must exist exactly in the file.
 type EditFileTool struct {
+	journaled
 	fs fileSystem
 }
 
@@ -70,13 +71,16 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("new_text is required")
 	}
 
-	if err := editFile(t.fs, path, oldText, newText); err != nil {
+	sysFs, rec := t.begin(ctx, t.fs, t.Name())
+	defer t.commit(rec)
+	if err := editFile(sysFs, path, oldText, newText); err != nil {
 		return ErrorResult(err.Error())
 	}
 	return SilentResult("File edited: " + path)
 }
 
 type AppendFileTool struct {
+	journaled
 	fs fileSystem
 }
 
@@ -126,7 +130,9 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]any) *Tool
 		return ErrorResult("content is required")
 	}
 
-	if err := appendFile(t.fs, path, content); err != nil {
+	sysFs, rec := t.begin(ctx, t.fs, t.Name())
+	defer t.commit(rec)
+	if err := appendFile(sysFs, path, content); err != nil {
 		return ErrorResult(err.Error())
 	}
 	return SilentResult("Appended to " + path)
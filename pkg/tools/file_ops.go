@@ -0,0 +1,264 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// trashDirName is the workspace directory delete_file moves files into.
+	trashDirName   = ".trash"
+	trashIDFormat  = "20060102-150405.000000000"
+	trashRetention = 7 * 24 * time.Hour
+)
+
+// MoveFileTool moves or renames a file or directory.
+type MoveFileTool struct {
+	journaled
+	fs fileSystem
+}
+
+func NewMoveFileTool(workspace string, restrict bool) *MoveFileTool {
+	return &MoveFileTool{fs: newFileSystem(workspace, restrict)}
+}
+
+func (t *MoveFileTool) Name() string {
+	return "move_file"
+}
+
+func (t *MoveFileTool) Description() string {
+	return "Move or rename a file or directory, creating the destination's parent directories. " +
+		"Fails if the destination exists unless overwrite is true."
+}
+
+func (t *MoveFileTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"source": map[string]any{
+				"type":        "string",
+				"description": "Path to move",
+			},
+			"destination": map[string]any{
+				"type":        "string",
+				"description": "New path",
+			},
+			"overwrite": map[string]any{
+				"type":        "boolean",
+				"description": "Replace an existing file at the destination",
+			},
+		},
+		"required": []string{"source", "destination"},
+	}
+}
+
+func (t *MoveFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	source, ok := args["source"].(string)
+	if !ok || source == "" {
+		return ErrorResult("source is required")
+	}
+	destination, ok := args["destination"].(string)
+	if !ok || destination == "" {
+		return ErrorResult("destination is required")
+	}
+	overwrite, _ := args["overwrite"].(bool)
+
+	if _, err := t.fs.Stat(source); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to move: %v", err))
+	}
+	if info, err := t.fs.Stat(destination); err == nil {
+		if !overwrite {
+			return ErrorResult("destination already exists: " + destination + " (set overwrite to replace it)")
+		}
+		if info.IsDir() {
+			return ErrorResult("destination is a directory: " + destination)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return ErrorResult(fmt.Sprintf("failed to move: %v", err))
+	}
+
+	sysFs, rec := t.begin(ctx, t.fs, t.Name())
+	defer t.commit(rec)
+	if err := sysFs.Rename(source, destination); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to move: %v", err))
+	}
+	return SilentResult(fmt.Sprintf("Moved %s to %s", source, destination))
+}
+
+// DeleteFileTool deletes files by moving them into the workspace trash,
+// from where they can be restored.
+type DeleteFileTool struct {
+	journaled
+	fs        fileSystem
+	workspace string
+	restrict  bool
+	now       func() time.Time
+}
+
+func NewDeleteFileTool(workspace string, restrict bool) *DeleteFileTool {
+	return &DeleteFileTool{
+		fs:        newFileSystem(workspace, restrict),
+		workspace: workspace,
+		restrict:  restrict,
+		now:       time.Now,
+	}
+}
+
+func (t *DeleteFileTool) Name() string {
+	return "delete_file"
+}
+
+func (t *DeleteFileTool) Description() string {
+	return "Delete a file or directory. Deleted paths are moved to the workspace trash (" + trashDirName +
+		") and kept for 7 days; call again with restore=true to undo the most recent deletion of a path. " +
+		"Directories require recursive=true."
+}
+
+func (t *DeleteFileTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path to delete or restore",
+			},
+			"recursive": map[string]any{
+				"type":        "boolean",
+				"description": "Allow deleting a directory and its contents",
+			},
+			"permanent": map[string]any{
+				"type":        "boolean",
+				"description": "Delete immediately instead of moving to the trash",
+			},
+			"restore": map[string]any{
+				"type":        "boolean",
+				"description": "Restore the most recently deleted copy of path from the trash",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *DeleteFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+	key, err := t.trashKey(path)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	sysFs, rec := t.begin(ctx, t.fs, t.Name())
+	defer t.commit(rec)
+	if restore, _ := args["restore"].(bool); restore {
+		return t.restore(sysFs, path, key)
+	}
+
+	info, err := t.fs.Stat(path)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to delete: %v", err))
+	}
+	if recursive, _ := args["recursive"].(bool); info.IsDir() && !recursive {
+		return ErrorResult(path + " is a directory; set recursive to delete it")
+	}
+
+	// Anything already in the trash, or asked to skip it, goes for good.
+	if permanent, _ := args["permanent"].(bool); permanent || key == trashDirName ||
+		strings.HasPrefix(key, trashDirName+string(filepath.Separator)) {
+		if err := sysFs.RemoveAll(path); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to delete: %v", err))
+		}
+		return SilentResult("Deleted " + path)
+	}
+
+	t.pruneTrash()
+	id := t.now().UTC().Format(trashIDFormat)
+	if err := sysFs.Rename(path, filepath.Join(t.trashDir(), id, key)); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to move %s to the trash: %v", path, err))
+	}
+	return SilentResult(fmt.Sprintf("Deleted %s (moved to %s; restore with delete_file restore=true)",
+		path, filepath.Join(trashDirName, id, key)))
+}
+
+func (t *DeleteFileTool) restore(sysFs fileSystem, path, key string) *ToolResult {
+	if _, err := t.fs.Stat(path); err == nil {
+		return ErrorResult("cannot restore: " + path + " exists")
+	}
+	ids, err := t.fs.ReadDir(t.trashDir())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ErrorResult(fmt.Sprintf("failed to read the trash: %v", err))
+	}
+	// IDs are timestamps; look through the newest first.
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Name() > ids[j].Name() })
+	for _, id := range ids {
+		trashed := filepath.Join(t.trashDir(), id.Name(), key)
+		if _, err := t.fs.Stat(trashed); err != nil {
+			continue
+		}
+		if err := sysFs.Rename(trashed, path); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to restore: %v", err))
+		}
+		return SilentResult("Restored " + path)
+	}
+	return ErrorResult("no deleted copy of " + path + " in the trash")
+}
+
+// trashDir is relative when restricted, since sandboxFs resolves paths
+// against the workspace.
+func (t *DeleteFileTool) trashDir() string {
+	if t.restrict {
+		return trashDirName
+	}
+	return filepath.Join(t.workspace, trashDirName)
+}
+
+// trashKey maps path to its location inside a trash entry: its path
+// relative to the workspace, or below "external" for paths outside it.
+func (t *DeleteFileTool) trashKey(path string) (string, error) {
+	if t.restrict {
+		rel, err := getSafeRelPath(t.workspace, path)
+		if err != nil {
+			return "", err
+		}
+		if rel == "." {
+			return "", errors.New("refusing to delete the workspace root")
+		}
+		return rel, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	workspace, err := filepath.Abs(t.workspace)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace path: %w", err)
+	}
+	if abs == workspace {
+		return "", errors.New("refusing to delete the workspace root")
+	}
+	if isWithinWorkspace(abs, workspace) {
+		return filepath.Rel(workspace, abs)
+	}
+	return filepath.Join("external", strings.TrimPrefix(abs, filepath.VolumeName(abs))), nil
+}
+
+// pruneTrash drops trash entries older than trashRetention.
+func (t *DeleteFileTool) pruneTrash() {
+	ids, err := t.fs.ReadDir(t.trashDir())
+	if err != nil {
+		return
+	}
+	for _, id := range ids {
+		deleted, err := time.Parse(trashIDFormat, id.Name())
+		if err == nil && t.now().Sub(deleted) > trashRetention {
+			t.fs.RemoveAll(filepath.Join(t.trashDir(), id.Name()))
+		}
+	}
+}
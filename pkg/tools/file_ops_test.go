@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMoveFileTool(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"a.txt": "a", "b.txt": "b"})
+	tool := NewMoveFileTool(workspace, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"source": "a.txt", "destination": "b.txt"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "already exists") {
+		t.Fatalf("expected refusal to overwrite, got %+v", result)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"source": "a.txt", "destination": "sub/dir/c.txt"})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "sub", "dir", "c.txt")); err != nil || string(data) != "a" {
+		t.Fatalf("moved file: %q, %v", data, err)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"source": "b.txt", "destination": "sub/dir/c.txt", "overwrite": true})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "sub", "dir", "c.txt")); string(data) != "b" {
+		t.Errorf("overwrite left %q", data)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"source": "sub/dir/c.txt", "destination": "../escaped.txt"})
+	if !result.IsError {
+		t.Error("expected moving outside the workspace to fail")
+	}
+}
+
+func TestDeleteFileTool_TrashAndRestore(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"notes/today.md": "v1", "dir/x": "x"})
+	tool := NewDeleteFileTool(workspace, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"path": "notes/today.md"})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "notes", "today.md")); !os.IsNotExist(err) {
+		t.Fatalf("file still present: %v", err)
+	}
+
+	// A newer file at the same path is deleted too; restore brings back
+	// the most recent one.
+	writeTree(t, workspace, map[string]string{"notes/today.md": "v2"})
+	tool.now = func() time.Time { return time.Now().Add(time.Second) }
+	if result = tool.Execute(ctx, map[string]any{"path": "notes/today.md"}); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if result = tool.Execute(ctx, map[string]any{"path": "notes/today.md", "restore": true}); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "notes", "today.md")); string(data) != "v2" {
+		t.Errorf("restored %q, want v2", data)
+	}
+	result = tool.Execute(ctx, map[string]any{"path": "notes/today.md", "restore": true})
+	if !result.IsError || !strings.Contains(result.ForLLM, "exists") {
+		t.Errorf("expected restore over an existing file to fail, got %+v", result)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"path": "dir"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "recursive") {
+		t.Errorf("expected directory delete without recursive to fail, got %+v", result)
+	}
+	if result = tool.Execute(ctx, map[string]any{"path": "dir", "recursive": true, "permanent": true}); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "dir")); !os.IsNotExist(err) {
+		t.Errorf("permanent delete left dir: %v", err)
+	}
+
+	for _, path := range []string{".", "../outside"} {
+		if result = tool.Execute(ctx, map[string]any{"path": path, "recursive": true}); !result.IsError {
+			t.Errorf("expected deleting %q to fail", path)
+		}
+	}
+}
+
+func TestDeleteFileTool_PrunesOldTrash(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"old.txt": "", "new.txt": ""})
+	tool := NewDeleteFileTool(workspace, true)
+	ctx := context.Background()
+
+	tool.now = func() time.Time { return time.Now().Add(-8 * 24 * time.Hour) }
+	if result := tool.Execute(ctx, map[string]any{"path": "old.txt"}); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	tool.now = time.Now
+	if result := tool.Execute(ctx, map[string]any{"path": "new.txt"}); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(workspace, trashDirName))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected only the recent trash entry, got %v (%v)", entries, err)
+	}
+	if result := tool.Execute(ctx, map[string]any{"path": "old.txt", "restore": true}); !result.IsError {
+		t.Error("expected the pruned file to be unrecoverable")
+	}
+}
+
+func TestDeleteFileTool_Unrestricted(t *testing.T) {
+	workspace, outside := t.TempDir(), t.TempDir()
+	target := filepath.Join(outside, "data.csv")
+	writeTree(t, outside, map[string]string{"data.csv": "1,2"})
+	tool := NewDeleteFileTool(workspace, false)
+	ctx := context.Background()
+
+	if result := tool.Execute(ctx, map[string]any{"path": target}); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	trashed := filepath.Join(workspace, trashDirName, "*", "external", outside, "data.csv")
+	if matches, _ := filepath.Glob(trashed); len(matches) != 1 {
+		t.Fatalf("expected the file under the workspace trash, got %v", matches)
+	}
+	if result := tool.Execute(ctx, map[string]any{"path": target, "restore": true}); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "1,2" {
+		t.Errorf("restored %q", data)
+	}
+}
@@ -103,7 +103,8 @@ func (t *ReadFileTool) Name() string {
 }
 
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file"
+	return "Read the contents of a file. Pass offset and limit to read a range of lines, " +
+		"which are then prefixed with their line numbers."
 }
 
 func (t *ReadFileTool) Parameters() map[string]any {
@@ -114,6 +115,14 @@ func (t *ReadFileTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Line number to start reading from (1-based)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of lines to read",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -129,10 +138,45 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
-	return NewToolResult(string(content))
+
+	offset, limit := intArg(args, "offset"), intArg(args, "limit")
+	if offset == 0 && limit == 0 {
+		return NewToolResult(string(content))
+	}
+	return NewToolResult(readLineRange(string(content), max(offset, 1), limit))
+}
+
+// readLineRange returns limit lines of content starting at line offset
+// (1-based), numbered, with a note on where to continue. A limit of 0
+// reads to the end.
+func readLineRange(content string, offset, limit int) string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+	if offset > total {
+		return fmt.Sprintf("[File has %d lines; offset %d is past the end.]", total, offset)
+	}
+	end := total
+	if limit > 0 {
+		end = min(offset-1+limit, total)
+	}
+
+	var sb strings.Builder
+	for i := offset - 1; i < end; i++ {
+		fmt.Fprintf(&sb, "%6d\t%s\n", i+1, strings.TrimSuffix(lines[i], "\n"))
+	}
+	if end < total {
+		fmt.Fprintf(&sb, "[Showing lines %d-%d of %d. Use offset=%d to continue.]", offset, end, total, end+1)
+	} else {
+		fmt.Fprintf(&sb, "[End of file, %d lines.]", total)
+	}
+	return sb.String()
 }
 
 type WriteFileTool struct {
+	journaled
 	fs fileSystem
 }
 
@@ -182,7 +226,9 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]any) *ToolR
 		return ErrorResult("content is required")
 	}
 
-	if err := t.fs.WriteFile(path, []byte(content)); err != nil {
+	sysFs, rec := t.begin(ctx, t.fs, t.Name())
+	defer t.commit(rec)
+	if err := sysFs.WriteFile(path, []byte(content)); err != nil {
 		return ErrorResult(err.Error())
 	}
 
@@ -255,6 +301,20 @@ type fileSystem interface {
 	ReadFile(path string) ([]byte, error)
 	WriteFile(path string, data []byte) error
 	ReadDir(path string) ([]os.DirEntry, error)
+	// Stat describes path without following a final symlink.
+	Stat(path string) (fs.FileInfo, error)
+	// Rename moves oldPath to newPath, creating newPath's parent directories.
+	Rename(oldPath, newPath string) error
+	RemoveAll(path string) error
+	// WithDirFS calls fn with a read-only fs.FS rooted at the directory path.
+	WithDirFS(path string, fn func(fsys fs.FS) error) error
+}
+
+func newFileSystem(workspace string, restrict bool) fileSystem {
+	if restrict {
+		return &sandboxFs{workspace: workspace}
+	}
+	return &hostFs{}
 }
 
 // hostFs is an unrestricted fileReadWriter that operates directly on the host filesystem.
@@ -300,6 +360,25 @@ func (h *hostFs) WriteFile(path string, data []byte) error {
 	return nil
 }
 
+func (h *hostFs) Stat(path string) (fs.FileInfo, error) {
+	return os.Lstat(path)
+}
+
+func (h *hostFs) Rename(oldPath, newPath string) error {
+	if err := os.MkdirAll(filepath.Dir(newPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+func (h *hostFs) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (h *hostFs) WithDirFS(path string, fn func(fsys fs.FS) error) error {
+	return fn(os.DirFS(path))
+}
+
 // sandboxFs is a sandboxed fileSystem that operates within a strictly defined workspace using os.Root.
 type sandboxFs struct {
 	workspace string
@@ -385,6 +464,50 @@ func (r *sandboxFs) ReadDir(path string) ([]os.DirEntry, error) {
 	return entries, err
 }
 
+func (r *sandboxFs) Stat(path string) (fs.FileInfo, error) {
+	var info fs.FileInfo
+	err := r.execute(path, func(root *os.Root, relPath string) error {
+		var err error
+		info, err = root.Lstat(relPath)
+		return err
+	})
+	return info, err
+}
+
+func (r *sandboxFs) Rename(oldPath, newPath string) error {
+	newRel, err := getSafeRelPath(r.workspace, newPath)
+	if err != nil {
+		return err
+	}
+	return r.execute(oldPath, func(root *os.Root, oldRel string) error {
+		if dir := filepath.Dir(newRel); dir != "." {
+			if err := root.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("failed to create parent directories: %w", err)
+			}
+		}
+		return root.Rename(oldRel, newRel)
+	})
+}
+
+func (r *sandboxFs) RemoveAll(path string) error {
+	return r.execute(path, func(root *os.Root, relPath string) error {
+		if relPath == "." {
+			return errors.New("refusing to remove the workspace root")
+		}
+		return root.RemoveAll(relPath)
+	})
+}
+
+func (r *sandboxFs) WithDirFS(path string, fn func(fsys fs.FS) error) error {
+	return r.execute(path, func(root *os.Root, relPath string) error {
+		fsys, err := fs.Sub(root.FS(), filepath.ToSlash(relPath))
+		if err != nil {
+			return err
+		}
+		return fn(fsys)
+	})
+}
+
 // Helper to get a safe relative path for os.Root usage
 func getSafeRelPath(workspace, path string) (string, error) {
 	if workspace == "" {
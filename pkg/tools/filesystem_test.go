@@ -487,3 +487,19 @@ func TestRootRW_Write(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, newData, content)
 }
+
+func TestFilesystemTool_ReadFile_LineRange(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "lines.txt"), []byte("one\ntwo\nthree\nfour\n"), 0o644))
+	tool := NewReadFileTool(workspace, true)
+
+	result := tool.Execute(context.Background(), map[string]any{"path": "lines.txt", "offset": 2.0, "limit": 2.0})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Equal(t, "     2\ttwo\n     3\tthree\n[Showing lines 2-3 of 4. Use offset=4 to continue.]", result.ForLLM)
+
+	result = tool.Execute(context.Background(), map[string]any{"path": "lines.txt", "offset": 4.0})
+	assert.Equal(t, "     4\tfour\n[End of file, 4 lines.]", result.ForLLM)
+
+	result = tool.Execute(context.Background(), map[string]any{"path": "lines.txt", "offset": 9.0})
+	assert.Contains(t, result.ForLLM, "past the end")
+}
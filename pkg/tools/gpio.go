@@ -0,0 +1,705 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/hardware"
+)
+
+const (
+	gpioDefaultWatch   = 10 * time.Second
+	gpioMaxWatch       = 60 * time.Second
+	gpioDefaultEvents  = 20
+	gpioMaxEvents      = 100
+	gpioWatchPollEvery = 200 * time.Millisecond
+)
+
+// GPIOTool reads and drives GPIO lines through the Linux GPIO character
+// device and PWM channels through sysfs. Lines written as outputs stay
+// requested, holding their value, until released.
+type GPIOTool struct {
+	open  func(chip string) (hardware.GPIOChip, error)
+	chips func() ([]string, error)
+	pwm   *hardware.PWM
+
+	mu   sync.Mutex
+	held map[string]*heldLine // by gpioKey
+}
+
+type heldLine struct {
+	line      hardware.GPIOLine
+	activeLow bool
+}
+
+// GPIOToolOptions configures a GPIOTool. Open and Chips default to the
+// character device (hardware.OpenGPIOChip and hardware.GPIOChips) and can
+// be replaced with simulated chips; PWMRoot defaults to /sys/class/pwm.
+type GPIOToolOptions struct {
+	Open    func(chip string) (hardware.GPIOChip, error)
+	Chips   func() ([]string, error)
+	PWMRoot string
+}
+
+func NewGPIOTool() *GPIOTool {
+	return NewGPIOToolWithOptions(GPIOToolOptions{})
+}
+
+func NewGPIOToolWithOptions(opts GPIOToolOptions) *GPIOTool {
+	if opts.Open == nil {
+		opts.Open = hardware.OpenGPIOChip
+	}
+	if opts.Chips == nil {
+		opts.Chips = hardware.GPIOChips
+	}
+	pwm := hardware.NewPWM()
+	if opts.PWMRoot != "" {
+		pwm.Root = opts.PWMRoot
+	}
+	return &GPIOTool{
+		open:  opts.Open,
+		chips: opts.Chips,
+		pwm:   pwm,
+		held:  make(map[string]*heldLine),
+	}
+}
+
+func (t *GPIOTool) Name() string {
+	return "gpio"
+}
+
+func (t *GPIOTool) Description() string {
+	return "Read and drive GPIO pins and PWM outputs, e.g. buttons, relays and LEDs. Actions: list (GPIO and PWM chips, or the lines of one chip), read (line value), write (drive a line high/low; it stays driven until release), release (free a written line), watch (record edges on an input for a bounded time), pwm (show or set a PWM channel). Linux only."
+}
+
+func (t *GPIOTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "read", "write", "release", "watch", "pwm"},
+				"description": "Action to perform: list (chips, or lines of a chip), read (read a line), write (set an output line), release (free a line set by write), watch (wait for edges on an input line), pwm (show or set a PWM channel)",
+			},
+			"chip": map[string]any{
+				"type":        "string",
+				"description": "GPIO chip (e.g. \"0\" for /dev/gpiochip0). For pwm, the PWM chip number (\"0\" for /sys/class/pwm/pwmchip0).",
+			},
+			"line": map[string]any{
+				"description": "Line offset on the chip (integer), or line name (string, e.g. \"GPIOA14\"); a name is searched on every chip when chip is omitted.",
+			},
+			"value": map[string]any{
+				"description": "Value for write: true/false, 1/0 or \"high\"/\"low\". With active_low, true drives the pin low.",
+			},
+			"active_low": map[string]any{
+				"type":        "boolean",
+				"description": "Treat the line as active-low. Default: false.",
+			},
+			"bias": map[string]any{
+				"type":        "string",
+				"enum":        []string{hardware.BiasPullUp, hardware.BiasPullDown, hardware.BiasDisabled},
+				"description": "Input bias for read/watch, e.g. pull-up for a button to ground.",
+			},
+			"edge": map[string]any{
+				"type":        "string",
+				"enum":        []string{hardware.EdgeRising, hardware.EdgeFalling, hardware.EdgeBoth},
+				"description": "Edges to watch for. Default: both.",
+			},
+			"debounce_ms": map[string]any{
+				"type":        "integer",
+				"description": "Debounce period for watch, in milliseconds.",
+			},
+			"timeout": map[string]any{
+				"type":        "integer",
+				"description": "Seconds to watch (1-60). Default: 10.",
+			},
+			"max_events": map[string]any{
+				"type":        "integer",
+				"description": "Stop watching after this many edges (1-100). Default: 20.",
+			},
+			"channel": map[string]any{
+				"type":        "integer",
+				"description": "PWM channel on the chip. Required for pwm.",
+			},
+			"frequency": map[string]any{
+				"type":        "number",
+				"description": "PWM frequency in Hz. Setting frequency, duty or enable changes the channel.",
+			},
+			"duty": map[string]any{
+				"type":        "number",
+				"description": "PWM duty cycle in percent (0-100).",
+			},
+			"polarity": map[string]any{
+				"type":        "string",
+				"enum":        []string{"normal", "inversed"},
+				"description": "PWM polarity.",
+			},
+			"enable": map[string]any{
+				"type":        "boolean",
+				"description": "Enable or disable the PWM output. Default when changing settings: true.",
+			},
+			"confirm": map[string]any{
+				"type":        "boolean",
+				"description": "Must be true for write and for changing PWM. Safety guard to prevent accidental writes.",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *GPIOTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, ok := args["action"].(string)
+	if !ok {
+		return ErrorResult("action is required")
+	}
+
+	switch action {
+	case "list":
+		return t.list(args)
+	case "read":
+		return t.read(args)
+	case "write":
+		return t.write(args)
+	case "release":
+		return t.release(args)
+	case "watch":
+		return t.watch(ctx, args)
+	case "pwm":
+		return t.pwmAction(args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: list, read, write, release, watch, pwm)", action))
+	}
+}
+
+// openChip opens a GPIO chip, turning errors into tool results.
+func (t *GPIOTool) openChip(chip string) (hardware.GPIOChip, *ToolResult) {
+	if _, ok := hardware.GPIOChipPath(chip); !ok {
+		return nil, ErrorResult("invalid chip identifier: must be a number (e.g. \"0\" for /dev/gpiochip0)")
+	}
+	c, err := t.open(chip)
+	if errors.Is(err, hardware.ErrUnsupported) {
+		return nil, ErrorResult("GPIO is only supported on Linux. This tool requires /dev/gpiochip* device files.")
+	}
+	if err != nil {
+		return nil, ErrorResult(err.Error())
+	}
+	return c, nil
+}
+
+// gpioKey identifies a line across calls.
+func gpioKey(chip string, offset int) string {
+	return strings.TrimPrefix(chip, "gpiochip") + ":" + strconv.Itoa(offset)
+}
+
+// list describes the GPIO and PWM chips, or the lines of one GPIO chip.
+func (t *GPIOTool) list(args map[string]any) *ToolResult {
+	if chip := gpioChipArg(args); chip != "" {
+		return t.listLines(chip)
+	}
+
+	ids, err := t.chips()
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to scan for GPIO chips: %v", err))
+	}
+	type chipEntry struct {
+		Chip string `json:"chip"`
+		hardware.GPIOChipInfo
+	}
+	gpioChips := make([]chipEntry, 0, len(ids))
+	for _, id := range ids {
+		c, errResult := t.openChip(id)
+		if errResult != nil {
+			return errResult
+		}
+		info, err := c.Info()
+		c.Close()
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		gpioChips = append(gpioChips, chipEntry{Chip: id, GPIOChipInfo: info})
+	}
+	pwmChips, err := t.pwm.Chips()
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to scan for PWM chips: %v", err))
+	}
+
+	if len(gpioChips) == 0 && len(pwmChips) == 0 {
+		return SilentResult(
+			"No GPIO or PWM chips found. You may need to:\n1. Check that GPIO is enabled in device tree\n2. Configure pinmux for your board (see hardware skill)\n3. Run as root or add the user to the gpio group",
+		)
+	}
+	result, err := json.MarshalIndent(map[string]any{
+		"gpio_chips": gpioChips,
+		"pwm_chips":  pwmChips,
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal chip list: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+func (t *GPIOTool) listLines(chip string) *ToolResult {
+	c, errResult := t.openChip(chip)
+	if errResult != nil {
+		return errResult
+	}
+	defer c.Close()
+
+	info, err := c.Info()
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	lines := make([]hardware.GPIOLineInfo, 0, info.Lines)
+	for i := range info.Lines {
+		li, err := c.LineInfo(i)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		lines = append(lines, li)
+	}
+
+	result, err := json.MarshalIndent(map[string]any{
+		"chip":  info.Name,
+		"label": info.Label,
+		"lines": lines,
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal line list: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+// gpioChipArg returns the chip argument, accepting a string or a number.
+func gpioChipArg(args map[string]any) string {
+	switch v := args["chip"].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.Itoa(int(v))
+	}
+	return ""
+}
+
+// resolveLine finds the chip and offset named by the chip and line
+// arguments.
+func (t *GPIOTool) resolveLine(args map[string]any) (string, int, *ToolResult) {
+	chip := gpioChipArg(args)
+	switch v := args["line"].(type) {
+	case float64:
+		if chip == "" {
+			return "", 0, ErrorResult("chip is required when line is an offset")
+		}
+		return t.checkOffset(chip, int(v))
+	case string:
+		if n, err := strconv.Atoi(v); err == nil && chip != "" {
+			return t.checkOffset(chip, n)
+		}
+		return t.findLine(chip, v)
+	default:
+		return "", 0, ErrorResult("line is required (an offset such as 14, or a line name)")
+	}
+}
+
+func (t *GPIOTool) checkOffset(chip string, offset int) (string, int, *ToolResult) {
+	c, errResult := t.openChip(chip)
+	if errResult != nil {
+		return "", 0, errResult
+	}
+	defer c.Close()
+	info, err := c.Info()
+	if err != nil {
+		return "", 0, ErrorResult(err.Error())
+	}
+	if offset < 0 || offset >= info.Lines {
+		return "", 0, ErrorResult(fmt.Sprintf("line must be between 0 and %d on chip %s", info.Lines-1, chip))
+	}
+	return chip, offset, nil
+}
+
+// findLine looks a line up by name on one chip, or on every chip.
+func (t *GPIOTool) findLine(chip, name string) (string, int, *ToolResult) {
+	ids := []string{chip}
+	if chip == "" {
+		var err error
+		if ids, err = t.chips(); err != nil {
+			return "", 0, ErrorResult(fmt.Sprintf("failed to scan for GPIO chips: %v", err))
+		}
+	}
+	for _, id := range ids {
+		c, errResult := t.openChip(id)
+		if errResult != nil {
+			return "", 0, errResult
+		}
+		info, err := c.Info()
+		if err != nil {
+			c.Close()
+			return "", 0, ErrorResult(err.Error())
+		}
+		for i := range info.Lines {
+			if li, err := c.LineInfo(i); err == nil && li.Name == name {
+				c.Close()
+				return id, i, nil
+			}
+		}
+		c.Close()
+	}
+	return "", 0, ErrorResult(fmt.Sprintf("no GPIO line named %q (use list with a chip to see line names)", name))
+}
+
+// lineConfig reads the input options shared by read and watch.
+func lineConfig(args map[string]any) (hardware.GPIOLineConfig, *ToolResult) {
+	var cfg hardware.GPIOLineConfig
+	cfg.ActiveLow, _ = args["active_low"].(bool)
+	if bias, ok := args["bias"].(string); ok {
+		switch bias {
+		case hardware.BiasPullUp, hardware.BiasPullDown, hardware.BiasDisabled:
+			cfg.Bias = bias
+		default:
+			return cfg, ErrorResult("bias must be pull-up, pull-down or disabled")
+		}
+	}
+	return cfg, nil
+}
+
+func (t *GPIOTool) lineResult(chip string, offset int, fields map[string]any) *ToolResult {
+	fields["chip"] = chip
+	fields["line"] = offset
+	result, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal result: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// read reads a line. A line held by write reports the value it drives.
+func (t *GPIOTool) read(args map[string]any) *ToolResult {
+	chip, offset, errResult := t.resolveLine(args)
+	if errResult != nil {
+		return errResult
+	}
+	cfg, errResult := lineConfig(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if h, ok := t.held[gpioKey(chip, offset)]; ok {
+		v, err := h.line.Value()
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return t.lineResult(chip, offset, map[string]any{"value": boolInt(v), "direction": "output"})
+	}
+
+	c, errResult := t.openChip(chip)
+	if errResult != nil {
+		return errResult
+	}
+	defer c.Close()
+	line, err := c.Request(offset, cfg)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	defer line.Close()
+	v, err := line.Value()
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return t.lineResult(chip, offset, map[string]any{"value": boolInt(v), "direction": "input"})
+}
+
+// parseLevel accepts true/false, 1/0 and high/low (or on/off).
+func parseLevel(v any) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	case string:
+		switch strings.ToLower(v) {
+		case "1", "high", "on", "true":
+			return true, true
+		case "0", "low", "off", "false":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// write drives a line, requesting it as an output on first use and
+// holding it so the value persists.
+func (t *GPIOTool) write(args map[string]any) *ToolResult {
+	confirm, _ := args["confirm"].(bool)
+	if !confirm {
+		return ErrorResult(
+			"write operations require confirm: true. Please confirm with the user before driving GPIO lines, as they may switch connected hardware.",
+		)
+	}
+	value, ok := parseLevel(args["value"])
+	if !ok {
+		return ErrorResult("value is required for write (true/false, 1/0 or \"high\"/\"low\")")
+	}
+	chip, offset, errResult := t.resolveLine(args)
+	if errResult != nil {
+		return errResult
+	}
+	activeLow, _ := args["active_low"].(bool)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := gpioKey(chip, offset)
+	if h, ok := t.held[key]; ok {
+		if h.activeLow == activeLow {
+			if err := h.line.SetValue(value); err != nil {
+				return ErrorResult(err.Error())
+			}
+			return t.lineResult(chip, offset, map[string]any{"value": boolInt(value), "held": true})
+		}
+		// Changing polarity needs a fresh request.
+		h.line.Close()
+		delete(t.held, key)
+	}
+
+	c, errResult := t.openChip(chip)
+	if errResult != nil {
+		return errResult
+	}
+	defer c.Close()
+	line, err := c.Request(offset, hardware.GPIOLineConfig{Output: true, Value: value, ActiveLow: activeLow})
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	t.held[key] = &heldLine{line: line, activeLow: activeLow}
+	return t.lineResult(chip, offset, map[string]any{"value": boolInt(value), "held": true})
+}
+
+// release frees a line held by write.
+func (t *GPIOTool) release(args map[string]any) *ToolResult {
+	chip, offset, errResult := t.resolveLine(args)
+	if errResult != nil {
+		return errResult
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := gpioKey(chip, offset)
+	h, ok := t.held[key]
+	if !ok {
+		return ErrorResult(fmt.Sprintf("line %d on chip %s is not held by this tool", offset, chip))
+	}
+	delete(t.held, key)
+	if err := h.line.Close(); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return SilentResult(fmt.Sprintf("Released line %d on chip %s", offset, chip))
+}
+
+// watch records edges on an input line until the timeout or event limit.
+func (t *GPIOTool) watch(ctx context.Context, args map[string]any) *ToolResult {
+	chip, offset, errResult := t.resolveLine(args)
+	if errResult != nil {
+		return errResult
+	}
+	cfg, errResult := lineConfig(args)
+	if errResult != nil {
+		return errResult
+	}
+	cfg.Edge = hardware.EdgeBoth
+	if e, ok := args["edge"].(string); ok {
+		switch e {
+		case hardware.EdgeRising, hardware.EdgeFalling, hardware.EdgeBoth:
+			cfg.Edge = e
+		default:
+			return ErrorResult("edge must be rising, falling or both")
+		}
+	}
+	if d, ok := args["debounce_ms"].(float64); ok {
+		if d < 0 || d > 1000 {
+			return ErrorResult("debounce_ms must be between 0 and 1000")
+		}
+		cfg.Debounce = time.Duration(d) * time.Millisecond
+	}
+	timeout := gpioDefaultWatch
+	if s, ok := args["timeout"].(float64); ok {
+		timeout = time.Duration(s) * time.Second
+		if timeout < time.Second || timeout > gpioMaxWatch {
+			return ErrorResult("timeout must be between 1 and 60 seconds")
+		}
+	}
+	maxEvents := gpioDefaultEvents
+	if n, ok := args["max_events"].(float64); ok {
+		maxEvents = int(n)
+		if maxEvents < 1 || maxEvents > gpioMaxEvents {
+			return ErrorResult("max_events must be between 1 and 100")
+		}
+	}
+
+	t.mu.Lock()
+	_, held := t.held[gpioKey(chip, offset)]
+	t.mu.Unlock()
+	if held {
+		return ErrorResult(fmt.Sprintf("line %d on chip %s is held as an output; release it first", offset, chip))
+	}
+
+	c, errResult := t.openChip(chip)
+	if errResult != nil {
+		return errResult
+	}
+	defer c.Close()
+	line, err := c.Request(offset, cfg)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	defer line.Close()
+
+	initial, err := line.Value()
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	type edgeEntry struct {
+		Edge     string `json:"edge"`
+		AfterMs  int64  `json:"after_ms"`
+		Time     string `json:"time"`
+		NewValue int    `json:"value"`
+	}
+	start := time.Now()
+	deadline := start.Add(timeout)
+	edges := []edgeEntry{}
+	for len(edges) < maxEvents && ctx.Err() == nil {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		ev, err := line.WaitEdge(min(remaining, gpioWatchPollEvery))
+		if errors.Is(err, hardware.ErrTimeout) {
+			continue
+		}
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		edges = append(edges, edgeEntry{
+			Edge:     ev.Edge,
+			AfterMs:  max(ev.Time.Sub(start).Milliseconds(), 0),
+			Time:     ev.Time.Format(time.RFC3339Nano),
+			NewValue: boolInt(ev.Edge == hardware.EdgeRising),
+		})
+	}
+
+	return t.lineResult(chip, offset, map[string]any{
+		"initial_value": boolInt(initial),
+		"edges":         edges,
+		"count":         len(edges),
+		"watched_ms":    time.Since(start).Milliseconds(),
+	})
+}
+
+// pwmAction shows a PWM channel, or changes it when frequency, duty,
+// polarity or enable is given.
+func (t *GPIOTool) pwmAction(args map[string]any) *ToolResult {
+	chip, err := strconv.Atoi(gpioChipArg(args))
+	if err != nil || chip < 0 {
+		return ErrorResult("chip is required for pwm (e.g. \"0\" for /sys/class/pwm/pwmchip0)")
+	}
+	channelF, ok := args["channel"].(float64)
+	if !ok || channelF < 0 {
+		return ErrorResult("channel is required for pwm")
+	}
+	channel := int(channelF)
+
+	freq, hasFreq := args["frequency"].(float64)
+	duty, hasDuty := args["duty"].(float64)
+	polarity, hasPolarity := args["polarity"].(string)
+	enable, hasEnable := args["enable"].(bool)
+	if !hasFreq && !hasDuty && !hasPolarity && !hasEnable {
+		state, err := t.pwm.State(chip, channel)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return t.pwmResult(chip, channel, state)
+	}
+
+	confirm, _ := args["confirm"].(bool)
+	if !confirm {
+		return ErrorResult(
+			"changing PWM requires confirm: true. Please confirm with the user before driving PWM outputs.",
+		)
+	}
+
+	// Unspecified settings keep their current values.
+	state, err := t.pwm.State(chip, channel)
+	if err != nil {
+		state = hardware.PWMState{} // not exported yet
+	}
+	if hasFreq {
+		if freq <= 0 || freq > 100e6 {
+			return ErrorResult("frequency must be between 0 and 100 MHz")
+		}
+		period := int64(math.Round(1e9 / freq))
+		if state.Period > 0 && !hasDuty {
+			// Keep the duty cycle as a fraction of the period.
+			state.Duty = int64(math.Round(float64(state.Duty) * float64(period) / float64(state.Period)))
+		}
+		state.Period = period
+	}
+	if state.Period <= 0 {
+		return ErrorResult("frequency is required: the channel has no period set")
+	}
+	if hasDuty {
+		if duty < 0 || duty > 100 {
+			return ErrorResult("duty must be between 0 and 100 percent")
+		}
+		state.Duty = int64(math.Round(float64(state.Period) * duty / 100))
+	}
+	if hasPolarity {
+		state.Polarity = polarity
+	}
+	state.Enabled = !hasEnable || enable
+
+	if err := t.pwm.Set(chip, channel, state); err != nil {
+		return ErrorResult(err.Error())
+	}
+	state, err = t.pwm.State(chip, channel)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return t.pwmResult(chip, channel, state)
+}
+
+func (t *GPIOTool) pwmResult(chip, channel int, s hardware.PWMState) *ToolResult {
+	fields := map[string]any{
+		"chip":          chip,
+		"channel":       channel,
+		"period_ns":     s.Period,
+		"duty_cycle_ns": s.Duty,
+		"enabled":       s.Enabled,
+	}
+	if s.Period > 0 {
+		fields["frequency_hz"] = math.Round(1e9/float64(s.Period)*1000) / 1000
+		fields["duty_percent"] = math.Round(float64(s.Duty)/float64(s.Period)*10000) / 100
+	}
+	if s.Polarity != "" {
+		fields["polarity"] = s.Polarity
+	}
+	result, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal PWM state: %v", err))
+	}
+	return SilentResult(string(result))
+}
@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/hardware"
+)
+
+// simGPIOTool returns a GPIO tool whose chip "0" is a simulated chip with
+// four lines, and whose PWM tree has one chip with two channels.
+func simGPIOTool(t *testing.T) (*GPIOTool, *hardware.SimGPIOChip, string) {
+	t.Helper()
+	chip := hardware.NewSimGPIOChip("gpiochip0", "LED", "BUTTON", "RELAY", "")
+
+	pwmRoot := t.TempDir()
+	channel := filepath.Join(pwmRoot, "pwmchip0", "pwm0")
+	if err := os.MkdirAll(channel, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for file, value := range map[string]string{
+		"../npwm": "2", "../export": "", "period": "0", "duty_cycle": "0", "enable": "0", "polarity": "normal",
+	} {
+		if err := os.WriteFile(filepath.Join(channel, file), []byte(value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tool := NewGPIOToolWithOptions(GPIOToolOptions{
+		Open: func(id string) (hardware.GPIOChip, error) {
+			if id != "0" && id != "gpiochip0" {
+				t.Errorf("opened chip %q, want 0", id)
+			}
+			return chip, nil
+		},
+		Chips:   func() ([]string, error) { return []string{"0"}, nil },
+		PWMRoot: pwmRoot,
+	})
+	return tool, chip, channel
+}
+
+func TestGPIOTool_List(t *testing.T) {
+	tool, _, _ := simGPIOTool(t)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"action": "list"})
+	if result.IsError {
+		t.Fatalf("list failed: %s", result.ForLLM)
+	}
+	for _, want := range []string{`"gpiochip0"`, `"lines": 4`, `"channels": 2`} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("list result missing %s:\n%s", want, result.ForLLM)
+		}
+	}
+
+	result = tool.Execute(ctx, map[string]any{"action": "list", "chip": "0"})
+	if result.IsError || !strings.Contains(result.ForLLM, `"BUTTON"`) {
+		t.Errorf("list lines: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"action": "list", "chip": "../../etc/passwd"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "invalid chip") {
+		t.Errorf("list with a bad chip: %s", result.ForLLM)
+	}
+}
+
+func TestGPIOTool_ReadWrite(t *testing.T) {
+	tool, chip, _ := simGPIOTool(t)
+	ctx := context.Background()
+
+	chip.SetInput(1, true)
+	result := tool.Execute(ctx, map[string]any{"action": "read", "line": "BUTTON"})
+	if result.IsError || !strings.Contains(result.ForLLM, `"value": 1`) {
+		t.Errorf("read BUTTON: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]any{"action": "read", "chip": "0", "line": float64(1), "active_low": true})
+	if result.IsError || !strings.Contains(result.ForLLM, `"value": 0`) {
+		t.Errorf("read active-low: %s", result.ForLLM)
+	}
+
+	args := map[string]any{"action": "write", "chip": "0", "line": float64(2), "value": "high"}
+	if result := tool.Execute(ctx, args); !result.IsError || !strings.Contains(result.ForLLM, "confirm") {
+		t.Errorf("write without confirm: %q", result.ForLLM)
+	}
+	if chip.Level(2) {
+		t.Fatal("line driven without confirm")
+	}
+
+	args["confirm"] = true
+	if result := tool.Execute(ctx, args); result.IsError {
+		t.Fatalf("write failed: %s", result.ForLLM)
+	}
+	if !chip.Level(2) {
+		t.Error("RELAY not driven high")
+	}
+	// The line stays held, so a second write reuses it.
+	args["value"] = false
+	if result := tool.Execute(ctx, args); result.IsError {
+		t.Fatalf("second write failed: %s", result.ForLLM)
+	}
+	if chip.Level(2) {
+		t.Error("RELAY not driven low")
+	}
+	if info, _ := chip.LineInfo(2); !info.Used || !info.Output {
+		t.Errorf("RELAY not held as an output: %+v", info)
+	}
+
+	release := map[string]any{"action": "release", "chip": "0", "line": "2"}
+	if result := tool.Execute(ctx, release); result.IsError {
+		t.Fatalf("release failed: %s", result.ForLLM)
+	}
+	if info, _ := chip.LineInfo(2); info.Used {
+		t.Error("RELAY still requested after release")
+	}
+	if result := tool.Execute(ctx, release); !result.IsError {
+		t.Error("releasing a free line should fail")
+	}
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "read", "chip": "0", "line": float64(9)}, "between 0 and 3"},
+		{map[string]any{"action": "read", "line": "NOPE"}, "no GPIO line named"},
+		{map[string]any{"action": "read", "line": float64(1)}, "chip is required"},
+		{map[string]any{"action": "write", "line": "LED", "value": 2.0, "confirm": true}, "value is required"},
+	} {
+		result := tool.Execute(ctx, tc.args)
+		if !result.IsError || !strings.Contains(result.ForLLM, tc.want) {
+			t.Errorf("%v: got %q, want error containing %q", tc.args, result.ForLLM, tc.want)
+		}
+	}
+}
+
+func TestGPIOTool_Watch(t *testing.T) {
+	tool, chip, _ := simGPIOTool(t)
+	ctx := context.Background()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		chip.SetInput(1, true)
+		chip.SetInput(1, false)
+		chip.SetInput(1, true)
+	}()
+	result := tool.Execute(ctx, map[string]any{
+		"action": "watch", "line": "BUTTON", "edge": "rising", "max_events": float64(2), "timeout": float64(5),
+	})
+	if result.IsError {
+		t.Fatalf("watch failed: %s", result.ForLLM)
+	}
+	var watched struct {
+		Initial int `json:"initial_value"`
+		Edges   []struct {
+			Edge string `json:"edge"`
+		} `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(result.ForLLM), &watched); err != nil {
+		t.Fatalf("watch result is not JSON: %v\n%s", err, result.ForLLM)
+	}
+	if watched.Initial != 0 || len(watched.Edges) != 2 ||
+		watched.Edges[0].Edge != "rising" || watched.Edges[1].Edge != "rising" {
+		t.Errorf("watch = %+v, want two rising edges from 0", watched)
+	}
+
+	// A cancelled context ends the watch early.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	start := time.Now()
+	result = tool.Execute(cancelled, map[string]any{"action": "watch", "line": "BUTTON"})
+	if result.IsError || time.Since(start) > time.Second {
+		t.Errorf("watch with a cancelled context: %s after %v", result.ForLLM, time.Since(start))
+	}
+
+	result = tool.Execute(ctx, map[string]any{"action": "watch", "line": "BUTTON", "timeout": float64(600)})
+	if !result.IsError || !strings.Contains(result.ForLLM, "between 1 and 60") {
+		t.Errorf("watch with a long timeout: %s", result.ForLLM)
+	}
+}
+
+func TestGPIOTool_PWM(t *testing.T) {
+	tool, _, channel := simGPIOTool(t)
+	ctx := context.Background()
+
+	args := map[string]any{"action": "pwm", "chip": "0", "channel": float64(0), "frequency": 1000.0, "duty": 25.0}
+	if result := tool.Execute(ctx, args); !result.IsError || !strings.Contains(result.ForLLM, "confirm") {
+		t.Errorf("pwm without confirm: %q", result.ForLLM)
+	}
+
+	args["confirm"] = true
+	result := tool.Execute(ctx, args)
+	if result.IsError {
+		t.Fatalf("pwm failed: %s", result.ForLLM)
+	}
+	for file, want := range map[string]string{"period": "1000000", "duty_cycle": "250000", "enable": "1"} {
+		b, _ := os.ReadFile(filepath.Join(channel, file))
+		if string(b) != want {
+			t.Errorf("%s = %q, want %q", file, b, want)
+		}
+	}
+
+	// Changing the frequency keeps the duty cycle.
+	result = tool.Execute(ctx, map[string]any{
+		"action": "pwm", "chip": "0", "channel": float64(0), "frequency": 500.0, "confirm": true,
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, `"duty_percent": 25`) {
+		t.Errorf("pwm frequency change: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"action": "pwm", "chip": "0", "channel": float64(0)})
+	if result.IsError || !strings.Contains(result.ForLLM, `"frequency_hz": 500`) {
+		t.Errorf("pwm status: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{
+		"action": "pwm", "chip": "0", "channel": float64(0), "duty": 150.0, "confirm": true,
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "between 0 and 100") {
+		t.Errorf("pwm with duty 150: %s", result.ForLLM)
+	}
+}
+
+func TestGPIOTool_Unsupported(t *testing.T) {
+	tool := NewGPIOToolWithOptions(GPIOToolOptions{
+		Open: func(string) (hardware.GPIOChip, error) { return nil, hardware.ErrUnsupported },
+	})
+	result := tool.Execute(context.Background(), map[string]any{"action": "list", "chip": "0"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "only supported on Linux") {
+		t.Errorf("got %q", result.ForLLM)
+	}
+}
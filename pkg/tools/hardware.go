@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/hardware"
+)
+
+// Shared by the i2c and spi tools for driver profiles and configured devices.
+
+// profileParameters are the tool parameters for read_sensor and set_output.
+func profileParameters(props map[string]any) {
+	props["name"] = map[string]any{
+		"type":        "string",
+		"description": "Name of a configured hardware device. Supplies its bus, address, profile and options.",
+	}
+	props["profile"] = map[string]any{
+		"type":        "string",
+		"description": "Driver profile for read_sensor/set_output (see the profiles action).",
+	}
+	props["output"] = map[string]any{
+		"type":        "string",
+		"description": "Output to set with set_output, e.g. \"text\" or \"power\" on a display.",
+	}
+	props["value"] = map[string]any{
+		"description": "Value for set_output: boolean, number or string depending on the output.",
+	}
+	props["options"] = map[string]any{
+		"type":        "object",
+		"description": "Numeric profile options, e.g. {\"shunt_ohms\": 0.05} for ina219.",
+	}
+}
+
+// findHardwareDevice returns the configured device called name on the
+// given interface.
+func findHardwareDevice(
+	devices []config.HardwareDeviceConfig, name, iface string,
+) (*config.HardwareDeviceConfig, *ToolResult) {
+	for i := range devices {
+		d := &devices[i]
+		if !strings.EqualFold(d.Name, name) {
+			continue
+		}
+		if di := hardwareInterface(d); di != iface {
+			return nil, ErrorResult(fmt.Sprintf("device %q is on %s; use the %s tool", name, di, di))
+		}
+		return d, nil
+	}
+	names := make([]string, 0, len(devices))
+	for _, d := range devices {
+		names = append(names, d.Name)
+	}
+	if len(names) == 0 {
+		return nil, ErrorResult(fmt.Sprintf("unknown device %q: no hardware devices are configured", name))
+	}
+	return nil, ErrorResult(fmt.Sprintf("unknown device %q (configured: %s)", name, strings.Join(names, ", ")))
+}
+
+// hardwareInterface returns the bus type of a configured device.
+func hardwareInterface(d *config.HardwareDeviceConfig) string {
+	if d.Interface == "" {
+		return "i2c"
+	}
+	return strings.ToLower(d.Interface)
+}
+
+// parseConfigAddress parses a configured I2C address such as "0x76".
+func parseConfigAddress(s string) (uint16, error) {
+	v, err := strconv.ParseUint(s, 0, 16)
+	if err != nil || v < 0x03 || v > 0x77 {
+		return 0, fmt.Errorf("invalid I2C address %q (want 0x03-0x77)", s)
+	}
+	return uint16(v), nil
+}
+
+// lookupProfile resolves a profile name, listing the known ones on error.
+func lookupProfile(name string) (*hardware.Profile, *ToolResult) {
+	p, ok := hardware.Lookup(name)
+	if !ok {
+		names := make([]string, 0)
+		for _, p := range hardware.Profiles() {
+			names = append(names, p.Name)
+		}
+		return nil, ErrorResult(fmt.Sprintf("unknown profile %q (available: %s)", name, strings.Join(names, ", ")))
+	}
+	return p, nil
+}
+
+// profileOptions merges the options argument over the configured ones.
+func profileOptions(configured map[string]float64, args map[string]any) (map[string]float64, *ToolResult) {
+	opts := make(map[string]float64, len(configured))
+	for k, v := range configured {
+		opts[k] = v
+	}
+	raw, ok := args["options"].(map[string]any)
+	if !ok {
+		return opts, nil
+	}
+	for k, v := range raw {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, ErrorResult(fmt.Sprintf("option %q must be a number", k))
+		}
+		opts[k] = f
+	}
+	return opts, nil
+}
+
+// listProfiles describes every driver profile.
+func listProfiles(spiOnly bool) *ToolResult {
+	type outputInfo struct {
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		Description string `json:"description"`
+	}
+	type profileInfo struct {
+		Name        string             `json:"name"`
+		Description string             `json:"description"`
+		Addresses   []string           `json:"addresses,omitempty"`
+		SPI         bool               `json:"spi,omitempty"`
+		Sensor      bool               `json:"read_sensor"`
+		Options     map[string]float64 `json:"options,omitempty"`
+		Outputs     []outputInfo       `json:"outputs,omitempty"`
+	}
+
+	var out []profileInfo
+	for _, p := range hardware.Profiles() {
+		if spiOnly && !p.SPI {
+			continue
+		}
+		info := profileInfo{
+			Name:        p.Name,
+			Description: p.Description,
+			SPI:         p.SPI,
+			Sensor:      p.Read != nil || len(p.Fields) > 0,
+			Options:     p.Options,
+		}
+		if !spiOnly {
+			for _, a := range p.Addresses {
+				info.Addresses = append(info.Addresses, fmt.Sprintf("0x%02x", a))
+			}
+		}
+		for _, o := range p.Outputs {
+			info.Outputs = append(info.Outputs, outputInfo{Name: o.Name, Type: o.Type, Description: o.Description})
+		}
+		out = append(out, info)
+	}
+
+	result, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal profiles: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+// readSensor takes a reading through a profile and formats it.
+func readSensor(target string, p *hardware.Profile, d hardware.Device, opts map[string]float64) *ToolResult {
+	ms, err := p.ReadSensor(d, opts)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	result, err := json.MarshalIndent(map[string]any{
+		"device":       target,
+		"profile":      p.Name,
+		"measurements": ms,
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal sensor reading: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+// setOutput applies a set_output call through a profile.
+func setOutput(
+	target string, p *hardware.Profile, d hardware.Device, args map[string]any, opts map[string]float64,
+) *ToolResult {
+	confirm, _ := args["confirm"].(bool)
+	if !confirm {
+		return ErrorResult(
+			"set_output requires confirm: true. Please confirm with the user before changing hardware outputs.",
+		)
+	}
+	output, _ := args["output"].(string)
+	if output == "" {
+		return ErrorResult("output is required for set_output")
+	}
+	if err := p.SetOutput(d, output, args["value"], opts); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return SilentResult(fmt.Sprintf("Set %s %s on %s", p.Name, output, target))
+}
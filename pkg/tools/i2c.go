@@ -3,17 +3,39 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"regexp"
-	"runtime"
+	"strings"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/hardware"
 )
 
 // I2CTool provides I2C bus interaction for reading sensors and controlling peripherals.
-type I2CTool struct{}
+type I2CTool struct {
+	devices []config.HardwareDeviceConfig
+	open    func(bus string) (hardware.I2C, error)
+}
+
+// I2CToolOptions configures an I2CTool. Devices are the configured
+// peripherals addressable by name; Open defaults to hardware.OpenI2C and
+// can be replaced with a simulated bus.
+type I2CToolOptions struct {
+	Devices []config.HardwareDeviceConfig
+	Open    func(bus string) (hardware.I2C, error)
+}
 
 func NewI2CTool() *I2CTool {
-	return &I2CTool{}
+	return NewI2CToolWithOptions(I2CToolOptions{})
+}
+
+func NewI2CToolWithOptions(opts I2CToolOptions) *I2CTool {
+	if opts.Open == nil {
+		opts.Open = hardware.OpenI2C
+	}
+	return &I2CTool{devices: opts.Devices, open: opts.Open}
 }
 
 func (t *I2CTool) Name() string {
@@ -21,53 +43,63 @@ func (t *I2CTool) Name() string {
 }
 
 func (t *I2CTool) Description() string {
-	return "Interact with I2C bus devices for reading sensors and controlling peripherals. Actions: detect (list buses), scan (find devices on a bus), read (read bytes from device), write (send bytes to device). Linux only."
+	desc := "Interact with I2C bus devices for reading sensors and controlling peripherals. Actions: detect (list buses), scan (find devices on a bus), identify (match devices to driver profiles), profiles (list driver profiles), read_sensor (converted readings through a profile), set_output (drive a profile output such as display text), read (raw bytes), write (raw bytes). Prefer read_sensor/set_output over raw access when a profile fits. Linux only."
+	if len(t.devices) > 0 {
+		names := make([]string, 0, len(t.devices))
+		for _, d := range t.devices {
+			if hardwareInterface(&d) == "i2c" {
+				names = append(names, fmt.Sprintf("%s (%s)", d.Name, d.Profile))
+			}
+		}
+		if len(names) > 0 {
+			desc += " Configured devices: " + strings.Join(names, ", ") + "."
+		}
+	}
+	return desc
 }
 
 func (t *I2CTool) Parameters() map[string]any {
-	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"action": map[string]any{
-				"type":        "string",
-				"enum":        []string{"detect", "scan", "read", "write"},
-				"description": "Action to perform: detect (list available I2C buses), scan (find devices on a bus), read (read bytes from a device), write (send bytes to a device)",
-			},
-			"bus": map[string]any{
-				"type":        "string",
-				"description": "I2C bus number (e.g. \"1\" for /dev/i2c-1). Required for scan/read/write.",
-			},
-			"address": map[string]any{
-				"type":        "integer",
-				"description": "7-bit I2C device address (0x03-0x77). Required for read/write.",
-			},
-			"register": map[string]any{
-				"type":        "integer",
-				"description": "Register address to read from or write to. If set, sends register byte before read/write.",
-			},
-			"data": map[string]any{
-				"type":        "array",
-				"items":       map[string]any{"type": "integer"},
-				"description": "Bytes to write (0-255 each). Required for write action.",
-			},
-			"length": map[string]any{
-				"type":        "integer",
-				"description": "Number of bytes to read (1-256). Default: 1. Used with read action.",
-			},
-			"confirm": map[string]any{
-				"type":        "boolean",
-				"description": "Must be true for write operations. Safety guard to prevent accidental writes.",
-			},
+	props := map[string]any{
+		"action": map[string]any{
+			"type":        "string",
+			"enum":        []string{"detect", "scan", "identify", "profiles", "read_sensor", "set_output", "read", "write"},
+			"description": "Action to perform: detect (list available I2C buses), scan (find devices on a bus), identify (match devices on a bus, or one address, to driver profiles), profiles (list driver profiles), read_sensor (take a converted reading), set_output (set a profile output), read (read bytes from a device), write (send bytes to a device)",
+		},
+		"bus": map[string]any{
+			"type":        "string",
+			"description": "I2C bus number (e.g. \"1\" for /dev/i2c-1). Required for scan/identify/read/write, and for read_sensor/set_output without name.",
+		},
+		"address": map[string]any{
+			"type":        "integer",
+			"description": "7-bit I2C device address (0x03-0x77). Required for read/write, and for read_sensor/set_output without name.",
+		},
+		"register": map[string]any{
+			"type":        "integer",
+			"description": "Register address to read from or write to. If set, sends register byte before read/write.",
+		},
+		"data": map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "integer"},
+			"description": "Bytes to write (0-255 each). Required for write action.",
+		},
+		"length": map[string]any{
+			"type":        "integer",
+			"description": "Number of bytes to read (1-256). Default: 1. Used with read action.",
+		},
+		"confirm": map[string]any{
+			"type":        "boolean",
+			"description": "Must be true for write and set_output. Safety guard to prevent accidental writes.",
 		},
-		"required": []string{"action"},
+	}
+	profileParameters(props)
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"action"},
 	}
 }
 
 func (t *I2CTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
-	if runtime.GOOS != "linux" {
-		return ErrorResult("I2C is only supported on Linux. This tool requires /dev/i2c-* device files.")
-	}
-
 	action, ok := args["action"].(string)
 	if !ok {
 		return ErrorResult("action is required")
@@ -78,12 +110,19 @@ func (t *I2CTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 		return t.detect()
 	case "scan":
 		return t.scan(args)
+	case "identify":
+		return t.identify(args)
+	case "profiles":
+		return listProfiles(false)
+	case "read_sensor", "set_output":
+		return t.profileAction(action, args)
 	case "read":
 		return t.readDevice(args)
 	case "write":
 		return t.writeDevice(args)
 	default:
-		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: detect, scan, read, write)", action))
+		return ErrorResult(fmt.Sprintf(
+			"unknown action: %s (valid: detect, scan, identify, profiles, read_sensor, set_output, read, write)", action))
 	}
 }
 
@@ -120,19 +159,342 @@ func (t *I2CTool) detect() *ToolResult {
 	return SilentResult(fmt.Sprintf("Found %d I2C bus(es):\n%s", len(buses), string(result)))
 }
 
-// Helper functions for I2C operations (used by platform-specific implementations)
+// openBus opens an I2C bus, turning errors into tool results.
+func (t *I2CTool) openBus(bus string) (hardware.I2C, *ToolResult) {
+	b, err := t.open(bus)
+	if errors.Is(err, hardware.ErrUnsupported) {
+		return nil, ErrorResult("I2C is only supported on Linux. This tool requires /dev/i2c-* device files.")
+	}
+	if err != nil {
+		return nil, ErrorResult(err.Error())
+	}
+	return b, nil
+}
+
+// scan probes a bus for devices.
+func (t *I2CTool) scan(args map[string]any) *ToolResult {
+	bus, errResult := parseI2CBus(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	b, errResult := t.openBus(bus)
+	if errResult != nil {
+		return errResult
+	}
+	defer b.Close()
+
+	entries, err := b.Scan()
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	type deviceEntry struct {
+		Address string `json:"address"`
+		Status  string `json:"status,omitempty"`
+	}
+
+	devPath := "/dev/i2c-" + bus
+	if len(entries) == 0 {
+		return SilentResult(fmt.Sprintf("No devices found on %s. Check wiring and pull-up resistors.", devPath))
+	}
+
+	found := make([]deviceEntry, 0, len(entries))
+	for _, e := range entries {
+		entry := deviceEntry{Address: fmt.Sprintf("0x%02x", e.Addr)}
+		if e.Busy {
+			entry.Status = "busy (in use by kernel driver)"
+		}
+		found = append(found, entry)
+	}
+
+	result, err := json.MarshalIndent(map[string]any{
+		"bus":     devPath,
+		"devices": found,
+		"count":   len(found),
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal scan results: %v", err))
+	}
+	return SilentResult(fmt.Sprintf("Scan of %s:\n%s", devPath, string(result)))
+}
+
+// identify matches the device at an address, or every device found on the
+// bus, against the driver profiles.
+func (t *I2CTool) identify(args map[string]any) *ToolResult {
+	bus, errResult := parseI2CBus(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	var addrs []uint16
+	if _, ok := args["address"]; ok {
+		addr, errResult := parseI2CAddress(args)
+		if errResult != nil {
+			return errResult
+		}
+		addrs = append(addrs, uint16(addr))
+	}
+
+	b, errResult := t.openBus(bus)
+	if errResult != nil {
+		return errResult
+	}
+	defer b.Close()
+
+	if addrs == nil {
+		entries, err := b.Scan()
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		for _, e := range entries {
+			if !e.Busy {
+				addrs = append(addrs, e.Addr)
+			}
+		}
+	}
+
+	type match struct {
+		Profile   string `json:"profile"`
+		Confirmed bool   `json:"confirmed"`
+	}
+	type deviceEntry struct {
+		Address    string  `json:"address"`
+		Candidates []match `json:"candidates"`
+	}
+
+	devices := make([]deviceEntry, 0, len(addrs))
+	for _, addr := range addrs {
+		entry := deviceEntry{Address: fmt.Sprintf("0x%02x", addr), Candidates: []match{}}
+		for _, c := range hardware.Identify(b, addr) {
+			entry.Candidates = append(entry.Candidates, match{Profile: c.Profile.Name, Confirmed: c.Confirmed})
+		}
+		devices = append(devices, entry)
+	}
+
+	result, err := json.MarshalIndent(map[string]any{
+		"bus":     "/dev/i2c-" + bus,
+		"devices": devices,
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal identify results: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+// profileAction runs read_sensor or set_output on a configured device or
+// on a bus/address, picking the profile by identification when none is
+// given.
+func (t *I2CTool) profileAction(action string, args map[string]any) *ToolResult {
+	var (
+		bus, profile string
+		addr         uint16
+		configured   map[string]float64
+	)
+	if name, _ := args["name"].(string); name != "" {
+		dev, errResult := findHardwareDevice(t.devices, name, "i2c")
+		if errResult != nil {
+			return errResult
+		}
+		if !isValidBusID(dev.Bus) {
+			return ErrorResult(fmt.Sprintf("device %q: invalid bus %q", dev.Name, dev.Bus))
+		}
+		a, err := parseConfigAddress(dev.Address)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("device %q: %v", dev.Name, err))
+		}
+		bus, addr, profile, configured = dev.Bus, a, dev.Profile, dev.Options
+	} else {
+		var errResult *ToolResult
+		if bus, errResult = parseI2CBus(args); errResult != nil {
+			return errResult
+		}
+		a, errResult := parseI2CAddress(args)
+		if errResult != nil {
+			return errResult
+		}
+		addr = uint16(a)
+		profile, _ = args["profile"].(string)
+	}
+
+	opts, errResult := profileOptions(configured, args)
+	if errResult != nil {
+		return errResult
+	}
+
+	b, errResult := t.openBus(bus)
+	if errResult != nil {
+		return errResult
+	}
+	defer b.Close()
+
+	var p *hardware.Profile
+	if profile != "" {
+		if p, errResult = lookupProfile(profile); errResult != nil {
+			return errResult
+		}
+	} else if p, errResult = pickProfile(b, addr); errResult != nil {
+		return errResult
+	}
+
+	target := fmt.Sprintf("/dev/i2c-%s@0x%02x", bus, addr)
+	dev := hardware.NewI2CDevice(b, addr)
+	if action == "set_output" {
+		return setOutput(target, p, dev, args, opts)
+	}
+	return readSensor(target, p, dev, opts)
+}
+
+// pickProfile chooses the profile for an address when the caller didn't
+// name one: a confirmed ID match, or the only profile for the address.
+func pickProfile(b hardware.I2C, addr uint16) (*hardware.Profile, *ToolResult) {
+	candidates := hardware.Identify(b, addr)
+	switch {
+	case len(candidates) == 0:
+		return nil, ErrorResult(fmt.Sprintf("no driver profile matches address 0x%02x; use read/write instead", addr))
+	case candidates[0].Confirmed, len(candidates) == 1:
+		return candidates[0].Profile, nil
+	}
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Profile.Name
+	}
+	return nil, ErrorResult(fmt.Sprintf(
+		"several profiles fit address 0x%02x (%s); pass profile to choose one", addr, strings.Join(names, ", ")))
+}
+
+// readDevice reads bytes from an I2C device, optionally at a specific register
+func (t *I2CTool) readDevice(args map[string]any) *ToolResult {
+	bus, errResult := parseI2CBus(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	addr, errResult := parseI2CAddress(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	length := 1
+	if l, ok := args["length"].(float64); ok {
+		length = int(l)
+	}
+	if length < 1 || length > 256 {
+		return ErrorResult("length must be between 1 and 256")
+	}
+
+	// If register is specified, write it first
+	var w []byte
+	if regFloat, ok := args["register"].(float64); ok {
+		reg := int(regFloat)
+		if reg < 0 || reg > 255 {
+			return ErrorResult("register must be between 0x00 and 0xFF")
+		}
+		w = []byte{byte(reg)}
+	}
+
+	b, errResult := t.openBus(bus)
+	if errResult != nil {
+		return errResult
+	}
+	defer b.Close()
+
+	buf := make([]byte, length)
+	if err := b.Tx(uint16(addr), w, buf); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	// Format as hex bytes
+	hexBytes := make([]string, length)
+	intBytes := make([]int, length)
+	for i, v := range buf {
+		hexBytes[i] = fmt.Sprintf("0x%02x", v)
+		intBytes[i] = int(v)
+	}
+
+	result, err := json.MarshalIndent(map[string]any{
+		"bus":     "/dev/i2c-" + bus,
+		"address": fmt.Sprintf("0x%02x", addr),
+		"bytes":   intBytes,
+		"hex":     hexBytes,
+		"length":  length,
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal read results: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+// writeDevice writes bytes to an I2C device, optionally at a specific register
+func (t *I2CTool) writeDevice(args map[string]any) *ToolResult {
+	confirm, _ := args["confirm"].(bool)
+	if !confirm {
+		return ErrorResult(
+			"write operations require confirm: true. Please confirm with the user before writing to I2C devices, as incorrect writes can misconfigure hardware.",
+		)
+	}
+
+	bus, errResult := parseI2CBus(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	addr, errResult := parseI2CAddress(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	dataRaw, ok := args["data"].([]any)
+	if !ok || len(dataRaw) == 0 {
+		return ErrorResult("data is required for write (array of byte values 0-255)")
+	}
+	if len(dataRaw) > 256 {
+		return ErrorResult("data too long: maximum 256 bytes per I2C transaction")
+	}
+
+	data := make([]byte, 0, len(dataRaw)+1)
+
+	// If register is specified, prepend it to the data
+	if regFloat, ok := args["register"].(float64); ok {
+		reg := int(regFloat)
+		if reg < 0 || reg > 255 {
+			return ErrorResult("register must be between 0x00 and 0xFF")
+		}
+		data = append(data, byte(reg))
+	}
+
+	for i, v := range dataRaw {
+		f, ok := v.(float64)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("data[%d] is not a valid byte value", i))
+		}
+		b := int(f)
+		if b < 0 || b > 255 {
+			return ErrorResult(fmt.Sprintf("data[%d] = %d is out of byte range (0-255)", i, b))
+		}
+		data = append(data, byte(b))
+	}
+
+	b, errResult := t.openBus(bus)
+	if errResult != nil {
+		return errResult
+	}
+	defer b.Close()
+
+	if err := b.Tx(uint16(addr), data, nil); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	return SilentResult(fmt.Sprintf("Wrote %d byte(s) to device 0x%02x on /dev/i2c-%s", len(data), addr, bus))
+}
 
 // isValidBusID checks that a bus identifier is a simple number (prevents path injection)
-//
-//nolint:unused // Used by i2c_linux.go
 func isValidBusID(id string) bool {
 	matched, _ := regexp.MatchString(`^\d+$`, id)
 	return matched
 }
 
 // parseI2CAddress extracts and validates an I2C address from args
-//
-//nolint:unused // Used by i2c_linux.go
 func parseI2CAddress(args map[string]any) (int, *ToolResult) {
 	addrFloat, ok := args["address"].(float64)
 	if !ok {
@@ -146,8 +508,6 @@ func parseI2CAddress(args map[string]any) (int, *ToolResult) {
 }
 
 // parseI2CBus extracts and validates an I2C bus from args
-//
-//nolint:unused // Used by i2c_linux.go
 func parseI2CBus(args map[string]any) (string, *ToolResult) {
 	bus, ok := args["bus"].(string)
 	if !ok || bus == "" {
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/hardware"
+)
+
+// simI2CTool returns an I2C tool whose bus "1" is a simulated bus holding
+// an INA219 at 0x40 and an SSD1306 at 0x3c.
+func simI2CTool(t *testing.T) (*I2CTool, *hardware.SimRegisters) {
+	t.Helper()
+	ina := hardware.NewSimRegisters(2)
+	ina.Set(0x01, 0x03, 0xe8) // 10 mV across the shunt
+	ina.Set(0x02, 0x5d, 0xc0) // 12 V bus
+	display := hardware.NewSimRegisters(1)
+
+	bus := hardware.NewSimI2C()
+	bus.Attach(0x40, ina)
+	bus.Attach(0x3c, display)
+	bus.SetBusy(0x68)
+
+	tool := NewI2CToolWithOptions(I2CToolOptions{
+		Devices: []config.HardwareDeviceConfig{
+			{Name: "battery", Profile: "ina219", Bus: "1", Address: "0x40", Options: map[string]float64{"shunt_ohms": 0.05}},
+			{Name: "oled", Profile: "ssd1306", Bus: "1", Address: "0x3c"},
+			{Name: "probe", Profile: "bme280", Interface: "spi", Bus: "0.0"},
+		},
+		Open: func(id string) (hardware.I2C, error) {
+			if id != "1" {
+				t.Errorf("opened bus %q, want 1", id)
+			}
+			return bus, nil
+		},
+	})
+	return tool, display
+}
+
+func TestI2CTool_ScanAndIdentify(t *testing.T) {
+	tool, _ := simI2CTool(t)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"action": "scan", "bus": "1"})
+	if result.IsError {
+		t.Fatalf("scan failed: %s", result.ForLLM)
+	}
+	for _, want := range []string{`"0x3c"`, `"0x40"`, `"0x68"`, "busy"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("scan result missing %s:\n%s", want, result.ForLLM)
+		}
+	}
+
+	result = tool.Execute(ctx, map[string]any{"action": "identify", "bus": "1"})
+	if result.IsError {
+		t.Fatalf("identify failed: %s", result.ForLLM)
+	}
+	var identified struct {
+		Devices []struct {
+			Address    string `json:"address"`
+			Candidates []struct {
+				Profile string `json:"profile"`
+			} `json:"candidates"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal([]byte(result.ForLLM), &identified); err != nil {
+		t.Fatalf("identify result is not JSON: %v\n%s", err, result.ForLLM)
+	}
+	got := map[string]string{}
+	for _, d := range identified.Devices {
+		if len(d.Candidates) > 0 {
+			got[d.Address] = d.Candidates[0].Profile
+		}
+	}
+	if got["0x3c"] != "ssd1306" || got["0x40"] != "ina219" || len(identified.Devices) != 2 {
+		t.Errorf("identify = %+v, want ssd1306 at 0x3c and ina219 at 0x40 (busy 0x68 skipped)", identified.Devices)
+	}
+}
+
+func TestI2CTool_ReadSensor(t *testing.T) {
+	tool, _ := simI2CTool(t)
+	ctx := context.Background()
+
+	current := func(args map[string]any) float64 {
+		t.Helper()
+		args["action"] = "read_sensor"
+		result := tool.Execute(ctx, args)
+		if result.IsError {
+			t.Fatalf("read_sensor %v failed: %s", args, result.ForLLM)
+		}
+		var reading struct {
+			Profile      string                 `json:"profile"`
+			Measurements []hardware.Measurement `json:"measurements"`
+		}
+		if err := json.Unmarshal([]byte(result.ForLLM), &reading); err != nil {
+			t.Fatalf("reading is not JSON: %v\n%s", err, result.ForLLM)
+		}
+		if reading.Profile != "ina219" {
+			t.Errorf("profile = %q, want ina219", reading.Profile)
+		}
+		for _, m := range reading.Measurements {
+			if m.Name == "current" {
+				return m.Value
+			}
+		}
+		t.Fatalf("no current in %s", result.ForLLM)
+		return 0
+	}
+
+	// By name, with the configured shunt.
+	if got := current(map[string]any{"name": "battery"}); got != 200 {
+		t.Errorf("current = %v mA, want 200", got)
+	}
+	// By address, with the profile picked by identification and the default shunt.
+	if got := current(map[string]any{"bus": "1", "address": float64(0x40)}); got != 100 {
+		t.Errorf("current = %v mA, want 100", got)
+	}
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"name": "nope"}, "configured: battery, oled, probe"},
+		{map[string]any{"name": "probe"}, "use the spi tool"},
+		{map[string]any{"name": "battery", "options": map[string]any{"gain": 2.0}}, "unknown option"},
+		{map[string]any{"bus": "1", "address": float64(0x40), "profile": "bogus"}, "unknown profile"},
+		{map[string]any{"name": "oled"}, "no sensor readings"},
+	} {
+		tc.args["action"] = "read_sensor"
+		result := tool.Execute(ctx, tc.args)
+		if !result.IsError || !strings.Contains(result.ForLLM, tc.want) {
+			t.Errorf("%v: got %q, want error containing %q", tc.args, result.ForLLM, tc.want)
+		}
+	}
+}
+
+func TestI2CTool_SetOutput(t *testing.T) {
+	tool, display := simI2CTool(t)
+	ctx := context.Background()
+
+	args := map[string]any{"action": "set_output", "name": "oled", "output": "text", "value": "hello"}
+	if result := tool.Execute(ctx, args); !result.IsError || !strings.Contains(result.ForLLM, "confirm") {
+		t.Errorf("set_output without confirm: %q", result.ForLLM)
+	}
+	if len(display.Writes) != 0 {
+		t.Fatal("display written without confirm")
+	}
+
+	args["confirm"] = true
+	if result := tool.Execute(ctx, args); result.IsError {
+		t.Fatalf("set_output failed: %s", result.ForLLM)
+	}
+	if len(display.Writes) != 9 { // window command + 8 pages
+		t.Errorf("got %d writes, want 9", len(display.Writes))
+	}
+}
+
+func TestI2CTool_RawReadWrite(t *testing.T) {
+	tool, _ := simI2CTool(t)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{
+		"action": "read", "bus": "1", "address": float64(0x40), "register": float64(0x02), "length": float64(2),
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, `"0x5d"`) {
+		t.Errorf("read: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{
+		"action": "write", "bus": "1", "address": float64(0x77), "data": []any{1.0}, "confirm": true,
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "no device at address 0x77") {
+		t.Errorf("write to an empty address: %s", result.ForLLM)
+	}
+}
+
+func TestI2CTool_Unsupported(t *testing.T) {
+	tool := NewI2CToolWithOptions(I2CToolOptions{
+		Open: func(string) (hardware.I2C, error) { return nil, hardware.ErrUnsupported },
+	})
+	result := tool.Execute(context.Background(), map[string]any{"action": "scan", "bus": "1"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "only supported on Linux") {
+		t.Errorf("got %q", result.ForLLM)
+	}
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// JournaledTool is an optional interface for tools that change files. With
+// a journal set they record every change so it can be undone.
+type JournaledTool interface {
+	Tool
+	SetJournal(j *journal.Journal)
+}
+
+// journaled is embedded by the tools that change files.
+type journaled struct {
+	journal *journal.Journal
+}
+
+func (t *journaled) SetJournal(j *journal.Journal) {
+	t.journal = j
+}
+
+// begin returns sysFs wrapped to record the changes made through it for
+// the tool call in ctx. Pass the recorder to commit when the call is done.
+func (t *journaled) begin(ctx context.Context, sysFs fileSystem, tool string) (fileSystem, *journal.Recorder) {
+	rec := t.journal.Begin(ctx, tool)
+	if rec == nil {
+		return sysFs, nil
+	}
+	return &journalFs{fileSystem: sysFs, rec: rec}, rec
+}
+
+func (t *journaled) commit(rec *journal.Recorder) {
+	if err := rec.Commit(); err != nil {
+		logger.WarnCF("tool", "Failed to journal file changes; they cannot be undone",
+			map[string]any{"error": err.Error()})
+	}
+}
+
+// journalFs records successful writes, renames and removals, reading the
+// old content first through the wrapped fileSystem.
+type journalFs struct {
+	fileSystem
+	rec *journal.Recorder
+}
+
+// abs resolves path the way the wrapped fileSystem does.
+func (f *journalFs) abs(p string) string {
+	if s, ok := f.fileSystem.(*sandboxFs); ok {
+		if rel, err := getSafeRelPath(s.workspace, p); err == nil {
+			return filepath.Join(s.workspace, rel)
+		}
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return abs
+}
+
+func (f *journalFs) WriteFile(p string, data []byte) error {
+	old, err := f.fileSystem.ReadFile(p)
+	existed := err == nil
+	if err := f.fileSystem.WriteFile(p, data); err != nil {
+		return err
+	}
+	f.rec.Write(f.abs(p), old, existed, data)
+	return nil
+}
+
+func (f *journalFs) Rename(oldPath, newPath string) error {
+	// A file the rename replaces is recorded as removed.
+	replaced, readErr := f.fileSystem.ReadFile(newPath)
+	if err := f.fileSystem.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if readErr == nil {
+		f.rec.Remove(f.abs(newPath), replaced)
+	}
+	f.rec.Rename(f.abs(oldPath), f.abs(newPath))
+	return nil
+}
+
+func (f *journalFs) RemoveAll(p string) error {
+	info, err := f.fileSystem.Stat(p)
+	if err != nil {
+		return f.fileSystem.RemoveAll(p)
+	}
+
+	// Snapshot the regular files about to go; directories come back as
+	// their files are restored.
+	type snapshot struct {
+		path string
+		data []byte
+	}
+	var files []snapshot
+	if info.Mode().IsRegular() {
+		if data, err := f.fileSystem.ReadFile(p); err == nil {
+			files = append(files, snapshot{f.abs(p), data})
+		}
+	} else if info.IsDir() {
+		base := f.abs(p)
+		err := f.fileSystem.WithDirFS(p, func(fsys fs.FS) error {
+			return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+				if err != nil || !d.Type().IsRegular() {
+					return nil
+				}
+				data, err := fs.ReadFile(fsys, name)
+				if err != nil {
+					return err
+				}
+				files = append(files, snapshot{filepath.Join(base, filepath.FromSlash(path.Clean(name))), data})
+				return nil
+			})
+		})
+		if err != nil {
+			return errors.New("cannot snapshot directory for the journal: " + err.Error())
+		}
+	}
+
+	if err := f.fileSystem.RemoveAll(p); err != nil {
+		return err
+	}
+	for _, file := range files {
+		f.rec.Remove(file.path, file.data)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
+)
+
+func TestJournaledTools_RevertTurn(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"a.txt": "one\n", "dir/b.txt": "b", "dir/c.txt": "c"})
+	j := journal.New(workspace, journal.Options{})
+
+	write := NewWriteFileTool(workspace, true)
+	edit := NewEditFileTool(workspace, true)
+	patch := NewApplyPatchTool(workspace, true)
+	move := NewMoveFileTool(workspace, true)
+	del := NewDeleteFileTool(workspace, true)
+	for _, tool := range []JournaledTool{write, edit, patch, move, del} {
+		tool.SetJournal(j)
+	}
+
+	ctx := journal.WithCall(context.Background(), journal.Call{Session: "s", Turn: "t"})
+	calls := []struct {
+		tool Tool
+		args map[string]any
+	}{
+		{write, map[string]any{"path": "new.txt", "content": "new"}},
+		{edit, map[string]any{"path": "a.txt", "old_text": "one", "new_text": "two"}},
+		{patch, map[string]any{"patch": "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-two\n+three\n"}},
+		{move, map[string]any{"source": "new.txt", "destination": "moved/new.txt"}},
+		{del, map[string]any{"path": "dir", "recursive": true, "permanent": true}},
+	}
+	for _, c := range calls {
+		if result := c.tool.Execute(ctx, c.args); result.IsError {
+			t.Fatalf("%s: %s", c.tool.Name(), result.ForLLM)
+		}
+	}
+	// A failed call records nothing.
+	edit.Execute(ctx, map[string]any{"path": "a.txt", "old_text": "missing", "new_text": "x"})
+
+	entries, err := j.Last("s", true)
+	if err != nil || len(entries) != len(calls) {
+		t.Fatalf("expected %d entries, got %+v (%v)", len(calls), entries, err)
+	}
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	if _, err := j.Revert(ids, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"a.txt": "one\n", "dir/b.txt": "b", "dir/c.txt": "c"} {
+		data, err := os.ReadFile(filepath.Join(workspace, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	for _, gone := range []string{"new.txt", "moved/new.txt"} {
+		if _, err := os.Stat(filepath.Join(workspace, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist after the revert: %v", gone, err)
+		}
+	}
+}
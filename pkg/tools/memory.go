@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/memory"
+)
+
+// MemorySearchTool retrieves relevant chunks from long-term memory,
+// daily notes and, if enabled, past session summaries.
+type MemorySearchTool struct {
+	index *memory.Index
+}
+
+func NewMemorySearchTool(index *memory.Index) *MemorySearchTool {
+	return &MemorySearchTool{index: index}
+}
+
+func (t *MemorySearchTool) Name() string {
+	return "memory_search"
+}
+
+func (t *MemorySearchTool) Description() string {
+	return "Search long-term memory, daily notes and past conversation summaries. " +
+		"Use this to recall facts, preferences or earlier decisions that are not in the current context."
+}
+
+func (t *MemorySearchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "What to look for, in natural language",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of results (default 5)",
+				"minimum":     1.0,
+				"maximum":     20.0,
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *MemorySearchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return ErrorResult("query is required")
+	}
+
+	limit := memory.DefaultTopK
+	if l, ok := args["limit"].(float64); ok && int(l) > 0 {
+		limit = min(int(l), 20)
+	}
+
+	results, err := t.index.Search(ctx, query, limit)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("memory search failed: %v", err)).WithError(err)
+	}
+	if len(results) == 0 {
+		return NewToolResult(fmt.Sprintf("No memory found for: %s", query))
+	}
+	return NewToolResult(memory.FormatResults(results))
+}
+
+// MemoryWriteTool appends facts to long-term memory or today's daily note.
+type MemoryWriteTool struct {
+	index *memory.Index
+}
+
+func NewMemoryWriteTool(index *memory.Index) *MemoryWriteTool {
+	return &MemoryWriteTool{index: index}
+}
+
+func (t *MemoryWriteTool) Name() string {
+	return "memory_write"
+}
+
+func (t *MemoryWriteTool) Description() string {
+	return "Save something worth remembering. Use target 'long_term' for durable facts and preferences " +
+		"(MEMORY.md) and 'daily' for notes about today (daily note)."
+}
+
+func (t *MemoryWriteTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"content": map[string]any{
+				"type":        "string",
+				"description": "The fact or note to save, written so it makes sense on its own",
+			},
+			"target": map[string]any{
+				"type":        "string",
+				"enum":        []string{"long_term", "daily"},
+				"description": "Where to save it (default long_term)",
+			},
+		},
+		"required": []string{"content"},
+	}
+}
+
+func (t *MemoryWriteTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	content, _ := args["content"].(string)
+	if strings.TrimSpace(content) == "" {
+		return ErrorResult("content is required")
+	}
+	target, _ := args["target"].(string)
+	if target == "" {
+		target = "long_term"
+	}
+
+	if err := t.index.Write(target, content); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write memory: %v", err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("Saved to %s memory", target))
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/memory"
+)
+
+func TestMemoryTools_WriteThenSearch(t *testing.T) {
+	idx := memory.NewIndex(t.TempDir(), nil, false)
+	write := NewMemoryWriteTool(idx)
+	search := NewMemorySearchTool(idx)
+	ctx := context.Background()
+
+	result := write.Execute(ctx, map[string]any{"content": "The user's favourite editor is Helix."})
+	if result.IsError {
+		t.Fatalf("memory_write failed: %s", result.ForLLM)
+	}
+	if !result.Silent {
+		t.Error("memory_write should be silent")
+	}
+
+	result = search.Execute(ctx, map[string]any{"query": "which editor", "limit": 3.0})
+	if result.IsError {
+		t.Fatalf("memory_search failed: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Helix") || !strings.Contains(result.ForLLM, "memory/MEMORY.md") {
+		t.Errorf("unexpected search result: %s", result.ForLLM)
+	}
+
+	result = search.Execute(ctx, map[string]any{"query": "spaceship"})
+	if result.IsError || !strings.Contains(result.ForLLM, "No memory found") {
+		t.Errorf("expected empty result, got %+v", result)
+	}
+}
+
+func TestMemoryTools_Validation(t *testing.T) {
+	idx := memory.NewIndex(t.TempDir(), nil, false)
+	ctx := context.Background()
+
+	if r := NewMemorySearchTool(idx).Execute(ctx, map[string]any{}); !r.IsError {
+		t.Error("expected error for missing query")
+	}
+	if r := NewMemoryWriteTool(idx).Execute(ctx, map[string]any{}); !r.IsError {
+		t.Error("expected error for missing content")
+	}
+	if r := NewMemoryWriteTool(idx).Execute(ctx, map[string]any{"content": "x", "target": "nowhere"}); !r.IsError {
+		t.Error("expected error for unknown target")
+	}
+}
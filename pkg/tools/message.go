@@ -2,13 +2,20 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
 )
 
 type SendCallback func(channel, chatID, content string) error
 
+// OutboundCallback delivers a complete outbound message, including media,
+// reply/thread targets and buttons.
+type OutboundCallback func(msg bus.OutboundMessage) error
+
 type MessageTool struct {
-	sendCallback   SendCallback
+	sendCallback   OutboundCallback
 	defaultChannel string
 	defaultChatID  string
 	sentInRound    bool // Tracks whether a message was sent in the current processing round
@@ -42,6 +49,31 @@ func (t *MessageTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional: target chat/user ID",
 			},
+			"reply_to": map[string]any{
+				"type":        "string",
+				"description": "Optional: ID of the message to reply to",
+			},
+			"thread_id": map[string]any{
+				"type":        "string",
+				"description": "Optional: thread or topic to post in",
+			},
+			"buttons": map[string]any{
+				"type":        "array",
+				"description": "Optional: quick-reply buttons. Pressing one sends its value (or text) back as a user message.",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"text":  map[string]any{"type": "string", "description": "Button label"},
+						"value": map[string]any{"type": "string", "description": "Reply sent when pressed"},
+						"url":   map[string]any{"type": "string", "description": "Open this URL instead of replying"},
+					},
+					"required": []string{"text"},
+				},
+			},
+			"silent": map[string]any{
+				"type":        "boolean",
+				"description": "Optional: deliver without a notification sound",
+			},
 		},
 		"required": []string{"content"},
 	}
@@ -58,7 +90,16 @@ func (t *MessageTool) HasSentInRound() bool {
 	return t.sentInRound
 }
 
+// SetSendCallback installs a text-only sender. Rich messages are flattened
+// with bus.OutboundMessage.PlainText before being handed to it.
 func (t *MessageTool) SetSendCallback(callback SendCallback) {
+	t.sendCallback = func(msg bus.OutboundMessage) error {
+		return callback(msg.Channel, msg.ChatID, msg.PlainText())
+	}
+}
+
+// SetOutboundCallback installs a sender that receives the full message.
+func (t *MessageTool) SetOutboundCallback(callback OutboundCallback) {
 	t.sendCallback = callback
 }
 
@@ -68,9 +109,33 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 		return &ToolResult{ForLLM: "content is required", IsError: true}
 	}
 
+	msg := bus.OutboundMessage{Content: content}
+	msg.ReplyTo, _ = args["reply_to"].(string)
+	msg.ThreadID, _ = args["thread_id"].(string)
+	msg.Silent, _ = args["silent"].(bool)
+	buttons, err := parseButtons(args["buttons"])
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	msg.Buttons = buttons
+
 	channel, _ := args["channel"].(string)
 	chatID, _ := args["chat_id"].(string)
+	target, errResult := t.send(channel, chatID, msg)
+	if errResult != nil {
+		return errResult
+	}
+
+	// Silent: user already received the message directly
+	return &ToolResult{
+		ForLLM: "Message sent to " + target,
+		Silent: true,
+	}
+}
 
+// send fills in the default target and delivers msg. It returns the
+// "channel:chat_id" the message went to, or an error result.
+func (t *MessageTool) send(channel, chatID string, msg bus.OutboundMessage) (string, *ToolResult) {
 	if channel == "" {
 		channel = t.defaultChannel
 	}
@@ -79,15 +144,17 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 	}
 
 	if channel == "" || chatID == "" {
-		return &ToolResult{ForLLM: "No target channel/chat specified", IsError: true}
+		return "", &ToolResult{ForLLM: "No target channel/chat specified", IsError: true}
 	}
 
 	if t.sendCallback == nil {
-		return &ToolResult{ForLLM: "Message sending not configured", IsError: true}
+		return "", &ToolResult{ForLLM: "Message sending not configured", IsError: true}
 	}
 
-	if err := t.sendCallback(channel, chatID, content); err != nil {
-		return &ToolResult{
+	msg.Channel = channel
+	msg.ChatID = chatID
+	if err := t.sendCallback(msg); err != nil {
+		return "", &ToolResult{
 			ForLLM:  fmt.Sprintf("sending message: %v", err),
 			IsError: true,
 			Err:     err,
@@ -95,9 +162,31 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 	}
 
 	t.sentInRound = true
-	// Silent: user already received the message directly
-	return &ToolResult{
-		ForLLM: fmt.Sprintf("Message sent to %s:%s", channel, chatID),
-		Silent: true,
+	return channel + ":" + chatID, nil
+}
+
+func parseButtons(raw any) ([]bus.Button, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, errors.New("buttons must be an array")
+	}
+	buttons := make([]bus.Button, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("buttons[%d] must be an object", i)
+		}
+		var b bus.Button
+		b.Text, _ = m["text"].(string)
+		b.Value, _ = m["value"].(string)
+		b.URL, _ = m["url"].(string)
+		if b.Text == "" {
+			return nil, fmt.Errorf("buttons[%d].text is required", i)
+		}
+		buttons = append(buttons, b)
 	}
+	return buttons, nil
 }
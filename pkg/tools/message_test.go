@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
 )
 
 func TestMessageTool_Execute_Success(t *testing.T) {
@@ -257,3 +259,74 @@ func TestMessageTool_Parameters(t *testing.T) {
 		t.Error("Expected chat_id type to be 'string'")
 	}
 }
+
+func TestMessageTool_Execute_RichOptions(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("telegram", "123")
+
+	var sent bus.OutboundMessage
+	tool.SetOutboundCallback(func(msg bus.OutboundMessage) error {
+		sent = msg
+		return nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"content":   "Deploy now?",
+		"reply_to":  "42",
+		"thread_id": "7",
+		"silent":    true,
+		"buttons": []any{
+			map[string]any{"text": "Yes", "value": "deploy"},
+			map[string]any{"text": "Runbook", "url": "https://example.com/runbook"},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+
+	if sent.Channel != "telegram" || sent.ChatID != "123" || sent.Content != "Deploy now?" {
+		t.Errorf("unexpected target/content: %+v", sent)
+	}
+	if sent.ReplyTo != "42" || sent.ThreadID != "7" || !sent.Silent {
+		t.Errorf("reply/thread/silent not forwarded: %+v", sent)
+	}
+	if len(sent.Buttons) != 2 || sent.Buttons[0].Reply() != "deploy" || sent.Buttons[1].URL == "" {
+		t.Errorf("buttons = %+v", sent.Buttons)
+	}
+}
+
+func TestMessageTool_Execute_InvalidButtons(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("telegram", "123")
+	tool.SetOutboundCallback(func(bus.OutboundMessage) error {
+		t.Error("callback should not be called")
+		return nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"content": "hi",
+		"buttons": []any{map[string]any{"value": "no label"}},
+	})
+	if !result.IsError {
+		t.Error("expected error for button without text")
+	}
+}
+
+func TestMessageTool_SendCallbackFlattensRichMessage(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("xmpp", "user@example.com")
+
+	var sentContent string
+	tool.SetSendCallback(func(channel, chatID, content string) error {
+		sentContent = content
+		return nil
+	})
+
+	tool.Execute(context.Background(), map[string]any{
+		"content": "Pick one",
+		"buttons": []any{map[string]any{"text": "A"}, map[string]any{"text": "B"}},
+	})
+	if sentContent != "Pick one\nReply with: A / B" {
+		t.Errorf("content = %q", sentContent)
+	}
+}
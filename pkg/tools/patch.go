@@ -0,0 +1,364 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// ApplyPatchTool applies a unified diff to one or more files. Every hunk
+// is checked before anything is written, so a patch applies completely or
+// not at all.
+type ApplyPatchTool struct {
+	journaled
+	fs fileSystem
+}
+
+func NewApplyPatchTool(workspace string, restrict bool) *ApplyPatchTool {
+	return &ApplyPatchTool{fs: newFileSystem(workspace, restrict)}
+}
+
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+func (t *ApplyPatchTool) Description() string {
+	return "Apply a unified diff (as produced by diff -u or git diff) to one or more files. " +
+		"Use --- /dev/null to create a file and +++ /dev/null to delete one. " +
+		"Hunks are located by their context lines, so line numbers may be approximate. " +
+		"Either every hunk applies or no file is changed."
+}
+
+func (t *ApplyPatchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": "Unified diff with ---/+++ file headers and @@ hunks",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	patch, ok := args["patch"].(string)
+	if !ok || strings.TrimSpace(patch) == "" {
+		return ErrorResult("patch is required")
+	}
+	files, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid patch: %v", err))
+	}
+
+	type change struct {
+		path    string
+		content []byte // nil deletes the file
+		summary string
+	}
+	changes := make([]change, 0, len(files))
+	for _, f := range files {
+		switch {
+		case f.oldPath == "":
+			if _, err := t.fs.Stat(f.newPath); err == nil {
+				return ErrorResult("cannot create " + f.newPath + ": it already exists")
+			}
+			content, err := applyHunks("", f.hunks)
+			if err != nil {
+				return ErrorResult(fmt.Sprintf("%s: %v", f.newPath, err))
+			}
+			changes = append(changes, change{f.newPath, []byte(content), "created " + f.newPath})
+		default:
+			old, err := t.fs.ReadFile(f.oldPath)
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			content, err := applyHunks(string(old), f.hunks)
+			if err != nil {
+				return ErrorResult(fmt.Sprintf("%s: %v", f.oldPath, err))
+			}
+			if f.newPath == "" {
+				changes = append(changes, change{f.oldPath, nil, "deleted " + f.oldPath})
+				continue
+			}
+			summary := fmt.Sprintf("patched %s (%d %s)", f.newPath, len(f.hunks), plural(len(f.hunks), "hunk"))
+			if f.newPath != f.oldPath {
+				summary = fmt.Sprintf("renamed %s to %s", f.oldPath, f.newPath)
+				if _, err := t.fs.Stat(f.newPath); err == nil {
+					return ErrorResult("cannot rename " + f.oldPath + ": " + f.newPath + " already exists")
+				}
+				changes = append(changes, change{f.oldPath, nil, ""})
+			}
+			changes = append(changes, change{f.newPath, []byte(content), summary})
+		}
+	}
+
+	sysFs, rec := t.begin(ctx, t.fs, t.Name())
+	defer t.commit(rec)
+	var summaries []string
+	for _, c := range changes {
+		if c.content == nil {
+			if err := sysFs.RemoveAll(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return ErrorResult(fmt.Sprintf("failed to delete %s: %v", c.path, err))
+			}
+		} else if err := sysFs.WriteFile(c.path, c.content); err != nil {
+			return ErrorResult(err.Error())
+		}
+		if c.summary != "" {
+			summaries = append(summaries, c.summary)
+		}
+	}
+	return SilentResult("Patch applied: " + strings.Join(summaries, ", "))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// filePatch is the part of a unified diff touching one file. An empty
+// oldPath creates the file and an empty newPath deletes it.
+type filePatch struct {
+	oldPath, newPath string
+	hunks            []hunk
+}
+
+type hunk struct {
+	oldStart int // 1-based line number from the @@ header, 0 if absent
+	lines    []hunkLine
+	// noNewlineOld and noNewlineNew record "\ No newline at end of file"
+	// markers on each side.
+	noNewlineOld, noNewlineNew bool
+}
+
+type hunkLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// parseUnifiedDiff splits a unified diff into per-file patches. Hunk line
+// counts in the @@ headers are ignored; a hunk runs until the next header,
+// which tolerates hand-written diffs with miscounted ranges.
+//
+//nolint:gocognit // line-oriented diff parser
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []filePatch
+	var cur *filePatch
+	var h *hunk
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			files = append(files, filePatch{
+				oldPath: diffPath(line[4:]),
+				newPath: diffPath(lines[i+1][4:]),
+			})
+			cur, h = &files[len(files)-1], nil
+			if cur.oldPath == "" && cur.newPath == "" {
+				return nil, fmt.Errorf("line %d: both sides are /dev/null", i+1)
+			}
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+			}
+			cur.hunks = append(cur.hunks, hunk{oldStart: hunkOldStart(line)})
+			h = &cur.hunks[len(cur.hunks)-1]
+		case h == nil:
+			// Text between files: "diff --git", "index", mode lines, commentary.
+		case strings.HasPrefix(line, `\`):
+			if n := len(h.lines); n > 0 {
+				switch h.lines[n-1].op {
+				case '-':
+					h.noNewlineOld = true
+				case '+':
+					h.noNewlineNew = true
+				default:
+					h.noNewlineOld, h.noNewlineNew = true, true
+				}
+			}
+		case line == "":
+			// Editors and models often strip the space from blank context
+			// lines; the final empty string is the patch's own newline.
+			if i < len(lines)-1 {
+				h.lines = append(h.lines, hunkLine{' ', ""})
+			}
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			h.lines = append(h.lines, hunkLine{line[0], line[1:]})
+		case strings.HasPrefix(line, "diff "):
+			h = nil
+		default:
+			return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", i+1, line)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no ---/+++ file headers found")
+	}
+	for _, f := range files {
+		if len(f.hunks) == 0 && f.newPath != "" && f.oldPath != "" {
+			return nil, fmt.Errorf("no hunks for %s", f.newPath)
+		}
+		for i := range f.hunks {
+			// Trailing blank "context" lines are usually blank lines
+			// between files, not part of the hunk.
+			h := &f.hunks[i]
+			for len(h.lines) > 0 && h.lines[len(h.lines)-1] == (hunkLine{' ', ""}) {
+				h.lines = h.lines[:len(h.lines)-1]
+			}
+		}
+	}
+	return files, nil
+}
+
+// diffPath extracts the file name from a ---/+++ header, dropping a
+// trailing timestamp and the a/ or b/ prefix git adds. /dev/null becomes "".
+func diffPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+func hunkOldStart(header string) int {
+	var start int
+	if _, err := fmt.Sscanf(header, "@@ -%d", &start); err != nil {
+		return 0
+	}
+	return start
+}
+
+// applyHunks applies hunks in order to content. Each hunk is located by its
+// context and removed lines, searching outward from the line its header
+// names (shifted by earlier hunks); a second pass ignores trailing
+// whitespace.
+func applyHunks(content string, hunks []hunk) (string, error) {
+	crlf := strings.Contains(content, "\r\n")
+	if crlf {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	delta, floor := 0, 0
+	for n, h := range hunks {
+		var old []string
+		for _, l := range h.lines {
+			if l.op != '+' {
+				old = append(old, l.text)
+			}
+		}
+
+		want := max(h.oldStart-1+delta, floor)
+		if h.oldStart == 0 {
+			want = floor
+		}
+		at := findHunk(lines, old, want, floor, false)
+		if at < 0 {
+			at = findHunk(lines, old, want, floor, true)
+		}
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d does not apply: its context was not found%s", n+1, hunkHint(old))
+		}
+
+		// Context lines keep the file's text, which may differ from the
+		// patch in trailing whitespace.
+		var repl []string
+		k := at
+		for _, l := range h.lines {
+			switch l.op {
+			case ' ':
+				repl = append(repl, lines[k])
+				k++
+			case '-':
+				k++
+			case '+':
+				repl = append(repl, l.text)
+			}
+		}
+
+		if at+len(old) == len(lines) {
+			if h.noNewlineNew {
+				trailingNewline = false
+			} else if h.noNewlineOld || len(lines) == 0 {
+				trailingNewline = true
+			}
+		}
+		lines = append(lines[:at], append(repl, lines[at+len(old):]...)...)
+		delta += len(repl) - len(old)
+		floor = at + len(repl)
+	}
+
+	result := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		result += "\n"
+	}
+	if crlf {
+		result = strings.ReplaceAll(result, "\n", "\r\n")
+	}
+	return result, nil
+}
+
+// findHunk returns the index at or after floor where old occurs in lines,
+// choosing the occurrence closest to want, or -1.
+func findHunk(lines, old []string, want, floor int, loose bool) int {
+	if len(old) == 0 {
+		// Pure insertion: trust the header.
+		return min(want, len(lines))
+	}
+	best := -1
+	for i := floor; i+len(old) <= len(lines); i++ {
+		if !linesMatch(lines[i:i+len(old)], old, loose) {
+			continue
+		}
+		if best < 0 || abs(i-want) < abs(best-want) {
+			best = i
+		}
+	}
+	return best
+}
+
+func linesMatch(a, b []string, loose bool) bool {
+	for i := range b {
+		x, y := a[i], b[i]
+		if loose {
+			x, y = strings.TrimRight(x, " \t"), strings.TrimRight(y, " \t")
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+func hunkHint(old []string) string {
+	for _, l := range old {
+		if strings.TrimSpace(l) != "" {
+			return fmt.Sprintf(" (expected a line %q); re-read the file and regenerate the patch", l)
+		}
+	}
+	return ""
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
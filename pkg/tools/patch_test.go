@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatchTool_MultiFile(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		"main.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n\n" +
+			"func helper() int {\n\treturn 1\n}\n",
+		"old.txt": "bye\n",
+	})
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -5,3 +5,3 @@ import "fmt"
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hello, world")
+ }
+@@ -20,3 +20,4 @@
+ func helper() int {
+-	return 1
++	// Offset hunk: its header line number is wrong.
++	return 2
+ }
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,2 @@
++# New
++text
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	tool := NewApplyPatchTool(workspace, true)
+	result := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "patched main.go (2 hunks), created docs/new.md, deleted old.txt") {
+		t.Errorf("unexpected summary: %s", result.ForLLM)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(workspace, "main.go"))
+	want := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello, world\")\n}\n\n" +
+		"func helper() int {\n\t// Offset hunk: its header line number is wrong.\n\treturn 2\n}\n"
+	if string(data) != want {
+		t.Errorf("main.go =\n%s\nwant\n%s", data, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "docs", "new.md")); string(data) != "# New\ntext\n" {
+		t.Errorf("new.md = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("old.txt not deleted: %v", err)
+	}
+}
+
+func TestApplyPatchTool_AllOrNothing(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"a.txt": "one\ntwo\n", "b.txt": "three\n"})
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ one
+-two
++TWO
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-four
++FOUR
+`
+	result := NewApplyPatchTool(workspace, true).Execute(context.Background(), map[string]any{"patch": patch})
+	if !result.IsError || !strings.Contains(result.ForLLM, "b.txt: hunk 1 does not apply") {
+		t.Fatalf("expected the second file to fail, got %+v", result)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "a.txt")); string(data) != "one\ntwo\n" {
+		t.Errorf("a.txt was modified by a failed patch: %q", data)
+	}
+}
+
+func TestApplyPatchTool_RejectsEscape(t *testing.T) {
+	workspace := t.TempDir()
+	patch := "--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n"
+	result := NewApplyPatchTool(workspace, true).Execute(context.Background(), map[string]any{"patch": patch})
+	if !result.IsError {
+		t.Fatal("expected a patch outside the workspace to fail")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(workspace), "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the workspace: %v", err)
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name, content, patch, want string
+	}{
+		{
+			name:    "crlf preserved",
+			content: "a\r\nb\r\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			want:    "a\r\nc\r\n",
+		},
+		{
+			name:    "no newline at end",
+			content: "a\nb",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n",
+			want:    "a\nc\n",
+		},
+		{
+			name:    "blank context without leading space",
+			content: "a\n\nb\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n\n-b\n+c\n",
+			want:    "a\n\nc\n",
+		},
+		{
+			name:    "trailing whitespace tolerated",
+			content: "a  \nb\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			want:    "a  \nc\n",
+		},
+		{
+			name:    "closest of repeated contexts",
+			content: "x\ny\nx\ny\nx\ny\n",
+			patch:   "--- a/f\n+++ b/f\n@@ -5,2 +5,2 @@\n x\n-y\n+z\n",
+			want:    "x\ny\nx\ny\nx\nz\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parseUnifiedDiff(tt.patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyHunks(tt.content, files[0].hunks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,717 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+const (
+	defaultMaxBackgroundProcesses = 4
+	defaultOutputBufferKB         = 256
+	maxPollWait                   = 30 * time.Second
+	maxPollChunk                  = 10000
+)
+
+// ProcessManager tracks the background processes started by one agent's
+// exec tool. Each process belongs to an owner, the session key of the
+// conversation that started it, and is only visible to that owner. A
+// session's processes are killed once it has been idle for idleTimeout.
+type ProcessManager struct {
+	maxProcesses int
+	maxLifetime  time.Duration
+	idleTimeout  time.Duration
+	bufferSize   int
+
+	mu     sync.Mutex
+	procs  map[string]*backgroundProcess
+	seq    int
+	closed bool
+	busy   map[string]int         // turns in progress per session
+	active map[string]time.Time   // last activity of sessions with processes
+	idle   map[string]*time.Timer // idle cleanup of those sessions
+}
+
+// NewProcessManager creates a manager with the configured limits.
+func NewProcessManager(cfg config.ExecBackgroundConfig) *ProcessManager {
+	maxProcs := cfg.MaxProcesses
+	if maxProcs <= 0 {
+		maxProcs = defaultMaxBackgroundProcesses
+	}
+	bufferKB := cfg.OutputBufferKB
+	if bufferKB <= 0 {
+		bufferKB = defaultOutputBufferKB
+	}
+	return &ProcessManager{
+		maxProcesses: maxProcs,
+		maxLifetime:  time.Duration(max(cfg.MaxLifetimeMinutes, 0)) * time.Minute,
+		idleTimeout:  time.Duration(max(cfg.IdleMinutes, 0)) * time.Minute,
+		bufferSize:   bufferKB << 10,
+		procs:        make(map[string]*backgroundProcess),
+		busy:         make(map[string]int),
+		active:       make(map[string]time.Time),
+		idle:         make(map[string]*time.Timer),
+	}
+}
+
+type backgroundProcess struct {
+	id      string
+	owner   string
+	command string
+	started time.Time
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *outputBuffer
+	stderr  *outputBuffer
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu       sync.Mutex
+	exitCode int
+	exitErr  string
+	ended    time.Time
+	killed   bool
+}
+
+// processInfo is the status reported for a background process.
+type processInfo struct {
+	ID       string
+	Command  string
+	PID      int
+	Running  bool
+	ExitCode int
+	Error    string
+	Started  time.Time
+	Ended    time.Time
+}
+
+// processContext returns the context a background process runs under: it
+// ends after the manager's maximum lifetime, if any.
+func (m *ProcessManager) processContext() (context.Context, context.CancelFunc) {
+	if m.maxLifetime > 0 {
+		return context.WithTimeout(context.Background(), m.maxLifetime)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Start runs cmd in the background for owner. cleanup, if not nil, runs
+// once the process has exited; cancel is the cancel func of the context
+// cmd was created with.
+func (m *ProcessManager) Start(
+	owner, command string,
+	cmd *exec.Cmd,
+	cancel context.CancelFunc,
+	cleanup func(),
+) (*backgroundProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, errors.New("process manager is shut down")
+	}
+	m.reapLocked()
+	if len(m.runningLocked()) >= m.maxProcesses {
+		return nil, fmt.Errorf("background process limit reached (%d); kill one first", m.maxProcesses)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	p := &backgroundProcess{
+		owner:   owner,
+		command: command,
+		cmd:     cmd,
+		stdin:   stdin,
+		stdout:  newOutputBuffer(m.bufferSize),
+		stderr:  newOutputBuffer(m.bufferSize),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	cmd.Stdout = p.stdout
+	cmd.Stderr = p.stderr
+	prepareCommandForTermination(cmd)
+	// Let Wait return once the shell exits even if a grandchild keeps the
+	// output pipes open.
+	cmd.WaitDelay = time.Second
+	cmd.Cancel = func() error { return terminateProcessTree(cmd) }
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	m.seq++
+	p.id = fmt.Sprintf("p%d", m.seq)
+	p.started = time.Now()
+	m.procs[p.id] = p
+	m.watchIdleLocked(owner)
+
+	go func() {
+		err := cmd.Wait()
+		// Kill anything the shell left behind in its process group.
+		_ = terminateProcessTree(cmd)
+		if cleanup != nil {
+			cleanup()
+		}
+		cancel()
+
+		p.mu.Lock()
+		p.ended = time.Now()
+		p.exitCode = cmd.ProcessState.ExitCode()
+		if err != nil && !p.killed {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				p.exitErr = err.Error()
+			}
+		}
+		p.mu.Unlock()
+		close(p.done)
+
+		logger.DebugCF("tools", "Background process exited", map[string]any{
+			"id":        p.id,
+			"exit_code": p.exitCode,
+		})
+	}()
+
+	logger.InfoCF("tools", "Background process started", map[string]any{
+		"id":      p.id,
+		"owner":   owner,
+		"pid":     cmd.Process.Pid,
+		"command": command,
+	})
+	return p, nil
+}
+
+// Get returns owner's process id.
+func (m *ProcessManager) Get(owner, id string) (*backgroundProcess, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.procs[id]
+	if !ok || p.owner != owner {
+		return nil, false
+	}
+	return p, true
+}
+
+// List returns owner's processes, oldest first.
+func (m *ProcessManager) List(owner string) []processInfo {
+	m.mu.Lock()
+	var procs []*backgroundProcess
+	for _, p := range m.procs {
+		if p.owner == owner {
+			procs = append(procs, p)
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(procs, func(i, j int) bool { return procs[i].started.Before(procs[j].started) })
+	infos := make([]processInfo, 0, len(procs))
+	for _, p := range procs {
+		infos = append(infos, p.info())
+	}
+	return infos
+}
+
+// Remove kills owner's process id if it is still running and forgets it.
+func (m *ProcessManager) Remove(owner, id string) bool {
+	p, ok := m.Get(owner, id)
+	if !ok {
+		return false
+	}
+	p.kill()
+	m.mu.Lock()
+	delete(m.procs, id)
+	m.mu.Unlock()
+	return true
+}
+
+// Hold marks sessionKey as in use until the returned func is called, so
+// its processes are not cleaned up as idle during a long turn.
+func (m *ProcessManager) Hold(sessionKey string) (release func()) {
+	m.mu.Lock()
+	m.busy[sessionKey]++
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.busy[sessionKey]--; m.busy[sessionKey] <= 0 {
+			delete(m.busy, sessionKey)
+		}
+		if _, ok := m.idle[sessionKey]; ok {
+			m.active[sessionKey] = time.Now()
+		}
+	}
+}
+
+// watchIdleLocked marks owner active and arms its idle cleanup. Caller
+// must hold m.mu.
+func (m *ProcessManager) watchIdleLocked(owner string) {
+	if m.idleTimeout <= 0 {
+		return
+	}
+	m.active[owner] = time.Now()
+	if _, ok := m.idle[owner]; !ok {
+		m.idle[owner] = time.AfterFunc(m.idleTimeout, func() { m.expireIdle(owner) })
+	}
+}
+
+// expireIdle kills owner's processes if it has been idle for idleTimeout,
+// or checks again when that time could have come.
+func (m *ProcessManager) expireIdle(owner string) {
+	m.mu.Lock()
+	timer, ok := m.idle[owner]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	if m.busy[owner] > 0 {
+		timer.Reset(m.idleTimeout)
+		m.mu.Unlock()
+		return
+	}
+	if wait := m.idleTimeout - time.Since(m.active[owner]); wait > 0 {
+		timer.Reset(wait)
+		m.mu.Unlock()
+		return
+	}
+	procs := m.takeSessionLocked(owner)
+	m.mu.Unlock()
+
+	if killed := killProcesses(procs); killed > 0 {
+		logger.InfoCF("tools", "Killed background processes of idle session", map[string]any{
+			"session": owner,
+			"count":   killed,
+		})
+	}
+}
+
+// KillSession kills and forgets every process started in sessionKey, for
+// when that conversation ends. It returns how many were still running.
+func (m *ProcessManager) KillSession(sessionKey string) int {
+	m.mu.Lock()
+	procs := m.takeSessionLocked(sessionKey)
+	m.mu.Unlock()
+
+	killed := killProcesses(procs)
+	if killed > 0 {
+		logger.InfoCF("tools", "Killed background processes for ended session", map[string]any{
+			"session": sessionKey,
+			"count":   killed,
+		})
+	}
+	return killed
+}
+
+// takeSessionLocked forgets sessionKey's processes and its idle cleanup
+// and returns the processes. Caller must hold m.mu.
+func (m *ProcessManager) takeSessionLocked(sessionKey string) []*backgroundProcess {
+	if timer, ok := m.idle[sessionKey]; ok {
+		timer.Stop()
+		delete(m.idle, sessionKey)
+		delete(m.active, sessionKey)
+	}
+	var procs []*backgroundProcess
+	for id, p := range m.procs {
+		if p.owner == sessionKey {
+			procs = append(procs, p)
+			delete(m.procs, id)
+		}
+	}
+	return procs
+}
+
+// killProcesses kills procs and returns how many were still running.
+func killProcesses(procs []*backgroundProcess) int {
+	killed := 0
+	for _, p := range procs {
+		if p.kill() {
+			killed++
+		}
+	}
+	return killed
+}
+
+// Close kills every process and refuses new ones.
+func (m *ProcessManager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	procs := m.procs
+	m.procs = make(map[string]*backgroundProcess)
+	for _, timer := range m.idle {
+		timer.Stop()
+	}
+	m.idle = make(map[string]*time.Timer)
+	m.mu.Unlock()
+
+	for _, p := range procs {
+		p.kill()
+	}
+	for _, p := range procs {
+		<-p.done
+	}
+}
+
+func (m *ProcessManager) runningLocked() []*backgroundProcess {
+	var running []*backgroundProcess
+	for _, p := range m.procs {
+		if p.running() {
+			running = append(running, p)
+		}
+	}
+	return running
+}
+
+// reapLocked forgets processes that exited over an hour ago so a long
+// conversation doesn't accumulate them.
+func (m *ProcessManager) reapLocked() {
+	for id, p := range m.procs {
+		p.mu.Lock()
+		stale := !p.ended.IsZero() && time.Since(p.ended) > time.Hour
+		p.mu.Unlock()
+		if stale {
+			delete(m.procs, id)
+		}
+	}
+}
+
+func (p *backgroundProcess) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// kill terminates the process tree and reports whether it was running.
+func (p *backgroundProcess) kill() bool {
+	if !p.running() {
+		return false
+	}
+	p.mu.Lock()
+	p.killed = true
+	p.mu.Unlock()
+	p.cancel()
+	return true
+}
+
+// wait blocks until the process exits, new output arrives past the given
+// offsets, or d passes.
+func (p *backgroundProcess) wait(ctx context.Context, d time.Duration, stdoutOff, stderrOff int64) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		if p.stdout.Len() > stdoutOff || p.stderr.Len() > stderrOff {
+			return
+		}
+		select {
+		case <-p.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case <-p.stdout.Notify():
+		case <-p.stderr.Notify():
+		}
+	}
+}
+
+func (p *backgroundProcess) info() processInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := processInfo{
+		ID:      p.id,
+		Command: p.command,
+		PID:     p.cmd.Process.Pid,
+		Running: p.ended.IsZero(),
+		Started: p.started,
+		Ended:   p.ended,
+		Error:   p.exitErr,
+	}
+	if !info.Running {
+		info.ExitCode = p.exitCode
+	}
+	return info
+}
+
+func (i processInfo) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (pid %d): ", i.ID, i.PID)
+	switch {
+	case i.Running:
+		fmt.Fprintf(&sb, "running for %s", time.Since(i.Started).Round(time.Second))
+	case i.Error != "":
+		fmt.Fprintf(&sb, "failed: %s", i.Error)
+	default:
+		fmt.Fprintf(&sb, "exited with code %d", i.ExitCode)
+	}
+	fmt.Fprintf(&sb, " — %s", i.Command)
+	return sb.String()
+}
+
+// outputBuffer keeps the most recent output of a stream. Offsets count
+// every byte ever written, so a reader can resume where it left off; bytes
+// that scrolled out of the buffer are reported as skipped.
+type outputBuffer struct {
+	mu      sync.Mutex
+	data    []byte
+	base    int64 // offset of data[0]
+	limit   int
+	changed chan struct{}
+}
+
+func newOutputBuffer(limit int) *outputBuffer {
+	return &outputBuffer{limit: limit, changed: make(chan struct{})}
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if over := len(b.data) - b.limit; over > 0 {
+		b.data = append(b.data[:0], b.data[over:]...)
+		b.base += int64(over)
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return len(p), nil
+}
+
+// Len returns the total number of bytes written.
+func (b *outputBuffer) Len() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.base + int64(len(b.data))
+}
+
+// Notify returns a channel closed at the next write.
+func (b *outputBuffer) Notify() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changed
+}
+
+// ReadAt returns up to limit bytes starting at offset, the offset to
+// read from next, and how many bytes before the returned data were lost.
+func (b *outputBuffer) ReadAt(offset int64, limit int) (data []byte, next, skipped int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	offset = max(offset, 0)
+	if offset < b.base {
+		skipped = b.base - offset
+		offset = b.base
+	}
+	start := min(offset-b.base, int64(len(b.data)))
+	end := min(start+int64(limit), int64(len(b.data)))
+	data = append([]byte(nil), b.data[start:end]...)
+	return data, b.base + end, skipped
+}
+
+// ProcessTool inspects and controls the background processes started with
+// exec's background option in the current session.
+type ProcessTool struct {
+	manager *ProcessManager
+}
+
+// NewProcessTool creates a process tool over manager.
+func NewProcessTool(manager *ProcessManager) *ProcessTool {
+	return &ProcessTool{manager: manager}
+}
+
+func (t *ProcessTool) Name() string {
+	return "process"
+}
+
+func (t *ProcessTool) Description() string {
+	return "Manage background processes started with exec (background=true). " +
+		"Actions: 'list' shows processes; 'poll' returns new stdout/stderr since the given offsets " +
+		"(pass back next_stdout_offset/next_stderr_offset) and optionally waits for output; " +
+		"'write' sends input to stdin; 'signal' sends a signal such as INT or TERM; " +
+		"'kill' stops a process and forgets it."
+}
+
+func (t *ProcessTool) Parameters() map[string]any {
+	signals := make([]string, 0, len(processSignals))
+	for name := range processSignals {
+		signals = append(signals, name)
+	}
+	sort.Strings(signals)
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type": "string",
+				"enum": []string{"list", "poll", "write", "signal", "kill"},
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Process handle returned by exec, e.g. p1",
+			},
+			"stdout_offset": map[string]any{
+				"type":        "integer",
+				"description": "poll: stdout offset to read from (default 0)",
+			},
+			"stderr_offset": map[string]any{
+				"type":        "integer",
+				"description": "poll: stderr offset to read from (default 0)",
+			},
+			"wait_seconds": map[string]any{
+				"type":        "integer",
+				"description": "poll: wait up to this long (max 30) for new output or exit",
+			},
+			"input": map[string]any{
+				"type":        "string",
+				"description": "write: text to send to stdin; include a trailing newline for line input",
+			},
+			"close_stdin": map[string]any{
+				"type":        "boolean",
+				"description": "write: close stdin after writing (sends EOF)",
+			},
+			"signal": map[string]any{
+				"type":        "string",
+				"enum":        signals,
+				"description": "signal: the signal to send (default TERM)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ProcessTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	owner := sessionKeyFrom(ctx)
+
+	if action == "list" {
+		infos := t.manager.List(owner)
+		if len(infos) == 0 {
+			return SilentResult("No background processes.")
+		}
+		lines := make([]string, 0, len(infos))
+		for _, info := range infos {
+			lines = append(lines, info.String())
+		}
+		return SilentResult(strings.Join(lines, "\n"))
+	}
+
+	id, _ := args["id"].(string)
+	if id == "" {
+		return ErrorResult("id is required")
+	}
+	p, ok := t.manager.Get(owner, id)
+	if !ok {
+		return ErrorResult(fmt.Sprintf("no background process %q", id))
+	}
+
+	switch action {
+	case "poll":
+		return t.poll(ctx, p, args)
+	case "write":
+		input, _ := args["input"].(string)
+		if !p.running() {
+			return ErrorResult(fmt.Sprintf("process %s has exited", id))
+		}
+		if input != "" {
+			if _, err := io.WriteString(p.stdin, input); err != nil {
+				return ErrorResult(fmt.Sprintf("writing to %s: %v", id, err))
+			}
+		}
+		if closeStdin, _ := args["close_stdin"].(bool); closeStdin {
+			_ = p.stdin.Close()
+		}
+		return SilentResult(fmt.Sprintf("Wrote %d bytes to %s", len(input), id))
+	case "signal":
+		name, _ := args["signal"].(string)
+		name = strings.TrimPrefix(strings.ToUpper(name), "SIG")
+		if name == "" {
+			name = "TERM"
+		}
+		sig, ok := processSignals[name]
+		if !ok {
+			return ErrorResult(fmt.Sprintf("unsupported signal %q", name))
+		}
+		if !p.running() {
+			return ErrorResult(fmt.Sprintf("process %s has exited", id))
+		}
+		if err := signalProcessTree(p.cmd, sig); err != nil {
+			return ErrorResult(fmt.Sprintf("signaling %s: %v", id, err))
+		}
+		return SilentResult(fmt.Sprintf("Sent SIG%s to %s", name, id))
+	case "kill":
+		info := p.info()
+		t.manager.Remove(owner, id)
+		if !info.Running {
+			return SilentResult(fmt.Sprintf("Process %s had already exited; removed", id))
+		}
+		return SilentResult(fmt.Sprintf("Killed %s", id))
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action %q", action))
+	}
+}
+
+func (t *ProcessTool) poll(ctx context.Context, p *backgroundProcess, args map[string]any) *ToolResult {
+	stdoutOff := int64(intArg(args, "stdout_offset"))
+	stderrOff := int64(intArg(args, "stderr_offset"))
+	wait := time.Duration(intArg(args, "wait_seconds")) * time.Second
+	p.wait(ctx, min(wait, maxPollWait), stdoutOff, stderrOff)
+
+	stdout, nextOut, skippedOut := p.stdout.ReadAt(stdoutOff, maxPollChunk)
+	stderr, nextErr, skippedErr := p.stderr.ReadAt(stderrOff, maxPollChunk)
+
+	var sb strings.Builder
+	sb.WriteString(p.info().String())
+	fmt.Fprintf(&sb, "\nnext_stdout_offset=%d next_stderr_offset=%d", nextOut, nextErr)
+	if more := p.stdout.Len() - nextOut; more > 0 {
+		fmt.Fprintf(&sb, " (%d more stdout bytes; poll again)", more)
+	}
+	writeStream := func(name string, data []byte, skipped int64) {
+		if skipped > 0 {
+			fmt.Fprintf(&sb, "\n[%d %s bytes dropped from the buffer]", skipped, name)
+		}
+		if len(data) > 0 {
+			fmt.Fprintf(&sb, "\n%s:\n%s", strings.ToUpper(name), data)
+		}
+	}
+	writeStream("stdout", stdout, skippedOut)
+	writeStream("stderr", stderr, skippedErr)
+	return SilentResult(sb.String())
+}
+
+type sessionKeyCtx struct{}
+
+// WithSessionKey returns a context carrying the session a tool call runs
+// in. Background processes started with it belong to that session, so
+// conversations sharing a channel and chat, such as API dispatches from
+// different keys, cannot reach each other's processes.
+func WithSessionKey(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionKeyCtx{}, sessionKey)
+}
+
+// sessionKeyFrom returns the session stored by WithSessionKey, if any.
+func sessionKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyCtx{}).(string)
+	return key
+}
+
+// intArg reads a non-negative integer argument, which arrives from JSON as
+// a float64.
+func intArg(args map[string]any, name string) int {
+	switch v := args[name].(type) {
+	case float64:
+		return max(int(v), 0)
+	case int:
+		return max(v, 0)
+	}
+	return 0
+}
@@ -0,0 +1,255 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+const testSession = "agent:main:telegram:direct:42"
+
+// sessionCtx is the context tool calls of testSession run with.
+var sessionCtx = WithSessionKey(context.Background(), testSession)
+
+func newBackgroundExecTool(t *testing.T, cfg config.ExecBackgroundConfig) (*ExecTool, *ProcessTool) {
+	t.Helper()
+	manager := NewProcessManager(cfg)
+	t.Cleanup(manager.Close)
+	exec := NewExecTool(t.TempDir(), false)
+	exec.SetProcessManager(manager)
+	return exec, NewProcessTool(manager)
+}
+
+var handleRe = regexp.MustCompile(`process (p\d+)`)
+
+func startBackground(t *testing.T, tool *ExecTool, command string) string {
+	t.Helper()
+	result := tool.Execute(sessionCtx, map[string]any{"command": command, "background": true})
+	if result.IsError {
+		t.Fatalf("start failed: %s", result.ForLLM)
+	}
+	m := handleRe.FindStringSubmatch(result.ForLLM)
+	if m == nil {
+		t.Fatalf("no handle in %q", result.ForLLM)
+	}
+	return m[1]
+}
+
+func processAction(t *testing.T, tool *ProcessTool, args map[string]any) *ToolResult {
+	t.Helper()
+	return tool.Execute(sessionCtx, args)
+}
+
+var nextOffsetRe = regexp.MustCompile(`next_stdout_offset=(\d+)`)
+
+func TestProcessTool_PollIncrementalOutput(t *testing.T) {
+	exec, proc := newBackgroundExecTool(t, config.ExecBackgroundConfig{})
+	id := startBackground(t, exec, "echo first; sleep 0.3; echo second")
+
+	result := processAction(t, proc, map[string]any{"action": "poll", "id": id, "wait_seconds": float64(5)})
+	if !strings.Contains(result.ForLLM, "first") {
+		t.Fatalf("expected first line, got: %s", result.ForLLM)
+	}
+	m := nextOffsetRe.FindStringSubmatch(result.ForLLM)
+	if m == nil {
+		t.Fatalf("no offset in %q", result.ForLLM)
+	}
+	offset, _ := strconv.Atoi(m[1])
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result = processAction(t, proc, map[string]any{
+			"action": "poll", "id": id, "stdout_offset": float64(offset), "wait_seconds": float64(2),
+		})
+		if strings.Contains(result.ForLLM, "exited with code 0") || time.Now().After(deadline) {
+			break
+		}
+	}
+	_, stdout, _ := strings.Cut(result.ForLLM, "STDOUT:")
+	if strings.Contains(stdout, "first") || !strings.Contains(stdout, "second") {
+		t.Fatalf("expected only new output, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "exited with code 0") {
+		t.Fatalf("expected exit status, got: %s", result.ForLLM)
+	}
+}
+
+func TestProcessTool_WriteStdin(t *testing.T) {
+	exec, proc := newBackgroundExecTool(t, config.ExecBackgroundConfig{})
+	id := startBackground(t, exec, "read line; echo got:$line")
+
+	result := processAction(t, proc, map[string]any{"action": "write", "id": id, "input": "hello\n"})
+	if result.IsError {
+		t.Fatalf("write failed: %s", result.ForLLM)
+	}
+	result = processAction(t, proc, map[string]any{"action": "poll", "id": id, "wait_seconds": float64(5)})
+	if !strings.Contains(result.ForLLM, "got:hello") {
+		t.Fatalf("expected echoed input, got: %s", result.ForLLM)
+	}
+}
+
+func TestProcessTool_SignalAndKill(t *testing.T) {
+	exec, proc := newBackgroundExecTool(t, config.ExecBackgroundConfig{})
+	id := startBackground(t, exec, "trap 'echo caught; exit 3' INT; while true; do sleep 0.1; done")
+	time.Sleep(200 * time.Millisecond)
+
+	result := processAction(t, proc, map[string]any{"action": "signal", "id": id, "signal": "SIGINT"})
+	if result.IsError {
+		t.Fatalf("signal failed: %s", result.ForLLM)
+	}
+	result = processAction(t, proc, map[string]any{"action": "poll", "id": id, "wait_seconds": float64(5)})
+	for i := 0; i < 20 && strings.Contains(result.ForLLM, "running"); i++ {
+		time.Sleep(100 * time.Millisecond)
+		result = processAction(t, proc, map[string]any{"action": "poll", "id": id})
+	}
+	if !strings.Contains(result.ForLLM, "caught") || !strings.Contains(result.ForLLM, "exited with code 3") {
+		t.Fatalf("expected trap to run, got: %s", result.ForLLM)
+	}
+
+	id = startBackground(t, exec, "sleep 60")
+	result = processAction(t, proc, map[string]any{"action": "kill", "id": id})
+	if result.IsError || !strings.Contains(result.ForLLM, "Killed") {
+		t.Fatalf("kill failed: %s", result.ForLLM)
+	}
+	result = processAction(t, proc, map[string]any{"action": "list"})
+	if strings.Contains(result.ForLLM, id+" ") {
+		t.Fatalf("killed process still listed: %s", result.ForLLM)
+	}
+}
+
+func TestProcessManager_LimitAndSessionCleanup(t *testing.T) {
+	exec, proc := newBackgroundExecTool(t, config.ExecBackgroundConfig{MaxProcesses: 2})
+	first := startBackground(t, exec, "sleep 60")
+	startBackground(t, exec, "sleep 60")
+
+	result := exec.Execute(sessionCtx, map[string]any{"command": "sleep 60", "background": true})
+	if !result.IsError || !strings.Contains(result.ForLLM, "limit") {
+		t.Fatalf("expected limit error, got: %s", result.ForLLM)
+	}
+
+	// Another conversation can't see these processes.
+	other := WithSessionKey(context.Background(), "agent:main:discord:direct:7")
+	result = proc.Execute(other, map[string]any{"action": "poll", "id": first})
+	if !result.IsError {
+		t.Fatalf("expected other session to be refused, got: %s", result.ForLLM)
+	}
+
+	p, _ := proc.manager.Get(testSession, first)
+	if killed := proc.manager.KillSession(testSession); killed != 2 {
+		t.Fatalf("KillSession killed %d processes, want 2", killed)
+	}
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process still running after session cleanup")
+	}
+	result = processAction(t, proc, map[string]any{"action": "list"})
+	if !strings.Contains(result.ForLLM, "No background processes") {
+		t.Fatalf("expected no processes, got: %s", result.ForLLM)
+	}
+}
+
+func TestProcessManager_MaxLifetime(t *testing.T) {
+	manager := NewProcessManager(config.ExecBackgroundConfig{})
+	manager.maxLifetime = 200 * time.Millisecond
+	defer manager.Close()
+	exec := NewExecTool(t.TempDir(), false)
+	exec.SetProcessManager(manager)
+
+	id := startBackground(t, exec, "sleep 60")
+	p, _ := manager.Get(testSession, id)
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process outlived its maximum lifetime")
+	}
+}
+
+func TestProcessManager_IdleSessionCleanup(t *testing.T) {
+	exec, proc := newBackgroundExecTool(t, config.ExecBackgroundConfig{})
+	manager := proc.manager
+	manager.idleTimeout = 200 * time.Millisecond
+
+	// A turn in progress keeps the session's processes alive.
+	release := manager.Hold(testSession)
+	id := startBackground(t, exec, "sleep 60")
+	p, _ := manager.Get(testSession, id)
+	select {
+	case <-p.done:
+		t.Fatal("process killed while its session was in a turn")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process outlived its idle session")
+	}
+	if _, ok := manager.Get(testSession, id); ok {
+		t.Error("idle session's process is still listed")
+	}
+}
+
+func TestOutputBuffer_DropsOldest(t *testing.T) {
+	b := newOutputBuffer(8)
+	b.Write([]byte("0123456789"))
+	b.Write([]byte("ab"))
+
+	data, next, skipped := b.ReadAt(0, 100)
+	if string(data) != "456789ab" || next != 12 || skipped != 4 {
+		t.Fatalf("ReadAt(0) = %q, %d, %d", data, next, skipped)
+	}
+	data, next, skipped = b.ReadAt(10, 1)
+	if string(data) != "a" || next != 11 || skipped != 0 {
+		t.Fatalf("ReadAt(10) = %q, %d, %d", data, next, skipped)
+	}
+}
+
+func TestExecTool_BackgroundUnavailable(t *testing.T) {
+	tool := NewExecTool(t.TempDir(), false)
+	result := tool.Execute(context.Background(), map[string]any{"command": "sleep 1", "background": true})
+	if !result.IsError {
+		t.Fatalf("expected error without a process manager, got: %s", result.ForLLM)
+	}
+}
+
+func TestProcessTool_APIKeysCannotReachEachOther(t *testing.T) {
+	exec, proc := newBackgroundExecTool(t, config.ExecBackgroundConfig{})
+	// Dispatches without a chat_id share the "api" channel and "dispatch"
+	// chat; only their session keys tell the callers apart.
+	alice := WithSessionKey(context.Background(), "api:alice:dispatch")
+	bob := WithSessionKey(context.Background(), "api:bob:dispatch")
+
+	result := exec.Execute(alice, map[string]any{"command": "sleep 60", "background": true})
+	m := handleRe.FindStringSubmatch(result.ForLLM)
+	if result.IsError || m == nil {
+		t.Fatalf("start failed: %s", result.ForLLM)
+	}
+	id := m[1]
+
+	if result := proc.Execute(bob, map[string]any{"action": "list"}); strings.Contains(result.ForLLM, id) {
+		t.Errorf("bob lists alice's process: %s", result.ForLLM)
+	}
+	for _, args := range []map[string]any{
+		{"action": "poll", "id": id},
+		{"action": "write", "id": id, "input": "x\n"},
+		{"action": "signal", "id": id, "signal": "TERM"},
+		{"action": "kill", "id": id},
+	} {
+		if result := proc.Execute(bob, args); !result.IsError {
+			t.Errorf("bob's %s reached alice's process: %s", args["action"], result.ForLLM)
+		}
+	}
+
+	if result := proc.Execute(alice, map[string]any{"action": "list"}); !strings.Contains(result.ForLLM, id+" ") {
+		t.Errorf("alice no longer lists the process: %s", result.ForLLM)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+	"github.com/tinyland-inc/tinyclaw/pkg/metrics"
 	"github.com/tinyland-inc/tinyclaw/pkg/providers"
 )
 
@@ -82,10 +83,12 @@ func (r *ToolRegistry) ExecuteWithContext(
 	start := time.Now()
 	result := tool.Execute(ctx, args)
 	duration := time.Since(start)
+	metrics.ToolDuration.Observe(duration.Seconds(), name)
 
 	// Log based on result type
 	switch {
 	case result.IsError:
+		metrics.ToolExecutions.Inc(name, "error")
 		logger.ErrorCF("tool", "Tool execution failed",
 			map[string]any{
 				"tool":     name,
@@ -93,12 +96,14 @@ func (r *ToolRegistry) ExecuteWithContext(
 				"error":    result.ForLLM,
 			})
 	case result.Async:
+		metrics.ToolExecutions.Inc(name, "async")
 		logger.InfoCF("tool", "Tool started (async)",
 			map[string]any{
 				"tool":     name,
 				"duration": duration.Milliseconds(),
 			})
 	default:
+		metrics.ToolExecutions.Inc(name, "ok")
 		logger.InfoCF("tool", "Tool execution completed",
 			map[string]any{
 				"tool":          name,
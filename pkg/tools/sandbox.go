@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// sandboxExitCode is the exit status of a sandboxed command that could not
+// be confined; the reason is written to stderr.
+const sandboxExitCode = 125
+
+// sandboxSpec describes one sandboxed command. It is passed as JSON to the
+// re-executed helper process that sets up the sandbox.
+type sandboxSpec struct {
+	Command        string   `json:"command"`
+	Dir            string   `json:"dir"`
+	ReadWritePaths []string `json:"read_write_paths"`
+	Network        bool     `json:"network"`
+	MemoryBytes    uint64   `json:"memory_bytes,omitempty"`
+	CPUSeconds     uint64   `json:"cpu_seconds,omitempty"`
+	MaxProcesses   uint64   `json:"max_processes,omitempty"`
+	Rlimits        bool     `json:"rlimits"` // false when a cgroup enforces the limits
+}
+
+// newSandboxSpec builds the spec for running command in dir with the
+// workspace writable.
+func newSandboxSpec(cfg config.ExecSandboxConfig, workspace, command, dir string) sandboxSpec {
+	spec := sandboxSpec{
+		Command:      command,
+		Dir:          dir,
+		Network:      cfg.Network,
+		MemoryBytes:  uint64(max(cfg.MemoryMB, 0)) << 20,
+		CPUSeconds:   uint64(max(cfg.CPUSeconds, 0)),
+		MaxProcesses: uint64(max(cfg.MaxProcesses, 0)),
+		Rlimits:      true,
+	}
+	for _, p := range append([]string{workspace}, cfg.ReadWritePaths...) {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			spec.ReadWritePaths = append(spec.ReadWritePaths, abs)
+		}
+	}
+	return spec
+}
+
+var sandboxCheck sync.Once
+
+// SetSandbox runs commands in the Linux exec sandbox when cfg.Enabled.
+// The sandbox fails closed: if the kernel cannot provide it, commands fail
+// instead of running unconfined.
+func (t *ExecTool) SetSandbox(cfg config.ExecSandboxConfig) {
+	if !cfg.Enabled {
+		t.sandbox = nil
+		return
+	}
+	t.sandbox = &cfg
+
+	sandboxCheck.Do(func() {
+		features, err := sandboxAvailable()
+		if err != nil {
+			logger.ErrorCF("tools", "Exec sandbox enabled but unavailable; exec commands will fail", map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		logger.InfoCF("tools", "Exec sandbox enabled", features)
+	})
+}
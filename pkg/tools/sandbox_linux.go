@@ -0,0 +1,601 @@
+package tools
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/logger"
+)
+
+// sandboxSpecEnv carries the sandboxSpec to the helper. A process started
+// with it set never reaches main: the init below confines it and execs
+// the command.
+const sandboxSpecEnv = "TINYCLAW_SANDBOX_SPEC"
+
+func init() {
+	if raw, ok := os.LookupEnv(sandboxSpecEnv); ok {
+		runSandboxHelper(raw)
+	}
+}
+
+var sandboxCgroupSeq atomic.Uint64
+
+// sandboxBaseEnv are the variables a sandboxed command always inherits;
+// anything else, such as provider API keys, must be named in the config.
+var sandboxBaseEnv = []string{"PATH", "HOME", "LANG", "TERM"}
+
+// sandboxEnv returns the environment for a sandboxed command: the base
+// variables and the configured extra ones that are set.
+func sandboxEnv(extra []string) []string {
+	var env []string
+	seen := make(map[string]bool)
+	for _, name := range append(slices.Clone(sandboxBaseEnv), extra...) {
+		if value, ok := os.LookupEnv(name); ok && !seen[name] {
+			seen[name] = true
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// sandboxCommand returns a command that re-executes this binary as the
+// sandbox helper in fresh namespaces, and a cleanup function to call once
+// it has exited.
+func sandboxCommand(ctx context.Context, cfg config.ExecSandboxConfig, spec sandboxSpec) (*exec.Cmd, func(), error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("sandbox: cannot locate executable: %w", err)
+	}
+
+	cleanup := func() {}
+	cgroupFD := -1
+	if cfg.CgroupParent != "" {
+		dir, fd, err := createSandboxCgroup(cfg.CgroupParent, spec)
+		if err != nil {
+			logger.WarnCF("tools", "Exec sandbox cgroup unavailable, using rlimits", map[string]any{
+				"cgroup_parent": cfg.CgroupParent,
+				"error":         err.Error(),
+			})
+		} else {
+			spec.Rlimits = false
+			cgroupFD = fd
+			cleanup = func() {
+				syscall.Close(fd)
+				removeSandboxCgroup(dir)
+			}
+		}
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC)
+	if !spec.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	cmd := exec.CommandContext(ctx, self)
+	cmd.Args = []string{"tinyclaw-sandbox"}
+	cmd.Env = append(sandboxEnv(cfg.Env), sandboxSpecEnv+"="+string(data))
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: flags,
+		// Keep the caller's IDs inside so file ownership looks the same;
+		// the helper's capabilities in the namespace end at exec.
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+	if cgroupFD >= 0 {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = cgroupFD
+	}
+	return cmd, cleanup, nil
+}
+
+// createSandboxCgroup makes a cgroup v2 child of parent with the spec's
+// memory and process limits and returns it with an open directory fd for
+// CLONE_INTO_CGROUP.
+func createSandboxCgroup(parent string, spec sandboxSpec) (string, int, error) {
+	dir := filepath.Join(parent, fmt.Sprintf("tinyclaw-exec-%d-%d", os.Getpid(), sandboxCgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", -1, err
+	}
+	limits := map[string]string{}
+	if spec.MemoryBytes > 0 {
+		limits["memory.max"] = strconv.FormatUint(spec.MemoryBytes, 10)
+		limits["memory.swap.max"] = "0"
+	}
+	if spec.MaxProcesses > 0 {
+		limits["pids.max"] = strconv.FormatUint(spec.MaxProcesses, 10)
+	}
+	for file, value := range limits {
+		err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0o644)
+		// Swap accounting is optional; the other limits are not.
+		if err != nil && file != "memory.swap.max" {
+			os.Remove(dir)
+			return "", -1, fmt.Errorf("setting %s: %w", file, err)
+		}
+	}
+	fd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		os.Remove(dir)
+		return "", -1, err
+	}
+	return dir, fd, nil
+}
+
+// removeSandboxCgroup kills anything the command left running and removes
+// its cgroup.
+func removeSandboxCgroup(dir string) {
+	_ = os.WriteFile(filepath.Join(dir, "cgroup.kill"), []byte("1"), 0o644)
+	for range 50 {
+		if err := os.Remove(dir); err == nil || os.IsNotExist(err) {
+			return
+		}
+		syscall.Nanosleep(&syscall.Timespec{Nsec: 20e6}, nil)
+	}
+}
+
+// runSandboxHelper runs in the re-executed child, which starts in new
+// namespaces. It confines itself and execs the shell; it never returns.
+func runSandboxHelper(raw string) {
+	// Landlock, seccomp and no_new_privs apply to the calling thread, which
+	// must be the one that calls execve.
+	runtime.LockOSThread()
+	os.Unsetenv(sandboxSpecEnv)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "exec sandbox: %v\n", err)
+		os.Exit(sandboxExitCode)
+	}
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		fail(fmt.Errorf("invalid spec: %w", err))
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		fail(err)
+	}
+	if err := enterSandbox(spec); err != nil {
+		fail(err)
+	}
+	// The environment is already reduced to sandboxEnv by the parent.
+	fail(syscall.Exec(sh, []string{"sh", "-c", spec.Command}, os.Environ()))
+}
+
+// enterSandbox sets up the filesystem view, limits, Landlock and seccomp
+// for the current process.
+func enterSandbox(spec sandboxSpec) error {
+	// Keep our mount changes out of the parent namespace.
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making mounts private: %w", err)
+	}
+
+	var writable []string
+	for _, p := range spec.ReadWritePaths {
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		if err := syscall.Mount(p, p, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("binding %s: %w", p, err)
+		}
+		writable = append(writable, p)
+	}
+
+	if err := setMountReadOnly("/", true); err != nil {
+		return fmt.Errorf("making filesystem read-only: %w", err)
+	}
+	for _, p := range writable {
+		if err := setMountReadOnly(p, false); err != nil {
+			return fmt.Errorf("making %s writable: %w", p, err)
+		}
+	}
+
+	// A private /tmp, unless a writable path lives under it.
+	privateTmp := !slicesAnyUnder(writable, "/tmp")
+	if privateTmp {
+		if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("mounting /tmp: %w", err)
+		}
+		writable = append(writable, "/tmp")
+	}
+
+	// Show only the sandbox's processes. This can fail where /proc is
+	// partly masked (as in some containers); the old view stays read-only.
+	_ = syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+
+	if !spec.Network {
+		// The new network namespace only has a loopback device, and it is down.
+		_ = setLoopbackUp()
+	}
+
+	if err := syscall.Chdir(cmp.Or(spec.Dir, "/")); err != nil {
+		return fmt.Errorf("entering %s: %w", spec.Dir, err)
+	}
+
+	if err := applySandboxRlimits(spec); err != nil {
+		return err
+	}
+	if err := prctl(prSetNoNewPrivs, 1); err != nil {
+		return fmt.Errorf("setting no_new_privs: %w", err)
+	}
+	if err := applyLandlock(writable); err != nil {
+		return fmt.Errorf("landlock: %w", err)
+	}
+	if err := installSeccompFilter(); err != nil {
+		return fmt.Errorf("seccomp: %w", err)
+	}
+	return nil
+}
+
+func slicesAnyUnder(paths []string, dir string) bool {
+	for _, p := range paths {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func applySandboxRlimits(spec sandboxSpec) error {
+	limits := map[int]uint64{}
+	if spec.CPUSeconds > 0 {
+		limits[syscall.RLIMIT_CPU] = spec.CPUSeconds
+	}
+	// Without a cgroup, fall back to address space and per-user process
+	// limits, which are coarser: RLIMIT_NPROC counts all of the user's
+	// processes, not just the sandbox's.
+	if spec.Rlimits {
+		if spec.MemoryBytes > 0 {
+			limits[syscall.RLIMIT_AS] = spec.MemoryBytes
+		}
+		if spec.MaxProcesses > 0 {
+			limits[rlimitNproc] = spec.MaxProcesses
+		}
+	}
+	for resource, value := range limits {
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("setting rlimit %d: %w", resource, err)
+		}
+	}
+	return nil
+}
+
+const (
+	rlimitNproc     = 6
+	prSetNoNewPrivs = 38
+	prSetSeccomp    = 22
+)
+
+func prctl(option, arg uintptr) error {
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, option, arg, 0, 0, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// mount_setattr(2), Linux 5.12+.
+const (
+	sysMountSetattr    = 442
+	atRecursive        = 0x8000
+	mountAttrReadOnly  = 0x1
+	mountAttrNoSuid    = 0x2
+	mountAttrStructLen = 32
+)
+
+type mountAttr struct {
+	attrSet     uint64
+	attrClr     uint64
+	propagation uint64
+	usernsFD    uint64
+}
+
+// setMountReadOnly changes the mount at path and every mount below it to
+// read-only (and nosuid) or back to read-write.
+func setMountReadOnly(path string, readOnly bool) error {
+	attr := mountAttr{attrClr: mountAttrReadOnly}
+	if readOnly {
+		attr = mountAttr{attrSet: mountAttrReadOnly | mountAttrNoSuid}
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	atFDCWD := -100
+	_, _, errno := syscall.Syscall6(sysMountSetattr, uintptr(atFDCWD), uintptr(unsafe.Pointer(p)),
+		atRecursive, uintptr(unsafe.Pointer(&attr)), mountAttrStructLen, 0)
+	if errno == syscall.ENOSYS {
+		return remountEach(path, readOnly)
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// remountEach is the pre-5.12 fallback for setMountReadOnly: remount each
+// mount under path individually, keeping flags the kernel won't let an
+// unprivileged namespace clear.
+func remountEach(path string, readOnly bool) error {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		target := fields[4]
+		if target != path && !strings.HasPrefix(target, strings.TrimSuffix(path, "/")+"/") {
+			continue
+		}
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT)
+		for _, opt := range strings.Split(fields[5], ",") {
+			switch opt {
+			case "nosuid":
+				flags |= syscall.MS_NOSUID
+			case "nodev":
+				flags |= syscall.MS_NODEV
+			case "noexec":
+				flags |= syscall.MS_NOEXEC
+			case "ro":
+				if !readOnly && target != path {
+					flags |= syscall.MS_RDONLY
+				}
+			}
+		}
+		if readOnly {
+			flags |= syscall.MS_RDONLY | syscall.MS_NOSUID
+		}
+		if err := syscall.Mount("", target, "", flags, ""); err != nil && target == path {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// setLoopbackUp brings up lo in a new network namespace so local servers
+// started by the command still work.
+func setLoopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var ifr [40]byte // struct ifreq
+	copy(ifr[:], "lo")
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCGIFFLAGS,
+		uintptr(unsafe.Pointer(&ifr[0]))); errno != 0 {
+		return errno
+	}
+	flags := binary.NativeEndian.Uint16(ifr[16:]) | syscall.IFF_UP
+	binary.NativeEndian.PutUint16(ifr[16:], flags)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS,
+		uintptr(unsafe.Pointer(&ifr[0]))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Landlock, Linux 5.13+.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+
+	llExecute    = 1 << 0
+	llWriteFile  = 1 << 1
+	llReadFile   = 1 << 2
+	llReadDir    = 1 << 3
+	llRemoveDir  = 1 << 4
+	llRemoveFile = 1 << 5
+	llMakeChar   = 1 << 6
+	llMakeDir    = 1 << 7
+	llMakeReg    = 1 << 8
+	llMakeSock   = 1 << 9
+	llMakeFifo   = 1 << 10
+	llMakeBlock  = 1 << 11
+	llMakeSym    = 1 << 12
+	llRefer      = 1 << 13 // ABI 2
+	llTruncate   = 1 << 14 // ABI 3
+
+	llReadOnly = llExecute | llReadFile | llReadDir
+)
+
+// landlockABI returns the kernel's Landlock ABI version, or 0 when
+// Landlock is unavailable.
+func landlockABI() int {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+}
+
+// applyLandlock limits the process to reading and executing everywhere,
+// full access under writable, and reading and writing existing files
+// under /dev. Kernels without Landlock are left to the read-only mounts.
+func applyLandlock(writable []string) error {
+	abi := landlockABI()
+	if abi == 0 {
+		return nil
+	}
+	handled := uint64(llReadOnly | llWriteFile | llRemoveDir | llRemoveFile | llMakeChar | llMakeDir |
+		llMakeReg | llMakeSock | llMakeFifo | llMakeBlock | llMakeSym)
+	if abi >= 2 {
+		handled |= llRefer
+	}
+	if abi >= 3 {
+		handled |= llTruncate
+	}
+
+	rulesetAttr := handled // struct landlock_ruleset_attr { __u64 handled_access_fs; }
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&rulesetAttr)), 8, 0)
+	if errno != 0 {
+		return fmt.Errorf("creating ruleset: %w", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	rules := map[string]uint64{
+		"/":    llReadOnly,
+		"/dev": llReadOnly | llWriteFile | (handled & llTruncate),
+	}
+	for _, p := range writable {
+		rules[p] = handled
+	}
+	for path, access := range rules {
+		if err := landlockAllow(int(fd), path, access); err != nil {
+			return fmt.Errorf("allowing %s: %w", path, err)
+		}
+	}
+
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("restricting: %w", errno)
+	}
+	return nil
+}
+
+func landlockAllow(rulesetFD int, path string, access uint64) error {
+	const oPath = 0x200000
+	fd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			return nil
+		}
+		return err
+	}
+	defer syscall.Close(fd)
+
+	// Regular files only accept file rights.
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err == nil && st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		access &= llExecute | llWriteFile | llReadFile | llTruncate
+	}
+
+	// struct landlock_path_beneath_attr is packed: __u64 allowed_access; __s32 parent_fd.
+	var attr [12]byte
+	binary.NativeEndian.PutUint64(attr[0:], access)
+	binary.NativeEndian.PutUint32(attr[8:], uint32(fd))
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFD), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&attr[0])), 0, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Seccomp BPF.
+const (
+	bpfLdWAbs = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJeqK   = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJgeK   = 0x35 // BPF_JMP | BPF_JGE | BPF_K
+	bpfRetK   = 0x06 // BPF_RET | BPF_K
+
+	seccompModeFilter      = 2
+	seccompRetKillProcess  = 0x80000000
+	seccompRetErrno        = 0x00050000
+	seccompRetAllow        = 0x7fff0000
+	seccompDataNrOffset    = 0
+	seccompDataArchOffset  = 4
+	seccompDeniedErrno     = uint32(syscall.EPERM)
+	seccompRetDeniedErrno  = seccompRetErrno | seccompDeniedErrno
+	seccompMaxInstructions = 4096
+)
+
+// seccompProgram builds a filter that kills processes using another
+// architecture's syscall table and fails deniedSyscalls with EPERM.
+func seccompProgram() []syscall.SockFilter {
+	prog := []syscall.SockFilter{
+		{Code: bpfLdWAbs, K: seccompDataArchOffset},
+		{Code: bpfJeqK, K: auditArch, Jt: 1},
+		{Code: bpfRetK, K: seccompRetKillProcess},
+		{Code: bpfLdWAbs, K: seccompDataNrOffset},
+	}
+	if x32ABIBit != 0 {
+		prog = append(prog,
+			syscall.SockFilter{Code: bpfJgeK, K: x32ABIBit, Jf: 1},
+			syscall.SockFilter{Code: bpfRetK, K: seccompRetDeniedErrno},
+		)
+	}
+	for _, nr := range deniedSyscalls {
+		prog = append(prog,
+			syscall.SockFilter{Code: bpfJeqK, K: nr, Jf: 1},
+			syscall.SockFilter{Code: bpfRetK, K: seccompRetDeniedErrno},
+		)
+	}
+	return append(prog, syscall.SockFilter{Code: bpfRetK, K: seccompRetAllow})
+}
+
+func installSeccompFilter() error {
+	if len(deniedSyscalls) == 0 {
+		return nil
+	}
+	prog := seccompProgram()
+	if len(prog) > seccompMaxInstructions {
+		return errors.New("filter too long")
+	}
+	fprog := syscall.SockFprog{Len: uint16(len(prog)), Filter: &prog[0]}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter,
+		uintptr(unsafe.Pointer(&fprog)), 0, 0, 0)
+	runtime.KeepAlive(prog)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// commonDeniedSyscalls have the same numbers on every architecture.
+var commonDeniedSyscalls = []uint32{
+	428, // open_tree
+	429, // move_mount
+	430, // fsopen
+	431, // fsconfig
+	432, // fsmount
+	433, // fspick
+	438, // pidfd_getfd
+	442, // mount_setattr
+}
+
+// sandboxAvailable reports whether this kernel can run the sandbox, and
+// which optional layers it has.
+func sandboxAvailable() (map[string]any, error) {
+	if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+		return nil, errors.New("user namespaces are not supported by this kernel")
+	}
+	if data, err := os.ReadFile("/proc/sys/user/max_user_namespaces"); err == nil &&
+		strings.TrimSpace(string(data)) == "0" {
+		return nil, errors.New("user namespaces are disabled (user.max_user_namespaces = 0)")
+	}
+	return map[string]any{
+		"landlock_abi": landlockABI(),
+		"seccomp":      len(deniedSyscalls) > 0,
+	}, nil
+}
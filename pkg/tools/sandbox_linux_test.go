@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+// newSandboxedExecTool returns an exec tool running in the sandbox, or
+// skips the test when this kernel or environment cannot create one.
+func newSandboxedExecTool(t *testing.T, cfg config.ExecSandboxConfig) (*ExecTool, string) {
+	t.Helper()
+	if err := exec.Command("unshare", "-Ur", "true").Run(); err != nil {
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+	workspace := t.TempDir()
+	tool := NewExecTool(workspace, false)
+	cfg.Enabled = true
+	tool.SetSandbox(cfg)
+	return tool, workspace
+}
+
+func runSandboxed(t *testing.T, tool *ExecTool, command string) *ToolResult {
+	t.Helper()
+	return tool.Execute(context.Background(), map[string]any{"command": command})
+}
+
+func TestExecSandbox_WorkspaceWritableRestReadOnly(t *testing.T) {
+	tool, workspace := newSandboxedExecTool(t, config.ExecSandboxConfig{})
+
+	result := runSandboxed(t, tool, "echo hello > out.txt && cat out.txt")
+	if result.IsError || !strings.Contains(result.ForLLM, "hello") {
+		t.Fatalf("workspace write failed: %s", result.ForLLM)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "out.txt")); err != nil || string(data) != "hello\n" {
+		t.Fatalf("file not visible outside the sandbox: %q, %v", data, err)
+	}
+
+	result = runSandboxed(t, tool, "touch /etc/tinyclaw-sandbox-test")
+	if !result.IsError {
+		t.Fatalf("expected write outside the workspace to fail: %s", result.ForLLM)
+	}
+	if _, err := os.Stat("/etc/tinyclaw-sandbox-test"); err == nil {
+		os.Remove("/etc/tinyclaw-sandbox-test")
+		t.Fatal("sandboxed command wrote to /etc")
+	}
+}
+
+func TestExecSandbox_OwnPIDNamespace(t *testing.T) {
+	tool, _ := newSandboxedExecTool(t, config.ExecSandboxConfig{})
+
+	result := runSandboxed(t, tool, "echo $$")
+	if result.IsError || strings.TrimSpace(result.ForLLM) != "1" {
+		t.Fatalf("expected the shell to be pid 1, got: %s", result.ForLLM)
+	}
+}
+
+func TestExecSandbox_Network(t *testing.T) {
+	tool, _ := newSandboxedExecTool(t, config.ExecSandboxConfig{})
+	result := runSandboxed(t, tool, "cat /proc/net/dev")
+	if result.IsError {
+		t.Fatalf("reading interfaces failed: %s", result.ForLLM)
+	}
+	for _, line := range strings.Split(result.ForLLM, "\n")[2:] {
+		name, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if name != "" && name != "lo" {
+			t.Fatalf("expected only loopback with network off, found %q", name)
+		}
+	}
+
+	tool, _ = newSandboxedExecTool(t, config.ExecSandboxConfig{Network: true})
+	hostIfaces, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		t.Skip(err)
+	}
+	result = runSandboxed(t, tool, "cat /proc/net/dev")
+	if result.IsError || strings.Count(result.ForLLM, ":") != strings.Count(string(hostIfaces), ":") {
+		t.Fatalf("expected host interfaces with network on, got: %s", result.ForLLM)
+	}
+}
+
+func TestExecSandbox_DeniedSyscalls(t *testing.T) {
+	if len(deniedSyscalls) == 0 {
+		t.Skip("no seccomp filter on this architecture")
+	}
+	tool, _ := newSandboxedExecTool(t, config.ExecSandboxConfig{})
+	if _, err := exec.LookPath("unshare"); err != nil {
+		t.Skip("unshare not installed")
+	}
+
+	result := runSandboxed(t, tool, "unshare -r true")
+	if !result.IsError {
+		t.Fatalf("expected unshare to be denied: %s", result.ForLLM)
+	}
+	result = runSandboxed(t, tool, "mount -t tmpfs none /mnt")
+	if !result.IsError {
+		t.Fatalf("expected mount to be denied: %s", result.ForLLM)
+	}
+}
+
+func TestExecSandbox_GuardStillApplies(t *testing.T) {
+	tool, _ := newSandboxedExecTool(t, config.ExecSandboxConfig{})
+
+	result := runSandboxed(t, tool, "sudo true")
+	if !result.IsError || !strings.Contains(result.ForLLM, "safety guard") {
+		t.Fatalf("expected the deny patterns to run first, got: %s", result.ForLLM)
+	}
+}
+
+func TestSandboxEnv_OnlyAllowedVariables(t *testing.T) {
+	t.Setenv("TINYCLAW_TEST_SECRET", "hunter2")
+	t.Setenv("TINYCLAW_TEST_EXTRA", "visible")
+	t.Setenv("PATH", "/usr/bin:/bin")
+
+	env := sandboxEnv([]string{"TINYCLAW_TEST_EXTRA", "PATH", "TINYCLAW_TEST_UNSET"})
+	joined := strings.Join(env, "\n")
+	if !strings.Contains(joined, "PATH=/usr/bin:/bin") || !strings.Contains(joined, "TINYCLAW_TEST_EXTRA=visible") {
+		t.Fatalf("allowed variables missing: %q", env)
+	}
+	if strings.Contains(joined, "hunter2") || strings.Contains(joined, "TINYCLAW_TEST_UNSET") {
+		t.Fatalf("unexpected variables passed: %q", env)
+	}
+	if strings.Count(joined, "PATH=") != 1 {
+		t.Fatalf("PATH passed twice: %q", env)
+	}
+}
+
+func TestExecSandbox_ReducedEnvironment(t *testing.T) {
+	t.Setenv("TINYCLAW_TEST_SECRET", "hunter2")
+	tool, _ := newSandboxedExecTool(t, config.ExecSandboxConfig{})
+
+	result := runSandboxed(t, tool, "env")
+	if result.IsError {
+		t.Fatalf("env failed: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "hunter2") || strings.Contains(result.ForLLM, sandboxSpecEnv) {
+		t.Fatalf("sandbox leaked the gateway environment: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+)
+
+var errSandboxUnsupported = errors.New("exec sandbox requires Linux")
+
+func sandboxCommand(context.Context, config.ExecSandboxConfig, sandboxSpec) (*exec.Cmd, func(), error) {
+	return nil, nil, errSandboxUnsupported
+}
+
+func sandboxAvailable() (map[string]any, error) {
+	return nil, errSandboxUnsupported
+}
@@ -0,0 +1,52 @@
+package tools
+
+// auditArch is AUDIT_ARCH_X86_64.
+const auditArch = 0xC000003E
+
+// x32ABIBit marks x32 syscall numbers, which the filter refuses outright.
+const x32ABIBit = 0x40000000
+
+// deniedSyscalls are refused with EPERM inside the exec sandbox: mounts and
+// namespaces, tracing other processes, kernel modules and keyrings, and
+// host-wide settings such as the clock.
+var deniedSyscalls = append([]uint32{
+	101, // ptrace
+	103, // syslog
+	134, // uselib
+	153, // vhangup
+	155, // pivot_root
+	159, // adjtimex
+	161, // chroot
+	163, // acct
+	164, // settimeofday
+	165, // mount
+	166, // umount2
+	167, // swapon
+	168, // swapoff
+	169, // reboot
+	170, // sethostname
+	171, // setdomainname
+	172, // iopl
+	173, // ioperm
+	175, // init_module
+	176, // delete_module
+	179, // quotactl
+	212, // lookup_dcookie
+	227, // clock_settime
+	246, // kexec_load
+	248, // add_key
+	249, // request_key
+	250, // keyctl
+	272, // unshare
+	298, // perf_event_open
+	303, // name_to_handle_at
+	304, // open_by_handle_at
+	305, // clock_adjtime
+	308, // setns
+	310, // process_vm_readv
+	311, // process_vm_writev
+	313, // finit_module
+	320, // kexec_file_load
+	321, // bpf
+	323, // userfaultfd
+}, commonDeniedSyscalls...)
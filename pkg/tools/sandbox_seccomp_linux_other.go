@@ -0,0 +1,12 @@
+//go:build linux && !amd64 && !arm64
+
+package tools
+
+// No seccomp filter is installed on other architectures; the namespaces,
+// read-only mounts and Landlock still apply.
+const (
+	auditArch = 0
+	x32ABIBit = 0
+)
+
+var deniedSyscalls []uint32
@@ -0,0 +1,356 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/journal"
+)
+
+const (
+	defaultGlobLimit   = 200
+	defaultGrepResults = 100
+	maxGrepContext     = 10
+	maxGrepFileSize    = 4 << 20
+)
+
+// skippedSearchDirs are never descended into by glob and grep: version
+// control metadata, dependency trees and the delete_file trash.
+var skippedSearchDirs = map[string]bool{
+	".git":          true,
+	".hg":           true,
+	".svn":          true,
+	"node_modules":  true,
+	trashDirName:    true,
+	journal.DirName: true,
+}
+
+// errSearchLimit stops a directory walk once enough results are collected.
+var errSearchLimit = errors.New("search limit reached")
+
+// matchGlob reports whether the slash-separated relative path name matches
+// pattern. "**" matches any number of directories; a pattern without a
+// slash is matched against the base name at any depth.
+func matchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// walkFiles calls fn for every regular file below fsys's root, skipping
+// the directories in skippedSearchDirs.
+func walkFiles(fsys fs.FS, fn func(name string, d fs.DirEntry) error) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable subdirectories are skipped, not fatal.
+			if name != "." && d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if name != "." && skippedSearchDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(name, d)
+	})
+}
+
+// GlobTool finds files by name pattern.
+type GlobTool struct {
+	fs fileSystem
+}
+
+func NewGlobTool(workspace string, restrict bool) *GlobTool {
+	return &GlobTool{fs: newFileSystem(workspace, restrict)}
+}
+
+func (t *GlobTool) Name() string {
+	return "glob"
+}
+
+func (t *GlobTool) Description() string {
+	return "Find files by name pattern, e.g. \"*.go\" (any depth) or \"src/**/test_*.py\". " +
+		"A pattern without a slash matches file names at any depth; ** matches any number of directories. " +
+		"Returns paths relative to the search directory, sorted."
+}
+
+func (t *GlobTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Glob pattern to match file paths against",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Directory to search (default: workspace root)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of paths to return (default %d)", defaultGlobLimit),
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GlobTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	dir, _ := args["path"].(string)
+	if dir == "" {
+		dir = "."
+	}
+	limit := intArg(args, "limit")
+	if limit == 0 {
+		limit = defaultGlobLimit
+	}
+
+	var matches []string
+	truncated := false
+	err := t.fs.WithDirFS(dir, func(fsys fs.FS) error {
+		return walkFiles(fsys, func(name string, _ fs.DirEntry) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !matchGlob(pattern, name) {
+				return nil
+			}
+			if len(matches) == limit {
+				truncated = true
+				return errSearchLimit
+			}
+			matches = append(matches, name)
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, errSearchLimit) {
+		return ErrorResult(fmt.Sprintf("glob failed: %v", err))
+	}
+
+	if len(matches) == 0 {
+		return NewToolResult("No files match " + pattern)
+	}
+	sort.Strings(matches)
+	result := strings.Join(matches, "\n")
+	if truncated {
+		result += fmt.Sprintf("\n[Showing the first %d matches; narrow the pattern or raise limit.]", limit)
+	}
+	return NewToolResult(result)
+}
+
+// GrepTool searches file contents with a regular expression.
+type GrepTool struct {
+	fs fileSystem
+}
+
+func NewGrepTool(workspace string, restrict bool) *GrepTool {
+	return &GrepTool{fs: newFileSystem(workspace, restrict)}
+}
+
+func (t *GrepTool) Name() string {
+	return "grep"
+}
+
+func (t *GrepTool) Description() string {
+	return "Search file contents with a regular expression (RE2 syntax). " +
+		"Prints matching lines as path:line:text, with optional context lines as path-line-text. " +
+		"Binary files are skipped."
+}
+
+func (t *GrepTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Regular expression to search for",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File or directory to search (default: workspace root)",
+			},
+			"include": map[string]any{
+				"type":        "string",
+				"description": "Only search files matching this glob, e.g. \"*.go\"",
+			},
+			"ignore_case": map[string]any{
+				"type":        "boolean",
+				"description": "Match case-insensitively",
+			},
+			"context": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Lines of context to show around each match (max %d)", maxGrepContext),
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of matching lines (default %d)", defaultGrepResults),
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GrepTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if ignoreCase, _ := args["ignore_case"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	target, _ := args["path"].(string)
+	if target == "" {
+		target = "."
+	}
+	include, _ := args["include"].(string)
+	g := &grepSearch{
+		re:      re,
+		context: min(intArg(args, "context"), maxGrepContext),
+		limit:   intArg(args, "max_results"),
+	}
+	if g.limit == 0 {
+		g.limit = defaultGrepResults
+	}
+
+	info, err := t.fs.Stat(target)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("grep failed: %v", err))
+	}
+	if info.IsDir() {
+		err = t.fs.WithDirFS(target, func(fsys fs.FS) error {
+			return walkFiles(fsys, func(name string, d fs.DirEntry) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if include != "" && !matchGlob(include, name) {
+					return nil
+				}
+				if fi, err := d.Info(); err != nil || fi.Size() > maxGrepFileSize {
+					return nil
+				}
+				data, err := fs.ReadFile(fsys, name)
+				if err != nil {
+					return nil
+				}
+				return g.file(name, data)
+			})
+		})
+	} else {
+		var data []byte
+		if data, err = t.fs.ReadFile(target); err == nil {
+			err = g.file(target, data)
+		}
+	}
+	if err != nil && !errors.Is(err, errSearchLimit) {
+		return ErrorResult(fmt.Sprintf("grep failed: %v", err))
+	}
+
+	if g.matches == 0 {
+		return NewToolResult("No matches for " + re.String())
+	}
+	result := strings.TrimSuffix(g.out.String(), "\n")
+	if g.truncated {
+		result += fmt.Sprintf("\n[Stopped after %d matching lines; narrow the search or raise max_results.]", g.limit)
+	}
+	return NewToolResult(result)
+}
+
+// grepSearch accumulates grep output across files.
+type grepSearch struct {
+	re        *regexp.Regexp
+	context   int
+	limit     int
+	matches   int
+	truncated bool
+	out       strings.Builder
+}
+
+// file appends the matches in one file, returning errSearchLimit once the
+// result limit is hit.
+func (g *grepSearch) file(name string, data []byte) error {
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	printed := -1 // index of the last line written for this file
+	for i, line := range lines {
+		if !g.re.MatchString(strings.TrimSuffix(line, "\r")) {
+			continue
+		}
+		if g.matches == g.limit {
+			g.truncated = true
+			return errSearchLimit
+		}
+		g.matches++
+
+		start := max(i-g.context, printed+1)
+		if g.context > 0 && (printed >= 0 && start > printed+1 || printed < 0 && g.out.Len() > 0) {
+			g.out.WriteString("--\n")
+		}
+		for j := start; j < i; j++ {
+			fmt.Fprintf(&g.out, "%s-%d-%s\n", name, j+1, strings.TrimSuffix(lines[j], "\r"))
+		}
+		fmt.Fprintf(&g.out, "%s:%d:%s\n", name, i+1, strings.TrimSuffix(line, "\r"))
+		printed = i
+
+		// Trailing context, stopping early at the next match so it is
+		// printed as a match rather than as context.
+		for j := i + 1; j <= min(i+g.context, len(lines)-1); j++ {
+			if g.re.MatchString(strings.TrimSuffix(lines[j], "\r")) {
+				break
+			}
+			fmt.Fprintf(&g.out, "%s-%d-%s\n", name, j+1, strings.TrimSuffix(lines[j], "\r"))
+			printed = j
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/tools/web.go", true},
+		{"*.go", "main.go.txt", false},
+		{"pkg/*.go", "pkg/a.go", true},
+		{"pkg/*.go", "pkg/tools/a.go", false},
+		{"pkg/**/*.go", "pkg/a.go", true},
+		{"pkg/**/*.go", "pkg/tools/deep/a.go", true},
+		{"**/test_*.py", "src/test_x.py", true},
+		{"./src/*.py", "src/x.py", true},
+		{"src/**", "src/a/b", true},
+		{"src/**", "lib/a", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGlobTool(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		"main.go":              "",
+		"pkg/util/util.go":     "",
+		"pkg/util/README.md":   "",
+		".git/hooks/x.go":      "",
+		"node_modules/m/m.go":  "",
+		".trash/1/old/file.go": "",
+	})
+	tool := NewGlobTool(workspace, true)
+
+	result := tool.Execute(context.Background(), map[string]any{"pattern": "*.go"})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if result.ForLLM != "main.go\npkg/util/util.go" {
+		t.Errorf("unexpected matches:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"pattern": "*", "path": "pkg", "limit": 1.0})
+	if !strings.HasPrefix(result.ForLLM, "util/") || !strings.Contains(result.ForLLM, "first 1 matches") {
+		t.Errorf("expected a truncated listing relative to pkg, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"pattern": "*.go", "path": "../"})
+	if !result.IsError {
+		t.Error("expected searching outside the workspace to fail")
+	}
+}
+
+func TestGrepTool(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		"a.go":    "package a\n\nfunc Alpha() {}\n\nfunc beta() {}\n",
+		"b.txt":   "nothing here\nALPHA upper\n",
+		"bin.dat": "alpha\x00binary",
+	})
+	tool := NewGrepTool(workspace, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"pattern": `func \w+`})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if result.ForLLM != "a.go:3:func Alpha() {}\na.go:5:func beta() {}" {
+		t.Errorf("unexpected output:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"pattern": "alpha", "ignore_case": true})
+	if !strings.Contains(result.ForLLM, "a.go:3:") || !strings.Contains(result.ForLLM, "b.txt:2:ALPHA upper") ||
+		strings.Contains(result.ForLLM, "bin.dat") {
+		t.Errorf("unexpected case-insensitive output:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"pattern": "Alpha", "include": "*.go", "context": 1.0})
+	want := "a.go-2-\na.go:3:func Alpha() {}\na.go-4-"
+	if result.ForLLM != want {
+		t.Errorf("context output = %q, want %q", result.ForLLM, want)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"pattern": "func", "path": "a.go", "max_results": 1.0})
+	if !strings.HasPrefix(result.ForLLM, "a.go:3:") || !strings.Contains(result.ForLLM, "Stopped after 1") {
+		t.Errorf("expected a single-file search stopped after one match, got:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"pattern": "("})
+	if !result.IsError {
+		t.Error("expected an invalid regexp to fail")
+	}
+}
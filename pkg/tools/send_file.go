@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+)
+
+// SendFileTool delivers a workspace file or remote URL as a chat attachment.
+// It shares the message tool's delivery callback and default target.
+type SendFileTool struct {
+	message   *MessageTool
+	workspace string
+	restrict  bool
+}
+
+func NewSendFileTool(message *MessageTool, workspace string, restrict bool) *SendFileTool {
+	return &SendFileTool{
+		message:   message,
+		workspace: workspace,
+		restrict:  restrict,
+	}
+}
+
+func (t *SendFileTool) Name() string {
+	return "send_file"
+}
+
+func (t *SendFileTool) Description() string {
+	return "Send a file to the user as an attachment (image, video, audio or document). Give either a workspace path or an http(s) URL."
+}
+
+func (t *SendFileTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path of the file to send, relative to the workspace",
+			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "URL of a remote file to send instead of a local path",
+			},
+			"caption": map[string]any{
+				"type":        "string",
+				"description": "Optional: caption shown with the file",
+			},
+			"channel": map[string]any{
+				"type":        "string",
+				"description": "Optional: target channel (telegram, slack, etc.)",
+			},
+			"chat_id": map[string]any{
+				"type":        "string",
+				"description": "Optional: target chat/user ID",
+			},
+			"silent": map[string]any{
+				"type":        "boolean",
+				"description": "Optional: deliver without a notification sound",
+			},
+		},
+	}
+}
+
+func (t *SendFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, _ := args["path"].(string)
+	url, _ := args["url"].(string)
+	caption, _ := args["caption"].(string)
+
+	var attachment bus.Attachment
+	switch {
+	case path != "" && url != "":
+		return ErrorResult("give either path or url, not both")
+	case path != "":
+		resolved, err := validatePath(path, t.workspace, t.restrict)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to stat file: %v", err))
+		}
+		if info.IsDir() {
+			return ErrorResult("path is a directory: " + path)
+		}
+		attachment.Path = resolved
+	case url != "":
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return ErrorResult("url must start with http:// or https://")
+		}
+		attachment.URL = url
+	default:
+		return ErrorResult("path or url is required")
+	}
+	attachment.Caption = caption
+
+	msg := bus.OutboundMessage{Media: []bus.Attachment{attachment}}
+	msg.Silent, _ = args["silent"].(bool)
+
+	channel, _ := args["channel"].(string)
+	chatID, _ := args["chat_id"].(string)
+	target, errResult := t.message.send(channel, chatID, msg)
+	if errResult != nil {
+		return errResult
+	}
+	return SilentResult(fmt.Sprintf("File %s sent to %s", attachment.Name(), target))
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/bus"
+)
+
+func newSendFileTestTool(t *testing.T, workspace string) (*SendFileTool, *MessageTool, *[]bus.OutboundMessage) {
+	t.Helper()
+	var sent []bus.OutboundMessage
+	mt := NewMessageTool()
+	mt.SetContext("telegram", "123")
+	mt.SetOutboundCallback(func(msg bus.OutboundMessage) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	return NewSendFileTool(mt, workspace, true), mt, &sent
+}
+
+func TestSendFileTool_WorkspacePath(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "out"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "out", "chart.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool, mt, sent := newSendFileTestTool(t, workspace)
+	result := tool.Execute(context.Background(), map[string]any{
+		"path":    "out/chart.png",
+		"caption": "Weekly chart",
+		"silent":  true,
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if !result.Silent {
+		t.Error("expected silent result")
+	}
+	if !strings.Contains(result.ForLLM, "chart.png") || !strings.Contains(result.ForLLM, "telegram:123") {
+		t.Errorf("ForLLM = %q", result.ForLLM)
+	}
+	if !mt.HasSentInRound() {
+		t.Error("send_file should count as a message sent this round")
+	}
+
+	if len(*sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(*sent))
+	}
+	msg := (*sent)[0]
+	if len(msg.Media) != 1 || !msg.Silent {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	a := msg.Media[0]
+	if a.Path != filepath.Join(workspace, "out", "chart.png") || a.Caption != "Weekly chart" {
+		t.Errorf("attachment = %+v", a)
+	}
+}
+
+func TestSendFileTool_URL(t *testing.T) {
+	tool, _, sent := newSendFileTestTool(t, t.TempDir())
+	result := tool.Execute(context.Background(), map[string]any{
+		"url":     "https://example.com/report.pdf",
+		"channel": "slack",
+		"chat_id": "C1",
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	msg := (*sent)[0]
+	if msg.Channel != "slack" || msg.ChatID != "C1" || msg.Media[0].URL != "https://example.com/report.pdf" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestSendFileTool_Errors(t *testing.T) {
+	workspace := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]any
+	}{
+		{"no source", map[string]any{}},
+		{"both sources", map[string]any{"path": "a.txt", "url": "https://example.com/a.txt"}},
+		{"missing file", map[string]any{"path": "missing.txt"}},
+		{"directory", map[string]any{"path": "."}},
+		{"outside workspace", map[string]any{"path": outside}},
+		{"bad scheme", map[string]any{"url": "file:///etc/passwd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool, _, sent := newSendFileTestTool(t, workspace)
+			if result := tool.Execute(context.Background(), tt.args); !result.IsError {
+				t.Errorf("expected error, got %q", result.ForLLM)
+			}
+			if len(*sent) != 0 {
+				t.Error("nothing should be sent on error")
+			}
+		})
+	}
+}
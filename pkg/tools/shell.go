@@ -22,6 +22,8 @@ type ExecTool struct {
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
 	restrictToWorkspace bool
+	sandbox             *config.ExecSandboxConfig
+	processes           *ProcessManager
 }
 
 var defaultDenyPatterns = []*regexp.Regexp{
@@ -101,13 +103,17 @@ func NewExecToolWithConfig(workingDir string, restrict bool, config *config.Conf
 		denyPatterns = append(denyPatterns, defaultDenyPatterns...)
 	}
 
-	return &ExecTool{
+	t := &ExecTool{
 		workingDir:          workingDir,
 		timeout:             60 * time.Second,
 		denyPatterns:        denyPatterns,
 		allowPatterns:       nil,
 		restrictToWorkspace: restrict,
 	}
+	if config != nil {
+		t.SetSandbox(config.Tools.Exec.Sandbox)
+	}
+	return t
 }
 
 func (t *ExecTool) Name() string {
@@ -115,7 +121,8 @@ func (t *ExecTool) Name() string {
 }
 
 func (t *ExecTool) Description() string {
-	return "Execute a shell command and return its output. Use with caution."
+	return "Execute a shell command and return its output. Use with caution. " +
+		"Set background=true for long-running commands, then use the process tool."
 }
 
 func (t *ExecTool) Parameters() map[string]any {
@@ -130,6 +137,11 @@ func (t *ExecTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional working directory for the command",
 			},
+			"background": map[string]any{
+				"type": "boolean",
+				"description": "Run detached and return a process handle instead of waiting, " +
+					"for servers, watchers, long builds and interactive programs",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -166,6 +178,10 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 		return ErrorResult(guardError)
 	}
 
+	if background, _ := args["background"].(bool); background {
+		return t.startBackground(ctx, command, cwd)
+	}
+
 	// timeout == 0 means no timeout
 	var cmdCtx context.Context
 	var cancel context.CancelFunc
@@ -176,15 +192,11 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	}
 	defer cancel()
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
-	} else {
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
-	}
-	if cwd != "" {
-		cmd.Dir = cwd
+	cmd, cleanup, err := t.buildCommand(cmdCtx, command, cwd)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to start command: %v", err))
 	}
+	defer cleanup()
 
 	prepareCommandForTermination(cmd)
 
@@ -201,7 +213,6 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 		done <- cmd.Wait()
 	}()
 
-	var err error
 	select {
 	case err = <-done:
 	case <-cmdCtx.Done():
@@ -257,6 +268,58 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	}
 }
 
+// buildCommand returns the command running command in cwd, inside the
+// sandbox when enabled, and a cleanup function to call after it exits.
+func (t *ExecTool) buildCommand(ctx context.Context, command, cwd string) (*exec.Cmd, func(), error) {
+	var cmd *exec.Cmd
+	cleanup := func() {}
+	switch {
+	case t.sandbox != nil:
+		spec := newSandboxSpec(*t.sandbox, t.workingDir, command, cwd)
+		var err error
+		cmd, cleanup, err = sandboxCommand(ctx, *t.sandbox, spec)
+		if err != nil {
+			return nil, nil, err
+		}
+	case runtime.GOOS == "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+	default:
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	if cwd != "" {
+		cmd.Dir = cwd
+	}
+	return cmd, cleanup, nil
+}
+
+// startBackground starts command detached for the session of ctx and
+// returns its handle for the process tool.
+func (t *ExecTool) startBackground(ctx context.Context, command, cwd string) *ToolResult {
+	if t.processes == nil {
+		return ErrorResult("background processes are not available here")
+	}
+	procCtx, cancel := t.processes.processContext()
+	cmd, cleanup, err := t.buildCommand(procCtx, command, cwd)
+	if err != nil {
+		cancel()
+		return ErrorResult(fmt.Sprintf("failed to start command: %v", err))
+	}
+	p, err := t.processes.Start(sessionKeyFrom(ctx), command, cmd, cancel, cleanup)
+	if err != nil {
+		cancel()
+		cleanup()
+		return ErrorResult(fmt.Sprintf("failed to start command: %v", err))
+	}
+	msg := fmt.Sprintf("Started background process %s (pid %d). "+
+		"Use the process tool to poll its output, write to stdin, signal or kill it.", p.id, cmd.Process.Pid)
+	return &ToolResult{ForLLM: msg, ForUser: msg}
+}
+
+// SetProcessManager enables background=true, with processes tracked by m.
+func (t *ExecTool) SetProcessManager(m *ProcessManager) {
+	t.processes = m
+}
+
 //nolint:gocognit,nestif // command guard: nested checks for dangerous patterns and workspace boundaries
 func (t *ExecTool) guardCommand(command, cwd string) string {
 	cmd := strings.TrimSpace(command)
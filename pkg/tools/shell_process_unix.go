@@ -3,15 +3,32 @@
 package tools
 
 import (
+	"os"
 	"os/exec"
 	"syscall"
 )
 
+// processSignals are the signals the process tool can send.
+var processSignals = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+}
+
 func prepareCommandForTermination(cmd *exec.Cmd) {
 	if cmd == nil {
 		return
 	}
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
 }
 
 func terminateProcessTree(cmd *exec.Cmd) error {
@@ -30,3 +47,11 @@ func terminateProcessTree(cmd *exec.Cmd) error {
 	_ = cmd.Process.Kill()
 	return nil
 }
+
+// signalProcessTree sends sig to the command's whole process group.
+func signalProcessTree(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig.(syscall.Signal))
+}
@@ -3,10 +3,18 @@
 package tools
 
 import (
+	"os"
 	"os/exec"
 	"strconv"
 )
 
+// processSignals are the signals the process tool can send. Windows can
+// only kill.
+var processSignals = map[string]os.Signal{
+	"KILL": os.Kill,
+	"TERM": os.Kill,
+}
+
 func prepareCommandForTermination(cmd *exec.Cmd) {
 	// no-op on Windows
 }
@@ -25,3 +33,9 @@ func terminateProcessTree(cmd *exec.Cmd) error {
 	_ = cmd.Process.Kill()
 	return nil
 }
+
+// signalProcessTree kills the command's process tree; other signals are
+// not supported on Windows.
+func signalProcessTree(cmd *exec.Cmd, _ os.Signal) error {
+	return terminateProcessTree(cmd)
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/skills"
+)
+
+// LoadSkillTool loads a skill's instructions into the conversation on
+// demand, so the system prompt only needs the short skill summaries.
+type LoadSkillTool struct {
+	loader *skills.SkillsLoader
+}
+
+// NewLoadSkillTool creates a LoadSkillTool over the agent's skills loader,
+// whose filter decides which skills can be loaded.
+func NewLoadSkillTool(loader *skills.SkillsLoader) *LoadSkillTool {
+	return &LoadSkillTool{loader: loader}
+}
+
+func (t *LoadSkillTool) Name() string {
+	return "load_skill"
+}
+
+func (t *LoadSkillTool) Description() string {
+	return "Load the full instructions of one of your skills by name. Call this before using a skill listed in the system prompt."
+}
+
+func (t *LoadSkillTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "The skill name, as listed under Skills in the system prompt",
+			},
+		},
+		"required": []string{"name"},
+	}
+}
+
+func (t *LoadSkillTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	name, _ := args["name"].(string)
+	if name == "" {
+		return ErrorResult("name is required")
+	}
+
+	info, content, err := t.loader.ReadSkill(name)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	return SilentResult(fmt.Sprintf(
+		"### Skill: %s\n\nFiles this skill refers to are relative to %s\n\n%s",
+		info.Name, filepath.Dir(info.Path), content,
+	))
+}
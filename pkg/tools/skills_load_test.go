@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/skills"
+)
+
+func newTestLoadSkillTool(t *testing.T, filter ...string) (*LoadSkillTool, string) {
+	t.Helper()
+	workspace := t.TempDir()
+	for _, name := range []string{"github", "weather"} {
+		dir := filepath.Join(workspace, "skills", name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		content := "---\nname: " + name + "\ndescription: The " + name + " skill\n---\n\nUse scripts/" + name + ".sh"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o644))
+	}
+	loader := skills.NewSkillsLoader(workspace, "", "")
+	loader.SetFilter(filter)
+	return NewLoadSkillTool(loader), workspace
+}
+
+func TestLoadSkillTool_Loads(t *testing.T) {
+	tool, workspace := newTestLoadSkillTool(t)
+	assert.Equal(t, "load_skill", tool.Name())
+
+	result := tool.Execute(context.Background(), map[string]any{"name": "weather"})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForLLM, "### Skill: weather")
+	assert.Contains(t, result.ForLLM, filepath.Join(workspace, "skills", "weather"))
+	assert.Contains(t, result.ForLLM, "Use scripts/weather.sh")
+	assert.NotContains(t, result.ForLLM, "description:")
+}
+
+func TestLoadSkillTool_Errors(t *testing.T) {
+	tool, _ := newTestLoadSkillTool(t, "!weather")
+
+	result := tool.Execute(context.Background(), map[string]any{})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "name is required")
+
+	result = tool.Execute(context.Background(), map[string]any{"name": "weather"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, `no skill named "weather"; available skills: github`)
+}
@@ -3,17 +3,40 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"path/filepath"
 	"regexp"
-	"runtime"
+	"strings"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/config"
+	"github.com/tinyland-inc/tinyclaw/pkg/hardware"
 )
 
 // SPITool provides SPI bus interaction for high-speed peripheral communication.
-type SPITool struct{}
+type SPITool struct {
+	devices []config.HardwareDeviceConfig
+	open    func(dev string, cfg hardware.SPIConfig) (hardware.SPI, error)
+}
+
+// SPIToolOptions configures an SPITool. Devices are the configured
+// peripherals addressable by name; Open defaults to hardware.OpenSPI and
+// can be replaced with a simulated device.
+type SPIToolOptions struct {
+	Devices []config.HardwareDeviceConfig
+	Open    func(dev string, cfg hardware.SPIConfig) (hardware.SPI, error)
+}
 
 func NewSPITool() *SPITool {
-	return &SPITool{}
+	return NewSPIToolWithOptions(SPIToolOptions{})
+}
+
+func NewSPIToolWithOptions(opts SPIToolOptions) *SPITool {
+	if opts.Open == nil {
+		opts.Open = hardware.OpenSPI
+	}
+	return &SPITool{devices: opts.Devices, open: opts.Open}
 }
 
 func (t *SPITool) Name() string {
@@ -21,57 +44,65 @@ func (t *SPITool) Name() string {
 }
 
 func (t *SPITool) Description() string {
-	return "Interact with SPI bus devices for high-speed peripheral communication. Actions: list (find SPI devices), transfer (full-duplex send/receive), read (receive bytes). Linux only."
+	desc := "Interact with SPI bus devices for high-speed peripheral communication. Actions: list (find SPI devices), profiles (list driver profiles usable over SPI), read_sensor (converted readings through a profile), set_output (drive a profile output), transfer (full-duplex send/receive), read (receive bytes). Prefer read_sensor/set_output over raw access when a profile fits. Linux only."
+	names := make([]string, 0, len(t.devices))
+	for _, d := range t.devices {
+		if hardwareInterface(&d) == "spi" {
+			names = append(names, fmt.Sprintf("%s (%s)", d.Name, d.Profile))
+		}
+	}
+	if len(names) > 0 {
+		desc += " Configured devices: " + strings.Join(names, ", ") + "."
+	}
+	return desc
 }
 
 func (t *SPITool) Parameters() map[string]any {
-	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"action": map[string]any{
-				"type":        "string",
-				"enum":        []string{"list", "transfer", "read"},
-				"description": "Action to perform: list (find available SPI devices), transfer (full-duplex send/receive), read (receive bytes by sending zeros)",
-			},
-			"device": map[string]any{
-				"type":        "string",
-				"description": "SPI device identifier (e.g. \"2.0\" for /dev/spidev2.0). Required for transfer/read.",
-			},
-			"speed": map[string]any{
-				"type":        "integer",
-				"description": "SPI clock speed in Hz. Default: 1000000 (1 MHz).",
-			},
-			"mode": map[string]any{
-				"type":        "integer",
-				"description": "SPI mode (0-3). Default: 0. Mode sets CPOL and CPHA: 0=0,0 1=0,1 2=1,0 3=1,1.",
-			},
-			"bits": map[string]any{
-				"type":        "integer",
-				"description": "Bits per word. Default: 8.",
-			},
-			"data": map[string]any{
-				"type":        "array",
-				"items":       map[string]any{"type": "integer"},
-				"description": "Bytes to send (0-255 each). Required for transfer action.",
-			},
-			"length": map[string]any{
-				"type":        "integer",
-				"description": "Number of bytes to read (1-4096). Required for read action.",
-			},
-			"confirm": map[string]any{
-				"type":        "boolean",
-				"description": "Must be true for transfer operations. Safety guard to prevent accidental writes.",
-			},
+	props := map[string]any{
+		"action": map[string]any{
+			"type":        "string",
+			"enum":        []string{"list", "profiles", "read_sensor", "set_output", "transfer", "read"},
+			"description": "Action to perform: list (find available SPI devices), profiles (list driver profiles), read_sensor (take a converted reading), set_output (set a profile output), transfer (full-duplex send/receive), read (receive bytes by sending zeros)",
+		},
+		"device": map[string]any{
+			"type":        "string",
+			"description": "SPI device identifier (e.g. \"2.0\" for /dev/spidev2.0). Required for transfer/read, and for read_sensor/set_output without name.",
+		},
+		"speed": map[string]any{
+			"type":        "integer",
+			"description": "SPI clock speed in Hz. Default: 1000000 (1 MHz).",
+		},
+		"mode": map[string]any{
+			"type":        "integer",
+			"description": "SPI mode (0-3). Default: 0. Mode sets CPOL and CPHA: 0=0,0 1=0,1 2=1,0 3=1,1.",
+		},
+		"bits": map[string]any{
+			"type":        "integer",
+			"description": "Bits per word. Default: 8.",
 		},
-		"required": []string{"action"},
+		"data": map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "integer"},
+			"description": "Bytes to send (0-255 each). Required for transfer action.",
+		},
+		"length": map[string]any{
+			"type":        "integer",
+			"description": "Number of bytes to read (1-4096). Required for read action.",
+		},
+		"confirm": map[string]any{
+			"type":        "boolean",
+			"description": "Must be true for transfer and set_output. Safety guard to prevent accidental writes.",
+		},
+	}
+	profileParameters(props)
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"action"},
 	}
 }
 
 func (t *SPITool) Execute(ctx context.Context, args map[string]any) *ToolResult {
-	if runtime.GOOS != "linux" {
-		return ErrorResult("SPI is only supported on Linux. This tool requires /dev/spidev* device files.")
-	}
-
 	action, ok := args["action"].(string)
 	if !ok {
 		return ErrorResult("action is required")
@@ -80,12 +111,17 @@ func (t *SPITool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	switch action {
 	case "list":
 		return t.list()
+	case "profiles":
+		return listProfiles(true)
+	case "read_sensor", "set_output":
+		return t.profileAction(action, args)
 	case "transfer":
 		return t.transfer(args)
 	case "read":
 		return t.readDevice(args)
 	default:
-		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: list, transfer, read)", action))
+		return ErrorResult(fmt.Sprintf(
+			"unknown action: %s (valid: list, profiles, read_sensor, set_output, transfer, read)", action))
 	}
 }
 
@@ -122,11 +158,179 @@ func (t *SPITool) list() *ToolResult {
 	return SilentResult(fmt.Sprintf("Found %d SPI device(s):\n%s", len(devices), string(result)))
 }
 
-// Helper function for SPI operations (used by platform-specific implementations)
+// openDevice opens an SPI device, turning errors into tool results.
+func (t *SPITool) openDevice(dev string, cfg hardware.SPIConfig) (hardware.SPI, *ToolResult) {
+	s, err := t.open(dev, cfg)
+	if errors.Is(err, hardware.ErrUnsupported) {
+		return nil, ErrorResult("SPI is only supported on Linux. This tool requires /dev/spidev* device files.")
+	}
+	if err != nil {
+		return nil, ErrorResult(err.Error())
+	}
+	return s, nil
+}
+
+// transfer performs a full-duplex transfer
+func (t *SPITool) transfer(args map[string]any) *ToolResult {
+	confirm, _ := args["confirm"].(bool)
+	if !confirm {
+		return ErrorResult(
+			"transfer operations require confirm: true. Please confirm with the user before sending data to SPI devices.",
+		)
+	}
+
+	dev, speed, mode, bits, errMsg := parseSPIArgs(args)
+	if errMsg != "" {
+		return ErrorResult(errMsg)
+	}
+
+	dataRaw, ok := args["data"].([]any)
+	if !ok || len(dataRaw) == 0 {
+		return ErrorResult("data is required for transfer (array of byte values 0-255)")
+	}
+	if len(dataRaw) > 4096 {
+		return ErrorResult("data too long: maximum 4096 bytes per SPI transfer")
+	}
+
+	txBuf := make([]byte, len(dataRaw))
+	for i, v := range dataRaw {
+		f, ok := v.(float64)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("data[%d] is not a valid byte value", i))
+		}
+		b := int(f)
+		if b < 0 || b > 255 {
+			return ErrorResult(fmt.Sprintf("data[%d] = %d is out of byte range (0-255)", i, b))
+		}
+		txBuf[i] = byte(b)
+	}
+
+	s, errResult := t.openDevice(dev, hardware.SPIConfig{Speed: speed, Mode: mode, Bits: bits})
+	if errResult != nil {
+		return errResult
+	}
+	defer s.Close()
+
+	rxBuf := make([]byte, len(txBuf))
+	if err := s.Tx(txBuf, rxBuf); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	// Format received bytes
+	hexBytes := make([]string, len(rxBuf))
+	intBytes := make([]int, len(rxBuf))
+	for i, b := range rxBuf {
+		hexBytes[i] = fmt.Sprintf("0x%02x", b)
+		intBytes[i] = int(b)
+	}
+
+	result, err := json.MarshalIndent(map[string]any{
+		"device":   "/dev/spidev" + dev,
+		"sent":     len(txBuf),
+		"received": intBytes,
+		"hex":      hexBytes,
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal transfer results: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+// readDevice reads bytes from SPI by sending zeros (read-only, no confirm needed)
+func (t *SPITool) readDevice(args map[string]any) *ToolResult {
+	dev, speed, mode, bits, errMsg := parseSPIArgs(args)
+	if errMsg != "" {
+		return ErrorResult(errMsg)
+	}
+
+	length := 0
+	if l, ok := args["length"].(float64); ok {
+		length = int(l)
+	}
+	if length < 1 || length > 4096 {
+		return ErrorResult("length is required for read (1-4096)")
+	}
+
+	s, errResult := t.openDevice(dev, hardware.SPIConfig{Speed: speed, Mode: mode, Bits: bits})
+	if errResult != nil {
+		return errResult
+	}
+	defer s.Close()
+
+	txBuf := make([]byte, length) // zeros
+	rxBuf := make([]byte, length)
+	if err := s.Tx(txBuf, rxBuf); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	hexBytes := make([]string, len(rxBuf))
+	intBytes := make([]int, len(rxBuf))
+	for i, b := range rxBuf {
+		hexBytes[i] = fmt.Sprintf("0x%02x", b)
+		intBytes[i] = int(b)
+	}
+
+	result, err := json.MarshalIndent(map[string]any{
+		"device": "/dev/spidev" + dev,
+		"bytes":  intBytes,
+		"hex":    hexBytes,
+		"length": len(rxBuf),
+	}, "", "  ")
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to marshal read results: %v", err))
+	}
+	return SilentResult(string(result))
+}
+
+// profileAction runs read_sensor or set_output on a configured device or
+// on a device given by identifier and profile.
+func (t *SPITool) profileAction(action string, args map[string]any) *ToolResult {
+	var (
+		profile    string
+		configured map[string]float64
+	)
+	if name, _ := args["name"].(string); name != "" {
+		dev, errResult := findHardwareDevice(t.devices, name, "spi")
+		if errResult != nil {
+			return errResult
+		}
+		args = maps.Clone(args)
+		args["device"] = dev.Bus
+		profile, configured = dev.Profile, dev.Options
+	} else if profile, _ = args["profile"].(string); profile == "" {
+		return ErrorResult("profile is required for read_sensor/set_output without name")
+	}
+
+	dev, speed, mode, bits, errMsg := parseSPIArgs(args)
+	if errMsg != "" {
+		return ErrorResult(errMsg)
+	}
+	p, errResult := lookupProfile(profile)
+	if errResult != nil {
+		return errResult
+	}
+	if !p.SPI {
+		return ErrorResult(fmt.Sprintf("profile %s does not support SPI", p.Name))
+	}
+	opts, errResult := profileOptions(configured, args)
+	if errResult != nil {
+		return errResult
+	}
+
+	s, errResult := t.openDevice(dev, hardware.SPIConfig{Speed: speed, Mode: mode, Bits: bits})
+	if errResult != nil {
+		return errResult
+	}
+	defer s.Close()
+
+	target := "/dev/spidev" + dev
+	if action == "set_output" {
+		return setOutput(target, p, hardware.NewSPIDevice(s), args, opts)
+	}
+	return readSensor(target, p, hardware.NewSPIDevice(s), opts)
+}
 
 // parseSPIArgs extracts and validates common SPI parameters
-//
-//nolint:unused // Used by spi_linux.go
 func parseSPIArgs(args map[string]any) (device string, speed uint32, mode uint8, bits uint8, errMsg string) {
 	dev, ok := args["device"].(string)
 	if !ok || dev == "" {
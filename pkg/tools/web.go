@@ -7,11 +7,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/tinyland-inc/tinyclaw/pkg/utils"
 )
 
 const (
@@ -503,27 +507,52 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]any) *ToolR
 	}
 }
 
+// Default web_fetch limits.
+const (
+	defaultFetchMaxChars = 50000
+	defaultFetchMaxBytes = 5 << 20
+)
+
 type WebFetchTool struct {
-	maxChars int
-	proxy    string
+	maxChars     int
+	maxBytes     int64
+	proxy        string
+	allowPrivate bool
+	cache        *fetchCache
+}
+
+// WebFetchToolOptions configures a WebFetchTool. A zero CacheTTL or empty
+// CacheDir disables the cache.
+type WebFetchToolOptions struct {
+	MaxChars             int
+	MaxBytes             int64
+	Proxy                string
+	AllowPrivateNetworks bool
+	CacheDir             string
+	CacheTTL             time.Duration
 }
 
 func NewWebFetchTool(maxChars int) *WebFetchTool {
-	if maxChars <= 0 {
-		maxChars = 50000
-	}
-	return &WebFetchTool{
-		maxChars: maxChars,
-	}
+	return NewWebFetchToolWithOptions(WebFetchToolOptions{MaxChars: maxChars})
 }
 
 func NewWebFetchToolWithProxy(maxChars int, proxy string) *WebFetchTool {
-	if maxChars <= 0 {
-		maxChars = 50000
+	return NewWebFetchToolWithOptions(WebFetchToolOptions{MaxChars: maxChars, Proxy: proxy})
+}
+
+func NewWebFetchToolWithOptions(opts WebFetchToolOptions) *WebFetchTool {
+	if opts.MaxChars <= 0 {
+		opts.MaxChars = defaultFetchMaxChars
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultFetchMaxBytes
 	}
 	return &WebFetchTool{
-		maxChars: maxChars,
-		proxy:    proxy,
+		maxChars:     opts.MaxChars,
+		maxBytes:     opts.MaxBytes,
+		proxy:        opts.Proxy,
+		allowPrivate: opts.AllowPrivateNetworks,
+		cache:        newFetchCache(opts.CacheDir, opts.CacheTTL),
 	}
 }
 
@@ -532,7 +561,10 @@ func (t *WebFetchTool) Name() string {
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
+	return "Fetch a URL and return its main content as Markdown with links kept (HTML pages), " +
+		"or the text of JSON, plain-text and PDF documents. Long content is paged: " +
+		"call again with the returned offset to read more. " +
+		"Use this to get weather info, news, articles, or any web content."
 }
 
 func (t *WebFetchTool) Parameters() map[string]any {
@@ -543,9 +575,14 @@ func (t *WebFetchTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "URL to fetch",
 			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Character offset to start reading from, for the next page of long content",
+				"minimum":     0.0,
+			},
 			"maxChars": map[string]any{
 				"type":        "integer",
-				"description": "Maximum characters to extract",
+				"description": "Maximum characters to return",
 				"minimum":     100.0,
 			},
 		},
@@ -553,7 +590,6 @@ func (t *WebFetchTool) Parameters() map[string]any {
 	}
 }
 
-//nolint:funlen,gocognit,gocyclo // web fetch execute: URL validation, fetch, content extraction, and format selection
 func (t *WebFetchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	urlStr, ok := args["url"].(string)
 	if !ok {
@@ -579,121 +615,175 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 			maxChars = int(mc)
 		}
 	}
+	offset := intArg(args, "offset")
+
+	// Key the cache by the normalized URL, which is what fetch records.
+	page, cached := t.cache.get(parsedURL.String())
+	if !cached {
+		page, err = t.fetch(ctx, parsedURL)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		if page.Status >= 200 && page.Status < 300 {
+			t.cache.put(page)
+		}
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	runes := []rune(page.Text)
+	start := min(offset, len(runes))
+	end := min(start+maxChars, len(runes))
+	text := string(runes[start:end])
+	truncated := end < len(runes)
+
+	var llm strings.Builder
+	fmt.Fprintf(&llm, "URL: %s\n", page.FinalURL)
+	if page.Title != "" {
+		fmt.Fprintf(&llm, "Title: %s\n", page.Title)
+	}
+	fmt.Fprintf(&llm, "Status: %d, content type %s, extractor %s, characters %d-%d of %d\n",
+		page.Status, page.ContentType, page.Extractor, start, end, len(runes))
+	if page.Capped {
+		fmt.Fprintf(&llm, "Note: the response exceeded %d bytes and was cut off.\n", t.maxBytes)
+	}
+	llm.WriteString("\n")
+	llm.WriteString(text)
+	if truncated {
+		fmt.Fprintf(&llm, "\n\n[Content continues: call web_fetch with offset=%d for more.]", end)
+	}
+
+	result := map[string]any{
+		"url":       urlStr,
+		"final_url": page.FinalURL,
+		"status":    page.Status,
+		"title":     page.Title,
+		"extractor": page.Extractor,
+		"offset":    start,
+		"truncated": truncated,
+		"length":    len(text),
+		"total":     len(runes),
+		"text":      text,
+	}
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to create request: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to marshal fetch result: %v", err))
+	}
+
+	return &ToolResult{
+		ForLLM:  llm.String(),
+		ForUser: string(resultJSON),
+	}
+}
+
+// fetch downloads u, refusing non-public addresses unless allowed, and
+// extracts its text.
+//
+//nolint:funlen // request setup, guarded redirects, capped read and extraction
+func (t *WebFetchTool) fetch(ctx context.Context, u *url.URL) (*fetchedPage, error) {
+	if !t.allowPrivate {
+		if err := utils.CheckPublicHost(ctx, u.Hostname()); err != nil {
+			return nil, err
+		}
 	}
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf,text/plain;q=0.9,*/*;q=0.8")
 
 	client, err := createHTTPClient(t.proxy, 60*time.Second)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to create HTTP client: %v", err))
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	if transport, ok := client.Transport.(*http.Transport); ok && !t.allowPrivate {
+		// Through a proxy the dialed address is the proxy's, so only the
+		// resolution check applies.
+		if proxyURL, _ := transport.Proxy(req); proxyURL == nil {
+			dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: utils.PublicOnlyControl}
+			transport.DialContext = dialer.DialContext
+		}
 	}
-
-	// Configure redirect handling
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		if len(via) >= 5 {
 			return errors.New("stopped after 5 redirects")
 		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+		}
+		if !t.allowPrivate {
+			return utils.CheckPublicHost(req.Context(), req.URL.Hostname())
+		}
 		return nil
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("request failed: %v", err))
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBytes+1))
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	page := &fetchedPage{
+		URL:       u.String(),
+		FinalURL:  resp.Request.URL.String(),
+		Status:    resp.StatusCode,
+		FetchedAt: time.Now(),
+	}
+	if int64(len(body)) > t.maxBytes {
+		body = body[:t.maxBytes]
+		page.Capped = true
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-
-	var text, extractor string
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	page.ContentType = mediaType
 
 	switch {
-	case strings.Contains(contentType, "application/json"):
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		page.Extractor = "raw"
+		page.Text = string(body)
 		var jsonData any
-		if unmarshalErr := json.Unmarshal(body, &jsonData); unmarshalErr == nil {
-			formatted, merr := json.MarshalIndent(jsonData, "", "  ")
-			if merr != nil {
-				text = string(body)
-				extractor = "raw"
-			} else {
-				text = string(formatted)
-				extractor = "json"
+		if json.Unmarshal(body, &jsonData) == nil {
+			if formatted, err := json.MarshalIndent(jsonData, "", "  "); err == nil {
+				page.Text = string(formatted)
+				page.Extractor = "json"
 			}
-		} else {
-			text = string(body)
-			extractor = "raw"
 		}
-	case strings.Contains(contentType, "text/html") || len(body) > 0 &&
-		(strings.HasPrefix(string(body), "<!DOCTYPE") || strings.HasPrefix(strings.ToLower(string(body)), "<html")):
-		text = t.extractText(string(body))
-		extractor = "text"
+	case mediaType == "application/pdf" || bytes.HasPrefix(body, []byte("%PDF-")):
+		text, err := extractPDFText(body)
+		if err != nil {
+			return nil, err
+		}
+		page.Text = text
+		page.Extractor = "pdf"
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" || looksLikeHTML(body):
+		readable := extractReadable(string(body), resp.Request.URL)
+		page.Title = readable.Title
+		page.Text = readable.Markdown
+		page.Extractor = "readability"
+	case strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/xml" || mediaType == "application/javascript":
+		page.Text = strings.ToValidUTF8(string(body), "\uFFFD")
+		page.Extractor = "raw"
 	default:
-		text = string(body)
-		extractor = "raw"
-	}
-
-	truncated := len(text) > maxChars
-	if truncated {
-		text = text[:maxChars]
-	}
-
-	result := map[string]any{
-		"url":       urlStr,
-		"status":    resp.StatusCode,
-		"extractor": extractor,
-		"truncated": truncated,
-		"length":    len(text),
-		"text":      text,
-	}
-
-	resultJSON, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to marshal fetch result: %v", err))
+		return nil, fmt.Errorf("unsupported content type %q (%d bytes)", mediaType, len(body))
 	}
+	return page, nil
+}
 
-	return &ToolResult{
-		ForLLM: fmt.Sprintf(
-			"Fetched %d bytes from %s (extractor: %s, truncated: %v)",
-			len(text),
-			urlStr,
-			extractor,
-			truncated,
-		),
-		ForUser: string(resultJSON),
-	}
+func looksLikeHTML(body []byte) bool {
+	head := strings.ToLower(strings.TrimSpace(string(body[:min(len(body), 512)])))
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html")
 }
 
+// extractText renders an HTML document's main content as Markdown.
 func (t *WebFetchTool) extractText(htmlContent string) string {
-	re := regexp.MustCompile(`<script[\s\S]*?</script>`)
-	result := re.ReplaceAllLiteralString(htmlContent, "")
-	re = regexp.MustCompile(`<style[\s\S]*?</style>`)
-	result = re.ReplaceAllLiteralString(result, "")
-	re = regexp.MustCompile(`<[^>]+>`)
-	result = re.ReplaceAllLiteralString(result, "")
-
-	result = strings.TrimSpace(result)
-
-	re = regexp.MustCompile(`[^\S\n]+`)
-	result = re.ReplaceAllString(result, " ")
-	re = regexp.MustCompile(`\n{3,}`)
-	result = re.ReplaceAllString(result, "\n\n")
-
-	lines := strings.Split(result, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			cleanLines = append(cleanLines, line)
-		}
-	}
-
-	return strings.Join(cleanLines, "\n")
+	return extractReadable(htmlContent, nil).Markdown
 }
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fetchedPage is the extracted result of one web_fetch, as cached on disk.
+type fetchedPage struct {
+	URL         string    `json:"url"`
+	FinalURL    string    `json:"final_url"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Title       string    `json:"title,omitempty"`
+	Extractor   string    `json:"extractor"`
+	Text        string    `json:"text"`
+	Capped      bool      `json:"capped,omitempty"` // the body exceeded the byte cap
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// fetchCache stores extracted pages as one JSON file per URL, so paging
+// through a long document doesn't refetch it.
+type fetchCache struct {
+	dir string
+	ttl time.Duration
+}
+
+func newFetchCache(dir string, ttl time.Duration) *fetchCache {
+	if dir == "" || ttl <= 0 {
+		return nil
+	}
+	return &fetchCache{dir: dir, ttl: ttl}
+}
+
+func (c *fetchCache) path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// get returns the cached page for rawURL if it is fresh.
+func (c *fetchCache) get(rawURL string) (*fetchedPage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(rawURL))
+	if err != nil {
+		return nil, false
+	}
+	var page fetchedPage
+	if json.Unmarshal(data, &page) != nil || page.URL != rawURL || time.Since(page.FetchedAt) > c.ttl {
+		return nil, false
+	}
+	return &page, true
+}
+
+// put stores page, replacing the file atomically. Failures only cost a
+// refetch, so they are ignored.
+func (c *fetchCache) put(page *fetchedPage) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(page)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return
+	}
+	c.prune()
+	tmp, err := os.CreateTemp(c.dir, ".fetch-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), c.path(page.URL)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// prune deletes expired entries.
+func (c *fetchCache) prune() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && time.Since(info.ModTime()) > c.ttl {
+			os.Remove(filepath.Join(c.dir, e.Name()))
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package tools
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Minimal PDF text extraction: inflate the content streams and collect the
+// strings shown by text operators. It handles the common case of text
+// PDFs with simple font encodings; scanned pages and CID-keyed fonts come
+// out empty or partial.
+
+var (
+	pdfStream     = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+	pdfLength     = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	errPDFNoText  = errors.New("no extractable text found in PDF (it may be scanned or use embedded font encodings)")
+	maxPDFInflate = int64(32 << 20)
+)
+
+// extractPDFText returns the text of a PDF document.
+func extractPDFText(data []byte) (string, error) {
+	var out strings.Builder
+	for pos := 0; pos < len(data); {
+		loc := pdfStream.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		dict := string(data[pos+loc[2] : pos+loc[3]])
+		// The match can start in an earlier object; keep this object's
+		// dictionary only.
+		if i := strings.LastIndex(dict, " obj"); i >= 0 {
+			dict = dict[i:]
+		}
+		start := pos + loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		pos = start + end + len("endstream")
+		if strings.Contains(dict, "/Image") || strings.Contains(dict, "/FontFile") ||
+			strings.Contains(dict, "/Length1") || strings.Contains(dict, "/XRef") ||
+			strings.Contains(dict, "/ObjStm") || strings.Contains(dict, "/Metadata") {
+			continue
+		}
+		raw := data[start : start+end]
+		if m := pdfLength.FindStringSubmatch(dict); m != nil && m[2] == "" {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > 0 && n <= len(raw) {
+				raw = raw[:n]
+			}
+		}
+
+		content := raw
+		if strings.Contains(dict, "/FlateDecode") {
+			zr, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			// Truncated or slightly corrupt streams still yield a prefix.
+			content, _ = io.ReadAll(io.LimitReader(zr, maxPDFInflate))
+			zr.Close()
+		} else if strings.Contains(dict, "/Filter") {
+			continue
+		}
+		pdfContentText(content, &out)
+	}
+
+	text := strings.TrimSpace(collapseLines.ReplaceAllString(out.String(), "\n\n"))
+	if text == "" {
+		return "", errPDFNoText
+	}
+	return text, nil
+}
+
+// pdfContentText appends the text shown by a content stream's Tj, TJ, '
+// and " operators, starting new lines on text positioning operators.
+//
+//nolint:gocognit // small tokenizer for PDF content streams
+func pdfContentText(content []byte, out *strings.Builder) {
+	var pending []string // string operands since the last operator
+	lineHasText := false
+	newline := func() {
+		if lineHasText {
+			out.WriteByte('\n')
+			lineHasText = false
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := pdfLiteralString(content[i:])
+			pending = append(pending, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return
+			}
+			pending = append(pending, pdfHexString(content[i+1:i+end]))
+			i += end + 1
+		case c == '[':
+			// TJ arrays: strings interleaved with kerning numbers; large
+			// negative adjustments are word gaps.
+			end := i + 1
+			var parts strings.Builder
+			for end < len(content) && content[end] != ']' {
+				switch {
+				case content[end] == '(':
+					s, n := pdfLiteralString(content[end:])
+					parts.WriteString(s)
+					end += n
+				case content[end] == '<':
+					gt := bytes.IndexByte(content[end:], '>')
+					if gt < 0 {
+						end = len(content)
+						break
+					}
+					parts.WriteString(pdfHexString(content[end+1 : end+gt]))
+					end += gt + 1
+				case content[end] == '-' || content[end] >= '0' && content[end] <= '9' || content[end] == '.':
+					start := end
+					for end < len(content) && (content[end] == '-' || content[end] == '.' ||
+						content[end] >= '0' && content[end] <= '9') {
+						end++
+					}
+					if num := string(content[start:end]); strings.HasPrefix(num, "-") && len(num) > 3 {
+						parts.WriteByte(' ')
+					}
+				default:
+					end++
+				}
+			}
+			pending = append(pending, parts.String())
+			i = end + 1
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFRegular(c):
+			start := i
+			for i < len(content) && isPDFRegular(content[i]) {
+				i++
+			}
+			switch string(content[start:i]) {
+			case "Tj", "TJ":
+				for _, s := range pending {
+					out.WriteString(s)
+					lineHasText = lineHasText || s != ""
+				}
+			case "'", `"`:
+				newline()
+				for _, s := range pending {
+					out.WriteString(s)
+					lineHasText = lineHasText || s != ""
+				}
+			case "Td", "TD", "T*", "Tm":
+				newline()
+			case "ET":
+				newline()
+			}
+			pending = pending[:0]
+		default:
+			i++
+		}
+	}
+	newline()
+}
+
+func isPDFRegular(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+// pdfLiteralString decodes a (...) string at the start of b and returns it
+// with the number of bytes consumed.
+func pdfLiteralString(b []byte) (string, int) {
+	var sb strings.Builder
+	depth := 0
+	i := 0
+	for i < len(b) {
+		c := b[i]
+		switch {
+		case c == '(':
+			if depth > 0 {
+				sb.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return pdfDecodeText(sb.String()), i + 1
+			}
+			sb.WriteByte(c)
+		case c == '\\' && i+1 < len(b):
+			i++
+			switch e := b[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				if e == '\r' && i+1 < len(b) && b[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					j := 0
+					for j < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7' {
+						v = v*8 + int(b[i]-'0')
+						i++
+						j++
+					}
+					i--
+					sb.WriteByte(byte(v))
+				} else {
+					sb.WriteByte(e)
+				}
+			}
+		default:
+			sb.WriteByte(c)
+		}
+		i++
+	}
+	return pdfDecodeText(sb.String()), len(b)
+}
+
+func pdfHexString(b []byte) string {
+	var digits []byte
+	for _, c := range b {
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	raw := make([]byte, len(digits)/2)
+	for i := range raw {
+		raw[i] = hexNibble(digits[2*i])<<4 | hexNibble(digits[2*i+1])
+	}
+	s := pdfDecodeText(string(raw))
+	// Two-byte glyph IDs from CID fonts decode to control characters;
+	// drop them rather than emit noise.
+	for _, r := range s {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			return ""
+		}
+	}
+	return s
+}
+
+func hexNibble(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+// pdfDecodeText turns a PDF string into UTF-8: UTF-16BE when it has a
+// byte order mark, otherwise Latin-1 (close enough to PDFDocEncoding and
+// WinAnsi for text).
+func pdfDecodeText(s string) string {
+	if strings.HasPrefix(s, "\xfe\xff") {
+		b := []byte(s[2:])
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		sb.WriteRune(rune(s[i]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,477 @@
+package tools
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Readability-style extraction: drop page chrome, pick the element holding
+// the main content, and render it as Markdown with links kept.
+
+var (
+	unlikelyCandidate = regexp.MustCompile(`(?i)\b(comment|sidebar|footer|footnote|masthead|menu|nav|navbar|` +
+		`share|social|promo|advert|ads?|sponsor|cookie|consent|banner|related|popup|modal|subscribe|` +
+		`newsletter|breadcrumbs?)\b`)
+	likelyCandidate = regexp.MustCompile(`(?i)\b(article|content|main|post|entry|story|body|text)\b`)
+	collapseSpaces  = regexp.MustCompile(`[^\S\n]+`)
+	collapseLines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// droppedElements never contain readable content.
+var droppedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Svg: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Template: true, atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Header: true,
+	atom.Canvas: true, atom.Object: true, atom.Embed: true, atom.Link: true, atom.Meta: true,
+}
+
+// readablePage is the main content of an HTML page.
+type readablePage struct {
+	Title    string
+	Markdown string
+}
+
+// extractReadable parses an HTML document and returns its title and main
+// content as Markdown. Relative links are resolved against base, which may
+// be nil.
+func extractReadable(doc string, base *url.URL) readablePage {
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		return readablePage{}
+	}
+	page := readablePage{Title: documentTitle(root)}
+	if b := findBase(root); b != "" && base != nil {
+		if u, err := base.Parse(b); err == nil {
+			base = u
+		}
+	}
+
+	prune(root)
+	content := mainContent(root)
+	if content == nil {
+		return page
+	}
+	r := &markdownRenderer{base: base}
+	r.render(content)
+	page.Markdown = tidyMarkdown(r.sb.String())
+	return page
+}
+
+func documentTitle(root *html.Node) string {
+	var title, ogTitle string
+	walk(root, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Title:
+			if title == "" {
+				title = strings.TrimSpace(textContent(n))
+			}
+		case atom.Meta:
+			if attr(n, "property") == "og:title" && ogTitle == "" {
+				ogTitle = strings.TrimSpace(attr(n, "content"))
+			}
+		}
+		return true
+	})
+	if ogTitle != "" {
+		return ogTitle
+	}
+	return collapseSpaces.ReplaceAllString(title, " ")
+}
+
+func findBase(root *html.Node) string {
+	var href string
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Base && href == "" {
+			href = attr(n, "href")
+		}
+		return href == ""
+	})
+	return href
+}
+
+// prune removes comments, hidden elements, page chrome and blocks whose
+// class or id marks them as such.
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if shouldDrop(c) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+func shouldDrop(n *html.Node) bool {
+	switch n.Type {
+	case html.CommentNode, html.DoctypeNode:
+		return true
+	case html.ElementNode:
+	default:
+		return false
+	}
+	if droppedElements[n.DataAtom] {
+		return true
+	}
+	if _, hidden := attrLookup(n, "hidden"); hidden || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	switch attr(n, "role") {
+	case "navigation", "banner", "contentinfo", "complementary", "dialog", "alert":
+		return true
+	}
+	if strings.Contains(strings.ReplaceAll(attr(n, "style"), " ", ""), "display:none") {
+		return true
+	}
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidate.MatchString(names) && !likelyCandidate.MatchString(names)
+}
+
+// mainContent picks the element holding the page's main text: the largest
+// article or main element if there is one, otherwise the best-scoring
+// container of paragraphs, otherwise the body.
+func mainContent(root *html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main" {
+			if l := len(strings.TrimSpace(textContent(n))); l > bestLen {
+				best, bestLen = n, l
+			}
+		}
+		return true
+	})
+	if best != nil && bestLen >= 140 {
+		return best
+	}
+
+	scores := map[*html.Node]float64{}
+	walk(root, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Li:
+		default:
+			return true
+		}
+		text := strings.TrimSpace(textContent(n))
+		if len(text) < 25 || n.Parent == nil {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		scores[n.Parent] += score
+		if gp := n.Parent.Parent; gp != nil {
+			scores[gp] += score / 2
+		}
+		return false
+	})
+	var top *html.Node
+	topScore := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		if likelyCandidate.MatchString(attr(n, "class") + " " + attr(n, "id")) {
+			score *= 1.25
+		}
+		if score > topScore {
+			top, topScore = n, score
+		}
+	}
+	if top != nil && topScore >= 5 {
+		return top
+	}
+	if best != nil {
+		return best
+	}
+	var body *html.Node
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Body {
+			body = n
+		}
+		return body == nil
+	})
+	return body
+}
+
+// linkDensity is the share of n's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			linked += len(textContent(c))
+			return false
+		}
+		return true
+	})
+	return float64(linked) / float64(total)
+}
+
+// markdownRenderer writes a node tree as Markdown.
+type markdownRenderer struct {
+	sb       strings.Builder
+	base     *url.URL
+	listDeep int
+	pre      bool
+}
+
+func (r *markdownRenderer) block() {
+	r.sb.WriteString("\n\n")
+}
+
+func (r *markdownRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+//nolint:gocyclo // one case per element kind
+func (r *markdownRenderer) render(n *html.Node) {
+	if n.Type == html.TextNode {
+		if r.pre {
+			r.sb.WriteString(n.Data)
+		} else {
+			r.sb.WriteString(collapseSpaces.ReplaceAllString(strings.ReplaceAll(n.Data, "\n", " "), " "))
+		}
+		return
+	}
+	if n.Type != html.ElementNode && n.Type != html.DocumentNode {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := r.inlineText(n)
+		if text != "" {
+			r.block()
+			r.sb.WriteString(strings.Repeat("#", level) + " " + text)
+			r.block()
+		}
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Figure, atom.Dl, atom.Details:
+		r.block()
+		r.children(n)
+		r.block()
+	case atom.Br:
+		r.sb.WriteString("\n")
+	case atom.Hr:
+		r.block()
+		r.sb.WriteString("---")
+		r.block()
+	case atom.A:
+		text := r.inlineText(n)
+		href := r.resolve(attr(n, "href"))
+		switch {
+		case text == "":
+		case href == "":
+			r.sb.WriteString(text)
+		default:
+			r.sb.WriteString("[" + text + "](" + href + ")")
+		}
+	case atom.Strong, atom.B:
+		if text := r.inlineText(n); text != "" {
+			r.sb.WriteString("**" + text + "**")
+		}
+	case atom.Em, atom.I:
+		if text := r.inlineText(n); text != "" {
+			r.sb.WriteString("_" + text + "_")
+		}
+	case atom.Code:
+		if r.pre {
+			r.children(n)
+		} else if text := r.inlineText(n); text != "" {
+			r.sb.WriteString("`" + text + "`")
+		}
+	case atom.Pre:
+		r.block()
+		r.sb.WriteString("```\n")
+		r.pre = true
+		r.children(n)
+		r.pre = false
+		r.sb.WriteString("\n```")
+		r.block()
+	case atom.Ul, atom.Ol:
+		r.block()
+		r.listDeep++
+		i := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom != atom.Li {
+				continue
+			}
+			i++
+			marker := "- "
+			if n.DataAtom == atom.Ol {
+				marker = strconv.Itoa(i) + ". "
+			}
+			r.sb.WriteString("\n" + strings.Repeat("  ", r.listDeep-1) + marker)
+			r.listItem(c)
+		}
+		r.listDeep--
+		r.block()
+	case atom.Blockquote:
+		inner := &markdownRenderer{base: r.base}
+		inner.children(n)
+		r.block()
+		for _, line := range strings.Split(tidyMarkdown(inner.sb.String()), "\n") {
+			r.sb.WriteString("> " + line + "\n")
+		}
+		r.block()
+	case atom.Table:
+		r.table(n)
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			if src := r.resolve(attr(n, "src")); src != "" {
+				r.sb.WriteString("![" + alt + "](" + src + ")")
+			}
+		}
+	case atom.Dt:
+		r.sb.WriteString("\n**" + r.inlineText(n) + "**\n")
+	default:
+		r.children(n)
+	}
+}
+
+// listItem renders an li inline, keeping nested lists indented.
+func (r *markdownRenderer) listItem(li *html.Node) {
+	inner := &markdownRenderer{base: r.base, listDeep: r.listDeep}
+	inner.children(li)
+	text := strings.TrimSpace(collapseLines.ReplaceAllString(inner.sb.String(), "\n"))
+	text = strings.ReplaceAll(text, "\n\n", "\n")
+	r.sb.WriteString(text)
+}
+
+func (r *markdownRenderer) table(n *html.Node) {
+	var rows [][]string
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []string
+		for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				inner := &markdownRenderer{base: r.base}
+				inner.children(cell)
+				text := strings.TrimSpace(strings.ReplaceAll(tidyMarkdown(inner.sb.String()), "\n", " "))
+				cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return
+	}
+	r.block()
+	for i, row := range rows {
+		r.sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			r.sb.WriteString("|" + strings.Repeat(" --- |", len(row)) + "\n")
+		}
+	}
+	r.block()
+}
+
+// resolve makes href absolute, dropping script and fragment-only links.
+func (r *markdownRenderer) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil {
+		u = r.base.ResolveReference(u)
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto", "":
+		return u.String()
+	}
+	return ""
+}
+
+// inlineText renders n's children as one line of Markdown.
+func (r *markdownRenderer) inlineText(n *html.Node) string {
+	inner := &markdownRenderer{base: r.base}
+	inner.children(n)
+	return strings.TrimSpace(collapseSpaces.ReplaceAllString(strings.ReplaceAll(inner.sb.String(), "\n", " "), " "))
+}
+
+func tidyMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence {
+			lines[i] = strings.TrimRight(line, " \t")
+			if strings.TrimSpace(lines[i]) == "" {
+				lines[i] = ""
+			} else if !strings.HasPrefix(strings.TrimLeft(line, " "), "- ") && !isOrderedItem(line) {
+				lines[i] = strings.TrimLeft(lines[i], " ")
+			}
+		}
+	}
+	return strings.TrimSpace(collapseLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func isOrderedItem(line string) bool {
+	line = strings.TrimLeft(line, " ")
+	i := 0
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	return i > 0 && strings.HasPrefix(line[i:], ". ")
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// walk visits n and its descendants depth-first; fn returns false to skip
+// a node's children.
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attrLookup(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attr(n *html.Node, key string) string {
+	v, _ := attrLookup(n, key)
+	return v
+}
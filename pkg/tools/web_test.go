@@ -1,15 +1,25 @@
 package tools
 
 import (
+	"bytes"
+	"compress/zlib"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 )
 
+// newLocalWebFetchTool returns a fetch tool allowed to reach httptest
+// servers on loopback.
+func newLocalWebFetchTool(maxChars int) *WebFetchTool {
+	return NewWebFetchToolWithOptions(WebFetchToolOptions{MaxChars: maxChars, AllowPrivateNetworks: true})
+}
+
 // TestWebTool_WebFetch_Success verifies successful URL fetching
 func TestWebTool_WebFetch_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -19,7 +29,7 @@ func TestWebTool_WebFetch_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(50000)
+	tool := newLocalWebFetchTool(50000)
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
@@ -58,7 +68,7 @@ func TestWebTool_WebFetch_JSON(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(50000)
+	tool := newLocalWebFetchTool(50000)
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
@@ -149,7 +159,7 @@ func TestWebTool_WebFetch_Truncation(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(1000) // Limit to 1000 chars
+	tool := newLocalWebFetchTool(1000) // Limit to 1000 chars
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
@@ -218,7 +228,7 @@ func TestWebTool_WebFetch_HTMLExtraction(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(50000)
+	tool := newLocalWebFetchTool(50000)
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
@@ -580,3 +590,184 @@ func TestWebTool_TavilySearch_Success(t *testing.T) {
 		t.Errorf("Expected 'via Tavily' in output, got: %s", result.ForUser)
 	}
 }
+
+func TestWebFetch_BlocksNonPublicAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(50000)
+	result := tool.Execute(context.Background(), map[string]any{"url": server.URL})
+	if !result.IsError || !strings.Contains(result.ForLLM, "non-public") {
+		t.Fatalf("expected loopback fetch to be blocked, got: %s", result.ForLLM)
+	}
+
+}
+
+func TestWebFetch_Pagination(t *testing.T) {
+	content := strings.Repeat("a", 150) + strings.Repeat("b", 150)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	tool := newLocalWebFetchTool(150)
+	result := tool.Execute(context.Background(), map[string]any{"url": server.URL})
+	if result.IsError || !strings.Contains(result.ForLLM, strings.Repeat("a", 150)) ||
+		strings.Contains(result.ForLLM, "bbb") {
+		t.Fatalf("unexpected first page: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "offset=150") {
+		t.Fatalf("expected a next offset, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"url": server.URL, "offset": float64(150)})
+	if !strings.Contains(result.ForLLM, strings.Repeat("b", 150)) || strings.Contains(result.ForLLM, "aaa") {
+		t.Fatalf("unexpected second page: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "Content continues") {
+		t.Fatalf("last page should not offer more: %s", result.ForLLM)
+	}
+}
+
+func TestWebFetch_ByteCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchToolWithOptions(WebFetchToolOptions{MaxBytes: 1000, AllowPrivateNetworks: true})
+	result := tool.Execute(context.Background(), map[string]any{"url": server.URL})
+	if result.IsError || !strings.Contains(result.ForLLM, "of 1000") || !strings.Contains(result.ForLLM, "cut off") {
+		t.Fatalf("expected the body capped at 1000 bytes, got: %s", result.ForLLM[:min(len(result.ForLLM), 300)])
+	}
+}
+
+func TestWebFetch_ReadabilityMarkdown(t *testing.T) {
+	page := `<html><head><title>Ignored</title><meta property="og:title" content="The Story"></head><body>
+<nav><a href="/">Home</a><a href="/about">About</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter for more, and more, and more updates.</p></div>
+<article>
+<h1>The Story</h1>
+<p>This is the first paragraph of the article, long enough to count, with commas, and more.</p>
+<p>It links to <a href="/docs/guide">the guide</a> and <a href="https://example.org/x">elsewhere</a>.</p>
+<ul><li>one</li><li>two</li></ul>
+<pre><code>go run ./...</code></pre>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+	base, _ := url.Parse("https://example.com/posts/1")
+	got := extractReadable(page, base)
+
+	if got.Title != "The Story" {
+		t.Errorf("title = %q", got.Title)
+	}
+	for _, want := range []string{
+		"# The Story",
+		"[the guide](https://example.com/docs/guide)",
+		"[elsewhere](https://example.org/x)",
+		"- one\n- two",
+		"```\ngo run ./...\n```",
+	} {
+		if !strings.Contains(got.Markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, got.Markdown)
+		}
+	}
+	for _, unwanted := range []string{"Home", "newsletter", "Copyright"} {
+		if strings.Contains(got.Markdown, unwanted) {
+			t.Errorf("markdown kept page chrome %q:\n%s", unwanted, got.Markdown)
+		}
+	}
+}
+
+// minimalPDF builds a one-page PDF whose content stream shows lines.
+func minimalPDF(t *testing.T, lines ...string) []byte {
+	t.Helper()
+	var content strings.Builder
+	content.WriteString("BT /F1 12 Tf 72 720 Td\n")
+	for i, line := range lines {
+		if i > 0 {
+			content.WriteString("0 -14 Td\n")
+		}
+		fmt.Fprintf(&content, "(%s) Tj\n", strings.NewReplacer("(", `\(`, ")", `\)`).Replace(line))
+	}
+	content.WriteString("ET")
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(content.String()))
+	zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	pdf.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	pdf.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj << /Length %d /Filter /FlateDecode >> stream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream endobj\ntrailer << /Root 1 0 R >>\n%%EOF")
+	return pdf.Bytes()
+}
+
+func TestWebFetch_PDF(t *testing.T) {
+	doc := minimalPDF(t, "Quarterly report", "Revenue (up) 12%")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(doc)
+	}))
+	defer server.Close()
+
+	result := newLocalWebFetchTool(50000).Execute(context.Background(), map[string]any{"url": server.URL})
+	if result.IsError {
+		t.Fatalf("PDF fetch failed: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Quarterly report\nRevenue (up) 12%") ||
+		!strings.Contains(result.ForLLM, "extractor pdf") {
+		t.Fatalf("unexpected PDF text: %s", result.ForLLM)
+	}
+}
+
+func TestWebFetch_Cache(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("cached body"))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchToolWithOptions(WebFetchToolOptions{
+		AllowPrivateNetworks: true,
+		CacheDir:             t.TempDir(),
+		CacheTTL:             time.Minute,
+	})
+	for range 2 {
+		result := tool.Execute(context.Background(), map[string]any{"url": server.URL})
+		if result.IsError || !strings.Contains(result.ForLLM, "cached body") {
+			t.Fatalf("fetch failed: %s", result.ForLLM)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("server hit %d times, want 1", hits)
+	}
+
+	// URLs that net/url re-encodes hit the cache too.
+	for range 2 {
+		result := tool.Execute(context.Background(), map[string]any{"url": server.URL + "/a page"})
+		if result.IsError {
+			t.Fatalf("fetch failed: %s", result.ForLLM)
+		}
+	}
+	if hits != 2 {
+		t.Fatalf("re-encoded URL: server hit %d times, want 2", hits)
+	}
+
+	tool.cache.ttl = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	tool.Execute(context.Background(), map[string]any{"url": server.URL})
+	if hits != 3 {
+		t.Fatalf("expired entry not refetched: %d hits", hits)
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.100.100.100":  false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+	} {
+		if got := IsPublicAddr(netip.MustParseAddr(addr)); got != public {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", addr, got, public)
+		}
+	}
+}
+
+func TestPublicHostChecks(t *testing.T) {
+	err := CheckPublicHost(context.Background(), "localhost")
+	if err == nil {
+		t.Fatal("expected localhost to be refused")
+	}
+	if PublicOnlyControl("tcp4", "169.254.169.254:80", nil) == nil {
+		t.Fatal("expected dial to the metadata address to be refused")
+	}
+	if PublicOnlyControl("tcp4", "93.184.216.34:443", nil) != nil {
+		t.Fatal("expected dial to a public address to be allowed")
+	}
+}
+
+func TestNewPublicHTTPClient_RefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	_, err := NewPublicHTTPClient(0).Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "non-public") {
+		t.Fatalf("expected loopback request to be refused, got %v", err)
+	}
+}